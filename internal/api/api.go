@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"reflect"
@@ -17,6 +18,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
@@ -28,6 +30,16 @@ import (
 	"github.com/bluenviron/mediamtx/internal/servers/rtsp"
 	"github.com/bluenviron/mediamtx/internal/servers/srt"
 	"github.com/bluenviron/mediamtx/internal/servers/webrtc"
+	"github.com/bluenviron/mediamtx/internal/staticsources"
+)
+
+const (
+	sourceProbeDefaultTimeout = 10 * time.Second
+	sourceProbeMaxTimeout     = 60 * time.Second
+
+	// name of the temporary path used to validate the configuration of probed sources.
+	// It is never added to the configuration in use.
+	sourceProbePathName = "probe"
 )
 
 func interfaceIsEmpty(i interface{}) bool {
@@ -111,6 +123,9 @@ func paramName(ctx *gin.Context) (string, bool) {
 type PathManager interface {
 	APIPathsList() (*defs.APIPathList, error)
 	APIPathsGet(string) (*defs.APIPath, error)
+	APIPathsRecordStart(string) error
+	APIPathsRecordStop(string) error
+	APIPathsClip(string, time.Time, time.Time) ([]byte, error)
 }
 
 // HLSServer contains methods used by the API and Metrics server.
@@ -147,27 +162,40 @@ type WebRTCServer interface {
 	APISessionsList() (*defs.APIWebRTCSessionList, error)
 	APISessionsGet(uuid.UUID) (*defs.APIWebRTCSession, error)
 	APISessionsKick(uuid.UUID) error
+	APIPathsSendData(string, []byte, bool) error
+}
+
+// PlaybackServer contains methods used by the API.
+type PlaybackServer interface {
+	DiscoveredPaths() []string
 }
 
 type apiParent interface {
 	logger.Writer
 	APIConfigSet(conf *conf.Conf)
+	APIReady() bool
+	APIRecordRetentionSet(name string, deleteAfter *conf.StringDuration, maxSize *conf.StringSize)
+	APILogLevelGet() conf.LogLevel
+	APILogLevelSet(level conf.LogLevel)
 }
 
 // API is an API server.
 type API struct {
-	Address      string
-	ReadTimeout  conf.StringDuration
-	Conf         *conf.Conf
-	PathManager  PathManager
-	RTSPServer   RTSPServer
-	RTSPSServer  RTSPServer
-	RTMPServer   RTMPServer
-	RTMPSServer  RTMPServer
-	HLSServer    HLSServer
-	WebRTCServer WebRTCServer
-	SRTServer    SRTServer
-	Parent       apiParent
+	Address            string
+	ReadTimeout        conf.StringDuration
+	TCPKeepalivePeriod conf.StringDuration
+	Conf               *conf.Conf
+	ConfPath           string
+	PathManager        PathManager
+	RTSPServer         RTSPServer
+	RTSPSServer        RTSPServer
+	RTMPServer         RTMPServer
+	RTMPSServer        RTMPServer
+	HLSServer          HLSServer
+	WebRTCServer       WebRTCServer
+	SRTServer          SRTServer
+	PlaybackServer     PlaybackServer
+	Parent             apiParent
 
 	httpServer *httpp.WrappedServer
 	mutex      sync.RWMutex
@@ -178,7 +206,13 @@ func (a *API) Initialize() error {
 	router := gin.New()
 	router.SetTrustedProxies(nil) //nolint:errcheck
 
+	router.GET("/healthz", a.onHealthz)
+	router.GET("/readyz", a.onReadyz)
+
 	group := router.Group("/")
+	group.Use(a.middlewareAuth)
+
+	group.GET("/v3/config/effective", a.onConfigEffectiveGet)
 
 	group.GET("/v3/config/global/get", a.onConfigGlobalGet)
 	group.PATCH("/v3/config/global/patch", a.onConfigGlobalPatch)
@@ -188,17 +222,31 @@ func (a *API) Initialize() error {
 
 	group.GET("/v3/config/paths/list", a.onConfigPathsList)
 	group.GET("/v3/config/paths/get/*name", a.onConfigPathsGet)
+	group.GET("/v3/config/paths/effective/*name", a.onConfigPathsEffectiveGet)
 	group.POST("/v3/config/paths/add/*name", a.onConfigPathsAdd)
 	group.PATCH("/v3/config/paths/patch/*name", a.onConfigPathsPatch)
 	group.POST("/v3/config/paths/replace/*name", a.onConfigPathsReplace)
 	group.DELETE("/v3/config/paths/delete/*name", a.onConfigPathsDelete)
+	group.POST("/v3/config/paths/reload/*name", a.onConfigPathsReload)
+
+	group.GET("/v3/config/loglevel", a.onConfigLogLevelGet)
+	group.PATCH("/v3/config/loglevel", a.onConfigLogLevelPatch)
 
 	group.GET("/v3/paths/list", a.onPathsList)
 	group.GET("/v3/paths/get/*name", a.onPathsGet)
+	group.GET("/v3/paths/readers/*name", a.onPathsReaders)
+	group.GET("/v3/paths/export", a.onPathsExport)
+	group.POST("/v3/paths/import", a.onPathsImport)
+	group.POST("/v3/paths/record/start/*name", a.onPathsRecordStart)
+	group.POST("/v3/paths/record/stop/*name", a.onPathsRecordStop)
+	group.POST("/v3/paths/clip/*name", a.onPathsClip)
+
+	group.POST("/v3/sources/probe", a.onSourcesProbe)
 
 	if !interfaceIsEmpty(a.HLSServer) {
 		group.GET("/v3/hlsmuxers/list", a.onHLSMuxersList)
 		group.GET("/v3/hlsmuxers/get/*name", a.onHLSMuxersGet)
+		group.GET("/v3/hls/signurl/*name", a.onHLSSignURL)
 	}
 
 	if !interfaceIsEmpty(a.RTSPServer) {
@@ -233,6 +281,7 @@ func (a *API) Initialize() error {
 		group.GET("/v3/webrtcsessions/list", a.onWebRTCSessionsList)
 		group.GET("/v3/webrtcsessions/get/:id", a.onWebRTCSessionsGet)
 		group.POST("/v3/webrtcsessions/kick/:id", a.onWebRTCSessionsKick)
+		group.POST("/v3/paths/sendData/*name", a.onPathsSendData)
 	}
 
 	if !interfaceIsEmpty(a.SRTServer) {
@@ -244,6 +293,9 @@ func (a *API) Initialize() error {
 	group.GET("/v3/recordings/list", a.onRecordingsList)
 	group.GET("/v3/recordings/get/*name", a.onRecordingsGet)
 	group.DELETE("/v3/recordings/deletesegment", a.onRecordingDeleteSegment)
+	group.GET("/v3/recordings/segments/*name", a.onRecordingsSegmentsList)
+	group.DELETE("/v3/recordings/segments/*name", a.onRecordingsSegmentsDelete)
+	group.PATCH("/v3/recordings/retention/patch/*name", a.onRecordingsRetentionPatch)
 
 	network, address := restrictnetwork.Restrict("tcp", a.Address)
 
@@ -252,8 +304,11 @@ func (a *API) Initialize() error {
 		network,
 		address,
 		time.Duration(a.ReadTimeout),
+		time.Duration(a.TCPKeepalivePeriod),
 		"",
 		"",
+		0,
+		nil,
 		router,
 		a,
 	)
@@ -287,6 +342,40 @@ func (a *API) writeError(ctx *gin.Context, status int, err error) {
 	})
 }
 
+func (a *API) onHealthz(ctx *gin.Context) {
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) onReadyz(ctx *gin.Context) {
+	if !a.Parent.APIReady() {
+		ctx.JSON(http.StatusServiceUnavailable, &defs.APIError{
+			Error: "not ready",
+		})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) middlewareAuth(ctx *gin.Context) {
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	authenticator := &auth.HTTPAuthenticator{
+		ExternalAuthenticationURL: c.ControlExternalAuthenticationURL(),
+		User:                      c.APIUser,
+		Pass:                      c.APIPass,
+		IPs:                       c.APIIPs,
+		Action:                    auth.ActionAPI,
+		Parent:                    a,
+	}
+
+	if !authenticator.Authenticate(ctx.Writer, ctx.Request) {
+		ctx.Abort()
+	}
+}
+
 func (a *API) onConfigGlobalGet(ctx *gin.Context) {
 	a.mutex.RLock()
 	c := a.Conf
@@ -557,6 +646,84 @@ func (a *API) onConfigPathsDelete(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+// onConfigPathsReload reads the configuration of a path from the configuration file
+// and applies it, while other paths are left untouched.
+func (a *API) onConfigPathsReload(ctx *gin.Context) {
+	confName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	if a.ConfPath == "" {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("configuration file not found"))
+		return
+	}
+
+	fileConf, _, err := conf.Load(a.ConfPath, nil)
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	p, ok := fileConf.OptionalPaths[confName]
+	if !ok {
+		a.writeError(ctx, http.StatusNotFound, fmt.Errorf("path configuration not found"))
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	newConf := a.Conf.Clone()
+
+	err = newConf.ReplacePath(confName, p)
+	if errors.Is(err, conf.ErrPathNotFound) {
+		err = newConf.AddPath(confName, p)
+	}
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err = newConf.Validate()
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	a.Conf = newConf
+	a.Parent.APIConfigSet(newConf)
+
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) onConfigLogLevelGet(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, &defs.APILogLevel{
+		LogLevel: a.Parent.APILogLevelGet(),
+	})
+}
+
+func (a *API) onConfigLogLevelPatch(ctx *gin.Context) {
+	var in defs.APILogLevelPatch
+	d := json.NewDecoder(ctx.Request.Body)
+	d.DisallowUnknownFields()
+	err := d.Decode(&in)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if in.LogLevel == nil {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("'logLevel' must be provided"))
+		return
+	}
+
+	a.Parent.APILogLevelSet(*in.LogLevel)
+
+	ctx.Status(http.StatusOK)
+}
+
 func (a *API) onPathsList(ctx *gin.Context) {
 	data, err := a.PathManager.APIPathsList()
 	if err != nil {
@@ -595,6 +762,264 @@ func (a *API) onPathsGet(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, data)
 }
 
+func (a *API) onPathsReaders(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	path, err := a.PathManager.APIPathsGet(pathName)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	readers := append([]defs.APIPathSourceOrReader(nil), path.Readers...)
+
+	// readers are sorted in order to make pages consistent between calls.
+	sort.Slice(readers, func(i, j int) bool {
+		if readers[i].Type != readers[j].Type {
+			return readers[i].Type < readers[j].Type
+		}
+		return readers[i].ID < readers[j].ID
+	})
+
+	itemCount := len(readers)
+	pageCount, err := paginate(&readers, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// statistics are fetched after pagination, in order to query only the readers of current page.
+	data := &defs.APIPathReaderList{
+		ItemCount: itemCount,
+		PageCount: pageCount,
+		Items:     make([]*defs.APIPathReader, len(readers)),
+	}
+
+	for i, r := range readers {
+		data.Items[i] = a.describePathReader(pathName, r)
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+// describePathReader fills the remote address and statistics of a reader.
+// Readers that have been closed in the meanwhile are returned without statistics.
+func (a *API) describePathReader(pathName string, r defs.APIPathSourceOrReader) *defs.APIPathReader {
+	item := &defs.APIPathReader{
+		Type: r.Type,
+		ID:   r.ID,
+	}
+
+	if r.Type == "hlsMuxer" {
+		if !interfaceIsEmpty(a.HLSServer) {
+			if muxer, err := a.HLSServer.APIMuxersGet(pathName); err == nil {
+				item.BytesSent = muxer.BytesSent
+			}
+		}
+		return item
+	}
+
+	id, err := uuid.Parse(r.ID)
+	if err != nil {
+		return item
+	}
+
+	switch r.Type {
+	case "rtspSession", "rtspsSession":
+		srv := a.RTSPServer
+		if r.Type == "rtspsSession" {
+			srv = a.RTSPSServer
+		}
+		if !interfaceIsEmpty(srv) {
+			if session, err := srv.APISessionsGet(id); err == nil {
+				item.RemoteAddr = session.RemoteAddr
+				item.BytesSent = session.BytesSent
+			}
+		}
+
+	case "rtmpConn", "rtmpsConn":
+		srv := a.RTMPServer
+		if r.Type == "rtmpsConn" {
+			srv = a.RTMPSServer
+		}
+		if !interfaceIsEmpty(srv) {
+			if conn, err := srv.APIConnsGet(id); err == nil {
+				item.RemoteAddr = conn.RemoteAddr
+				item.BytesSent = conn.BytesSent
+			}
+		}
+
+	case "srtConn":
+		if !interfaceIsEmpty(a.SRTServer) {
+			if conn, err := a.SRTServer.APIConnsGet(id); err == nil {
+				item.RemoteAddr = conn.RemoteAddr
+				item.BytesSent = conn.BytesSent
+			}
+		}
+
+	case "webrtcSession":
+		if !interfaceIsEmpty(a.WebRTCServer) {
+			if session, err := a.WebRTCServer.APISessionsGet(id); err == nil {
+				item.RemoteAddr = session.RemoteAddr
+				item.BytesSent = session.BytesSent
+			}
+		}
+	}
+
+	return item
+}
+
+func (a *API) onPathsRecordStart(ctx *gin.Context) {
+	a.onPathsRecord(ctx, a.PathManager.APIPathsRecordStart)
+}
+
+func (a *API) onPathsRecordStop(ctx *gin.Context) {
+	a.onPathsRecord(ctx, a.PathManager.APIPathsRecordStop)
+}
+
+func (a *API) onPathsRecord(ctx *gin.Context, cb func(string) error) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	err := cb(pathName)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+type apiPathsClipReq struct {
+	Start conf.StringDuration `json:"start"`
+	End   conf.StringDuration `json:"end"`
+}
+
+func (a *API) onPathsClip(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	var in apiPathsClipReq
+	err := json.NewDecoder(ctx.Request.Body).Decode(&in)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if in.End < 0 || in.Start <= in.End {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("'start' must be greater than 'end'"))
+		return
+	}
+
+	now := time.Now()
+
+	byts, err := a.PathManager.APIPathsClip(pathName,
+		now.Add(-time.Duration(in.Start)), now.Add(-time.Duration(in.End)))
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=\"clip.mp4\"")
+	ctx.Data(http.StatusOK, "video/mp4", byts)
+}
+
+func (a *API) onSourcesProbe(ctx *gin.Context) {
+	timeout := sourceProbeDefaultTimeout
+
+	if v := ctx.Query("timeout"); v != "" {
+		var d conf.StringDuration
+		err := d.UnmarshalJSON([]byte(`"` + v + `"`))
+		if err != nil {
+			a.writeError(ctx, http.StatusBadRequest, err)
+			return
+		}
+
+		if d <= 0 || time.Duration(d) > sourceProbeMaxTimeout {
+			a.writeError(ctx, http.StatusBadRequest,
+				fmt.Errorf("'timeout' must be greater than zero and less than or equal to %v", sourceProbeMaxTimeout))
+			return
+		}
+
+		timeout = time.Duration(d)
+	}
+
+	var p conf.OptionalPath
+	err := json.NewDecoder(ctx.Request.Body).Decode(&p)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate the source and fill missing parameters with path defaults,
+	// by using a copy of the configuration that is then discarded.
+	a.mutex.RLock()
+	newConf := a.Conf.Clone()
+	a.mutex.RUnlock()
+
+	newConf.RemovePath(sourceProbePathName) //nolint:errcheck
+
+	err = newConf.AddPath(sourceProbePathName, &p)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err = newConf.Validate()
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	pathConf := newConf.Paths[sourceProbePathName]
+
+	if !pathConf.HasStaticSource() {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("'source' must be a static source"))
+		return
+	}
+
+	desc, err := staticsources.Probe(ctx.Request.Context(), staticsources.ProbeParams{
+		ResolvedSource: pathConf.Source,
+		ReadTimeout:    newConf.ReadTimeout,
+		WriteTimeout:   newConf.WriteTimeout,
+		WriteQueueSize: newConf.WriteQueueSize,
+		Conf:           pathConf,
+		Timeout:        timeout,
+		Parent:         a,
+	})
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &defs.APISourceProbe{
+		Tracks:       defs.MediasToCodecs(desc.Medias),
+		TrackDetails: defs.MediasToAPITracks(desc.Medias),
+	})
+}
+
 func (a *API) onRTSPConnsList(ctx *gin.Context) {
 	data, err := a.RTSPServer.APIConnsList()
 	if err != nil {
@@ -941,6 +1366,49 @@ func (a *API) onHLSMuxersGet(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, data)
 }
 
+func (a *API) onHLSSignURL(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	duration := time.Hour
+	if v := ctx.Query("duration"); v != "" {
+		var d conf.StringDuration
+		err := d.UnmarshalJSON([]byte(`"` + v + `"`))
+		if err != nil || d <= 0 {
+			a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid 'duration' parameter"))
+			return
+		}
+		duration = time.Duration(d)
+	}
+
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	_, pathConf, _, err := conf.FindPathConf(c.Paths, pathName)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if pathConf.HLSSignedURLSecret == "" {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("signed URLs are disabled on path '%s'", pathName))
+		return
+	}
+
+	expires := time.Now().Add(duration).Truncate(time.Second)
+	token := hls.SignedURLToken(pathConf.HLSSignedURLSecret, pathName, expires)
+
+	ctx.JSON(http.StatusOK, &defs.APIHLSSignedURL{
+		Token:   token,
+		Expires: expires,
+		Query:   "token=" + token + "&expires=" + strconv.FormatInt(expires.Unix(), 10),
+	})
+}
+
 func (a *API) onWebRTCSessionsList(ctx *gin.Context) {
 	data, err := a.WebRTCServer.APISessionsList()
 	if err != nil {
@@ -999,6 +1467,31 @@ func (a *API) onWebRTCSessionsKick(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *API) onPathsSendData(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// messages are sent as text, unless they are explicitly marked as binary.
+	isBinary := ctx.ContentType() == "application/octet-stream"
+
+	err = a.WebRTCServer.APIPathsSendData(pathName, data, isBinary)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
 func (a *API) onSRTConnsList(ctx *gin.Context) {
 	data, err := a.SRTServer.APIConnsList()
 	if err != nil {
@@ -1064,6 +1557,10 @@ func (a *API) onRecordingsList(ctx *gin.Context) {
 
 	pathNames := getAllPathsWithRecordings(c.Paths)
 
+	if !interfaceIsEmpty(a.PlaybackServer) {
+		pathNames = removeDuplicatesAndSort(append(pathNames, a.PlaybackServer.DiscoveredPaths()...))
+	}
+
 	data := defs.APIRecordingList{}
 
 	data.ItemCount = len(pathNames)
@@ -1077,13 +1574,31 @@ func (a *API) onRecordingsList(ctx *gin.Context) {
 	data.Items = make([]*defs.APIRecording, len(pathNames))
 
 	for i, pathName := range pathNames {
-		_, pathConf, _, _ := conf.FindPathConf(c.Paths, pathName)
+		pathConf, _ := a.findRecordingPathConf(c, pathName)
 		data.Items[i] = recordingEntry(pathConf, pathName)
 	}
 
 	ctx.JSON(http.StatusOK, data)
 }
 
+// findRecordingPathConf returns the configuration of a path with recordings.
+// Paths discovered by the playback server that are not present
+// in the configuration use default path settings.
+func (a *API) findRecordingPathConf(c *conf.Conf, pathName string) (*conf.Path, error) {
+	_, pathConf, _, err := conf.FindPathConf(c.Paths, pathName)
+	if err != nil && !interfaceIsEmpty(a.PlaybackServer) {
+		for _, name := range a.PlaybackServer.DiscoveredPaths() {
+			if name == pathName {
+				pathConf = c.PathDefaults.Clone()
+				pathConf.Name = pathName
+				return pathConf, nil
+			}
+		}
+	}
+
+	return pathConf, err
+}
+
 func (a *API) onRecordingsGet(ctx *gin.Context) {
 	pathName, ok := paramName(ctx)
 	if !ok {
@@ -1095,7 +1610,7 @@ func (a *API) onRecordingsGet(ctx *gin.Context) {
 	c := a.Conf
 	a.mutex.RUnlock()
 
-	_, pathConf, _, err := conf.FindPathConf(c.Paths, pathName)
+	pathConf, err := a.findRecordingPathConf(c, pathName)
 	if err != nil {
 		a.writeError(ctx, http.StatusBadRequest, err)
 		return
@@ -1138,9 +1653,16 @@ func (a *API) onRecordingDeleteSegment(ctx *gin.Context) {
 		pathConf.RecordFormat,
 	)
 
-	segmentPath := record.Path{
-		Start: start,
-	}.Encode(pathFormat)
+	segmentPath, err := findSegmentPath(pathFormat, start)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if record.IsSegmentBeingWritten(segmentPath) {
+		a.writeError(ctx, http.StatusConflict, fmt.Errorf("segment is being written"))
+		return
+	}
 
 	err = os.Remove(segmentPath)
 	if err != nil {
@@ -1151,6 +1673,177 @@ func (a *API) onRecordingDeleteSegment(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *API) onRecordingsSegmentsList(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	pathConf, err := a.findRecordingPathConf(c, pathName)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if !pathConf.Playback {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("playback is disabled on path '%s'", pathName))
+		return
+	}
+
+	files, err := recordingSegmentFiles(pathConf, pathName)
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	data := defs.APIRecordingSegmentFileList{
+		ItemCount: len(files),
+	}
+
+	pageCount, err := paginate(&files, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+	data.Items = files
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onRecordingsSegmentsDelete(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, ctx.Query("start"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid 'start' parameter: %w", err))
+		return
+	}
+
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	_, pathConf, _, err := conf.FindPathConf(c.Paths, pathName)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if !pathConf.Playback {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("playback is disabled on path '%s'", pathName))
+		return
+	}
+
+	pathFormat := record.PathAddExtension(
+		strings.ReplaceAll(pathConf.RecordPath, "%path", pathName),
+		pathConf.RecordFormat,
+	)
+
+	segmentPath, err := findSegmentPath(pathFormat, start)
+	if err != nil {
+		if errors.Is(err, errSegmentNotFound) || errors.Is(err, os.ErrNotExist) {
+			a.writeError(ctx, http.StatusNotFound, errSegmentNotFound)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if record.IsSegmentBeingWritten(segmentPath) {
+		a.writeError(ctx, http.StatusConflict, fmt.Errorf("segment is being written"))
+		return
+	}
+
+	err = os.Remove(segmentPath)
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	a.Log(logger.Info, "segment of path '%s' starting at %v deleted", pathName, start)
+
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) onRecordingsRetentionPatch(ctx *gin.Context) {
+	confName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	var in defs.APIRecordingRetentionPatch
+	d := json.NewDecoder(ctx.Request.Body)
+	d.DisallowUnknownFields()
+	err := d.Decode(&in)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if in.RecordDeleteAfter == nil && in.RecordMaxSize == nil {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("'recordDeleteAfter' or 'recordMaxSize' must be provided"))
+		return
+	}
+
+	values := make(map[string]interface{})
+	if in.RecordDeleteAfter != nil {
+		values["recordDeleteAfter"] = in.RecordDeleteAfter
+	}
+	if in.RecordMaxSize != nil {
+		values["recordMaxSize"] = in.RecordMaxSize
+	}
+
+	byts, _ := json.Marshal(values)
+
+	var p conf.OptionalPath
+	err = json.Unmarshal(byts, &p)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	newConf := a.Conf.Clone()
+
+	err = newConf.PatchPath(confName, &p)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	err = newConf.Validate()
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if in.Persist {
+		a.Conf = newConf
+		a.Parent.APIConfigSet(newConf)
+	} else {
+		a.Parent.APIRecordRetentionSet(confName, in.RecordDeleteAfter, in.RecordMaxSize)
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
 // ReloadConf is called by core.
 func (a *API) ReloadConf(conf *conf.Conf) {
 	a.mutex.Lock()
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+const redacted = "REDACTED"
+
+// isSecretKey returns whether a configuration key contains a secret,
+// like a password, a passphrase or a secret used to sign URLs.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "pass") || strings.Contains(key, "secret")
+}
+
+// headersKeys contains configuration keys whose values are headers,
+// which can contain credentials (i.e. Authorization or tokens).
+var headersKeys = func() map[string]struct{} {
+	ret := make(map[string]struct{})
+	headersType := reflect.TypeOf(conf.Headers(nil))
+
+	for _, t := range []reflect.Type{reflect.TypeOf(conf.Conf{}), reflect.TypeOf(conf.Path{})} {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Type == headersType {
+				ret[strings.Split(f.Tag.Get("json"), ",")[0]] = struct{}{}
+			}
+		}
+	}
+
+	return ret
+}()
+
+func redactHeaders(v interface{}) interface{} {
+	headers, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	for key, val := range headers {
+		if s, ok := val.(string); ok && s != "" {
+			headers[key] = redacted
+		}
+	}
+	return headers
+}
+
+// redactURL removes the password from URLs that contain credentials,
+// like sources and push destinations.
+func redactURL(v string) string {
+	if !strings.Contains(v, "://") {
+		return v
+	}
+
+	u, err := url.Parse(v)
+	if err != nil || u.User == nil {
+		return v
+	}
+
+	if _, ok := u.User.Password(); !ok {
+		return v
+	}
+
+	u.User = url.UserPassword(u.User.Username(), redacted)
+	return u.String()
+}
+
+func redactSecrets(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		for key, val := range tv {
+			if s, ok := val.(string); ok && s != "" && isSecretKey(key) {
+				tv[key] = redacted
+			} else if _, ok := headersKeys[key]; ok {
+				tv[key] = redactHeaders(val)
+			} else {
+				tv[key] = redactSecrets(val)
+			}
+		}
+		return tv
+
+	case []interface{}:
+		for i, val := range tv {
+			tv[i] = redactSecrets(val)
+		}
+		return tv
+
+	case string:
+		return redactURL(tv)
+
+	default:
+		return v
+	}
+}
+
+// toGeneric converts a value into maps, slices and scalars,
+// preserving numbers in order to avoid converting integers into floats.
+func toGeneric(v interface{}) (interface{}, error) {
+	byts, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(byts))
+	dec.UseNumber()
+
+	var generic interface{}
+	err = dec.Decode(&generic)
+	return generic, err
+}
+
+// effectiveConfig returns the configuration in use, in which
+// path configurations are merged with path defaults.
+func effectiveConfig(c *conf.Conf) (interface{}, error) {
+	global, err := toGeneric(c.Global())
+	if err != nil {
+		return nil, err
+	}
+
+	pathDefaults, err := toGeneric(c.PathDefaults)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := toGeneric(c.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := global.(map[string]interface{})
+	ret["pathDefaults"] = pathDefaults
+	ret["paths"] = paths
+
+	return ret, nil
+}
+
+func (a *API) writeEffectiveConfig(ctx *gin.Context, generic interface{}) {
+	byts, err := yaml.Marshal(redactSecrets(generic))
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/yaml", byts)
+}
+
+func (a *API) onConfigEffectiveGet(ctx *gin.Context) {
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	generic, err := effectiveConfig(c)
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	a.writeEffectiveConfig(ctx, generic)
+}
+
+func (a *API) onConfigPathsEffectiveGet(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	_, pathConf, _, err := conf.FindPathConf(c.Paths, pathName)
+	if err != nil {
+		a.writeError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	generic, err := toGeneric(pathConf)
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	a.writeEffectiveConfig(ctx, generic)
+}
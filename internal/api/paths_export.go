@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+// runtimePathConf returns the configuration of a path created at runtime
+// from a configuration with a regular expression.
+// The source is resolved, since the path is exported without the regular expression.
+func runtimePathConf(c *conf.Conf, name string, confName string) (interface{}, error) {
+	generic, err := toGeneric(c.OptionalPaths[confName])
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := generic.(map[string]interface{})
+	if !ok {
+		return generic, nil
+	}
+
+	if source, ok := values["source"].(string); ok {
+		source = strings.ReplaceAll(source, "$MTX_PATH", name)
+
+		if pathConf, ok := c.Paths[confName]; ok && pathConf.Regexp != nil {
+			matches := pathConf.Regexp.FindStringSubmatch(name)
+			if len(matches) > 1 {
+				for i, ma := range matches[1:] {
+					source = strings.ReplaceAll(source, "$G"+strconv.FormatInt(int64(i+1), 10), ma)
+				}
+			}
+		}
+
+		values["source"] = source
+	}
+
+	return values, nil
+}
+
+// exportedPathConf returns the configuration of a path
+// in the format used by /v3/paths/export.
+func exportedPathConf(c *conf.Conf, name string, confName string) (interface{}, error) {
+	if confName == name {
+		return toGeneric(c.OptionalPaths[name])
+	}
+	return runtimePathConf(c, name, confName)
+}
+
+// restoreSecrets replaces redacted values of an imported path
+// with the values of the configuration in use.
+func restoreSecrets(imported interface{}, existing interface{}, redactedExisting interface{}) interface{} {
+	switch tv := imported.(type) {
+	case map[string]interface{}:
+		em, _ := existing.(map[string]interface{})
+		rm, _ := redactedExisting.(map[string]interface{})
+		for key, val := range tv {
+			tv[key] = restoreSecrets(val, em[key], rm[key])
+		}
+		return tv
+
+	case []interface{}:
+		el, _ := existing.([]interface{})
+		rl, _ := redactedExisting.([]interface{})
+		if len(el) != len(tv) || len(rl) != len(tv) {
+			return tv
+		}
+		for i, val := range tv {
+			tv[i] = restoreSecrets(val, el[i], rl[i])
+		}
+		return tv
+
+	case string:
+		if es, ok := existing.(string); ok && es != tv && redactedExisting == tv {
+			return es
+		}
+		return tv
+
+	default:
+		return imported
+	}
+}
+
+func containsRedacted(v interface{}) bool {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		for _, val := range tv {
+			if containsRedacted(val) {
+				return true
+			}
+		}
+
+	case []interface{}:
+		for _, val := range tv {
+			if containsRedacted(val) {
+				return true
+			}
+		}
+
+	case string:
+		return tv == redacted || strings.Contains(tv, ":"+redacted+"@")
+	}
+
+	return false
+}
+
+func (a *API) onPathsExport(ctx *gin.Context) {
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	paths := make(map[string]interface{}, len(c.OptionalPaths))
+
+	for name := range c.OptionalPaths {
+		generic, err := exportedPathConf(c, name, name)
+		if err != nil {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		paths[name] = generic
+	}
+
+	// paths created at runtime from regular expressions
+	if !interfaceIsEmpty(a.PathManager) {
+		data, err := a.PathManager.APIPathsList()
+		if err != nil {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		for _, pa := range data.Items {
+			if _, ok := paths[pa.Name]; ok || pa.ConfName == pa.Name {
+				continue
+			}
+
+			generic, err := exportedPathConf(c, pa.Name, pa.ConfName)
+			if err != nil {
+				a.writeError(ctx, http.StatusInternalServerError, err)
+				return
+			}
+			paths[pa.Name] = generic
+		}
+	}
+
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"paths": redactSecrets(paths),
+	})
+}
+
+func (a *API) onPathsImport(ctx *gin.Context) {
+	var generic struct {
+		Paths map[string]interface{} `json:"paths"`
+	}
+
+	dec := json.NewDecoder(ctx.Request.Body)
+	dec.UseNumber()
+	err := dec.Decode(&generic)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// secrets are redacted by /v3/paths/export.
+	// Redacted values are replaced with the ones of the configuration in use.
+	for name, p := range generic.Paths {
+		confName, _, _, err2 := conf.FindPathConf(a.Conf.Paths, name)
+		if err2 == nil {
+			existing, err2 := exportedPathConf(a.Conf, name, confName)
+			if err2 != nil {
+				a.writeError(ctx, http.StatusInternalServerError, err2)
+				return
+			}
+
+			redactedExisting, _ := exportedPathConf(a.Conf, name, confName)
+			generic.Paths[name] = restoreSecrets(p, existing, redactSecrets(redactedExisting))
+		}
+
+		if containsRedacted(generic.Paths[name]) {
+			a.writeError(ctx, http.StatusBadRequest,
+				fmt.Errorf("path '%s' contains redacted secrets, that must be provided", name))
+			return
+		}
+	}
+
+	byts, err := json.Marshal(generic)
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	var in defs.APIPathsExport
+	err = json.NewDecoder(bytes.NewReader(byts)).Decode(&in)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	newConf := a.Conf.Clone()
+
+	// paths that already exist are replaced, in order to reconcile them
+	// with the imported ones without creating duplicates.
+	newConf.ImportPaths(in.Paths)
+
+	err = newConf.Validate()
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	a.Conf = newConf
+	a.Parent.APIConfigSet(newConf)
+
+	ctx.Status(http.StatusOK)
+}
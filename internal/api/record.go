@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
@@ -15,6 +16,8 @@ import (
 
 var errFound = errors.New("found")
 
+var errSegmentNotFound = errors.New("segment not found")
+
 func fixedPathHasRecordings(pathConf *conf.Path) bool {
 	recordPath := record.PathAddExtension(
 		strings.ReplaceAll(pathConf.RecordPath, "%path", pathConf.Name),
@@ -49,6 +52,72 @@ func fixedPathHasRecordings(pathConf *conf.Path) bool {
 	return errors.Is(err, errFound)
 }
 
+// findSegmentPath finds the segment with given start.
+// Segment paths can't be rebuilt from the start only,
+// since they may contain stream properties and labels.
+func findSegmentPath(recordPath string, start time.Time) (string, error) {
+	// we have to convert to absolute paths
+	// otherwise, recordPath and fpath inside Walk() won't have common elements
+	recordPath, _ = filepath.Abs(recordPath)
+
+	commonPath := record.CommonPath(recordPath)
+
+	var segmentPath string
+
+	err := filepath.Walk(commonPath, func(fpath string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			var pa record.Path
+			ok := pa.Decode(recordPath, fpath)
+			if ok && pa.Start.Equal(start) {
+				segmentPath = fpath
+				return errFound
+			}
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return "", err
+	}
+
+	if segmentPath == "" {
+		return "", errSegmentNotFound
+	}
+
+	return segmentPath, nil
+}
+
+func recordingSegmentFiles(
+	pathConf *conf.Path,
+	pathName string,
+) ([]*defs.APIRecordingSegmentFile, error) {
+	files, err := playback.FindSegmentFiles(pathConf, pathName)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*defs.APIRecordingSegmentFile, len(files))
+
+	for i, f := range files {
+		ret[i] = &defs.APIRecordingSegmentFile{
+			Start:   f.Start,
+			Size:    uint64(f.Size),
+			Writing: record.IsSegmentBeingWritten(f.Path),
+		}
+
+		if f.Duration != nil {
+			v := f.Duration.Seconds()
+			ret[i].Duration = &v
+		}
+	}
+
+	return ret, nil
+}
+
 func regexpPathGetRecordings(pathConf *conf.Path) []string {
 	recordPath := record.PathAddExtension(
 		pathConf.RecordPath,
@@ -3,16 +3,37 @@ package asyncwriter
 
 import (
 	"fmt"
+	"sync"
 
-	"github.com/bluenviron/gortsplib/v4/pkg/ringbuffer"
-
+	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/logger"
 )
 
+type entry struct {
+	track interface{}
+	cb    func() error
+
+	// size of the element, that is returned to the growth budget
+	// when the element is removed. It is zero for regular elements.
+	grownSize uint64
+}
+
 // Writer is an asynchronous writer.
 type Writer struct {
+	queueSize      int
+	maxQueueSize   int
+	budget         *GrowthBudget
+	overflowPolicy conf.ReaderOverflowPolicy
 	writeErrLogger logger.Writer
-	buffer         *ringbuffer.RingBuffer
+
+	mutex           sync.Mutex
+	cond            *sync.Cond
+	queue           []entry
+	closed          bool
+	overflowed      bool
+	keyframeTracks  map[interface{}]struct{}
+	waitingKeyframe map[interface{}]struct{}
+	extension       int
 
 	// out
 	err chan error
@@ -21,15 +42,39 @@ type Writer struct {
 // New allocates a Writer.
 func New(
 	queueSize int,
+	overflowPolicy conf.ReaderOverflowPolicy,
 	parent logger.Writer,
 ) *Writer {
-	buffer, _ := ringbuffer.New(uint64(queueSize))
+	w := &Writer{
+		queueSize:       queueSize,
+		overflowPolicy:  overflowPolicy,
+		writeErrLogger:  logger.NewLimitedLogger(parent),
+		keyframeTracks:  make(map[interface{}]struct{}),
+		waitingKeyframe: make(map[interface{}]struct{}),
+		err:             make(chan error),
+	}
+	w.cond = sync.NewCond(&w.mutex)
+	return w
+}
 
-	return &Writer{
-		writeErrLogger: logger.NewLimitedLogger(parent),
-		buffer:         buffer,
-		err:            make(chan error),
+// NewReader allocates a Writer for a reader of a path.
+// The queue size and the overflow policy are taken from the path configuration,
+// defaultQueueSize is used when the path does not specify a queue size.
+func NewReader(
+	pathConf *conf.Path,
+	defaultQueueSize int,
+	budget *GrowthBudget,
+	parent logger.Writer,
+) *Writer {
+	queueSize := pathConf.ReaderQueueSize
+	if queueSize == 0 {
+		queueSize = defaultQueueSize
 	}
+
+	w := New(queueSize, pathConf.ReaderOverflowPolicy, parent)
+	w.maxQueueSize = pathConf.ReaderQueueMaxSize
+	w.budget = budget
+	return w
 }
 
 // Start starts the writer routine.
@@ -39,7 +84,13 @@ func (w *Writer) Start() {
 
 // Stop stops the writer routine.
 func (w *Writer) Stop() {
-	w.buffer.Close()
+	w.mutex.Lock()
+	w.closed = true
+	w.releaseAll()
+	w.queue = nil
+	w.cond.Signal()
+	w.mutex.Unlock()
+
 	<-w.err
 }
 
@@ -54,22 +105,178 @@ func (w *Writer) run() {
 
 func (w *Writer) runInner() error {
 	for {
-		cb, ok := w.buffer.Pull()
-		if !ok {
-			return fmt.Errorf("terminated")
+		cb, err := w.pull()
+		if err != nil {
+			return err
 		}
 
-		err := cb.(func() error)()
+		err = cb()
 		if err != nil {
 			return err
 		}
 	}
 }
 
+func (w *Writer) pull() (func() error, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for len(w.queue) == 0 && !w.closed && !w.overflowed {
+		w.cond.Wait()
+	}
+
+	if w.closed {
+		return nil, fmt.Errorf("terminated")
+	}
+
+	if w.overflowed {
+		return nil, fmt.Errorf("write queue is full")
+	}
+
+	e := w.removeFirst()
+	if w.extension > 0 {
+		w.extension--
+	}
+
+	return e.cb, nil
+}
+
 // Push appends an element to the queue.
-func (w *Writer) Push(cb func() error) {
-	ok := w.buffer.Push(cb)
-	if !ok {
-		w.writeErrLogger.Log(logger.Warn, "write queue is full")
+// track identifies the track the element belongs to, while randomAccess
+// tells whether the element can be decoded without previous elements of the same track.
+// size is the size in bytes of the element, and is used to limit the memory
+// used by queues that grow beyond their regular size.
+func (w *Writer) Push(track interface{}, randomAccess bool, size uint64, cb func() error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed || w.overflowed {
+		return
+	}
+
+	if !randomAccess {
+		w.keyframeTracks[track] = struct{}{}
+	}
+
+	// the queue can temporarily grow beyond its size,
+	// before the overflow policy is applied.
+	grown := false
+	if len(w.queue) >= w.size() {
+		grown = w.grow(size)
+	}
+
+	if len(w.queue) >= w.size() && !grown {
+		switch w.overflowPolicy {
+		case conf.ReaderOverflowPolicyDisconnect:
+			w.overflowed = true
+			w.cond.Signal()
+			return
+
+		case conf.ReaderOverflowPolicyDropOldest:
+			w.writeErrLogger.Log(logger.Warn, "write queue is full, dropping oldest frame")
+
+		case conf.ReaderOverflowPolicyDropToKeyframe:
+			w.writeErrLogger.Log(logger.Warn, "write queue is full, dropping frames until next keyframe")
+			w.dropKeyframeTracks()
+
+		default:
+			w.writeErrLogger.Log(logger.Warn, "write queue is full")
+			return
+		}
+
+		// tracks without keyframes (i.e. audio) are dropped independently.
+		if len(w.queue) >= w.size() {
+			w.removeFirst()
+		}
+	}
+
+	if _, ok := w.waitingKeyframe[track]; ok {
+		if !randomAccess {
+			if grown {
+				w.budget.Release(size)
+			}
+			return
+		}
+		delete(w.waitingKeyframe, track)
+	}
+
+	e := entry{
+		track: track,
+		cb:    cb,
+	}
+	if grown {
+		e.grownSize = size
+	}
+
+	w.queue = append(w.queue, e)
+	w.cond.Signal()
+}
+
+// Clear discards all queued elements.
+func (w *Writer) Clear() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.releaseAll()
+	for i := range w.queue {
+		w.queue[i] = entry{}
+	}
+	w.queue = w.queue[:0]
+	w.waitingKeyframe = make(map[interface{}]struct{})
+	w.extension = 0
+}
+
+// Extend allows the queue to temporarily contain n additional elements,
+// in order to send a burst of elements (i.e. a replay) without overflowing.
+// The extension is reduced by one each time an element is pulled from the queue.
+func (w *Writer) Extend(n int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.extension += n
+}
+
+func (w *Writer) size() int {
+	return w.queueSize + w.extension
+}
+
+// grow allows the queue to temporarily exceed its size, up to maxQueueSize,
+// as long as the growth budget is not exhausted.
+func (w *Writer) grow(size uint64) bool {
+	if len(w.queue) >= w.maxQueueSize+w.extension {
+		return false
+	}
+	return w.budget.Acquire(size)
+}
+
+func (w *Writer) removeFirst() entry {
+	e := w.queue[0]
+	w.budget.Release(e.grownSize)
+	w.queue[0] = entry{}
+	w.queue = w.queue[1:]
+	return e
+}
+
+func (w *Writer) releaseAll() {
+	for _, e := range w.queue {
+		w.budget.Release(e.grownSize)
+	}
+}
+
+func (w *Writer) dropKeyframeTracks() {
+	n := 0
+	for _, e := range w.queue {
+		if _, ok := w.keyframeTracks[e.track]; ok {
+			w.waitingKeyframe[e.track] = struct{}{}
+			w.budget.Release(e.grownSize)
+			continue
+		}
+		w.queue[n] = e
+		n++
+	}
+
+	for i := n; i < len(w.queue); i++ {
+		w.queue[i] = entry{}
 	}
+	w.queue = w.queue[:n]
 }
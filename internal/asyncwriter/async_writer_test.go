@@ -0,0 +1,139 @@
+package asyncwriter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+type nilLogger struct{}
+
+func (nilLogger) Log(logger.Level, string, ...interface{}) {}
+
+func TestWriterOverflowPolicy(t *testing.T) {
+	for _, ca := range []string{
+		"dropNewest",
+		"disconnect",
+		"dropOldest",
+		"dropToKeyframe",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			var policy conf.ReaderOverflowPolicy
+			err := policy.UnmarshalJSON([]byte(`"` + ca + `"`))
+			require.NoError(t, err)
+
+			w := New(4, policy, nilLogger{})
+
+			var written []string
+			push := func(track string, randomAccess bool, id string) {
+				w.Push(track, randomAccess, 1, func() error {
+					written = append(written, id)
+					return nil
+				})
+			}
+
+			push("video", true, "v1")
+			push("audio", true, "a1")
+			push("video", false, "v2")
+			push("audio", true, "a2")
+			push("video", false, "v3") // overflow
+			push("audio", true, "a3")
+			push("video", true, "v4")
+
+			if ca == "disconnect" {
+				w.Start()
+				err := <-w.Error()
+				require.EqualError(t, err, "write queue is full")
+				require.Equal(t, []string(nil), written)
+				return
+			}
+
+			for _, e := range w.queue {
+				err := e.cb()
+				require.NoError(t, err)
+			}
+
+			switch ca {
+			case "dropNewest":
+				require.Equal(t, []string{"v1", "a1", "v2", "a2"}, written)
+
+			case "dropOldest":
+				require.Equal(t, []string{"a2", "v3", "a3", "v4"}, written)
+
+			default:
+				require.Equal(t, []string{"a1", "a2", "a3", "v4"}, written)
+			}
+		})
+	}
+}
+
+func TestWriterGrowth(t *testing.T) {
+	budget := &GrowthBudget{}
+	budget.SetLimit(2000)
+
+	pathConf := &conf.Path{
+		ReaderQueueSize:      2,
+		ReaderQueueMaxSize:   4,
+		ReaderOverflowPolicy: conf.ReaderOverflowPolicyDisconnect,
+	}
+
+	w1 := NewReader(pathConf, 512, budget, nilLogger{})
+	w2 := NewReader(pathConf, 512, budget, nilLogger{})
+
+	for i := 0; i < 4; i++ {
+		w1.Push("video", true, 1000, func() error { return nil })
+	}
+	require.Equal(t, 4, len(w1.queue))
+	require.Equal(t, false, w1.overflowed)
+	require.Equal(t, uint64(2000), budget.used)
+
+	// the queue can't grow beyond its maximum size
+	w1.Push("video", true, 0, func() error { return nil })
+	require.Equal(t, true, w1.overflowed)
+
+	// the budget is exhausted, therefore the queue can't grow
+	w2.Push("video", true, 1000, func() error { return nil })
+	w2.Push("video", true, 1000, func() error { return nil })
+	require.Equal(t, false, w2.overflowed)
+	w2.Push("video", true, 1000, func() error { return nil })
+	require.Equal(t, true, w2.overflowed)
+
+	_, err := w1.pull()
+	require.Error(t, err)
+
+	w1.Start()
+	w1.Stop()
+	require.Equal(t, uint64(0), budget.used)
+}
+
+func TestWriterGrowthRelease(t *testing.T) {
+	budget := &GrowthBudget{}
+	budget.SetLimit(1000)
+
+	pathConf := &conf.Path{
+		ReaderQueueSize:      1,
+		ReaderQueueMaxSize:   2,
+		ReaderOverflowPolicy: conf.ReaderOverflowPolicyDropNewest,
+	}
+
+	w := NewReader(pathConf, 512, budget, nilLogger{})
+
+	w.Push("video", true, 1000, func() error { return nil })
+	w.Push("video", true, 1000, func() error { return nil })
+	require.Equal(t, uint64(1000), budget.used)
+
+	// elements that don't fit into the budget are dropped
+	w.Push("video", true, 1000, func() error { return nil })
+	require.Equal(t, 2, len(w.queue))
+
+	_, err := w.pull()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), budget.used)
+
+	_, err = w.pull()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), budget.used)
+}
@@ -0,0 +1,51 @@
+package asyncwriter
+
+import (
+	"sync"
+)
+
+// GrowthBudget limits the total size, in bytes, of the elements
+// that are queued by several Writers beyond their regular queue size.
+// A nil budget is unlimited.
+type GrowthBudget struct {
+	mutex sync.Mutex
+	limit uint64
+	used  uint64
+}
+
+// SetLimit sets the maximum size of additional elements.
+// Zero means that queues can't grow.
+func (b *GrowthBudget) SetLimit(limit uint64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.limit = limit
+}
+
+// Acquire reserves n bytes. It returns false if the budget is exhausted.
+func (b *GrowthBudget) Acquire(n uint64) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if (b.used + n) > b.limit {
+		return false
+	}
+
+	b.used += n
+	return true
+}
+
+// Release returns n bytes to the budget.
+func (b *GrowthBudget) Release(n uint64) {
+	if b == nil || n == 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.used -= n
+}
@@ -0,0 +1,56 @@
+// Package auth contains authentication utilities.
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Action is an action that can be performed by a client.
+type Action string
+
+// actions.
+const (
+	ActionPublish Action = "publish"
+	ActionRead    Action = "read"
+	ActionAPI     Action = "api"
+	ActionMetrics Action = "metrics"
+	ActionPprof   Action = "pprof"
+)
+
+// ExternalRequest is the body sent to the external authentication server.
+// Path, protocol and ID are empty when the action is not related to a path.
+type ExternalRequest struct {
+	IP       string     `json:"ip"`
+	User     string     `json:"user"`
+	Password string     `json:"password"`
+	Path     string     `json:"path"`
+	Protocol string     `json:"protocol"`
+	ID       *uuid.UUID `json:"id"`
+	Action   Action     `json:"action"`
+	Query    string     `json:"query"`
+}
+
+// DoExternal performs external authentication.
+func DoExternal(ur string, req ExternalRequest) error {
+	enc, _ := json.Marshal(req)
+	res, err := http.Post(ur, "application/json", bytes.NewReader(enc))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		if resBody, err := io.ReadAll(res.Body); err == nil && len(resBody) != 0 {
+			return fmt.Errorf("server replied with code %d: %s", res.StatusCode, string(resBody))
+		}
+		return fmt.Errorf("server replied with code %d", res.StatusCode)
+	}
+
+	return nil
+}
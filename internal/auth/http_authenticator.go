@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+const (
+	pauseAfterAuthError = 2 * time.Second
+)
+
+// HTTPAuthenticator checks whether HTTP requests are allowed to perform
+// an action that is not related to a path, like accessing the API, metrics or pprof.
+// When no credentials and no external authentication URL are set, all requests are allowed.
+type HTTPAuthenticator struct {
+	ExternalAuthenticationURL string
+	User                      conf.Credential
+	Pass                      conf.Credential
+	IPs                       conf.IPsOrCIDRs
+	Action                    Action
+	Parent                    logger.Writer
+}
+
+func (a *HTTPAuthenticator) check(r *http.Request, ip net.IP) error {
+	user, pass, _ := r.BasicAuth()
+	return a.Check(ip, user, pass, r.URL.RawQuery)
+}
+
+// Check checks credentials that are not provided through a HTTP request,
+// for instance by a gRPC client.
+// It returns a defs.AuthenticationError when they are not allowed.
+func (a *HTTPAuthenticator) Check(ip net.IP, user string, pass string, query string) error {
+	if a.ExternalAuthenticationURL != "" {
+		err := DoExternal(a.ExternalAuthenticationURL, ExternalRequest{
+			IP:       ip.String(),
+			User:     user,
+			Password: pass,
+			Action:   a.Action,
+			Query:    query,
+		})
+		if err != nil {
+			return defs.AuthenticationError{Message: fmt.Sprintf("external authentication failed: %s", err)}
+		}
+	}
+
+	if a.IPs != nil && !a.IPs.Contains(ip) {
+		return defs.AuthenticationError{Message: fmt.Sprintf("IP %s not allowed", ip)}
+	}
+
+	if !a.User.IsEmpty() && (!a.User.Check(user) || !a.Pass.Check(pass)) {
+		return defs.AuthenticationError{Message: "invalid credentials"}
+	}
+
+	return nil
+}
+
+// Authenticate checks a request.
+// When the request is not allowed, the response is written and false is returned.
+func (a *HTTPAuthenticator) Authenticate(w http.ResponseWriter, r *http.Request) bool {
+	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+	err := a.check(r, net.ParseIP(host))
+	if err == nil {
+		return true
+	}
+
+	if _, _, hasCredentials := r.BasicAuth(); !hasCredentials {
+		w.Header().Set("WWW-Authenticate", `Basic realm="mediamtx"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+
+	a.Parent.Log(logger.Info, "connection %v failed to authenticate: %v",
+		r.RemoteAddr, err.(defs.AuthenticationError).Message)
+
+	// wait some seconds to mitigate brute force attacks
+	<-time.After(pauseAfterAuthError)
+
+	w.WriteHeader(http.StatusUnauthorized)
+	return false
+}
+
+// Wrap returns a handler that authenticates requests before passing them to h.
+func (a *HTTPAuthenticator) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Authenticate(w, r) {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+type nilLogger struct{}
+
+func (nilLogger) Log(logger.Level, string, ...interface{}) {}
+
+func mustCredential(t *testing.T, v string) conf.Credential {
+	var c conf.Credential
+	err := c.UnmarshalJSON([]byte(`"` + v + `"`))
+	require.NoError(t, err)
+	return c
+}
+
+func TestHTTPAuthenticatorCredentials(t *testing.T) {
+	a := &HTTPAuthenticator{
+		User:   mustCredential(t, "myuser"),
+		Pass:   mustCredential(t, "mypass"),
+		Action: ActionAPI,
+		Parent: nilLogger{},
+	}
+
+	h := a.Wrap(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, ca := range []struct {
+		name   string
+		user   string
+		pass   string
+		status int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"invalid credentials", "myuser", "wrong", http.StatusUnauthorized},
+		{"valid credentials", "myuser", "mypass", http.StatusOK},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if ca.user != "" {
+				req.SetBasicAuth(ca.user, ca.pass)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			require.Equal(t, ca.status, w.Code)
+
+			if ca.name == "no credentials" {
+				require.Equal(t, `Basic realm="mediamtx"`, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestHTTPAuthenticatorIPs(t *testing.T) {
+	var ips conf.IPsOrCIDRs
+	err := ips.UnmarshalJSON([]byte(`["10.0.0.0/8"]`))
+	require.NoError(t, err)
+
+	a := &HTTPAuthenticator{
+		IPs:    ips,
+		Action: ActionMetrics,
+		Parent: nilLogger{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	require.Equal(t, true, a.Authenticate(httptest.NewRecorder(), req))
+
+	req.RemoteAddr = "192.168.1.1:4567"
+	require.Equal(t, false, a.Authenticate(httptest.NewRecorder(), req))
+}
+
+func TestHTTPAuthenticatorExternal(t *testing.T) {
+	var received []ExternalRequest
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in ExternalRequest
+		err := json.NewDecoder(r.Body).Decode(&in)
+		require.NoError(t, err)
+		received = append(received, in)
+
+		// grant metrics only
+		if in.Action != ActionMetrics {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})}
+	go s.Serve(ln) //nolint:errcheck
+	defer s.Close()
+
+	for _, ca := range []struct {
+		action  Action
+		allowed bool
+	}{
+		{ActionAPI, false},
+		{ActionMetrics, true},
+		{ActionPprof, false},
+	} {
+		t.Run(string(ca.action), func(t *testing.T) {
+			a := &HTTPAuthenticator{
+				ExternalAuthenticationURL: "http://" + ln.Addr().String() + "/auth",
+				Action:                    ca.action,
+				Parent:                    nilLogger{},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			require.Equal(t, ca.allowed, a.Authenticate(httptest.NewRecorder(), req))
+		})
+	}
+
+	require.Equal(t, []ExternalRequest{
+		{IP: "192.0.2.1", Action: ActionAPI},
+		{IP: "192.0.2.1", Action: ActionMetrics},
+		{IP: "192.0.2.1", Action: ActionPprof},
+	}, received)
+}
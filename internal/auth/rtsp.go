@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/headers"
+)
+
+// digest algorithms supported by the RTSP server, sorted by preference.
+var rtspDigestAlgorithms = []string{"SHA-256", "MD5"}
+
+func md5Hex(in string) string {
+	h := md5.Sum([]byte(in))
+	return hex.EncodeToString(h[:])
+}
+
+func sha256Hex(in string) string {
+	h := sha256.Sum256([]byte(in))
+	return hex.EncodeToString(h[:])
+}
+
+func digestHashFunc(algorithm *string) (func(string) string, error) {
+	// when algorithm is missing, MD5 is used.
+	if algorithm == nil {
+		return md5Hex, nil
+	}
+
+	switch strings.ToUpper(*algorithm) {
+	case "MD5":
+		return md5Hex, nil
+
+	case "SHA-256":
+		return sha256Hex, nil
+	}
+
+	return nil, fmt.Errorf("unsupported digest algorithm: %s", *algorithm)
+}
+
+func containsMethod(list []headers.AuthMethod, item headers.AuthMethod) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateRTSPWWWAuthenticate generates a WWW-Authenticate header.
+// When digest is enabled, a challenge is generated for each supported algorithm,
+// starting from the strongest one.
+// The MD5 challenge doesn't contain the algorithm, in order to preserve compatibility
+// with clients that don't support it.
+func GenerateRTSPWWWAuthenticate(methods []headers.AuthMethod, realm string, nonce string) base.HeaderValue {
+	if methods == nil {
+		methods = []headers.AuthMethod{headers.AuthBasic, headers.AuthDigest}
+	}
+
+	var ret base.HeaderValue
+
+	for _, m := range methods {
+		switch m {
+		case headers.AuthBasic:
+			ret = append(ret, headers.Authenticate{
+				Method: headers.AuthBasic,
+				Realm:  &realm,
+			}.Marshal()...)
+
+		case headers.AuthDigest:
+			for _, algorithm := range rtspDigestAlgorithms {
+				h := headers.Authenticate{
+					Method: headers.AuthDigest,
+					Realm:  &realm,
+					Nonce:  &nonce,
+				}
+
+				if algorithm != "MD5" {
+					h.Algorithm = &algorithm
+				}
+
+				ret = append(ret, h.Marshal()...)
+			}
+		}
+	}
+
+	return ret
+}
+
+// ValidateRTSP validates credentials contained in a RTSP request.
+// Digest responses computed with either MD5 or SHA-256 are accepted.
+func ValidateRTSP(
+	req *base.Request,
+	user string,
+	pass string,
+	baseURL *base.URL,
+	methods []headers.AuthMethod,
+	realm string,
+	nonce string,
+) error {
+	if methods == nil {
+		methods = []headers.AuthMethod{headers.AuthBasic, headers.AuthDigest}
+	}
+
+	var auth headers.Authorization
+	err := auth.Unmarshal(req.Header["Authorization"])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case auth.Method == headers.AuthBasic && containsMethod(methods, headers.AuthBasic):
+		if auth.BasicUser != user || auth.BasicPass != pass {
+			return fmt.Errorf("authentication failed")
+		}
+
+	case auth.Method == headers.AuthDigest && containsMethod(methods, headers.AuthDigest):
+		vals := auth.DigestValues
+
+		switch {
+		case vals.Realm == nil:
+			return fmt.Errorf("realm is missing")
+
+		case vals.Nonce == nil:
+			return fmt.Errorf("nonce is missing")
+
+		case vals.Username == nil:
+			return fmt.Errorf("username is missing")
+
+		case vals.URI == nil:
+			return fmt.Errorf("uri is missing")
+
+		case vals.Response == nil:
+			return fmt.Errorf("response is missing")
+		}
+
+		if *vals.Nonce != nonce {
+			return fmt.Errorf("wrong nonce")
+		}
+
+		if *vals.Realm != realm {
+			return fmt.Errorf("wrong realm")
+		}
+
+		if *vals.Username != user {
+			return fmt.Errorf("authentication failed")
+		}
+
+		hash, err := digestHashFunc(vals.Algorithm)
+		if err != nil {
+			return err
+		}
+
+		ur := req.URL
+
+		if *vals.URI != ur.String() {
+			// in SETUP requests, VLC strips the control attribute.
+			// try again with the base URL.
+			if baseURL == nil || *vals.URI != baseURL.String() {
+				return fmt.Errorf("wrong URL")
+			}
+			ur = baseURL
+		}
+
+		response := hash(hash(user+":"+realm+":"+pass) +
+			":" + nonce + ":" + hash(string(req.Method)+":"+ur.String()))
+
+		if !strings.EqualFold(*vals.Response, response) {
+			return fmt.Errorf("authentication failed")
+		}
+
+	default:
+		return fmt.Errorf("no supported authentication methods found")
+	}
+
+	return nil
+}
+
+// RTSPSender allows a RTSP client to authenticate with the strongest
+// digest algorithm offered by the server.
+// The RTSP client computes digest responses with MD5 only; RTSPSender reads
+// challenges from responses and replaces these responses when a stronger algorithm is available.
+type RTSPSender struct {
+	mutex     sync.Mutex
+	realm     string
+	nonce     string
+	algorithm string
+}
+
+// OnResponse reads challenges contained in a response.
+// Challenges are read once, like the RTSP client does.
+func (s *RTSPSender) OnResponse(res *base.Response) {
+	if res.StatusCode != base.StatusUnauthorized {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.algorithm != "" {
+		return
+	}
+
+	best := len(rtspDigestAlgorithms)
+
+	for _, v := range res.Header["WWW-Authenticate"] {
+		if !strings.HasPrefix(v, "Digest ") {
+			continue
+		}
+
+		var h headers.Authenticate
+		err := h.Unmarshal(base.HeaderValue{v})
+		if err != nil || h.Realm == nil || h.Nonce == nil {
+			continue
+		}
+
+		algorithm := "MD5"
+		if h.Algorithm != nil {
+			algorithm = strings.ToUpper(*h.Algorithm)
+		}
+
+		for i, a := range rtspDigestAlgorithms {
+			if a == algorithm && i < best {
+				best = i
+				s.realm = *h.Realm
+				s.nonce = *h.Nonce
+				s.algorithm = algorithm
+			}
+		}
+	}
+}
+
+// OnRequest replaces the digest response of a request when
+// the server offered an algorithm different than MD5.
+func (s *RTSPSender) OnRequest(req *base.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.algorithm == "" || s.algorithm == "MD5" || req.URL == nil || req.URL.User == nil {
+		return
+	}
+
+	var auth headers.Authorization
+	err := auth.Unmarshal(req.Header["Authorization"])
+	if err != nil || auth.Method != headers.AuthDigest {
+		return
+	}
+
+	hash, err := digestHashFunc(&s.algorithm)
+	if err != nil {
+		return
+	}
+
+	user := req.URL.User.Username()
+	pass, _ := req.URL.User.Password()
+	urStr := req.URL.CloneWithoutCredentials().String()
+
+	response := hash(hash(user+":"+s.realm+":"+pass) +
+		":" + s.nonce + ":" + hash(string(req.Method)+":"+urStr))
+
+	req.Header["Authorization"] = headers.Authorization{
+		Method: headers.AuthDigest,
+		DigestValues: headers.Authenticate{
+			Method:    headers.AuthDigest,
+			Username:  &user,
+			Realm:     &s.realm,
+			Nonce:     &s.nonce,
+			URI:       &urStr,
+			Response:  &response,
+			Algorithm: &s.algorithm,
+		},
+	}.Marshal()
+}
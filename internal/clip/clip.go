@@ -0,0 +1,307 @@
+// Package clip contains utilities to export clips from the buffer of a stream.
+package clip
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
+	"github.com/bluenviron/mediacommon/pkg/codecs/opus"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+const (
+	timeScale = 90000
+)
+
+func durationGoToMp4(v time.Duration) uint64 {
+	secs := v / time.Second
+	dec := v % time.Second
+	return uint64(secs)*timeScale + uint64(dec)*timeScale/uint64(time.Second)
+}
+
+type sample struct {
+	*fmp4.PartSample
+	dts time.Duration
+}
+
+type track struct {
+	initTrack *fmp4.InitTrack
+	samples   []*sample
+
+	// H264 and H265
+	dtsExtractor interface {
+		Extract([][]byte, time.Duration) (time.Duration, error)
+	}
+}
+
+func (t *track) processH264(codec *fmp4.CodecH264, tunit *unit.H264) error {
+	if tunit.AU == nil {
+		return nil
+	}
+
+	randomAccess := false
+
+	for _, nalu := range tunit.AU {
+		switch h264.NALUType(nalu[0] & 0x1F) {
+		case h264.NALUTypeSPS:
+			codec.SPS = nalu
+
+		case h264.NALUTypePPS:
+			codec.PPS = nalu
+
+		case h264.NALUTypeIDR:
+			randomAccess = true
+		}
+	}
+
+	if t.dtsExtractor == nil {
+		if !randomAccess {
+			return nil
+		}
+		t.dtsExtractor = h264.NewDTSExtractor()
+	}
+
+	return t.addH26x(tunit.AU, tunit.PTS, randomAccess)
+}
+
+func (t *track) processH265(codec *fmp4.CodecH265, tunit *unit.H265) error {
+	if tunit.AU == nil {
+		return nil
+	}
+
+	randomAccess := false
+
+	for _, nalu := range tunit.AU {
+		switch h265.NALUType((nalu[0] >> 1) & 0b111111) {
+		case h265.NALUType_VPS_NUT:
+			codec.VPS = nalu
+
+		case h265.NALUType_SPS_NUT:
+			codec.SPS = nalu
+
+		case h265.NALUType_PPS_NUT:
+			codec.PPS = nalu
+
+		case h265.NALUType_IDR_W_RADL, h265.NALUType_IDR_N_LP, h265.NALUType_CRA_NUT:
+			randomAccess = true
+		}
+	}
+
+	if t.dtsExtractor == nil {
+		if !randomAccess {
+			return nil
+		}
+		t.dtsExtractor = h265.NewDTSExtractor()
+	}
+
+	return t.addH26x(tunit.AU, tunit.PTS, randomAccess)
+}
+
+func (t *track) addH26x(au [][]byte, pts time.Duration, randomAccess bool) error {
+	dts, err := t.dtsExtractor.Extract(au, pts)
+	if err != nil {
+		return err
+	}
+
+	sampl, err := fmp4.NewPartSampleH26x(
+		int32(durationGoToMp4(pts-dts)),
+		randomAccess,
+		au)
+	if err != nil {
+		return err
+	}
+
+	t.samples = append(t.samples, &sample{
+		PartSample: sampl,
+		dts:        dts,
+	})
+	return nil
+}
+
+func (t *track) processMPEG4Audio(sampleRate time.Duration, tunit *unit.MPEG4Audio) {
+	for i, au := range tunit.AUs {
+		t.samples = append(t.samples, &sample{
+			PartSample: &fmp4.PartSample{
+				Payload: au,
+			},
+			dts: tunit.PTS + time.Duration(i)*mpeg4audio.SamplesPerAccessUnit*time.Second/sampleRate,
+		})
+	}
+}
+
+func (t *track) processOpus(tunit *unit.Opus) {
+	var dt time.Duration
+
+	for _, packet := range tunit.Packets {
+		t.samples = append(t.samples, &sample{
+			PartSample: &fmp4.PartSample{
+				Payload: packet,
+			},
+			dts: tunit.PTS + dt,
+		})
+		dt += opus.PacketDuration(packet)
+	}
+}
+
+// fillDurations sets the duration of samples by using the DTS of the following sample.
+// The last sample has the same duration of the previous one.
+func (t *track) fillDurations(startDTS time.Duration) {
+	for i, s := range t.samples {
+		if i != len(t.samples)-1 {
+			s.Duration = uint32(durationGoToMp4(t.samples[i+1].dts-startDTS) - durationGoToMp4(s.dts-startDTS))
+		} else if i != 0 {
+			s.Duration = t.samples[i-1].Duration
+		}
+	}
+}
+
+// Marshal encodes buffered units into a fragmented MP4 file.
+// Tracks with unsupported codecs are skipped.
+// Video samples that precede the first keyframe of their track are discarded.
+func Marshal(units []stream.BufferedUnit) ([]byte, error) {
+	var tracks []*track
+	processors := make(map[format.Format]func(unit.Unit) error)
+
+	addTrack := func(codec fmp4.Codec) *track {
+		t := &track{
+			initTrack: &fmp4.InitTrack{
+				ID:        len(tracks) + 1,
+				TimeScale: timeScale,
+				Codec:     codec,
+			},
+		}
+		tracks = append(tracks, t)
+		return t
+	}
+
+	for _, bu := range units {
+		proc, ok := processors[bu.Format]
+		if !ok {
+			switch forma := bu.Format.(type) {
+			case *format.H264:
+				sps, pps := forma.SafeParams()
+				codec := &fmp4.CodecH264{SPS: sps, PPS: pps}
+				t := addTrack(codec)
+				proc = func(u unit.Unit) error {
+					return t.processH264(codec, u.(*unit.H264))
+				}
+
+			case *format.H265:
+				vps, sps, pps := forma.SafeParams()
+				codec := &fmp4.CodecH265{VPS: vps, SPS: sps, PPS: pps}
+				t := addTrack(codec)
+				proc = func(u unit.Unit) error {
+					return t.processH265(codec, u.(*unit.H265))
+				}
+
+			case *format.MPEG4Audio:
+				t := addTrack(&fmp4.CodecMPEG4Audio{Config: *forma.GetConfig()})
+				sampleRate := time.Duration(forma.ClockRate())
+				proc = func(u unit.Unit) error {
+					t.processMPEG4Audio(sampleRate, u.(*unit.MPEG4Audio))
+					return nil
+				}
+
+			case *format.Opus:
+				channelCount := 1
+				if forma.IsStereo {
+					channelCount = 2
+				}
+				t := addTrack(&fmp4.CodecOpus{ChannelCount: channelCount})
+				proc = func(u unit.Unit) error {
+					t.processOpus(u.(*unit.Opus))
+					return nil
+				}
+
+			default:
+				proc = func(unit.Unit) error {
+					return nil
+				}
+			}
+
+			processors[bu.Format] = proc
+		}
+
+		err := proc(bu.Unit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// drop tracks without samples or parameters
+	n := 0
+	for _, t := range tracks {
+		switch codec := t.initTrack.Codec.(type) {
+		case *fmp4.CodecH264:
+			if codec.SPS == nil || codec.PPS == nil {
+				continue
+			}
+
+		case *fmp4.CodecH265:
+			if codec.VPS == nil || codec.SPS == nil || codec.PPS == nil {
+				continue
+			}
+		}
+
+		if len(t.samples) != 0 {
+			t.initTrack.ID = n + 1
+			tracks[n] = t
+			n++
+		}
+	}
+	tracks = tracks[:n]
+
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("buffer does not contain any supported track")
+	}
+
+	// the clip starts with the earliest sample among all tracks.
+	startDTS := tracks[0].samples[0].dts
+	for _, t := range tracks[1:] {
+		if t.samples[0].dts < startDTS {
+			startDTS = t.samples[0].dts
+		}
+	}
+
+	initBlock := &fmp4.Init{}
+	part := &fmp4.Part{SequenceNumber: 1}
+
+	for _, t := range tracks {
+		t.fillDurations(startDTS)
+
+		initBlock.Tracks = append(initBlock.Tracks, t.initTrack)
+
+		partTrack := &fmp4.PartTrack{
+			ID:       t.initTrack.ID,
+			BaseTime: durationGoToMp4(t.samples[0].dts - startDTS),
+		}
+		for _, s := range t.samples {
+			partTrack.Samples = append(partTrack.Samples, s.PartSample)
+		}
+		part.Tracks = append(part.Tracks, partTrack)
+	}
+
+	var buf seekablebuffer.Buffer
+
+	err := initBlock.Marshal(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var partBuf seekablebuffer.Buffer
+
+	err = part.Marshal(&partBuf)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buf.Bytes(), partBuf.Bytes()...), nil
+}
@@ -0,0 +1,61 @@
+package clip
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+func TestMarshal(t *testing.T) {
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{test.FormatH264},
+	}
+
+	var units []stream.BufferedUnit
+
+	// non-IDR frame that precedes the first keyframe is discarded
+	units = append(units, stream.BufferedUnit{
+		Media:  medi,
+		Format: test.FormatH264,
+		Unit: &unit.H264{
+			Base: unit.Base{PTS: 0},
+			AU:   [][]byte{{1}},
+		},
+	})
+
+	for i := 1; i < 4; i++ {
+		units = append(units, stream.BufferedUnit{
+			Media:  medi,
+			Format: test.FormatH264,
+			Unit: &unit.H264{
+				Base: unit.Base{PTS: time.Duration(i) * time.Second},
+				AU: [][]byte{
+					test.FormatH264.SPS,
+					test.FormatH264.PPS,
+					{5},
+				},
+			},
+		})
+	}
+
+	byts, err := Marshal(units)
+	require.NoError(t, err)
+
+	var init fmp4.Init
+	err = init.Unmarshal(bytes.NewReader(byts))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(init.Tracks))
+
+	_, err = Marshal(nil)
+	require.EqualError(t, err, "buffer does not contain any supported track")
+}
@@ -3,12 +3,16 @@ package conf
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +29,10 @@ import (
 // ErrPathNotFound is returned when a path is not found.
 var ErrPathNotFound = errors.New("path not found")
 
+// minimum size of outgoing RTP packets.
+// Smaller packets would contain a few bytes of payload only.
+const minMaxPayloadSize = 128
+
 func sortedKeys(paths map[string]*OptionalPath) []string {
 	ret := make([]string, len(paths))
 	i := 0
@@ -85,72 +93,118 @@ func copyStructFields(dest interface{}, source interface{}) {
 // Conf is a configuration.
 type Conf struct {
 	// General
-	LogLevel                  LogLevel        `json:"logLevel"`
-	LogDestinations           LogDestinations `json:"logDestinations"`
-	LogFile                   string          `json:"logFile"`
-	ReadTimeout               StringDuration  `json:"readTimeout"`
-	WriteTimeout              StringDuration  `json:"writeTimeout"`
-	ReadBufferCount           *int            `json:"readBufferCount,omitempty"` // deprecated
-	WriteQueueSize            int             `json:"writeQueueSize"`
-	UDPMaxPayloadSize         int             `json:"udpMaxPayloadSize"`
-	ExternalAuthenticationURL string          `json:"externalAuthenticationURL"`
-	Metrics                   bool            `json:"metrics"`
-	MetricsAddress            string          `json:"metricsAddress"`
-	PPROF                     bool            `json:"pprof"`
-	PPROFAddress              string          `json:"pprofAddress"`
-	RunOnConnect              string          `json:"runOnConnect"`
-	RunOnConnectRestart       bool            `json:"runOnConnectRestart"`
-	RunOnDisconnect           string          `json:"runOnDisconnect"`
+	LogLevel                             LogLevel        `json:"logLevel"`
+	LogDestinations                      LogDestinations `json:"logDestinations"`
+	LogFile                              string          `json:"logFile"`
+	ReadTimeout                          StringDuration  `json:"readTimeout"`
+	WriteTimeout                         StringDuration  `json:"writeTimeout"`
+	TCPKeepalivePeriod                   StringDuration  `json:"tcpKeepalivePeriod"`
+	ReadBufferCount                      *int            `json:"readBufferCount,omitempty"` // deprecated
+	WriteQueueSize                       int             `json:"writeQueueSize"`
+	ReaderQueueMaxTotalGrowth            StringSize      `json:"readerQueueMaxTotalGrowth"`
+	UDPMaxPayloadSize                    int             `json:"udpMaxPayloadSize"`
+	DSCP                                 int             `json:"dscp"`
+	TLSMinVersion                        TLSVersion      `json:"tlsMinVersion"`
+	TLSCipherSuites                      TLSCipherSuites `json:"tlsCipherSuites"`
+	MaxTotalIngestBitrate                uint64          `json:"maxTotalIngestBitrate"`
+	MaxTotalIngestGracePeriod            StringDuration  `json:"maxTotalIngestGracePeriod"`
+	ExternalAuthenticationURL            string          `json:"externalAuthenticationURL"`
+	ExternalAuthenticationControlActions bool            `json:"externalAuthenticationControlActions"`
+	Metrics                              bool            `json:"metrics"`
+	MetricsAddress                       string          `json:"metricsAddress"`
+	MetricsPathLabels                    []string        `json:"metricsPathLabels"`
+	MetricsUser                          Credential      `json:"metricsUser"`
+	MetricsPass                          Credential      `json:"metricsPass"`
+	MetricsIPs                           IPsOrCIDRs      `json:"metricsIPs"`
+	MetricsOTLP                          bool            `json:"metricsOTLP"`
+	MetricsOTLPEndpoint                  string          `json:"metricsOTLPEndpoint"`
+	MetricsOTLPInterval                  StringDuration  `json:"metricsOTLPInterval"`
+	MetricsOTLPHeaders                   Headers         `json:"metricsOTLPHeaders"`
+	MetricsOTLPFingerprint               string          `json:"metricsOTLPFingerprint"`
+	PPROF                                bool            `json:"pprof"`
+	PPROFAddress                         string          `json:"pprofAddress"`
+	PPROFUser                            Credential      `json:"pprofUser"`
+	PPROFPass                            Credential      `json:"pprofPass"`
+	PPROFIPs                             IPsOrCIDRs      `json:"pprofIPs"`
+	RunOnConnect                         string          `json:"runOnConnect"`
+	RunOnConnectRestart                  bool            `json:"runOnConnectRestart"`
+	RunOnDisconnect                      string          `json:"runOnDisconnect"`
 
 	// API
-	API        bool   `json:"api"`
-	APIAddress string `json:"apiAddress"`
+	API        bool       `json:"api"`
+	APIAddress string     `json:"apiAddress"`
+	APIUser    Credential `json:"apiUser"`
+	APIPass    Credential `json:"apiPass"`
+	APIIPs     IPsOrCIDRs `json:"apiIPs"`
+
+	// gRPC API
+	GRPCAPI           bool   `json:"grpcAPI"`
+	GRPCAPIAddress    string `json:"grpcAPIAddress"`
+	GRPCAPIEncryption bool   `json:"grpcAPIEncryption"`
+	GRPCAPIServerKey  string `json:"grpcAPIServerKey"`
+	GRPCAPIServerCert string `json:"grpcAPIServerCert"`
 
 	// Playback
-	Playback        bool   `json:"playback"`
-	PlaybackAddress string `json:"playbackAddress"`
+	Playback                   bool           `json:"playback"`
+	PlaybackAddress            string         `json:"playbackAddress"`
+	PlaybackAutoDiscover       bool           `json:"playbackAutoDiscover"`
+	PlaybackAutoDiscoverPeriod StringDuration `json:"playbackAutoDiscoverPeriod"`
 
 	// RTSP server
-	RTSP              bool        `json:"rtsp"`
-	RTSPDisable       *bool       `json:"rtspDisable,omitempty"` // deprecated
-	Protocols         Protocols   `json:"protocols"`
-	Encryption        Encryption  `json:"encryption"`
-	RTSPAddress       string      `json:"rtspAddress"`
-	RTSPSAddress      string      `json:"rtspsAddress"`
-	RTPAddress        string      `json:"rtpAddress"`
-	RTCPAddress       string      `json:"rtcpAddress"`
-	MulticastIPRange  string      `json:"multicastIPRange"`
-	MulticastRTPPort  int         `json:"multicastRTPPort"`
-	MulticastRTCPPort int         `json:"multicastRTCPPort"`
-	ServerKey         string      `json:"serverKey"`
-	ServerCert        string      `json:"serverCert"`
-	AuthMethods       AuthMethods `json:"authMethods"`
+	RTSP                   bool               `json:"rtsp"`
+	RTSPDisable            *bool              `json:"rtspDisable,omitempty"` // deprecated
+	Protocols              Protocols          `json:"protocols"`
+	RTSPTransportsByIP     RTSPTransportsByIP `json:"rtspTransportsByIP"`
+	Encryption             Encryption         `json:"encryption"`
+	RTSPAddress            string             `json:"rtspAddress"`
+	RTSPSAddress           string             `json:"rtspsAddress"`
+	RTSPWebSocket          bool               `json:"rtspWebSocket"`
+	RTSPWebSocketAddress   string             `json:"rtspWebSocketAddress"`
+	RTPAddress             string             `json:"rtpAddress"`
+	RTCPAddress            string             `json:"rtcpAddress"`
+	MulticastIPRange       string             `json:"multicastIPRange"`
+	MulticastRTPPort       int                `json:"multicastRTPPort"`
+	MulticastRTCPPort      int                `json:"multicastRTCPPort"`
+	ServerKey              string             `json:"serverKey"`
+	ServerCert             string             `json:"serverCert"`
+	AuthMethods            AuthMethods        `json:"authMethods"`
+	RTSPSenderReportPeriod StringDuration     `json:"rtspSenderReportPeriod"`
+	RTSPConnRateLimit      int                `json:"rtspConnRateLimit"`
+	RTSPRequestRateLimit   int                `json:"rtspRequestRateLimit"`
+	RTSPRateLimitExemptIPs IPsOrCIDRs         `json:"rtspRateLimitExemptIPs"`
+	RTSPMaxSessions        int                `json:"rtspMaxSessions"`
 
 	// RTMP server
-	RTMP           bool       `json:"rtmp"`
-	RTMPDisable    *bool      `json:"rtmpDisable,omitempty"` // deprecated
-	RTMPAddress    string     `json:"rtmpAddress"`
-	RTMPEncryption Encryption `json:"rtmpEncryption"`
-	RTMPSAddress   string     `json:"rtmpsAddress"`
-	RTMPServerKey  string     `json:"rtmpServerKey"`
-	RTMPServerCert string     `json:"rtmpServerCert"`
+	RTMP                    bool           `json:"rtmp"`
+	RTMPDisable             *bool          `json:"rtmpDisable,omitempty"` // deprecated
+	RTMPAddress             string         `json:"rtmpAddress"`
+	RTMPEncryption          Encryption     `json:"rtmpEncryption"`
+	RTMPSAddress            string         `json:"rtmpsAddress"`
+	RTMPServerKey           string         `json:"rtmpServerKey"`
+	RTMPServerCert          string         `json:"rtmpServerCert"`
+	RTMPPublishPathTemplate string         `json:"rtmpPublishPathTemplate"`
+	RTMPHandshakeTimeout    StringDuration `json:"rtmpHandshakeTimeout"`
+	RTMPMaxConns            int            `json:"rtmpMaxConns"`
 
 	// HLS server
-	HLS                bool           `json:"hls"`
-	HLSDisable         *bool          `json:"hlsDisable,omitempty"` // depreacted
-	HLSAddress         string         `json:"hlsAddress"`
-	HLSEncryption      bool           `json:"hlsEncryption"`
-	HLSServerKey       string         `json:"hlsServerKey"`
-	HLSServerCert      string         `json:"hlsServerCert"`
-	HLSAlwaysRemux     bool           `json:"hlsAlwaysRemux"`
-	HLSVariant         HLSVariant     `json:"hlsVariant"`
-	HLSSegmentCount    int            `json:"hlsSegmentCount"`
-	HLSSegmentDuration StringDuration `json:"hlsSegmentDuration"`
-	HLSPartDuration    StringDuration `json:"hlsPartDuration"`
-	HLSSegmentMaxSize  StringSize     `json:"hlsSegmentMaxSize"`
-	HLSAllowOrigin     string         `json:"hlsAllowOrigin"`
-	HLSTrustedProxies  IPsOrCIDRs     `json:"hlsTrustedProxies"`
-	HLSDirectory       string         `json:"hlsDirectory"`
+	HLS                bool             `json:"hls"`
+	HLSDisable         *bool            `json:"hlsDisable,omitempty"` // depreacted
+	HLSAddress         string           `json:"hlsAddress"`
+	HLSEncryption      bool             `json:"hlsEncryption"`
+	HLSServerKey       string           `json:"hlsServerKey"`
+	HLSServerCert      string           `json:"hlsServerCert"`
+	HLSAlwaysRemux     bool             `json:"hlsAlwaysRemux"`
+	HLSVariant         HLSVariant       `json:"hlsVariant"`
+	HLSSegmentCount    int              `json:"hlsSegmentCount"`
+	HLSWindowDuration  StringDuration   `json:"hlsWindowDuration"`
+	HLSSegmentDuration StringDuration   `json:"hlsSegmentDuration"`
+	HLSPartDuration    StringDuration   `json:"hlsPartDuration"`
+	HLSSegmentMaxSize  StringSize       `json:"hlsSegmentMaxSize"`
+	HLSBurstOnConnect  int              `json:"hlsBurstOnConnect"`
+	HLSTimestampWrap   HLSTimestampWrap `json:"hlsTimestampWrap"`
+	HLSAllowOrigin     string           `json:"hlsAllowOrigin"`
+	HLSTrustedProxies  IPsOrCIDRs       `json:"hlsTrustedProxies"`
+	HLSDirectory       string           `json:"hlsDirectory"`
 
 	// WebRTC server
 	WebRTC                      bool              `json:"webrtc"`
@@ -162,19 +216,30 @@ type Conf struct {
 	WebRTCAllowOrigin           string            `json:"webrtcAllowOrigin"`
 	WebRTCTrustedProxies        IPsOrCIDRs        `json:"webrtcTrustedProxies"`
 	WebRTCLocalUDPAddress       string            `json:"webrtcLocalUDPAddress"`
+	WebRTCLocalUDPPortRange     PortRange         `json:"webrtcLocalUDPPortRange"`
 	WebRTCLocalTCPAddress       string            `json:"webrtcLocalTCPAddress"`
 	WebRTCIPsFromInterfaces     bool              `json:"webrtcIPsFromInterfaces"`
 	WebRTCIPsFromInterfacesList []string          `json:"webrtcIPsFromInterfacesList"`
 	WebRTCAdditionalHosts       []string          `json:"webrtcAdditionalHosts"`
+	WebRTCICEInterfaceFilter    []string          `json:"webrtcICEInterfaceFilter"`
+	WebRTCICEUDPMuxOnly         bool              `json:"webrtcICEUDPMuxOnly"`
+	WebRTCICEDisableMDNS        bool              `json:"webrtcICEDisableMDNS"`
+	WebRTCICEDisconnectTimeout  StringDuration    `json:"webrtcICEDisconnectTimeout"`
+	WebRTCICERecoveryTimeout    StringDuration    `json:"webrtcICERecoveryTimeout"`
 	WebRTCICEServers2           []WebRTCICEServer `json:"webrtcICEServers2"`
+	WebRTCNACKBufferSize        int               `json:"webrtcNACKBufferSize"`
+	WebRTCNACKMaxAge            StringDuration    `json:"webrtcNACKMaxAge"`
+	WebRTCMaxPayloadSize        int               `json:"webrtcMaxPayloadSize"`
 	WebRTCICEUDPMuxAddress      *string           `json:"webrtcICEUDPMuxAddress,omitempty"`  // deprecated
 	WebRTCICETCPMuxAddress      *string           `json:"webrtcICETCPMuxAddress,omitempty"`  // deprecated
 	WebRTCICEHostNAT1To1IPs     *[]string         `json:"webrtcICEHostNAT1To1IPs,omitempty"` // deprecated
 	WebRTCICEServers            *[]string         `json:"webrtcICEServers,omitempty"`        // deprecated
 
 	// SRT server
-	SRT        bool   `json:"srt"`
-	SRTAddress string `json:"srtAddress"`
+	SRT                 bool           `json:"srt"`
+	SRTAddress          string         `json:"srtAddress"`
+	SRTHandshakeTimeout StringDuration `json:"srtHandshakeTimeout"`
+	SRTMaxConns         int            `json:"srtMaxConns"`
 
 	// Record (deprecated)
 	Record                *bool           `json:"record,omitempty"`                // deprecated
@@ -199,16 +264,28 @@ func (conf *Conf) setDefaults() {
 	conf.LogFile = "mediamtx.log"
 	conf.ReadTimeout = 10 * StringDuration(time.Second)
 	conf.WriteTimeout = 10 * StringDuration(time.Second)
+	conf.TCPKeepalivePeriod = 15 * StringDuration(time.Second)
 	conf.WriteQueueSize = 512
+	conf.ReaderQueueMaxTotalGrowth = 64 * 1024 * 1024
 	conf.UDPMaxPayloadSize = 1472
+	conf.TLSMinVersion = tls.VersionTLS12
 	conf.MetricsAddress = "127.0.0.1:9998"
+	conf.MetricsPathLabels = []string{}
+	conf.MetricsOTLPEndpoint = "http://localhost:4318/v1/metrics"
+	conf.MetricsOTLPInterval = 10 * StringDuration(time.Second)
 	conf.PPROFAddress = "127.0.0.1:9999"
 
 	// API
 	conf.APIAddress = "127.0.0.1:9997"
 
+	// gRPC API
+	conf.GRPCAPIAddress = "127.0.0.1:9995"
+	conf.GRPCAPIServerKey = "server.key"
+	conf.GRPCAPIServerCert = "server.crt"
+
 	// Playback server
 	conf.PlaybackAddress = ":9996"
+	conf.PlaybackAutoDiscoverPeriod = 10 * StringDuration(time.Second)
 
 	// RTSP server
 	conf.RTSP = true
@@ -219,6 +296,7 @@ func (conf *Conf) setDefaults() {
 	}
 	conf.RTSPAddress = ":8554"
 	conf.RTSPSAddress = ":8322"
+	conf.RTSPWebSocketAddress = ":8555"
 	conf.RTPAddress = ":8000"
 	conf.RTCPAddress = ":8001"
 	conf.MulticastIPRange = "224.1.0.0/16"
@@ -227,6 +305,7 @@ func (conf *Conf) setDefaults() {
 	conf.ServerKey = "server.key"
 	conf.ServerCert = "server.crt"
 	conf.AuthMethods = AuthMethods{headers.AuthBasic}
+	conf.RTSPSenderReportPeriod = 10 * StringDuration(time.Second)
 
 	// RTMP server
 	conf.RTMP = true
@@ -234,6 +313,7 @@ func (conf *Conf) setDefaults() {
 	conf.RTMPSAddress = ":1936"
 	conf.RTMPServerKey = "server.key"
 	conf.RTMPServerCert = "server.crt"
+	conf.RTMPHandshakeTimeout = 10 * StringDuration(time.Second)
 
 	// HLS
 	conf.HLS = true
@@ -257,11 +337,17 @@ func (conf *Conf) setDefaults() {
 	conf.WebRTCIPsFromInterfaces = true
 	conf.WebRTCIPsFromInterfacesList = []string{}
 	conf.WebRTCAdditionalHosts = []string{}
+	conf.WebRTCICEInterfaceFilter = []string{}
 	conf.WebRTCICEServers2 = []WebRTCICEServer{}
+	conf.WebRTCNACKBufferSize = 1024
+	conf.WebRTCNACKMaxAge = 1 * StringDuration(time.Second)
+	conf.WebRTCMaxPayloadSize = 1200
+	conf.WebRTCICEDisconnectTimeout = 5 * StringDuration(time.Second)
 
 	// SRT server
 	conf.SRT = true
 	conf.SRTAddress = ":8890"
+	conf.SRTHandshakeTimeout = 10 * StringDuration(time.Second)
 
 	conf.PathDefaults.setDefaults()
 }
@@ -348,6 +434,46 @@ func (conf Conf) Clone() *Conf {
 	return &dest
 }
 
+func addressPort(address string) int {
+	_, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return 0
+	}
+	return port
+}
+
+// udpListenerInRange returns the name of the first enabled UDP listener whose port is in the given range.
+func (conf *Conf) udpListenerInRange(r PortRange) string {
+	if conf.RTSP {
+		if _, ok := conf.Protocols[Protocol(gortsplib.TransportUDP)]; ok {
+			if r.Contains(addressPort(conf.RTPAddress)) {
+				return "rtpAddress"
+			}
+			if r.Contains(addressPort(conf.RTCPAddress)) {
+				return "rtcpAddress"
+			}
+		}
+		if _, ok := conf.Protocols[Protocol(gortsplib.TransportUDPMulticast)]; ok {
+			if r.Contains(conf.MulticastRTPPort) {
+				return "multicastRTPPort"
+			}
+			if r.Contains(conf.MulticastRTCPPort) {
+				return "multicastRTCPPort"
+			}
+		}
+	}
+
+	if conf.SRT && r.Contains(addressPort(conf.SRTAddress)) {
+		return "srtAddress"
+	}
+
+	return ""
+}
+
 // Validate checks the configuration for errors.
 func (conf *Conf) Validate() error {
 	// General
@@ -358,9 +484,18 @@ func (conf *Conf) Validate() error {
 	if (conf.WriteQueueSize & (conf.WriteQueueSize - 1)) != 0 {
 		return fmt.Errorf("'writeQueueSize' must be a power of two")
 	}
+	if conf.TCPKeepalivePeriod < 0 {
+		return fmt.Errorf("'tcpKeepalivePeriod' can't be negative")
+	}
 	if conf.UDPMaxPayloadSize > 1472 {
 		return fmt.Errorf("'udpMaxPayloadSize' must be less than 1472")
 	}
+	if conf.UDPMaxPayloadSize < minMaxPayloadSize {
+		return fmt.Errorf("'udpMaxPayloadSize' must be at least %d", minMaxPayloadSize)
+	}
+	if conf.DSCP < 0 || conf.DSCP > 63 {
+		return fmt.Errorf("'dscp' must be between 0 and 63")
+	}
 	if conf.ExternalAuthenticationURL != "" {
 		if !strings.HasPrefix(conf.ExternalAuthenticationURL, "http://") &&
 			!strings.HasPrefix(conf.ExternalAuthenticationURL, "https://") {
@@ -370,6 +505,38 @@ func (conf *Conf) Validate() error {
 		if contains(conf.AuthMethods, headers.AuthDigest) {
 			return fmt.Errorf("'externalAuthenticationURL' can't be used when 'digest' is in authMethods")
 		}
+
+		if !conf.APIUser.IsEmpty() || len(conf.APIIPs) > 0 ||
+			!conf.MetricsUser.IsEmpty() || len(conf.MetricsIPs) > 0 ||
+			!conf.PPROFUser.IsEmpty() || len(conf.PPROFIPs) > 0 {
+			return fmt.Errorf("credentials or IPs of the API, metrics and pprof servers" +
+				" can't be used together with 'externalAuthenticationURL'")
+		}
+	}
+	for _, label := range conf.MetricsPathLabels {
+		if label == "name" || label == "state" {
+			return fmt.Errorf("'%s' is a reserved label name and can't be used in 'metricsPathLabels'", label)
+		}
+		if err := checkLabelName(label); err != nil {
+			return err
+		}
+	}
+	if conf.MetricsOTLP {
+		if !strings.HasPrefix(conf.MetricsOTLPEndpoint, "http://") &&
+			!strings.HasPrefix(conf.MetricsOTLPEndpoint, "https://") {
+			return fmt.Errorf("'metricsOTLPEndpoint' must be a HTTP URL")
+		}
+		if conf.MetricsOTLPInterval <= 0 {
+			return fmt.Errorf("'metricsOTLPInterval' must be greater than zero")
+		}
+	}
+
+	// Playback
+
+	if conf.PlaybackAutoDiscover {
+		if conf.PlaybackAutoDiscoverPeriod <= 0 {
+			return fmt.Errorf("'playbackAutoDiscoverPeriod' must be greater than zero")
+		}
 	}
 
 	// RTSP
@@ -377,6 +544,25 @@ func (conf *Conf) Validate() error {
 	if conf.RTSPDisable != nil {
 		conf.RTSP = !*conf.RTSPDisable
 	}
+	if conf.RTSPSenderReportPeriod <= 0 {
+		return fmt.Errorf("'rtspSenderReportPeriod' must be greater than zero")
+	}
+	if conf.RTSPConnRateLimit < 0 {
+		return fmt.Errorf("'rtspConnRateLimit' can't be negative")
+	}
+	if conf.RTSPRequestRateLimit < 0 {
+		return fmt.Errorf("'rtspRequestRateLimit' can't be negative")
+	}
+	if conf.RTSPMaxSessions < 0 {
+		return fmt.Errorf("'rtspMaxSessions' can't be negative")
+	}
+	for _, e := range conf.RTSPTransportsByIP {
+		for p := range e.Transports {
+			if _, ok := conf.Protocols[p]; !ok {
+				return fmt.Errorf("'rtspTransportsByIP' contains transports that are not enabled in 'protocols'")
+			}
+		}
+	}
 	if conf.Encryption == EncryptionStrict {
 		if _, ok := conf.Protocols[Protocol(gortsplib.TransportUDP)]; ok {
 			return fmt.Errorf("strict encryption can't be used with the UDP transport protocol")
@@ -391,12 +577,28 @@ func (conf *Conf) Validate() error {
 	if conf.RTMPDisable != nil {
 		conf.RTMP = !*conf.RTMPDisable
 	}
+	if conf.RTMPPublishPathTemplate != "" &&
+		!strings.Contains(conf.RTMPPublishPathTemplate, "{user}") {
+		return fmt.Errorf("'rtmpPublishPathTemplate' must contain '{user}'")
+	}
+	if conf.RTMPHandshakeTimeout < 0 {
+		return fmt.Errorf("'rtmpHandshakeTimeout' can't be negative")
+	}
+	if conf.RTMPMaxConns < 0 {
+		return fmt.Errorf("'rtmpMaxConns' can't be negative")
+	}
 
 	// HLS
 
 	if conf.HLSDisable != nil {
 		conf.HLS = !*conf.HLSDisable
 	}
+	if conf.HLSWindowDuration < 0 {
+		return fmt.Errorf("'hlsWindowDuration' can't be negative")
+	}
+	if conf.HLSBurstOnConnect < 0 || conf.HLSBurstOnConnect == 1 {
+		return fmt.Errorf("'hlsBurstOnConnect' must be 0 or at least 2")
+	}
 
 	// WebRTC
 
@@ -434,18 +636,79 @@ func (conf *Conf) Validate() error {
 			!strings.HasPrefix(server.URL, "turns:") {
 			return fmt.Errorf("invalid ICE server: '%s'", server.URL)
 		}
+		if !strings.HasPrefix(server.URL, "stun:") &&
+			(server.Username == "" || server.Password == "") {
+			return fmt.Errorf("TURN server '%s' requires a username and a password", server.URL)
+		}
 	}
 	if conf.WebRTCLocalUDPAddress == "" &&
+		conf.WebRTCLocalUDPPortRange.IsEmpty() &&
 		conf.WebRTCLocalTCPAddress == "" &&
 		len(conf.WebRTCICEServers2) == 0 {
-		return fmt.Errorf("at least one between 'webrtcLocalUDPAddress'," +
+		return fmt.Errorf("at least one between 'webrtcLocalUDPAddress', 'webrtcLocalUDPPortRange'," +
 			" 'webrtcLocalTCPAddress' or 'webrtcICEServers2' must be filled")
 	}
-	if conf.WebRTCLocalUDPAddress != "" || conf.WebRTCLocalTCPAddress != "" {
+	if !conf.WebRTCLocalUDPPortRange.IsEmpty() {
+		if conf.WebRTCLocalUDPAddress != "" {
+			return fmt.Errorf("'webrtcLocalUDPPortRange' can't be used together with 'webrtcLocalUDPAddress'")
+		}
+		if conf.WebRTC {
+			if name := conf.udpListenerInRange(conf.WebRTCLocalUDPPortRange); name != "" {
+				return fmt.Errorf("'webrtcLocalUDPPortRange' overlaps with '%s'", name)
+			}
+		}
+	}
+	if conf.WebRTCLocalUDPAddress != "" || !conf.WebRTCLocalUDPPortRange.IsEmpty() ||
+		conf.WebRTCLocalTCPAddress != "" {
 		if !conf.WebRTCIPsFromInterfaces && len(conf.WebRTCAdditionalHosts) == 0 {
 			return fmt.Errorf("at least one between 'webrtcIPsFromInterfaces' or 'webrtcAdditionalHosts' must be filled")
 		}
 	}
+	for _, host := range conf.WebRTCAdditionalHosts {
+		// IPv6 literals can be enclosed in brackets.
+		if strings.ContainsAny(host, ":[]") {
+			if net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")) == nil {
+				return fmt.Errorf("invalid 'webrtcAdditionalHosts' entry '%s'", host)
+			}
+		}
+	}
+	for _, entry := range conf.WebRTCICEInterfaceFilter {
+		if _, err := path.Match(strings.TrimPrefix(entry, "!"), ""); err != nil {
+			return fmt.Errorf("invalid 'webrtcICEInterfaceFilter' entry '%s': %w", entry, err)
+		}
+	}
+	if conf.WebRTCICEUDPMuxOnly {
+		if conf.WebRTCLocalUDPAddress == "" || len(conf.WebRTCAdditionalHosts) == 0 {
+			return fmt.Errorf("'webrtcICEUDPMuxOnly' requires 'webrtcLocalUDPAddress' and 'webrtcAdditionalHosts' to be filled")
+		}
+	}
+	if conf.WebRTCNACKBufferSize < 0 {
+		return fmt.Errorf("'webrtcNACKBufferSize' can't be negative")
+	}
+	if conf.WebRTCNACKMaxAge <= 0 {
+		return fmt.Errorf("'webrtcNACKMaxAge' must be greater than zero")
+	}
+	if conf.WebRTCICEDisconnectTimeout <= 0 {
+		return fmt.Errorf("'webrtcICEDisconnectTimeout' must be greater than zero")
+	}
+	if conf.WebRTCICERecoveryTimeout < 0 {
+		return fmt.Errorf("'webrtcICERecoveryTimeout' can't be negative")
+	}
+	if conf.WebRTCMaxPayloadSize > 1472 {
+		return fmt.Errorf("'webrtcMaxPayloadSize' must be less than 1472")
+	}
+	if conf.WebRTCMaxPayloadSize < minMaxPayloadSize {
+		return fmt.Errorf("'webrtcMaxPayloadSize' must be at least %d", minMaxPayloadSize)
+	}
+
+	// SRT
+
+	if conf.SRTHandshakeTimeout < 0 {
+		return fmt.Errorf("'srtHandshakeTimeout' can't be negative")
+	}
+	if conf.SRTMaxConns < 0 {
+		return fmt.Errorf("'srtMaxConns' can't be negative")
+	}
 
 	// Record (deprecated)
 	if conf.Record != nil {
@@ -467,6 +730,10 @@ func (conf *Conf) Validate() error {
 		conf.PathDefaults.RecordDeleteAfter = *conf.RecordDeleteAfter
 	}
 
+	if conf.PlaybackAutoDiscover && !strings.Contains(conf.PathDefaults.RecordPath, "%path") {
+		return fmt.Errorf("'playbackAutoDiscover' requires 'recordPath' of path defaults to contain %%path")
+	}
+
 	hasAllOthers := false
 	for name := range conf.OptionalPaths {
 		if name == "all" || name == "all_others" || name == "~^.*$" {
@@ -496,6 +763,63 @@ func (conf *Conf) Validate() error {
 		}
 	}
 
+	err := checkPathDependencies(conf.Paths)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MaxReaderQueueSize returns the maximum size that the write queue of a reader can reach.
+func (conf *Conf) MaxReaderQueueSize() int {
+	ret := conf.WriteQueueSize
+
+	for _, pconf := range conf.Paths {
+		if pconf.ReaderQueueSize > ret {
+			ret = pconf.ReaderQueueSize
+		}
+		if pconf.ReaderQueueMaxSize > ret {
+			ret = pconf.ReaderQueueMaxSize
+		}
+	}
+
+	return ret
+}
+
+// CheckFiles checks that files referenced by enabled features exist and are readable.
+func (conf *Conf) CheckFiles() error {
+	type file struct {
+		param string
+		fpath string
+	}
+
+	var files []file
+
+	if conf.RTSP && conf.Encryption != EncryptionNo {
+		files = append(files, file{"serverKey", conf.ServerKey}, file{"serverCert", conf.ServerCert})
+	}
+	if conf.RTMP && conf.RTMPEncryption != EncryptionNo {
+		files = append(files, file{"rtmpServerKey", conf.RTMPServerKey}, file{"rtmpServerCert", conf.RTMPServerCert})
+	}
+	if conf.HLS && conf.HLSEncryption {
+		files = append(files, file{"hlsServerKey", conf.HLSServerKey}, file{"hlsServerCert", conf.HLSServerCert})
+	}
+	if conf.WebRTC && conf.WebRTCEncryption {
+		files = append(files, file{"webrtcServerKey", conf.WebRTCServerKey}, file{"webrtcServerCert", conf.WebRTCServerCert})
+	}
+	if conf.GRPCAPI && conf.GRPCAPIEncryption {
+		files = append(files, file{"grpcAPIServerKey", conf.GRPCAPIServerKey}, file{"grpcAPIServerCert", conf.GRPCAPIServerCert})
+	}
+
+	for _, f := range files {
+		fi, err := os.Open(f.fpath)
+		if err != nil {
+			return fmt.Errorf("'%s' is not readable: %w", f.param, err)
+		}
+		fi.Close()
+	}
+
 	return nil
 }
 
@@ -549,6 +873,14 @@ func (conf *Conf) PatchPath(name string, optional2 *OptionalPath) error {
 		return ErrPathNotFound
 	}
 
+	// paths without settings are stored as nil
+	if optional == nil {
+		optional = &OptionalPath{
+			Values: newOptionalPathValues(),
+		}
+		conf.OptionalPaths[name] = optional
+	}
+
 	copyStructFields(optional.Values, optional2.Values)
 	return nil
 }
@@ -564,6 +896,26 @@ func (conf *Conf) ReplacePath(name string, optional2 *OptionalPath) error {
 	return nil
 }
 
+// ControlExternalAuthenticationURL returns the external authentication URL
+// used by the API, metrics and pprof servers.
+func (conf *Conf) ControlExternalAuthenticationURL() string {
+	if !conf.ExternalAuthenticationControlActions {
+		return ""
+	}
+	return conf.ExternalAuthenticationURL
+}
+
+// ImportPaths adds paths. Paths that already exist are replaced.
+func (conf *Conf) ImportPaths(paths map[string]*OptionalPath) {
+	if conf.OptionalPaths == nil {
+		conf.OptionalPaths = make(map[string]*OptionalPath)
+	}
+
+	for name, p := range paths {
+		conf.OptionalPaths[name] = p
+	}
+}
+
 // RemovePath removes a path.
 func (conf *Conf) RemovePath(name string) error {
 	if _, ok := conf.OptionalPaths[name]; !ok {
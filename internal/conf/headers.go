@@ -0,0 +1,64 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
+// Headers is a parameter that contains additional headers.
+type Headers map[string]string
+
+// MarshalJSON implements json.Marshaler.
+func (d Headers) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string(d))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Headers) UnmarshalJSON(b []byte) error {
+	var in map[string]string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*d = nil
+
+	if len(in) == 0 {
+		return nil
+	}
+
+	*d = make(Headers)
+
+	for k, v := range in {
+		if k == "" || strings.ContainsAny(k, " \t\r\n:") {
+			return fmt.Errorf("invalid header name '%s'", k)
+		}
+
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("invalid value of header '%s'", k)
+		}
+
+		(*d)[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *Headers) UnmarshalEnv(_ string, v string) error {
+	in := make(map[string]string)
+
+	if v != "" {
+		for _, entry := range strings.Split(v, ",") {
+			parts := strings.SplitN(entry, ":", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid entry '%s'", entry)
+			}
+			in[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	byts, _ := json.Marshal(in)
+	return d.UnmarshalJSON(byts)
+}
@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// HLSTimestampWrap is the hlsTimestampWrap parameter.
+type HLSTimestampWrap int
+
+// supported values.
+const (
+	HLSTimestampWrapDiscontinuity HLSTimestampWrap = iota
+	HLSTimestampWrapNone
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d HLSTimestampWrap) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case HLSTimestampWrapNone:
+		out = "none"
+
+	default:
+		out = "discontinuity"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *HLSTimestampWrap) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "none":
+		*d = HLSTimestampWrapNone
+
+	case "discontinuity":
+		*d = HLSTimestampWrapDiscontinuity
+
+	default:
+		return fmt.Errorf("invalid HLS timestamp wrap '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *HLSTimestampWrap) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
@@ -64,3 +64,21 @@ func (d *IPsOrCIDRs) ToTrustedProxies() []string {
 	}
 	return ret
 }
+
+// Contains checks whether an IP is equal to one of the IPs or is contained in one of the CIDRs.
+func (d IPsOrCIDRs) Contains(ip net.IP) bool {
+	for _, item := range d {
+		switch titem := item.(type) {
+		case net.IP:
+			if titem.Equal(ip) {
+				return true
+			}
+
+		case *net.IPNet:
+			if titem.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
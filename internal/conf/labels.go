@@ -0,0 +1,70 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var reLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func checkLabelName(name string) error {
+	if !reLabelName.MatchString(name) || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("invalid label name '%s': it must contain only letters, digits and underscores,"+
+			" must not start with a digit and must not start with two underscores", name)
+	}
+	return nil
+}
+
+// Labels is the labels parameter.
+// It contains arbitrary metadata of a path.
+type Labels map[string]string
+
+// MarshalJSON implements json.Marshaler.
+func (d Labels) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string(d))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Labels) UnmarshalJSON(b []byte) error {
+	var in map[string]string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*d = nil
+
+	if len(in) == 0 {
+		return nil
+	}
+
+	*d = make(Labels)
+
+	for k, v := range in {
+		if err := checkLabelName(k); err != nil {
+			return err
+		}
+		(*d)[k] = v
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *Labels) UnmarshalEnv(_ string, v string) error {
+	in := make(map[string]string)
+
+	if v != "" {
+		for _, entry := range strings.Split(v, ",") {
+			parts := strings.SplitN(entry, ":", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid entry '%s'", entry)
+			}
+			in[parts[0]] = parts[1]
+		}
+	}
+
+	byts, _ := json.Marshal(in)
+	return d.UnmarshalJSON(byts)
+}
@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OnNewPublisher is the onNewPublisher parameter.
+type OnNewPublisher int
+
+// supported values.
+const (
+	OnNewPublisherTakeover OnNewPublisher = iota
+	OnNewPublisherReject
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d OnNewPublisher) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case OnNewPublisherReject:
+		out = "reject"
+
+	default:
+		out = "takeover"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *OnNewPublisher) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "reject":
+		*d = OnNewPublisherReject
+
+	case "takeover":
+		*d = OnNewPublisherTakeover
+
+	default:
+		return fmt.Errorf("invalid onNewPublisher value '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *OnNewPublisher) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
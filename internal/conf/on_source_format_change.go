@@ -0,0 +1,63 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OnSourceFormatChange is the onSourceFormatChange parameter.
+type OnSourceFormatChange int
+
+// supported values.
+const (
+	OnSourceFormatChangeIgnore OnSourceFormatChange = iota
+	OnSourceFormatChangeRenegotiate
+	OnSourceFormatChangeReconnectReaders
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d OnSourceFormatChange) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case OnSourceFormatChangeRenegotiate:
+		out = "renegotiate"
+
+	case OnSourceFormatChangeReconnectReaders:
+		out = "reconnectReaders"
+
+	default:
+		out = "ignore"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *OnSourceFormatChange) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "ignore":
+		*d = OnSourceFormatChangeIgnore
+
+	case "renegotiate":
+		*d = OnSourceFormatChangeRenegotiate
+
+	case "reconnectReaders":
+		*d = OnSourceFormatChangeReconnectReaders
+
+	default:
+		return fmt.Errorf("invalid onSourceFormatChange value '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *OnSourceFormatChange) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
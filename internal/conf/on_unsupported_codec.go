@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OnUnsupportedCodec is the onUnsupportedCodec parameter.
+type OnUnsupportedCodec int
+
+// supported values.
+const (
+	OnUnsupportedCodecSkipTrack OnUnsupportedCodec = iota
+	OnUnsupportedCodecRejectSource
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d OnUnsupportedCodec) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case OnUnsupportedCodecRejectSource:
+		out = "rejectSource"
+
+	default:
+		out = "skipTrack"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *OnUnsupportedCodec) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "skipTrack":
+		*d = OnUnsupportedCodecSkipTrack
+
+	case "rejectSource":
+		*d = OnUnsupportedCodecRejectSource
+
+	default:
+		return fmt.Errorf("invalid onUnsupportedCodec value '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *OnUnsupportedCodec) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
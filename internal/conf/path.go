@@ -7,15 +7,20 @@ import (
 	gourl "net/url"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/bluenviron/gohlslib"
+	"github.com/bluenviron/gortsplib/v4"
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/headers"
 )
 
 var rePathName = regexp.MustCompile(`^[0-9a-zA-Z_\-/\.~]+$`)
 
+var reLanguageTag = regexp.MustCompile(`^[a-zA-Z]{2,8}(-[a-zA-Z0-9]{1,8})*$`)
+
 func isValidPathName(name string) error {
 	if name == "" {
 		return fmt.Errorf("cannot be empty")
@@ -87,23 +92,56 @@ type Path struct {
 	Name   string         `json:"name"` // filled by Check()
 
 	// General
-	Source                     string         `json:"source"`
-	SourceFingerprint          string         `json:"sourceFingerprint"`
-	SourceOnDemand             bool           `json:"sourceOnDemand"`
-	SourceOnDemandStartTimeout StringDuration `json:"sourceOnDemandStartTimeout"`
-	SourceOnDemandCloseAfter   StringDuration `json:"sourceOnDemandCloseAfter"`
-	MaxReaders                 int            `json:"maxReaders"`
-	SRTReadPassphrase          string         `json:"srtReadPassphrase"`
-	Fallback                   string         `json:"fallback"`
+	Enabled                    bool                 `json:"enabled"`
+	Source                     string               `json:"source"`
+	SourceFingerprint          string               `json:"sourceFingerprint"`
+	SourceUserAgent            string               `json:"sourceUserAgent"`
+	SourceExtraHeaders         Headers              `json:"sourceExtraHeaders"`
+	SourceOnDemand             bool                 `json:"sourceOnDemand"`
+	SourceOnDemandStartTimeout StringDuration       `json:"sourceOnDemandStartTimeout"`
+	SourceOnDemandCloseAfter   StringDuration       `json:"sourceOnDemandCloseAfter"`
+	SourceReadFailureGrace     StringDuration       `json:"sourceReadFailureGrace"`
+	SourceConnectTimeout       StringDuration       `json:"sourceConnectTimeout"`
+	MaxReaders                 int                  `json:"maxReaders"`
+	MaxPublishBitrate          int                  `json:"maxPublishBitrate"`
+	MaxPublishBitrateWindow    StringDuration       `json:"maxPublishBitrateWindow"`
+	UseAbsoluteTimestamp       bool                 `json:"useAbsoluteTimestamp"`
+	RTSPTransports             Protocols            `json:"rtspTransports"`
+	RTSPMaxSessions            int                  `json:"rtspMaxSessions"`
+	ReaderOverflowPolicy       ReaderOverflowPolicy `json:"readerOverflowPolicy"`
+	ReaderQueueSize            int                  `json:"readerQueueSize"`
+	ReaderQueueMaxSize         int                  `json:"readerQueueMaxSize"`
+	SRTReadPassphrase          string               `json:"srtReadPassphrase"`
+	Fallback                   string               `json:"fallback"`
+	PathIdleTimeout            StringDuration       `json:"pathIdleTimeout"`
+	LiveBufferDuration         StringDuration       `json:"liveBufferDuration"`
+	ReaderInstantStart         bool                 `json:"readerInstantStart"`
+	OnSourceFormatChange       OnSourceFormatChange `json:"onSourceFormatChange"`
+	OnUnsupportedCodec         OnUnsupportedCodec   `json:"onUnsupportedCodec"`
+	SynthesizeVideo            bool                 `json:"synthesizeVideo"`
+	RepeatParameterSets        bool                 `json:"repeatParameterSets"`
+	Labels                     Labels               `json:"labels"`
 
 	// Record and playback
-	Record                bool           `json:"record"`
-	Playback              bool           `json:"playback"`
-	RecordPath            string         `json:"recordPath"`
-	RecordFormat          RecordFormat   `json:"recordFormat"`
-	RecordPartDuration    StringDuration `json:"recordPartDuration"`
-	RecordSegmentDuration StringDuration `json:"recordSegmentDuration"`
-	RecordDeleteAfter     StringDuration `json:"recordDeleteAfter"`
+	Record                 bool            `json:"record"`
+	Playback               bool            `json:"playback"`
+	RecordPath             string          `json:"recordPath"`
+	RecordFormat           RecordFormat    `json:"recordFormat"`
+	RecordPartDuration     StringDuration  `json:"recordPartDuration"`
+	RecordSegmentDuration  StringDuration  `json:"recordSegmentDuration"`
+	RecordDeleteAfter      StringDuration  `json:"recordDeleteAfter"`
+	RecordMaxSize          StringSize      `json:"recordMaxSize"`
+	RecordVideoMode        RecordVideoMode `json:"recordVideoMode"`
+	RecordVideoFramerate   float64         `json:"recordVideoFramerate"`
+	RecordAudio            bool            `json:"recordAudio"`
+	RecordPrft             bool            `json:"recordPrft"`
+	RecordInterleaveWindow StringDuration  `json:"recordInterleaveWindow"`
+	RecordDTSMode          RecordDTSMode   `json:"recordDTSMode"`
+	FMP4FragmentDuration   StringDuration  `json:"fmp4FragmentDuration"`
+	RecordOutputs          RecordOutputs   `json:"recordOutputs"`
+
+	// Push
+	PushDestinations []string `json:"pushDestinations"`
 
 	// Authentication
 	PublishUser Credential `json:"publishUser"`
@@ -113,18 +151,31 @@ type Path struct {
 	ReadPass    Credential `json:"readPass"`
 	ReadIPs     IPsOrCIDRs `json:"readIPs"`
 
+	// HLS
+	HLSSignedURLSecret string   `json:"hlsSignedURLSecret"`
+	HLSSegmentBaseURL  string   `json:"hlsSegmentBaseURL"`
+	HLSAudioLanguages  []string `json:"hlsAudioLanguages"`
+
 	// Publisher source
-	OverridePublisher        bool   `json:"overridePublisher"`
-	DisablePublisherOverride *bool  `json:"disablePublisherOverride,omitempty"` // deprecated
-	SRTPublishPassphrase     string `json:"srtPublishPassphrase"`
+	OnNewPublisher           OnNewPublisher `json:"onNewPublisher"`
+	OverridePublisher        bool           `json:"overridePublisher"`
+	DisablePublisherOverride *bool          `json:"disablePublisherOverride,omitempty"` // deprecated
+	SRTPublishPassphrase     string         `json:"srtPublishPassphrase"`
 
 	// RTSP source
-	RTSPTransport       RTSPTransport  `json:"rtspTransport"`
-	RTSPAnyPort         bool           `json:"rtspAnyPort"`
-	SourceProtocol      *RTSPTransport `json:"sourceProtocol,omitempty"`      // deprecated
-	SourceAnyPortEnable *bool          `json:"sourceAnyPortEnable,omitempty"` // deprecated
-	RTSPRangeType       RTSPRangeType  `json:"rtspRangeType"`
-	RTSPRangeStart      string         `json:"rtspRangeStart"`
+	RTSPTransport            RTSPTransport            `json:"rtspTransport"`
+	RTSPAnyPort              bool                     `json:"rtspAnyPort"`
+	SourceProtocol           *RTSPTransport           `json:"sourceProtocol,omitempty"`      // deprecated
+	SourceAnyPortEnable      *bool                    `json:"sourceAnyPortEnable,omitempty"` // deprecated
+	RTSPRangeType            RTSPRangeType            `json:"rtspRangeType"`
+	RTSPRangeStart           string                   `json:"rtspRangeStart"`
+	RTSPPayloadTypeOverrides RTSPPayloadTypeOverrides `json:"rtspPayloadTypeOverrides"`
+	RTSPByeBehavior          RTSPByeBehavior          `json:"rtspByeBehavior"`
+	RTSPMaxMalformedPackets  int                      `json:"rtspMaxMalformedPackets"`
+
+	// HLS source
+	HLSSourceMaxIdleConns    int            `json:"hlsSourceMaxIdleConns"`
+	HLSSourceIdleConnTimeout StringDuration `json:"hlsSourceIdleConnTimeout"`
 
 	// Redirect source
 	SourceRedirect string `json:"sourceRedirect"`
@@ -172,6 +223,7 @@ type Path struct {
 	RunOnDemandStartTimeout    StringDuration `json:"runOnDemandStartTimeout"`
 	RunOnDemandCloseAfter      StringDuration `json:"runOnDemandCloseAfter"`
 	RunOnUnDemand              string         `json:"runOnUnDemand"`
+	DependsOn                  []string       `json:"dependsOn"`
 	RunOnReady                 string         `json:"runOnReady"`
 	RunOnReadyRestart          bool           `json:"runOnReadyRestart"`
 	RunOnNotReady              string         `json:"runOnNotReady"`
@@ -184,9 +236,16 @@ type Path struct {
 
 func (pconf *Path) setDefaults() {
 	// General
+	pconf.Enabled = true
 	pconf.Source = "publisher"
 	pconf.SourceOnDemandStartTimeout = 10 * StringDuration(time.Second)
 	pconf.SourceOnDemandCloseAfter = 10 * StringDuration(time.Second)
+	pconf.MaxPublishBitrateWindow = 5 * StringDuration(time.Second)
+	pconf.RTSPTransports = Protocols{
+		Protocol(gortsplib.TransportUDP):          {},
+		Protocol(gortsplib.TransportUDPMulticast): {},
+		Protocol(gortsplib.TransportTCP):          {},
+	}
 
 	// Record and playback
 	pconf.Playback = true
@@ -195,10 +254,22 @@ func (pconf *Path) setDefaults() {
 	pconf.RecordPartDuration = 100 * StringDuration(time.Millisecond)
 	pconf.RecordSegmentDuration = 3600 * StringDuration(time.Second)
 	pconf.RecordDeleteAfter = 24 * 3600 * StringDuration(time.Second)
+	pconf.RecordAudio = true
+
+	// Push
+	pconf.PushDestinations = []string{}
+
+	// HLS
+	pconf.HLSAudioLanguages = []string{}
 
 	// Publisher source
+	pconf.OnNewPublisher = OnNewPublisherTakeover
 	pconf.OverridePublisher = true
 
+	// HLS source
+	pconf.HLSSourceMaxIdleConns = 10
+	pconf.HLSSourceIdleConnTimeout = 90 * StringDuration(time.Second)
+
 	// Raspberry Pi Camera source
 	pconf.RPICameraWidth = 1920
 	pconf.RPICameraHeight = 1080
@@ -223,6 +294,7 @@ func (pconf *Path) setDefaults() {
 	// Hooks
 	pconf.RunOnDemandStartTimeout = 10 * StringDuration(time.Second)
 	pconf.RunOnDemandCloseAfter = 10 * StringDuration(time.Second)
+	pconf.DependsOn = []string{}
 }
 
 func newPath(defaults *Path, partial *OptionalPath) *Path {
@@ -330,12 +402,18 @@ func (pconf *Path) validate(conf *Conf, name string) error {
 		}
 
 	case strings.HasPrefix(pconf.Source, "srt://"):
-
-		_, err := gourl.Parse(pconf.Source)
+		u, err := gourl.Parse(pconf.Source)
 		if err != nil {
 			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
 		}
 
+		switch u.Query().Get("mode") {
+		case "", "caller", "listener":
+		default:
+			return fmt.Errorf("'%s' is not a valid SRT mode, supported modes are 'caller' and 'listener'",
+				u.Query().Get("mode"))
+		}
+
 	case strings.HasPrefix(pconf.Source, "whep://") ||
 		strings.HasPrefix(pconf.Source, "wheps://"):
 		_, err := gourl.Parse(pconf.Source)
@@ -343,10 +421,22 @@ func (pconf *Path) validate(conf *Conf, name string) error {
 			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
 		}
 
+	case strings.HasPrefix(pconf.Source, "onvif://") ||
+		strings.HasPrefix(pconf.Source, "onvifs://"):
+		u, err := gourl.Parse(pconf.Source)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
+		}
+		if u.Query().Get("profile") == "" {
+			return fmt.Errorf("'%s' doesn't contain the 'profile' parameter", pconf.Source)
+		}
+
 	case pconf.Source == "redirect":
 
 	case pconf.Source == "rpiCamera":
 
+	case isRegisteredStaticSource(pconf.Source):
+
 	default:
 		return fmt.Errorf("invalid source: '%s'", pconf.Source)
 	}
@@ -355,6 +445,18 @@ func (pconf *Path) validate(conf *Conf, name string) error {
 			return fmt.Errorf("'sourceOnDemand' is useless when source is 'publisher'")
 		}
 	}
+	if pconf.SourceReadFailureGrace < 0 || pconf.SourceReadFailureGrace > conf.ReadTimeout {
+		return fmt.Errorf("'sourceReadFailureGrace' must be between zero and 'readTimeout'")
+	}
+	if pconf.SourceConnectTimeout < 0 {
+		return fmt.Errorf("'sourceConnectTimeout' can't be negative")
+	}
+	if pconf.MaxPublishBitrate < 0 {
+		return fmt.Errorf("'maxPublishBitrate' can't be negative")
+	}
+	if pconf.MaxPublishBitrateWindow <= 0 {
+		return fmt.Errorf("'maxPublishBitrateWindow' must be greater than zero")
+	}
 	if pconf.SRTReadPassphrase != "" {
 		err := srtCheckPassphrase(pconf.SRTReadPassphrase)
 		if err != nil {
@@ -374,6 +476,83 @@ func (pconf *Path) validate(conf *Conf, name string) error {
 			}
 		}
 	}
+	if len(pconf.RTSPTransports) == 0 {
+		return fmt.Errorf("'rtspTransports' must contain at least one transport")
+	}
+	if pconf.RTSPMaxSessions < 0 {
+		return fmt.Errorf("'rtspMaxSessions' can't be negative")
+	}
+	if pconf.ReaderQueueSize < 0 {
+		return fmt.Errorf("'readerQueueSize' can't be negative")
+	}
+	if pconf.ReaderQueueMaxSize != 0 {
+		queueSize := pconf.ReaderQueueSize
+		if queueSize == 0 {
+			queueSize = conf.WriteQueueSize
+		}
+		if pconf.ReaderQueueMaxSize < queueSize {
+			return fmt.Errorf("'readerQueueMaxSize' must be greater than or equal to the reader queue size (%d)",
+				queueSize)
+		}
+	}
+	if pconf.LiveBufferDuration < 0 {
+		return fmt.Errorf("'liveBufferDuration' can't be negative")
+	}
+
+	// Record and playback
+
+	if pconf.RecordDeleteAfter < 0 {
+		return fmt.Errorf("'recordDeleteAfter' can't be negative")
+	}
+	if pconf.RecordVideoFramerate < 0 {
+		return fmt.Errorf("'recordVideoFramerate' can't be negative")
+	}
+	if pconf.RecordInterleaveWindow < 0 {
+		return fmt.Errorf("'recordInterleaveWindow' can't be negative")
+	}
+	if pconf.FMP4FragmentDuration < 0 {
+		return fmt.Errorf("'fmp4FragmentDuration' can't be negative")
+	}
+	for i, out := range pconf.RecordOutputs {
+		if out.RecordPath == "" {
+			return fmt.Errorf("'recordPath' of record output %d is empty", i)
+		}
+		if out.RecordPath == pconf.RecordPath {
+			return fmt.Errorf("record output %d has the same 'recordPath' of the main recording", i)
+		}
+		for _, other := range pconf.RecordOutputs[:i] {
+			if other.RecordPath == out.RecordPath {
+				return fmt.Errorf("record output %d has the same 'recordPath' of another output", i)
+			}
+		}
+		if out.RecordPartDuration <= 0 || out.RecordSegmentDuration <= 0 {
+			return fmt.Errorf("'recordPartDuration' and 'recordSegmentDuration' of record output %d must be positive", i)
+		}
+		if out.RecordDeleteAfter < 0 {
+			return fmt.Errorf("'recordDeleteAfter' of record output %d can't be negative", i)
+		}
+		if out.RecordVideoFramerate < 0 {
+			return fmt.Errorf("'recordVideoFramerate' of record output %d can't be negative", i)
+		}
+	}
+
+	// Push
+
+	for i, dest := range pconf.PushDestinations {
+		u, err := gourl.Parse(dest)
+		if err != nil {
+			return fmt.Errorf("push destination %d is not a valid URL: %w", i, err)
+		}
+		if u.Scheme != "rtmp" && u.Scheme != "rtmps" && u.Scheme != "srt" {
+			return fmt.Errorf("push destination %d has an unsupported scheme, "+
+				"supported schemes are 'rtmp', 'rtmps' and 'srt'", i)
+		}
+		for _, other := range pconf.PushDestinations[:i] {
+			if other == dest {
+				return fmt.Errorf("push destination %d is duplicated", i)
+			}
+		}
+	}
 
 	// Authentication
 
@@ -410,11 +589,41 @@ func (pconf *Path) validate(conf *Conf, name string) error {
 		}
 	}
 
+	// HLS
+
+	if pconf.HLSSegmentBaseURL != "" {
+		u, err := gourl.Parse(pconf.HLSSegmentBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("'hlsSegmentBaseURL' must be a HTTP or HTTPS URL")
+		}
+	}
+
+	if len(pconf.HLSAudioLanguages) != 0 && conf.HLSVariant == HLSVariant(gohlslib.MuxerVariantMPEGTS) {
+		return fmt.Errorf("'hlsAudioLanguages' is not supported with the 'mpegts' HLS variant")
+	}
+
+	for i, lang := range pconf.HLSAudioLanguages {
+		if !reLanguageTag.MatchString(lang) {
+			return fmt.Errorf("invalid language in 'hlsAudioLanguages': '%s'", lang)
+		}
+		for _, other := range pconf.HLSAudioLanguages[:i] {
+			if strings.EqualFold(lang, other) {
+				return fmt.Errorf("language '%s' is duplicated in 'hlsAudioLanguages'", lang)
+			}
+		}
+	}
+
 	// Publisher source
 
 	if pconf.DisablePublisherOverride != nil {
 		pconf.OverridePublisher = !*pconf.DisablePublisherOverride
 	}
+	// overridePublisher is kept for compatibility: when it is disabled,
+	// new publishers are rejected whatever the value of onNewPublisher is.
+	if !pconf.OverridePublisher {
+		pconf.OnNewPublisher = OnNewPublisherReject
+	}
+	pconf.OverridePublisher = (pconf.OnNewPublisher == OnNewPublisherTakeover)
 	if pconf.SRTPublishPassphrase != "" {
 		if pconf.Source != "publisher" {
 			return fmt.Errorf("'srtPublishPassphase' can only be used when source is 'publisher'")
@@ -434,6 +643,18 @@ func (pconf *Path) validate(conf *Conf, name string) error {
 	if pconf.SourceAnyPortEnable != nil {
 		pconf.RTSPAnyPort = *pconf.SourceAnyPortEnable
 	}
+	if pconf.RTSPMaxMalformedPackets < 0 {
+		return fmt.Errorf("'rtspMaxMalformedPackets' can't be negative")
+	}
+
+	// HLS source
+
+	if pconf.HLSSourceMaxIdleConns <= 0 {
+		return fmt.Errorf("'hlsSourceMaxIdleConns' must be greater than zero")
+	}
+	if pconf.HLSSourceIdleConnTimeout < 0 {
+		return fmt.Errorf("'hlsSourceIdleConnTimeout' can't be negative")
+	}
 
 	// Redirect source
 
@@ -507,6 +728,87 @@ func (pconf *Path) validate(conf *Conf, name string) error {
 	if (pconf.RunOnDemand != "" || pconf.RunOnUnDemand != "") && pconf.Source != "publisher" {
 		return fmt.Errorf("'runOnDemand' and 'runOnUnDemand' can be used only when source is 'publisher'")
 	}
+	if len(pconf.DependsOn) != 0 && pconf.RunOnDemand == "" {
+		return fmt.Errorf("'dependsOn' can be used only with 'runOnDemand'")
+	}
+	for i, dep := range pconf.DependsOn {
+		if dep == "" {
+			return fmt.Errorf("dependency %d is empty", i)
+		}
+		for _, other := range pconf.DependsOn[:i] {
+			if other == dep {
+				return fmt.Errorf("dependency '%s' is duplicated", dep)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkPathDependencies checks that dependencies between paths exist and do not form cycles.
+func checkPathDependencies(paths map[string]*Path) error {
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	deps := make(map[string][]string)
+
+	for _, name := range names {
+		for _, dep := range paths[name].DependsOn {
+			depConfName, _, _, err := FindPathConf(paths, dep)
+			if err != nil {
+				return fmt.Errorf("path '%s' depends on '%s', which is not configured", name, dep)
+			}
+			deps[name] = append(deps[name], depConfName)
+		}
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			i := 0
+			for stack[i] != name {
+				i++
+			}
+			return fmt.Errorf("dependency cycle between paths: '%s'",
+				strings.Join(append(stack[i:], name), "' -> '"))
+
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+
+		for _, dep := range deps[name] {
+			err := visit(dep)
+			if err != nil {
+				return err
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range names {
+		err := visit(name)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -528,7 +830,10 @@ func (pconf Path) HasStaticSource() bool {
 		strings.HasPrefix(pconf.Source, "srt://") ||
 		strings.HasPrefix(pconf.Source, "whep://") ||
 		strings.HasPrefix(pconf.Source, "wheps://") ||
-		pconf.Source == "rpiCamera"
+		strings.HasPrefix(pconf.Source, "onvif://") ||
+		strings.HasPrefix(pconf.Source, "onvifs://") ||
+		pconf.Source == "rpiCamera" ||
+		isRegisteredStaticSource(pconf.Source)
 }
 
 // HasOnDemandStaticSource checks whether the path has a on demand static source.
@@ -0,0 +1,76 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortRange is a range of ports, in the format "min-max".
+// The zero value represents a disabled range.
+type PortRange struct {
+	Min uint16
+	Max uint16
+}
+
+// IsEmpty returns whether the range is disabled.
+func (r PortRange) IsEmpty() bool {
+	return r.Max == 0
+}
+
+// Contains returns whether the range contains a port.
+func (r PortRange) Contains(port int) bool {
+	return !r.IsEmpty() && port >= int(r.Min) && port <= int(r.Max)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r PortRange) MarshalJSON() ([]byte, error) {
+	if r.IsEmpty() {
+		return json.Marshal("")
+	}
+	return json.Marshal(strconv.FormatUint(uint64(r.Min), 10) + "-" + strconv.FormatUint(uint64(r.Max), 10))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *PortRange) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	if in == "" {
+		*r = PortRange{}
+		return nil
+	}
+
+	parts := strings.Split(in, "-")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid port range '%s'", in)
+	}
+
+	min, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil || min == 0 {
+		return fmt.Errorf("invalid port range '%s'", in)
+	}
+
+	max, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port range '%s'", in)
+	}
+
+	if max < min {
+		return fmt.Errorf("port range '%s' is empty", in)
+	}
+
+	*r = PortRange{
+		Min: uint16(min),
+		Max: uint16(max),
+	}
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (r *PortRange) UnmarshalEnv(_ string, v string) error {
+	return r.UnmarshalJSON([]byte(`"` + v + `"`))
+}
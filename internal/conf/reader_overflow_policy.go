@@ -0,0 +1,70 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ReaderOverflowPolicy is the readerOverflowPolicy parameter.
+type ReaderOverflowPolicy int
+
+// supported values.
+const (
+	ReaderOverflowPolicyDropNewest ReaderOverflowPolicy = iota
+	ReaderOverflowPolicyDisconnect
+	ReaderOverflowPolicyDropOldest
+	ReaderOverflowPolicyDropToKeyframe
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d ReaderOverflowPolicy) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case ReaderOverflowPolicyDisconnect:
+		out = "disconnect"
+
+	case ReaderOverflowPolicyDropOldest:
+		out = "dropOldest"
+
+	case ReaderOverflowPolicyDropToKeyframe:
+		out = "dropToKeyframe"
+
+	default:
+		out = "dropNewest"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *ReaderOverflowPolicy) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "dropNewest":
+		*d = ReaderOverflowPolicyDropNewest
+
+	case "disconnect":
+		*d = ReaderOverflowPolicyDisconnect
+
+	case "dropOldest":
+		*d = ReaderOverflowPolicyDropOldest
+
+	case "dropToKeyframe":
+		*d = ReaderOverflowPolicyDropToKeyframe
+
+	default:
+		return fmt.Errorf("invalid reader overflow policy '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *ReaderOverflowPolicy) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RecordDTSMode is the recordDTSMode parameter.
+type RecordDTSMode int
+
+// supported values.
+const (
+	RecordDTSModeBitstream RecordDTSMode = iota
+	RecordDTSModeDecodeOrder
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d RecordDTSMode) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case RecordDTSModeDecodeOrder:
+		out = "decodeOrder"
+
+	default:
+		out = "bitstream"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *RecordDTSMode) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "decodeOrder":
+		*d = RecordDTSModeDecodeOrder
+
+	case "bitstream":
+		*d = RecordDTSModeBitstream
+
+	default:
+		return fmt.Errorf("invalid record DTS mode '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *RecordDTSMode) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
@@ -0,0 +1,63 @@
+package conf
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RecordOutput is an additional recording of a path.
+type RecordOutput struct {
+	RecordPath            string          `json:"recordPath"`
+	RecordFormat          RecordFormat    `json:"recordFormat"`
+	RecordPartDuration    StringDuration  `json:"recordPartDuration"`
+	RecordSegmentDuration StringDuration  `json:"recordSegmentDuration"`
+	RecordDeleteAfter     StringDuration  `json:"recordDeleteAfter"`
+	RecordMaxSize         StringSize      `json:"recordMaxSize"`
+	RecordVideoMode       RecordVideoMode `json:"recordVideoMode"`
+	RecordVideoFramerate  float64         `json:"recordVideoFramerate"`
+	RecordAudio           bool            `json:"recordAudio"`
+}
+
+func (o *RecordOutput) setDefaults() {
+	o.RecordFormat = RecordFormatFMP4
+	o.RecordPartDuration = 100 * StringDuration(time.Millisecond)
+	o.RecordSegmentDuration = 3600 * StringDuration(time.Second)
+	o.RecordDeleteAfter = 24 * 3600 * StringDuration(time.Second)
+	o.RecordAudio = true
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *RecordOutput) UnmarshalJSON(b []byte) error {
+	o.setDefaults()
+
+	type alias RecordOutput
+	return json.Unmarshal(b, (*alias)(o))
+}
+
+// RecordOutputs is the recordOutputs parameter.
+type RecordOutputs []RecordOutput
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *RecordOutputs) UnmarshalJSON(b []byte) error {
+	var in []RecordOutput
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*d = nil
+
+	if len(in) == 0 {
+		return nil
+	}
+
+	*d = in
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *RecordOutputs) UnmarshalEnv(_ string, v string) error {
+	if v == "" {
+		return nil
+	}
+	return d.UnmarshalJSON([]byte(v))
+}
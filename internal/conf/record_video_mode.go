@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RecordVideoMode is the recordVideoMode parameter.
+type RecordVideoMode int
+
+// supported values.
+const (
+	RecordVideoModeAll RecordVideoMode = iota
+	RecordVideoModeKeyframesOnly
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d RecordVideoMode) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case RecordVideoModeKeyframesOnly:
+		out = "keyframesOnly"
+
+	default:
+		out = "all"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *RecordVideoMode) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "keyframesOnly":
+		*d = RecordVideoModeKeyframesOnly
+
+	case "all":
+		*d = RecordVideoModeAll
+
+	default:
+		return fmt.Errorf("invalid record video mode '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *RecordVideoMode) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
@@ -0,0 +1,63 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RTSPByeBehavior is the rtspByeBehavior parameter.
+type RTSPByeBehavior int
+
+// supported values.
+const (
+	RTSPByeBehaviorIgnore RTSPByeBehavior = iota
+	RTSPByeBehaviorReconnect
+	RTSPByeBehaviorSSRC
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d RTSPByeBehavior) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case RTSPByeBehaviorReconnect:
+		out = "reconnect"
+
+	case RTSPByeBehaviorSSRC:
+		out = "ssrc"
+
+	default:
+		out = "ignore"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *RTSPByeBehavior) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "ignore":
+		*d = RTSPByeBehaviorIgnore
+
+	case "reconnect":
+		*d = RTSPByeBehaviorReconnect
+
+	case "ssrc":
+		*d = RTSPByeBehaviorSSRC
+
+	default:
+		return fmt.Errorf("invalid RTSP BYE behavior: '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *RTSPByeBehavior) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
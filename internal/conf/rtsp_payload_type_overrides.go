@@ -0,0 +1,66 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RTSPPayloadTypeOverrides is the rtspPayloadTypeOverrides parameter.
+// It maps dynamic RTP payload types to RTP maps (i.e. "H264/90000").
+type RTSPPayloadTypeOverrides map[int]string
+
+// MarshalJSON implements json.Marshaler.
+func (d RTSPPayloadTypeOverrides) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[int]string(d))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *RTSPPayloadTypeOverrides) UnmarshalJSON(b []byte) error {
+	var in map[string]string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*d = nil
+
+	if len(in) == 0 {
+		return nil
+	}
+
+	*d = make(RTSPPayloadTypeOverrides)
+
+	for k, v := range in {
+		pt, err := strconv.ParseUint(k, 10, 8)
+		if err != nil || pt < 96 || pt > 127 {
+			return fmt.Errorf("invalid payload type '%s': only dynamic payload types (96-127) can be overridden", k)
+		}
+
+		if v == "" {
+			return fmt.Errorf("empty RTP map for payload type %d", pt)
+		}
+
+		(*d)[int(pt)] = v
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *RTSPPayloadTypeOverrides) UnmarshalEnv(_ string, v string) error {
+	in := make(map[string]string)
+
+	if v != "" {
+		for _, entry := range strings.Split(v, ",") {
+			parts := strings.SplitN(entry, ":", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid entry '%s'", entry)
+			}
+			in[parts[0]] = parts[1]
+		}
+	}
+
+	byts, _ := json.Marshal(in)
+	return d.UnmarshalJSON(byts)
+}
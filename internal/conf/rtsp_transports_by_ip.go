@@ -0,0 +1,110 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// RTSPTransportsByIPEntry is an entry of RTSPTransportsByIP.
+type RTSPTransportsByIPEntry struct {
+	Network    *net.IPNet
+	Transports Protocols
+}
+
+// RTSPTransportsByIP is the rtspTransportsByIP parameter.
+// Entries are sorted from the most specific network to the least specific one.
+type RTSPTransportsByIP []RTSPTransportsByIPEntry
+
+// MarshalJSON implements json.Marshaler.
+func (d RTSPTransportsByIP) MarshalJSON() ([]byte, error) {
+	out := make(map[string]Protocols, len(d))
+
+	for _, e := range d {
+		ones, bits := e.Network.Mask.Size()
+		if ones == bits {
+			out[e.Network.IP.String()] = e.Transports
+		} else {
+			out[e.Network.String()] = e.Transports
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *RTSPTransportsByIP) UnmarshalJSON(b []byte) error {
+	var in map[string]Protocols
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*d = nil
+
+	for k, transports := range in {
+		var network *net.IPNet
+
+		if _, ipnet, err := net.ParseCIDR(k); err == nil {
+			network = ipnet
+		} else if ip := net.ParseIP(k); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				network = &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+			} else {
+				network = &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+			}
+		} else {
+			return fmt.Errorf("unable to parse IP/CIDR '%s'", k)
+		}
+
+		if len(transports) == 0 {
+			return fmt.Errorf("no transports provided for '%s'", k)
+		}
+
+		*d = append(*d, RTSPTransportsByIPEntry{
+			Network:    network,
+			Transports: transports,
+		})
+	}
+
+	sort.Slice(*d, func(i, j int) bool {
+		onesI, _ := (*d)[i].Network.Mask.Size()
+		onesJ, _ := (*d)[j].Network.Mask.Size()
+		if onesI != onesJ {
+			return onesI > onesJ
+		}
+		return (*d)[i].Network.String() < (*d)[j].Network.String()
+	})
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *RTSPTransportsByIP) UnmarshalEnv(_ string, v string) error {
+	in := make(map[string][]string)
+
+	if v != "" {
+		for _, entry := range strings.Split(v, ";") {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid entry '%s'", entry)
+			}
+			in[strings.TrimSpace(parts[0])] = strings.Split(strings.TrimSpace(parts[1]), ",")
+		}
+	}
+
+	byts, _ := json.Marshal(in)
+	return d.UnmarshalJSON(byts)
+}
+
+// Find returns the transports allowed for the given IP.
+// When the IP is not covered by any entry, false is returned.
+func (d RTSPTransportsByIP) Find(ip net.IP) (Protocols, bool) {
+	for _, e := range d {
+		if e.Network.Contains(ip) {
+			return e.Transports, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,14 @@
+package conf
+
+// isRegisteredStaticSource checks whether a source is provided by a
+// static source registered in the staticsource package.
+var isRegisteredStaticSource = func(string) bool {
+	return false
+}
+
+// SetStaticSourceChecker sets the function used to check whether a source
+// is provided by a registered static source.
+// It is called by the staticsource package, that holds the registry.
+func SetStaticSourceChecker(f func(source string) bool) {
+	isRegisteredStaticSource = f
+}
@@ -0,0 +1,68 @@
+package conf
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func tlsCipherSuiteByName(name string) (uint16, bool) {
+	for _, cs := range tls.CipherSuites() {
+		if cs.Name == name {
+			return cs.ID, true
+		}
+	}
+	for _, cs := range tls.InsecureCipherSuites() {
+		if cs.Name == name {
+			return cs.ID, true
+		}
+	}
+	return 0, false
+}
+
+// TLSCipherSuites is the tlsCipherSuites parameter.
+type TLSCipherSuites []uint16
+
+// MarshalJSON implements json.Marshaler.
+func (d TLSCipherSuites) MarshalJSON() ([]byte, error) {
+	out := make([]string, len(d))
+
+	for i, v := range d {
+		out[i] = tls.CipherSuiteName(v)
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *TLSCipherSuites) UnmarshalJSON(b []byte) error {
+	var in []string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*d = nil
+
+	for _, v := range in {
+		id, ok := tlsCipherSuiteByName(v)
+		if !ok {
+			return fmt.Errorf("unknown TLS cipher suite: '%s'", v)
+		}
+
+		*d = append(*d, id)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *TLSCipherSuites) UnmarshalEnv(_ string, v string) error {
+	if v == "" {
+		*d = nil
+		return nil
+	}
+
+	byts, _ := json.Marshal(strings.Split(v, ","))
+	return d.UnmarshalJSON(byts)
+}
@@ -0,0 +1,66 @@
+package conf
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+)
+
+// TLSVersion is the tlsMinVersion parameter.
+type TLSVersion uint16
+
+// MarshalJSON implements json.Marshaler.
+func (d TLSVersion) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case tls.VersionTLS10:
+		out = "1.0"
+
+	case tls.VersionTLS11:
+		out = "1.1"
+
+	case tls.VersionTLS12:
+		out = "1.2"
+
+	case tls.VersionTLS13:
+		out = "1.3"
+
+	default:
+		return nil, fmt.Errorf("invalid TLS version: %v", d)
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *TLSVersion) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "1.0":
+		*d = tls.VersionTLS10
+
+	case "1.1":
+		*d = tls.VersionTLS11
+
+	case "1.2":
+		*d = tls.VersionTLS12
+
+	case "1.3":
+		*d = tls.VersionTLS13
+
+	default:
+		return fmt.Errorf("invalid TLS version: '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *TLSVersion) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
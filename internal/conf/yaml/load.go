@@ -4,6 +4,7 @@ package yaml
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"gopkg.in/yaml.v2"
 )
@@ -13,9 +14,17 @@ func convertKeys(i interface{}) (interface{}, error) {
 	case map[interface{}]interface{}:
 		m2 := map[string]interface{}{}
 		for k, v := range x {
-			ks, ok := k.(string)
-			if !ok {
-				return nil, fmt.Errorf("integer keys are not supported (%v)", k)
+			var ks string
+
+			switch k := k.(type) {
+			case string:
+				ks = k
+
+			case int:
+				ks = strconv.Itoa(k)
+
+			default:
+				return nil, fmt.Errorf("unsupported key type (%v)", k)
 			}
 
 			var err error
@@ -12,10 +12,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
@@ -24,6 +27,7 @@ import (
 	"github.com/pion/rtp"
 	"github.com/stretchr/testify/require"
 
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp"
 	"github.com/bluenviron/mediamtx/internal/protocols/webrtc"
 	"github.com/bluenviron/mediamtx/internal/test"
@@ -39,6 +43,10 @@ var testMediaAAC = &description.Media{
 	Formats: []format.Format{test.FormatMPEG4Audio},
 }
 
+func intPtr(v int) *int {
+	return &v
+}
+
 func checkClose(t *testing.T, closeFunc func() error) {
 	require.NoError(t, closeFunc())
 }
@@ -81,6 +89,24 @@ func checkError(t *testing.T, msg string, body io.Reader) {
 	require.Equal(t, map[string]interface{}{"error": msg}, resErr)
 }
 
+func TestAPIHealthReady(t *testing.T) {
+	p, ok := newInstance("api: yes\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/healthz", nil, nil)
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/readyz", nil, nil)
+
+	p.ready.Store(false)
+
+	res, err := hc.Get("http://localhost:9997/readyz")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+}
+
 func TestAPIPathsList(t *testing.T) {
 	type pathSource struct {
 		Type string `json:"type"`
@@ -290,13 +316,23 @@ func TestAPIPathsGet(t *testing.T) {
 				Type string `json:"type"`
 			}
 
+			type pathTrack struct {
+				Codec       string `json:"codec"`
+				PayloadType uint8  `json:"payloadType"`
+				ClockRate   int    `json:"clockRate"`
+				Width       *int   `json:"width"`
+				Height      *int   `json:"height"`
+			}
+
 			type path struct {
-				Name          string     `json:"name"`
-				Source        pathSource `json:"source"`
-				Ready         bool       `json:"Ready"`
-				Tracks        []string   `json:"tracks"`
-				BytesReceived uint64     `json:"bytesReceived"`
-				BytesSent     uint64     `json:"bytesSent"`
+				Name          string      `json:"name"`
+				Source        pathSource  `json:"source"`
+				Ready         bool        `json:"Ready"`
+				Tracks        []string    `json:"tracks"`
+				TrackDetails  []pathTrack `json:"trackDetails"`
+				SourceState   string      `json:"sourceState"`
+				BytesReceived uint64      `json:"bytesReceived"`
+				BytesSent     uint64      `json:"bytesSent"`
 			}
 
 			var pathName string
@@ -326,6 +362,14 @@ func TestAPIPathsGet(t *testing.T) {
 					},
 					Ready:  true,
 					Tracks: []string{"H264"},
+					TrackDetails: []pathTrack{{
+						Codec:       "H264",
+						PayloadType: 96,
+						ClockRate:   90000,
+						Width:       intPtr(1920),
+						Height:      intPtr(1080),
+					}},
+					SourceState: "ready",
 				}, out)
 			} else {
 				res, err := hc.Get("http://localhost:9997/v3/paths/get/" + pathName)
@@ -339,6 +383,318 @@ func TestAPIPathsGet(t *testing.T) {
 	}
 }
 
+func TestAPIPathsGetSourceStats(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	source := gortsplib.Client{}
+	err := source.StartRecording("rtsp://localhost:8554/mypath",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	type pathSourceStats struct {
+		MsSinceLastPacket *float64 `json:"msSinceLastPacket"`
+		MsGapMax          float64  `json:"msGapMax"`
+		Stalls            uint64   `json:"stalls"`
+	}
+
+	type path struct {
+		SourceStats *pathSourceStats `json:"sourceStats"`
+	}
+
+	var out path
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+	require.Equal(t, &pathSourceStats{}, out.SourceStats)
+
+	for i := 0; i < 3; i++ {
+		err = source.WritePacketRTP(testMediaH264, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: 1123 + uint16(i),
+				Timestamp:      45343 + 9000*uint32(i),
+				SSRC:           563423,
+			},
+			Payload: []byte{5},
+		})
+		require.NoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+	require.NotNil(t, out.SourceStats.MsSinceLastPacket)
+	require.Greater(t, out.SourceStats.MsGapMax, float64(50))
+	require.Equal(t, uint64(0), out.SourceStats.Stalls)
+}
+
+func TestAPIPathsReaders(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	source := gortsplib.Client{}
+	err := source.StartRecording("rtsp://localhost:8554/mypath",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	for i := 0; i < 2; i++ {
+		reader := gortsplib.Client{}
+
+		u, err := base.ParseURL("rtsp://127.0.0.1:8554/mypath")
+		require.NoError(t, err)
+
+		err = reader.Start(u.Scheme, u.Host)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		desc, _, err := reader.Describe(u)
+		require.NoError(t, err)
+
+		err = reader.SetupAll(desc.BaseURL, desc.Medias)
+		require.NoError(t, err)
+
+		_, err = reader.Play(nil)
+		require.NoError(t, err)
+	}
+
+	type pathReader struct {
+		Type       string `json:"type"`
+		ID         string `json:"id"`
+		RemoteAddr string `json:"remoteAddr"`
+	}
+
+	type pathReaderList struct {
+		ItemCount int          `json:"itemCount"`
+		PageCount int          `json:"pageCount"`
+		Items     []pathReader `json:"items"`
+	}
+
+	var out pathReaderList
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/readers/mypath?itemsPerPage=1", nil, &out)
+	require.Equal(t, 2, out.ItemCount)
+	require.Equal(t, 2, out.PageCount)
+	require.Len(t, out.Items, 1)
+	require.Equal(t, "rtspSession", out.Items[0].Type)
+	require.NotEmpty(t, out.Items[0].ID)
+	require.Contains(t, out.Items[0].RemoteAddr, "127.0.0.1:")
+
+	// pages are consistent between calls.
+	for i := 0; i < 5; i++ {
+		var out0 pathReaderList
+		httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/readers/mypath?itemsPerPage=1&page=0", nil, &out0)
+		var out1 pathReaderList
+		httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/readers/mypath?itemsPerPage=1&page=1", nil, &out1)
+		require.Equal(t, out.Items[0].ID, out0.Items[0].ID)
+		require.Less(t, out0.Items[0].ID, out1.Items[0].ID)
+	}
+
+	res, err := hc.Get("http://localhost:9997/v3/paths/readers/nonexisting")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	checkError(t, "path not found", res.Body)
+}
+
+func TestAPIPathsRecord(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-api-record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n" +
+		"    recordPath: " + filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f") + "\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	res, err := hc.Post("http://localhost:9997/v3/paths/record/start/mypath", "", nil)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	checkError(t, "path not found", res.Body)
+
+	source := gortsplib.Client{}
+	err = source.StartRecording("rtsp://localhost:8554/mypath",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	res2, err := hc.Post("http://localhost:9997/v3/paths/record/stop/mypath", "", nil)
+	require.NoError(t, err)
+	defer res2.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res2.StatusCode)
+	checkError(t, "recording has not been started", res2.Body)
+
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/record/start/mypath", nil, nil)
+
+	for i := 0; i < 4; i++ {
+		err := source.WritePacketRTP(testMediaH264, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: 1123 + uint16(i),
+				Timestamp:      45343 + 90000*uint32(i),
+				SSRC:           563423,
+			},
+			Payload: []byte{5},
+		})
+		require.NoError(t, err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/record/stop/mypath", nil, nil)
+
+	files, err := os.ReadDir(filepath.Join(dir, "mypath"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+	require.Equal(t, ".mp4", filepath.Ext(files[0].Name()))
+}
+
+func TestAPISourcesProbe(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	res, err := hc.Post("http://localhost:9997/v3/sources/probe", "application/json",
+		bytes.NewReader([]byte(`{"source":"publisher"}`)))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	checkError(t, "'source' must be a static source", res.Body)
+
+	res2, err := hc.Post("http://localhost:9997/v3/sources/probe?timeout=1s", "application/json",
+		bytes.NewReader([]byte(`{"source":"rtsp://localhost:8554/mypath"}`)))
+	require.NoError(t, err)
+	defer res2.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res2.StatusCode)
+
+	source := gortsplib.Client{}
+	err = source.StartRecording("rtsp://localhost:8554/mypath",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	var out map[string]interface{}
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/sources/probe",
+		map[string]interface{}{
+			"source":        "rtsp://localhost:8554/mypath",
+			"rtspTransport": "tcp",
+		}, &out)
+	require.Equal(t, []interface{}{"H264"}, out["tracks"])
+
+	// no path is created
+	var out2 map[string]interface{}
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/config/paths/list", nil, &out2)
+	for _, item := range out2["items"].([]interface{}) {
+		require.NotEqual(t, "probe", item.(map[string]interface{})["name"])
+	}
+}
+
+func TestAPIPathsClip(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n" +
+		"    liveBufferDuration: 10s\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	res, err := hc.Post("http://localhost:9997/v3/paths/clip/mypath", "application/json",
+		bytes.NewReader([]byte(`{"start":"10s","end":"0s"}`)))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	checkError(t, "path not found", res.Body)
+
+	source := gortsplib.Client{}
+	err = source.StartRecording("rtsp://localhost:8554/mypath",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	res2, err := hc.Post("http://localhost:9997/v3/paths/clip/mypath", "application/json",
+		bytes.NewReader([]byte(`{"start":"0s","end":"10s"}`)))
+	require.NoError(t, err)
+	defer res2.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res2.StatusCode)
+	checkError(t, "'start' must be greater than 'end'", res2.Body)
+
+	for i := 0; i < 4; i++ {
+		err := source.WritePacketRTP(testMediaH264, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: 1123 + uint16(i),
+				Timestamp:      45343 + 90000*uint32(i),
+				SSRC:           563423,
+			},
+			Payload: []byte{5},
+		})
+		require.NoError(t, err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	res3, err := hc.Post("http://localhost:9997/v3/paths/clip/mypath", "application/json",
+		bytes.NewReader([]byte(`{"start":"10s","end":"0s"}`)))
+	require.NoError(t, err)
+	defer res3.Body.Close()
+	require.Equal(t, http.StatusOK, res3.StatusCode)
+	require.Equal(t, "video/mp4", res3.Header.Get("Content-Type"))
+
+	byts, err := io.ReadAll(res3.Body)
+	require.NoError(t, err)
+	require.Greater(t, len(byts), 8)
+	require.Equal(t, []byte("ftyp"), byts[4:8])
+}
+
+const testAPIRTSPSDP = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s= \r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"t=0 0\r\n" +
+	"m=video 0 RTP/AVP 96\r\n" +
+	"a=control:trackID=0\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=fmtp:96 packetization-mode=1; profile-level-id=42C028; " +
+	"sprop-parameter-sets=Z0LAKNkAeAIn5YQAAAMABAAAAwDwPGDJIA==,CAYHCA==\r\n"
+
+// sdpSecretLines returns the lines of a SDP that contain secrets.
+func sdpSecretLines(sdp string) []string {
+	var ret []string
+	for _, line := range strings.Split(sdp, "\r\n") {
+		if strings.HasPrefix(line, "a=fingerprint:") ||
+			strings.HasPrefix(line, "a=ice-ufrag:") ||
+			strings.HasPrefix(line, "a=ice-pwd:") {
+			ret = append(ret, line)
+		}
+	}
+	return ret
+}
+
 func TestAPIProtocolListGet(t *testing.T) {
 	serverCertFpath, err := test.CreateTempFile(test.TLSCertPub)
 	require.NoError(t, err)
@@ -614,6 +970,7 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"path":          "mypath",
 							"query":         "key=val",
 							"remoteAddr":    out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteAddr"],
+							"sdp":           testAPIRTSPSDP,
 							"state":         "publish",
 							"transport":     "UDP",
 						},
@@ -648,6 +1005,7 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"path":          "mypath",
 							"query":         "key=val",
 							"remoteAddr":    out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteAddr"],
+							"sdp":           testAPIRTSPSDP,
 							"state":         "publish",
 							"transport":     "TCP",
 						},
@@ -705,16 +1063,31 @@ func TestAPIProtocolListGet(t *testing.T) {
 				}, out1)
 
 			case "webrtc":
+				item := out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})
+
+				for _, key := range []string{"offer", "answer"} {
+					require.Equal(t, []string{
+						"a=fingerprint:redacted",
+						"a=ice-ufrag:redacted",
+						"a=ice-pwd:redacted",
+						"a=ice-ufrag:redacted",
+						"a=ice-pwd:redacted",
+					}, sdpSecretLines(item[key].(string)))
+				}
+
 				require.Equal(t, map[string]interface{}{
 					"itemCount": float64(1),
 					"pageCount": float64(1),
 					"items": []interface{}{
 						map[string]interface{}{
+							"bandwidthEstimate":         out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["bandwidthEstimate"],
 							"bytesReceived":             out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["bytesReceived"],
 							"bytesSent":                 out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["bytesSent"],
 							"created":                   out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["created"],
 							"id":                        out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["id"],
+							"answer":                    item["answer"],
 							"localCandidate":            out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["localCandidate"],
+							"offer":                     item["offer"],
 							"path":                      "mypath",
 							"peerConnectionEstablished": true,
 							"query":                     "key=val",
@@ -1136,3 +1509,58 @@ func TestAPIProtocolKickNotFound(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIPathsGetWaitingSource(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  mypath:\n" +
+		"    source: rtsp://localhost:8555/nonexisting\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	var out map[string]interface{}
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+	require.Equal(t, false, out["ready"])
+	require.Equal(t, "waiting", out["sourceState"])
+	require.Equal(t, []interface{}{}, out["trackDetails"])
+}
+
+func TestAPIConfigLogLevel(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"logLevel: info\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	var out map[string]interface{}
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/config/loglevel", nil, &out)
+	require.Equal(t, map[string]interface{}{"logLevel": "info"}, out)
+
+	httpRequest(t, hc, http.MethodPatch, "http://localhost:9997/v3/config/loglevel", map[string]interface{}{
+		"logLevel": "debug",
+	}, nil)
+
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/config/loglevel", nil, &out)
+	require.Equal(t, map[string]interface{}{"logLevel": "debug"}, out)
+	require.Equal(t, logger.Debug, p.logger.Level())
+
+	// the configuration is left untouched
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/config/global/get", nil, &out)
+	require.Equal(t, "info", out["logLevel"])
+
+	func() {
+		req, err := http.NewRequest(http.MethodPatch, "http://localhost:9997/v3/config/loglevel",
+			bytes.NewReader([]byte(`{"logLevel":"verbose"}`)))
+		require.NoError(t, err)
+
+		res, err := hc.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		checkError(t, "invalid log level: 'verbose'", res.Body)
+	}()
+}
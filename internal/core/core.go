@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -17,9 +18,11 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/bluenviron/mediamtx/internal/api"
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/confwatcher"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/grpcapi"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/metrics"
 	"github.com/bluenviron/mediamtx/internal/playback"
@@ -47,14 +50,31 @@ func gatherCleanerEntries(paths map[string]*conf.Path) []record.CleanerEntry {
 	out := make(map[record.CleanerEntry]struct{})
 
 	for _, pa := range paths {
-		if pa.Record && pa.RecordDeleteAfter != 0 {
+		if !pa.Record {
+			continue
+		}
+
+		if pa.RecordDeleteAfter != 0 || pa.RecordMaxSize != 0 {
 			entry := record.CleanerEntry{
 				Path:        pa.RecordPath,
 				Format:      pa.RecordFormat,
 				DeleteAfter: time.Duration(pa.RecordDeleteAfter),
+				MaxSize:     uint64(pa.RecordMaxSize),
 			}
 			out[entry] = struct{}{}
 		}
+
+		for _, ro := range pa.RecordOutputs {
+			if ro.RecordDeleteAfter != 0 || ro.RecordMaxSize != 0 {
+				entry := record.CleanerEntry{
+					Path:        ro.RecordPath,
+					Format:      ro.RecordFormat,
+					DeleteAfter: time.Duration(ro.RecordDeleteAfter),
+					MaxSize:     uint64(ro.RecordMaxSize),
+				}
+				out[entry] = struct{}{}
+			}
+		}
 	}
 
 	out2 := make([]record.CleanerEntry, len(out))
@@ -69,42 +89,63 @@ func gatherCleanerEntries(paths map[string]*conf.Path) []record.CleanerEntry {
 		if out2[i].Path != out2[j].Path {
 			return out2[i].Path < out2[j].Path
 		}
-		return out2[i].DeleteAfter < out2[j].DeleteAfter
+		if out2[i].DeleteAfter != out2[j].DeleteAfter {
+			return out2[i].DeleteAfter < out2[j].DeleteAfter
+		}
+		return out2[i].MaxSize < out2[j].MaxSize
 	})
 
 	return out2
 }
 
+type apiRecordRetentionSetReq struct {
+	name        string
+	deleteAfter *conf.StringDuration
+	maxSize     *conf.StringSize
+}
+
 var cli struct {
-	Version  bool   `help:"print version"`
-	Confpath string `arg:"" default:""`
+	Version     bool   `help:"print version"`
+	CheckConfig bool   `help:"validate the configuration and exit, without starting any server"`
+	Confpath    string `arg:"" default:""`
 }
 
 // Core is an instance of MediaMTX.
 type Core struct {
-	ctx             context.Context
-	ctxCancel       func()
-	confPath        string
-	conf            *conf.Conf
-	logger          *logger.Logger
-	externalCmdPool *externalcmd.Pool
-	metrics         *metrics.Metrics
-	pprof           *pprof.PPROF
-	recordCleaner   *record.Cleaner
-	playbackServer  *playback.Server
-	pathManager     *pathManager
-	rtspServer      *rtsp.Server
-	rtspsServer     *rtsp.Server
-	rtmpServer      *rtmp.Server
-	rtmpsServer     *rtmp.Server
-	hlsServer       *hls.Server
-	webRTCServer    *webrtc.Server
-	srtServer       *srt.Server
-	api             *api.API
-	confWatcher     *confwatcher.ConfWatcher
+	ctx               context.Context
+	ctxCancel         func()
+	confPath          string
+	conf              *conf.Conf
+	logger            *logger.Logger
+	externalCmdPool   *externalcmd.Pool
+	readerQueueBudget *asyncwriter.GrowthBudget
+	metrics           *metrics.Metrics
+	pprof             *pprof.PPROF
+	recordCleaner     *record.Cleaner
+	playbackServer    *playback.Server
+	pathManager       *pathManager
+	rtspServer        *rtsp.Server
+	rtspsServer       *rtsp.Server
+	rtmpServer        *rtmp.Server
+	rtmpsServer       *rtmp.Server
+	hlsServer         *hls.Server
+	webRTCServer      *webrtc.Server
+	srtServer         *srt.Server
+	api               *api.API
+	grpcAPI           *grpcapi.Server
+	confWatcher       *confwatcher.ConfWatcher
+
+	// retention rules set by the API without changing the configuration
+	recordRetentionOverrides map[string]*conf.Path
+
+	// true when all resources have been created and are not being reloaded or closed
+	ready atomic.Bool
 
 	// in
-	chAPIConfigSet chan *conf.Conf
+	chAPIConfigSet          chan *conf.Conf
+	chAPIRecordRetentionSet chan apiRecordRetentionSetReq
+	chAPILogLevelGet        chan chan conf.LogLevel
+	chAPILogLevelSet        chan conf.LogLevel
 
 	// out
 	done chan struct{}
@@ -139,10 +180,13 @@ func New(args []string) (*Core, bool) {
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
 	p := &Core{
-		ctx:            ctx,
-		ctxCancel:      ctxCancel,
-		chAPIConfigSet: make(chan *conf.Conf),
-		done:           make(chan struct{}),
+		ctx:                     ctx,
+		ctxCancel:               ctxCancel,
+		chAPIConfigSet:          make(chan *conf.Conf),
+		chAPIRecordRetentionSet: make(chan apiRecordRetentionSetReq),
+		chAPILogLevelGet:        make(chan chan conf.LogLevel),
+		chAPILogLevelSet:        make(chan conf.LogLevel),
+		done:                    make(chan struct{}),
 	}
 
 	p.conf, p.confPath, err = conf.Load(cli.Confpath, defaultConfPaths)
@@ -151,6 +195,17 @@ func New(args []string) (*Core, bool) {
 		return nil, false
 	}
 
+	if cli.CheckConfig {
+		err = p.conf.CheckFiles()
+		if err != nil {
+			fmt.Printf("ERR: %s\n", err)
+			return nil, false
+		}
+
+		fmt.Println("configuration is valid")
+		os.Exit(0)
+	}
+
 	err = p.createResources(true)
 	if err != nil {
 		if p.logger != nil {
@@ -162,6 +217,8 @@ func New(args []string) (*Core, bool) {
 		return nil, false
 	}
 
+	p.ready.Store(true)
+
 	go p.run()
 
 	return p, true
@@ -223,6 +280,17 @@ outer:
 				break outer
 			}
 
+		case req := <-p.chAPIRecordRetentionSet:
+			p.Log(logger.Info, "changing recording retention rules of '%s' (API request)", req.name)
+			p.setRecordRetention(req)
+
+		case res := <-p.chAPILogLevelGet:
+			res <- conf.LogLevel(p.logger.Level())
+
+		case level := <-p.chAPILogLevelSet:
+			p.Log(logger.Info, "changing log level (API request)")
+			p.logger.SetLevel(logger.Level(level))
+
 		case <-interrupt:
 			p.Log(logger.Info, "shutting down gracefully")
 			break outer
@@ -232,6 +300,8 @@ outer:
 		}
 	}
 
+	p.ready.Store(false)
+
 	p.ctxCancel()
 
 	p.closeResources(nil, false)
@@ -276,14 +346,31 @@ func (p *Core) createResources(initial bool) error {
 		gin.SetMode(gin.ReleaseMode)
 
 		p.externalCmdPool = externalcmd.NewPool()
+		p.readerQueueBudget = &asyncwriter.GrowthBudget{}
 	}
 
-	if p.conf.Metrics &&
+	p.readerQueueBudget.SetLimit(uint64(p.conf.ReaderQueueMaxTotalGrowth))
+
+	if (p.conf.Metrics || p.conf.MetricsOTLP) &&
 		p.metrics == nil {
 		i := &metrics.Metrics{
-			Address:     p.conf.MetricsAddress,
-			ReadTimeout: p.conf.ReadTimeout,
-			Parent:      p,
+			ReadTimeout:               p.conf.ReadTimeout,
+			TCPKeepalivePeriod:        p.conf.TCPKeepalivePeriod,
+			PathLabels:                p.conf.MetricsPathLabels,
+			ExternalAuthenticationURL: p.conf.ControlExternalAuthenticationURL(),
+			User:                      p.conf.MetricsUser,
+			Pass:                      p.conf.MetricsPass,
+			IPs:                       p.conf.MetricsIPs,
+			OTLPInterval:              p.conf.MetricsOTLPInterval,
+			OTLPHeaders:               p.conf.MetricsOTLPHeaders,
+			OTLPFingerprint:           p.conf.MetricsOTLPFingerprint,
+			Parent:                    p,
+		}
+		if p.conf.Metrics {
+			i.Address = p.conf.MetricsAddress
+		}
+		if p.conf.MetricsOTLP {
+			i.OTLPEndpoint = p.conf.MetricsOTLPEndpoint
 		}
 		err := i.Initialize()
 		if err != nil {
@@ -295,9 +382,14 @@ func (p *Core) createResources(initial bool) error {
 	if p.conf.PPROF &&
 		p.pprof == nil {
 		i := &pprof.PPROF{
-			Address:     p.conf.PPROFAddress,
-			ReadTimeout: p.conf.ReadTimeout,
-			Parent:      p,
+			Address:                   p.conf.PPROFAddress,
+			ReadTimeout:               p.conf.ReadTimeout,
+			TCPKeepalivePeriod:        p.conf.TCPKeepalivePeriod,
+			ExternalAuthenticationURL: p.conf.ControlExternalAuthenticationURL(),
+			User:                      p.conf.PPROFUser,
+			Pass:                      p.conf.PPROFPass,
+			IPs:                       p.conf.PPROFIPs,
+			Parent:                    p,
 		}
 		err := i.Initialize()
 		if err != nil {
@@ -306,23 +398,19 @@ func (p *Core) createResources(initial bool) error {
 		p.pprof = i
 	}
 
-	cleanerEntries := gatherCleanerEntries(p.conf.Paths)
-	if len(cleanerEntries) != 0 &&
-		p.recordCleaner == nil {
-		p.recordCleaner = &record.Cleaner{
-			Entries: cleanerEntries,
-			Parent:  p,
-		}
-		p.recordCleaner.Initialize()
-	}
+	p.updateRecordCleaner()
 
 	if p.conf.Playback &&
 		p.playbackServer == nil {
 		i := &playback.Server{
-			Address:     p.conf.PlaybackAddress,
-			ReadTimeout: p.conf.ReadTimeout,
-			PathConfs:   p.conf.Paths,
-			Parent:      p,
+			Address:            p.conf.PlaybackAddress,
+			ReadTimeout:        p.conf.ReadTimeout,
+			TCPKeepalivePeriod: p.conf.TCPKeepalivePeriod,
+			PathConfs:          p.conf.Paths,
+			PathDefaults:       &p.conf.PathDefaults,
+			AutoDiscover:       p.conf.PlaybackAutoDiscover,
+			AutoDiscoverPeriod: p.conf.PlaybackAutoDiscoverPeriod,
+			Parent:             p,
 		}
 		err := i.Initialize()
 		if err != nil {
@@ -341,6 +429,8 @@ func (p *Core) createResources(initial bool) error {
 			writeTimeout:              p.conf.WriteTimeout,
 			writeQueueSize:            p.conf.WriteQueueSize,
 			udpMaxPayloadSize:         p.conf.UDPMaxPayloadSize,
+			maxTotalIngestBitrate:     p.conf.MaxTotalIngestBitrate,
+			maxTotalIngestGracePeriod: p.conf.MaxTotalIngestGracePeriod,
 			pathConfs:                 p.conf.Paths,
 			externalCmdPool:           p.externalCmdPool,
 			parent:                    p,
@@ -359,12 +449,21 @@ func (p *Core) createResources(initial bool) error {
 		_, useUDP := p.conf.Protocols[conf.Protocol(gortsplib.TransportUDP)]
 		_, useMulticast := p.conf.Protocols[conf.Protocol(gortsplib.TransportUDPMulticast)]
 
+		webSocketAddress := ""
+		if p.conf.RTSPWebSocket {
+			webSocketAddress = p.conf.RTSPWebSocketAddress
+		}
+
 		i := &rtsp.Server{
 			Address:             p.conf.RTSPAddress,
 			AuthMethods:         p.conf.AuthMethods,
 			ReadTimeout:         p.conf.ReadTimeout,
+			TCPKeepalivePeriod:  p.conf.TCPKeepalivePeriod,
 			WriteTimeout:        p.conf.WriteTimeout,
 			WriteQueueSize:      p.conf.WriteQueueSize,
+			MaxWriteQueueSize:   p.conf.MaxReaderQueueSize(),
+			ReaderQueueBudget:   p.readerQueueBudget,
+			DSCP:                p.conf.DSCP,
 			UseUDP:              useUDP,
 			UseMulticast:        useMulticast,
 			RTPAddress:          p.conf.RTPAddress,
@@ -376,7 +475,14 @@ func (p *Core) createResources(initial bool) error {
 			ServerCert:          "",
 			ServerKey:           "",
 			RTSPAddress:         p.conf.RTSPAddress,
+			WebSocketAddress:    webSocketAddress,
 			Protocols:           p.conf.Protocols,
+			TransportsByIP:      p.conf.RTSPTransportsByIP,
+			SenderReportPeriod:  p.conf.RTSPSenderReportPeriod,
+			ConnRateLimit:       p.conf.RTSPConnRateLimit,
+			RequestRateLimit:    p.conf.RTSPRequestRateLimit,
+			RateLimitExemptIPs:  p.conf.RTSPRateLimitExemptIPs,
+			MaxSessions:         p.conf.RTSPMaxSessions,
 			RunOnConnect:        p.conf.RunOnConnect,
 			RunOnConnectRestart: p.conf.RunOnConnectRestart,
 			RunOnDisconnect:     p.conf.RunOnDisconnect,
@@ -403,8 +509,12 @@ func (p *Core) createResources(initial bool) error {
 			Address:             p.conf.RTSPSAddress,
 			AuthMethods:         p.conf.AuthMethods,
 			ReadTimeout:         p.conf.ReadTimeout,
+			TCPKeepalivePeriod:  p.conf.TCPKeepalivePeriod,
 			WriteTimeout:        p.conf.WriteTimeout,
 			WriteQueueSize:      p.conf.WriteQueueSize,
+			MaxWriteQueueSize:   p.conf.MaxReaderQueueSize(),
+			ReaderQueueBudget:   p.readerQueueBudget,
+			DSCP:                p.conf.DSCP,
 			UseUDP:              false,
 			UseMulticast:        false,
 			RTPAddress:          "",
@@ -415,8 +525,17 @@ func (p *Core) createResources(initial bool) error {
 			IsTLS:               true,
 			ServerCert:          p.conf.ServerCert,
 			ServerKey:           p.conf.ServerKey,
+			TLSMinVersion:       p.conf.TLSMinVersion,
+			TLSCipherSuites:     p.conf.TLSCipherSuites,
 			RTSPAddress:         p.conf.RTSPAddress,
+			WebSocketAddress:    "",
 			Protocols:           p.conf.Protocols,
+			TransportsByIP:      p.conf.RTSPTransportsByIP,
+			SenderReportPeriod:  p.conf.RTSPSenderReportPeriod,
+			ConnRateLimit:       p.conf.RTSPConnRateLimit,
+			RequestRateLimit:    p.conf.RTSPRequestRateLimit,
+			RateLimitExemptIPs:  p.conf.RTSPRateLimitExemptIPs,
+			MaxSessions:         p.conf.RTSPMaxSessions,
 			RunOnConnect:        p.conf.RunOnConnect,
 			RunOnConnectRestart: p.conf.RunOnConnectRestart,
 			RunOnDisconnect:     p.conf.RunOnDisconnect,
@@ -442,8 +561,10 @@ func (p *Core) createResources(initial bool) error {
 		i := &rtmp.Server{
 			Address:             p.conf.RTMPAddress,
 			ReadTimeout:         p.conf.ReadTimeout,
+			TCPKeepalivePeriod:  p.conf.TCPKeepalivePeriod,
 			WriteTimeout:        p.conf.WriteTimeout,
 			WriteQueueSize:      p.conf.WriteQueueSize,
+			ReaderQueueBudget:   p.readerQueueBudget,
 			IsTLS:               false,
 			ServerCert:          "",
 			ServerKey:           "",
@@ -451,6 +572,9 @@ func (p *Core) createResources(initial bool) error {
 			RunOnConnect:        p.conf.RunOnConnect,
 			RunOnConnectRestart: p.conf.RunOnConnectRestart,
 			RunOnDisconnect:     p.conf.RunOnDisconnect,
+			PublishPathTemplate: p.conf.RTMPPublishPathTemplate,
+			HandshakeTimeout:    p.conf.RTMPHandshakeTimeout,
+			MaxConns:            p.conf.RTMPMaxConns,
 			ExternalCmdPool:     p.externalCmdPool,
 			PathManager:         p.pathManager,
 			Parent:              p,
@@ -473,15 +597,22 @@ func (p *Core) createResources(initial bool) error {
 		i := &rtmp.Server{
 			Address:             p.conf.RTMPSAddress,
 			ReadTimeout:         p.conf.ReadTimeout,
+			TCPKeepalivePeriod:  p.conf.TCPKeepalivePeriod,
 			WriteTimeout:        p.conf.WriteTimeout,
 			WriteQueueSize:      p.conf.WriteQueueSize,
+			ReaderQueueBudget:   p.readerQueueBudget,
 			IsTLS:               true,
 			ServerCert:          p.conf.RTMPServerCert,
 			ServerKey:           p.conf.RTMPServerKey,
+			TLSMinVersion:       p.conf.TLSMinVersion,
+			TLSCipherSuites:     p.conf.TLSCipherSuites,
 			RTSPAddress:         p.conf.RTSPAddress,
 			RunOnConnect:        p.conf.RunOnConnect,
 			RunOnConnectRestart: p.conf.RunOnConnectRestart,
 			RunOnDisconnect:     p.conf.RunOnDisconnect,
+			PublishPathTemplate: p.conf.RTMPPublishPathTemplate,
+			HandshakeTimeout:    p.conf.RTMPHandshakeTimeout,
+			MaxConns:            p.conf.RTMPMaxConns,
 			ExternalCmdPool:     p.externalCmdPool,
 			PathManager:         p.pathManager,
 			Parent:              p,
@@ -504,18 +635,25 @@ func (p *Core) createResources(initial bool) error {
 			Encryption:                p.conf.HLSEncryption,
 			ServerKey:                 p.conf.HLSServerKey,
 			ServerCert:                p.conf.HLSServerCert,
+			TLSMinVersion:             p.conf.TLSMinVersion,
+			TLSCipherSuites:           p.conf.TLSCipherSuites,
 			ExternalAuthenticationURL: p.conf.ExternalAuthenticationURL,
 			AlwaysRemux:               p.conf.HLSAlwaysRemux,
 			Variant:                   p.conf.HLSVariant,
 			SegmentCount:              p.conf.HLSSegmentCount,
+			WindowDuration:            p.conf.HLSWindowDuration,
 			SegmentDuration:           p.conf.HLSSegmentDuration,
 			PartDuration:              p.conf.HLSPartDuration,
 			SegmentMaxSize:            p.conf.HLSSegmentMaxSize,
+			BurstOnConnect:            p.conf.HLSBurstOnConnect,
+			TimestampWrap:             p.conf.HLSTimestampWrap,
 			AllowOrigin:               p.conf.HLSAllowOrigin,
 			TrustedProxies:            p.conf.HLSTrustedProxies,
 			Directory:                 p.conf.HLSDirectory,
 			ReadTimeout:               p.conf.ReadTimeout,
+			TCPKeepalivePeriod:        p.conf.TCPKeepalivePeriod,
 			WriteQueueSize:            p.conf.WriteQueueSize,
+			ReaderQueueBudget:         p.readerQueueBudget,
 			PathManager:               p.pathManager,
 			Parent:                    p,
 		}
@@ -539,15 +677,29 @@ func (p *Core) createResources(initial bool) error {
 			Encryption:            p.conf.WebRTCEncryption,
 			ServerKey:             p.conf.WebRTCServerKey,
 			ServerCert:            p.conf.WebRTCServerCert,
+			TLSMinVersion:         p.conf.TLSMinVersion,
+			TLSCipherSuites:       p.conf.TLSCipherSuites,
 			AllowOrigin:           p.conf.WebRTCAllowOrigin,
 			TrustedProxies:        p.conf.WebRTCTrustedProxies,
 			ReadTimeout:           p.conf.ReadTimeout,
+			TCPKeepalivePeriod:    p.conf.TCPKeepalivePeriod,
 			WriteQueueSize:        p.conf.WriteQueueSize,
+			ReaderQueueBudget:     p.readerQueueBudget,
+			DSCP:                  p.conf.DSCP,
 			LocalUDPAddress:       p.conf.WebRTCLocalUDPAddress,
+			LocalUDPPortRange:     p.conf.WebRTCLocalUDPPortRange,
 			LocalTCPAddress:       p.conf.WebRTCLocalTCPAddress,
 			IPsFromInterfaces:     p.conf.WebRTCIPsFromInterfaces,
 			IPsFromInterfacesList: p.conf.WebRTCIPsFromInterfacesList,
 			AdditionalHosts:       p.conf.WebRTCAdditionalHosts,
+			ICEInterfaceFilter:    p.conf.WebRTCICEInterfaceFilter,
+			ICEUDPMuxOnly:         p.conf.WebRTCICEUDPMuxOnly,
+			ICEDisableMDNS:        p.conf.WebRTCICEDisableMDNS,
+			ICEDisconnectTimeout:  p.conf.WebRTCICEDisconnectTimeout,
+			ICERecoveryTimeout:    p.conf.WebRTCICERecoveryTimeout,
+			NACKBufferSize:        p.conf.WebRTCNACKBufferSize,
+			NACKMaxAge:            p.conf.WebRTCNACKMaxAge,
+			MaxPayloadSize:        p.conf.WebRTCMaxPayloadSize,
 			ICEServers:            p.conf.WebRTCICEServers2,
 			ExternalCmdPool:       p.externalCmdPool,
 			PathManager:           p.pathManager,
@@ -572,7 +724,11 @@ func (p *Core) createResources(initial bool) error {
 			ReadTimeout:         p.conf.ReadTimeout,
 			WriteTimeout:        p.conf.WriteTimeout,
 			WriteQueueSize:      p.conf.WriteQueueSize,
+			ReaderQueueBudget:   p.readerQueueBudget,
 			UDPMaxPayloadSize:   p.conf.UDPMaxPayloadSize,
+			DSCP:                p.conf.DSCP,
+			HandshakeTimeout:    p.conf.SRTHandshakeTimeout,
+			MaxConns:            p.conf.SRTMaxConns,
 			RunOnConnect:        p.conf.RunOnConnect,
 			RunOnConnectRestart: p.conf.RunOnConnectRestart,
 			RunOnDisconnect:     p.conf.RunOnDisconnect,
@@ -594,18 +750,21 @@ func (p *Core) createResources(initial bool) error {
 	if p.conf.API &&
 		p.api == nil {
 		i := &api.API{
-			Address:      p.conf.APIAddress,
-			ReadTimeout:  p.conf.ReadTimeout,
-			Conf:         p.conf,
-			PathManager:  p.pathManager,
-			RTSPServer:   p.rtspServer,
-			RTSPSServer:  p.rtspsServer,
-			RTMPServer:   p.rtmpServer,
-			RTMPSServer:  p.rtmpsServer,
-			HLSServer:    p.hlsServer,
-			WebRTCServer: p.webRTCServer,
-			SRTServer:    p.srtServer,
-			Parent:       p,
+			Address:            p.conf.APIAddress,
+			ReadTimeout:        p.conf.ReadTimeout,
+			TCPKeepalivePeriod: p.conf.TCPKeepalivePeriod,
+			Conf:               p.conf,
+			ConfPath:           p.confPath,
+			PathManager:        p.pathManager,
+			RTSPServer:         p.rtspServer,
+			RTSPSServer:        p.rtspsServer,
+			RTMPServer:         p.rtmpServer,
+			RTMPSServer:        p.rtmpsServer,
+			HLSServer:          p.hlsServer,
+			WebRTCServer:       p.webRTCServer,
+			SRTServer:          p.srtServer,
+			PlaybackServer:     p.playbackServer,
+			Parent:             p,
 		}
 		err := i.Initialize()
 		if err != nil {
@@ -614,6 +773,31 @@ func (p *Core) createResources(initial bool) error {
 		p.api = i
 	}
 
+	if p.conf.GRPCAPI &&
+		p.grpcAPI == nil {
+		i := &grpcapi.Server{
+			Address:                   p.conf.GRPCAPIAddress,
+			Encryption:                p.conf.GRPCAPIEncryption,
+			ServerKey:                 p.conf.GRPCAPIServerKey,
+			ServerCert:                p.conf.GRPCAPIServerCert,
+			ReadTimeout:               p.conf.ReadTimeout,
+			WriteQueueSize:            p.conf.WriteQueueSize,
+			ReaderQueueBudget:         p.readerQueueBudget,
+			ExternalAuthenticationURL: p.conf.ControlExternalAuthenticationURL(),
+			User:                      p.conf.APIUser,
+			Pass:                      p.conf.APIPass,
+			IPs:                       p.conf.APIIPs,
+			ExternalCmdPool:           p.externalCmdPool,
+			PathManager:               p.pathManager,
+			Parent:                    p,
+		}
+		err := i.Initialize()
+		if err != nil {
+			return err
+		}
+		p.grpcAPI = i
+	}
+
 	if initial && p.confPath != "" {
 		p.confWatcher, err = confwatcher.New(p.confPath)
 		if err != nil {
@@ -633,23 +817,43 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 	closeMetrics := newConf == nil ||
 		newConf.Metrics != p.conf.Metrics ||
 		newConf.MetricsAddress != p.conf.MetricsAddress ||
+		!reflect.DeepEqual(newConf.MetricsPathLabels, p.conf.MetricsPathLabels) ||
+		newConf.ControlExternalAuthenticationURL() != p.conf.ControlExternalAuthenticationURL() ||
+		newConf.MetricsUser != p.conf.MetricsUser ||
+		newConf.MetricsPass != p.conf.MetricsPass ||
+		!reflect.DeepEqual(newConf.MetricsIPs, p.conf.MetricsIPs) ||
+		newConf.MetricsOTLP != p.conf.MetricsOTLP ||
+		newConf.MetricsOTLPEndpoint != p.conf.MetricsOTLPEndpoint ||
+		newConf.MetricsOTLPInterval != p.conf.MetricsOTLPInterval ||
+		!reflect.DeepEqual(newConf.MetricsOTLPHeaders, p.conf.MetricsOTLPHeaders) ||
+		newConf.MetricsOTLPFingerprint != p.conf.MetricsOTLPFingerprint ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		closeLogger
 
 	closePPROF := newConf == nil ||
 		newConf.PPROF != p.conf.PPROF ||
 		newConf.PPROFAddress != p.conf.PPROFAddress ||
+		newConf.ControlExternalAuthenticationURL() != p.conf.ControlExternalAuthenticationURL() ||
+		newConf.PPROFUser != p.conf.PPROFUser ||
+		newConf.PPROFPass != p.conf.PPROFPass ||
+		!reflect.DeepEqual(newConf.PPROFIPs, p.conf.PPROFIPs) ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		closeLogger
 
 	closeRecorderCleaner := newConf == nil ||
-		!reflect.DeepEqual(gatherCleanerEntries(newConf.Paths), gatherCleanerEntries(p.conf.Paths)) ||
+		len(gatherCleanerEntries(newConf.Paths)) == 0 ||
 		closeLogger
 
 	closePlaybackServer := newConf == nil ||
 		newConf.Playback != p.conf.Playback ||
 		newConf.PlaybackAddress != p.conf.PlaybackAddress ||
+		newConf.PlaybackAutoDiscover != p.conf.PlaybackAutoDiscover ||
+		newConf.PlaybackAutoDiscoverPeriod != p.conf.PlaybackAutoDiscoverPeriod ||
+		(newConf.PlaybackAutoDiscover && !reflect.DeepEqual(newConf.PathDefaults, p.conf.PathDefaults)) ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		closeLogger
 	if !closePlaybackServer && p.playbackServer != nil && !reflect.DeepEqual(newConf.Paths, p.conf.Paths) {
 		p.playbackServer.ReloadPathConfs(newConf.Paths)
@@ -660,10 +864,13 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.ExternalAuthenticationURL != p.conf.ExternalAuthenticationURL ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
 		!reflect.DeepEqual(newConf.AuthMethods, p.conf.AuthMethods) ||
+		newConf.RTSPSenderReportPeriod != p.conf.RTSPSenderReportPeriod ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
 		newConf.WriteTimeout != p.conf.WriteTimeout ||
 		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
 		newConf.UDPMaxPayloadSize != p.conf.UDPMaxPayloadSize ||
+		newConf.MaxTotalIngestBitrate != p.conf.MaxTotalIngestBitrate ||
+		newConf.MaxTotalIngestGracePeriod != p.conf.MaxTotalIngestGracePeriod ||
 		closeMetrics ||
 		closeLogger
 	if !closePathManager && !reflect.DeepEqual(newConf.Paths, p.conf.Paths) {
@@ -674,10 +881,20 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.RTSP != p.conf.RTSP ||
 		newConf.Encryption != p.conf.Encryption ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
+		newConf.RTSPWebSocket != p.conf.RTSPWebSocket ||
+		newConf.RTSPWebSocketAddress != p.conf.RTSPWebSocketAddress ||
 		!reflect.DeepEqual(newConf.AuthMethods, p.conf.AuthMethods) ||
+		newConf.RTSPSenderReportPeriod != p.conf.RTSPSenderReportPeriod ||
+		newConf.RTSPConnRateLimit != p.conf.RTSPConnRateLimit ||
+		newConf.RTSPRequestRateLimit != p.conf.RTSPRequestRateLimit ||
+		!reflect.DeepEqual(newConf.RTSPRateLimitExemptIPs, p.conf.RTSPRateLimitExemptIPs) ||
+		newConf.RTSPMaxSessions != p.conf.RTSPMaxSessions ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		newConf.WriteTimeout != p.conf.WriteTimeout ||
 		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
+		newConf.MaxReaderQueueSize() != p.conf.MaxReaderQueueSize() ||
+		newConf.DSCP != p.conf.DSCP ||
 		!reflect.DeepEqual(newConf.Protocols, p.conf.Protocols) ||
 		newConf.RTPAddress != p.conf.RTPAddress ||
 		newConf.RTCPAddress != p.conf.RTCPAddress ||
@@ -686,6 +903,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.MulticastRTCPPort != p.conf.MulticastRTCPPort ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
 		!reflect.DeepEqual(newConf.Protocols, p.conf.Protocols) ||
+		!reflect.DeepEqual(newConf.RTSPTransportsByIP, p.conf.RTSPTransportsByIP) ||
 		newConf.RunOnConnect != p.conf.RunOnConnect ||
 		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
 		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
@@ -698,13 +916,24 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.Encryption != p.conf.Encryption ||
 		newConf.RTSPSAddress != p.conf.RTSPSAddress ||
 		!reflect.DeepEqual(newConf.AuthMethods, p.conf.AuthMethods) ||
+		newConf.RTSPSenderReportPeriod != p.conf.RTSPSenderReportPeriod ||
+		newConf.RTSPConnRateLimit != p.conf.RTSPConnRateLimit ||
+		newConf.RTSPRequestRateLimit != p.conf.RTSPRequestRateLimit ||
+		!reflect.DeepEqual(newConf.RTSPRateLimitExemptIPs, p.conf.RTSPRateLimitExemptIPs) ||
+		newConf.RTSPMaxSessions != p.conf.RTSPMaxSessions ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		newConf.WriteTimeout != p.conf.WriteTimeout ||
 		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
+		newConf.MaxReaderQueueSize() != p.conf.MaxReaderQueueSize() ||
+		newConf.DSCP != p.conf.DSCP ||
 		newConf.ServerCert != p.conf.ServerCert ||
 		newConf.ServerKey != p.conf.ServerKey ||
+		newConf.TLSMinVersion != p.conf.TLSMinVersion ||
+		!reflect.DeepEqual(newConf.TLSCipherSuites, p.conf.TLSCipherSuites) ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
 		!reflect.DeepEqual(newConf.Protocols, p.conf.Protocols) ||
+		!reflect.DeepEqual(newConf.RTSPTransportsByIP, p.conf.RTSPTransportsByIP) ||
 		newConf.RunOnConnect != p.conf.RunOnConnect ||
 		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
 		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
@@ -717,12 +946,16 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.RTMPEncryption != p.conf.RTMPEncryption ||
 		newConf.RTMPAddress != p.conf.RTMPAddress ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		newConf.WriteTimeout != p.conf.WriteTimeout ||
 		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
 		newConf.RunOnConnect != p.conf.RunOnConnect ||
 		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
 		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
+		newConf.RTMPPublishPathTemplate != p.conf.RTMPPublishPathTemplate ||
+		newConf.RTMPHandshakeTimeout != p.conf.RTMPHandshakeTimeout ||
+		newConf.RTMPMaxConns != p.conf.RTMPMaxConns ||
 		closeMetrics ||
 		closePathManager ||
 		closeLogger
@@ -732,14 +965,20 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.RTMPEncryption != p.conf.RTMPEncryption ||
 		newConf.RTMPSAddress != p.conf.RTMPSAddress ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		newConf.WriteTimeout != p.conf.WriteTimeout ||
 		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
 		newConf.RTMPServerCert != p.conf.RTMPServerCert ||
 		newConf.RTMPServerKey != p.conf.RTMPServerKey ||
+		newConf.TLSMinVersion != p.conf.TLSMinVersion ||
+		!reflect.DeepEqual(newConf.TLSCipherSuites, p.conf.TLSCipherSuites) ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
 		newConf.RunOnConnect != p.conf.RunOnConnect ||
 		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
 		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
+		newConf.RTMPPublishPathTemplate != p.conf.RTMPPublishPathTemplate ||
+		newConf.RTMPHandshakeTimeout != p.conf.RTMPHandshakeTimeout ||
+		newConf.RTMPMaxConns != p.conf.RTMPMaxConns ||
 		closeMetrics ||
 		closePathManager ||
 		closeLogger
@@ -750,17 +989,23 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.HLSEncryption != p.conf.HLSEncryption ||
 		newConf.HLSServerKey != p.conf.HLSServerKey ||
 		newConf.HLSServerCert != p.conf.HLSServerCert ||
+		newConf.TLSMinVersion != p.conf.TLSMinVersion ||
+		!reflect.DeepEqual(newConf.TLSCipherSuites, p.conf.TLSCipherSuites) ||
 		newConf.ExternalAuthenticationURL != p.conf.ExternalAuthenticationURL ||
 		newConf.HLSAlwaysRemux != p.conf.HLSAlwaysRemux ||
 		newConf.HLSVariant != p.conf.HLSVariant ||
 		newConf.HLSSegmentCount != p.conf.HLSSegmentCount ||
+		newConf.HLSWindowDuration != p.conf.HLSWindowDuration ||
 		newConf.HLSSegmentDuration != p.conf.HLSSegmentDuration ||
 		newConf.HLSPartDuration != p.conf.HLSPartDuration ||
 		newConf.HLSSegmentMaxSize != p.conf.HLSSegmentMaxSize ||
+		newConf.HLSBurstOnConnect != p.conf.HLSBurstOnConnect ||
+		newConf.HLSTimestampWrap != p.conf.HLSTimestampWrap ||
 		newConf.HLSAllowOrigin != p.conf.HLSAllowOrigin ||
 		!reflect.DeepEqual(newConf.HLSTrustedProxies, p.conf.HLSTrustedProxies) ||
 		newConf.HLSDirectory != p.conf.HLSDirectory ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
 		closePathManager ||
 		closeMetrics ||
@@ -772,15 +1017,28 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.WebRTCEncryption != p.conf.WebRTCEncryption ||
 		newConf.WebRTCServerKey != p.conf.WebRTCServerKey ||
 		newConf.WebRTCServerCert != p.conf.WebRTCServerCert ||
+		newConf.TLSMinVersion != p.conf.TLSMinVersion ||
+		!reflect.DeepEqual(newConf.TLSCipherSuites, p.conf.TLSCipherSuites) ||
 		newConf.WebRTCAllowOrigin != p.conf.WebRTCAllowOrigin ||
 		!reflect.DeepEqual(newConf.WebRTCTrustedProxies, p.conf.WebRTCTrustedProxies) ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
+		newConf.DSCP != p.conf.DSCP ||
 		newConf.WebRTCLocalUDPAddress != p.conf.WebRTCLocalUDPAddress ||
+		newConf.WebRTCLocalUDPPortRange != p.conf.WebRTCLocalUDPPortRange ||
 		newConf.WebRTCLocalTCPAddress != p.conf.WebRTCLocalTCPAddress ||
 		newConf.WebRTCIPsFromInterfaces != p.conf.WebRTCIPsFromInterfaces ||
 		!reflect.DeepEqual(newConf.WebRTCIPsFromInterfacesList, p.conf.WebRTCIPsFromInterfacesList) ||
 		!reflect.DeepEqual(newConf.WebRTCAdditionalHosts, p.conf.WebRTCAdditionalHosts) ||
+		!reflect.DeepEqual(newConf.WebRTCICEInterfaceFilter, p.conf.WebRTCICEInterfaceFilter) ||
+		newConf.WebRTCICEUDPMuxOnly != p.conf.WebRTCICEUDPMuxOnly ||
+		newConf.WebRTCICEDisableMDNS != p.conf.WebRTCICEDisableMDNS ||
+		newConf.WebRTCICEDisconnectTimeout != p.conf.WebRTCICEDisconnectTimeout ||
+		newConf.WebRTCICERecoveryTimeout != p.conf.WebRTCICERecoveryTimeout ||
+		newConf.WebRTCNACKBufferSize != p.conf.WebRTCNACKBufferSize ||
+		newConf.WebRTCNACKMaxAge != p.conf.WebRTCNACKMaxAge ||
+		newConf.WebRTCMaxPayloadSize != p.conf.WebRTCMaxPayloadSize ||
 		!reflect.DeepEqual(newConf.WebRTCICEServers2, p.conf.WebRTCICEServers2) ||
 		closeMetrics ||
 		closePathManager ||
@@ -789,11 +1047,14 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 	closeSRTServer := newConf == nil ||
 		newConf.SRT != p.conf.SRT ||
 		newConf.SRTAddress != p.conf.SRTAddress ||
+		newConf.SRTHandshakeTimeout != p.conf.SRTHandshakeTimeout ||
+		newConf.SRTMaxConns != p.conf.SRTMaxConns ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
 		newConf.WriteTimeout != p.conf.WriteTimeout ||
 		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
 		newConf.UDPMaxPayloadSize != p.conf.UDPMaxPayloadSize ||
+		newConf.DSCP != p.conf.DSCP ||
 		newConf.RunOnConnect != p.conf.RunOnConnect ||
 		newConf.RunOnConnectRestart != p.conf.RunOnConnectRestart ||
 		newConf.RunOnDisconnect != p.conf.RunOnDisconnect ||
@@ -804,6 +1065,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.API != p.conf.API ||
 		newConf.APIAddress != p.conf.APIAddress ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.TCPKeepalivePeriod != p.conf.TCPKeepalivePeriod ||
 		closePathManager ||
 		closeRTSPServer ||
 		closeRTSPSServer ||
@@ -811,6 +1073,22 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		closeHLSServer ||
 		closeWebRTCServer ||
 		closeSRTServer ||
+		closePlaybackServer ||
+		closeLogger
+
+	closeGRPCAPI := newConf == nil ||
+		newConf.GRPCAPI != p.conf.GRPCAPI ||
+		newConf.GRPCAPIAddress != p.conf.GRPCAPIAddress ||
+		newConf.GRPCAPIEncryption != p.conf.GRPCAPIEncryption ||
+		newConf.GRPCAPIServerKey != p.conf.GRPCAPIServerKey ||
+		newConf.GRPCAPIServerCert != p.conf.GRPCAPIServerCert ||
+		newConf.ReadTimeout != p.conf.ReadTimeout ||
+		newConf.WriteQueueSize != p.conf.WriteQueueSize ||
+		newConf.ControlExternalAuthenticationURL() != p.conf.ControlExternalAuthenticationURL() ||
+		!reflect.DeepEqual(newConf.APIUser, p.conf.APIUser) ||
+		!reflect.DeepEqual(newConf.APIPass, p.conf.APIPass) ||
+		!reflect.DeepEqual(newConf.APIIPs, p.conf.APIIPs) ||
+		closePathManager ||
 		closeLogger
 
 	if newConf == nil && p.confWatcher != nil {
@@ -818,6 +1096,11 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		p.confWatcher = nil
 	}
 
+	if closeGRPCAPI && p.grpcAPI != nil {
+		p.grpcAPI.Close()
+		p.grpcAPI = nil
+	}
+
 	if p.api != nil {
 		if closeAPI {
 			p.api.Close()
@@ -932,10 +1215,92 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 	}
 }
 
+func (p *Core) recordCleanerPaths() map[string]*conf.Path {
+	if len(p.recordRetentionOverrides) == 0 {
+		return p.conf.Paths
+	}
+
+	out := make(map[string]*conf.Path, len(p.conf.Paths))
+
+	for name, pathConf := range p.conf.Paths {
+		if override, ok := p.recordRetentionOverrides[name]; ok {
+			out[name] = override
+		} else {
+			out[name] = pathConf
+		}
+	}
+
+	return out
+}
+
+// updateRecordCleaner creates, updates or closes the record cleaner.
+// Entries of an existing cleaner are replaced, in order to apply new
+// retention rules without interrupting the cleaner.
+func (p *Core) updateRecordCleaner() {
+	cleanerEntries := gatherCleanerEntries(p.recordCleanerPaths())
+
+	switch {
+	case len(cleanerEntries) == 0:
+		if p.recordCleaner != nil {
+			p.recordCleaner.Close()
+			p.recordCleaner = nil
+		}
+
+	case p.recordCleaner == nil:
+		p.recordCleaner = &record.Cleaner{
+			Entries: cleanerEntries,
+			Parent:  p,
+		}
+		p.recordCleaner.Initialize()
+
+	default:
+		p.recordCleaner.ReloadEntries(cleanerEntries)
+	}
+}
+
+func (p *Core) setRecordRetention(req apiRecordRetentionSetReq) {
+	pathConf, ok := p.conf.Paths[req.name]
+	if !ok {
+		return
+	}
+
+	override, ok := p.recordRetentionOverrides[req.name]
+	if !ok {
+		override = pathConf.Clone()
+	}
+
+	if req.deleteAfter != nil {
+		override.RecordDeleteAfter = *req.deleteAfter
+	}
+	if req.maxSize != nil {
+		override.RecordMaxSize = *req.maxSize
+	}
+
+	if p.recordRetentionOverrides == nil {
+		p.recordRetentionOverrides = make(map[string]*conf.Path)
+	}
+	p.recordRetentionOverrides[req.name] = override
+
+	p.updateRecordCleaner()
+}
+
 func (p *Core) reloadConf(newConf *conf.Conf, calledByAPI bool) error {
+	// retention rules and log level that have not been persisted are discarded.
+	p.recordRetentionOverrides = nil
+	p.logger.SetLevel(logger.Level(p.conf.LogLevel))
+
+	p.ready.Store(false)
+
 	p.closeResources(newConf, calledByAPI)
 	p.conf = newConf
-	return p.createResources(false)
+
+	err := p.createResources(false)
+	if err != nil {
+		return err
+	}
+
+	p.ready.Store(true)
+	return nil
 }
 
 // APIConfigSet is called by api.
@@ -945,3 +1310,41 @@ func (p *Core) APIConfigSet(conf *conf.Conf) {
 	case <-p.ctx.Done():
 	}
 }
+
+// APILogLevelGet is called by api.
+func (p *Core) APILogLevelGet() conf.LogLevel {
+	res := make(chan conf.LogLevel)
+
+	select {
+	case p.chAPILogLevelGet <- res:
+		return <-res
+
+	case <-p.ctx.Done():
+		return p.conf.LogLevel
+	}
+}
+
+// APILogLevelSet is called by api.
+func (p *Core) APILogLevelSet(level conf.LogLevel) {
+	select {
+	case p.chAPILogLevelSet <- level:
+	case <-p.ctx.Done():
+	}
+}
+
+// APIReady is called by api.
+func (p *Core) APIReady() bool {
+	return p.ready.Load()
+}
+
+// APIRecordRetentionSet is called by api.
+func (p *Core) APIRecordRetentionSet(name string, deleteAfter *conf.StringDuration, maxSize *conf.StringSize) {
+	select {
+	case p.chAPIRecordRetentionSet <- apiRecordRetentionSetReq{
+		name:        name,
+		deleteAfter: deleteAfter,
+		maxSize:     maxSize,
+	}:
+	case <-p.ctx.Done():
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"io"
 	"net"
 	"net/http"
@@ -362,3 +363,103 @@ webrtc_sessions_bytes_sent 0
 		require.Equal(t, "paths 0\n", string(bo))
 	})
 }
+
+func TestMetricsPathLabels(t *testing.T) {
+	p, ok := newInstance("metrics: yes\n" +
+		"metricsPathLabels: [site, camera]\n" +
+		"paths:\n" +
+		"  mypath:\n" +
+		"    labels:\n" +
+		"      site: \"north \\\"gate\\\"\"\n" +
+		"      owner: security\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	bo := httpPullFile(t, hc, "http://localhost:9998/metrics")
+
+	require.Contains(t, string(bo), `paths{name="mypath",state="notReady",site="north \"gate\"",camera=""} 1`+"\n")
+	require.NotContains(t, string(bo), "owner")
+}
+
+func TestMetricsOTLP(t *testing.T) {
+	received := make(chan []byte, 1)
+
+	ln, err := net.Listen("tcp", "localhost:4318")
+	require.NoError(t, err)
+
+	s := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/v1/metrics", r.URL.Path)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.Equal(t, "Bearer mytoken", r.Header.Get("Authorization"))
+
+			byts, err2 := io.ReadAll(r.Body)
+			require.NoError(t, err2)
+
+			select {
+			case received <- byts:
+			default:
+			}
+		}),
+	}
+	go s.Serve(ln)
+	defer s.Shutdown(context.Background())
+
+	p, ok := newInstance("metricsOTLP: yes\n" +
+		"metricsOTLPEndpoint: http://localhost:4318/v1/metrics\n" +
+		"metricsOTLPInterval: 100ms\n" +
+		"metricsOTLPHeaders:\n" +
+		"  Authorization: Bearer mytoken\n" +
+		"paths:\n" +
+		"  mypath:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	var byts []byte
+	select {
+	case byts = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("metrics not received")
+	}
+
+	var req struct {
+		ResourceMetrics []struct {
+			ScopeMetrics []struct {
+				Metrics []map[string]json.RawMessage `json:"metrics"`
+			} `json:"scopeMetrics"`
+		} `json:"resourceMetrics"`
+	}
+	err = json.Unmarshal(byts, &req)
+	require.NoError(t, err)
+
+	metrics := make(map[string]map[string]json.RawMessage)
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		var name string
+		err = json.Unmarshal(m["name"], &name)
+		require.NoError(t, err)
+		metrics[name] = m
+	}
+
+	var gauge struct {
+		DataPoints []struct {
+			Attributes []map[string]interface{} `json:"attributes"`
+			AsInt      string                   `json:"asInt"`
+		} `json:"dataPoints"`
+	}
+	err = json.Unmarshal(metrics["paths"]["gauge"], &gauge)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{
+		{"key": "name", "value": map[string]interface{}{"stringValue": "mypath"}},
+		{"key": "state", "value": map[string]interface{}{"stringValue": "notReady"}},
+	}, gauge.DataPoints[0].Attributes)
+	require.Equal(t, "1", gauge.DataPoints[0].AsInt)
+
+	require.Contains(t, metrics["paths_bytes_received"], "sum")
+	require.Contains(t, metrics["rtsp_conns"], "gauge")
+
+	// metrics listener is not opened when only OTLP is enabled
+	_, err = net.Dial("tcp", "localhost:9998")
+	require.Error(t, err)
+}
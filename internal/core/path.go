@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,13 +13,16 @@ import (
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 
+	"github.com/bluenviron/mediamtx/internal/clip"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/hooks"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/push"
 	"github.com/bluenviron/mediamtx/internal/record"
 	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/synthvideo"
 )
 
 func emptyTimer() *time.Timer {
@@ -31,6 +35,7 @@ type pathParent interface {
 	logger.Writer
 	pathReady(*path)
 	pathNotReady(*path)
+	pathsReady([]string) bool
 	closePath(*path)
 }
 
@@ -63,6 +68,22 @@ type pathAPIPathsGetReq struct {
 	res  chan pathAPIPathsGetRes
 }
 
+type pathAPIPathsRecordReq struct {
+	start bool
+	res   chan error
+}
+
+type pathAPIPathsClipRes struct {
+	units []stream.BufferedUnit
+	err   error
+}
+
+type pathAPIPathsClipReq struct {
+	start time.Time
+	end   time.Time
+	res   chan pathAPIPathsClipRes
+}
+
 type path struct {
 	parentCtx         context.Context
 	logLevel          conf.LogLevel
@@ -85,7 +106,10 @@ type path struct {
 	source                         defs.Source
 	publisherQuery                 string
 	stream                         *stream.Stream
-	recordAgent                    *record.Agent
+	recordAgents                   []*record.Agent
+	pushAgents                     []*push.Agent
+	videoGenerator                 *synthvideo.Generator
+	apiRecord                      bool
 	readyTime                      time.Time
 	onUnDemandHook                 func(string)
 	onNotReadyHook                 func()
@@ -96,8 +120,12 @@ type path struct {
 	onDemandStaticSourceReadyTimer *time.Timer
 	onDemandStaticSourceCloseTimer *time.Timer
 	onDemandPublisherState         pathOnDemandState
+	onDemandPublisherQuery         string
 	onDemandPublisherReadyTimer    *time.Timer
 	onDemandPublisherCloseTimer    *time.Timer
+	idleTimer                      *time.Timer
+	idleTimerRunning               bool
+	dependenciesReady              bool
 
 	// in
 	chReloadConf              chan *conf.Path
@@ -111,6 +139,11 @@ type path struct {
 	chAddReader               chan defs.PathAddReaderReq
 	chRemoveReader            chan defs.PathRemoveReaderReq
 	chAPIPathsGet             chan pathAPIPathsGetReq
+	chAPIPathsRecord          chan pathAPIPathsRecordReq
+	chAPIPathsClip            chan pathAPIPathsClipReq
+	chSourceFormatChange      chan struct{}
+	chPublishBitrateExceeded  chan struct{}
+	chDependenciesChanged     chan struct{}
 
 	// out
 	done chan struct{}
@@ -126,6 +159,7 @@ func (pa *path) initialize() {
 	pa.onDemandStaticSourceCloseTimer = emptyTimer()
 	pa.onDemandPublisherReadyTimer = emptyTimer()
 	pa.onDemandPublisherCloseTimer = emptyTimer()
+	pa.idleTimer = emptyTimer()
 	pa.chReloadConf = make(chan *conf.Path)
 	pa.chStaticSourceSetReady = make(chan defs.PathSourceStaticSetReadyReq)
 	pa.chStaticSourceSetNotReady = make(chan defs.PathSourceStaticSetNotReadyReq)
@@ -137,6 +171,12 @@ func (pa *path) initialize() {
 	pa.chAddReader = make(chan defs.PathAddReaderReq)
 	pa.chRemoveReader = make(chan defs.PathRemoveReaderReq)
 	pa.chAPIPathsGet = make(chan pathAPIPathsGetReq)
+	pa.chAPIPathsRecord = make(chan pathAPIPathsRecordReq)
+	pa.chAPIPathsClip = make(chan pathAPIPathsClipReq)
+	pa.chSourceFormatChange = make(chan struct{}, 1)
+	pa.chPublishBitrateExceeded = make(chan struct{}, 1)
+	pa.chDependenciesChanged = make(chan struct{}, 1)
+	pa.dependenciesReady = pa.parent.pathsReady(pa.conf.DependsOn)
 	pa.done = make(chan struct{})
 
 	pa.Log(logger.Debug, "created")
@@ -169,7 +209,7 @@ func (pa *path) run() {
 	if pa.conf.Source == "redirect" {
 		pa.source = &sourceRedirect{}
 	} else if pa.conf.HasStaticSource() {
-		resolvedSource := pa.conf.Source
+		resolvedSource := strings.ReplaceAll(pa.conf.Source, "$MTX_PATH", pa.name)
 		if len(pa.matches) > 1 {
 			for i, ma := range pa.matches[1:] {
 				resolvedSource = strings.ReplaceAll(resolvedSource, "$G"+strconv.FormatInt(int64(i+1), 10), ma)
@@ -210,6 +250,7 @@ func (pa *path) run() {
 	pa.onDemandStaticSourceCloseTimer.Stop()
 	pa.onDemandPublisherReadyTimer.Stop()
 	pa.onDemandPublisherCloseTimer.Stop()
+	pa.idleTimer.Stop()
 
 	onUnInitHook()
 
@@ -244,6 +285,8 @@ func (pa *path) run() {
 
 func (pa *path) runInner() error {
 	for {
+		pa.updateIdleTimer()
+
 		select {
 		case <-pa.onDemandStaticSourceReadyTimer.C:
 			pa.doOnDemandStaticSourceReadyTimer()
@@ -269,6 +312,15 @@ func (pa *path) runInner() error {
 		case <-pa.onDemandPublisherCloseTimer.C:
 			pa.doOnDemandPublisherCloseTimer()
 
+		case <-pa.idleTimer.C:
+			if pa.conf.Regexp != nil {
+				return fmt.Errorf("idle")
+			}
+
+			// timer is not re-armed until the path gets used again.
+			pa.idleTimer = emptyTimer()
+			pa.doIdleTimer()
+
 		case newConf := <-pa.chReloadConf:
 			pa.doReloadConf(newConf)
 
@@ -322,12 +374,93 @@ func (pa *path) runInner() error {
 		case req := <-pa.chAPIPathsGet:
 			pa.doAPIPathsGet(req)
 
+		case req := <-pa.chAPIPathsRecord:
+			pa.doAPIPathsRecord(req)
+
+		case req := <-pa.chAPIPathsClip:
+			pa.doAPIPathsClip(req)
+
+		case <-pa.chDependenciesChanged:
+			pa.doDependenciesChanged()
+
+		case <-pa.chSourceFormatChange:
+			pa.doSourceFormatChange()
+
+		case <-pa.chPublishBitrateExceeded:
+			pa.doPublishBitrateExceeded()
+
 		case <-pa.ctx.Done():
 			return fmt.Errorf("terminated")
 		}
 	}
 }
 
+func (pa *path) doPublishBitrateExceeded() {
+	if pa.stream == nil {
+		return
+	}
+
+	if publisher, ok := pa.source.(defs.Publisher); ok {
+		pa.Log(logger.Warn, "closing publisher, since its bitrate exceeds 'maxPublishBitrate' (%d bit/s)",
+			pa.conf.MaxPublishBitrate)
+		publisher.Close()
+	}
+}
+
+func (pa *path) doSourceFormatChange() {
+	if pa.stream == nil {
+		return
+	}
+
+	switch pa.conf.OnSourceFormatChange {
+	case conf.OnSourceFormatChangeRenegotiate:
+		// HLS, RTMP and SRT readers receive the new parameters in-band,
+		// while RTSP and WebRTC sessions have to describe or negotiate the stream again.
+		pa.Log(logger.Info, "format of the source changed, closing RTSP and WebRTC readers")
+
+		for r := range pa.readers {
+			switch r.APIReaderDescribe().Type {
+			case "rtspSession", "rtspsSession", "webrtcSession":
+				pa.executeRemoveReader(r)
+				r.Close()
+			}
+		}
+
+	case conf.OnSourceFormatChangeReconnectReaders:
+		pa.Log(logger.Info, "format of the source changed, closing readers")
+
+		for r := range pa.readers {
+			pa.executeRemoveReader(r)
+			r.Close()
+		}
+	}
+}
+
+func (pa *path) doDependenciesChanged() {
+	ready := pa.parent.pathsReady(pa.conf.DependsOn)
+	if ready == pa.dependenciesReady {
+		return
+	}
+	pa.dependenciesReady = ready
+
+	if pa.onDemandPublisherState == pathOnDemandStateInitial {
+		return
+	}
+
+	if ready {
+		pa.Log(logger.Info, "dependencies are ready")
+		if pa.onUnDemandHook == nil {
+			pa.onDemandPublisherRunCommand()
+		}
+	} else {
+		pa.Log(logger.Info, "waiting for dependencies")
+		if pa.onUnDemandHook != nil {
+			pa.onUnDemandHook("dependency not ready")
+			pa.onUnDemandHook = nil
+		}
+	}
+}
+
 func (pa *path) doOnDemandStaticSourceReadyTimer() {
 	for _, req := range pa.describeRequestsOnHold {
 		req.Res <- defs.PathDescribeRes{Err: fmt.Errorf("source of path '%s' has timed out", pa.name)}
@@ -365,7 +498,19 @@ func (pa *path) doOnDemandPublisherCloseTimer() {
 	pa.onDemandPublisherStop("not needed by anyone")
 }
 
+func (pa *path) doIdleTimer() {
+	if publisher, ok := pa.source.(defs.Publisher); ok {
+		pa.Log(logger.Info, "closing idle publisher")
+		publisher.Close()
+		pa.executeRemovePublisher()
+	}
+
+	pa.publisherQuery = ""
+}
+
 func (pa *path) doReloadConf(newConf *conf.Path) {
+	oldConf := pa.conf
+
 	pa.confMutex.Lock()
 	pa.conf = newConf
 	pa.confMutex.Unlock()
@@ -374,13 +519,22 @@ func (pa *path) doReloadConf(newConf *conf.Path) {
 		go pa.source.(*staticSourceHandler).reloadConf(newConf)
 	}
 
-	if pa.conf.Record {
-		if pa.stream != nil && pa.recordAgent == nil {
-			pa.startRecording()
+	if pa.shouldRecord() {
+		if pa.stream != nil {
+			if pa.recordAgents == nil {
+				pa.startRecording()
+			} else if recordConfChanged(oldConf, newConf) {
+				pa.stopRecording()
+				pa.startRecording()
+			}
 		}
-	} else if pa.recordAgent != nil {
-		pa.recordAgent.Close()
-		pa.recordAgent = nil
+	} else if pa.recordAgents != nil {
+		pa.stopRecording()
+	}
+
+	if pa.stream != nil && !reflect.DeepEqual(newConf.PushDestinations, oldConf.PushDestinations) {
+		pa.stopPushing()
+		pa.startPushing()
 	}
 }
 
@@ -481,14 +635,18 @@ func (pa *path) doAddPublisher(req defs.PathAddPublisherReq) {
 	}
 
 	if pa.source != nil {
-		if !pa.conf.OverridePublisher {
+		if pa.conf.OnNewPublisher == conf.OnNewPublisherReject {
 			req.Res <- defs.PathAddPublisherRes{Err: fmt.Errorf("someone is already publishing to path '%s'", pa.name)}
 			return
 		}
 
 		pa.Log(logger.Info, "closing existing publisher")
 		pa.source.(defs.Publisher).Close()
-		pa.executeRemovePublisher()
+
+		// the stream and its readers are kept, in order to be passed to the new publisher.
+		if pa.stream == nil {
+			pa.executeRemovePublisher()
+		}
 	}
 
 	pa.source = req.Author
@@ -503,10 +661,18 @@ func (pa *path) doStartPublisher(req defs.PathStartPublisherReq) {
 		return
 	}
 
-	err := pa.setReady(req.Desc, req.GenerateRTPPackets)
-	if err != nil {
-		req.Res <- defs.PathStartPublisherRes{Err: err}
-		return
+	// the publisher replaced a previous one.
+	if pa.stream != nil && !pa.stream.ReplacePublisher(req.Desc, req.GenerateRTPPackets) {
+		pa.Log(logger.Info, "tracks of the new publisher are different, closing readers")
+		pa.setNotReady()
+	}
+
+	if pa.stream == nil {
+		err := pa.setReady(req.Desc, req.GenerateRTPPackets)
+		if err != nil {
+			req.Res <- defs.PathStartPublisherRes{Err: err}
+			return
+		}
 	}
 
 	req.Author.Log(logger.Info, "is publishing to path '%s', %s",
@@ -575,6 +741,57 @@ func (pa *path) doRemoveReader(req defs.PathRemoveReaderReq) {
 	}
 }
 
+func (pa *path) doAPIPathsClip(req pathAPIPathsClipReq) {
+	if pa.stream == nil {
+		req.res <- pathAPIPathsClipRes{err: fmt.Errorf("path is not ready")}
+		return
+	}
+
+	units, err := pa.stream.BufferedUnits(req.start, req.end)
+	if err != nil {
+		req.res <- pathAPIPathsClipRes{err: err}
+		return
+	}
+
+	req.res <- pathAPIPathsClipRes{units: units}
+}
+
+func (pa *path) doAPIPathsRecord(req pathAPIPathsRecordReq) {
+	if req.start {
+		if pa.conf.Record {
+			req.res <- fmt.Errorf("path is recorded continuously, since 'record' is enabled")
+			return
+		}
+
+		if pa.apiRecord {
+			req.res <- fmt.Errorf("recording has already been started")
+			return
+		}
+
+		if pa.stream == nil {
+			req.res <- fmt.Errorf("path is not ready")
+			return
+		}
+
+		pa.apiRecord = true
+		pa.startRecording()
+
+		req.res <- nil
+		return
+	}
+
+	if !pa.apiRecord {
+		req.res <- fmt.Errorf("recording has not been started")
+		return
+	}
+
+	// closing agents finalizes their current segment.
+	pa.apiRecord = false
+	pa.stopRecording()
+
+	req.res <- nil
+}
+
 func (pa *path) doAPIPathsGet(req pathAPIPathsGetReq) {
 	req.res <- pathAPIPathsGetRes{
 		data: &defs.APIPath{
@@ -601,12 +818,46 @@ func (pa *path) doAPIPathsGet(req pathAPIPathsGetReq) {
 				}
 				return defs.MediasToCodecs(pa.stream.Desc().Medias)
 			}(),
+			TrackDetails: func() []defs.APIPathTrack {
+				if pa.stream == nil {
+					return []defs.APIPathTrack{}
+				}
+				return defs.MediasToAPITracks(pa.stream.Desc().Medias)
+			}(),
+			SourceState: func() defs.APIPathSourceState {
+				switch {
+				case pa.stream != nil:
+					return defs.APIPathSourceStateReady
+				case pa.source != nil:
+					return defs.APIPathSourceStateWaiting
+				default:
+					return defs.APIPathSourceStateNone
+				}
+			}(),
+			SourceStats: func() *defs.APIPathSourceStats {
+				if pa.stream == nil {
+					return nil
+				}
+				return sourceStatsToAPI(pa.stream.SourceStats())
+			}(),
+			GOP: func() *defs.APIPathGOP {
+				if pa.stream == nil {
+					return nil
+				}
+				return gopStatsToAPI(pa.stream.GOPStats())
+			}(),
 			BytesReceived: func() uint64 {
 				if pa.stream == nil {
 					return 0
 				}
 				return pa.stream.BytesReceived()
 			}(),
+			PacketsMalformed: func() uint64 {
+				if pa.stream == nil {
+					return 0
+				}
+				return pa.stream.PacketsMalformed()
+			}(),
 			BytesSent: func() uint64 {
 				if pa.stream == nil {
 					return 0
@@ -620,6 +871,13 @@ func (pa *path) doAPIPathsGet(req pathAPIPathsGetReq) {
 				}
 				return ret
 			}(),
+			Labels: func() map[string]string {
+				ret := make(map[string]string, len(pa.conf.Labels))
+				for k, v := range pa.conf.Labels {
+					ret[k] = v
+				}
+				return ret
+			}(),
 		},
 	}
 }
@@ -655,6 +913,26 @@ func (pa *path) shouldClose() bool {
 		len(pa.readerAddRequestsOnHold) == 0
 }
 
+func (pa *path) isIdle() bool {
+	return pa.stream == nil &&
+		len(pa.readers) == 0 &&
+		len(pa.describeRequestsOnHold) == 0 &&
+		len(pa.readerAddRequestsOnHold) == 0
+}
+
+func (pa *path) updateIdleTimer() {
+	if pa.conf.PathIdleTimeout != 0 && pa.isIdle() {
+		if !pa.idleTimerRunning {
+			pa.idleTimer = time.NewTimer(time.Duration(pa.conf.PathIdleTimeout))
+			pa.idleTimerRunning = true
+		}
+	} else if pa.idleTimerRunning {
+		pa.idleTimer.Stop()
+		pa.idleTimer = emptyTimer()
+		pa.idleTimerRunning = false
+	}
+}
+
 func (pa *path) onDemandStaticSourceStart() {
 	pa.source.(*staticSourceHandler).start(true)
 
@@ -683,18 +961,29 @@ func (pa *path) onDemandStaticSourceStop(reason string) {
 }
 
 func (pa *path) onDemandPublisherStart(query string) {
+	pa.onDemandPublisherQuery = query
+
+	// the command is started when dependencies are ready
+	if pa.dependenciesReady {
+		pa.onDemandPublisherRunCommand()
+	} else {
+		pa.Log(logger.Info, "waiting for dependencies")
+	}
+
+	pa.onDemandPublisherReadyTimer.Stop()
+	pa.onDemandPublisherReadyTimer = time.NewTimer(time.Duration(pa.conf.RunOnDemandStartTimeout))
+
+	pa.onDemandPublisherState = pathOnDemandStateWaitingReady
+}
+
+func (pa *path) onDemandPublisherRunCommand() {
 	pa.onUnDemandHook = hooks.OnDemand(hooks.OnDemandParams{
 		Logger:          pa,
 		ExternalCmdPool: pa.externalCmdPool,
 		Conf:            pa.conf,
 		ExternalCmdEnv:  pa.ExternalCmdEnv(),
-		Query:           query,
+		Query:           pa.onDemandPublisherQuery,
 	})
-
-	pa.onDemandPublisherReadyTimer.Stop()
-	pa.onDemandPublisherReadyTimer = time.NewTimer(time.Duration(pa.conf.RunOnDemandStartTimeout))
-
-	pa.onDemandPublisherState = pathOnDemandStateWaitingReady
 }
 
 func (pa *path) onDemandPublisherScheduleClose() {
@@ -710,28 +999,110 @@ func (pa *path) onDemandPublisherStop(reason string) {
 		pa.onDemandPublisherCloseTimer = emptyTimer()
 	}
 
-	pa.onUnDemandHook(reason)
-	pa.onUnDemandHook = nil
+	if pa.onUnDemandHook != nil {
+		pa.onUnDemandHook(reason)
+		pa.onUnDemandHook = nil
+	}
 
 	pa.onDemandPublisherState = pathOnDemandStateInitial
 }
 
 func (pa *path) setReady(desc *description.Session, allocateEncoder bool) error {
+	var synthMedia *description.Media
+
+	// the description of the publisher is left untouched,
+	// since the publisher uses its medias to write to the stream.
+	if pa.conf.SynthesizeVideo && !hasVideo(desc) {
+		synthMedia = synthvideo.NewMedia()
+		extended := *desc
+		extended.Medias = append(append([]*description.Media(nil), desc.Medias...), synthMedia)
+		desc = &extended
+	}
+
+	bufferDuration := time.Duration(pa.conf.LiveBufferDuration)
+
+	// the smallest buffer, that contains frames received since the last keyframe.
+	if pa.conf.ReaderInstantStart && bufferDuration == 0 {
+		bufferDuration = time.Nanosecond
+	}
+
 	var err error
 	pa.stream, err = stream.New(
 		pa.udpMaxPayloadSize,
 		desc,
 		allocateEncoder,
+		bufferDuration,
 		logger.NewLimitedLogger(pa.source),
 	)
 	if err != nil {
 		return err
 	}
 
-	if pa.conf.Record {
+	if unsupported := pa.stream.UnsupportedFormats(); unsupported != nil {
+		if pa.conf.OnUnsupportedCodec == conf.OnUnsupportedCodecRejectSource {
+			pa.stream.Close()
+			pa.stream = nil
+			return fmt.Errorf("codec %s is not supported: %w", unsupported[0].Format.Codec(), unsupported[0].Err)
+		}
+
+		for _, u := range unsupported {
+			pa.Log(logger.Warn, "skipping track with codec %s, since it is not supported: %v", u.Format.Codec(), u.Err)
+		}
+	}
+
+	if pa.conf.RepeatParameterSets {
+		err = pa.stream.RepeatParameterSets()
+		if err != nil {
+			pa.stream.Close()
+			pa.stream = nil
+			return err
+		}
+	}
+
+	if pa.conf.OnSourceFormatChange != conf.OnSourceFormatChangeIgnore {
+		pa.stream.OnFormatChange(func() {
+			select {
+			case pa.chSourceFormatChange <- struct{}{}:
+			default:
+			}
+		})
+	}
+
+	if pa.conf.Source == "publisher" && pa.conf.MaxPublishBitrate != 0 {
+		pa.stream.OnBitrateExceeded(
+			uint64(pa.conf.MaxPublishBitrate),
+			time.Duration(pa.conf.MaxPublishBitrateWindow),
+			func() {
+				select {
+				case pa.chPublishBitrateExceeded <- struct{}{}:
+				default:
+				}
+			})
+	}
+
+	if synthMedia != nil {
+		pa.stream.ExcludeFromSourceStats(synthMedia)
+
+		pa.videoGenerator = &synthvideo.Generator{
+			Stream: pa.stream,
+			Media:  synthMedia,
+			Parent: pa,
+		}
+		err = pa.videoGenerator.Initialize()
+		if err != nil {
+			pa.videoGenerator = nil
+			pa.stream.Close()
+			pa.stream = nil
+			return err
+		}
+	}
+
+	if pa.shouldRecord() {
 		pa.startRecording()
 	}
 
+	pa.startPushing()
+
 	pa.readyTime = time.Now()
 
 	pa.onNotReadyHook = hooks.OnReady(hooks.OnReadyParams{
@@ -772,9 +1143,15 @@ func (pa *path) setNotReady() {
 
 	pa.onNotReadyHook()
 
-	if pa.recordAgent != nil {
-		pa.recordAgent.Close()
-		pa.recordAgent = nil
+	if pa.recordAgents != nil {
+		pa.stopRecording()
+	}
+
+	pa.stopPushing()
+
+	if pa.videoGenerator != nil {
+		pa.videoGenerator.Close()
+		pa.videoGenerator = nil
 	}
 
 	if pa.stream != nil {
@@ -783,46 +1160,203 @@ func (pa *path) setNotReady() {
 	}
 }
 
+func hasVideo(desc *description.Session) bool {
+	for _, medi := range desc.Medias {
+		if medi.Type == description.MediaTypeVideo {
+			return true
+		}
+	}
+	return false
+}
+
+func durationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func sourceStatsToAPI(stats stream.SourceStats) *defs.APIPathSourceStats {
+	ret := &defs.APIPathSourceStats{
+		MsGapP50: durationToMs(stats.GapP50),
+		MsGapP90: durationToMs(stats.GapP90),
+		MsGapP99: durationToMs(stats.GapP99),
+		MsGapMax: durationToMs(stats.GapMax),
+		Stalls:   stats.Stalls,
+	}
+
+	if !stats.LastPacket.IsZero() {
+		v := durationToMs(time.Since(stats.LastPacket))
+		ret.MsSinceLastPacket = &v
+	}
+
+	return ret
+}
+
+func gopStatsToAPI(stats *stream.GOPStats) *defs.APIPathGOP {
+	if stats == nil {
+		return nil
+	}
+
+	return &defs.APIPathGOP{
+		Count:             stats.Count,
+		MsLastInterval:    durationToMs(stats.LastInterval),
+		LastFrameCount:    stats.LastFrameCount,
+		MsAverageInterval: durationToMs(stats.AverageInterval),
+		MsIntervalStdDev:  durationToMs(stats.IntervalStdDev),
+		AverageFrameCount: stats.AverageFrameCount,
+		FrameCountStdDev:  stats.FrameCountStdDev,
+	}
+}
+
+// recordConfChanged returns whether record agents have to be recreated.
+func recordConfChanged(oldConf *conf.Path, newConf *conf.Path) bool {
+	return newConf.RecordPath != oldConf.RecordPath ||
+		newConf.RecordFormat != oldConf.RecordFormat ||
+		newConf.RecordPartDuration != oldConf.RecordPartDuration ||
+		newConf.RecordSegmentDuration != oldConf.RecordSegmentDuration ||
+		newConf.RecordVideoMode != oldConf.RecordVideoMode ||
+		newConf.RecordVideoFramerate != oldConf.RecordVideoFramerate ||
+		newConf.RecordAudio != oldConf.RecordAudio ||
+		newConf.RecordPrft != oldConf.RecordPrft ||
+		newConf.RecordDTSMode != oldConf.RecordDTSMode ||
+		newConf.FMP4FragmentDuration != oldConf.FMP4FragmentDuration ||
+		!reflect.DeepEqual(newConf.RecordOutputs, oldConf.RecordOutputs) ||
+		!reflect.DeepEqual(newConf.Labels, oldConf.Labels)
+}
+
+// shouldRecord returns whether the path has to be recorded,
+// either because of the configuration or because of a request from the API.
+func (pa *path) shouldRecord() bool {
+	return pa.conf.Record || pa.apiRecord
+}
+
 func (pa *path) startRecording() {
-	pa.recordAgent = &record.Agent{
-		WriteQueueSize:  pa.writeQueueSize,
-		PathFormat:      pa.conf.RecordPath,
-		Format:          pa.conf.RecordFormat,
-		PartDuration:    time.Duration(pa.conf.RecordPartDuration),
-		SegmentDuration: time.Duration(pa.conf.RecordSegmentDuration),
-		PathName:        pa.name,
-		Stream:          pa.stream,
+	pa.recordAgents = append(pa.recordAgents, pa.newRecordAgent(
+		pa.conf.RecordPath,
+		pa.conf.RecordFormat,
+		pa.conf.RecordPartDuration,
+		pa.conf.RecordSegmentDuration,
+		pa.conf.RecordVideoMode,
+		pa.conf.RecordVideoFramerate,
+		pa.conf.RecordAudio,
+	))
+
+	// additional recordings read the same stream, each one with its own agent,
+	// therefore a failure of one of them doesn't affect the others.
+	for _, ro := range pa.conf.RecordOutputs {
+		pa.recordAgents = append(pa.recordAgents, pa.newRecordAgent(
+			ro.RecordPath,
+			ro.RecordFormat,
+			ro.RecordPartDuration,
+			ro.RecordSegmentDuration,
+			ro.RecordVideoMode,
+			ro.RecordVideoFramerate,
+			ro.RecordAudio,
+		))
+	}
+}
+
+func (pa *path) stopRecording() {
+	for _, agent := range pa.recordAgents {
+		agent.Close()
+	}
+	pa.recordAgents = nil
+}
+
+func (pa *path) newRecordAgent(
+	pathFormat string,
+	format conf.RecordFormat,
+	partDuration conf.StringDuration,
+	segmentDuration conf.StringDuration,
+	videoMode conf.RecordVideoMode,
+	videoFramerate float64,
+	audio bool,
+) *record.Agent {
+	// the configuration can be replaced by a reload while the agent is running,
+	// therefore it is copied here and read under lock inside callbacks.
+	pathConf := pa.SafeConf()
+
+	if format == conf.RecordFormatFMP4 && pathConf.FMP4FragmentDuration != 0 {
+		partDuration = pathConf.FMP4FragmentDuration
+	}
+
+	agent := &record.Agent{
+		WriteQueueSize:   pa.writeQueueSize,
+		PathFormat:       pathFormat,
+		Format:           format,
+		PartDuration:     time.Duration(partDuration),
+		SegmentDuration:  time.Duration(segmentDuration),
+		VideoMode:        videoMode,
+		VideoFramerate:   videoFramerate,
+		SkipAudio:        !audio,
+		WritePrft:        pathConf.RecordPrft,
+		InterleaveWindow: time.Duration(pathConf.RecordInterleaveWindow),
+		DTSMode:          pathConf.RecordDTSMode,
+		PathName:         pa.name,
+		Labels:           pathConf.Labels,
+		Stream:           pa.stream,
 		OnSegmentCreate: func(segmentPath string) {
-			if pa.conf.RunOnRecordSegmentCreate != "" {
+			// hooks are applied without restarting the agent.
+			runOnRecordSegmentCreate := pa.SafeConf().RunOnRecordSegmentCreate
+			if runOnRecordSegmentCreate != "" {
 				env := pa.ExternalCmdEnv()
 				env["MTX_SEGMENT_PATH"] = segmentPath
 
 				pa.Log(logger.Info, "runOnRecordSegmentCreate command launched")
 				externalcmd.NewCmd(
 					pa.externalCmdPool,
-					pa.conf.RunOnRecordSegmentCreate,
+					runOnRecordSegmentCreate,
 					false,
 					env,
 					nil)
 			}
 		},
-		OnSegmentComplete: func(segmentPath string) {
-			if pa.conf.RunOnRecordSegmentComplete != "" {
+		OnSegmentComplete: func(segmentPath string, start time.Time, duration time.Duration) {
+			runOnRecordSegmentComplete := pa.SafeConf().RunOnRecordSegmentComplete
+			if runOnRecordSegmentComplete != "" {
 				env := pa.ExternalCmdEnv()
 				env["MTX_SEGMENT_PATH"] = segmentPath
+				env["MTX_SEGMENT_START"] = start.Format(time.RFC3339Nano)
+				env["MTX_SEGMENT_DURATION"] = strconv.FormatFloat(duration.Seconds(), 'f', -1, 64)
 
+				// the command runs in a separate goroutine, therefore it doesn't stall recording.
 				pa.Log(logger.Info, "runOnRecordSegmentComplete command launched")
 				externalcmd.NewCmd(
 					pa.externalCmdPool,
-					pa.conf.RunOnRecordSegmentComplete,
+					runOnRecordSegmentComplete,
 					false,
 					env,
-					nil)
+					func(err error) {
+						pa.Log(logger.Warn, "runOnRecordSegmentComplete command failed: %v", err)
+					})
 			}
 		},
 		Parent: pa,
 	}
-	pa.recordAgent.Initialize()
+	agent.Initialize()
+	return agent
+}
+
+func (pa *path) startPushing() {
+	// each destination has its own agent,
+	// therefore a failure of one of them doesn't affect the others.
+	for _, dest := range pa.conf.PushDestinations {
+		agent := &push.Agent{
+			URL:            dest,
+			ReadTimeout:    pa.readTimeout,
+			WriteTimeout:   pa.writeTimeout,
+			WriteQueueSize: pa.writeQueueSize,
+			Stream:         pa.stream,
+			Parent:         pa,
+		}
+		agent.Initialize()
+		pa.pushAgents = append(pa.pushAgents, agent)
+	}
+}
+
+func (pa *path) stopPushing() {
+	for _, agent := range pa.pushAgents {
+		agent.Close()
+	}
+	pa.pushAgents = nil
 }
 
 func (pa *path) executeRemoveReader(r defs.Reader) {
@@ -899,6 +1433,14 @@ func (pa *path) staticSourceHandlerSetReady(
 	}
 }
 
+// dependenciesChanged is called by pathManager.
+func (pa *path) dependenciesChanged() {
+	select {
+	case pa.chDependenciesChanged <- struct{}{}:
+	default:
+	}
+}
+
 // staticSourceHandlerSetNotReady is called by staticSourceHandler.
 func (pa *path) staticSourceHandlerSetNotReady(
 	staticSourceHandlerCtx context.Context, req defs.PathSourceStaticSetNotReadyReq,
@@ -991,6 +1533,45 @@ func (pa *path) RemoveReader(req defs.PathRemoveReaderReq) {
 	}
 }
 
+// APIPathsRecord is called by api.
+func (pa *path) APIPathsRecord(start bool) error {
+	req := pathAPIPathsRecordReq{
+		start: start,
+		res:   make(chan error),
+	}
+
+	select {
+	case pa.chAPIPathsRecord <- req:
+		return <-req.res
+
+	case <-pa.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsClip is called by api.
+func (pa *path) APIPathsClip(start time.Time, end time.Time) ([]byte, error) {
+	req := pathAPIPathsClipReq{
+		start: start,
+		end:   end,
+		res:   make(chan pathAPIPathsClipRes),
+	}
+
+	select {
+	case pa.chAPIPathsClip <- req:
+		res := <-req.res
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		// encoding is performed outside of the path goroutine.
+		return clip.Marshal(res.units)
+
+	case <-pa.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
 // APIPathsGet is called by api.
 func (pa *path) APIPathsGet(req pathAPIPathsGetReq) (*defs.APIPath, error) {
 	req.res = make(chan pathAPIPathsGetRes)
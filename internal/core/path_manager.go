@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
@@ -16,7 +17,31 @@ import (
 func pathConfCanBeUpdated(oldPathConf *conf.Path, newPathConf *conf.Path) bool {
 	clone := oldPathConf.Clone()
 
+	// parameters that are read when needed
+	clone.MaxReaders = newPathConf.MaxReaders
+	clone.Fallback = newPathConf.Fallback
+	clone.Labels = newPathConf.Labels
+	clone.Playback = newPathConf.Playback
+	clone.OnNewPublisher = newPathConf.OnNewPublisher
+	clone.OverridePublisher = newPathConf.OverridePublisher
+	clone.DisablePublisherOverride = newPathConf.DisablePublisherOverride
+	clone.RunOnRecordSegmentCreate = newPathConf.RunOnRecordSegmentCreate
+	clone.RunOnRecordSegmentComplete = newPathConf.RunOnRecordSegmentComplete
+
+	// recording is restarted without affecting the source and readers
 	clone.Record = newPathConf.Record
+	clone.RecordPath = newPathConf.RecordPath
+	clone.RecordFormat = newPathConf.RecordFormat
+	clone.RecordPartDuration = newPathConf.RecordPartDuration
+	clone.RecordSegmentDuration = newPathConf.RecordSegmentDuration
+	clone.RecordDeleteAfter = newPathConf.RecordDeleteAfter
+	clone.RecordMaxSize = newPathConf.RecordMaxSize
+	clone.RecordVideoMode = newPathConf.RecordVideoMode
+	clone.RecordVideoFramerate = newPathConf.RecordVideoFramerate
+	clone.RecordAudio = newPathConf.RecordAudio
+	clone.RecordPrft = newPathConf.RecordPrft
+	clone.FMP4FragmentDuration = newPathConf.FMP4FragmentDuration
+	clone.RecordOutputs = newPathConf.RecordOutputs
 
 	clone.RPICameraBrightness = newPathConf.RPICameraBrightness
 	clone.RPICameraContrast = newPathConf.RPICameraContrast
@@ -46,6 +71,18 @@ type pathManagerParent interface {
 	logger.Writer
 }
 
+const (
+	pathManagerIngestCheckInterval = 1 * time.Second
+)
+
+type pathManagerIngest struct {
+	stream    *stream.Stream
+	publisher defs.Publisher
+	readyTime time.Time
+	lastBytes uint64
+	bitrate   uint64
+}
+
 type pathManager struct {
 	logLevel                  conf.LogLevel
 	externalAuthenticationURL string
@@ -55,6 +92,8 @@ type pathManager struct {
 	writeTimeout              conf.StringDuration
 	writeQueueSize            int
 	udpMaxPayloadSize         int
+	maxTotalIngestBitrate     uint64
+	maxTotalIngestGracePeriod conf.StringDuration
 	pathConfs                 map[string]*conf.Path
 	externalCmdPool           *externalcmd.Pool
 	parent                    pathManagerParent
@@ -65,6 +104,11 @@ type pathManager struct {
 	hlsManager  pathManagerHLSServer
 	paths       map[string]*path
 	pathsByConf map[string]map[*path]struct{}
+	ingests     map[*path]*pathManagerIngest
+
+	// names of ready paths, read by paths in order to check their dependencies.
+	readyPathsMutex sync.RWMutex
+	readyPaths      map[string]struct{}
 
 	// in
 	chReloadConf   chan map[string]*conf.Path
@@ -87,6 +131,8 @@ func (pm *pathManager) initialize() {
 	pm.ctxCancel = ctxCancel
 	pm.paths = make(map[string]*path)
 	pm.pathsByConf = make(map[string]map[*path]struct{})
+	pm.ingests = make(map[*path]*pathManagerIngest)
+	pm.readyPaths = make(map[string]struct{})
 	pm.chReloadConf = make(chan map[string]*conf.Path)
 	pm.chSetHLSServer = make(chan pathManagerHLSServer)
 	pm.chClosePath = make(chan *path)
@@ -100,7 +146,7 @@ func (pm *pathManager) initialize() {
 	pm.chAPIPathsGet = make(chan pathAPIPathsGetReq)
 
 	for pathConfName, pathConf := range pm.pathConfs {
-		if pathConf.Regexp == nil {
+		if pathConf.Regexp == nil && pathConf.Enabled {
 			pm.createPath(pathConfName, pathConf, pathConfName, nil)
 		}
 	}
@@ -125,9 +171,19 @@ func (pm *pathManager) Log(level logger.Level, format string, args ...interface{
 func (pm *pathManager) run() {
 	defer pm.wg.Done()
 
+	var ingestCheckTicker <-chan time.Time
+	if pm.maxTotalIngestBitrate != 0 {
+		t := time.NewTicker(pathManagerIngestCheckInterval)
+		defer t.Stop()
+		ingestCheckTicker = t.C
+	}
+
 outer:
 	for {
 		select {
+		case <-ingestCheckTicker:
+			pm.doCheckIngest()
+
 		case newPaths := <-pm.chReloadConf:
 			pm.doReloadConf(newPaths)
 
@@ -200,7 +256,7 @@ func (pm *pathManager) doReloadConf(newPaths map[string]*conf.Path) {
 
 	// add new paths
 	for pathConfName, pathConf := range pm.pathConfs {
-		if _, ok := pm.paths[pathConfName]; !ok && pathConf.Regexp == nil {
+		if _, ok := pm.paths[pathConfName]; !ok && pathConf.Regexp == nil && pathConf.Enabled {
 			pm.createPath(pathConfName, pathConf, pathConfName, nil)
 		}
 	}
@@ -221,16 +277,110 @@ func (pm *pathManager) doPathReady(pa *path) {
 	if pm.hlsManager != nil {
 		pm.hlsManager.PathReady(pa)
 	}
+
+	pm.setPathReady(pa, true)
+
+	// path is blocked until pathReady() returns, therefore its fields can be read.
+	if publisher, ok := pa.source.(defs.Publisher); ok {
+		pm.ingests[pa] = &pathManagerIngest{
+			stream:    pa.stream,
+			publisher: publisher,
+			readyTime: pa.readyTime,
+			lastBytes: pa.stream.BytesReceived(),
+		}
+	}
 }
 
 func (pm *pathManager) doPathNotReady(pa *path) {
 	if pm.hlsManager != nil {
 		pm.hlsManager.PathNotReady(pa)
 	}
+
+	pm.setPathReady(pa, false)
+
+	delete(pm.ingests, pa)
+}
+
+// setPathReady updates the readiness of a path and notifies paths that depend on it.
+func (pm *pathManager) setPathReady(pa *path, ready bool) {
+	if pmpa, ok := pm.paths[pa.name]; !ok || pmpa != pa {
+		return
+	}
+
+	pm.readyPathsMutex.Lock()
+	if ready {
+		pm.readyPaths[pa.name] = struct{}{}
+	} else {
+		delete(pm.readyPaths, pa.name)
+	}
+	pm.readyPathsMutex.Unlock()
+
+	for _, other := range pm.paths {
+		for _, dep := range other.conf.DependsOn {
+			if dep == pa.name {
+				other.dependenciesChanged()
+				break
+			}
+		}
+	}
+}
+
+func (pm *pathManager) doCheckIngest() {
+	for _, ing := range pm.ingests {
+		bytes := ing.stream.BytesReceived()
+		ing.bitrate = (bytes - ing.lastBytes) * 8 * uint64(time.Second) / uint64(pathManagerIngestCheckInterval)
+		ing.lastBytes = bytes
+	}
+
+	if pm.maxTotalIngestGracePeriod == 0 || pm.totalIngestBitrate() <= pm.maxTotalIngestBitrate {
+		return
+	}
+
+	var newestPath *path
+	var newest *pathManagerIngest
+
+	for pa, ing := range pm.ingests {
+		if newest == nil || ing.readyTime.After(newest.readyTime) {
+			newestPath = pa
+			newest = ing
+		}
+	}
+
+	// give the newest publisher time to stabilize its bitrate before closing it.
+	if time.Since(newest.readyTime) < time.Duration(pm.maxTotalIngestGracePeriod) {
+		return
+	}
+
+	pm.Log(logger.Warn, "closing publisher of path '%s' since total ingest bitrate (%d bit/s) exceeds %d bit/s",
+		newestPath.name, pm.totalIngestBitrate(), pm.maxTotalIngestBitrate)
+
+	newest.publisher.Close()
+	delete(pm.ingests, newestPath)
+}
+
+func (pm *pathManager) totalIngestBitrate() uint64 {
+	var total uint64
+	for _, ing := range pm.ingests {
+		total += ing.bitrate
+	}
+	return total
+}
+
+func (pm *pathManager) findPathConf(name string) (string, *conf.Path, []string, error) {
+	pathConfName, pathConf, pathMatches, err := conf.FindPathConf(pm.pathConfs, name)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if !pathConf.Enabled {
+		return "", nil, nil, defs.PathDisabledError{PathName: name}
+	}
+
+	return pathConfName, pathConf, pathMatches, nil
 }
 
 func (pm *pathManager) doFindPathConf(req defs.PathFindPathConfReq) {
-	_, pathConf, _, err := conf.FindPathConf(pm.pathConfs, req.AccessRequest.Name)
+	_, pathConf, _, err := pm.findPathConf(req.AccessRequest.Name)
 	if err != nil {
 		req.Res <- defs.PathFindPathConfRes{Err: err}
 		return
@@ -247,7 +397,7 @@ func (pm *pathManager) doFindPathConf(req defs.PathFindPathConfReq) {
 }
 
 func (pm *pathManager) doDescribe(req defs.PathDescribeReq) {
-	pathConfName, pathConf, pathMatches, err := conf.FindPathConf(pm.pathConfs, req.AccessRequest.Name)
+	pathConfName, pathConf, pathMatches, err := pm.findPathConf(req.AccessRequest.Name)
 	if err != nil {
 		req.Res <- defs.PathDescribeRes{Err: err}
 		return
@@ -269,7 +419,7 @@ func (pm *pathManager) doDescribe(req defs.PathDescribeReq) {
 }
 
 func (pm *pathManager) doAddReader(req defs.PathAddReaderReq) {
-	pathConfName, pathConf, pathMatches, err := conf.FindPathConf(pm.pathConfs, req.AccessRequest.Name)
+	pathConfName, pathConf, pathMatches, err := pm.findPathConf(req.AccessRequest.Name)
 	if err != nil {
 		req.Res <- defs.PathAddReaderRes{Err: err}
 		return
@@ -293,7 +443,7 @@ func (pm *pathManager) doAddReader(req defs.PathAddReaderReq) {
 }
 
 func (pm *pathManager) doAddPublisher(req defs.PathAddPublisherReq) {
-	pathConfName, pathConf, pathMatches, err := conf.FindPathConf(pm.pathConfs, req.AccessRequest.Name)
+	pathConfName, pathConf, pathMatches, err := pm.findPathConf(req.AccessRequest.Name)
 	if err != nil {
 		req.Res <- defs.PathAddPublisherRes{Err: err}
 		return
@@ -308,6 +458,14 @@ func (pm *pathManager) doAddPublisher(req defs.PathAddPublisherReq) {
 		}
 	}
 
+	if pm.maxTotalIngestBitrate != 0 {
+		if total := pm.totalIngestBitrate(); total >= pm.maxTotalIngestBitrate {
+			req.Res <- defs.PathAddPublisherRes{Err: fmt.Errorf(
+				"total ingest bitrate (%d bit/s) has reached the maximum (%d bit/s)", total, pm.maxTotalIngestBitrate)}
+			return
+		}
+	}
+
 	// create path if it doesn't exist
 	if _, ok := pm.paths[req.AccessRequest.Name]; !ok {
 		pm.createPath(pathConfName, pathConf, req.AccessRequest.Name, pathMatches)
@@ -369,6 +527,8 @@ func (pm *pathManager) createPath(
 }
 
 func (pm *pathManager) removePath(pa *path) {
+	pm.setPathReady(pa, false)
+	delete(pm.ingests, pa)
 	delete(pm.pathsByConf[pa.confName], pa)
 	if len(pm.pathsByConf[pa.confName]) == 0 {
 		delete(pm.pathsByConf, pa.confName)
@@ -402,6 +562,19 @@ func (pm *pathManager) pathNotReady(pa *path) {
 	}
 }
 
+// pathsReady is called by path.
+func (pm *pathManager) pathsReady(names []string) bool {
+	pm.readyPathsMutex.RLock()
+	defer pm.readyPathsMutex.RUnlock()
+
+	for _, name := range names {
+		if _, ok := pm.readyPaths[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // closePath is called by path.
 func (pm *pathManager) closePath(pa *path) {
 	select {
@@ -521,6 +694,57 @@ func (pm *pathManager) APIPathsList() (*defs.APIPathList, error) {
 	}
 }
 
+// APIPathsRecordStart is called by api.
+func (pm *pathManager) APIPathsRecordStart(name string) error {
+	return pm.apiPathsRecord(name, true)
+}
+
+// APIPathsRecordStop is called by api.
+func (pm *pathManager) APIPathsRecordStop(name string) error {
+	return pm.apiPathsRecord(name, false)
+}
+
+func (pm *pathManager) apiPathsRecord(name string, start bool) error {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return res.err
+		}
+
+		return res.path.APIPathsRecord(start)
+
+	case <-pm.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsClip is called by api.
+func (pm *pathManager) APIPathsClip(name string, start time.Time, end time.Time) ([]byte, error) {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		return res.path.APIPathsClip(start, end)
+
+	case <-pm.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
 // APIPathsGet is called by api.
 func (pm *pathManager) APIPathsGet(name string) (*defs.APIPath, error) {
 	req := pathAPIPathsGetReq{
@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -462,6 +463,42 @@ func TestPathMaxReaders(t *testing.T) {
 	}
 }
 
+func TestPathMaxTotalIngestBitrate(t *testing.T) {
+	p, ok := newInstance("maxTotalIngestBitrate: 8\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source := gortsplib.Client{}
+	err := source.StartRecording(
+		"rtsp://localhost:8554/stream1",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	err = source.WritePacketRTP(testMediaH264, &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 1123,
+			Timestamp:      45343,
+			SSRC:           563423,
+		},
+		Payload: []byte{5},
+	})
+	require.NoError(t, err)
+
+	time.Sleep(1500 * time.Millisecond)
+
+	source2 := gortsplib.Client{}
+	err = source2.StartRecording(
+		"rtsp://localhost:8554/stream2",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.Error(t, err)
+}
+
 func TestPathRecord(t *testing.T) {
 	dir, err := os.MkdirTemp("", "rtsp-path-record")
 	require.NoError(t, err)
@@ -540,6 +577,138 @@ func TestPathRecord(t *testing.T) {
 	require.Equal(t, 2, len(files))
 }
 
+func TestPathRecordOutputs(t *testing.T) {
+	dir, err := os.MkdirTemp("", "rtsp-path-record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, ok := newInstance("paths:\n" +
+		"  all_others:\n" +
+		"    record: yes\n" +
+		"    recordPath: " + filepath.Join(dir, "fmp4", "%path/%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+		"    recordOutputs:\n" +
+		"    - recordPath: " + filepath.Join(dir, "mpegts", "%path/%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+		"      recordFormat: mpegts\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source := gortsplib.Client{}
+	err = source.StartRecording(
+		"rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	for i := 0; i < 4; i++ {
+		err := source.WritePacketRTP(testMediaH264, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: 1123 + uint16(i),
+				Timestamp:      45343 + 90000*uint32(i),
+				SSRC:           563423,
+			},
+			Payload: []byte{5},
+		})
+		require.NoError(t, err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	files, err := os.ReadDir(filepath.Join(dir, "fmp4", "mystream"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+	require.Equal(t, ".mp4", filepath.Ext(files[0].Name()))
+
+	files, err = os.ReadDir(filepath.Join(dir, "mpegts", "mystream"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+	require.Equal(t, ".ts", filepath.Ext(files[0].Name()))
+}
+
+func TestPathReloadConf(t *testing.T) {
+	dir, err := os.MkdirTemp("", "rtsp-path-record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  cam1:\n" +
+		"    record: yes\n" +
+		"    recordPath: " + filepath.Join(dir, "first", "%path/%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+		"  cam2:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source1 := gortsplib.Client{}
+	err = source1.StartRecording(
+		"rtsp://localhost:8554/cam1",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source1.Close()
+
+	source2 := gortsplib.Client{}
+	err = source2.StartRecording(
+		"rtsp://localhost:8554/cam2",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source2.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	isReady := func(name string) bool {
+		var out struct {
+			Ready bool `json:"ready"`
+		}
+		httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/"+name, nil, &out)
+		return out.Ready
+	}
+
+	// recording parameters restart the recording only.
+	httpRequest(t, hc, http.MethodPatch, "http://localhost:9997/v3/config/paths/patch/cam1", map[string]interface{}{
+		"recordPath": filepath.Join(dir, "second", "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		"maxReaders": 5,
+	}, nil)
+
+	time.Sleep(500 * time.Millisecond)
+
+	require.Equal(t, true, isReady("cam1"))
+	require.Equal(t, true, isReady("cam2"))
+
+	for i := 0; i < 4; i++ {
+		err := source1.WritePacketRTP(testMediaH264, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: 1123 + uint16(i),
+				Timestamp:      45343 + 90000*uint32(i),
+				SSRC:           563423,
+			},
+			Payload: []byte{5},
+		})
+		require.NoError(t, err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	files, err := os.ReadDir(filepath.Join(dir, "second", "cam1"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+
+	// other parameters recreate the path, without affecting other paths.
+	httpRequest(t, hc, http.MethodPatch, "http://localhost:9997/v3/config/paths/patch/cam2", map[string]interface{}{
+		"publishUser": "myuser",
+		"publishPass": "mypass",
+	}, nil)
+
+	time.Sleep(500 * time.Millisecond)
+
+	require.Equal(t, true, isReady("cam1"))
+	require.Equal(t, false, isReady("cam2"))
+}
+
 func TestPathFallback(t *testing.T) {
 	for _, ca := range []string{
 		"absolute",
@@ -649,3 +818,371 @@ func TestPathSourceRegexp(t *testing.T) {
 	_, _, err = reader.Describe(u)
 	require.NoError(t, err)
 }
+
+func TestPathDisabled(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  mypath:\n" +
+		"    enabled: no\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/mypath")
+	require.NoError(t, err)
+
+	source := gortsplib.Client{}
+
+	err = source.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer source.Close()
+
+	_, err = source.Announce(u, &description.Session{Medias: []*description.Media{testMediaH264}})
+	require.EqualError(t, err, "bad status code: 400 (Bad Request)")
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	httpRequest(t, hc, http.MethodPatch, "http://localhost:9997/v3/config/paths/patch/mypath", map[string]interface{}{
+		"enabled": true,
+	}, nil)
+
+	time.Sleep(500 * time.Millisecond)
+
+	source2 := gortsplib.Client{}
+	err = source2.StartRecording("rtsp://127.0.0.1:8554/mypath",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source2.Close()
+}
+
+func TestPathDependsOn(t *testing.T) {
+	onDemandFile := filepath.Join(os.TempDir(), "ondemand_depends")
+	os.Remove(onDemandFile)
+	defer os.Remove(onDemandFile)
+
+	p, ok := newInstance(fmt.Sprintf("rtmp: no\n"+
+		"hls: no\n"+
+		"webrtc: no\n"+
+		"paths:\n"+
+		"  dep:\n"+
+		"  main:\n"+
+		"    runOnDemand: touch %s\n"+
+		"    runOnDemandStartTimeout: 3s\n"+
+		"    dependsOn: [dep]\n", onDemandFile))
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/main")
+	require.NoError(t, err)
+
+	reader := gortsplib.Client{}
+	err = reader.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		reader.Describe(u) //nolint:errcheck
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+
+	_, err = os.Stat(onDemandFile)
+	require.True(t, os.IsNotExist(err))
+
+	source := gortsplib.Client{}
+	err = source.StartRecording("rtsp://127.0.0.1:8554/dep",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(onDemandFile)
+		return err == nil
+	}, 2*time.Second, 50*time.Millisecond)
+
+	<-done
+}
+
+func TestPathSourceOriginEdge(t *testing.T) {
+	var stream *gortsplib.ServerStream
+	var mutex sync.Mutex
+	describeCount := 0
+
+	s := gortsplib.Server{
+		Handler: &testServer{
+			onDescribe: func(ctx *gortsplib.ServerHandlerOnDescribeCtx,
+			) (*base.Response, *gortsplib.ServerStream, error) {
+				require.Equal(t, "/cam/1", ctx.Path)
+				mutex.Lock()
+				describeCount++
+				mutex.Unlock()
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onSetup: func(ctx *gortsplib.ServerHandlerOnSetupCtx) (*base.Response, *gortsplib.ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(ctx *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress: "127.0.0.1:8555",
+	}
+
+	err := s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	stream = gortsplib.NewServerStream(&s, &description.Session{Medias: []*description.Media{testMediaH264}})
+	defer stream.Close()
+
+	p, ok := newInstance(
+		"paths:\n" +
+			"  all_others:\n" +
+			"    source: rtsp://127.0.0.1:8555/$MTX_PATH\n" +
+			"    sourceOnDemand: yes\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/cam/1")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			reader := gortsplib.Client{}
+
+			err := reader.Start(u.Scheme, u.Host)
+			require.NoError(t, err)
+			defer reader.Close()
+
+			_, _, err = reader.Describe(u)
+			require.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Equal(t, 1, describeCount)
+}
+
+func TestPathIdleTimeout(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n" +
+		"    pathIdleTimeout: 1s\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source := gortsplib.Client{}
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/mystream")
+	require.NoError(t, err)
+
+	err = source.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer source.Close()
+
+	// announce without recording, in order to leave the path without a ready stream.
+	_, err = source.Announce(u, &description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	var out struct {
+		ItemCount int `json:"itemCount"`
+	}
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/list", nil, &out)
+	require.Equal(t, 1, out.ItemCount)
+
+	time.Sleep(2 * time.Second)
+
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/list", nil, &out)
+	require.Equal(t, 0, out.ItemCount)
+}
+
+func TestPathSynthesizeVideo(t *testing.T) {
+	p, ok := newInstance("paths:\n" +
+		"  all_others:\n" +
+		"    synthesizeVideo: yes\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source := gortsplib.Client{}
+	err := source.StartRecording(
+		"rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{testMediaAAC}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	reader := gortsplib.Client{}
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/mystream")
+	require.NoError(t, err)
+
+	err = reader.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	desc, _, err := reader.Describe(u)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(desc.Medias))
+	require.Equal(t, description.MediaTypeVideo, desc.Medias[1].Type)
+
+	err = reader.SetupAll(desc.BaseURL, desc.Medias)
+	require.NoError(t, err)
+
+	recv := make(chan struct{})
+	var once sync.Once
+
+	reader.OnPacketRTP(desc.Medias[1], desc.Medias[1].Formats[0], func(_ *rtp.Packet) {
+		once.Do(func() { close(recv) })
+	})
+
+	_, err = reader.Play(nil)
+	require.NoError(t, err)
+
+	<-recv
+}
+
+func TestPathReaderInstantStart(t *testing.T) {
+	p, ok := newInstance("paths:\n" +
+		"  all_others:\n" +
+		"    readerInstantStart: yes\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source := gortsplib.Client{}
+	err := source.StartRecording(
+		"rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	for i, payload := range [][]byte{
+		{0x65, 0x01}, // IDR
+		{0x41, 0x02}, // non-IDR
+	} {
+		err = source.WritePacketRTP(testMediaH264, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				SequenceNumber: 1000 + uint16(i),
+				Timestamp:      45343 + uint32(i)*3000,
+				SSRC:           563423,
+				Marker:         true,
+			},
+			Payload: payload,
+		})
+		require.NoError(t, err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	reader := gortsplib.Client{}
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/mystream")
+	require.NoError(t, err)
+
+	err = reader.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	desc, _, err := reader.Describe(u)
+	require.NoError(t, err)
+
+	err = reader.SetupAll(desc.BaseURL, desc.Medias)
+	require.NoError(t, err)
+
+	recv := make(chan []byte, 2)
+
+	reader.OnPacketRTP(desc.Medias[0], desc.Medias[0].Formats[0], func(pkt *rtp.Packet) {
+		recv <- pkt.Payload
+	})
+
+	_, err = reader.Play(nil)
+	require.NoError(t, err)
+
+	// frames are received without waiting for the publisher to send a new keyframe.
+	require.Equal(t, []byte{0x65, 0x01}, <-recv)
+	require.Equal(t, []byte{0x41, 0x02}, <-recv)
+}
+
+func TestPathSourceFormatChange(t *testing.T) {
+	p, ok := newInstance("paths:\n" +
+		"  all_others:\n" +
+		"    onSourceFormatChange: reconnectReaders\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source := gortsplib.Client{}
+	err := source.StartRecording(
+		"rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{testMediaH264}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	reader := gortsplib.Client{Transport: func() *gortsplib.Transport {
+		v := gortsplib.TransportTCP
+		return &v
+	}()}
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/mystream")
+	require.NoError(t, err)
+
+	err = reader.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	desc, _, err := reader.Describe(u)
+	require.NoError(t, err)
+
+	err = reader.SetupAll(desc.BaseURL, desc.Medias)
+	require.NoError(t, err)
+
+	_, err = reader.Play(nil)
+	require.NoError(t, err)
+
+	readerDone := make(chan error)
+	go func() {
+		readerDone <- reader.Wait()
+	}()
+
+	// a SPS with a different resolution.
+	err = source.WritePacketRTP(testMediaH264, &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: 1000,
+			Timestamp:      45343,
+			SSRC:           563423,
+			Marker:         true,
+		},
+		Payload: []byte{
+			0x67, 0x64, 0x00, 0x1f, 0xac, 0xd9, 0x40, 0x50,
+			0x05, 0xbb, 0x01, 0x6a, 0x02, 0x02, 0x02, 0x80,
+			0x00, 0x00, 0x03, 0x00, 0x80, 0x00, 0x00, 0x1e,
+			0x07, 0x8c, 0x18, 0xcb,
+		},
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-readerDone:
+	case <-time.After(5 * time.Second):
+		t.Errorf("reader has not been closed")
+	}
+}
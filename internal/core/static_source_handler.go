@@ -3,19 +3,13 @@ package core
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
-	hlssource "github.com/bluenviron/mediamtx/internal/staticsources/hls"
-	rpicamerasource "github.com/bluenviron/mediamtx/internal/staticsources/rpicamera"
-	rtmpsource "github.com/bluenviron/mediamtx/internal/staticsources/rtmp"
-	rtspsource "github.com/bluenviron/mediamtx/internal/staticsources/rtsp"
-	srtsource "github.com/bluenviron/mediamtx/internal/staticsources/srt"
-	udpsource "github.com/bluenviron/mediamtx/internal/staticsources/udp"
-	webrtcsource "github.com/bluenviron/mediamtx/internal/staticsources/webrtc"
+	_ "github.com/bluenviron/mediamtx/internal/staticsources" // register built-in static sources
+	"github.com/bluenviron/mediamtx/pkg/staticsource"
 )
 
 const (
@@ -57,62 +51,14 @@ func (s *staticSourceHandler) initialize() {
 	s.chInstanceSetReady = make(chan defs.PathSourceStaticSetReadyReq)
 	s.chInstanceSetNotReady = make(chan defs.PathSourceStaticSetNotReadyReq)
 
-	switch {
-	case strings.HasPrefix(s.resolvedSource, "rtsp://") ||
-		strings.HasPrefix(s.resolvedSource, "rtsps://"):
-		s.instance = &rtspsource.Source{
-			ResolvedSource: s.resolvedSource,
-			ReadTimeout:    s.readTimeout,
-			WriteTimeout:   s.writeTimeout,
-			WriteQueueSize: s.writeQueueSize,
-			Parent:         s,
-		}
-
-	case strings.HasPrefix(s.resolvedSource, "rtmp://") ||
-		strings.HasPrefix(s.resolvedSource, "rtmps://"):
-		s.instance = &rtmpsource.Source{
-			ResolvedSource: s.resolvedSource,
-			ReadTimeout:    s.readTimeout,
-			WriteTimeout:   s.writeTimeout,
-			Parent:         s,
-		}
-
-	case strings.HasPrefix(s.resolvedSource, "http://") ||
-		strings.HasPrefix(s.resolvedSource, "https://"):
-		s.instance = &hlssource.Source{
-			ResolvedSource: s.resolvedSource,
-			ReadTimeout:    s.readTimeout,
-			Parent:         s,
-		}
-
-	case strings.HasPrefix(s.resolvedSource, "udp://"):
-		s.instance = &udpsource.Source{
-			ResolvedSource: s.resolvedSource,
-			ReadTimeout:    s.readTimeout,
-			Parent:         s,
-		}
-
-	case strings.HasPrefix(s.resolvedSource, "srt://"):
-		s.instance = &srtsource.Source{
-			ResolvedSource: s.resolvedSource,
-			ReadTimeout:    s.readTimeout,
-			Parent:         s,
-		}
-
-	case strings.HasPrefix(s.resolvedSource, "whep://") ||
-		strings.HasPrefix(s.resolvedSource, "wheps://"):
-		s.instance = &webrtcsource.Source{
-			ResolvedSource: s.resolvedSource,
-			ReadTimeout:    s.readTimeout,
-			Parent:         s,
-		}
-
-	case s.resolvedSource == "rpiCamera":
-		s.instance = &rpicamerasource.Source{
-			LogLevel: s.logLevel,
-			Parent:   s,
-		}
-	}
+	s.instance = staticsource.New(staticsource.Params{
+		ResolvedSource: s.resolvedSource,
+		LogLevel:       s.logLevel,
+		ReadTimeout:    s.readTimeout,
+		WriteTimeout:   s.writeTimeout,
+		WriteQueueSize: s.writeQueueSize,
+		Parent:         s,
+	})
 }
 
 func (s *staticSourceHandler) close(reason string) {
@@ -363,3 +363,189 @@ func TestWebRTCPublish(t *testing.T) {
 		})
 	}
 }
+
+func TestWebRTCPublishAV1(t *testing.T) {
+	p, ok := newInstance("paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	su, err := url.Parse("http://localhost:8889/teststream/whip")
+	require.NoError(t, err)
+
+	s := &webrtc.WHIPClient{
+		HTTPClient: hc,
+		URL:        su,
+		Log:        test.NilLogger{},
+	}
+
+	tracks, err := s.Publish(context.Background(), &format.AV1{PayloadTyp: 96}, nil)
+	require.NoError(t, err)
+	defer checkClose(t, s.Close)
+
+	err = tracks[0].WriteRTP(&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 123,
+			Timestamp:      45343,
+			SSRC:           563423,
+		},
+		Payload: []byte{0x10, 0x01, 0x02},
+	})
+	require.NoError(t, err)
+
+	time.Sleep(200 * time.Millisecond)
+
+	c := gortsplib.Client{
+		OnDecodeError: func(err error) {
+			panic(err)
+		},
+	}
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/teststream")
+	require.NoError(t, err)
+
+	err = c.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer c.Close()
+
+	desc, _, err := c.Describe(u)
+	require.NoError(t, err)
+
+	var forma *format.AV1
+	medi := desc.FindFormat(&forma)
+	require.NotNil(t, medi)
+
+	_, err = c.Setup(desc.BaseURL, medi, 0, 0)
+	require.NoError(t, err)
+
+	received := make(chan struct{})
+
+	c.OnPacketRTP(medi, forma, func(pkt *rtp.Packet) {
+		require.Equal(t, []byte{0x10, 0x01, 0x02}, pkt.Payload)
+		// header extensions, like the dependency descriptor, are not forwarded.
+		require.Equal(t, false, pkt.Header.Extension)
+		close(received)
+	})
+
+	_, err = c.Play(nil)
+	require.NoError(t, err)
+
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 124,
+			Timestamp:      45343,
+			SSRC:           563423,
+		},
+		Payload: []byte{0x10, 0x01, 0x02},
+	}
+	err = pkt.Header.SetExtension(1, []byte{0x80, 0x01, 0x02})
+	require.NoError(t, err)
+
+	err = tracks[0].WriteRTP(pkt)
+	require.NoError(t, err)
+
+	<-received
+}
+
+func TestWebRTCReadDataChannel(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	medi := &description.Media{
+		Type: description.MediaTypeVideo,
+		Formats: []format.Format{&format.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+		}},
+	}
+
+	source := gortsplib.Client{}
+	err := source.StartRecording(
+		"rtsp://localhost:8554/teststream",
+		&description.Session{Medias: []*description.Media{medi}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	api, err := webrtc.NewAPI(webrtc.APIConf{
+		LocalRandomUDP:    true,
+		IPsFromInterfaces: true,
+	})
+	require.NoError(t, err)
+
+	pc, err := api.NewPeerConnection(pwebrtc.Configuration{})
+	require.NoError(t, err)
+	defer pc.Close() //nolint:errcheck
+
+	_, err = pc.AddTransceiverFromKind(pwebrtc.RTPCodecTypeVideo, pwebrtc.RTPTransceiverInit{
+		Direction: pwebrtc.RTPTransceiverDirectionRecvonly,
+	})
+	require.NoError(t, err)
+
+	dc, err := pc.CreateDataChannel("data", nil)
+	require.NoError(t, err)
+
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+
+	recv := make(chan pwebrtc.DataChannelMessage)
+	dc.OnMessage(func(msg pwebrtc.DataChannelMessage) {
+		recv <- msg
+	})
+
+	offer, err := pc.CreateOffer(nil)
+	require.NoError(t, err)
+
+	gatheringDone := pwebrtc.GatheringCompletePromise(pc)
+	err = pc.SetLocalDescription(offer)
+	require.NoError(t, err)
+	<-gatheringDone
+
+	res, err := webrtc.PostOffer(context.Background(), hc,
+		"http://localhost:8889/teststream/whep", pc.LocalDescription())
+	require.NoError(t, err)
+
+	err = pc.SetRemoteDescription(*res.Answer)
+	require.NoError(t, err)
+
+	select {
+	case <-opened:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("data channel not opened")
+	}
+
+	// wait for the server to register the data channel.
+	time.Sleep(500 * time.Millisecond)
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:9997/v3/paths/sendData/teststream",
+		bytes.NewReader([]byte(`{"score":"2-1"}`)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	res2, err := hc.Do(req)
+	require.NoError(t, err)
+	defer res2.Body.Close()
+	require.Equal(t, http.StatusOK, res2.StatusCode)
+
+	select {
+	case msg := <-recv:
+		require.Equal(t, true, msg.IsString)
+		require.Equal(t, []byte(`{"score":"2-1"}`), msg.Data)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message not received")
+	}
+}
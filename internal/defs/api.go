@@ -20,6 +20,13 @@ type APIPathConfList struct {
 	Items     []*conf.Path `json:"items"`
 }
 
+// APIPathsExport contains the configuration of all paths, including
+// the ones created at runtime. Only values that differ from path defaults are present.
+// Secrets are redacted.
+type APIPathsExport struct {
+	Paths map[string]*conf.OptionalPath `json:"paths"`
+}
+
 // APIPathSourceOrReader is a source or a reader.
 type APIPathSourceOrReader struct {
 	Type string `json:"type"`
@@ -28,15 +35,80 @@ type APIPathSourceOrReader struct {
 
 // APIPath is a path.
 type APIPath struct {
-	Name          string                  `json:"name"`
-	ConfName      string                  `json:"confName"`
-	Source        *APIPathSourceOrReader  `json:"source"`
-	Ready         bool                    `json:"ready"`
-	ReadyTime     *time.Time              `json:"readyTime"`
-	Tracks        []string                `json:"tracks"`
-	BytesReceived uint64                  `json:"bytesReceived"`
-	BytesSent     uint64                  `json:"bytesSent"`
-	Readers       []APIPathSourceOrReader `json:"readers"`
+	Name             string                  `json:"name"`
+	ConfName         string                  `json:"confName"`
+	Source           *APIPathSourceOrReader  `json:"source"`
+	Ready            bool                    `json:"ready"`
+	ReadyTime        *time.Time              `json:"readyTime"`
+	Tracks           []string                `json:"tracks"`
+	TrackDetails     []APIPathTrack          `json:"trackDetails"`
+	SourceState      APIPathSourceState      `json:"sourceState"`
+	SourceStats      *APIPathSourceStats     `json:"sourceStats"`
+	GOP              *APIPathGOP             `json:"gop"`
+	BytesReceived    uint64                  `json:"bytesReceived"`
+	PacketsMalformed uint64                  `json:"packetsMalformed"`
+	BytesSent        uint64                  `json:"bytesSent"`
+	Readers          []APIPathSourceOrReader `json:"readers"`
+	Labels           map[string]string       `json:"labels"`
+}
+
+// APIPathGOP contains statistics about the distance between keyframes
+// of the first video track. Statistics are reset when the source reconnects.
+type APIPathGOP struct {
+	// number of complete GOPs
+	Count uint64 `json:"count"`
+
+	// duration and frame count of the last complete GOP
+	MsLastInterval float64 `json:"msLastInterval"`
+	LastFrameCount uint64  `json:"lastFrameCount"`
+
+	// average and standard deviation of durations and frame counts of all complete GOPs
+	MsAverageInterval float64 `json:"msAverageInterval"`
+	MsIntervalStdDev  float64 `json:"msIntervalStdDev"`
+	AverageFrameCount float64 `json:"averageFrameCount"`
+	FrameCountStdDev  float64 `json:"frameCountStdDev"`
+}
+
+// APIPathSourceStats contains statistics about the timing of data received from the source.
+// Statistics are reset when the source reconnects.
+type APIPathSourceStats struct {
+	// time elapsed since the last packet, nil if no packet has been received yet
+	MsSinceLastPacket *float64 `json:"msSinceLastPacket"`
+
+	// percentiles and maximum of gaps between recent packets
+	MsGapP50 float64 `json:"msGapP50"`
+	MsGapP90 float64 `json:"msGapP90"`
+	MsGapP99 float64 `json:"msGapP99"`
+	MsGapMax float64 `json:"msGapMax"`
+
+	// number of gaps between packets longer than one second
+	Stalls uint64 `json:"stalls"`
+}
+
+// APIPathSourceState is the state of the source of a path.
+type APIPathSourceState string
+
+// source states.
+const (
+	APIPathSourceStateNone    APIPathSourceState = "none"
+	APIPathSourceStateWaiting APIPathSourceState = "waiting"
+	APIPathSourceStateReady   APIPathSourceState = "ready"
+)
+
+// APIPathTrack contains details about a track of a path.
+// Resolution is filled only when it can be extracted from codec parameters.
+type APIPathTrack struct {
+	Codec       string `json:"codec"`
+	PayloadType uint8  `json:"payloadType"`
+	ClockRate   int    `json:"clockRate"`
+	Width       *int   `json:"width"`
+	Height      *int   `json:"height"`
+}
+
+// APISourceProbe is the result of a source probe.
+type APISourceProbe struct {
+	Tracks       []string       `json:"tracks"`
+	TrackDetails []APIPathTrack `json:"trackDetails"`
 }
 
 // APIPathList is a list of paths.
@@ -46,6 +118,21 @@ type APIPathList struct {
 	Items     []*APIPath `json:"items"`
 }
 
+// APIPathReader is a reader of a path.
+type APIPathReader struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	RemoteAddr string `json:"remoteAddr"`
+	BytesSent  uint64 `json:"bytesSent"`
+}
+
+// APIPathReaderList is a list of readers of a path.
+type APIPathReaderList struct {
+	ItemCount int              `json:"itemCount"`
+	PageCount int              `json:"pageCount"`
+	Items     []*APIPathReader `json:"items"`
+}
+
 // APIHLSMuxer is an HLS muxer.
 type APIHLSMuxer struct {
 	Path        string    `json:"path"`
@@ -61,6 +148,13 @@ type APIHLSMuxerList struct {
 	Items     []*APIHLSMuxer `json:"items"`
 }
 
+// APIHLSSignedURL contains the parameters of a signed HLS URL.
+type APIHLSSignedURL struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+	Query   string    `json:"query"`
+}
+
 // APIRTMPConnState is the state of a RTMP connection.
 type APIRTMPConnState string
 
@@ -125,6 +219,7 @@ type APIRTSPSession struct {
 	Path          string              `json:"path"`
 	Query         string              `json:"query"`
 	Transport     *string             `json:"transport"`
+	SDP           *string             `json:"sdp"`
 	BytesReceived uint64              `json:"bytesReceived"`
 	BytesSent     uint64              `json:"bytesSent"`
 }
@@ -304,8 +399,11 @@ type APIWebRTCSession struct {
 	State                     APIWebRTCSessionState `json:"state"`
 	Path                      string                `json:"path"`
 	Query                     string                `json:"query"`
+	Offer                     string                `json:"offer"`
+	Answer                    string                `json:"answer"`
 	BytesReceived             uint64                `json:"bytesReceived"`
 	BytesSent                 uint64                `json:"bytesSent"`
+	BandwidthEstimate         uint64                `json:"bandwidthEstimate"`
 }
 
 // APIWebRTCSessionList is a list of WebRTC sessions.
@@ -326,6 +424,38 @@ type APIRecording struct {
 	Segments []*APIRecordingSegment `json:"segments"`
 }
 
+// APIRecordingSegmentFile is a recording segment file.
+type APIRecordingSegmentFile struct {
+	Start    time.Time `json:"start"`
+	Duration *float64  `json:"duration"`
+	Size     uint64    `json:"size"`
+	Writing  bool      `json:"writing"`
+}
+
+// APIRecordingSegmentFileList is a list of recording segment files.
+type APIRecordingSegmentFileList struct {
+	ItemCount int                        `json:"itemCount"`
+	PageCount int                        `json:"pageCount"`
+	Items     []*APIRecordingSegmentFile `json:"items"`
+}
+
+// APIRecordingRetentionPatch is a request to change the retention rules of recordings.
+type APIRecordingRetentionPatch struct {
+	RecordDeleteAfter *conf.StringDuration `json:"recordDeleteAfter"`
+	RecordMaxSize     *conf.StringSize     `json:"recordMaxSize"`
+	Persist           bool                 `json:"persist"`
+}
+
+// APILogLevel is the current log level.
+type APILogLevel struct {
+	LogLevel conf.LogLevel `json:"logLevel"`
+}
+
+// APILogLevelPatch is a request to change the log level.
+type APILogLevelPatch struct {
+	LogLevel *conf.LogLevel `json:"logLevel"`
+}
+
 // APIRecordingList is a list of recordings.
 type APIRecordingList struct {
 	ItemCount int             `json:"itemCount"`
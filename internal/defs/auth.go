@@ -10,6 +10,7 @@ const (
 	AuthProtocolHLS    AuthProtocol = "hls"
 	AuthProtocolWebRTC AuthProtocol = "webrtc"
 	AuthProtocolSRT    AuthProtocol = "srt"
+	AuthProtocolGRPC   AuthProtocol = "grpc"
 )
 
 // AuthenticationError is a authentication error.
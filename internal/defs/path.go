@@ -23,6 +23,16 @@ func (e PathNoOnePublishingError) Error() string {
 	return fmt.Sprintf("no one is publishing to path '%s'", e.PathName)
 }
 
+// PathDisabledError is returned when a path is disabled.
+type PathDisabledError struct {
+	PathName string
+}
+
+// Error implements the error interface.
+func (e PathDisabledError) Error() string {
+	return fmt.Sprintf("path '%s' is disabled", e.PathName)
+}
+
 // Path is a path.
 type Path interface {
 	Name() string
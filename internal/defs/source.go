@@ -6,6 +6,8 @@ import (
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
 
 	"github.com/bluenviron/mediamtx/internal/logger"
 )
@@ -52,6 +54,61 @@ func MediasToCodecs(medias []*description.Media) []string {
 	return FormatsToCodecs(formats)
 }
 
+// FormatResolution returns the resolution of a video format, when available.
+func FormatResolution(forma format.Format) (int, int, bool) {
+	switch forma := forma.(type) {
+	case *format.H264:
+		sps, _ := forma.SafeParams()
+		if sps == nil {
+			return 0, 0, false
+		}
+
+		var s h264.SPS
+		if err := s.Unmarshal(sps); err != nil {
+			return 0, 0, false
+		}
+		return s.Width(), s.Height(), true
+
+	case *format.H265:
+		_, sps, _ := forma.SafeParams()
+		if sps == nil {
+			return 0, 0, false
+		}
+
+		var s h265.SPS
+		if err := s.Unmarshal(sps); err != nil {
+			return 0, 0, false
+		}
+		return s.Width(), s.Height(), true
+	}
+
+	return 0, 0, false
+}
+
+// MediasToAPITracks returns details about tracks of given medias.
+func MediasToAPITracks(medias []*description.Media) []APIPathTrack {
+	ret := []APIPathTrack{}
+
+	for _, media := range medias {
+		for _, forma := range media.Formats {
+			track := APIPathTrack{
+				Codec:       forma.Codec(),
+				PayloadType: forma.PayloadType(),
+				ClockRate:   forma.ClockRate(),
+			}
+
+			if width, height, ok := FormatResolution(forma); ok {
+				track.Width = &width
+				track.Height = &height
+			}
+
+			ret = append(ret, track)
+		}
+	}
+
+	return ret
+}
+
 // MediasInfo returns a description of medias.
 func MediasInfo(medias []*description.Media) string {
 	var formats []format.Format
@@ -0,0 +1,32 @@
+// Package dscp contains utilities to mark outgoing packets with a DSCP value.
+package dscp
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// SetPacketConn sets the DSCP value of packets sent through a PacketConn.
+// Both the IPv4 ToS field and the IPv6 traffic class are set, since the socket
+// may be dual-stack. An error is returned only when none of them can be set,
+// for instance because the platform doesn't support it.
+func SetPacketConn(pc net.PacketConn, v int) error {
+	if v == 0 {
+		return nil
+	}
+
+	// DSCP is stored in the 6 most significant bits.
+	tos := v << 2
+
+	err4 := ipv4.NewPacketConn(pc).SetTOS(tos)
+	err6 := ipv6.NewPacketConn(pc).SetTrafficClass(tos)
+
+	if err4 != nil && err6 != nil {
+		return fmt.Errorf("unable to set DSCP: %w", err4)
+	}
+
+	return nil
+}
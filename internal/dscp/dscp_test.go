@@ -0,0 +1,22 @@
+package dscp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/ipv4"
+)
+
+func TestSetPacketConn(t *testing.T) {
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	err = SetPacketConn(pc, 46)
+	require.NoError(t, err)
+
+	tos, err := ipv4.NewPacketConn(pc).TOS()
+	require.NoError(t, err)
+	require.Equal(t, 46<<2, tos)
+}
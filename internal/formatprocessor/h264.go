@@ -112,6 +112,16 @@ func (t *formatProcessorH264) createEncoder(
 	return t.encoder.Init()
 }
 
+// RepeatParameterSets implements ParameterSetsRepeater.
+// RTP packets are re-encoded from access units, that always contain
+// SPS and PPS before key frames, without duplicates.
+func (t *formatProcessorH264) RepeatParameterSets() error {
+	if t.encoder != nil {
+		return nil
+	}
+	return t.createEncoder(nil, nil)
+}
+
 func (t *formatProcessorH264) updateTrackParametersFromRTPPacket(payload []byte) {
 	sps, pps := rtpH264ExtractParams(payload)
 
@@ -133,6 +143,10 @@ func (t *formatProcessorH264) updateTrackParametersFromAU(au [][]byte) {
 	update := false
 
 	for _, nalu := range au {
+		if len(nalu) == 0 { // empty NALU: skip
+			continue
+		}
+
 		typ := h264.NALUType(nalu[0] & 0x1F)
 
 		switch typ {
@@ -160,6 +174,10 @@ func (t *formatProcessorH264) remuxAccessUnit(au [][]byte) [][]byte {
 	n := 0
 
 	for _, nalu := range au {
+		if len(nalu) == 0 { // empty NALU: remove
+			continue
+		}
+
 		typ := h264.NALUType(nalu[0] & 0x1F)
 
 		switch typ {
@@ -196,6 +214,10 @@ func (t *formatProcessorH264) remuxAccessUnit(au [][]byte) [][]byte {
 	}
 
 	for _, nalu := range au {
+		if len(nalu) == 0 { // empty NALU: remove
+			continue
+		}
+
 		typ := h264.NALUType(nalu[0] & 0x1F)
 
 		switch typ {
@@ -201,6 +201,124 @@ func TestH264EmptyPacket(t *testing.T) {
 	require.Equal(t, []*rtp.Packet(nil), unit.RTPPackets)
 }
 
+func TestH264EmptyNALU(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+
+	p, err := New(1472, forma, true)
+	require.NoError(t, err)
+
+	unit := &unit.H264{
+		AU: [][]byte{
+			{},
+			{0x05, 0x01, 0x02}, // IDR
+		},
+	}
+
+	err = p.ProcessUnit(unit)
+	require.NoError(t, err)
+
+	// empty NALUs must be removed.
+	require.Equal(t, [][]byte{{0x05, 0x01, 0x02}}, unit.AU)
+}
+
+func TestH264RepeatParameterSets(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+		SPS:               []byte{7, 4, 5, 6},
+		PPS:               []byte{8, 1},
+	}
+
+	p, err := New(1472, forma, false)
+	require.NoError(t, err)
+
+	err = p.(ParameterSetsRepeater).RepeatParameterSets()
+	require.NoError(t, err)
+
+	enc, err := forma.CreateEncoder()
+	require.NoError(t, err)
+
+	dec, err := forma.CreateDecoder()
+	require.NoError(t, err)
+
+	for _, ca := range []struct {
+		name string
+		au   [][]byte
+	}{
+		{
+			"without parameters",
+			[][]byte{{byte(h264.NALUTypeIDR)}},
+		},
+		{
+			"with parameters",
+			[][]byte{{7, 4, 5, 6}, {8, 1}, {byte(h264.NALUTypeIDR)}},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			pkts, err := enc.Encode(ca.au)
+			require.NoError(t, err)
+
+			data, err := p.ProcessRTPPacket(pkts[0], time.Time{}, 0, false)
+			require.NoError(t, err)
+
+			var au [][]byte
+			for _, pkt := range data.GetRTPPackets() {
+				au, err = dec.Decode(pkt)
+				if err == nil {
+					break
+				}
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, [][]byte{
+				{7, 4, 5, 6},
+				{8, 1},
+				{byte(h264.NALUTypeIDR)},
+			}, au)
+		})
+	}
+}
+
+func TestH264ReducedPayloadSize(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+
+	p, err := New(1300, forma, true)
+	require.NoError(t, err)
+
+	idr := append([]byte{0x65}, bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 3000/4)...)
+
+	unit := &unit.H264{
+		AU: [][]byte{idr},
+	}
+
+	err = p.ProcessUnit(unit)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(unit.RTPPackets))
+
+	var reassembled []byte
+
+	for i, pkt := range unit.RTPPackets {
+		require.LessOrEqual(t, pkt.MarshalSize(), 1300)
+
+		// FU-A indicator and header
+		require.Equal(t, byte(0x7c), pkt.Payload[0])
+		require.Equal(t, i == 0, (pkt.Payload[1]&0x80) != 0)
+		require.Equal(t, i == len(unit.RTPPackets)-1, (pkt.Payload[1]&0x40) != 0)
+		require.Equal(t, byte(0x05), pkt.Payload[1]&0x1F)
+		require.Equal(t, i == len(unit.RTPPackets)-1, pkt.Marker)
+
+		reassembled = append(reassembled, pkt.Payload[2:]...)
+	}
+
+	require.Equal(t, idr[1:], reassembled)
+}
+
 func FuzzRTPH264ExtractParams(f *testing.F) {
 	f.Fuzz(func(t *testing.T, b []byte) {
 		rtpH264ExtractParams(b)
@@ -119,6 +119,16 @@ func (t *formatProcessorH265) createEncoder(
 	return t.encoder.Init()
 }
 
+// RepeatParameterSets implements ParameterSetsRepeater.
+// RTP packets are re-encoded from access units, that always contain
+// VPS, SPS and PPS before key frames, without duplicates.
+func (t *formatProcessorH265) RepeatParameterSets() error {
+	if t.encoder != nil {
+		return nil
+	}
+	return t.createEncoder(nil, nil)
+}
+
 func (t *formatProcessorH265) updateTrackParametersFromRTPPacket(payload []byte) {
 	vps, sps, pps := rtpH265ExtractParams(payload)
 
@@ -145,6 +155,10 @@ func (t *formatProcessorH265) updateTrackParametersFromAU(au [][]byte) {
 	update := false
 
 	for _, nalu := range au {
+		if len(nalu) == 0 { // empty NALU: skip
+			continue
+		}
+
 		typ := h265.NALUType((nalu[0] >> 1) & 0b111111)
 
 		switch typ {
@@ -178,6 +192,10 @@ func (t *formatProcessorH265) remuxAccessUnit(au [][]byte) [][]byte {
 	n := 0
 
 	for _, nalu := range au {
+		if len(nalu) == 0 { // empty NALU: remove
+			continue
+		}
+
 		typ := h265.NALUType((nalu[0] >> 1) & 0b111111)
 
 		switch typ {
@@ -215,6 +233,10 @@ func (t *formatProcessorH265) remuxAccessUnit(au [][]byte) [][]byte {
 	}
 
 	for _, nalu := range au {
+		if len(nalu) == 0 { // empty NALU: remove
+			continue
+		}
+
 		typ := h265.NALUType((nalu[0] >> 1) & 0b111111)
 
 		switch typ {
@@ -32,6 +32,12 @@ type Processor interface {
 	) (Unit, error)
 }
 
+// ParameterSetsRepeater is implemented by processors of formats that carry parameter sets.
+type ParameterSetsRepeater interface {
+	// insert the latest parameter sets before every key frame of generated RTP packets.
+	RepeatParameterSets() error
+}
+
 // New allocates a Processor.
 func New(
 	udpMaxPayloadSize int,
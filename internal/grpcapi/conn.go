@@ -0,0 +1,165 @@
+package grpcapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/hooks"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+)
+
+// maximum size of the payload of messages that contain the stream.
+// It is a multiple of the size of MPEG-TS packets.
+const messageMaxSize = 188 * 340
+
+// messageWriter sends written data to a gRPC client.
+// Writes are blocked by gRPC flow control when the client is not able
+// to keep up with the stream, and in this case the reader queue is filled
+// and the overflow policy of the path applies.
+type messageWriter struct {
+	ss grpc.ServerStream
+}
+
+// Write implements io.Writer.
+func (w *messageWriter) Write(p []byte) (int, error) {
+	err := w.ss.SendMsg(&wrapperspb.BytesValue{Value: p})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// SetWriteDeadline implements mpegts.DeadlineConn.
+// gRPC doesn't allow to set deadlines on writes,
+// dead clients are detected with keepalives.
+func (w *messageWriter) SetWriteDeadline(_ time.Time) error {
+	return nil
+}
+
+type conn struct {
+	writeQueueSize    int
+	readerQueueBudget *asyncwriter.GrowthBudget
+	externalCmdPool   *externalcmd.Pool
+	pathManager       PathManager
+	parent            logger.Writer
+
+	ctx       context.Context
+	ctxCancel func()
+	ss        grpc.ServerStream
+	uuid      uuid.UUID
+}
+
+func (c *conn) initialize(ss grpc.ServerStream) {
+	c.ctx, c.ctxCancel = context.WithCancel(ss.Context())
+	c.ss = ss
+	c.uuid = uuid.New()
+}
+
+// Close implements defs.Reader.
+func (c *conn) Close() {
+	c.ctxCancel()
+}
+
+// Log implements logger.Writer.
+func (c *conn) Log(level logger.Level, format string, args ...interface{}) {
+	ip, _, _ := clientCredentials(c.ss.Context())
+	c.parent.Log(level, "[conn %v] "+format, append([]interface{}{ip}, args...)...)
+}
+
+// APIReaderDescribe implements defs.Reader.
+func (c *conn) APIReaderDescribe() defs.APIPathSourceOrReader {
+	return defs.APIPathSourceOrReader{
+		Type: "grpcConn",
+		ID:   c.uuid.String(),
+	}
+}
+
+func (c *conn) run(pathNameAndQuery string) error {
+	defer c.ctxCancel()
+
+	pathName, query, _ := strings.Cut(pathNameAndQuery, "?")
+
+	ip, user, pass := clientCredentials(c.ctx)
+
+	path, stream, err := c.pathManager.AddReader(defs.PathAddReaderReq{
+		Author: c,
+		AccessRequest: defs.PathAccessRequest{
+			Name:  pathName,
+			Query: query,
+			IP:    ip,
+			User:  user,
+			Pass:  pass,
+			Proto: defs.AuthProtocolGRPC,
+			ID:    &c.uuid,
+		},
+	})
+	if err != nil {
+		var terr defs.AuthenticationError
+		if errors.As(err, &terr) {
+			c.Log(logger.Info, "failed to authenticate: %v", terr.Message)
+
+			// wait some seconds to mitigate brute force attacks
+			<-time.After(pauseAfterAuthError)
+
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		var terr2 defs.PathNoOnePublishingError
+		if errors.As(err, &terr2) {
+			return status.Error(codes.NotFound, err.Error())
+		}
+
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	defer path.RemoveReader(defs.PathRemoveReaderReq{Author: c})
+
+	writer := asyncwriter.NewReader(path.SafeConf(), c.writeQueueSize, c.readerQueueBudget, c)
+
+	defer stream.RemoveReader(writer)
+
+	mw := &messageWriter{ss: c.ss}
+	bw := bufio.NewWriterSize(mw, messageMaxSize)
+
+	err = mpegts.FromStream(stream, writer, bw, mw, 0)
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	c.Log(logger.Info, "is reading from path '%s', %s",
+		path.Name(), defs.FormatsInfo(stream.FormatsForReader(writer)))
+
+	onUnreadHook := hooks.OnRead(hooks.OnReadParams{
+		Logger:          c,
+		ExternalCmdPool: c.externalCmdPool,
+		Conf:            path.SafeConf(),
+		ExternalCmdEnv:  path.ExternalCmdEnv(),
+		Reader:          c.APIReaderDescribe(),
+		Query:           query,
+	})
+	defer onUnreadHook()
+
+	writer.Start()
+
+	select {
+	case <-c.ctx.Done():
+		writer.Stop()
+		return status.Error(codes.Aborted, "terminated")
+
+	case err := <-writer.Error():
+		return status.Error(codes.Aborted, err.Error())
+	}
+}
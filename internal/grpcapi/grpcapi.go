@@ -0,0 +1,299 @@
+// Package grpcapi contains the gRPC API server.
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/auth"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+const (
+	pauseAfterAuthError = 2 * time.Second
+
+	// interval between checks of the state of paths.
+	watchPathsInterval = 1 * time.Second
+)
+
+// PathManager contains methods used by the gRPC API server.
+type PathManager interface {
+	APIPathsList() (*defs.APIPathList, error)
+	AddReader(req defs.PathAddReaderReq) (defs.Path, *stream.Stream, error)
+}
+
+type serverParent interface {
+	logger.Writer
+}
+
+// pathState contains the fields of a path whose change
+// causes the list of paths to be sent to watchers.
+type pathState struct {
+	Name    string
+	Ready   bool
+	Source  *defs.APIPathSourceOrReader
+	Tracks  []string
+	Readers []defs.APIPathSourceOrReader
+}
+
+func pathsState(list *defs.APIPathList) []byte {
+	state := make([]pathState, len(list.Items))
+	for i, pa := range list.Items {
+		// readers are stored in a map, therefore their order is random
+		// and must be normalized in order to compare states.
+		readers := append([]defs.APIPathSourceOrReader(nil), pa.Readers...)
+		sort.Slice(readers, func(i, j int) bool {
+			if readers[i].Type != readers[j].Type {
+				return readers[i].Type < readers[j].Type
+			}
+			return readers[i].ID < readers[j].ID
+		})
+
+		state[i] = pathState{
+			Name:    pa.Name,
+			Ready:   pa.Ready,
+			Source:  pa.Source,
+			Tracks:  pa.Tracks,
+			Readers: readers,
+		}
+	}
+
+	byts, _ := json.Marshal(state)
+	return byts
+}
+
+// pathListToStruct converts a path list into a Struct,
+// with the same format used by the HTTP API.
+func pathListToStruct(list *defs.APIPathList) (*structpb.Struct, error) {
+	list.ItemCount = len(list.Items)
+	list.PageCount = 1
+
+	byts, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	err = json.Unmarshal(byts, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return structpb.NewStruct(m)
+}
+
+// clientCredentials returns the IP, user and password of a client.
+// Credentials are provided with the "authorization" metadata,
+// with the same format of the HTTP Authorization header.
+func clientCredentials(ctx context.Context) (net.IP, string, string) {
+	var ip net.IP
+	if p, ok := peer.FromContext(ctx); ok {
+		if addr, ok2 := p.Addr.(*net.TCPAddr); ok2 {
+			ip = addr.IP
+		}
+	}
+
+	var user, pass string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) != 0 {
+			r := &http.Request{Header: http.Header{"Authorization": v}}
+			user, pass, _ = r.BasicAuth()
+		}
+	}
+
+	return ip, user, pass
+}
+
+// Server is a gRPC API server.
+type Server struct {
+	Address                   string
+	Encryption                bool
+	ServerKey                 string
+	ServerCert                string
+	ReadTimeout               conf.StringDuration
+	WriteQueueSize            int
+	ReaderQueueBudget         *asyncwriter.GrowthBudget
+	ExternalAuthenticationURL string
+	User                      conf.Credential
+	Pass                      conf.Credential
+	IPs                       conf.IPsOrCIDRs
+	ExternalCmdPool           *externalcmd.Pool
+	PathManager               PathManager
+	Parent                    serverParent
+
+	ctx        context.Context
+	ctxCancel  func()
+	ln         net.Listener
+	grpcServer *grpc.Server
+	wg         sync.WaitGroup
+}
+
+// Initialize initializes the server.
+func (s *Server) Initialize() error {
+	network, address := restrictnetwork.Restrict("tcp", s.Address)
+
+	var err error
+	s.ln, err = net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	// dead clients are detected with keepalives, since writes are not subject to deadlines.
+	opts := []grpc.ServerOption{grpc.KeepaliveParams(keepalive.ServerParameters{
+		Time:    time.Duration(s.ReadTimeout),
+		Timeout: time.Duration(s.ReadTimeout),
+	})}
+
+	if s.Encryption {
+		cert, err := tls.LoadX509KeyPair(s.ServerCert, s.ServerKey)
+		if err != nil {
+			s.ln.Close()
+			return err
+		}
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+		})))
+	}
+
+	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+
+	s.grpcServer = grpc.NewServer(opts...)
+	s.grpcServer.RegisterService(&serviceDesc, s)
+
+	s.wg.Add(1)
+	go s.run()
+
+	s.Log(logger.Info, "listener opened on "+address)
+
+	return nil
+}
+
+// Close closes the server.
+func (s *Server) Close() {
+	s.Log(logger.Info, "listener is closing")
+	s.ctxCancel()
+	s.grpcServer.Stop()
+	s.wg.Wait()
+}
+
+// Log implements logger.Writer.
+func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
+	s.Parent.Log(level, "[gRPC API] "+format, args...)
+}
+
+func (s *Server) run() {
+	defer s.wg.Done()
+
+	err := s.grpcServer.Serve(s.ln)
+	if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		s.Log(logger.Error, err.Error())
+	}
+}
+
+func (s *Server) authenticate(ctx context.Context) error {
+	ip, user, pass := clientCredentials(ctx)
+
+	authenticator := &auth.HTTPAuthenticator{
+		ExternalAuthenticationURL: s.ExternalAuthenticationURL,
+		User:                      s.User,
+		Pass:                      s.Pass,
+		IPs:                       s.IPs,
+		Action:                    auth.ActionAPI,
+		Parent:                    s,
+	}
+
+	err := authenticator.Check(ip, user, pass, "")
+	if err != nil {
+		s.Log(logger.Info, "client %v failed to authenticate: %v", ip, err.(defs.AuthenticationError).Message)
+
+		// wait some seconds to mitigate brute force attacks
+		<-time.After(pauseAfterAuthError)
+
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return nil
+}
+
+func (s *Server) watchPaths(ss grpc.ServerStream) error {
+	err := s.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(watchPathsInterval)
+	defer ticker.Stop()
+
+	var lastState []byte
+
+	for {
+		list, err := s.PathManager.APIPathsList()
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		state := pathsState(list)
+
+		if !bytes.Equal(state, lastState) {
+			lastState = state
+
+			msg, err := pathListToStruct(list)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+
+			err = ss.SendMsg(msg)
+			if err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+
+		case <-ss.Context().Done():
+			return ss.Context().Err()
+
+		case <-s.ctx.Done():
+			return status.Error(codes.Unavailable, "terminated")
+		}
+	}
+}
+
+func (s *Server) readPath(req *wrapperspb.StringValue, ss grpc.ServerStream) error {
+	c := &conn{
+		writeQueueSize:    s.WriteQueueSize,
+		readerQueueBudget: s.ReaderQueueBudget,
+		externalCmdPool:   s.ExternalCmdPool,
+		pathManager:       s.PathManager,
+		parent:            s,
+	}
+	c.initialize(ss)
+
+	return c.run(req.Value)
+}
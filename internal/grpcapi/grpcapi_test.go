@@ -0,0 +1,299 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+type dummyPath struct{}
+
+func (p *dummyPath) Name() string {
+	return "mypath"
+}
+
+func (p *dummyPath) SafeConf() *conf.Path {
+	return &conf.Path{}
+}
+
+func (p *dummyPath) ExternalCmdEnv() externalcmd.Environment {
+	return externalcmd.Environment{}
+}
+
+func (p *dummyPath) StartPublisher(_ defs.PathStartPublisherReq) (*stream.Stream, error) {
+	return nil, nil
+}
+
+func (p *dummyPath) StopPublisher(_ defs.PathStopPublisherReq) {
+}
+
+func (p *dummyPath) RemovePublisher(_ defs.PathRemovePublisherReq) {
+}
+
+func (p *dummyPath) RemoveReader(_ defs.PathRemoveReaderReq) {
+}
+
+type dummyPathManager struct {
+	stream *stream.Stream
+	calls  int
+}
+
+func (pm *dummyPathManager) APIPathsList() (*defs.APIPathList, error) {
+	// simulate the random order of readers.
+	readers := []defs.APIPathSourceOrReader{
+		{Type: "rtspSession", ID: "a"},
+		{Type: "rtspSession", ID: "b"},
+	}
+	if pm.calls%2 != 0 {
+		readers[0], readers[1] = readers[1], readers[0]
+	}
+	pm.calls++
+
+	return &defs.APIPathList{
+		Items: []*defs.APIPath{{
+			Name:    "mypath",
+			Ready:   true,
+			Tracks:  []string{"H264"},
+			Readers: readers,
+		}},
+	}, nil
+}
+
+func (pm *dummyPathManager) AddReader(req defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+	if req.AccessRequest.Name != "mypath" || req.AccessRequest.Query != "key=value" ||
+		req.AccessRequest.User != "myuser" || req.AccessRequest.Pass != "mypass" {
+		return nil, nil, defs.AuthenticationError{Message: "invalid credentials"}
+	}
+	return &dummyPath{}, pm.stream, nil
+}
+
+func mustCredential(t *testing.T, v string) conf.Credential {
+	var c conf.Credential
+	err := c.UnmarshalJSON([]byte(`"` + v + `"`))
+	require.NoError(t, err)
+	return c
+}
+
+// messageReader reads messages sent by ReadPath.
+type messageReader struct {
+	cs  grpc.ClientStream
+	buf []byte
+}
+
+func (r *messageReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		var msg wrapperspb.BytesValue
+		err := r.cs.RecvMsg(&msg)
+		if err != nil {
+			return 0, err
+		}
+		r.buf = msg.Value
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func newClientStream(
+	ctx context.Context,
+	t *testing.T,
+	cc *grpc.ClientConn,
+	method string,
+	user string,
+	pass string,
+	req interface{},
+) grpc.ClientStream {
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization",
+		"Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+pass)))
+
+	cs, err := cc.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/mediamtx.API/"+method)
+	require.NoError(t, err)
+
+	err = cs.SendMsg(req)
+	require.NoError(t, err)
+
+	err = cs.CloseSend()
+	require.NoError(t, err)
+
+	return cs
+}
+
+func TestWatchPaths(t *testing.T) {
+	s := &Server{
+		Address:        "127.0.0.1:9995",
+		ReadTimeout:    conf.StringDuration(10 * time.Second),
+		WriteQueueSize: 512,
+		User:           mustCredential(t, "myuser"),
+		Pass:           mustCredential(t, "mypass"),
+		PathManager:    &dummyPathManager{},
+		Parent:         test.NilLogger{},
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	cc, err := grpc.NewClient("127.0.0.1:9995", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+
+	t.Run("ok", func(t *testing.T) {
+		cs := newClientStream(context.Background(), t, cc, "WatchPaths", "myuser", "mypass", &emptypb.Empty{})
+
+		var msg structpb.Struct
+		err = cs.RecvMsg(&msg)
+		require.NoError(t, err)
+
+		items := msg.Fields["items"].GetListValue().Values
+		require.Len(t, items, 1)
+		require.Equal(t, "mypath", items[0].GetStructValue().Fields["name"].GetStringValue())
+		require.Equal(t, true, items[0].GetStructValue().Fields["ready"].GetBoolValue())
+
+		// a change in the order of readers must not be notified.
+		recv := make(chan error)
+		go func() {
+			recv <- cs.RecvMsg(&msg)
+		}()
+
+		select {
+		case err = <-recv:
+			t.Errorf("unexpected message (%v)", err)
+		case <-time.After(2500 * time.Millisecond):
+		}
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		cs := newClientStream(context.Background(), t, cc, "WatchPaths", "myuser", "wrong", &emptypb.Empty{})
+
+		var msg structpb.Struct
+		err = cs.RecvMsg(&msg)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestWatchPathsTLS(t *testing.T) {
+	serverCertFpath, err := test.CreateTempFile(test.TLSCertPub)
+	require.NoError(t, err)
+	defer os.Remove(serverCertFpath)
+
+	serverKeyFpath, err := test.CreateTempFile(test.TLSCertKey)
+	require.NoError(t, err)
+	defer os.Remove(serverKeyFpath)
+
+	s := &Server{
+		Address:        "127.0.0.1:9995",
+		Encryption:     true,
+		ServerKey:      serverKeyFpath,
+		ServerCert:     serverCertFpath,
+		ReadTimeout:    conf.StringDuration(10 * time.Second),
+		WriteQueueSize: 512,
+		User:           mustCredential(t, "myuser"),
+		Pass:           mustCredential(t, "mypass"),
+		PathManager:    &dummyPathManager{},
+		Parent:         test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	cc, err := grpc.NewClient("127.0.0.1:9995", grpc.WithTransportCredentials(
+		credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})))
+	require.NoError(t, err)
+	defer cc.Close()
+
+	cs := newClientStream(context.Background(), t, cc, "WatchPaths", "myuser", "mypass", &emptypb.Empty{})
+
+	var msg structpb.Struct
+	err = cs.RecvMsg(&msg)
+	require.NoError(t, err)
+	require.Len(t, msg.Fields["items"].GetListValue().Values, 1)
+}
+
+func TestReadPath(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{test.FormatH264},
+	}}}
+
+	strm, err := stream.New(
+		1460,
+		desc,
+		true,
+		0,
+		test.NilLogger{},
+	)
+	require.NoError(t, err)
+	defer strm.Close()
+
+	s := &Server{
+		Address:        "127.0.0.1:9995",
+		ReadTimeout:    conf.StringDuration(10 * time.Second),
+		WriteQueueSize: 512,
+		PathManager:    &dummyPathManager{stream: strm},
+		Parent:         test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	cc, err := grpc.NewClient("127.0.0.1:9995", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	cs := newClientStream(ctx, t, cc, "ReadPath", "myuser", "mypass", &wrapperspb.StringValue{Value: "mypath?key=value"})
+
+	// write frames until the reader is registered.
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			strm.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+				AU: [][]byte{
+					{5, 1}, // IDR
+				},
+			})
+
+			select {
+			case <-time.After(50 * time.Millisecond):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	r, err := mpegts.NewReader(&messageReader{cs: cs})
+	require.NoError(t, err)
+
+	require.Equal(t, []*mpegts.Track{{
+		PID:   256,
+		Codec: &mpegts.CodecH264{},
+	}}, r.Tracks())
+}
@@ -0,0 +1,53 @@
+package grpcapi
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// service is the service described in apidocs/grpcapi.proto.
+// Messages are well-known types, therefore the service
+// is registered manually instead of using generated code.
+type service interface {
+	watchPaths(ss grpc.ServerStream) error
+	readPath(req *wrapperspb.StringValue, ss grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "mediamtx.API",
+	HandlerType: (*service)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPaths",
+			Handler:       watchPathsHandler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ReadPath",
+			Handler:       readPathHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "grpcapi.proto",
+}
+
+func watchPathsHandler(srv interface{}, ss grpc.ServerStream) error {
+	var req emptypb.Empty
+	err := ss.RecvMsg(&req)
+	if err != nil {
+		return err
+	}
+
+	return srv.(service).watchPaths(ss)
+}
+
+func readPathHandler(srv interface{}, ss grpc.ServerStream) error {
+	var req wrapperspb.StringValue
+	err := ss.RecvMsg(&req)
+	if err != nil {
+		return err
+	}
+
+	return srv.(service).readPath(&req, ss)
+}
@@ -0,0 +1,22 @@
+// Package keepalive contains a function that creates listeners with a TCP keepalive period.
+package keepalive
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Listen creates a listener that sets TCP keepalive on accepted connections.
+// When period is zero, keepalive is disabled.
+// As in the standard library, errors that occur while setting keepalive
+// are ignored, in order not to stop servers when a client resets its connection
+// before it has been accepted.
+func Listen(network string, address string, period time.Duration) (net.Listener, error) {
+	lc := net.ListenConfig{KeepAlive: period}
+	if period == 0 {
+		lc.KeepAlive = -1
+	}
+
+	return lc.Listen(context.Background(), network, address)
+}
@@ -0,0 +1,60 @@
+package keepalive
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListener(t *testing.T) {
+	for _, period := range []time.Duration{0, 15 * time.Second} {
+		t.Run(period.String(), func(t *testing.T) {
+			ln, err := Listen("tcp", "127.0.0.1:0", period)
+			require.NoError(t, err)
+			defer ln.Close()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				conn, err2 := net.Dial("tcp", ln.Addr().String())
+				require.NoError(t, err2)
+				conn.Close()
+			}()
+
+			conn, err := ln.Accept()
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, ok := conn.(*net.TCPConn)
+			require.Equal(t, true, ok)
+
+			<-done
+		})
+	}
+}
+
+func TestListenerReset(t *testing.T) {
+	ln, err := Listen("tcp", "127.0.0.1:0", 15*time.Second)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	// a client that resets the connection before it is accepted
+	// must not cause an error in Accept().
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	err = conn.(*net.TCPConn).SetLinger(0)
+	require.NoError(t, err)
+	conn.Close()
+
+	conn2, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn2.Close()
+
+	for i := 0; i < 2; i++ {
+		nconn, err := ln.Accept()
+		require.NoError(t, err)
+		nconn.Close()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gookit/color"
@@ -12,7 +13,7 @@ import (
 
 // Logger is a log handler.
 type Logger struct {
-	level Level
+	level atomic.Int32
 
 	destinations []destination
 	mutex        sync.Mutex
@@ -20,9 +21,8 @@ type Logger struct {
 
 // New allocates a log handler.
 func New(level Level, destinations []Destination, filePath string) (*Logger, error) {
-	lh := &Logger{
-		level: level,
-	}
+	lh := &Logger{}
+	lh.level.Store(int32(level))
 
 	for _, destType := range destinations {
 		switch destType {
@@ -140,9 +140,20 @@ func writeContent(buf *bytes.Buffer, format string, args []interface{}) {
 	buf.WriteByte('\n')
 }
 
+// Level returns the current log level.
+func (lh *Logger) Level() Level {
+	return Level(lh.level.Load())
+}
+
+// SetLevel changes the log level.
+// It can be called while other goroutines are writing log entries.
+func (lh *Logger) SetLevel(level Level) {
+	lh.level.Store(int32(level))
+}
+
 // Log writes a log entry.
 func (lh *Logger) Log(level Level, format string, args ...interface{}) {
-	if level < lh.level {
+	if level < lh.Level() {
 		return
 	}
 
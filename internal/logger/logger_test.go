@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerSetLevel(t *testing.T) {
+	lh, err := New(Info, nil, "")
+	require.NoError(t, err)
+	defer lh.Close()
+
+	require.Equal(t, Info, lh.Level())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				lh.Log(Debug, "test %d", j)
+			}
+		}()
+	}
+
+	lh.SetLevel(Debug)
+	wg.Wait()
+
+	require.Equal(t, Debug, lh.Level())
+}
@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/bluenviron/mediamtx/internal/api"
+	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
@@ -22,12 +24,67 @@ func interfaceIsEmpty(i interface{}) bool {
 	return reflect.ValueOf(i).Kind() != reflect.Ptr || reflect.ValueOf(i).IsNil()
 }
 
-func metric(key string, tags string, value int64) string {
-	return key + tags + " " + strconv.FormatInt(value, 10) + "\n"
+type metricLabel struct {
+	key   string
+	value string
 }
 
-func metricFloat(key string, tags string, value float64) string {
-	return key + tags + " " + strconv.FormatFloat(value, 'f', -1, 64) + "\n"
+type metricSample struct {
+	key        string
+	labels     []metricLabel
+	value      int64
+	floatValue float64
+	isFloat    bool
+	isCounter  bool
+}
+
+func metric(key string, labels []metricLabel, value int64) metricSample {
+	return metricSample{key: key, labels: labels, value: value}
+}
+
+func metricCounter(key string, labels []metricLabel, value int64) metricSample {
+	return metricSample{key: key, labels: labels, value: value, isCounter: true}
+}
+
+func metricFloat(key string, labels []metricLabel, value float64) metricSample {
+	return metricSample{key: key, labels: labels, floatValue: value, isFloat: true}
+}
+
+func pathLabels(labels map[string]string, names []string) []metricLabel {
+	ret := make([]metricLabel, len(names))
+	for i, name := range names {
+		ret[i] = metricLabel{name, labels[name]}
+	}
+	return ret
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func marshalPrometheus(samples []metricSample) string {
+	out := ""
+
+	for _, s := range samples {
+		out += s.key
+
+		if len(s.labels) != 0 {
+			out += "{"
+			for i, l := range s.labels {
+				if i != 0 {
+					out += ","
+				}
+				out += l.key + "=\"" + labelValueReplacer.Replace(l.value) + "\""
+			}
+			out += "}"
+		}
+
+		if s.isFloat {
+			out += " " + strconv.FormatFloat(s.floatValue, 'f', -1, 64) + "\n"
+		} else {
+			out += " " + strconv.FormatInt(s.value, 10) + "\n"
+		}
+	}
+
+	return out
 }
 
 type metricsParent interface {
@@ -36,11 +93,22 @@ type metricsParent interface {
 
 // Metrics is a metrics provider.
 type Metrics struct {
-	Address     string
-	ReadTimeout conf.StringDuration
-	Parent      metricsParent
+	Address                   string
+	ReadTimeout               conf.StringDuration
+	TCPKeepalivePeriod        conf.StringDuration
+	PathLabels                []string
+	ExternalAuthenticationURL string
+	User                      conf.Credential
+	Pass                      conf.Credential
+	IPs                       conf.IPsOrCIDRs
+	OTLPEndpoint              string
+	OTLPInterval              conf.StringDuration
+	OTLPHeaders               conf.Headers
+	OTLPFingerprint           string
+	Parent                    metricsParent
 
 	httpServer   *httpp.WrappedServer
+	otlpExporter *otlpExporter
 	mutex        sync.Mutex
 	pathManager  api.PathManager
 	rtspServer   api.RTSPServer
@@ -53,12 +121,49 @@ type Metrics struct {
 }
 
 // Initialize initializes metrics.
+// The HTTP listener is opened only when Address is set,
+// metrics are pushed with OTLP only when OTLPEndpoint is set.
 func (m *Metrics) Initialize() error {
+	if m.Address != "" {
+		err := m.initializeListener()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.OTLPEndpoint != "" {
+		m.otlpExporter = &otlpExporter{
+			endpoint:    m.OTLPEndpoint,
+			interval:    time.Duration(m.OTLPInterval),
+			timeout:     time.Duration(m.ReadTimeout),
+			headers:     m.OTLPHeaders,
+			fingerprint: m.OTLPFingerprint,
+			collect:     m.collect,
+			parent:      m,
+		}
+		m.otlpExporter.initialize()
+
+		m.Log(logger.Info, "pushing metrics to "+m.OTLPEndpoint)
+	}
+
+	return nil
+}
+
+func (m *Metrics) initializeListener() error {
 	router := gin.New()
 	router.SetTrustedProxies(nil) //nolint:errcheck
 
 	router.GET("/metrics", m.onMetrics)
 
+	authenticator := &auth.HTTPAuthenticator{
+		ExternalAuthenticationURL: m.ExternalAuthenticationURL,
+		User:                      m.User,
+		Pass:                      m.Pass,
+		IPs:                       m.IPs,
+		Action:                    auth.ActionMetrics,
+		Parent:                    m,
+	}
+
 	network, address := restrictnetwork.Restrict("tcp", m.Address)
 
 	var err error
@@ -66,9 +171,12 @@ func (m *Metrics) Initialize() error {
 		network,
 		address,
 		time.Duration(m.ReadTimeout),
+		time.Duration(m.TCPKeepalivePeriod),
 		"",
 		"",
-		router,
+		0,
+		nil,
+		authenticator.Wrap(router),
 		m,
 	)
 	if err != nil {
@@ -82,8 +190,14 @@ func (m *Metrics) Initialize() error {
 
 // Close closes Metrics.
 func (m *Metrics) Close() {
-	m.Log(logger.Info, "listener is closing")
-	m.httpServer.Close()
+	if m.otlpExporter != nil {
+		m.otlpExporter.close()
+	}
+
+	if m.httpServer != nil {
+		m.Log(logger.Info, "listener is closing")
+		m.httpServer.Close()
+	}
 }
 
 // Log implements logger.Writer.
@@ -92,7 +206,12 @@ func (m *Metrics) Log(level logger.Level, format string, args ...interface{}) {
 }
 
 func (m *Metrics) onMetrics(ctx *gin.Context) {
-	out := ""
+	ctx.Writer.WriteHeader(http.StatusOK)
+	io.WriteString(ctx.Writer, marshalPrometheus(m.collect())) //nolint:errcheck
+}
+
+func (m *Metrics) collect() []metricSample {
+	var out []metricSample
 
 	data, err := m.pathManager.APIPathsList()
 	if err == nil && len(data.Items) != 0 {
@@ -104,26 +223,27 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 				state = "notReady"
 			}
 
-			tags := "{name=\"" + i.Name + "\",state=\"" + state + "\"}"
-			out += metric("paths", tags, 1)
-			out += metric("paths_bytes_received", tags, int64(i.BytesReceived))
-			out += metric("paths_bytes_sent", tags, int64(i.BytesSent))
+			tags := append([]metricLabel{{"name", i.Name}, {"state", state}},
+				pathLabels(i.Labels, m.PathLabels)...)
+			out = append(out, metric("paths", tags, 1))
+			out = append(out, metricCounter("paths_bytes_received", tags, int64(i.BytesReceived)))
+			out = append(out, metricCounter("paths_bytes_sent", tags, int64(i.BytesSent)))
 		}
 	} else {
-		out += metric("paths", "", 0)
+		out = append(out, metric("paths", nil, 0))
 	}
 
 	if !interfaceIsEmpty(m.hlsManager) {
 		data, err := m.hlsManager.APIMuxersList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{name=\"" + i.Path + "\"}"
-				out += metric("hls_muxers", tags, 1)
-				out += metric("hls_muxers_bytes_sent", tags, int64(i.BytesSent))
+				tags := []metricLabel{{"name", i.Path}}
+				out = append(out, metric("hls_muxers", tags, 1))
+				out = append(out, metricCounter("hls_muxers_bytes_sent", tags, int64(i.BytesSent)))
 			}
 		} else {
-			out += metric("hls_muxers", "", 0)
-			out += metric("hls_muxers_bytes_sent", "", 0)
+			out = append(out, metric("hls_muxers", nil, 0))
+			out = append(out, metricCounter("hls_muxers_bytes_sent", nil, 0))
 		}
 	}
 
@@ -132,15 +252,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspServer.APIConnsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\"}"
-					out += metric("rtsp_conns", tags, 1)
-					out += metric("rtsp_conns_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsp_conns_bytes_sent", tags, int64(i.BytesSent))
+					tags := []metricLabel{{"id", i.ID.String()}}
+					out = append(out, metric("rtsp_conns", tags, 1))
+					out = append(out, metricCounter("rtsp_conns_bytes_received", tags, int64(i.BytesReceived)))
+					out = append(out, metricCounter("rtsp_conns_bytes_sent", tags, int64(i.BytesSent)))
 				}
 			} else {
-				out += metric("rtsp_conns", "", 0)
-				out += metric("rtsp_conns_bytes_received", "", 0)
-				out += metric("rtsp_conns_bytes_sent", "", 0)
+				out = append(out, metric("rtsp_conns", nil, 0))
+				out = append(out, metricCounter("rtsp_conns_bytes_received", nil, 0))
+				out = append(out, metricCounter("rtsp_conns_bytes_sent", nil, 0))
 			}
 		}()
 
@@ -148,15 +268,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspServer.APISessionsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-					out += metric("rtsp_sessions", tags, 1)
-					out += metric("rtsp_sessions_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsp_sessions_bytes_sent", tags, int64(i.BytesSent))
+					tags := []metricLabel{{"id", i.ID.String()}, {"state", string(i.State)}}
+					out = append(out, metric("rtsp_sessions", tags, 1))
+					out = append(out, metricCounter("rtsp_sessions_bytes_received", tags, int64(i.BytesReceived)))
+					out = append(out, metricCounter("rtsp_sessions_bytes_sent", tags, int64(i.BytesSent)))
 				}
 			} else {
-				out += metric("rtsp_sessions", "", 0)
-				out += metric("rtsp_sessions_bytes_received", "", 0)
-				out += metric("rtsp_sessions_bytes_sent", "", 0)
+				out = append(out, metric("rtsp_sessions", nil, 0))
+				out = append(out, metricCounter("rtsp_sessions_bytes_received", nil, 0))
+				out = append(out, metricCounter("rtsp_sessions_bytes_sent", nil, 0))
 			}
 		}()
 	}
@@ -166,15 +286,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspsServer.APIConnsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\"}"
-					out += metric("rtsps_conns", tags, 1)
-					out += metric("rtsps_conns_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsps_conns_bytes_sent", tags, int64(i.BytesSent))
+					tags := []metricLabel{{"id", i.ID.String()}}
+					out = append(out, metric("rtsps_conns", tags, 1))
+					out = append(out, metricCounter("rtsps_conns_bytes_received", tags, int64(i.BytesReceived)))
+					out = append(out, metricCounter("rtsps_conns_bytes_sent", tags, int64(i.BytesSent)))
 				}
 			} else {
-				out += metric("rtsps_conns", "", 0)
-				out += metric("rtsps_conns_bytes_received", "", 0)
-				out += metric("rtsps_conns_bytes_sent", "", 0)
+				out = append(out, metric("rtsps_conns", nil, 0))
+				out = append(out, metricCounter("rtsps_conns_bytes_received", nil, 0))
+				out = append(out, metricCounter("rtsps_conns_bytes_sent", nil, 0))
 			}
 		}()
 
@@ -182,15 +302,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspsServer.APISessionsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-					out += metric("rtsps_sessions", tags, 1)
-					out += metric("rtsps_sessions_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsps_sessions_bytes_sent", tags, int64(i.BytesSent))
+					tags := []metricLabel{{"id", i.ID.String()}, {"state", string(i.State)}}
+					out = append(out, metric("rtsps_sessions", tags, 1))
+					out = append(out, metricCounter("rtsps_sessions_bytes_received", tags, int64(i.BytesReceived)))
+					out = append(out, metricCounter("rtsps_sessions_bytes_sent", tags, int64(i.BytesSent)))
 				}
 			} else {
-				out += metric("rtsps_sessions", "", 0)
-				out += metric("rtsps_sessions_bytes_received", "", 0)
-				out += metric("rtsps_sessions_bytes_sent", "", 0)
+				out = append(out, metric("rtsps_sessions", nil, 0))
+				out = append(out, metricCounter("rtsps_sessions_bytes_received", nil, 0))
+				out = append(out, metricCounter("rtsps_sessions_bytes_sent", nil, 0))
 			}
 		}()
 	}
@@ -199,15 +319,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.rtmpServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("rtmp_conns", tags, 1)
-				out += metric("rtmp_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("rtmp_conns_bytes_sent", tags, int64(i.BytesSent))
+				tags := []metricLabel{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, metric("rtmp_conns", tags, 1))
+				out = append(out, metricCounter("rtmp_conns_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metricCounter("rtmp_conns_bytes_sent", tags, int64(i.BytesSent)))
 			}
 		} else {
-			out += metric("rtmp_conns", "", 0)
-			out += metric("rtmp_conns_bytes_received", "", 0)
-			out += metric("rtmp_conns_bytes_sent", "", 0)
+			out = append(out, metric("rtmp_conns", nil, 0))
+			out = append(out, metricCounter("rtmp_conns_bytes_received", nil, 0))
+			out = append(out, metricCounter("rtmp_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -215,15 +335,15 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.rtmpsServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("rtmps_conns", tags, 1)
-				out += metric("rtmps_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("rtmps_conns_bytes_sent", tags, int64(i.BytesSent))
+				tags := []metricLabel{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, metric("rtmps_conns", tags, 1))
+				out = append(out, metricCounter("rtmps_conns_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metricCounter("rtmps_conns_bytes_sent", tags, int64(i.BytesSent)))
 			}
 		} else {
-			out += metric("rtmps_conns", "", 0)
-			out += metric("rtmps_conns_bytes_received", "", 0)
-			out += metric("rtmps_conns_bytes_sent", "", 0)
+			out = append(out, metric("rtmps_conns", nil, 0))
+			out = append(out, metricCounter("rtmps_conns_bytes_received", nil, 0))
+			out = append(out, metricCounter("rtmps_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -231,64 +351,64 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.srtServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("srt_conns", tags, 1)
-				out += metric("srt_conns_packets_sent", tags, int64(i.PacketsSent))
-				out += metric("srt_conns_packets_received", tags, int64(i.PacketsReceived))
-				out += metric("srt_conns_packets_sent_unique", tags, int64(i.PacketsSentUnique))
-				out += metric("srt_conns_packets_received_unique", tags, int64(i.PacketsReceivedUnique))
-				out += metric("srt_conns_packets_send_loss", tags, int64(i.PacketsSendLoss))
-				out += metric("srt_conns_packets_received_loss", tags, int64(i.PacketsReceivedLoss))
-				out += metric("srt_conns_packets_retrans", tags, int64(i.PacketsRetrans))
-				out += metric("srt_conns_packets_received_retrans", tags, int64(i.PacketsReceivedRetrans))
-				out += metric("srt_conns_packets_sent_ack", tags, int64(i.PacketsSentACK))
-				out += metric("srt_conns_packets_received_ack", tags, int64(i.PacketsReceivedACK))
-				out += metric("srt_conns_packets_sent_nak", tags, int64(i.PacketsSentNAK))
-				out += metric("srt_conns_packets_received_nak", tags, int64(i.PacketsReceivedNAK))
-				out += metric("srt_conns_packets_sent_km", tags, int64(i.PacketsSentKM))
-				out += metric("srt_conns_packets_received_km", tags, int64(i.PacketsReceivedKM))
-				out += metric("srt_conns_us_snd_duration", tags, int64(i.UsSndDuration))
-				out += metric("srt_conns_packets_send_drop", tags, int64(i.PacketsSendDrop))
-				out += metric("srt_conns_packets_received_drop", tags, int64(i.PacketsReceivedDrop))
-				out += metric("srt_conns_packets_received_undecrypt", tags, int64(i.PacketsReceivedUndecrypt))
-				out += metric("srt_conns_bytes_sent", tags, int64(i.BytesSent))
-				out += metric("srt_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("srt_conns_bytes_sent_unique", tags, int64(i.BytesSentUnique))
-				out += metric("srt_conns_bytes_received_unique", tags, int64(i.BytesReceivedUnique))
-				out += metric("srt_conns_bytes_received_loss", tags, int64(i.BytesReceivedLoss))
-				out += metric("srt_conns_bytes_retrans", tags, int64(i.BytesRetrans))
-				out += metric("srt_conns_bytes_received_retrans", tags, int64(i.BytesReceivedRetrans))
-				out += metric("srt_conns_bytes_send_drop", tags, int64(i.BytesSendDrop))
-				out += metric("srt_conns_bytes_received_drop", tags, int64(i.BytesReceivedDrop))
-				out += metric("srt_conns_bytes_received_undecrypt", tags, int64(i.BytesReceivedUndecrypt))
-				out += metricFloat("srt_conns_us_packets_send_period", tags, i.UsPacketsSendPeriod)
-				out += metric("srt_conns_packets_flow_window", tags, int64(i.PacketsFlowWindow))
-				out += metric("srt_conns_packets_flight_size", tags, int64(i.PacketsFlightSize))
-				out += metricFloat("srt_conns_ms_rtt", tags, i.MsRTT)
-				out += metricFloat("srt_conns_mbps_send_rate", tags, i.MbpsSendRate)
-				out += metricFloat("srt_conns_mbps_receive_rate", tags, i.MbpsReceiveRate)
-				out += metricFloat("srt_conns_mbps_link_capacity", tags, i.MbpsLinkCapacity)
-				out += metric("srt_conns_bytes_avail_send_buf", tags, int64(i.BytesAvailSendBuf))
-				out += metric("srt_conns_bytes_avail_receive_buf", tags, int64(i.BytesAvailReceiveBuf))
-				out += metricFloat("srt_conns_mbps_max_bw", tags, i.MbpsMaxBW)
-				out += metric("srt_conns_bytes_mss", tags, int64(i.ByteMSS))
-				out += metric("srt_conns_packets_send_buf", tags, int64(i.PacketsSendBuf))
-				out += metric("srt_conns_bytes_send_buf", tags, int64(i.BytesSendBuf))
-				out += metric("srt_conns_ms_send_buf", tags, int64(i.MsSendBuf))
-				out += metric("srt_conns_ms_send_tsb_pd_delay", tags, int64(i.MsSendTsbPdDelay))
-				out += metric("srt_conns_packets_receive_buf", tags, int64(i.PacketsReceiveBuf))
-				out += metric("srt_conns_bytes_receive_buf", tags, int64(i.BytesReceiveBuf))
-				out += metric("srt_conns_ms_receive_buf", tags, int64(i.MsReceiveBuf))
-				out += metric("srt_conns_ms_receive_tsb_pd_delay", tags, int64(i.MsReceiveTsbPdDelay))
-				out += metric("srt_conns_packets_reorder_tolerance", tags, int64(i.PacketsReorderTolerance))
-				out += metric("srt_conns_packets_received_avg_belated_time", tags, int64(i.PacketsReceivedAvgBelatedTime))
-				out += metricFloat("srt_conns_packets_send_loss_rate", tags, i.PacketsSendLossRate)
-				out += metricFloat("srt_conns_packets_received_loss_rate", tags, i.PacketsReceivedLossRate)
+				tags := []metricLabel{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, metric("srt_conns", tags, 1))
+				out = append(out, metricCounter("srt_conns_packets_sent", tags, int64(i.PacketsSent)))
+				out = append(out, metricCounter("srt_conns_packets_received", tags, int64(i.PacketsReceived)))
+				out = append(out, metricCounter("srt_conns_packets_sent_unique", tags, int64(i.PacketsSentUnique)))
+				out = append(out, metricCounter("srt_conns_packets_received_unique", tags, int64(i.PacketsReceivedUnique)))
+				out = append(out, metricCounter("srt_conns_packets_send_loss", tags, int64(i.PacketsSendLoss)))
+				out = append(out, metricCounter("srt_conns_packets_received_loss", tags, int64(i.PacketsReceivedLoss)))
+				out = append(out, metricCounter("srt_conns_packets_retrans", tags, int64(i.PacketsRetrans)))
+				out = append(out, metricCounter("srt_conns_packets_received_retrans", tags, int64(i.PacketsReceivedRetrans)))
+				out = append(out, metricCounter("srt_conns_packets_sent_ack", tags, int64(i.PacketsSentACK)))
+				out = append(out, metricCounter("srt_conns_packets_received_ack", tags, int64(i.PacketsReceivedACK)))
+				out = append(out, metricCounter("srt_conns_packets_sent_nak", tags, int64(i.PacketsSentNAK)))
+				out = append(out, metricCounter("srt_conns_packets_received_nak", tags, int64(i.PacketsReceivedNAK)))
+				out = append(out, metricCounter("srt_conns_packets_sent_km", tags, int64(i.PacketsSentKM)))
+				out = append(out, metricCounter("srt_conns_packets_received_km", tags, int64(i.PacketsReceivedKM)))
+				out = append(out, metricCounter("srt_conns_us_snd_duration", tags, int64(i.UsSndDuration)))
+				out = append(out, metricCounter("srt_conns_packets_send_drop", tags, int64(i.PacketsSendDrop)))
+				out = append(out, metricCounter("srt_conns_packets_received_drop", tags, int64(i.PacketsReceivedDrop)))
+				out = append(out, metricCounter("srt_conns_packets_received_undecrypt", tags, int64(i.PacketsReceivedUndecrypt)))
+				out = append(out, metricCounter("srt_conns_bytes_sent", tags, int64(i.BytesSent)))
+				out = append(out, metricCounter("srt_conns_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metricCounter("srt_conns_bytes_sent_unique", tags, int64(i.BytesSentUnique)))
+				out = append(out, metricCounter("srt_conns_bytes_received_unique", tags, int64(i.BytesReceivedUnique)))
+				out = append(out, metricCounter("srt_conns_bytes_received_loss", tags, int64(i.BytesReceivedLoss)))
+				out = append(out, metricCounter("srt_conns_bytes_retrans", tags, int64(i.BytesRetrans)))
+				out = append(out, metricCounter("srt_conns_bytes_received_retrans", tags, int64(i.BytesReceivedRetrans)))
+				out = append(out, metricCounter("srt_conns_bytes_send_drop", tags, int64(i.BytesSendDrop)))
+				out = append(out, metricCounter("srt_conns_bytes_received_drop", tags, int64(i.BytesReceivedDrop)))
+				out = append(out, metricCounter("srt_conns_bytes_received_undecrypt", tags, int64(i.BytesReceivedUndecrypt)))
+				out = append(out, metricFloat("srt_conns_us_packets_send_period", tags, i.UsPacketsSendPeriod))
+				out = append(out, metric("srt_conns_packets_flow_window", tags, int64(i.PacketsFlowWindow)))
+				out = append(out, metric("srt_conns_packets_flight_size", tags, int64(i.PacketsFlightSize)))
+				out = append(out, metricFloat("srt_conns_ms_rtt", tags, i.MsRTT))
+				out = append(out, metricFloat("srt_conns_mbps_send_rate", tags, i.MbpsSendRate))
+				out = append(out, metricFloat("srt_conns_mbps_receive_rate", tags, i.MbpsReceiveRate))
+				out = append(out, metricFloat("srt_conns_mbps_link_capacity", tags, i.MbpsLinkCapacity))
+				out = append(out, metric("srt_conns_bytes_avail_send_buf", tags, int64(i.BytesAvailSendBuf)))
+				out = append(out, metric("srt_conns_bytes_avail_receive_buf", tags, int64(i.BytesAvailReceiveBuf)))
+				out = append(out, metricFloat("srt_conns_mbps_max_bw", tags, i.MbpsMaxBW))
+				out = append(out, metric("srt_conns_bytes_mss", tags, int64(i.ByteMSS)))
+				out = append(out, metric("srt_conns_packets_send_buf", tags, int64(i.PacketsSendBuf)))
+				out = append(out, metric("srt_conns_bytes_send_buf", tags, int64(i.BytesSendBuf)))
+				out = append(out, metric("srt_conns_ms_send_buf", tags, int64(i.MsSendBuf)))
+				out = append(out, metric("srt_conns_ms_send_tsb_pd_delay", tags, int64(i.MsSendTsbPdDelay)))
+				out = append(out, metric("srt_conns_packets_receive_buf", tags, int64(i.PacketsReceiveBuf)))
+				out = append(out, metric("srt_conns_bytes_receive_buf", tags, int64(i.BytesReceiveBuf)))
+				out = append(out, metric("srt_conns_ms_receive_buf", tags, int64(i.MsReceiveBuf)))
+				out = append(out, metric("srt_conns_ms_receive_tsb_pd_delay", tags, int64(i.MsReceiveTsbPdDelay)))
+				out = append(out, metric("srt_conns_packets_reorder_tolerance", tags, int64(i.PacketsReorderTolerance)))
+				out = append(out, metric("srt_conns_packets_received_avg_belated_time", tags, int64(i.PacketsReceivedAvgBelatedTime)))
+				out = append(out, metricFloat("srt_conns_packets_send_loss_rate", tags, i.PacketsSendLossRate))
+				out = append(out, metricFloat("srt_conns_packets_received_loss_rate", tags, i.PacketsReceivedLossRate))
 			}
 		} else {
-			out += metric("srt_conns", "", 0)
-			out += metric("srt_conns_bytes_received", "", 0)
-			out += metric("srt_conns_bytes_sent", "", 0)
+			out = append(out, metric("srt_conns", nil, 0))
+			out = append(out, metricCounter("srt_conns_bytes_received", nil, 0))
+			out = append(out, metricCounter("srt_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -296,20 +416,19 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.webRTCServer.APISessionsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("webrtc_sessions", tags, 1)
-				out += metric("webrtc_sessions_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("webrtc_sessions_bytes_sent", tags, int64(i.BytesSent))
+				tags := []metricLabel{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, metric("webrtc_sessions", tags, 1))
+				out = append(out, metricCounter("webrtc_sessions_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metricCounter("webrtc_sessions_bytes_sent", tags, int64(i.BytesSent)))
 			}
 		} else {
-			out += metric("webrtc_sessions", "", 0)
-			out += metric("webrtc_sessions_bytes_received", "", 0)
-			out += metric("webrtc_sessions_bytes_sent", "", 0)
+			out = append(out, metric("webrtc_sessions", nil, 0))
+			out = append(out, metricCounter("webrtc_sessions_bytes_received", nil, 0))
+			out = append(out, metricCounter("webrtc_sessions_bytes_sent", nil, 0))
 		}
 	}
 
-	ctx.Writer.WriteHeader(http.StatusOK)
-	io.WriteString(ctx.Writer, out) //nolint:errcheck
+	return out
 }
 
 // SetPathManager is called by core.
@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/tls"
+)
+
+const (
+	otlpAggregationTemporalityCumulative = 2
+)
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsInt        *string        `json:"asInt,omitempty"`
+	AsDouble     *float64       `json:"asDouble,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpExportMetricsServiceRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+// marshalOTLP encodes samples with the OTLP/JSON encoding.
+// 64-bit integers are encoded as strings, as required by the protobuf JSON mapping.
+func marshalOTLP(samples []metricSample, now time.Time) ([]byte, error) {
+	timeUnixNano := strconv.FormatInt(now.UnixNano(), 10)
+
+	// samples with the same key are grouped into a single metric, preserving order.
+	var metrics []otlpMetric
+	metricIndexes := make(map[string]int)
+
+	for _, s := range samples {
+		dp := otlpNumberDataPoint{
+			TimeUnixNano: timeUnixNano,
+		}
+
+		for _, l := range s.labels {
+			dp.Attributes = append(dp.Attributes, otlpKeyValue{
+				Key:   l.key,
+				Value: otlpAnyValue{StringValue: l.value},
+			})
+		}
+
+		if s.isFloat {
+			v := s.floatValue
+			dp.AsDouble = &v
+		} else {
+			v := strconv.FormatInt(s.value, 10)
+			dp.AsInt = &v
+		}
+
+		i, ok := metricIndexes[s.key]
+		if !ok {
+			m := otlpMetric{Name: s.key}
+			if s.isCounter {
+				m.Sum = &otlpSum{
+					AggregationTemporality: otlpAggregationTemporalityCumulative,
+					IsMonotonic:            true,
+				}
+			} else {
+				m.Gauge = &otlpGauge{}
+			}
+
+			i = len(metrics)
+			metricIndexes[s.key] = i
+			metrics = append(metrics, m)
+		}
+
+		if metrics[i].Sum != nil {
+			metrics[i].Sum.DataPoints = append(metrics[i].Sum.DataPoints, dp)
+		} else {
+			metrics[i].Gauge.DataPoints = append(metrics[i].Gauge.DataPoints, dp)
+		}
+	}
+
+	return json.Marshal(otlpExportMetricsServiceRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{{
+					Key:   "service.name",
+					Value: otlpAnyValue{StringValue: "mediamtx"},
+				}},
+			},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   otlpScope{Name: "mediamtx"},
+				Metrics: metrics,
+			}},
+		}},
+	})
+}
+
+// otlpExporter periodically pushes metrics to an OpenTelemetry collector.
+type otlpExporter struct {
+	endpoint    string
+	interval    time.Duration
+	timeout     time.Duration
+	headers     map[string]string
+	fingerprint string
+	collect     func() []metricSample
+	parent      logger.Writer
+
+	ctx        context.Context
+	ctxCancel  func()
+	httpClient *http.Client
+
+	done chan struct{}
+}
+
+func (e *otlpExporter) initialize() {
+	e.ctx, e.ctxCancel = context.WithCancel(context.Background())
+
+	e.httpClient = &http.Client{
+		Timeout: e.timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: tls.ConfigForFingerprint(e.fingerprint),
+		},
+	}
+
+	e.done = make(chan struct{})
+
+	go e.run()
+}
+
+func (e *otlpExporter) close() {
+	e.ctxCancel()
+	<-e.done
+}
+
+// Log implements logger.Writer.
+func (e *otlpExporter) Log(level logger.Level, format string, args ...interface{}) {
+	e.parent.Log(level, "[otlp] "+format, args...)
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.done)
+
+	defer e.httpClient.CloseIdleConnections()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := e.export()
+			if err != nil {
+				e.Log(logger.Warn, "unable to export metrics: %v", err)
+			}
+
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *otlpExporter) export() error {
+	byts, err := marshalOTLP(e.collect(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodPost, e.endpoint, bytes.NewReader(byts))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, res.Body) //nolint:errcheck
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("bad status code: %d", res.StatusCode)
+	}
+
+	return nil
+}
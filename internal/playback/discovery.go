@@ -0,0 +1,161 @@
+package playback
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/record"
+)
+
+// scannedDir is the result of the scan of a directory.
+type scannedDir struct {
+	modTime time.Time
+	names   []string
+	subdirs []string
+}
+
+// recordPathScanner finds the names of paths that have recordings inside a record path.
+// The content of directories is read again only when their modification time changes,
+// therefore subsequent scans of large archives are cheap.
+type recordPathScanner struct {
+	recordPath string
+	dirs       map[string]*scannedDir
+}
+
+func (s *recordPathScanner) scan() map[string]struct{} {
+	names := make(map[string]struct{})
+	visited := make(map[string]*scannedDir)
+
+	s.scanDir(record.CommonPath(s.recordPath), visited, names)
+
+	// forget directories that have been removed
+	s.dirs = visited
+
+	return names
+}
+
+func (s *recordPathScanner) scanDir(
+	dir string,
+	visited map[string]*scannedDir,
+	names map[string]struct{},
+) {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	sd, ok := s.dirs[dir]
+	if !ok || !sd.modTime.Equal(info.ModTime()) {
+		var entries []os.DirEntry
+		entries, err = os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+
+		sd = &scannedDir{modTime: info.ModTime()}
+
+		for _, entry := range entries {
+			fpath := filepath.Join(dir, entry.Name())
+
+			if entry.IsDir() {
+				sd.subdirs = append(sd.subdirs, fpath)
+				continue
+			}
+
+			var pa record.Path
+			ok = pa.Decode(s.recordPath, fpath)
+			if ok && pa.Path != "" {
+				sd.names = append(sd.names, pa.Path)
+			}
+		}
+	}
+
+	visited[dir] = sd
+
+	for _, name := range sd.names {
+		names[name] = struct{}{}
+	}
+
+	for _, subdir := range sd.subdirs {
+		s.scanDir(subdir, visited, names)
+	}
+}
+
+// discoverer finds paths that have recordings but are not explicitly
+// present in the configuration.
+// It scans the record path of default path settings and the record paths
+// of paths defined with regular expressions.
+type discoverer struct {
+	scanners map[string]*recordPathScanner
+}
+
+func (d *discoverer) discover(pathConfs map[string]*conf.Path, pathDefaults *conf.Path) []string {
+	if d.scanners == nil {
+		d.scanners = make(map[string]*recordPathScanner)
+	}
+
+	// nil stands for default path settings
+	sources := []*conf.Path{nil}
+	for _, pathConf := range pathConfs {
+		if pathConf.Regexp != nil {
+			sources = append(sources, pathConf)
+		}
+	}
+
+	names := make(map[string]struct{})
+	used := make(map[string]struct{})
+
+	for _, source := range sources {
+		pathConf := source
+		if pathConf == nil {
+			pathConf = pathDefaults
+		}
+
+		if !strings.Contains(pathConf.RecordPath, "%path") {
+			continue
+		}
+
+		recordPath := record.PathAddExtension(
+			pathConf.RecordPath,
+			pathConf.RecordFormat,
+		)
+
+		// we have to convert to absolute paths
+		// otherwise, recordPath and directory entries won't have common elements
+		recordPath, _ = filepath.Abs(recordPath)
+
+		scanner, ok := d.scanners[recordPath]
+		if !ok {
+			scanner = &recordPathScanner{recordPath: recordPath}
+			d.scanners[recordPath] = scanner
+		}
+		used[recordPath] = struct{}{}
+
+		for name := range scanner.scan() {
+			// keep the path only if its recordings belong to the scanned configuration
+			_, found, _, err := conf.FindPathConf(pathConfs, name)
+			if (source == nil && err != nil) || (source != nil && err == nil && found == source) {
+				names[name] = struct{}{}
+			}
+		}
+	}
+
+	for recordPath := range d.scanners {
+		if _, ok := used[recordPath]; !ok {
+			delete(d.scanners, recordPath)
+		}
+	}
+
+	out := make([]string, 0, len(names))
+	for name := range names {
+		out = append(out, name)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
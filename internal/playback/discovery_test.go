@@ -0,0 +1,55 @@
+package playback
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordPathScanner(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-playback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"path1", "path2"} {
+		err = os.Mkdir(filepath.Join(dir, name), 0o755)
+		require.NoError(t, err)
+
+		err = os.WriteFile(filepath.Join(dir, name, "2008-11-07_11-22-00-500000.mp4"), []byte{}, 0o644)
+		require.NoError(t, err)
+	}
+
+	s := &recordPathScanner{
+		recordPath: filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f.mp4"),
+	}
+
+	require.Equal(t, map[string]struct{}{
+		"path1": {},
+		"path2": {},
+	}, s.scan())
+
+	require.Len(t, s.dirs, 3)
+
+	// unchanged directories are not read again
+	cached := s.dirs[filepath.Join(dir, "path1")]
+	s.scan()
+	require.Same(t, cached, s.dirs[filepath.Join(dir, "path1")])
+
+	err = os.RemoveAll(filepath.Join(dir, "path2"))
+	require.NoError(t, err)
+
+	err = os.Mkdir(filepath.Join(dir, "path3"), 0o755)
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "path3", "2008-11-07_11-22-00-500000.mp4"), []byte{}, 0o644)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]struct{}{
+		"path1": {},
+		"path3": {},
+	}, s.scan())
+
+	require.Len(t, s.dirs, 3)
+}
@@ -33,6 +33,25 @@ func durationMp4ToGo(v uint64, timeScale uint32) time.Duration {
 
 var errTerminated = errors.New("terminated")
 
+// writePart applies the speed filter, if any, to a part, and writes it.
+func writePart(part *fmp4.Part, sf *speedFilter, buf *seekablebuffer.Buffer, w io.Writer) error {
+	if sf != nil {
+		sf.apply(part)
+		if len(part.Tracks) == 0 {
+			return nil
+		}
+	}
+
+	err := part.Marshal(buf)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf.Bytes())
+	buf.Reset()
+	return err
+}
+
 func fmp4ReadInit(r io.ReadSeeker) ([]byte, error) {
 	buf := make([]byte, 8)
 	_, err := io.ReadFull(r, buf)
@@ -88,6 +107,7 @@ func fmp4SeekAndMuxParts(
 	init []byte,
 	minTime time.Duration,
 	maxTime time.Duration,
+	sf *speedFilter,
 	w io.Writer,
 ) (time.Duration, error) {
 	minTimeMP4 := durationGoToMp4(minTime, 90000)
@@ -219,17 +239,10 @@ func fmp4SeekAndMuxParts(
 					}
 				}
 
-				err := outPart.Marshal(&outBuf)
-				if err != nil {
-					return nil, err
-				}
-
-				_, err = w.Write(outBuf.Bytes())
+				err := writePart(outPart, sf, &outBuf, w)
 				if err != nil {
 					return nil, err
 				}
-
-				outBuf.Reset()
 			}
 
 			outPart = nil
@@ -253,6 +266,7 @@ func fmp4MuxParts(
 	r io.ReadSeeker,
 	startTime time.Duration,
 	maxTime time.Duration,
+	sf *speedFilter,
 	w io.Writer,
 ) (time.Duration, error) {
 	maxTimeMP4 := durationGoToMp4(maxTime, 90000)
@@ -346,17 +360,10 @@ func fmp4MuxParts(
 
 		case "mdat":
 			if outPart.Tracks != nil {
-				err := outPart.Marshal(&outBuf)
+				err := writePart(outPart, sf, &outBuf, w)
 				if err != nil {
 					return nil, err
 				}
-
-				_, err = w.Write(outBuf.Bytes())
-				if err != nil {
-					return nil, err
-				}
-
-				outBuf.Reset()
 			}
 
 			outPart = nil
@@ -374,6 +381,7 @@ func fmp4SeekAndMux(
 	fpath string,
 	minTime time.Duration,
 	maxTime time.Duration,
+	sf *speedFilter,
 	w io.Writer,
 ) (time.Duration, error) {
 	f, err := os.Open(fpath)
@@ -387,7 +395,14 @@ func fmp4SeekAndMux(
 		return 0, err
 	}
 
-	elapsed, err := fmp4SeekAndMuxParts(f, init, minTime, maxTime, w)
+	if sf != nil {
+		err = sf.initialize(init)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	elapsed, err := fmp4SeekAndMuxParts(f, init, minTime, maxTime, sf, w)
 	if err != nil {
 		return 0, err
 	}
@@ -399,6 +414,7 @@ func fmp4Mux(
 	fpath string,
 	startTime time.Duration,
 	maxTime time.Duration,
+	sf *speedFilter,
 	w io.Writer,
 ) (time.Duration, error) {
 	f, err := os.Open(fpath)
@@ -407,7 +423,7 @@ func fmp4Mux(
 	}
 	defer f.Close()
 
-	return fmp4MuxParts(f, startTime, maxTime, w)
+	return fmp4MuxParts(f, startTime, maxTime, sf, w)
 }
 
 func fmp4Duration(fpath string) (time.Duration, error) {
@@ -469,6 +485,26 @@ func fmp4Duration(fpath string) (time.Duration, error) {
 			break
 		}
 
+		// skip prft, written when recordPrft is enabled
+		if bytes.Equal(buf[4:], []byte{'p', 'r', 'f', 't'}) {
+			prftSize := uint32(buf[0])<<24 | uint32(buf[1])<<16 | uint32(buf[2])<<8 | uint32(buf[3])
+
+			_, err = f.Seek(int64(prftSize)-8, io.SeekCurrent)
+			if err != nil {
+				break
+			}
+
+			moofPos, err = f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return 0, err
+			}
+
+			_, err = io.ReadFull(f, buf)
+			if err != nil {
+				break
+			}
+		}
+
 		if !bytes.Equal(buf[4:], []byte{'m', 'o', 'o', 'f'}) {
 			return 0, fmt.Errorf("moof box not found")
 		}
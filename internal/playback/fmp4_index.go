@@ -0,0 +1,174 @@
+package playback
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/abema/go-mp4"
+)
+
+// fmp4Keyframe is a keyframe of a fMP4 segment.
+type fmp4Keyframe struct {
+	// decode timestamp, relative to the start of the segment.
+	dts time.Duration
+
+	// position and size of the fragment (or subsegment) that starts with the keyframe,
+	// or that contains it.
+	offset uint64
+	size   uint64
+}
+
+// fmp4KeyframesFromSidx computes keyframes from the subsegments listed by a sidx box.
+func fmp4KeyframesFromSidx(sidx *mp4.Sidx, sidxEnd uint64) []fmp4Keyframe {
+	var out []fmp4Keyframe
+
+	offset := sidxEnd + sidx.GetFirstOffset()
+	elapsed := sidx.GetEarliestPresentationTime()
+
+	for _, ref := range sidx.References {
+		// references to other sidx boxes are not supported
+		if !ref.ReferenceType && ref.StartsWithSAP && sidx.Timescale != 0 {
+			out = append(out, fmp4Keyframe{
+				dts:    durationMp4ToGo(elapsed, sidx.Timescale),
+				offset: offset,
+				size:   uint64(ref.ReferencedSize),
+			})
+		}
+
+		offset += uint64(ref.ReferencedSize)
+		elapsed += uint64(ref.SubsegmentDuration)
+	}
+
+	return out
+}
+
+// fmp4Keyframes returns the keyframes of video tracks of a fMP4 segment.
+// When the segment has a sidx box, subsegments are used.
+// Otherwise, sample tables of fragments are scanned.
+func fmp4Keyframes(r io.ReadSeeker) ([]fmp4Keyframe, error) {
+	videoTracks := make(map[uint32]struct{})
+	var curTrackID uint32
+	var sidxKeyframes []fmp4Keyframe
+	sidxFound := false
+	var keyframes []fmp4Keyframe
+	moofOffset := uint64(0)
+	pendingStart := 0
+	var tfhd *mp4.Tfhd
+	var tfdt *mp4.Tfdt
+
+	_, err := mp4.ReadBoxStructure(r, func(h *mp4.ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type.String() {
+		case "moov", "trak", "mdia":
+			return h.Expand()
+
+		case "tkhd":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			curTrackID = box.(*mp4.Tkhd).TrackID
+
+		case "hdlr":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			if string(box.(*mp4.Hdlr).HandlerType[:]) == "vide" {
+				videoTracks[curTrackID] = struct{}{}
+			}
+
+		case "sidx":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			sidx := box.(*mp4.Sidx)
+
+			if _, ok := videoTracks[sidx.ReferenceID]; ok {
+				sidxFound = true
+				sidxKeyframes = append(sidxKeyframes,
+					fmp4KeyframesFromSidx(sidx, h.BoxInfo.Offset+h.BoxInfo.Size)...)
+			}
+
+		case "moof":
+			if sidxFound {
+				return nil, errTerminated
+			}
+			moofOffset = h.BoxInfo.Offset
+			pendingStart = len(keyframes)
+			return h.Expand()
+
+		case "traf":
+			return h.Expand()
+
+		case "tfhd":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			tfhd = box.(*mp4.Tfhd)
+
+		case "tfdt":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			tfdt = box.(*mp4.Tfdt)
+
+		case "trun":
+			if _, ok := videoTracks[tfhd.TrackID]; !ok {
+				return nil, nil
+			}
+
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			trun := box.(*mp4.Trun)
+
+			elapsed := tfdt.BaseMediaDecodeTimeV1
+
+			for _, e := range trun.Entries {
+				if (e.SampleFlags & sampleFlagIsNonSyncSample) == 0 {
+					keyframes = append(keyframes, fmp4Keyframe{
+						dts:    durationMp4ToGo(elapsed, 90000),
+						offset: moofOffset,
+					})
+				}
+				elapsed += uint64(e.SampleDuration)
+			}
+
+		case "mdat":
+			// keyframes of the current fragment are completed by its mdat
+			for i := pendingStart; i < len(keyframes); i++ {
+				keyframes[i].size = h.BoxInfo.Offset + h.BoxInfo.Size - moofOffset
+			}
+			pendingStart = len(keyframes)
+		}
+		return nil, nil
+	})
+	// segments that are being written may end with a truncated box
+	if err != nil && !errors.Is(err, errTerminated) &&
+		!errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	if sidxFound {
+		return sidxKeyframes, nil
+	}
+
+	// remove keyframes of an incomplete fragment
+	return keyframes[:pendingStart], nil
+}
+
+func fmp4KeyframesFromFile(fpath string) ([]fmp4Keyframe, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return fmp4Keyframes(f)
+}
@@ -0,0 +1,76 @@
+package playback
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/abema/go-mp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/test"
+)
+
+func TestFMP4KeyframesSidx(t *testing.T) {
+	init := fmp4.Init{
+		Tracks: []*fmp4.InitTrack{{
+			ID:        1,
+			TimeScale: 90000,
+			Codec: &fmp4.CodecH264{
+				SPS: test.FormatH264.SPS,
+				PPS: test.FormatH264.PPS,
+			},
+		}},
+	}
+
+	var buf seekablebuffer.Buffer
+	err := init.Marshal(&buf)
+	require.NoError(t, err)
+
+	sidx := &mp4.Sidx{
+		FullBox:                    mp4.FullBox{Version: 0},
+		ReferenceID:                1,
+		Timescale:                  1000,
+		EarliestPresentationTimeV0: 2000,
+		FirstOffsetV0:              0,
+		ReferenceCount:             3,
+		References: []mp4.SidxReference{
+			{ReferencedSize: 100, SubsegmentDuration: 1000, StartsWithSAP: true},
+			{ReferencedSize: 200, SubsegmentDuration: 1000, StartsWithSAP: false},
+			{ReferencedSize: 300, SubsegmentDuration: 1000, StartsWithSAP: true},
+		},
+	}
+
+	var payload bytes.Buffer
+	_, err = mp4.Marshal(&payload, sidx, mp4.Context{})
+	require.NoError(t, err)
+
+	sidxOffset := uint64(buf.Len())
+	sidxSize := uint64(8 + payload.Len())
+
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(sidxSize))
+	copy(header[4:], "sidx")
+
+	byts := append(buf.Bytes(), header...)
+	byts = append(byts, payload.Bytes()...)
+
+	keyframes, err := fmp4Keyframes(bytes.NewReader(byts))
+	require.NoError(t, err)
+
+	require.Equal(t, []fmp4Keyframe{
+		{
+			dts:    2 * time.Second,
+			offset: sidxOffset + sidxSize,
+			size:   100,
+		},
+		{
+			dts:    4 * time.Second,
+			offset: sidxOffset + sidxSize + 300,
+			size:   300,
+		},
+	}, keyframes)
+}
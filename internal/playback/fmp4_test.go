@@ -3,11 +3,15 @@ package playback
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
 	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/stretchr/testify/require"
 )
 
 func writeBenchInit(f io.WriteSeeker) {
@@ -73,3 +77,61 @@ func BenchmarkFMP4ReadInit(b *testing.B) {
 		}()
 	}
 }
+
+func TestFMP4DurationPrft(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-playback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	init := fmp4.Init{
+		Tracks: []*fmp4.InitTrack{{
+			ID:        1,
+			TimeScale: 90000,
+			Codec: &fmp4.CodecH264{
+				SPS: test.FormatH264.SPS,
+				PPS: test.FormatH264.PPS,
+			},
+		}},
+	}
+
+	var buf seekablebuffer.Buffer
+	err = init.Marshal(&buf)
+	require.NoError(t, err)
+	byts := buf.Bytes()
+
+	prft := []byte{
+		0x00, 0x00, 0x00, 0x20, 'p', 'r', 'f', 't',
+		0x01, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x01,
+		0xcb, 0xdd, 0xcb, 0xfd, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	for i := 0; i < 2; i++ {
+		part := fmp4.Part{
+			SequenceNumber: uint32(i),
+			Tracks: []*fmp4.PartTrack{{
+				ID:       1,
+				BaseTime: uint64(i) * 90000,
+				Samples: []*fmp4.PartSample{{
+					Duration: 90000,
+					Payload:  []byte{1, 2},
+				}},
+			}},
+		}
+
+		var buf seekablebuffer.Buffer
+		err = part.Marshal(&buf)
+		require.NoError(t, err)
+
+		byts = append(byts, prft...)
+		byts = append(byts, buf.Bytes()...)
+	}
+
+	fpath := filepath.Join(dir, "segment.mp4")
+	err = os.WriteFile(fpath, byts, 0o644)
+	require.NoError(t, err)
+
+	d, err := fmp4Duration(fpath)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, d)
+}
@@ -1,8 +1,10 @@
 package playback
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -149,6 +151,55 @@ func FindSegments(
 	return segments, nil
 }
 
+// SegmentFile contains details about the file of a segment.
+type SegmentFile struct {
+	Path     string
+	Start    time.Time
+	Duration *time.Duration
+	Size     int64
+}
+
+// FindSegmentFiles returns details about the files of all segments of a path.
+// Duration is available in case of fMP4 segments only.
+func FindSegmentFiles(
+	pathConf *conf.Path,
+	pathName string,
+) ([]*SegmentFile, error) {
+	segments, err := FindSegments(pathConf, pathName)
+	if err != nil {
+		if errors.Is(err, errNoSegmentsFound) {
+			return []*SegmentFile{}, nil
+		}
+		return nil, err
+	}
+
+	out := make([]*SegmentFile, 0, len(segments))
+
+	for _, seg := range segments {
+		fi, err := os.Stat(seg.fpath)
+		if err != nil {
+			// segment has been deleted in the meanwhile
+			continue
+		}
+
+		sf := &SegmentFile{
+			Path:  seg.fpath,
+			Start: seg.Start,
+			Size:  fi.Size(),
+		}
+
+		if pathConf.RecordFormat == conf.RecordFormatFMP4 {
+			if d, err := fmp4Duration(seg.fpath); err == nil {
+				sf.Duration = &d
+			}
+		}
+
+		out = append(out, sf)
+	}
+
+	return out, nil
+}
+
 func canBeConcatenated(seg1, seg2 *Segment) bool {
 	end1 := seg1.Start.Add(seg1.duration)
 	return !seg2.Start.Before(end1.Add(-concatenationTolerance)) && !seg2.Start.After(end1.Add(concatenationTolerance))
@@ -2,10 +2,14 @@
 package playback
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -33,11 +37,28 @@ func parseDuration(raw string) (time.Duration, error) {
 	return time.ParseDuration(raw)
 }
 
+// downloadAborted checks whether an error has been caused by a client disconnection.
+func downloadAborted(err error) bool {
+	var neterr *net.OpError
+	return errors.As(err, &neterr) || errors.Is(err, context.Canceled)
+}
+
 type listEntry struct {
 	Start    time.Time `json:"start"`
 	Duration float64   `json:"duration"`
 }
 
+type pathEntry struct {
+	Name string `json:"name"`
+}
+
+type indexEntry struct {
+	Time         time.Time `json:"time"`
+	SegmentStart time.Time `json:"segmentStart"`
+	Offset       uint64    `json:"offset"`
+	Size         uint64    `json:"size"`
+}
+
 type writerWrapper struct {
 	ctx     *gin.Context
 	written bool
@@ -54,13 +75,22 @@ func (w *writerWrapper) Write(p []byte) (int, error) {
 
 // Server is the playback server.
 type Server struct {
-	Address     string
-	ReadTimeout conf.StringDuration
-	PathConfs   map[string]*conf.Path
-	Parent      logger.Writer
-
+	Address            string
+	ReadTimeout        conf.StringDuration
+	TCPKeepalivePeriod conf.StringDuration
+	PathConfs          map[string]*conf.Path
+	PathDefaults       *conf.Path
+	AutoDiscover       bool
+	AutoDiscoverPeriod conf.StringDuration
+	Parent             logger.Writer
+
+	ctx        context.Context
+	ctxCancel  func()
+	wg         sync.WaitGroup
 	httpServer *httpp.WrappedServer
 	mutex      sync.RWMutex
+	discoverer discoverer
+	discovered []string
 }
 
 // Initialize initializes API.
@@ -72,6 +102,8 @@ func (p *Server) Initialize() error {
 
 	group.GET("/list", p.onList)
 	group.GET("/get", p.onGet)
+	group.GET("/index", p.onIndex)
+	group.GET("/paths", p.onPaths)
 
 	network, address := restrictnetwork.Restrict("tcp", p.Address)
 
@@ -80,8 +112,11 @@ func (p *Server) Initialize() error {
 		network,
 		address,
 		time.Duration(p.ReadTimeout),
+		time.Duration(p.TCPKeepalivePeriod),
 		"",
 		"",
+		0,
+		nil,
 		router,
 		p,
 	)
@@ -91,12 +126,21 @@ func (p *Server) Initialize() error {
 
 	p.Log(logger.Info, "listener opened on "+address)
 
+	p.ctx, p.ctxCancel = context.WithCancel(context.Background())
+
+	if p.AutoDiscover {
+		p.wg.Add(1)
+		go p.runDiscovery()
+	}
+
 	return nil
 }
 
 // Close closes Server.
 func (p *Server) Close() {
 	p.Log(logger.Info, "listener is closing")
+	p.ctxCancel()
+	p.wg.Wait()
 	p.httpServer.Close()
 }
 
@@ -112,6 +156,69 @@ func (p *Server) ReloadPathConfs(pathConfs map[string]*conf.Path) {
 	p.PathConfs = pathConfs
 }
 
+// runDiscovery periodically scans record paths
+// in order to serve recordings of paths that appear at runtime.
+// Scans are performed in background in order not to delay the startup.
+func (p *Server) runDiscovery() {
+	defer p.wg.Done()
+
+	p.discover()
+
+	t := time.NewTicker(time.Duration(p.AutoDiscoverPeriod))
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			p.discover()
+
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Server) discover() {
+	p.mutex.RLock()
+	pathConfs := p.PathConfs
+	p.mutex.RUnlock()
+
+	names := p.discoverer.discover(pathConfs, p.PathDefaults)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, name := range names {
+		if !p.isDiscovered(name) {
+			p.Log(logger.Info, "discovered recordings of path '%s'", name)
+		}
+	}
+
+	p.discovered = names
+}
+
+// DiscoveredPaths returns the names of discovered paths
+// that have playback enabled. It is called by api.API.
+func (p *Server) DiscoveredPaths() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	out := []string{}
+
+	for _, name := range p.discovered {
+		_, pathConf, _, err := conf.FindPathConf(p.PathConfs, name)
+		if err != nil {
+			pathConf = p.PathDefaults
+		}
+
+		if pathConf.Playback {
+			out = append(out, name)
+		}
+	}
+
+	return out
+}
+
 func (p *Server) writeError(ctx *gin.Context, status int, err error) {
 	// show error in logs
 	p.Log(logger.Error, err.Error())
@@ -125,9 +232,36 @@ func (p *Server) safeFindPathConf(name string) (*conf.Path, error) {
 	defer p.mutex.RUnlock()
 
 	_, pathConf, _, err := conf.FindPathConf(p.PathConfs, name)
+	if err != nil && p.isDiscovered(name) {
+		return p.discoveredPathConf(name), nil
+	}
+
 	return pathConf, err
 }
 
+func (p *Server) isDiscovered(name string) bool {
+	i := sort.SearchStrings(p.discovered, name)
+	return i < len(p.discovered) && p.discovered[i] == name
+}
+
+// discoveredPathConf returns the configuration of a discovered path,
+// that is a copy of default path settings.
+func (p *Server) discoveredPathConf(name string) *conf.Path {
+	pathConf := p.PathDefaults.Clone()
+	pathConf.Name = name
+	return pathConf
+}
+
+func (p *Server) onPaths(ctx *gin.Context) {
+	out := []pathEntry{}
+
+	for _, name := range p.DiscoveredPaths() {
+		out = append(out, pathEntry{Name: name})
+	}
+
+	ctx.JSON(http.StatusOK, out)
+}
+
 func (p *Server) onList(ctx *gin.Context) {
 	pathName := ctx.Query("path")
 
@@ -200,6 +334,29 @@ func (p *Server) onGet(ctx *gin.Context) {
 		return
 	}
 
+	var maxRate uint64
+	if v := ctx.Query("maxRate"); v != "" {
+		maxRate, err = strconv.ParseUint(v, 10, 64)
+		if err != nil || maxRate == 0 {
+			p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid maxRate: %s", v))
+			return
+		}
+	}
+
+	var sf *speedFilter
+	if v := ctx.Query("speed"); v != "" {
+		speed, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(speed > 0) || math.IsInf(speed, 0) {
+			p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid speed: %s", v))
+			return
+		}
+
+		speed = clampSpeed(speed)
+		if speed != 1 {
+			sf = &speedFilter{speed: speed}
+		}
+	}
+
 	pathConf, err := p.safeFindPathConf(pathName)
 	if err != nil {
 		p.writeError(ctx, http.StatusBadRequest, err)
@@ -222,6 +379,16 @@ func (p *Server) onGet(ctx *gin.Context) {
 	}
 
 	ww := &writerWrapper{ctx: ctx}
+
+	var w io.Writer = ww
+	if maxRate != 0 {
+		w = &throttledWriter{
+			ctx:     ctx.Request.Context(),
+			w:       ww,
+			maxRate: maxRate,
+		}
+	}
+
 	minTime := start.Sub(segments[0].Start)
 	maxTime := minTime + duration
 
@@ -229,11 +396,11 @@ func (p *Server) onGet(ctx *gin.Context) {
 		segments[0].fpath,
 		minTime,
 		maxTime,
-		ww)
+		sf,
+		w)
 	if err != nil {
 		// user aborted the download
-		var neterr *net.OpError
-		if errors.As(err, &neterr) {
+		if downloadAborted(err) {
 			return
 		}
 
@@ -262,11 +429,10 @@ func (p *Server) onGet(ctx *gin.Context) {
 			return
 		}
 
-		elapsed, err := fmp4Mux(seg.fpath, overallElapsed, duration, ctx.Writer)
+		elapsed, err := fmp4Mux(seg.fpath, overallElapsed, duration, sf, w)
 		if err != nil {
 			// user aborted the download
-			var neterr *net.OpError
-			if errors.As(err, &neterr) {
+			if downloadAborted(err) {
 				return
 			}
 
@@ -280,3 +446,65 @@ func (p *Server) onGet(ctx *gin.Context) {
 		overallElapsed += elapsed
 	}
 }
+
+func (p *Server) onIndex(ctx *gin.Context) {
+	pathName := ctx.Query("path")
+
+	start, err := time.Parse(time.RFC3339, ctx.Query("start"))
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid start: %w", err))
+		return
+	}
+
+	duration, err := parseDuration(ctx.Query("duration"))
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid duration: %w", err))
+		return
+	}
+
+	pathConf, err := p.safeFindPathConf(pathName)
+	if err != nil {
+		p.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	segments, err := findSegmentsInTimespan(pathConf, pathName, start, duration)
+	if err != nil {
+		if errors.Is(err, errNoSegmentsFound) {
+			p.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			p.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	if pathConf.RecordFormat != conf.RecordFormatFMP4 {
+		p.writeError(ctx, http.StatusBadRequest, fmt.Errorf("format of recording segments is not fmp4"))
+		return
+	}
+
+	end := start.Add(duration)
+	out := []indexEntry{}
+
+	for _, seg := range segments {
+		keyframes, err := fmp4KeyframesFromFile(seg.fpath)
+		if err != nil {
+			p.writeError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		for _, kf := range keyframes {
+			t := seg.Start.Add(kf.dts)
+			if !t.Before(start) && t.Before(end) {
+				out = append(out, indexEntry{
+					Time:         t,
+					SegmentStart: seg.Start,
+					Offset:       kf.offset,
+					Size:         kf.size,
+				})
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, out)
+}
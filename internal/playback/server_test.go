@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 
@@ -213,6 +214,145 @@ func TestServerGet(t *testing.T) {
 	}, parts)
 }
 
+func TestServerGetSpeed(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-playback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+	require.NoError(t, err)
+
+	writeSegment1(t, filepath.Join(dir, "mypath", "2008-11-07_11-22-00-500000.mp4"))
+	writeSegment2(t, filepath.Join(dir, "mypath", "2008-11-07_11-23-02-500000.mp4"))
+
+	s := &Server{
+		Address:     "127.0.0.1:9996",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		PathConfs: map[string]*conf.Path{
+			"mypath": {
+				Playback:   true,
+				RecordPath: filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+			},
+		},
+		Parent: &test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, ca := range []struct {
+		speed string
+		parts fmp4.Parts
+	}{
+		{
+			"2",
+			fmp4.Parts{
+				{
+					Tracks: []*fmp4.PartTrack{{
+						ID: 1,
+						Samples: []*fmp4.PartSample{
+							{
+								Duration: 0,
+								Payload:  []byte{3, 4},
+							},
+							{
+								Duration:        45000,
+								IsNonSyncSample: true,
+								Payload:         []byte{5, 6},
+							},
+						},
+					}},
+				},
+				{
+					Tracks: []*fmp4.PartTrack{{
+						ID:       1,
+						BaseTime: 45000,
+						Samples: []*fmp4.PartSample{{
+							Duration: 45000,
+							Payload:  []byte{7, 8},
+						}},
+					}},
+				},
+			},
+		},
+		{
+			"8",
+			fmp4.Parts{
+				{
+					Tracks: []*fmp4.PartTrack{{
+						ID: 1,
+						Samples: []*fmp4.PartSample{{
+							Duration: 11250,
+							Payload:  []byte{3, 4},
+						}},
+					}},
+				},
+				{
+					Tracks: []*fmp4.PartTrack{{
+						ID:       1,
+						BaseTime: 11250,
+						Samples: []*fmp4.PartSample{{
+							Duration: 11250,
+							Payload:  []byte{7, 8},
+						}},
+					}},
+				},
+			},
+		},
+	} {
+		t.Run(ca.speed, func(t *testing.T) {
+			v := url.Values{}
+			v.Set("path", "mypath")
+			v.Set("start", time.Date(2008, 11, 0o7, 11, 23, 1, 500000000, time.Local).Format(time.RFC3339Nano))
+			v.Set("duration", "2")
+			v.Set("speed", ca.speed)
+
+			u := &url.URL{
+				Scheme:   "http",
+				Host:     "localhost:9996",
+				Path:     "/get",
+				RawQuery: v.Encode(),
+			}
+
+			res, err := http.Get(u.String())
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			buf, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			var parts fmp4.Parts
+			err = parts.Unmarshal(buf)
+			require.NoError(t, err)
+
+			require.Equal(t, ca.parts, parts)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		v := url.Values{}
+		v.Set("path", "mypath")
+		v.Set("start", time.Date(2008, 11, 0o7, 11, 23, 1, 500000000, time.Local).Format(time.RFC3339Nano))
+		v.Set("duration", "2")
+		v.Set("speed", "-1")
+
+		u := &url.URL{
+			Scheme:   "http",
+			Host:     "localhost:9996",
+			Path:     "/get",
+			RawQuery: v.Encode(),
+		}
+
+		res, err := http.Get(u.String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
 func TestServerList(t *testing.T) {
 	dir, err := os.MkdirTemp("", "mediamtx-playback")
 	require.NoError(t, err)
@@ -274,3 +414,181 @@ func TestServerList(t *testing.T) {
 		},
 	}, out)
 }
+
+func TestServerAutoDiscover(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-playback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+	require.NoError(t, err)
+
+	writeSegment1(t, filepath.Join(dir, "mypath", "2008-11-07_11-22-00-500000.mp4"))
+
+	err = os.MkdirAll(filepath.Join(dir, "cams", "cam1"), 0o755)
+	require.NoError(t, err)
+
+	writeSegment1(t, filepath.Join(dir, "cams", "cam1", "2008-11-07_11-22-00-500000.mp4"))
+
+	s := &Server{
+		Address:     "127.0.0.1:9996",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		PathConfs: map[string]*conf.Path{
+			"~^cam": {
+				Name:       "~^cam",
+				Regexp:     regexp.MustCompile("^cam"),
+				Playback:   true,
+				RecordPath: filepath.Join(dir, "cams", "%path/%Y-%m-%d_%H-%M-%S-%f"),
+			},
+		},
+		PathDefaults: &conf.Path{
+			Playback:   true,
+			RecordPath: filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		},
+		AutoDiscover:       true,
+		AutoDiscoverPeriod: conf.StringDuration(100 * time.Millisecond),
+		Parent:             &test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	getPaths := func() interface{} {
+		res, err := http.Get("http://localhost:9996/paths")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var out interface{}
+		err = json.NewDecoder(res.Body).Decode(&out)
+		require.NoError(t, err)
+
+		return out
+	}
+
+	require.Eventually(t, func() bool {
+		return len(getPaths().([]interface{})) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"name": "cam1"},
+		map[string]interface{}{"name": "mypath"},
+	}, getPaths())
+
+	require.Equal(t, []string{"cam1", "mypath"}, s.DiscoveredPaths())
+
+	res, err := http.Get("http://localhost:9996/list?path=mypath")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	err = os.Mkdir(filepath.Join(dir, "newpath"), 0o755)
+	require.NoError(t, err)
+
+	writeSegment1(t, filepath.Join(dir, "newpath", "2008-11-07_11-22-00-500000.mp4"))
+
+	require.Eventually(t, func() bool {
+		return len(getPaths().([]interface{})) == 3
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"name": "cam1"},
+		map[string]interface{}{"name": "mypath"},
+		map[string]interface{}{"name": "newpath"},
+	}, getPaths())
+
+	res, err = http.Get("http://localhost:9996/list?path=newpath")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Get("http://localhost:9996/list?path=otherpath")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestServerIndex(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-playback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+	require.NoError(t, err)
+
+	writeSegment1(t, filepath.Join(dir, "mypath", "2008-11-07_11-22-00-500000.mp4"))
+	writeSegment2(t, filepath.Join(dir, "mypath", "2008-11-07_11-23-02-500000.mp4"))
+
+	s := &Server{
+		Address:     "127.0.0.1:9996",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		PathConfs: map[string]*conf.Path{
+			"mypath": {
+				Playback:   true,
+				RecordPath: filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+			},
+		},
+		Parent: &test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	v := url.Values{}
+	v.Set("path", "mypath")
+	v.Set("start", time.Date(2008, 11, 0o7, 11, 22, 45, 500000000, time.Local).Format(time.RFC3339Nano))
+	v.Set("duration", "20")
+
+	u := &url.URL{
+		Scheme:   "http",
+		Host:     "localhost:9996",
+		Path:     "/index",
+		RawQuery: v.Encode(),
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	require.NoError(t, err)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var out []indexEntry
+	err = json.NewDecoder(res.Body).Decode(&out)
+	require.NoError(t, err)
+
+	require.Len(t, out, 3)
+
+	for i, ca := range []struct {
+		time         time.Time
+		segmentStart time.Time
+	}{
+		{
+			time.Date(2008, 11, 0o7, 11, 23, 0, 500000000, time.Local),
+			time.Date(2008, 11, 0o7, 11, 22, 0, 500000000, time.Local),
+		},
+		{
+			time.Date(2008, 11, 0o7, 11, 23, 2, 500000000, time.Local),
+			time.Date(2008, 11, 0o7, 11, 23, 2, 500000000, time.Local),
+		},
+		{
+			time.Date(2008, 11, 0o7, 11, 23, 3, 500000000, time.Local),
+			time.Date(2008, 11, 0o7, 11, 23, 2, 500000000, time.Local),
+		},
+	} {
+		require.True(t, ca.time.Equal(out[i].Time))
+		require.True(t, ca.segmentStart.Equal(out[i].SegmentStart))
+	}
+
+	// keyframes of the same fragment share position and size
+	require.Equal(t, out[1].Offset, out[2].Offset)
+	require.Equal(t, out[1].Size, out[2].Size)
+
+	byts, err := os.ReadFile(filepath.Join(dir, "mypath", "2008-11-07_11-23-02-500000.mp4"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("moof"), byts[out[1].Offset+4:out[1].Offset+8])
+	require.Equal(t, uint64(len(byts)), out[1].Offset+out[1].Size)
+}
@@ -0,0 +1,111 @@
+package playback
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+)
+
+const (
+	minSpeed = 0.25
+	maxSpeed = 64
+
+	// above this speed, only key frames of video tracks are kept,
+	// since decoders are not able to decode all frames in time.
+	keyframesOnlySpeed = 4
+)
+
+// clampSpeed limits speed to the supported range.
+func clampSpeed(speed float64) float64 {
+	return math.Min(math.Max(speed, minSpeed), maxSpeed)
+}
+
+// speedFilter changes timestamps of parts in order to play them at a different speed.
+// Since compressed audio can't be retimed, audio tracks are removed.
+type speedFilter struct {
+	speed float64
+
+	videoTracks map[int]struct{}
+}
+
+func (f *speedFilter) initialize(init []byte) error {
+	var fi fmp4.Init
+	err := fi.Unmarshal(bytes.NewReader(init))
+	if err != nil {
+		return err
+	}
+
+	f.videoTracks = make(map[int]struct{})
+
+	for _, track := range fi.Tracks {
+		if track.Codec.IsVideo() {
+			f.videoTracks[track.ID] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+func (f *speedFilter) scale(v uint64) uint64 {
+	return uint64(math.Round(float64(v) / f.speed))
+}
+
+// apply retimes a part. Timestamps of the part must be relative to the start of the output,
+// therefore the filter doesn't need to keep any state between parts.
+func (f *speedFilter) apply(part *fmp4.Part) {
+	n := 0
+
+	for _, track := range part.Tracks {
+		if _, ok := f.videoTracks[track.ID]; !ok {
+			continue
+		}
+
+		if f.speed > keyframesOnlySpeed {
+			f.dropNonKeyframes(track)
+			if len(track.Samples) == 0 {
+				continue
+			}
+		}
+
+		// compute durations from scaled absolute timestamps,
+		// in order to avoid accumulating rounding errors.
+		t := track.BaseTime
+		track.BaseTime = f.scale(t)
+
+		for _, sa := range track.Samples {
+			end := t + uint64(sa.Duration)
+			sa.Duration = uint32(f.scale(end) - f.scale(t))
+			sa.PTSOffset = int32(math.Round(float64(sa.PTSOffset) / f.speed))
+			t = end
+		}
+
+		part.Tracks[n] = track
+		n++
+	}
+
+	part.Tracks = part.Tracks[:n]
+}
+
+// dropNonKeyframes removes non-key frames from a track.
+// Each key frame lasts until the next one.
+func (f *speedFilter) dropNonKeyframes(track *fmp4.PartTrack) {
+	var samples []*fmp4.PartSample
+
+	for _, sa := range track.Samples {
+		switch {
+		case !sa.IsNonSyncSample:
+			samples = append(samples, sa)
+
+		case samples != nil:
+			samples[len(samples)-1].Duration += sa.Duration
+
+		// samples that precede the first key frame of the part
+		// belong to a key frame that has already been written.
+		default:
+			track.BaseTime += uint64(sa.Duration)
+		}
+	}
+
+	track.Samples = samples
+}
@@ -0,0 +1,56 @@
+package playback
+
+import (
+	"testing"
+
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClampSpeed(t *testing.T) {
+	require.Equal(t, 0.25, clampSpeed(0.01))
+	require.Equal(t, 2.0, clampSpeed(2))
+	require.Equal(t, 64.0, clampSpeed(1000))
+}
+
+func TestSpeedFilterRemovesAudio(t *testing.T) {
+	sf := &speedFilter{
+		speed:       3,
+		videoTracks: map[int]struct{}{1: {}},
+	}
+
+	part := &fmp4.Part{
+		Tracks: []*fmp4.PartTrack{
+			{
+				ID:       1,
+				BaseTime: 90000,
+				Samples: []*fmp4.PartSample{
+					{Duration: 3000, Payload: []byte{1}},
+					{Duration: 3000, IsNonSyncSample: true, Payload: []byte{2}},
+					{Duration: 3000, IsNonSyncSample: true, Payload: []byte{3}},
+				},
+			},
+			{
+				ID:       2,
+				BaseTime: 90000,
+				Samples: []*fmp4.PartSample{
+					{Duration: 1024, Payload: []byte{4}},
+				},
+			},
+		},
+	}
+
+	sf.apply(part)
+
+	require.Equal(t, &fmp4.Part{
+		Tracks: []*fmp4.PartTrack{{
+			ID:       1,
+			BaseTime: 30000,
+			Samples: []*fmp4.PartSample{
+				{Duration: 1000, Payload: []byte{1}},
+				{Duration: 1000, IsNonSyncSample: true, Payload: []byte{2}},
+				{Duration: 1000, IsNonSyncSample: true, Payload: []byte{3}},
+			},
+		}},
+	}, part)
+}
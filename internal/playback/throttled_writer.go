@@ -0,0 +1,76 @@
+package playback
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// throttledWriter is a io.Writer that limits the rate of written data.
+// Waits are interrupted as soon as the context is canceled, i.e. when the client disconnects.
+type throttledWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	maxRate uint64 // bytes per second
+
+	start   time.Time
+	written uint64
+}
+
+func (t *throttledWriter) wait() error {
+	if t.start.IsZero() {
+		t.start = time.Now()
+		return nil
+	}
+
+	expected := time.Duration(float64(t.written) * float64(time.Second) / float64(t.maxRate))
+
+	d := expected - time.Since(t.start)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	}
+}
+
+// Write implements io.Writer.
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	// split data into chunks in order to avoid bursts.
+	chunkSize := int(t.maxRate / 10)
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	n := 0
+
+	for len(p) > 0 {
+		err := t.wait()
+		if err != nil {
+			return n, err
+		}
+
+		chunk := p
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+
+		nn, err := t.w.Write(chunk)
+		n += nn
+		t.written += uint64(nn)
+		if err != nil {
+			return n, err
+		}
+
+		p = p[nn:]
+	}
+
+	return n, nil
+}
@@ -0,0 +1,53 @@
+package playback
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := &throttledWriter{
+		ctx:     context.Background(),
+		w:       &buf,
+		maxRate: 1000,
+	}
+
+	start := time.Now()
+
+	n, err := w.Write(make([]byte, 300))
+	require.NoError(t, err)
+	require.Equal(t, 300, n)
+
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Equal(t, 300, buf.Len())
+}
+
+func TestThrottledWriterCancel(t *testing.T) {
+	var buf bytes.Buffer
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+
+	w := &throttledWriter{
+		ctx:     ctx,
+		w:       &buf,
+		maxRate: 10,
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ctxCancel()
+	}()
+
+	start := time.Now()
+
+	_, err := w.Write(make([]byte, 100))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Less(t, buf.Len(), 100)
+}
@@ -8,6 +8,7 @@ import (
 	// start pprof
 	_ "net/http/pprof"
 
+	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
@@ -20,15 +21,29 @@ type pprofParent interface {
 
 // PPROF is a pprof exporter.
 type PPROF struct {
-	Address     string
-	ReadTimeout conf.StringDuration
-	Parent      pprofParent
+	Address                   string
+	ReadTimeout               conf.StringDuration
+	TCPKeepalivePeriod        conf.StringDuration
+	ExternalAuthenticationURL string
+	User                      conf.Credential
+	Pass                      conf.Credential
+	IPs                       conf.IPsOrCIDRs
+	Parent                    pprofParent
 
 	httpServer *httpp.WrappedServer
 }
 
 // Initialize initializes PPROF.
 func (pp *PPROF) Initialize() error {
+	authenticator := &auth.HTTPAuthenticator{
+		ExternalAuthenticationURL: pp.ExternalAuthenticationURL,
+		User:                      pp.User,
+		Pass:                      pp.Pass,
+		IPs:                       pp.IPs,
+		Action:                    auth.ActionPprof,
+		Parent:                    pp,
+	}
+
 	network, address := restrictnetwork.Restrict("tcp", pp.Address)
 
 	var err error
@@ -36,9 +51,12 @@ func (pp *PPROF) Initialize() error {
 		network,
 		address,
 		time.Duration(pp.ReadTimeout),
+		time.Duration(pp.TCPKeepalivePeriod),
 		"",
 		"",
-		http.DefaultServeMux,
+		0,
+		nil,
+		authenticator.Wrap(http.DefaultServeMux),
 		pp,
 	)
 	if err != nil {
@@ -0,0 +1,75 @@
+package httpp
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// prefix of listen addresses of Unix sockets.
+const unixSocketPrefix = "unix://"
+
+// permissions of Unix sockets.
+const unixSocketMode = 0o660
+
+// removeStaleUnixSocket removes a socket file left by a previous instance.
+func removeStaleUnixSocket(fpath string) error {
+	fi, err := os.Lstat(fpath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("'%s' already exists and is not a socket", fpath)
+	}
+
+	conn, err := net.Dial("unix", fpath)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("'%s' is in use by another process", fpath)
+	}
+
+	return os.Remove(fpath)
+}
+
+func listenUnixSocket(fpath string) (net.Listener, error) {
+	err := removeStaleUnixSocket(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(fpath, unixSocketMode)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+// connections received through Unix sockets don't have a remote address,
+// they are considered local.
+type handlerUnixRemoteAddr struct {
+	http.Handler
+}
+
+func (h *handlerUnixRemoteAddr) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := net.SplitHostPort(r.RemoteAddr); err != nil {
+		r.RemoteAddr = "127.0.0.1:0"
+	}
+	h.Handler.ServeHTTP(w, r)
+}
+
+func isUnixSocket(address string) bool {
+	return strings.HasPrefix(address, unixSocketPrefix)
+}
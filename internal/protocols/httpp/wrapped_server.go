@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/bluenviron/mediamtx/internal/keepalive"
 	"github.com/bluenviron/mediamtx/internal/logger"
 )
 
@@ -19,7 +20,7 @@ func (nilWriter) Write(p []byte) (int, error) {
 }
 
 // WrappedServer is a wrapper around http.Server that provides:
-// - net.Listener allocation and closure
+// - net.Listener allocation and closure, with support for Unix sockets
 // - TLS allocation
 // - exit on panic
 // - logging
@@ -31,16 +32,28 @@ type WrappedServer struct {
 }
 
 // NewWrappedServer allocates a WrappedServer.
+// When address starts with "unix://", the server listens on a Unix socket,
+// that is removed when the server is closed.
 func NewWrappedServer(
 	network string,
 	address string,
 	readTimeout time.Duration,
+	tcpKeepalivePeriod time.Duration,
 	serverCert string,
 	serverKey string,
+	tlsMinVersion uint16,
+	tlsCipherSuites []uint16,
 	handler http.Handler,
 	parent logger.Writer,
 ) (*WrappedServer, error) {
-	ln, err := net.Listen(network, address)
+	var ln net.Listener
+	var err error
+
+	if isUnixSocket(address) {
+		ln, err = listenUnixSocket(address[len(unixSocketPrefix):])
+	} else {
+		ln, err = keepalive.Listen(network, address, tcpKeepalivePeriod)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +68,8 @@ func NewWrappedServer(
 
 		tlsConfig = &tls.Config{
 			Certificates: []tls.Certificate{crt},
+			MinVersion:   tlsMinVersion,
+			CipherSuites: tlsCipherSuites,
 		}
 	}
 
@@ -64,6 +79,9 @@ func NewWrappedServer(
 	h = &handlerServerHeader{h}
 	h = &handlerLogger{h, parent}
 	h = &handlerExitOnPanic{h}
+	if isUnixSocket(address) {
+		h = &handlerUnixRemoteAddr{h}
+	}
 
 	s := &WrappedServer{
 		ln: ln,
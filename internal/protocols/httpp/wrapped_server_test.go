@@ -1,14 +1,20 @@
 package httpp
 
 import (
+	"context"
+	"crypto/tls"
 	"io"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/test"
 )
 
 type testLogger struct{}
@@ -21,8 +27,11 @@ func TestFilterEmptyPath(t *testing.T) {
 		"tcp",
 		"localhost:4555",
 		10*time.Second,
+		15*time.Second,
 		"",
 		"",
+		0,
+		nil,
 		nil,
 		&testLogger{})
 	require.NoError(t, err)
@@ -42,3 +51,135 @@ func TestFilterEmptyPath(t *testing.T) {
 	_, err = io.ReadFull(conn, buf)
 	require.NoError(t, err)
 }
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-httpp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "api.sock")
+
+	// create a stale socket
+	ln, err := net.Listen("unix", fpath)
+	require.NoError(t, err)
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	s, err := NewWrappedServer(
+		"tcp",
+		"unix://"+fpath,
+		10*time.Second,
+		15*time.Second,
+		"",
+		"",
+		0,
+		nil,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.RemoteAddr)) //nolint:errcheck
+		}),
+		&testLogger{})
+	require.NoError(t, err)
+
+	fi, err := os.Stat(fpath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o660), fi.Mode().Perm())
+
+	_, err = NewWrappedServer(
+		"tcp",
+		"unix://"+fpath,
+		10*time.Second,
+		15*time.Second,
+		"",
+		"",
+		0,
+		nil,
+		nil,
+		&testLogger{})
+	require.EqualError(t, err, "'"+fpath+"' is in use by another process")
+
+	hc := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", fpath)
+		},
+	}}
+
+	res, err := hc.Get("http://localhost/")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	byts, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:0", string(byts))
+
+	s.Close()
+
+	_, err = os.Stat(fpath)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestTLSOptions(t *testing.T) {
+	serverCertFpath, err := test.CreateTempFile(test.TLSCertPub)
+	require.NoError(t, err)
+	defer os.Remove(serverCertFpath)
+
+	serverKeyFpath, err := test.CreateTempFile(test.TLSCertKey)
+	require.NoError(t, err)
+	defer os.Remove(serverKeyFpath)
+
+	s, err := NewWrappedServer(
+		"tcp",
+		"localhost:4555",
+		10*time.Second,
+		15*time.Second,
+		serverCertFpath,
+		serverKeyFpath,
+		tls.VersionTLS12,
+		[]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}),
+		&testLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, ca := range []struct {
+		name   string
+		config *tls.Config
+		ok     bool
+	}{
+		{
+			"allowed",
+			&tls.Config{
+				MaxVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			},
+			true,
+		},
+		{
+			"version too old",
+			&tls.Config{
+				MinVersion: tls.VersionTLS11,
+				MaxVersion: tls.VersionTLS11,
+			},
+			false,
+		},
+		{
+			"cipher suite not allowed",
+			&tls.Config{
+				MaxVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			},
+			false,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			ca.config.InsecureSkipVerify = true
+
+			conn, err := tls.Dial("tcp", "localhost:4555", ca.config)
+			if ca.ok {
+				require.NoError(t, err)
+				conn.Close()
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
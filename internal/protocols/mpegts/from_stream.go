@@ -10,7 +10,6 @@ import (
 	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
 	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
 	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
-	srt "github.com/datarhei/gosrt"
 
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/stream"
@@ -21,12 +20,17 @@ func durationGoToMPEGTS(v time.Duration) int64 {
 	return int64(v.Seconds() * 90000)
 }
 
+// DeadlineConn is a connection whose write deadline can be set.
+type DeadlineConn interface {
+	SetWriteDeadline(t time.Time) error
+}
+
 // FromStream links a server stream to a MPEG-TS writer.
 func FromStream(
 	stream *stream.Stream,
 	writer *asyncwriter.Writer,
 	bw *bufio.Writer,
-	sconn srt.Conn,
+	sconn DeadlineConn,
 	writeTimeout time.Duration,
 ) error {
 	var w *mcmpegts.Writer
@@ -0,0 +1,82 @@
+package mpegts
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// ReadFailureGrace allows a MPEG-TS reader to tolerate read failures
+// until a grace period elapses without receiving any data.
+// Since MPEG-TS is made of fixed-size packets with a sync byte,
+// reading can be resumed after a failure.
+//
+// Stalls are detected when no data is received for ReadTimeout,
+// and are tolerated for the grace period before the reader fails.
+type ReadFailureGrace struct {
+	// grace period. Zero means that failures are never tolerated.
+	Grace time.Duration
+
+	// maximum time that can pass without receiving data before a stall is detected.
+	ReadTimeout time.Duration
+
+	lastData time.Time
+	stalled  bool
+}
+
+type readFailureGraceReader struct {
+	r io.Reader
+	g *ReadFailureGrace
+}
+
+func (r *readFailureGraceReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.g.lastData = time.Now()
+		r.g.stalled = false
+	}
+	return n, err
+}
+
+// Wrap returns a io.Reader that resets the failure timer when data is received.
+func (g *ReadFailureGrace) Wrap(r io.Reader) io.Reader {
+	g.lastData = time.Now()
+	g.stalled = false
+	return &readFailureGraceReader{r: r, g: g}
+}
+
+// Deadline returns the read deadline, that is computed from the last time data was received.
+// When a stall has been detected, the deadline is extended by the grace period.
+func (g *ReadFailureGrace) Deadline() time.Time {
+	if g.stalled {
+		return g.lastData.Add(g.ReadTimeout + g.Grace)
+	}
+	return g.lastData.Add(g.ReadTimeout)
+}
+
+// Tolerate returns whether a read error can be ignored.
+// Timeouts are tolerated once per stall, closed connections are never tolerated.
+func (g *ReadFailureGrace) Tolerate(err error) bool {
+	if g.Grace <= 0 {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		if g.stalled {
+			return false
+		}
+		g.stalled = true
+		return true
+	}
+
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+		return false
+	}
+
+	if g.stalled {
+		return time.Since(g.lastData) < g.ReadTimeout+g.Grace
+	}
+	return time.Since(g.lastData) < g.Grace
+}
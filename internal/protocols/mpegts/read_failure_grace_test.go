@@ -0,0 +1,70 @@
+package mpegts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFailureGrace(t *testing.T) {
+	g := &ReadFailureGrace{
+		Grace:       100 * time.Millisecond,
+		ReadTimeout: time.Second,
+	}
+
+	r := g.Wrap(bytes.NewReader([]byte{1, 2, 3}))
+
+	start := time.Now()
+	_, err := r.Read(make([]byte, 3))
+	require.NoError(t, err)
+	require.False(t, g.Deadline().Before(start.Add(time.Second)))
+
+	require.True(t, g.Tolerate(fmt.Errorf("generic error")))
+	require.False(t, g.Tolerate(io.EOF))
+
+	time.Sleep(150 * time.Millisecond)
+	require.False(t, g.Tolerate(fmt.Errorf("generic error")))
+
+	// any data resets the timer.
+	r = g.Wrap(bytes.NewReader([]byte{1}))
+	_, err = r.Read(make([]byte, 1))
+	require.NoError(t, err)
+	require.True(t, g.Tolerate(fmt.Errorf("generic error")))
+}
+
+func TestReadFailureGraceStall(t *testing.T) {
+	g := &ReadFailureGrace{
+		Grace:       500 * time.Millisecond,
+		ReadTimeout: time.Second,
+	}
+
+	r := g.Wrap(bytes.NewReader([]byte{1, 2, 3}))
+	_, err := r.Read(make([]byte, 1))
+	require.NoError(t, err)
+
+	deadline := g.Deadline()
+
+	// a stall is tolerated for the grace period.
+	require.True(t, g.Tolerate(fmt.Errorf("wrapped: %w", os.ErrDeadlineExceeded)))
+	require.Equal(t, deadline.Add(500*time.Millisecond), g.Deadline())
+
+	// the source fails when the grace period elapses without data.
+	require.False(t, g.Tolerate(fmt.Errorf("wrapped: %w", os.ErrDeadlineExceeded)))
+
+	// any data ends the stall.
+	_, err = r.Read(make([]byte, 1))
+	require.NoError(t, err)
+	require.True(t, g.Tolerate(fmt.Errorf("wrapped: %w", os.ErrDeadlineExceeded)))
+}
+
+func TestReadFailureGraceDisabled(t *testing.T) {
+	g := &ReadFailureGrace{ReadTimeout: time.Second}
+	g.Wrap(bytes.NewReader(nil))
+	require.False(t, g.Tolerate(fmt.Errorf("generic error")))
+	require.False(t, g.Tolerate(fmt.Errorf("wrapped: %w", os.ErrDeadlineExceeded)))
+}
@@ -0,0 +1,157 @@
+package mpegts
+
+import (
+	"fmt"
+	"time"
+)
+
+// SCTE-35 splice command types.
+const (
+	SCTE35CommandSpliceInsert = 0x05
+	SCTE35CommandTimeSignal   = 0x06
+)
+
+const scte35TableID = 0xFC
+
+// SCTE35SpliceInfo contains the fields of a SCTE-35 splice information section
+// that are needed to place the splice point.
+type SCTE35SpliceInfo struct {
+	CommandType uint8
+
+	// splice_insert only.
+	EventID      uint32
+	Cancel       bool
+	OutOfNetwork bool
+	Duration     time.Duration
+
+	// splice time in 90khz units, including pts_adjustment.
+	// It is nil when the splice is immediate.
+	PTS *int64
+}
+
+func parseSpliceTime(buf []byte) (*int64, int, error) {
+	if len(buf) < 1 {
+		return nil, 0, fmt.Errorf("buffer is too short")
+	}
+
+	if (buf[0] & 0x80) == 0 {
+		return nil, 1, nil
+	}
+
+	if len(buf) < 5 {
+		return nil, 0, fmt.Errorf("buffer is too short")
+	}
+
+	v := int64(buf[0]&0x01)<<32 | int64(buf[1])<<24 | int64(buf[2])<<16 | int64(buf[3])<<8 | int64(buf[4])
+	return &v, 5, nil
+}
+
+// Unmarshal decodes a SCTE-35 splice information section.
+func (s *SCTE35SpliceInfo) Unmarshal(buf []byte) error {
+	*s = SCTE35SpliceInfo{}
+
+	if len(buf) < 14 {
+		return fmt.Errorf("buffer is too short")
+	}
+
+	if buf[0] != scte35TableID {
+		return fmt.Errorf("invalid table ID: %d", buf[0])
+	}
+
+	sectionLength := int(buf[1]&0x0F)<<8 | int(buf[2])
+	if sectionLength < 11 || len(buf) < 3+sectionLength {
+		return fmt.Errorf("invalid section length")
+	}
+
+	if (buf[4] & 0x80) != 0 {
+		return fmt.Errorf("encrypted sections are not supported")
+	}
+
+	ptsAdjustment := int64(buf[4]&0x01)<<32 | int64(buf[5])<<24 | int64(buf[6])<<16 | int64(buf[7])<<8 | int64(buf[8])
+
+	s.CommandType = buf[13]
+	cmd := buf[14 : 3+sectionLength]
+
+	switch s.CommandType {
+	case SCTE35CommandSpliceInsert:
+		if len(cmd) < 5 {
+			return fmt.Errorf("buffer is too short")
+		}
+
+		s.EventID = uint32(cmd[0])<<24 | uint32(cmd[1])<<16 | uint32(cmd[2])<<8 | uint32(cmd[3])
+		s.Cancel = (cmd[4] & 0x80) != 0
+		cmd = cmd[5:]
+
+		if s.Cancel {
+			return nil
+		}
+
+		if len(cmd) < 1 {
+			return fmt.Errorf("buffer is too short")
+		}
+
+		s.OutOfNetwork = (cmd[0] & 0x80) != 0
+		programSplice := (cmd[0] & 0x40) != 0
+		hasDuration := (cmd[0] & 0x20) != 0
+		immediate := (cmd[0] & 0x10) != 0
+		cmd = cmd[1:]
+
+		if programSplice {
+			if !immediate {
+				pts, n, err := parseSpliceTime(cmd)
+				if err != nil {
+					return err
+				}
+				s.PTS = pts
+				cmd = cmd[n:]
+			}
+		} else {
+			if len(cmd) < 1 {
+				return fmt.Errorf("buffer is too short")
+			}
+			componentCount := int(cmd[0])
+			cmd = cmd[1:]
+
+			for i := 0; i < componentCount; i++ {
+				if len(cmd) < 1 {
+					return fmt.Errorf("buffer is too short")
+				}
+				cmd = cmd[1:]
+
+				if !immediate {
+					pts, n, err := parseSpliceTime(cmd)
+					if err != nil {
+						return err
+					}
+					// the splice time of the first component is used.
+					if s.PTS == nil {
+						s.PTS = pts
+					}
+					cmd = cmd[n:]
+				}
+			}
+		}
+
+		if hasDuration {
+			if len(cmd) < 5 {
+				return fmt.Errorf("buffer is too short")
+			}
+			v := int64(cmd[0]&0x01)<<32 | int64(cmd[1])<<24 | int64(cmd[2])<<16 | int64(cmd[3])<<8 | int64(cmd[4])
+			s.Duration = time.Duration(v) * time.Second / 90000
+		}
+
+	case SCTE35CommandTimeSignal:
+		pts, _, err := parseSpliceTime(cmd)
+		if err != nil {
+			return err
+		}
+		s.PTS = pts
+	}
+
+	if s.PTS != nil {
+		v := (*s.PTS + ptsAdjustment) & 0x1FFFFFFFF
+		s.PTS = &v
+	}
+
+	return nil
+}
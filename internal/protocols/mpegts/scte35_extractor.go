@@ -0,0 +1,233 @@
+package mpegts
+
+import (
+	"io"
+)
+
+const (
+	tsPacketSize       = 188
+	tsSyncByte         = 0x47
+	streamTypeSCTE35   = 0x86
+	tableIDPAT         = 0x00
+	tableIDPMT         = 0x02
+	sectionHeaderSize  = 8
+	sectionTrailerSize = 4
+)
+
+// psiAssembler reassembles PSI sections split across multiple packets.
+type psiAssembler struct {
+	buf     []byte
+	started bool
+}
+
+func (a *psiAssembler) push(payload []byte, unitStart bool, cb func([]byte)) {
+	if unitStart {
+		if len(payload) < 1 {
+			a.reset()
+			return
+		}
+
+		pointer := int(payload[0])
+		if 1+pointer > len(payload) {
+			a.reset()
+			return
+		}
+
+		// bytes before the pointer complete the previous section.
+		if a.started {
+			a.buf = append(a.buf, payload[1:1+pointer]...)
+			a.flush(cb)
+		}
+
+		a.buf = append([]byte(nil), payload[1+pointer:]...)
+		a.started = true
+	} else {
+		if !a.started {
+			return
+		}
+		a.buf = append(a.buf, payload...)
+	}
+
+	a.flush(cb)
+}
+
+func (a *psiAssembler) flush(cb func([]byte)) {
+	for {
+		if len(a.buf) == 0 {
+			return
+		}
+
+		// stuffing bytes
+		if a.buf[0] == 0xFF {
+			a.reset()
+			return
+		}
+
+		if len(a.buf) < 3 {
+			return
+		}
+
+		l := 3 + (int(a.buf[1]&0x0F)<<8 | int(a.buf[2]))
+		if len(a.buf) < l {
+			return
+		}
+
+		cb(a.buf[:l:l])
+		a.buf = a.buf[l:]
+	}
+}
+
+func (a *psiAssembler) reset() {
+	a.buf = nil
+	a.started = false
+}
+
+// SCTE35Extractor is a io.Reader that extracts SCTE-35 splice information sections
+// from a MPEG-TS stream, which is not supported by the MPEG-TS reader.
+// OnSection is called from inside Read() for every section found.
+type SCTE35Extractor struct {
+	R io.Reader
+
+	// called when a section is found.
+	OnSection func(section []byte)
+
+	buf        []byte
+	assemblers map[uint16]*psiAssembler
+	pmtPIDs    map[uint16]struct{}
+	scte35PIDs map[uint16]struct{}
+}
+
+// Read implements io.Reader.
+func (e *SCTE35Extractor) Read(p []byte) (int, error) {
+	n, err := e.R.Read(p)
+	if n > 0 {
+		e.process(p[:n])
+	}
+	return n, err
+}
+
+func (e *SCTE35Extractor) process(byts []byte) {
+	if e.assemblers == nil {
+		e.assemblers = map[uint16]*psiAssembler{0: {}}
+		e.pmtPIDs = make(map[uint16]struct{})
+		e.scte35PIDs = make(map[uint16]struct{})
+	}
+
+	e.buf = append(e.buf, byts...)
+	buf := e.buf
+
+	for len(buf) >= tsPacketSize {
+		if buf[0] != tsSyncByte {
+			buf = buf[1:]
+			continue
+		}
+
+		e.processPacket(buf[:tsPacketSize])
+		buf = buf[tsPacketSize:]
+	}
+
+	e.buf = append(e.buf[:0], buf...)
+}
+
+func (e *SCTE35Extractor) processPacket(pkt []byte) {
+	pid := uint16(pkt[1]&0x1F)<<8 | uint16(pkt[2])
+
+	a, ok := e.assemblers[pid]
+	if !ok {
+		return
+	}
+
+	unitStart := (pkt[1] & 0x40) != 0
+	adaptationFieldControl := (pkt[3] >> 4) & 0x03
+
+	if (adaptationFieldControl & 0x01) == 0 {
+		return
+	}
+
+	payload := pkt[4:]
+
+	if (adaptationFieldControl & 0x02) != 0 {
+		l := 1 + int(payload[0])
+		if l > len(payload) {
+			return
+		}
+		payload = payload[l:]
+	}
+
+	a.push(payload, unitStart, func(section []byte) {
+		e.processSection(pid, section)
+	})
+}
+
+func (e *SCTE35Extractor) processSection(pid uint16, section []byte) {
+	switch {
+	case pid == 0:
+		if section[0] == tableIDPAT {
+			e.processPAT(section)
+		}
+
+	case hasPID(e.pmtPIDs, pid):
+		if section[0] == tableIDPMT {
+			e.processPMT(section)
+		}
+
+	case hasPID(e.scte35PIDs, pid):
+		if section[0] == scte35TableID && e.OnSection != nil {
+			e.OnSection(append([]byte(nil), section...))
+		}
+	}
+}
+
+func (e *SCTE35Extractor) processPAT(section []byte) {
+	if len(section) < sectionHeaderSize+sectionTrailerSize {
+		return
+	}
+
+	entries := section[sectionHeaderSize : len(section)-sectionTrailerSize]
+
+	for len(entries) >= 4 {
+		programNumber := uint16(entries[0])<<8 | uint16(entries[1])
+		pid := uint16(entries[2]&0x1F)<<8 | uint16(entries[3])
+		entries = entries[4:]
+
+		if programNumber != 0 && !hasPID(e.pmtPIDs, pid) {
+			e.pmtPIDs[pid] = struct{}{}
+			e.assemblers[pid] = &psiAssembler{}
+		}
+	}
+}
+
+func (e *SCTE35Extractor) processPMT(section []byte) {
+	if len(section) < sectionHeaderSize+4+sectionTrailerSize {
+		return
+	}
+
+	body := section[sectionHeaderSize : len(section)-sectionTrailerSize]
+
+	programInfoLength := int(body[2]&0x0F)<<8 | int(body[3])
+	if 4+programInfoLength > len(body) {
+		return
+	}
+	body = body[4+programInfoLength:]
+
+	for len(body) >= 5 {
+		streamType := body[0]
+		pid := uint16(body[1]&0x1F)<<8 | uint16(body[2])
+		esInfoLength := int(body[3]&0x0F)<<8 | int(body[4])
+
+		if 5+esInfoLength > len(body) {
+			return
+		}
+		body = body[5+esInfoLength:]
+
+		if streamType == streamTypeSCTE35 && !hasPID(e.scte35PIDs, pid) {
+			e.scte35PIDs[pid] = struct{}{}
+			e.assemblers[pid] = &psiAssembler{}
+		}
+	}
+}
+
+func hasPID(pids map[uint16]struct{}, pid uint16) bool {
+	_, ok := pids[pid]
+	return ok
+}
@@ -0,0 +1,103 @@
+package mpegts
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// splice_insert, out of network, with splice time and break duration.
+const testSCTE35Section = "/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo="
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestSCTE35SpliceInfoUnmarshal(t *testing.T) {
+	section, err := base64.StdEncoding.DecodeString(testSCTE35Section)
+	require.NoError(t, err)
+
+	var info SCTE35SpliceInfo
+	err = info.Unmarshal(section)
+	require.NoError(t, err)
+
+	require.Equal(t, SCTE35SpliceInfo{
+		CommandType:  SCTE35CommandSpliceInsert,
+		EventID:      0x4800008F,
+		OutOfNetwork: true,
+		Duration:     time.Duration(5426421) * time.Second / 90000,
+		PTS:          int64Ptr(1936310318),
+	}, info)
+}
+
+func tsPSIPacket(pid uint16, section []byte) []byte {
+	pkt := make([]byte, tsPacketSize)
+	for i := range pkt {
+		pkt[i] = 0xFF
+	}
+
+	pkt[0] = tsSyncByte
+	pkt[1] = 0x40 | byte(pid>>8)
+	pkt[2] = byte(pid)
+	pkt[3] = 0x10
+	pkt[4] = 0 // pointer field
+	copy(pkt[5:], section)
+
+	return pkt
+}
+
+func TestSCTE35Extractor(t *testing.T) {
+	section, err := base64.StdEncoding.DecodeString(testSCTE35Section)
+	require.NoError(t, err)
+
+	var stream []byte
+
+	// PAT: program 1 -> PMT PID 0x1000
+	stream = append(stream, tsPSIPacket(0, []byte{
+		0x00, 0xB0, 0x0D, 0x00, 0x01, 0xC1, 0x00, 0x00,
+		0x00, 0x01, 0xF0, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	})...)
+
+	// PMT: H264 on PID 0x100, SCTE-35 on PID 0x101
+	stream = append(stream, tsPSIPacket(0x1000, []byte{
+		0x02, 0xB0, 0x17, 0x00, 0x01, 0xC1, 0x00, 0x00,
+		0xE1, 0x00, 0xF0, 0x00,
+		0x1B, 0xE1, 0x00, 0xF0, 0x00,
+		0x86, 0xE1, 0x01, 0xF0, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	})...)
+
+	stream = append(stream, tsPSIPacket(0x101, section)...)
+
+	var sections [][]byte
+
+	e := &SCTE35Extractor{
+		R: bytes.NewReader(stream),
+		OnSection: func(section []byte) {
+			sections = append(sections, section)
+		},
+	}
+
+	// read with a small buffer in order to split packets.
+	buf := make([]byte, 100)
+	for {
+		_, err := e.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, [][]byte{section}, sections)
+}
+
+func TestSCTE35PTSDiff(t *testing.T) {
+	require.Equal(t, time.Second, scte35PTSDiff(90000, 0))
+	require.Equal(t, -time.Second, scte35PTSDiff(0, 90000))
+	require.Equal(t, time.Second, scte35PTSDiff(45000, 0x1FFFFFFFF-44999))
+}
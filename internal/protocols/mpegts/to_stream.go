@@ -18,15 +18,23 @@ var ErrNoTracks = errors.New("no supported tracks found (supported are H265, H26
 	" MPEG-4 Video, MPEG-1/2 Video, Opus, MPEG-4 Audio, MPEG-1 Audio, AC-3")
 
 // ToStream converts a MPEG-TS stream to a server stream.
-func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, error) {
+// When scte35 is not nil, SCTE-35 splice information is forwarded to the stream too.
+func ToStream(r *mpegts.Reader, scte35 *SCTE35Extractor, stream **stream.Stream) ([]*description.Media, error) {
 	var medias []*description.Media //nolint:prealloc
 
 	var td *mpegts.TimeDecoder
+	var lastRawPTS int64
+	var lastPTS time.Duration
+	var lastNTP time.Time
+
 	decodeTime := func(t int64) time.Duration {
 		if td == nil {
 			td = mpegts.NewTimeDecoder(t)
 		}
-		return td.Decode(t)
+		lastRawPTS = t
+		lastPTS = td.Decode(t)
+		lastNTP = time.Now()
+		return lastPTS
 	}
 
 	for _, track := range r.Tracks() { //nolint:dupl
@@ -200,5 +208,47 @@ func ToStream(r *mpegts.Reader, stream **stream.Stream) ([]*description.Media, e
 		return nil, ErrNoTracks
 	}
 
+	if scte35 != nil {
+		scte35.OnSection = func(section []byte) {
+			// splice times are expressed relative to the last received PTS.
+			if td == nil {
+				return
+			}
+
+			var info SCTE35SpliceInfo
+			err := info.Unmarshal(section)
+			if err != nil {
+				return
+			}
+
+			pts := lastPTS
+			ntp := lastNTP
+
+			if info.PTS != nil {
+				diff := scte35PTSDiff(*info.PTS, lastRawPTS)
+				pts += diff
+				ntp = ntp.Add(diff)
+			}
+
+			(*stream).WriteSCTE35(&unit.SCTE35{
+				Base: unit.Base{
+					NTP: ntp,
+					PTS: pts,
+				},
+				Section: section,
+			})
+		}
+	}
+
 	return medias, nil
 }
+
+// scte35PTSDiff returns the difference between two 33-bit timestamps,
+// taking into account overflows.
+func scte35PTSDiff(a int64, b int64) time.Duration {
+	diff := (a - b) & 0x1FFFFFFFF
+	if diff >= 0x100000000 {
+		diff -= 0x200000000
+	}
+	return time.Duration(diff) * time.Second / 90000
+}
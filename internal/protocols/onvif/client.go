@@ -0,0 +1,250 @@
+// Package onvif contains a minimal ONVIF client.
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	deviceServicePath = "/onvif/device_service"
+
+	nsDevice = "http://www.onvif.org/ver10/device/wsdl"
+	nsMedia  = "http://www.onvif.org/ver10/media/wsdl"
+	nsSchema = "http://www.onvif.org/ver10/schema"
+	nsWSSE   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	nsWSU    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+
+	passwordDigestType = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0" +
+		"#PasswordDigest"
+	nonceEncodingType = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0" +
+		"#Base64Binary"
+
+	maxResponseSize = 1024 * 1024
+)
+
+// PasswordDigest computes the password digest of a WS-Security UsernameToken.
+func PasswordDigest(nonce []byte, created string, pass string) string {
+	h := sha1.New() //nolint:gosec
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(pass))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+type envelope struct {
+	Body struct {
+		Content []byte `xml:",innerxml"`
+	} `xml:"Body"`
+}
+
+type fault struct {
+	Reason struct {
+		Text string `xml:"Text"`
+	} `xml:"Reason"`
+	// SOAP 1.1
+	FaultString string `xml:"faultstring"`
+}
+
+func (f fault) message() string {
+	if f.Reason.Text != "" {
+		return f.Reason.Text
+	}
+	return f.FaultString
+}
+
+type getSystemDateAndTimeResponse struct {
+	UTCDateTime struct {
+		Date struct {
+			Year  int `xml:"Year"`
+			Month int `xml:"Month"`
+			Day   int `xml:"Day"`
+		} `xml:"Date"`
+		Time struct {
+			Hour   int `xml:"Hour"`
+			Minute int `xml:"Minute"`
+			Second int `xml:"Second"`
+		} `xml:"Time"`
+	} `xml:"SystemDateAndTime>UTCDateTime"`
+}
+
+type getCapabilitiesResponse struct {
+	MediaXAddr string `xml:"Capabilities>Media>XAddr"`
+}
+
+type getStreamURIResponse struct {
+	URI string `xml:"MediaUri>Uri"`
+}
+
+// Client is a ONVIF client.
+// Requests are authenticated with WS-Security UsernameToken.
+type Client struct {
+	HTTPClient *http.Client
+	URL        *url.URL
+	User       string
+	Pass       string
+
+	// difference between the clock of the device and the local one.
+	clockOffset time.Duration
+}
+
+func (c *Client) securityHeader() (string, error) {
+	if c.User == "" {
+		return "", nil
+	}
+
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	created := time.Now().Add(c.clockOffset).UTC().Format("2006-01-02T15:04:05.000Z")
+
+	return `<s:Header>` +
+		`<Security s:mustUnderstand="1" xmlns="` + nsWSSE + `">` +
+		`<UsernameToken>` +
+		`<Username>` + html.EscapeString(c.User) + `</Username>` +
+		`<Password Type="` + passwordDigestType + `">` + PasswordDigest(nonce, created, c.Pass) + `</Password>` +
+		`<Nonce EncodingType="` + nonceEncodingType + `">` + base64.StdEncoding.EncodeToString(nonce) + `</Nonce>` +
+		`<Created xmlns="` + nsWSU + `">` + created + `</Created>` +
+		`</UsernameToken>` +
+		`</Security>` +
+		`</s:Header>`, nil
+}
+
+func (c *Client) do(ctx context.Context, ur string, authenticate bool, body string, out interface{}) error {
+	header := ""
+
+	if authenticate {
+		var err error
+		header, err = c.securityHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	byts := []byte(`<?xml version="1.0" encoding="UTF-8"?>` +
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">` +
+		header +
+		`<s:Body>` + body + `</s:Body>` +
+		`</s:Envelope>`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ur, bytes.NewReader(byts))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	var env envelope
+	err = xml.Unmarshal(resBody, &env)
+	if err != nil {
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("bad status code: %v", res.StatusCode)
+		}
+		return fmt.Errorf("invalid SOAP response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var f fault
+		err = xml.Unmarshal(env.Body.Content, &f)
+		if err == nil && f.message() != "" {
+			return fmt.Errorf("bad status code: %v (%s)", res.StatusCode, strings.TrimSpace(f.message()))
+		}
+		return fmt.Errorf("bad status code: %v", res.StatusCode)
+	}
+
+	return xml.Unmarshal(env.Body.Content, out)
+}
+
+func (c *Client) deviceServiceURL() string {
+	u := &url.URL{
+		Scheme: c.URL.Scheme,
+		Host:   c.URL.Host,
+		Path:   deviceServicePath,
+	}
+	return u.String()
+}
+
+// syncClock reads the clock of the device,
+// in order to generate UsernameTokens that are accepted
+// even when clocks are not synchronized.
+func (c *Client) syncClock(ctx context.Context) {
+	var res getSystemDateAndTimeResponse
+	err := c.do(ctx, c.deviceServiceURL(), false,
+		`<GetSystemDateAndTime xmlns="`+nsDevice+`"/>`, &res)
+	if err != nil || res.UTCDateTime.Date.Year == 0 {
+		return
+	}
+
+	deviceTime := time.Date(
+		res.UTCDateTime.Date.Year,
+		time.Month(res.UTCDateTime.Date.Month),
+		res.UTCDateTime.Date.Day,
+		res.UTCDateTime.Time.Hour,
+		res.UTCDateTime.Time.Minute,
+		res.UTCDateTime.Time.Second,
+		0,
+		time.UTC)
+
+	c.clockOffset = time.Until(deviceTime)
+}
+
+// GetStreamURI returns the RTSP URI of the stream of a media profile.
+func (c *Client) GetStreamURI(ctx context.Context, profileToken string) (string, error) {
+	c.syncClock(ctx)
+
+	var capRes getCapabilitiesResponse
+	err := c.do(ctx, c.deviceServiceURL(), true,
+		`<GetCapabilities xmlns="`+nsDevice+`"><Category>Media</Category></GetCapabilities>`, &capRes)
+	if err != nil {
+		return "", fmt.Errorf("GetCapabilities failed: %w", err)
+	}
+
+	if capRes.MediaXAddr == "" {
+		return "", fmt.Errorf("device doesn't provide a media service")
+	}
+
+	var uriRes getStreamURIResponse
+	err = c.do(ctx, strings.TrimSpace(capRes.MediaXAddr), true,
+		`<GetStreamUri xmlns="`+nsMedia+`">`+
+			`<StreamSetup>`+
+			`<Stream xmlns="`+nsSchema+`">RTP-Unicast</Stream>`+
+			`<Transport xmlns="`+nsSchema+`"><Protocol>RTSP</Protocol></Transport>`+
+			`</StreamSetup>`+
+			`<ProfileToken>`+html.EscapeString(profileToken)+`</ProfileToken>`+
+			`</GetStreamUri>`, &uriRes)
+	if err != nil {
+		return "", fmt.Errorf("GetStreamUri failed: %w", err)
+	}
+
+	uri := strings.TrimSpace(uriRes.URI)
+	if uri == "" {
+		return "", fmt.Errorf("device returned an empty stream URI")
+	}
+
+	return uri, nil
+}
@@ -0,0 +1,132 @@
+package onvif
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testUsernameToken struct {
+	Username string `xml:"Header>Security>UsernameToken>Username"`
+	Password string `xml:"Header>Security>UsernameToken>Password"`
+	Nonce    string `xml:"Header>Security>UsernameToken>Nonce"`
+	Created  string `xml:"Header>Security>UsernameToken>Created"`
+}
+
+func checkUsernameToken(body []byte, user string, pass string) bool {
+	var tok testUsernameToken
+	err := xml.Unmarshal(body, &tok)
+	if err != nil || tok.Username != user {
+		return false
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(tok.Nonce)
+	if err != nil {
+		return false
+	}
+
+	return tok.Password == PasswordDigest(nonce, tok.Created, pass)
+}
+
+func writeSOAP(w http.ResponseWriter, statusCode int, body string) {
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>` + //nolint:errcheck
+		`<SOAP-ENV:Envelope xmlns:SOAP-ENV="http://www.w3.org/2003/05/soap-envelope"` +
+		` xmlns:tds="http://www.onvif.org/ver10/device/wsdl"` +
+		` xmlns:trt="http://www.onvif.org/ver10/media/wsdl"` +
+		` xmlns:tt="http://www.onvif.org/ver10/schema">` +
+		`<SOAP-ENV:Body>` + body + `</SOAP-ENV:Body>` +
+		`</SOAP-ENV:Envelope>`))
+}
+
+func TestClientGetStreamURI(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:8180")
+	require.NoError(t, err)
+
+	s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		switch {
+		case strings.Contains(string(body), "GetSystemDateAndTime"):
+			// the clock of the device is one hour ahead
+			now := time.Now().UTC().Add(1 * time.Hour)
+			writeSOAP(w, http.StatusOK, `<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime>`+
+				`<tt:UTCDateTime>`+
+				`<tt:Date><tt:Year>`+now.Format("2006")+`</tt:Year><tt:Month>`+now.Format("1")+
+				`</tt:Month><tt:Day>`+now.Format("2")+`</tt:Day></tt:Date>`+
+				`<tt:Time><tt:Hour>`+now.Format("15")+`</tt:Hour><tt:Minute>`+now.Format("4")+
+				`</tt:Minute><tt:Second>`+now.Format("5")+`</tt:Second></tt:Time>`+
+				`</tt:UTCDateTime>`+
+				`</tds:SystemDateAndTime></tds:GetSystemDateAndTimeResponse>`)
+			return
+		}
+
+		if !checkUsernameToken(body, "myuser", "mypass") {
+			writeSOAP(w, http.StatusBadRequest, `<SOAP-ENV:Fault><SOAP-ENV:Reason>`+
+				`<SOAP-ENV:Text xml:lang="en">Sender not Authorized</SOAP-ENV:Text>`+
+				`</SOAP-ENV:Reason></SOAP-ENV:Fault>`)
+			return
+		}
+
+		var tok testUsernameToken
+		xml.Unmarshal(body, &tok) //nolint:errcheck
+		created, err := time.Parse(time.RFC3339, tok.Created)
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now().Add(1*time.Hour), created, 10*time.Second)
+
+		switch {
+		case r.URL.Path == "/onvif/device_service" && strings.Contains(string(body), "GetCapabilities"):
+			writeSOAP(w, http.StatusOK, `<tds:GetCapabilitiesResponse><tds:Capabilities>`+
+				`<tt:Media><tt:XAddr>http://127.0.0.1:8180/onvif/media_service</tt:XAddr></tt:Media>`+
+				`</tds:Capabilities></tds:GetCapabilitiesResponse>`)
+
+		case r.URL.Path == "/onvif/media_service" && strings.Contains(string(body), "GetStreamUri"):
+			require.Contains(t, string(body), "<ProfileToken>Profile_1</ProfileToken>")
+			writeSOAP(w, http.StatusOK, `<trt:GetStreamUriResponse><trt:MediaUri>`+
+				`<tt:Uri>rtsp://127.0.0.1:554/stream1</tt:Uri>`+
+				`</trt:MediaUri></trt:GetStreamUriResponse>`)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})}
+
+	go s.Serve(ln)
+	defer s.Shutdown(context.Background())
+
+	t.Run("ok", func(t *testing.T) {
+		c := &Client{
+			HTTPClient: &http.Client{},
+			URL:        &url.URL{Scheme: "http", Host: "127.0.0.1:8180"},
+			User:       "myuser",
+			Pass:       "mypass",
+		}
+
+		uri, err := c.GetStreamURI(context.Background(), "Profile_1")
+		require.NoError(t, err)
+		require.Equal(t, "rtsp://127.0.0.1:554/stream1", uri)
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		c := &Client{
+			HTTPClient: &http.Client{},
+			URL:        &url.URL{Scheme: "http", Host: "127.0.0.1:8180"},
+			User:       "myuser",
+			Pass:       "wrongpass",
+		}
+
+		_, err := c.GetStreamURI(context.Background(), "Profile_1")
+		require.EqualError(t, err, "GetCapabilities failed: bad status code: 400 (Sender not Authorized)")
+	})
+}
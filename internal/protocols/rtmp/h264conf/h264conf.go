@@ -19,39 +19,57 @@ func (c *Conf) Unmarshal(buf []byte) error {
 
 	pos := 5
 
-	spsCount := buf[pos] & 0x1F
+	spsCount := int(buf[pos] & 0x1F)
 	pos++
-	if spsCount != 1 {
-		return fmt.Errorf("sps count != 1 is unsupported")
+	if spsCount == 0 {
+		return fmt.Errorf("sps count is zero")
 	}
 
-	spsLen := int(uint16(buf[pos])<<8 | uint16(buf[pos+1]))
-	pos += 2
-	if (len(buf) - pos) < spsLen {
-		return fmt.Errorf("invalid size 2")
+	// when there are multiple parameters, use the first one
+	for i := 0; i < spsCount; i++ {
+		if (len(buf) - pos) < 2 {
+			return fmt.Errorf("invalid size 2")
+		}
+
+		spsLen := int(uint16(buf[pos])<<8 | uint16(buf[pos+1]))
+		pos += 2
+		if (len(buf) - pos) < spsLen {
+			return fmt.Errorf("invalid size 2")
+		}
+
+		if i == 0 {
+			c.SPS = buf[pos : pos+spsLen]
+		}
+		pos += spsLen
 	}
 
-	c.SPS = buf[pos : pos+spsLen]
-	pos += spsLen
-
 	if (len(buf) - pos) < 3 {
 		return fmt.Errorf("invalid size 3")
 	}
 
-	ppsCount := buf[pos]
+	ppsCount := int(buf[pos])
 	pos++
-	if ppsCount != 1 {
-		return fmt.Errorf("pps count != 1 is unsupported")
+	if ppsCount == 0 {
+		return fmt.Errorf("pps count is zero")
 	}
 
-	ppsLen := int(uint16(buf[pos])<<8 | uint16(buf[pos+1]))
-	pos += 2
-	if (len(buf) - pos) < ppsLen {
-		return fmt.Errorf("invalid size")
+	for i := 0; i < ppsCount; i++ {
+		if (len(buf) - pos) < 2 {
+			return fmt.Errorf("invalid size")
+		}
+
+		ppsLen := int(uint16(buf[pos])<<8 | uint16(buf[pos+1]))
+		pos += 2
+		if (len(buf) - pos) < ppsLen {
+			return fmt.Errorf("invalid size")
+		}
+
+		if i == 0 {
+			c.PPS = buf[pos : pos+ppsLen]
+		}
+		pos += ppsLen
 	}
 
-	c.PPS = buf[pos : pos+ppsLen]
-
 	return nil
 }
 
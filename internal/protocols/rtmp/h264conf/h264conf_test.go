@@ -22,6 +22,16 @@ func TestUnmarshal(t *testing.T) {
 	require.Equal(t, decoded, dec)
 }
 
+func TestUnmarshalMultipleParams(t *testing.T) {
+	var dec Conf
+	err := dec.Unmarshal([]byte{
+		0x1, 0x32, 0xa3, 0x8, 0xff, 0xe2, 0x0, 0x4, 0x45, 0x32, 0xa3, 0x8, 0x0, 0x2, 0x45, 0x33,
+		0x2, 0x0, 0x2, 0x45, 0x34, 0x0, 0x2, 0x45, 0x35,
+	})
+	require.NoError(t, err)
+	require.Equal(t, decoded, dec)
+}
+
 func TestMarshal(t *testing.T) {
 	enc, err := decoded.Marshal()
 	require.NoError(t, err)
@@ -120,17 +120,112 @@ func h265FindNALU(array []mp4.HEVCNaluArray, typ h265.NALUType) []byte {
 	return nil
 }
 
-func trackFromH264DecoderConfig(data []byte) (format.Format, error) {
+// h264SplitNALU splits a NALU that contains Annex-B start codes.
+func h264SplitNALU(nalu []byte) ([][]byte, error) {
+	if !bytes.Contains(nalu, []byte{0x00, 0x00, 0x01}) {
+		return [][]byte{nalu}, nil
+	}
+
+	// remove the leading start code, if present
+	trimmed := bytes.TrimLeft(nalu, "\x00")
+	if (len(nalu)-len(trimmed)) >= 2 && len(trimmed) >= 1 && trimmed[0] == 0x01 {
+		nalu = trimmed[1:]
+	}
+
+	return h264.AnnexBUnmarshal(append([]byte{0x00, 0x00, 0x00, 0x01}, nalu...))
+}
+
+// h264UnmarshalAU decodes a H264 access unit.
+// Access units are supposed to be in AVCC format, but some encoders
+// use Annex-B start codes instead of, or inside, AVCC framing.
+func h264UnmarshalAU(payload []byte) ([][]byte, error) {
+	au, err := h264.AVCCUnmarshal(payload)
+	if err != nil {
+		if errors.Is(err, h264.ErrAVCCNoNALUs) {
+			return nil, err
+		}
+
+		var err2 error
+		au, err2 = h264.AnnexBUnmarshal(payload)
+		if err2 != nil {
+			return nil, fmt.Errorf("unable to decode AVCC: %w", err)
+		}
+
+		return au, nil
+	}
+
+	var ret [][]byte
+
+	for _, nalu := range au {
+		split, err := h264SplitNALU(nalu)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, split...)
+	}
+
+	return ret, nil
+}
+
+// h264FindParams returns SPS and PPS contained in an access unit.
+func h264FindParams(au [][]byte) ([]byte, []byte) {
+	var sps []byte
+	var pps []byte
+
+	for _, nalu := range au {
+		switch h264.NALUType(nalu[0] & 0x1F) {
+		case h264.NALUTypeSPS:
+			sps = nalu
+
+		case h264.NALUTypePPS:
+			pps = nalu
+		}
+	}
+
+	return sps, pps
+}
+
+func h264ParamsFromDecoderConfig(data []byte) ([]byte, []byte, error) {
 	var conf h264conf.Conf
 	err := conf.Unmarshal(data)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse H264 config: %w", err)
+		// some encoders send parameters with Annex-B start codes
+		// instead of a AVCDecoderConfigurationRecord
+		au, err2 := h264.AnnexBUnmarshal(data)
+		if err2 == nil {
+			sps, pps := h264FindParams(au)
+			if sps != nil && pps != nil {
+				return sps, pps, nil
+			}
+		}
+
+		return nil, nil, fmt.Errorf("unable to parse H264 config: %w", err)
+	}
+
+	// some encoders put start codes inside parameters
+	sps, err := h264SplitNALU(conf.SPS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse H264 config: %w", err)
+	}
+
+	pps, err := h264SplitNALU(conf.PPS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse H264 config: %w", err)
+	}
+
+	return sps[0], pps[0], nil
+}
+
+func trackFromH264DecoderConfig(data []byte) (format.Format, error) {
+	sps, pps, err := h264ParamsFromDecoderConfig(data)
+	if err != nil {
+		return nil, err
 	}
 
 	return &format.H264{
 		PayloadTyp:        96,
-		SPS:               conf.SPS,
-		PPS:               conf.PPS,
+		SPS:               sps,
+		PPS:               pps,
 		PacketizationMode: 1,
 	}, nil
 }
@@ -211,8 +306,9 @@ func tracksFromMetadata(conn *Conn, payload []interface{}) (format.Format, forma
 					}
 
 					// format used by OBS < 29.1 to publish H265
+					// or H264 with parameters sent in-band only
 				} else if msg.Type == message.VideoTypeAU && msg.IsKeyFrame {
-					nalus, err := h264.AVCCUnmarshal(msg.Payload)
+					nalus, err := h264UnmarshalAU(msg.Payload)
 					if err != nil {
 						if errors.Is(err, h264.ErrAVCCNoNALUs) {
 							continue
@@ -246,6 +342,13 @@ func tracksFromMetadata(conn *Conn, payload []interface{}) (format.Format, forma
 							SPS:        sps,
 							PPS:        pps,
 						}
+					} else if sps, pps := h264FindParams(nalus); sps != nil && pps != nil {
+						videoTrack = &format.H264{
+							PayloadTyp:        96,
+							SPS:               sps,
+							PPS:               pps,
+							PacketizationMode: 1,
+						}
 					}
 				}
 			}
@@ -391,19 +494,36 @@ outer:
 				startTime = msg.DTS
 			}
 
-			if msg.Type == message.VideoTypeConfig {
-				if videoTrack == nil {
+			if videoTrack == nil {
+				switch msg.Type {
+				case message.VideoTypeConfig:
 					var err error
 					videoTrack, err = trackFromH264DecoderConfig(msg.Payload)
 					if err != nil {
 						return nil, nil, err
 					}
 
-					// stop the analysis if both tracks are found
-					if videoTrack != nil && audioTrack != nil {
-						return videoTrack, audioTrack, nil
+				// parameters sent in-band only, without a AVCDecoderConfigurationRecord
+				case message.VideoTypeAU:
+					if msg.IsKeyFrame {
+						au, err := h264UnmarshalAU(msg.Payload)
+						if err == nil {
+							if sps, pps := h264FindParams(au); sps != nil && pps != nil {
+								videoTrack = &format.H264{
+									PayloadTyp:        96,
+									SPS:               sps,
+									PPS:               pps,
+									PacketizationMode: 1,
+								}
+							}
+						}
 					}
 				}
+
+				// stop the analysis if both tracks are found
+				if videoTrack != nil && audioTrack != nil {
+					return videoTrack, audioTrack, nil
+				}
 			}
 
 			if (msg.DTS - startTime) >= analyzePeriod {
@@ -594,26 +714,25 @@ func (r *Reader) OnDataH264(cb OnDataH26xFunc) {
 		if msg, ok := msg.(*message.Video); ok {
 			switch msg.Type {
 			case message.VideoTypeConfig:
-				var conf h264conf.Conf
-				err := conf.Unmarshal(msg.Payload)
+				sps, pps, err := h264ParamsFromDecoderConfig(msg.Payload)
 				if err != nil {
-					return fmt.Errorf("unable to parse H264 config: %w", err)
+					return err
 				}
 
 				au := [][]byte{
-					conf.SPS,
-					conf.PPS,
+					sps,
+					pps,
 				}
 
 				cb(msg.DTS+msg.PTSDelta, au)
 
 			case message.VideoTypeAU:
-				au, err := h264.AVCCUnmarshal(msg.Payload)
+				au, err := h264UnmarshalAU(msg.Payload)
 				if err != nil {
 					if errors.Is(err, h264.ErrAVCCNoNALUs) {
 						return nil
 					}
-					return fmt.Errorf("unable to decode AVCC: %w", err)
+					return err
 				}
 
 				cb(msg.DTS+msg.PTSDelta, au)
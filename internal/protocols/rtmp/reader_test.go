@@ -308,6 +308,87 @@ func TestReadTracks(t *testing.T) {
 				},
 			},
 		},
+		{
+			"h264, parameters in-band only",
+			&format.H264{
+				PayloadTyp:        96,
+				SPS:               h264SPS,
+				PPS:               h264PPS,
+				PacketizationMode: 1,
+			},
+			nil,
+			[]message.Message{
+				&message.DataAMF0{
+					ChunkStreamID:   4,
+					MessageStreamID: 1,
+					Payload: []interface{}{
+						"@setDataFrame",
+						"onMetaData",
+						flvio.AMFMap{
+							{
+								K: "videocodecid",
+								V: float64(message.CodecH264),
+							},
+						},
+					},
+				},
+				&message.Video{
+					ChunkStreamID:   message.VideoChunkStreamID,
+					MessageStreamID: 0x1000000,
+					Codec:           message.CodecH264,
+					IsKeyFrame:      true,
+					Type:            message.VideoTypeAU,
+					Payload: func() []byte {
+						buf, _ := h264.AnnexBMarshal([][]byte{
+							h264SPS,
+							h264PPS,
+							{0x65, 0x88, 0x84, 0x00},
+						})
+						return buf
+					}(),
+				},
+			},
+		},
+		{
+			"h264, annex-b decoder config",
+			&format.H264{
+				PayloadTyp:        96,
+				SPS:               h264SPS,
+				PPS:               h264PPS,
+				PacketizationMode: 1,
+			},
+			nil,
+			[]message.Message{
+				&message.DataAMF0{
+					ChunkStreamID:   4,
+					MessageStreamID: 1,
+					Payload: []interface{}{
+						"@setDataFrame",
+						"onMetaData",
+						flvio.AMFMap{
+							{
+								K: "videocodecid",
+								V: float64(message.CodecH264),
+							},
+						},
+					},
+				},
+				&message.Video{
+					ChunkStreamID:   message.VideoChunkStreamID,
+					MessageStreamID: 0x1000000,
+					Codec:           message.CodecH264,
+					IsKeyFrame:      true,
+					Type:            message.VideoTypeConfig,
+					Payload: func() []byte {
+						buf, _ := h264.AnnexBMarshal([][]byte{
+							h264SPS,
+							h264PPS,
+						})
+						return buf
+					}(),
+				},
+			},
+		},
 		{
 			"aac, issue mediamtx/386 (missing metadata)",
 			nil,
@@ -908,3 +989,42 @@ func TestReadTracks(t *testing.T) {
 		})
 	}
 }
+
+func TestH264UnmarshalAU(t *testing.T) {
+	for _, ca := range []struct {
+		name    string
+		payload []byte
+	}{
+		{
+			"avcc",
+			[]byte{
+				0x00, 0x00, 0x00, 0x02, 0x09, 0xf0,
+				0x00, 0x00, 0x00, 0x04, 0x65, 0x88, 0x84, 0x00,
+			},
+		},
+		{
+			"annex-b",
+			[]byte{
+				0x00, 0x00, 0x00, 0x01, 0x09, 0xf0,
+				0x00, 0x00, 0x01, 0x65, 0x88, 0x84, 0x00,
+			},
+		},
+		{
+			"annex-b inside avcc",
+			[]byte{
+				0x00, 0x00, 0x00, 0x0e,
+				0x00, 0x00, 0x00, 0x01, 0x09, 0xf0,
+				0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x84, 0x00,
+			},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			au, err := h264UnmarshalAU(ca.payload)
+			require.NoError(t, err)
+			require.Equal(t, [][]byte{
+				{0x09, 0xf0},
+				{0x65, 0x88, 0x84, 0x00},
+			}, au)
+		})
+	}
+}
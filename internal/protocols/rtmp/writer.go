@@ -1,6 +1,7 @@
 package rtmp
 
 import (
+	"bytes"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
@@ -46,6 +47,10 @@ func mpeg1AudioChannels(m mpeg1audio.ChannelMode) bool {
 // Writer is a wrapper around Conn that provides utilities to mux outgoing data.
 type Writer struct {
 	conn *Conn
+
+	// H264 parameters that have been sent in the last decoder configuration.
+	sps []byte
+	pps []byte
 }
 
 // NewWriter allocates a Writer.
@@ -116,19 +121,7 @@ func (w *Writer) writeTracks(videoTrack format.Format, audioTrack format.Format)
 		// write decoder config only if SPS and PPS are available.
 		// if they're not available yet, they're sent later.
 		if sps, pps := videoTrack.SafeParams(); sps != nil && pps != nil {
-			buf, _ := h264conf.Conf{
-				SPS: sps,
-				PPS: pps,
-			}.Marshal()
-
-			err = w.conn.Write(&message.Video{
-				ChunkStreamID:   message.VideoChunkStreamID,
-				MessageStreamID: 0x1000000,
-				Codec:           message.CodecH264,
-				IsKeyFrame:      true,
-				Type:            message.VideoTypeConfig,
-				Payload:         buf,
-			})
+			err = w.writeH264Config(0, sps, pps)
 			if err != nil {
 				return err
 			}
@@ -165,8 +158,56 @@ func (w *Writer) writeTracks(videoTrack format.Format, audioTrack format.Format)
 	return nil
 }
 
+func (w *Writer) writeH264Config(dts time.Duration, sps []byte, pps []byte) error {
+	buf, _ := h264conf.Conf{
+		SPS: sps,
+		PPS: pps,
+	}.Marshal()
+
+	err := w.conn.Write(&message.Video{
+		ChunkStreamID:   message.VideoChunkStreamID,
+		MessageStreamID: 0x1000000,
+		Codec:           message.CodecH264,
+		IsKeyFrame:      true,
+		Type:            message.VideoTypeConfig,
+		Payload:         buf,
+		DTS:             dts,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.sps = sps
+	w.pps = pps
+	return nil
+}
+
 // WriteH264 writes H264 data.
+// When the parameters contained in a IDR access unit differ from the ones sent previously,
+// a new decoder configuration is sent, since most players ignore in-band parameters.
 func (w *Writer) WriteH264(pts time.Duration, dts time.Duration, idrPresent bool, au [][]byte) error {
+	if idrPresent {
+		var sps []byte
+		var pps []byte
+
+		for _, nalu := range au {
+			switch h264.NALUType(nalu[0] & 0x1F) {
+			case h264.NALUTypeSPS:
+				sps = nalu
+
+			case h264.NALUTypePPS:
+				pps = nalu
+			}
+		}
+
+		if sps != nil && pps != nil && (!bytes.Equal(sps, w.sps) || !bytes.Equal(pps, w.pps)) {
+			err := w.writeH264Config(dts, sps, pps)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	avcc, err := h264.AVCCMarshal(au)
 	if err != nil {
 		return err
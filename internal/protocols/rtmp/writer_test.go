@@ -96,3 +96,58 @@ func TestWriteTracks(t *testing.T) {
 		Payload:         []byte{0x12, 0x10},
 	}, msg)
 }
+
+func TestWriteH264ParamsChange(t *testing.T) {
+	videoTrack := &format.H264{
+		PayloadTyp: 96,
+		SPS: []byte{
+			0x67, 0x64, 0x00, 0x0c, 0xac, 0x3b, 0x50, 0xb0,
+			0x4b, 0x42, 0x00, 0x00, 0x03, 0x00, 0x02, 0x00,
+			0x00, 0x03, 0x00, 0x3d, 0x08,
+		},
+		PPS: []byte{
+			0x68, 0xee, 0x3c, 0x80,
+		},
+		PacketizationMode: 1,
+	}
+
+	var buf bytes.Buffer
+	c := newNoHandshakeConn(&buf)
+
+	w, err := NewWriter(c, videoTrack, nil)
+	require.NoError(t, err)
+
+	bc := bytecounter.NewReadWriter(&buf)
+	mrw := message.NewReadWriter(bc, bc, true)
+
+	for i := 0; i < 2; i++ {
+		_, err = mrw.Read()
+		require.NoError(t, err)
+	}
+
+	// same parameters: no decoder configuration is sent.
+	err = w.WriteH264(0, 0, true, [][]byte{videoTrack.SPS, videoTrack.PPS, {0x05, 0x01}})
+	require.NoError(t, err)
+
+	msg, err := mrw.Read()
+	require.NoError(t, err)
+	require.Equal(t, message.VideoTypeAU, msg.(*message.Video).Type)
+
+	// different parameters: a new decoder configuration is sent.
+	newSPS := []byte{
+		0x67, 0x64, 0x00, 0x1f, 0xac, 0xd9, 0x40, 0x50,
+		0x05, 0xbb, 0x01, 0x6a, 0x02, 0x02, 0x02, 0x80,
+		0x00, 0x00, 0x03, 0x00, 0x80, 0x00, 0x00, 0x1e,
+		0x07, 0x8c, 0x18, 0xcb,
+	}
+	err = w.WriteH264(0, 0, true, [][]byte{newSPS, videoTrack.PPS, {0x05, 0x01}})
+	require.NoError(t, err)
+
+	msg, err = mrw.Read()
+	require.NoError(t, err)
+	require.Equal(t, message.VideoTypeConfig, msg.(*message.Video).Type)
+
+	msg, err = mrw.Read()
+	require.NoError(t, err)
+	require.Equal(t, message.VideoTypeAU, msg.(*message.Video).Type)
+}
@@ -0,0 +1,53 @@
+// Package sdp contains SDP utilities.
+package sdp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// attributes whose value allows to compromise the session.
+var secretAttributes = []string{
+	"a=ice-ufrag:",
+	"a=ice-pwd:",
+	"a=fingerprint:",
+	"a=crypto:",
+	"a=key-mgmt:",
+}
+
+var reURLCredentials = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/@\s]*@`)
+
+var reCandidateUfrag = regexp.MustCompile(`( ufrag )\S+`)
+
+// Redact removes credentials and keys from a SDP, in order to allow
+// exposing it without compromising the session.
+// It removes ICE credentials, DTLS fingerprints, SRTP keys and
+// credentials embedded into URLs.
+func Redact(sdp string) string {
+	lines := strings.SplitAfter(sdp, "\n")
+
+	for i, line := range lines {
+		content := strings.TrimRight(line, "\r\n")
+		ending := line[len(content):]
+
+		redacted := false
+		for _, attr := range secretAttributes {
+			if strings.HasPrefix(content, attr) {
+				content = attr + "redacted"
+				redacted = true
+				break
+			}
+		}
+
+		if !redacted {
+			if strings.HasPrefix(content, "a=candidate:") {
+				content = reCandidateUfrag.ReplaceAllString(content, "${1}redacted")
+			}
+			content = reURLCredentials.ReplaceAllString(content, "${1}")
+		}
+
+		lines[i] = content + ending
+	}
+
+	return strings.Join(lines, "")
+}
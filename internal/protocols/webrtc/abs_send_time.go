@@ -0,0 +1,56 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+)
+
+// absSendTimeInterceptorFactory allocates interceptors that fill
+// the abs-send-time header extension of outgoing RTP packets.
+// The extension contains the time at which packets leave the server,
+// since this is the timing used by receivers to estimate bandwidth.
+type absSendTimeInterceptorFactory struct{}
+
+func (absSendTimeInterceptorFactory) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return &absSendTimeInterceptor{}, nil
+}
+
+type absSendTimeInterceptor struct {
+	interceptor.NoOp
+}
+
+func (*absSendTimeInterceptor) BindLocalStream(
+	info *interceptor.StreamInfo,
+	writer interceptor.RTPWriter,
+) interceptor.RTPWriter {
+	var id uint8
+	for _, ext := range info.RTPHeaderExtensions {
+		if ext.URI == sdp.ABSSendTimeURI {
+			id = uint8(ext.ID)
+			break
+		}
+	}
+
+	// extension has not been negotiated
+	if id == 0 {
+		return writer
+	}
+
+	return interceptor.RTPWriterFunc(func(
+		header *rtp.Header,
+		payload []byte,
+		attributes interceptor.Attributes,
+	) (int, error) {
+		if header.GetExtension(id) == nil {
+			ext, err := rtp.NewAbsSendTimeExtension(time.Now()).Marshal()
+			if err == nil {
+				header.SetExtension(id, ext) //nolint:errcheck
+			}
+		}
+
+		return writer.Write(header, payload, attributes)
+	})
+}
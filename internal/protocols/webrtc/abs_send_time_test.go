@@ -0,0 +1,64 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbsSendTimeInterceptor(t *testing.T) {
+	i, err := absSendTimeInterceptorFactory{}.NewInterceptor("")
+	require.NoError(t, err)
+
+	var written *rtp.Header
+
+	w := i.BindLocalStream(&interceptor.StreamInfo{
+		RTPHeaderExtensions: []interceptor.RTPHeaderExtension{
+			{URI: sdp.TransportCCURI, ID: 1},
+			{URI: sdp.ABSSendTimeURI, ID: 3},
+		},
+	}, interceptor.RTPWriterFunc(func(header *rtp.Header, _ []byte, _ interceptor.Attributes) (int, error) {
+		written = header
+		return 0, nil
+	}))
+
+	_, err = w.Write(&rtp.Header{Version: 2}, []byte{1, 2, 3}, nil)
+	require.NoError(t, err)
+
+	buf := written.GetExtension(3)
+	require.NotNil(t, buf)
+
+	var ext rtp.AbsSendTimeExtension
+	err = ext.Unmarshal(buf)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), ext.Estimate(time.Now()), 100*time.Millisecond)
+}
+
+func TestAPIHeaderExtensions(t *testing.T) {
+	api, err := NewAPI(APIConf{
+		LocalRandomUDP:    true,
+		IPsFromInterfaces: true,
+	})
+	require.NoError(t, err)
+
+	pc, err := api.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer pc.Close() //nolint:errcheck
+
+	_, err = pc.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionSendonly,
+	})
+	require.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	require.NoError(t, err)
+
+	require.Contains(t, offer.SDP, sdp.ABSSendTimeURI)
+	require.Contains(t, offer.SDP, sdp.TransportCCURI)
+	require.Contains(t, offer.SDP, "transport-cc")
+}
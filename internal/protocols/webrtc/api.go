@@ -1,8 +1,14 @@
 package webrtc
 
 import (
+	"strings"
+	"time"
+
 	"github.com/pion/ice/v2"
 	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/nack"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/transport/v2/stdnet"
 	"github.com/pion/webrtc/v3"
 )
 
@@ -15,6 +21,16 @@ func stringInSlice(a string, list []string) bool {
 	return false
 }
 
+// default ICE timeouts of pion, used when only the disconnect timeout is changed.
+const (
+	iceDefaultFailedTimeout     = 25 * time.Second
+	iceDefaultKeepaliveInterval = 2 * time.Second
+)
+
+// AV1 dependency descriptor, used by browsers to signal the layers of AV1 SVC streams.
+const av1DependencyDescriptorURI = "https://aomediacodec.github.io/av1-rtp-spec/" +
+	"#dependency-descriptor-rtp-header-extension"
+
 var videoCodecs = []webrtc.RTPCodecParameters{
 	{
 		RTPCodecCapability: webrtc.RTPCodecCapability{
@@ -102,9 +118,40 @@ type APIConf struct {
 	ICEUDPMux             ice.UDPMux
 	ICETCPMux             ice.TCPMux
 	LocalRandomUDP        bool
+	LocalUDPPortMin       uint16
+	LocalUDPPortMax       uint16
 	IPsFromInterfaces     bool
 	IPsFromInterfacesList []string
 	AdditionalHosts       []string
+	InterfaceFilter       []string
+	UDPMuxOnly            bool
+	DisableMDNS           bool
+
+	// DSCP value of UDP sockets created by ICE. Zero leaves it unset.
+	// Sockets of ICEUDPMux have to be marked by the caller.
+	DSCP int
+
+	// time without received data after which the ICE connection
+	// is considered disconnected. Zero means the default value.
+	ICEDisconnectTimeout time.Duration
+
+	// number of sent packets per track that are retained in order to
+	// be sent again when receivers report them as lost. Zero disables retransmissions.
+	NACKBufferSize int
+	// packets older than this are not sent again.
+	NACKMaxAge time.Duration
+
+	// optional bandwidth estimation of readers.
+	BandwidthEstimation *BandwidthEstimation
+}
+
+// normalizeHosts removes brackets from IPv6 literals.
+func normalizeHosts(hosts []string) []string {
+	out := make([]string, len(hosts))
+	for i, host := range hosts {
+		out[i] = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	}
+	return out
 }
 
 // NewAPI allocates a webrtc API.
@@ -112,11 +159,28 @@ func NewAPI(cnf APIConf) (*webrtc.API, error) {
 	settingsEngine := webrtc.SettingEngine{}
 
 	settingsEngine.SetInterfaceFilter(func(iface string) bool {
-		return cnf.IPsFromInterfaces && (len(cnf.IPsFromInterfacesList) == 0 ||
-			stringInSlice(iface, cnf.IPsFromInterfacesList))
+		// in UDP mux only mode, only additional hosts are advertised.
+		return !cnf.UDPMuxOnly && cnf.IPsFromInterfaces && (len(cnf.IPsFromInterfacesList) == 0 ||
+			stringInSlice(iface, cnf.IPsFromInterfacesList)) &&
+			InterfaceFilterMatches(cnf.InterfaceFilter, iface)
 	})
 
-	settingsEngine.SetAdditionalHosts(cnf.AdditionalHosts)
+	settingsEngine.SetAdditionalHosts(normalizeHosts(cnf.AdditionalHosts))
+
+	if cnf.DisableMDNS {
+		settingsEngine.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+	}
+
+	if cnf.ICEDisconnectTimeout != 0 {
+		// keepalives must be sent more often than the timeout,
+		// otherwise idle connections are considered disconnected.
+		keepaliveInterval := iceDefaultKeepaliveInterval
+		if keepaliveInterval > cnf.ICEDisconnectTimeout/2 {
+			keepaliveInterval = cnf.ICEDisconnectTimeout / 2
+		}
+
+		settingsEngine.SetICETimeouts(cnf.ICEDisconnectTimeout, iceDefaultFailedTimeout, keepaliveInterval)
+	}
 
 	var networkTypes []webrtc.NetworkType
 
@@ -127,15 +191,30 @@ func NewAPI(cnf APIConf) (*webrtc.API, error) {
 		settingsEngine.SetICEUDPMux(cnf.ICEUDPMux)
 	}
 
-	if cnf.ICETCPMux != nil {
+	if cnf.ICETCPMux != nil && !cnf.UDPMuxOnly {
 		settingsEngine.SetICETCPMux(cnf.ICETCPMux)
 		networkTypes = append(networkTypes, webrtc.NetworkTypeTCP4)
 	}
 
+	if cnf.DSCP != 0 {
+		n, err := stdnet.NewNet()
+		if err != nil {
+			return nil, err
+		}
+		settingsEngine.SetNet(&dscpNet{Net: n, dscp: cnf.DSCP})
+	}
+
 	if cnf.LocalRandomUDP {
 		settingsEngine.SetICEUDPRandom(true)
 	}
 
+	if cnf.LocalUDPPortMax != 0 {
+		err := settingsEngine.SetEphemeralUDPPortRange(cnf.LocalUDPPortMin, cnf.LocalUDPPortMax)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	settingsEngine.SetNetworkTypes(networkTypes)
 
 	mediaEngine := &webrtc.MediaEngine{}
@@ -154,12 +233,67 @@ func NewAPI(cnf APIConf) (*webrtc.API, error) {
 		}
 	}
 
+	// browsers publish AV1 SVC streams only when this extension is negotiated.
+	err := mediaEngine.RegisterHeaderExtension(
+		webrtc.RTPHeaderExtensionCapability{URI: av1DependencyDescriptorURI},
+		webrtc.RTPCodecTypeVideo)
+	if err != nil {
+		return nil, err
+	}
+
+	// abs-send-time allows receivers to perform delay-based bandwidth estimation.
+	for _, typ := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecTypeAudio} {
+		err = mediaEngine.RegisterHeaderExtension(
+			webrtc.RTPHeaderExtensionCapability{URI: sdp.ABSSendTimeURI},
+			typ)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	interceptorRegistry := &interceptor.Registry{}
 
-	err := webrtc.RegisterDefaultInterceptors(mediaEngine, interceptorRegistry)
+	// these are the default interceptors, with a custom NACK responder.
+	if cnf.NACKBufferSize > 0 {
+		interceptorRegistry.Add(nackResponderInterceptorFactory{
+			bufferSize: cnf.NACKBufferSize,
+			maxAge:     cnf.NACKMaxAge,
+		})
+	}
+
+	generator, err := nack.NewGeneratorInterceptor()
 	if err != nil {
 		return nil, err
 	}
+	interceptorRegistry.Add(generator)
+
+	mediaEngine.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack"}, webrtc.RTPCodecTypeVideo)
+	mediaEngine.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack", Parameter: "pli"}, webrtc.RTPCodecTypeVideo)
+	mediaEngine.RegisterFeedback(webrtc.RTCPFeedback{Type: "goog-remb"}, webrtc.RTPCodecTypeVideo)
+
+	err = webrtc.ConfigureRTCPReports(interceptorRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	err = webrtc.ConfigureTWCCSender(mediaEngine, interceptorRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	// the estimator must be added before the interceptor that fills transport-wide sequence numbers,
+	// in order to read them.
+	if cnf.BandwidthEstimation != nil {
+		interceptorRegistry.Add(cnf.BandwidthEstimation.factory)
+	}
+
+	// transport-wide sequence numbers allow receivers to send transport-wide congestion control feedback.
+	err = webrtc.ConfigureTWCCHeaderExtensionSender(mediaEngine, interceptorRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	interceptorRegistry.Add(absSendTimeInterceptorFactory{})
 
 	return webrtc.NewAPI(
 		webrtc.WithSettingEngine(settingsEngine),
@@ -0,0 +1,103 @@
+package webrtc
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	bandwidthEstimationInitialBitrate = 1_000_000
+)
+
+// BandwidthEstimation estimates the bandwidth available to readers,
+// by using transport-wide congestion control (TWCC) and REMB feedback.
+// It must be passed to both NewAPI() and PeerConnection.
+type BandwidthEstimation struct {
+	factory *cc.InterceptorFactory
+
+	mutex     sync.Mutex
+	estimator cc.BandwidthEstimator
+}
+
+// NewBandwidthEstimation allocates a BandwidthEstimation.
+func NewBandwidthEstimation() (*BandwidthEstimation, error) {
+	b := &BandwidthEstimation{}
+
+	var err error
+	b.factory, err = cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
+		// packets are sent as soon as they are available, the estimate is only reported.
+		return gcc.NewSendSideBWE(
+			gcc.SendSideBWEInitialBitrate(bandwidthEstimationInitialBitrate),
+			gcc.SendSideBWEPacer(gcc.NewNoOpPacer()))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// this is called synchronously by NewPeerConnection().
+	b.factory.OnNewPeerConnection(func(_ string, estimator cc.BandwidthEstimator) {
+		b.estimator = estimator
+	})
+
+	return b, nil
+}
+
+// newPeerConnection creates a peer connection and returns the associated estimator.
+// Creation is serialized in order to associate each peer connection with its estimator.
+func (b *BandwidthEstimation) newPeerConnection(
+	api *webrtc.API,
+	configuration webrtc.Configuration,
+) (*webrtc.PeerConnection, cc.BandwidthEstimator, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.estimator = nil
+
+	pc, err := api.NewPeerConnection(configuration)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pc, b.estimator, nil
+}
+
+// bandwidthFeedback collects bandwidth feedback sent by a remote peer.
+type bandwidthFeedback struct {
+	estimator    cc.BandwidthEstimator
+	twccReceived atomic.Bool
+	remb         atomic.Uint64
+}
+
+func (f *bandwidthFeedback) onRTCP(pkts []rtcp.Packet) {
+	for _, pkt := range pkts {
+		switch pkt := pkt.(type) {
+		case *rtcp.TransportLayerCC:
+			f.twccReceived.Store(true)
+
+		case *rtcp.ReceiverEstimatedMaximumBitrate:
+			f.remb.Store(uint64(pkt.Bitrate))
+		}
+	}
+}
+
+// estimate returns the estimated bandwidth, in bits per second.
+// Zero means that no estimate is available.
+func (f *bandwidthFeedback) estimate() uint64 {
+	remb := f.remb.Load()
+
+	// the TWCC-based estimate is meaningful only if the remote peer sends TWCC feedback.
+	if f.estimator != nil && f.twccReceived.Load() {
+		est := uint64(f.estimator.GetTargetBitrate())
+		if remb != 0 && remb < est {
+			return remb
+		}
+		return est
+	}
+
+	return remb
+}
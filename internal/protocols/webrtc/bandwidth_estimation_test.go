@@ -0,0 +1,50 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/test"
+)
+
+func TestBandwidthEstimation(t *testing.T) {
+	bwe, err := NewBandwidthEstimation()
+	require.NoError(t, err)
+
+	api, err := NewAPI(APIConf{
+		BandwidthEstimation: bwe,
+	})
+	require.NoError(t, err)
+
+	pc := &PeerConnection{
+		API:                 api,
+		BandwidthEstimation: bwe,
+		Publish:             true,
+		Log:                 test.NilLogger{},
+	}
+	err = pc.Start()
+	require.NoError(t, err)
+	defer pc.Close()
+
+	require.NotNil(t, pc.bandwidthFeedback.estimator)
+
+	// no feedback
+	require.Equal(t, uint64(0), pc.BandwidthEstimate())
+
+	// REMB only
+	pc.bandwidthFeedback.onRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{
+		Bitrate: 300000,
+	}})
+	require.Equal(t, uint64(300000), pc.BandwidthEstimate())
+
+	// TWCC and REMB, the lowest estimate is used
+	pc.bandwidthFeedback.onRTCP([]rtcp.Packet{&rtcp.TransportLayerCC{}})
+	require.Equal(t, uint64(300000), pc.BandwidthEstimate())
+
+	pc.bandwidthFeedback.onRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{
+		Bitrate: 5000000,
+	}})
+	require.Equal(t, uint64(bandwidthEstimationInitialBitrate), pc.BandwidthEstimate())
+}
@@ -0,0 +1,38 @@
+package webrtc
+
+import (
+	"net"
+
+	"github.com/bluenviron/mediamtx/internal/dscp"
+	"github.com/pion/transport/v2"
+)
+
+// dscpNet is a transport.Net that sets the DSCP value
+// of every UDP socket created by ICE.
+type dscpNet struct {
+	transport.Net
+	dscp int
+}
+
+func (n *dscpNet) ListenPacket(network string, address string) (net.PacketConn, error) {
+	pc, err := n.Net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// errors are ignored, since DSCP is not supported by all platforms.
+	dscp.SetPacketConn(pc, n.dscp) //nolint:errcheck
+
+	return pc, nil
+}
+
+func (n *dscpNet) ListenUDP(network string, locAddr *net.UDPAddr) (transport.UDPConn, error) {
+	conn, err := n.Net.ListenUDP(network, locAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	dscp.SetPacketConn(conn, n.dscp) //nolint:errcheck
+
+	return conn, nil
+}
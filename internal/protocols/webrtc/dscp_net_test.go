@@ -0,0 +1,33 @@
+package webrtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pion/transport/v2/stdnet"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/ipv4"
+)
+
+func TestDSCPNet(t *testing.T) {
+	n, err := stdnet.NewNet()
+	require.NoError(t, err)
+
+	dn := &dscpNet{Net: n, dscp: 46}
+
+	conn, err := dn.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	tos, err := ipv4.NewPacketConn(conn).TOS()
+	require.NoError(t, err)
+	require.Equal(t, 46<<2, tos)
+
+	pc, err := dn.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	tos, err = ipv4.NewPacketConn(pc).TOS()
+	require.NoError(t, err)
+	require.Equal(t, 46<<2, tos)
+}
@@ -19,6 +19,14 @@ const (
 	keyFrameInterval = 2 * time.Second
 )
 
+// removeExtensions removes header extensions (i.e. the AV1 dependency descriptor),
+// since their IDs are negotiated with the publisher and are meaningless to readers.
+func removeExtensions(pkt *rtp.Packet) {
+	pkt.Header.Extension = false
+	pkt.Header.ExtensionProfile = 0
+	pkt.Header.Extensions = nil
+}
+
 // IncomingTrack is an incoming track.
 type IncomingTrack struct {
 	track *webrtc.TrackRemote
@@ -148,6 +156,8 @@ func (t *IncomingTrack) ReadRTP() (*rtp.Packet, error) {
 				continue
 			}
 
+			removeExtensions(pkt)
+
 			return pkt, nil
 		}
 
@@ -174,6 +184,8 @@ func (t *IncomingTrack) ReadRTP() (*rtp.Packet, error) {
 			continue
 		}
 
+		removeExtensions(pkt)
+
 		return pkt, nil
 	}
 }
@@ -0,0 +1,30 @@
+package webrtc
+
+import (
+	"path"
+	"strings"
+)
+
+// InterfaceFilterMatches checks whether a network interface is allowed by a filter.
+// Entries starting with "!" exclude interfaces, other entries include them.
+// Entries can contain shell-style wildcards (i.e. "veth*").
+// When there are no inclusive entries, all interfaces that are not excluded are allowed.
+func InterfaceFilterMatches(filter []string, iface string) bool {
+	hasIncludes := false
+	included := false
+
+	for _, entry := range filter {
+		if strings.HasPrefix(entry, "!") {
+			if ok, _ := path.Match(entry[1:], iface); ok {
+				return false
+			}
+		} else {
+			hasIncludes = true
+			if ok, _ := path.Match(entry, iface); ok {
+				included = true
+			}
+		}
+	}
+
+	return !hasIncludes || included
+}
@@ -0,0 +1,29 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterfaceFilterMatches(t *testing.T) {
+	for _, ca := range []struct {
+		name   string
+		filter []string
+		iface  string
+		match  bool
+	}{
+		{"empty", nil, "eth0", true},
+		{"include", []string{"eth0"}, "eth0", true},
+		{"include other", []string{"eth0"}, "eth1", false},
+		{"exclude", []string{"!docker0"}, "docker0", false},
+		{"exclude other", []string{"!docker0"}, "eth0", true},
+		{"exclude wildcard", []string{"!veth*"}, "veth1234", false},
+		{"include and exclude", []string{"eth*", "!eth1"}, "eth1", false},
+		{"include and exclude other", []string{"eth*", "!eth1"}, "eth0", true},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			require.Equal(t, ca.match, InterfaceFilterMatches(ca.filter, ca.iface))
+		})
+	}
+}
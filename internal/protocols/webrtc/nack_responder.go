@@ -0,0 +1,170 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// nackResponderInterceptorFactory allocates interceptors that retain
+// recently sent RTP packets and send them again when receivers report
+// them as lost through NACKs.
+// Retransmissions use the SSRC of the original stream,
+// since the WebRTC library doesn't support sending RTX streams.
+type nackResponderInterceptorFactory struct {
+	bufferSize int
+	maxAge     time.Duration
+}
+
+func (f nackResponderInterceptorFactory) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return &nackResponderInterceptor{
+		bufferSize: f.bufferSize,
+		maxAge:     f.maxAge,
+		streams:    make(map[uint32]*nackResponderStream),
+	}, nil
+}
+
+type nackSentPacket struct {
+	header  rtp.Header
+	payload []byte
+	sent    time.Time
+}
+
+type nackResponderStream struct {
+	writer interceptor.RTPWriter
+
+	mutex   sync.Mutex
+	packets []*nackSentPacket
+}
+
+// add stores a packet, overwriting the one with the same position in the buffer.
+func (s *nackResponderStream) add(header *rtp.Header, payload []byte, now time.Time) {
+	pkt := &nackSentPacket{
+		header:  header.Clone(),
+		payload: append([]byte(nil), payload...),
+		sent:    now,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.packets[int(header.SequenceNumber)%len(s.packets)] = pkt
+}
+
+// get returns a packet, if it's still in the buffer and it isn't older than maxAge.
+func (s *nackResponderStream) get(seqNum uint16, now time.Time, maxAge time.Duration) *nackSentPacket {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pkt := s.packets[int(seqNum)%len(s.packets)]
+	if pkt == nil || pkt.header.SequenceNumber != seqNum || now.Sub(pkt.sent) > maxAge {
+		return nil
+	}
+
+	return pkt
+}
+
+type nackResponderInterceptor struct {
+	interceptor.NoOp
+	bufferSize int
+	maxAge     time.Duration
+
+	mutex   sync.Mutex
+	streams map[uint32]*nackResponderStream
+}
+
+func streamSupportsNACK(info *interceptor.StreamInfo) bool {
+	for _, fb := range info.RTCPFeedback {
+		if fb.Type == "nack" && fb.Parameter == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *nackResponderInterceptor) BindLocalStream(
+	info *interceptor.StreamInfo,
+	writer interceptor.RTPWriter,
+) interceptor.RTPWriter {
+	if !streamSupportsNACK(info) {
+		return writer
+	}
+
+	stream := &nackResponderStream{
+		writer:  writer,
+		packets: make([]*nackSentPacket, i.bufferSize),
+	}
+
+	i.mutex.Lock()
+	i.streams[info.SSRC] = stream
+	i.mutex.Unlock()
+
+	return interceptor.RTPWriterFunc(func(
+		header *rtp.Header,
+		payload []byte,
+		attributes interceptor.Attributes,
+	) (int, error) {
+		stream.add(header, payload, time.Now())
+		return writer.Write(header, payload, attributes)
+	})
+}
+
+func (i *nackResponderInterceptor) UnbindLocalStream(info *interceptor.StreamInfo) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	delete(i.streams, info.SSRC)
+}
+
+func (i *nackResponderInterceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+
+		pkts, err := attr.GetRTCPPackets(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, pkt := range pkts {
+			if nack, ok := pkt.(*rtcp.TransportLayerNack); ok {
+				i.resend(nack)
+			}
+		}
+
+		return n, attr, nil
+	})
+}
+
+func (i *nackResponderInterceptor) resend(nack *rtcp.TransportLayerNack) {
+	i.mutex.Lock()
+	stream, ok := i.streams[nack.MediaSSRC]
+	i.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+
+	for _, pair := range nack.Nacks {
+		for _, seqNum := range pair.PacketList() {
+			pkt := stream.get(seqNum, now, i.maxAge)
+			if pkt == nil {
+				continue
+			}
+
+			header := pkt.header
+			stream.writer.Write(&header, pkt.payload, nil) //nolint:errcheck
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNACKResponder(t *testing.T) {
+	i, err := nackResponderInterceptorFactory{
+		bufferSize: 4,
+		maxAge:     1 * time.Second,
+	}.NewInterceptor("")
+	require.NoError(t, err)
+
+	var written []uint16
+
+	writer := i.BindLocalStream(&interceptor.StreamInfo{
+		SSRC:         1234,
+		RTCPFeedback: []interceptor.RTCPFeedback{{Type: "nack"}},
+	}, interceptor.RTPWriterFunc(func(header *rtp.Header, _ []byte, _ interceptor.Attributes) (int, error) {
+		written = append(written, header.SequenceNumber)
+		return 0, nil
+	}))
+
+	for seqNum := uint16(1); seqNum <= 6; seqNum++ {
+		_, err = writer.Write(&rtp.Header{SSRC: 1234, SequenceNumber: seqNum}, []byte{1, 2}, nil)
+		require.NoError(t, err)
+	}
+
+	byts, err := rtcp.Marshal([]rtcp.Packet{&rtcp.TransportLayerNack{
+		MediaSSRC: 1234,
+		Nacks:     rtcp.NackPairsFromSequenceNumbers([]uint16{1, 5, 7}),
+	}})
+	require.NoError(t, err)
+
+	reader := i.BindRTCPReader(interceptor.RTCPReaderFunc(
+		func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+			return copy(b, byts), a, nil
+		}))
+
+	_, _, err = reader.Read(make([]byte, 1500), nil)
+	require.NoError(t, err)
+
+	// packet 1 has been overwritten, packet 7 has never been sent.
+	require.Equal(t, []uint16{1, 2, 3, 4, 5, 6, 5}, written)
+
+	// packets older than maxAge are not sent again.
+	stream := i.(*nackResponderInterceptor).streams[1234]
+	require.NotNil(t, stream.get(5, time.Now(), 1*time.Second))
+	require.Nil(t, stream.get(5, time.Now().Add(2*time.Second), 1*time.Second))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 )
@@ -15,7 +16,11 @@ type OutgoingTrack struct {
 	track *webrtc.TrackLocalStaticRTP
 }
 
-func newOutgoingTrack(forma format.Format, addTrack addTrackFunc) (*OutgoingTrack, error) {
+func newOutgoingTrack(
+	forma format.Format,
+	addTrack addTrackFunc,
+	onRTCP func([]rtcp.Packet),
+) (*OutgoingTrack, error) {
 	t := &OutgoingTrack{}
 
 	switch forma := forma.(type) {
@@ -138,10 +143,15 @@ func newOutgoingTrack(forma format.Format, addTrack addTrackFunc) (*OutgoingTrac
 	go func() {
 		buf := make([]byte, 1500)
 		for {
-			_, _, err := sender.Read(buf)
+			n, _, err := sender.Read(buf)
 			if err != nil {
 				return
 			}
+
+			pkts, err := rtcp.Unmarshal(buf[:n])
+			if err == nil {
+				onRTCP(pkts)
+			}
 		}
 	}()
 
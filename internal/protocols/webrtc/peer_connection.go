@@ -26,19 +26,28 @@ type trackRecvPair struct {
 
 // PeerConnection is a wrapper around webrtc.PeerConnection.
 type PeerConnection struct {
-	ICEServers []webrtc.ICEServer
-	API        *webrtc.API
-	Publish    bool
-	Log        logger.Writer
+	ICEServers          []webrtc.ICEServer
+	API                 *webrtc.API
+	BandwidthEstimation *BandwidthEstimation
+	Publish             bool
+	Log                 logger.Writer
+
+	// time allowed to a disconnected connection to recover
+	// before Disconnected() returns. Zero means that it returns immediately.
+	RecoveryTimeout time.Duration
 
 	wr                *webrtc.PeerConnection
 	stateChangeMutex  sync.Mutex
+	recoveryTimer     *time.Timer
 	newLocalCandidate chan *webrtc.ICECandidateInit
 	connected         chan struct{}
 	disconnected      chan struct{}
 	closed            chan struct{}
 	gatheringDone     chan struct{}
 	incomingTrack     chan trackRecvPair
+	dataChannelMutex  sync.Mutex
+	dataChannel       *webrtc.DataChannel
+	bandwidthFeedback bandwidthFeedback
 }
 
 // Start starts the peer connection.
@@ -48,7 +57,12 @@ func (co *PeerConnection) Start() error {
 	}
 
 	var err error
-	co.wr, err = co.API.NewPeerConnection(configuration)
+
+	if co.BandwidthEstimation != nil {
+		co.wr, co.bandwidthFeedback.estimator, err = co.BandwidthEstimation.newPeerConnection(co.API, configuration)
+	} else {
+		co.wr, err = co.API.NewPeerConnection(configuration)
+	}
 	if err != nil {
 		return err
 	}
@@ -85,6 +99,16 @@ func (co *PeerConnection) Start() error {
 		})
 	}
 
+	// data channels are opened by the remote peer, since it generates the offer.
+	co.wr.OnDataChannel(func(dc *webrtc.DataChannel) {
+		co.dataChannelMutex.Lock()
+		defer co.dataChannelMutex.Unlock()
+
+		if co.dataChannel == nil {
+			co.dataChannel = dc
+		}
+	})
+
 	co.wr.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
 		co.stateChangeMutex.Lock()
 		defer co.stateChangeMutex.Unlock()
@@ -99,15 +123,47 @@ func (co *PeerConnection) Start() error {
 
 		switch state {
 		case webrtc.PeerConnectionStateConnected:
+			if co.recoveryTimer != nil {
+				co.recoveryTimer.Stop()
+				co.recoveryTimer = nil
+				co.Log.Log(logger.Info, "peer connection recovered")
+				return
+			}
+
 			co.Log.Log(logger.Info, "peer connection established, local candidate: %v, remote candidate: %v",
 				co.LocalCandidate(), co.RemoteCandidate())
 
 			close(co.connected)
 
 		case webrtc.PeerConnectionStateDisconnected:
-			close(co.disconnected)
+			if co.RecoveryTimeout == 0 {
+				co.closeDisconnected()
+				return
+			}
+
+			if co.recoveryTimer == nil {
+				co.Log.Log(logger.Warn, "peer connection disconnected, waiting %v for it to recover", co.RecoveryTimeout)
+
+				var t *time.Timer
+				t = time.AfterFunc(co.RecoveryTimeout, func() {
+					co.stateChangeMutex.Lock()
+					defer co.stateChangeMutex.Unlock()
+
+					if co.recoveryTimer == t {
+						co.recoveryTimer = nil
+						co.closeDisconnected()
+					}
+				})
+				co.recoveryTimer = t
+			}
+
+		// ICE failure may occur without a previous disconnection.
+		case webrtc.PeerConnectionStateFailed:
+			co.stopRecoveryTimer()
+			co.closeDisconnected()
 
 		case webrtc.PeerConnectionStateClosed:
+			co.stopRecoveryTimer()
 			close(co.closed)
 		}
 	})
@@ -128,6 +184,21 @@ func (co *PeerConnection) Start() error {
 	return nil
 }
 
+func (co *PeerConnection) stopRecoveryTimer() {
+	if co.recoveryTimer != nil {
+		co.recoveryTimer.Stop()
+		co.recoveryTimer = nil
+	}
+}
+
+func (co *PeerConnection) closeDisconnected() {
+	select {
+	case <-co.disconnected:
+	default:
+		close(co.disconnected)
+	}
+}
+
 // Close closes the connection.
 func (co *PeerConnection) Close() {
 	co.wr.Close() //nolint:errcheck
@@ -271,7 +342,7 @@ func (co *PeerConnection) SetupOutgoingTracks(
 
 	for _, forma := range []format.Format{videoTrack, audioTrack} {
 		if forma != nil {
-			track, err := newOutgoingTrack(forma, co.wr.AddTrack)
+			track, err := newOutgoingTrack(forma, co.wr.AddTrack, co.bandwidthFeedback.onRTCP)
 			if err != nil {
 				return nil, err
 			}
@@ -283,6 +354,13 @@ func (co *PeerConnection) SetupOutgoingTracks(
 	return tracks, nil
 }
 
+// DataChannel returns the data channel opened by the remote peer, if any.
+func (co *PeerConnection) DataChannel() *webrtc.DataChannel {
+	co.dataChannelMutex.Lock()
+	defer co.dataChannelMutex.Unlock()
+	return co.dataChannel
+}
+
 // Connected returns when connected.
 func (co *PeerConnection) Connected() <-chan struct{} {
 	return co.connected
@@ -359,6 +437,12 @@ func (co *PeerConnection) BytesReceived() uint64 {
 	return 0
 }
 
+// BandwidthEstimate returns the estimated bandwidth available to the remote peer,
+// in bits per second. Zero means that no estimate is available.
+func (co *PeerConnection) BandwidthEstimate() uint64 {
+	return co.bandwidthFeedback.estimate()
+}
+
 // BytesSent returns sent bytes.
 func (co *PeerConnection) BytesSent() uint64 {
 	for _, stats := range co.wr.GetStats() {
@@ -13,6 +13,11 @@ import (
 	"github.com/bluenviron/mediamtx/internal/logger"
 )
 
+const (
+	whipClientNACKBufferSize = 1024
+	whipClientNACKMaxAge     = 1 * time.Second
+)
+
 // WHIPClient is a WHIP client.
 type WHIPClient struct {
 	HTTPClient *http.Client
@@ -36,6 +41,8 @@ func (c *WHIPClient) Publish(
 	api, err := NewAPI(APIConf{
 		LocalRandomUDP:    true,
 		IPsFromInterfaces: true,
+		NACKBufferSize:    whipClientNACKBufferSize,
+		NACKMaxAge:        whipClientNACKMaxAge,
 	})
 	if err != nil {
 		return nil, err
@@ -78,7 +85,7 @@ func (c *WHIPClient) Publish(
 
 	err = c.pc.SetAnswer(res.Answer)
 	if err != nil {
-		c.pc.Close()
+		c.Close() //nolint:errcheck
 		return nil, err
 	}
 
@@ -91,7 +98,7 @@ outer:
 		case ca := <-c.pc.NewLocalCandidate():
 			err := WHIPPatchCandidate(context.Background(), c.HTTPClient, c.URL.String(), offer, res.ETag, ca)
 			if err != nil {
-				c.pc.Close()
+				c.Close() //nolint:errcheck
 				return nil, err
 			}
 
@@ -100,8 +107,12 @@ outer:
 		case <-c.pc.Connected():
 			break outer
 
+		case <-c.pc.Disconnected():
+			c.Close() //nolint:errcheck
+			return nil, fmt.Errorf("peer connection closed")
+
 		case <-t.C:
-			c.pc.Close()
+			c.Close() //nolint:errcheck
 			return nil, fmt.Errorf("deadline exceeded while waiting connection")
 		}
 	}
@@ -156,20 +167,20 @@ func (c *WHIPClient) Read(ctx context.Context) ([]*IncomingTrack, error) {
 	var sdp sdp.SessionDescription
 	err = sdp.Unmarshal([]byte(res.Answer.SDP))
 	if err != nil {
-		c.pc.Close()
+		c.Close() //nolint:errcheck
 		return nil, err
 	}
 
 	// check that there are at most two tracks
 	_, err = TrackCount(sdp.MediaDescriptions)
 	if err != nil {
-		c.pc.Close()
+		c.Close() //nolint:errcheck
 		return nil, err
 	}
 
 	err = c.pc.SetAnswer(res.Answer)
 	if err != nil {
-		c.pc.Close()
+		c.Close() //nolint:errcheck
 		return nil, err
 	}
 
@@ -182,7 +193,7 @@ outer:
 		case ca := <-c.pc.NewLocalCandidate():
 			err := WHIPPatchCandidate(context.Background(), c.HTTPClient, c.URL.String(), offer, res.ETag, ca)
 			if err != nil {
-				c.pc.Close()
+				c.Close() //nolint:errcheck
 				return nil, err
 			}
 
@@ -191,16 +202,26 @@ outer:
 		case <-c.pc.Connected():
 			break outer
 
+		case <-c.pc.Disconnected():
+			c.Close() //nolint:errcheck
+			return nil, fmt.Errorf("peer connection closed")
+
 		case <-t.C:
-			c.pc.Close()
+			c.Close() //nolint:errcheck
 			return nil, fmt.Errorf("deadline exceeded while waiting connection")
 		}
 	}
 
-	return c.pc.GatherIncomingTracks(ctx, 0)
+	tracks, err := c.pc.GatherIncomingTracks(ctx, 0)
+	if err != nil {
+		c.Close() //nolint:errcheck
+		return nil, err
+	}
+
+	return tracks, nil
 }
 
-// Close closes the client.
+// Close deletes the session and closes the client.
 func (c *WHIPClient) Close() error {
 	err := WHIPDeleteSession(context.Background(), c.HTTPClient, c.URL.String())
 	c.pc.Close()
@@ -23,7 +23,8 @@ func WHIPDeleteSession(
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
+	// some servers reply with 204 instead of 200.
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("bad status code: %v", res.StatusCode)
 	}
 
@@ -52,6 +52,9 @@ func PostOffer(
 	}
 
 	Location := res.Header.Get("Location")
+	if Location == "" {
+		return nil, fmt.Errorf("Location is missing")
+	}
 
 	etag := res.Header.Get("ETag")
 	if etag == "" {
@@ -0,0 +1,201 @@
+package websocket
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type framing int32
+
+const (
+	framingUnknown framing = iota
+	framingBinary
+	framingBase64
+	framingText
+)
+
+var netConnUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+	// subprotocols used by websockify and compatible clients.
+	Subprotocols: []string{"binary", "base64"},
+}
+
+// NetConn is a net.Conn that exchanges data through a WebSocket connection.
+// Data can be framed into binary messages, into base64-encoded text messages
+// or into plain text messages. The framing is negotiated through the
+// "binary" and "base64" subprotocols, or is detected from the first message
+// received from the client. Outgoing messages use the same framing.
+type NetConn struct {
+	wc *websocket.Conn
+
+	framing   atomic.Int32
+	buf       []byte
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewNetConn allocates a NetConn.
+func NewNetConn(w http.ResponseWriter, req *http.Request) (*NetConn, error) {
+	wc, err := netConnUpgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &NetConn{
+		wc: wc,
+	}
+
+	switch wc.Subprotocol() {
+	case "binary":
+		c.framing.Store(int32(framingBinary))
+
+	case "base64":
+		c.framing.Store(int32(framingBase64))
+	}
+
+	return c, nil
+}
+
+// Read implements net.Conn.
+func (c *NetConn) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		typ, byts, err := c.wc.ReadMessage()
+		if err != nil {
+			var ce *websocket.CloseError
+			if errors.As(err, &ce) {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+
+		byts, err = c.decode(typ, byts)
+		if err != nil {
+			return 0, err
+		}
+
+		c.buf = byts
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+func (c *NetConn) decode(typ int, byts []byte) ([]byte, error) {
+	f := framing(c.framing.Load())
+
+	if f == framingUnknown {
+		switch {
+		case typ == websocket.BinaryMessage:
+			f = framingBinary
+
+		case isBase64(byts):
+			f = framingBase64
+
+		default:
+			f = framingText
+		}
+
+		c.framing.Store(int32(f))
+	}
+
+	if typ == websocket.TextMessage && f == framingBase64 {
+		dec, err := base64.StdEncoding.DecodeString(string(byts))
+		if err != nil {
+			return nil, err
+		}
+		return dec, nil
+	}
+
+	return byts, nil
+}
+
+// isBase64 checks whether a text message is encoded in base64.
+// RTSP messages always contain spaces, therefore they are never detected as base64.
+func isBase64(byts []byte) bool {
+	if len(byts) == 0 {
+		return false
+	}
+
+	_, err := base64.StdEncoding.DecodeString(string(byts))
+	return err == nil
+}
+
+// Write implements net.Conn.
+func (c *NetConn) Write(p []byte) (int, error) {
+	var err error
+
+	switch framing(c.framing.Load()) {
+	case framingBase64:
+		err = c.wc.WriteMessage(websocket.TextMessage, []byte(base64.StdEncoding.EncodeToString(p)))
+
+	case framingText:
+		err = c.wc.WriteMessage(websocket.TextMessage, p)
+
+	default:
+		err = c.wc.WriteMessage(websocket.BinaryMessage, p)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close implements net.Conn.
+// A close message is sent to the peer before closing the connection.
+func (c *NetConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.wc.WriteControl(websocket.CloseMessage, //nolint:errcheck
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(writeTimeout))
+		c.closeErr = c.wc.Close()
+	})
+	return c.closeErr
+}
+
+// LocalAddr implements net.Conn.
+func (c *NetConn) LocalAddr() net.Addr {
+	return c.wc.LocalAddr()
+}
+
+// RemoteAddr implements net.Conn.
+// Connections received through Unix sockets don't have a remote address,
+// they are considered local.
+func (c *NetConn) RemoteAddr() net.Addr {
+	addr := c.wc.RemoteAddr()
+	if _, ok := addr.(*net.TCPAddr); !ok {
+		return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	}
+	return addr
+}
+
+// SetDeadline implements net.Conn.
+func (c *NetConn) SetDeadline(t time.Time) error {
+	err := c.wc.SetReadDeadline(t)
+	if err != nil {
+		return err
+	}
+	return c.wc.SetWriteDeadline(t)
+}
+
+// SetReadDeadline implements net.Conn.
+func (c *NetConn) SetReadDeadline(t time.Time) error {
+	return c.wc.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements net.Conn.
+func (c *NetConn) SetWriteDeadline(t time.Time) error {
+	return c.wc.SetWriteDeadline(t)
+}
@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetConn(t *testing.T) {
+	for _, ca := range []string{
+		"binary subprotocol",
+		"base64 subprotocol",
+		"binary detected",
+		"base64 detected",
+		"text detected",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			s := &http.Server{
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					c, err := NewNetConn(w, r)
+					require.NoError(t, err)
+					defer c.Close()
+
+					buf := make([]byte, 4)
+					_, err = c.Read(buf)
+					require.NoError(t, err)
+					require.Equal(t, []byte("OPTI"), buf)
+
+					_, err = c.Read(buf)
+					require.NoError(t, err)
+					require.Equal(t, []byte("ONS "), buf)
+
+					_, err = c.Write([]byte("RTSP/1.0 200 OK"))
+					require.NoError(t, err)
+				}),
+			}
+
+			ln, err := net.Listen("tcp", "localhost:6344")
+			require.NoError(t, err)
+
+			go s.Serve(ln)
+			defer s.Shutdown(context.Background())
+
+			dialer := *websocket.DefaultDialer
+
+			switch ca {
+			case "binary subprotocol":
+				dialer.Subprotocols = []string{"binary"}
+
+			case "base64 subprotocol":
+				dialer.Subprotocols = []string{"base64"}
+			}
+
+			c, res, err := dialer.Dial("ws://localhost:6344/", nil)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			defer c.Close() //nolint:errcheck
+
+			req := []byte("OPTIONS rtsp://localhost RTSP/1.0\r\n\r\n")
+
+			switch ca {
+			case "binary subprotocol", "binary detected":
+				err = c.WriteMessage(websocket.BinaryMessage, req)
+
+			case "base64 subprotocol", "base64 detected":
+				err = c.WriteMessage(websocket.TextMessage, []byte(base64.StdEncoding.EncodeToString(req)))
+
+			case "text detected":
+				err = c.WriteMessage(websocket.TextMessage, req)
+			}
+			require.NoError(t, err)
+
+			typ, byts, err := c.ReadMessage()
+			require.NoError(t, err)
+
+			switch ca {
+			case "binary subprotocol", "binary detected":
+				require.Equal(t, websocket.BinaryMessage, typ)
+				require.Equal(t, []byte("RTSP/1.0 200 OK"), byts)
+
+			case "base64 subprotocol", "base64 detected":
+				require.Equal(t, websocket.TextMessage, typ)
+				require.Equal(t, base64.StdEncoding.EncodeToString([]byte("RTSP/1.0 200 OK")), string(byts))
+
+			case "text detected":
+				require.Equal(t, websocket.TextMessage, typ)
+				require.Equal(t, []byte("RTSP/1.0 200 OK"), byts)
+			}
+
+			_, _, err = c.ReadMessage()
+			require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package push contains the push agent.
+package push
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+	"github.com/bluenviron/mediamtx/internal/protocols/rtmp"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+// redactURL removes credentials and stream keys from a destination URL,
+// since they usually allow anyone to publish.
+func redactURL(ur string) string {
+	u, err := url.Parse(ur)
+	if err != nil {
+		return "invalid URL"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Agent republishes a stream to a RTMP or SRT destination.
+type Agent struct {
+	URL            string
+	ReadTimeout    conf.StringDuration
+	WriteTimeout   conf.StringDuration
+	WriteQueueSize int
+	Stream         *stream.Stream
+	Parent         logger.Writer
+
+	restartPause time.Duration
+
+	unsupportedWarned bool
+
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+// Initialize initializes Agent.
+func (a *Agent) Initialize() {
+	if a.restartPause == 0 {
+		a.restartPause = 5 * time.Second
+	}
+
+	a.terminate = make(chan struct{})
+	a.done = make(chan struct{})
+
+	go a.run()
+}
+
+// Log implements logger.Writer.
+func (a *Agent) Log(level logger.Level, format string, args ...interface{}) {
+	a.Parent.Log(level, "[push "+redactURL(a.URL)+"] "+format, args...)
+}
+
+// Close closes the agent.
+func (a *Agent) Close() {
+	a.Log(logger.Info, "pushing stopped")
+	close(a.terminate)
+	<-a.done
+}
+
+func (a *Agent) run() {
+	defer close(a.done)
+
+	for {
+		ai := &agentInstance{
+			agent: a,
+		}
+		ai.initialize()
+
+		select {
+		case err := <-ai.done:
+			// the stream doesn't change during the lifetime of the agent,
+			// therefore there's no point in retrying.
+			if errors.Is(err, rtmp.ErrNoSupportedCodecs) || errors.Is(err, mpegts.ErrNoTracks) {
+				a.Log(logger.Error, "%v, pushing disabled", err)
+				<-a.terminate
+				return
+			}
+
+			a.Log(logger.Error, err.Error())
+
+		case <-a.terminate:
+			ai.close()
+			return
+		}
+
+		select {
+		case <-time.After(a.restartPause):
+		case <-a.terminate:
+			return
+		}
+	}
+}
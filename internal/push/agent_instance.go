@@ -0,0 +1,205 @@
+package push
+
+import (
+	"bufio"
+	"context"
+	ctls "crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	srt "github.com/datarhei/gosrt"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+	"github.com/bluenviron/mediamtx/internal/protocols/rtmp"
+)
+
+type agentInstance struct {
+	agent *Agent
+
+	ctx       context.Context
+	ctxCancel func()
+
+	done chan error
+}
+
+func (ai *agentInstance) initialize() {
+	ai.ctx, ai.ctxCancel = context.WithCancel(context.Background())
+	ai.done = make(chan error, 1)
+
+	go ai.run()
+}
+
+func (ai *agentInstance) close() {
+	ai.ctxCancel()
+	<-ai.done
+}
+
+func (ai *agentInstance) run() {
+	ai.done <- ai.runInner()
+}
+
+func (ai *agentInstance) runInner() error {
+	ai.agent.Log(logger.Debug, "connecting")
+
+	u, err := url.Parse(ai.agent.URL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme == "srt" {
+		return ai.runSRT()
+	}
+	return ai.runRTMP(u)
+}
+
+// runConn runs a connected session and closes the connection
+// when the session ends or the instance is closed.
+func (ai *agentInstance) runConn(c io.Closer, session func() error) error {
+	sessionDone := make(chan error)
+	go func() {
+		sessionDone <- session()
+	}()
+
+	select {
+	case err := <-sessionDone:
+		c.Close()
+		return err
+
+	case <-ai.ctx.Done():
+		c.Close()
+		<-sessionDone
+		return fmt.Errorf("terminated")
+	}
+}
+
+func (ai *agentInstance) runRTMP(u *url.URL) error {
+	// add default port
+	if u.Port() == "" {
+		if u.Scheme == "rtmp" {
+			u.Host = net.JoinHostPort(u.Hostname(), "1935")
+		} else {
+			u.Host = net.JoinHostPort(u.Hostname(), "1936")
+		}
+	}
+
+	nconn, err := func() (net.Conn, error) {
+		ctx2, cancel2 := context.WithTimeout(ai.ctx, time.Duration(ai.agent.ReadTimeout))
+		defer cancel2()
+
+		if u.Scheme == "rtmp" {
+			return (&net.Dialer{}).DialContext(ctx2, "tcp", u.Host)
+		}
+
+		return (&ctls.Dialer{
+			Config: &ctls.Config{},
+		}).DialContext(ctx2, "tcp", u.Host)
+	}()
+	if err != nil {
+		return err
+	}
+
+	return ai.runConn(nconn, func() error {
+		nconn.SetReadDeadline(time.Now().Add(time.Duration(ai.agent.ReadTimeout)))
+		nconn.SetWriteDeadline(time.Now().Add(time.Duration(ai.agent.WriteTimeout)))
+		conn, err := rtmp.NewClientConn(nconn, u, true)
+		if err != nil {
+			return err
+		}
+
+		writer := asyncwriter.New(ai.agent.WriteQueueSize, conf.ReaderOverflowPolicyDropOldest, ai.agent)
+
+		defer ai.agent.Stream.RemoveReader(writer)
+
+		err = rtmp.FromStream(ai.agent.Stream, writer, conn, nconn, time.Duration(ai.agent.WriteTimeout))
+		if err != nil {
+			return err
+		}
+
+		// disable read deadline
+		nconn.SetReadDeadline(time.Time{})
+
+		return ai.runWriter(writer)
+	})
+}
+
+func (ai *agentInstance) runSRT() error {
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL(ai.agent.URL)
+	if err != nil {
+		return err
+	}
+
+	err = srtConf.Validate()
+	if err != nil {
+		return err
+	}
+
+	sconn, err := srt.Dial("srt", address, srtConf)
+	if err != nil {
+		return err
+	}
+
+	return ai.runConn(sconn, func() error {
+		writer := asyncwriter.New(ai.agent.WriteQueueSize, conf.ReaderOverflowPolicyDropOldest, ai.agent)
+
+		defer ai.agent.Stream.RemoveReader(writer)
+
+		bw := bufio.NewWriterSize(sconn, int(srtConf.PayloadSize))
+
+		err := mpegts.FromStream(ai.agent.Stream, writer, bw, sconn, time.Duration(ai.agent.WriteTimeout))
+		if err != nil {
+			return err
+		}
+
+		return ai.runWriter(writer)
+	})
+}
+
+func (ai *agentInstance) runWriter(writer *asyncwriter.Writer) error {
+	formats := ai.agent.Stream.FormatsForReader(writer)
+
+	ai.agent.Log(logger.Info, "is pushing %s", defs.FormatsInfo(formats))
+
+	// warn once about tracks that can't be sent to the destination,
+	// since the stream doesn't change between reconnections.
+	if !ai.agent.unsupportedWarned {
+		ai.agent.unsupportedWarned = true
+
+		for _, media := range ai.agent.Stream.Desc().Medias {
+			for _, forma := range media.Formats {
+				if !containsFormat(formats, forma) {
+					ai.agent.Log(logger.Warn, "skipping track with codec %s, since it is not supported by the destination",
+						forma.Codec())
+				}
+			}
+		}
+	}
+
+	writer.Start()
+
+	select {
+	case <-ai.ctx.Done():
+		writer.Stop()
+		return fmt.Errorf("terminated")
+
+	case err := <-writer.Error():
+		return err
+	}
+}
+
+func containsFormat(formats []format.Format, forma format.Format) bool {
+	for _, f := range formats {
+		if f == forma {
+			return true
+		}
+	}
+	return false
+}
@@ -15,10 +15,17 @@ type Agent struct {
 	Format            conf.RecordFormat
 	PartDuration      time.Duration
 	SegmentDuration   time.Duration
+	VideoMode         conf.RecordVideoMode
+	VideoFramerate    float64
+	SkipAudio         bool
+	WritePrft         bool
+	InterleaveWindow  time.Duration
+	DTSMode           conf.RecordDTSMode
 	PathName          string
+	Labels            map[string]string
 	Stream            *stream.Stream
 	OnSegmentCreate   OnSegmentFunc
-	OnSegmentComplete OnSegmentFunc
+	OnSegmentComplete OnSegmentCompleteFunc
 	Parent            logger.Writer
 
 	restartPause time.Duration
@@ -36,7 +43,7 @@ func (w *Agent) Initialize() {
 		}
 	}
 	if w.OnSegmentComplete == nil {
-		w.OnSegmentComplete = func(string) {
+		w.OnSegmentComplete = func(string, time.Time, time.Duration) {
 		}
 	}
 	if w.restartPause == 0 {
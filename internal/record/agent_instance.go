@@ -4,20 +4,26 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
 
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
 )
 
-// OnSegmentFunc is the prototype of the function passed as runOnSegmentStart / runOnSegmentComplete
+// OnSegmentFunc is the prototype of the function passed as runOnSegmentStart
 type OnSegmentFunc = func(string)
 
+// OnSegmentCompleteFunc is the prototype of the function passed as runOnSegmentComplete
+type OnSegmentCompleteFunc = func(path string, start time.Time, duration time.Duration)
+
 type sample struct {
 	*fmp4.PartSample
-	dts time.Duration
-	ntp time.Time
+	dts        time.Duration
+	ntp        time.Time
+	disposable bool
 }
 
 type agentInstance struct {
@@ -42,7 +48,7 @@ func (a *agentInstance) initialize() {
 	a.terminate = make(chan struct{})
 	a.done = make(chan struct{})
 
-	a.writer = asyncwriter.New(a.agent.WriteQueueSize, a.agent)
+	a.writer = asyncwriter.New(a.agent.WriteQueueSize, conf.ReaderOverflowPolicyDropOldest, a.agent)
 
 	switch a.agent.Format {
 	case conf.RecordFormatMPEGTS:
@@ -61,6 +67,32 @@ func (a *agentInstance) initialize() {
 	go a.run()
 }
 
+// segmentPath returns the path of a segment.
+// Stream properties are resolved when the segment is created.
+func (a *agentInstance) segmentPath(start time.Time) string {
+	p := Path{
+		Start:  start,
+		Labels: a.agent.Labels,
+	}
+
+	for _, media := range a.agent.Stream.Desc().Medias {
+		if media.Type != description.MediaTypeVideo {
+			continue
+		}
+
+		forma := media.Formats[0]
+		p.VideoCodec = forma.Codec()
+
+		if width, height, ok := defs.FormatResolution(forma); ok {
+			p.Width = width
+			p.Height = height
+		}
+		break
+	}
+
+	return p.Encode(a.pathFormat)
+}
+
 func (a *agentInstance) close() {
 	close(a.terminate)
 	<-a.done
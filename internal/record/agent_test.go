@@ -1,6 +1,7 @@
 package record
 
 import (
+	"encoding/binary"
 	"os"
 	"path/filepath"
 	"testing"
@@ -138,6 +139,7 @@ func TestAgent(t *testing.T) {
 				1460,
 				desc,
 				true,
+				0,
 				&test.NilLogger{},
 			)
 			require.NoError(t, err)
@@ -150,7 +152,13 @@ func TestAgent(t *testing.T) {
 			recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
 
 			segCreated := make(chan struct{}, 4)
-			segDone := make(chan struct{}, 4)
+			type segment struct {
+				path     string
+				start    time.Time
+				duration time.Duration
+			}
+
+			segDone := make(chan segment, 4)
 
 			var f conf.RecordFormat
 			if ca == "fmp4" {
@@ -170,8 +178,8 @@ func TestAgent(t *testing.T) {
 				OnSegmentCreate: func(fpath string) {
 					segCreated <- struct{}{}
 				},
-				OnSegmentComplete: func(fpath string) {
-					segDone <- struct{}{}
+				OnSegmentComplete: func(fpath string, start time.Time, duration time.Duration) {
+					segDone <- segment{fpath, start, duration}
 				},
 				Parent:       &test.NilLogger{},
 				restartPause: 1 * time.Millisecond,
@@ -190,11 +198,6 @@ func TestAgent(t *testing.T) {
 				},
 			})
 
-			for i := 0; i < 2; i++ {
-				<-segCreated
-				<-segDone
-			}
-
 			var ext string
 			if ca == "fmp4" {
 				ext = "mp4"
@@ -202,6 +205,19 @@ func TestAgent(t *testing.T) {
 				ext = "ts"
 			}
 
+			for i := 0; i < 2; i++ {
+				<-segCreated
+				seg := <-segDone
+
+				if i == 0 {
+					require.Equal(t, segment{
+						filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000."+ext),
+						time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC),
+						1 * time.Second,
+					}, seg)
+				}
+			}
+
 			_, err = os.Stat(filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000."+ext))
 			require.NoError(t, err)
 
@@ -254,6 +270,7 @@ func TestAgentFMP4NegativeDTS(t *testing.T) {
 		1460,
 		desc,
 		true,
+		0,
 		&test.NilLogger{},
 	)
 	require.NoError(t, err)
@@ -322,3 +339,470 @@ func TestAgentFMP4NegativeDTS(t *testing.T) {
 
 	require.Equal(t, true, found)
 }
+
+func TestAgentKeyframesOnly(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{
+		{
+			Type: description.MediaTypeVideo,
+			Formats: []rtspformat.Format{&rtspformat.H264{
+				PayloadTyp:        96,
+				PacketizationMode: 1,
+			}},
+		},
+		{
+			Type: description.MediaTypeAudio,
+			Formats: []rtspformat.Format{&rtspformat.MPEG4Audio{
+				PayloadTyp: 96,
+				Config: &mpeg4audio.Config{
+					Type:         2,
+					SampleRate:   44100,
+					ChannelCount: 2,
+				},
+				SizeLength:       13,
+				IndexLength:      3,
+				IndexDeltaLength: 3,
+			}},
+		},
+	}}
+
+	stream, err := stream.New(
+		1460,
+		desc,
+		true,
+		0,
+		&test.NilLogger{},
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+
+	w := &Agent{
+		WriteQueueSize:  1024,
+		PathFormat:      recordPath,
+		Format:          conf.RecordFormatFMP4,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 10 * time.Second,
+		VideoMode:       conf.RecordVideoModeKeyframesOnly,
+		SkipAudio:       true,
+		PathName:        "mypath",
+		Stream:          stream,
+		Parent:          &test.NilLogger{},
+	}
+	w.Initialize()
+
+	for i, idr := range []bool{true, false, false, true, false, true} {
+		au := [][]byte{{1}} // non-IDR
+		if idr {
+			au = [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			}
+		}
+
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: time.Duration(i) * 100 * time.Millisecond,
+				NTP: time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC),
+			},
+			AU: au,
+		})
+
+		stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.MPEG4Audio{
+			Base: unit.Base{
+				PTS: time.Duration(i) * 100 * time.Millisecond,
+			},
+			AUs: [][]byte{{1, 2, 3, 4}},
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	byts, err := os.ReadFile(filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4"))
+	require.NoError(t, err)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(byts)
+	require.NoError(t, err)
+
+	var durations []uint32
+
+	for _, part := range parts {
+		for _, track := range part.Tracks {
+			require.Equal(t, 1, track.ID)
+
+			for _, sample := range track.Samples {
+				require.Equal(t, false, sample.IsNonSyncSample)
+				durations = append(durations, sample.Duration)
+			}
+		}
+	}
+
+	require.Equal(t, []uint32{300 * 90, 200 * 90}, durations)
+}
+
+func TestAgentPathVariables(t *testing.T) {
+	for _, ca := range []string{"fmp4", "mpegts"} {
+		t.Run(ca, func(t *testing.T) {
+			desc := &description.Session{Medias: []*description.Media{{
+				Type: description.MediaTypeVideo,
+				Formats: []rtspformat.Format{&rtspformat.H264{
+					PayloadTyp:        96,
+					PacketizationMode: 1,
+				}},
+			}}}
+
+			stream, err := stream.New(
+				1460,
+				desc,
+				true,
+				0,
+				&test.NilLogger{},
+			)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			dir, err := os.MkdirTemp("", "mediamtx-agent")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			recordPath := filepath.Join(dir, "%path/%codec_%widthx%height_%label{site}_%label{camera}/%Y-%m-%d_%H-%M-%S-%f")
+
+			var format conf.RecordFormat
+			var ext string
+
+			if ca == "fmp4" {
+				format = conf.RecordFormatFMP4
+				ext = "mp4"
+			} else {
+				format = conf.RecordFormatMPEGTS
+				ext = "ts"
+			}
+
+			w := &Agent{
+				WriteQueueSize:  1024,
+				PathFormat:      recordPath,
+				Format:          format,
+				PartDuration:    100 * time.Millisecond,
+				SegmentDuration: 1 * time.Second,
+				PathName:        "mypath",
+				Labels:          map[string]string{"site": "rome"},
+				Stream:          stream,
+				Parent:          &test.NilLogger{},
+			}
+			w.Initialize()
+
+			for i := 0; i < 3; i++ {
+				stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+					Base: unit.Base{
+						PTS: time.Duration(i) * 200 * time.Millisecond,
+						NTP: time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC),
+					},
+					AU: [][]byte{
+						test.FormatH264.SPS,
+						test.FormatH264.PPS,
+						{5}, // IDR
+					},
+				})
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			w.Close()
+
+			_, err = os.Stat(filepath.Join(dir, "mypath", "H264_1920x1080_rome_unknown", "2008-05-20_22-15-25-000000."+ext))
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAgentPrft(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type: description.MediaTypeVideo,
+		Formats: []rtspformat.Format{&rtspformat.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+		}},
+	}}}
+
+	stream, err := stream.New(
+		1460,
+		desc,
+		true,
+		0,
+		&test.NilLogger{},
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+
+	w := &Agent{
+		WriteQueueSize:  1024,
+		PathFormat:      recordPath,
+		Format:          conf.RecordFormatFMP4,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 10 * time.Second,
+		WritePrft:       true,
+		PathName:        "mypath",
+		Stream:          stream,
+		Parent:          &test.NilLogger{},
+	}
+	w.Initialize()
+
+	start := time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: time.Duration(i) * 100 * time.Millisecond,
+				NTP: start.Add(time.Duration(i) * 100 * time.Millisecond),
+			},
+			AU: [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			},
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	byts, err := os.ReadFile(filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4"))
+	require.NoError(t, err)
+
+	var boxes [][]byte
+	for i := 0; i < len(byts); {
+		size := int(binary.BigEndian.Uint32(byts[i:]))
+		if string(byts[i+4:i+8]) == "prft" {
+			boxes = append(boxes, byts[i:i+size])
+		}
+		i += size
+	}
+
+	require.Equal(t, [][]byte{
+		marshalPrft(1, start, 0),
+		marshalPrft(1, start.Add(100*time.Millisecond), 100*90),
+		marshalPrft(1, start.Add(200*time.Millisecond), 200*90),
+	}, boxes)
+
+	require.Equal(t, uint64(0xcbddcbfd00000000), ntpTimestamp(start))
+}
+
+func TestAgentInterleave(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{
+		{
+			Type: description.MediaTypeVideo,
+			Formats: []rtspformat.Format{&rtspformat.H264{
+				PayloadTyp:        96,
+				PacketizationMode: 1,
+			}},
+		},
+		{
+			Type: description.MediaTypeAudio,
+			Formats: []rtspformat.Format{&rtspformat.MPEG4Audio{
+				PayloadTyp: 96,
+				Config: &mpeg4audio.Config{
+					Type:         2,
+					SampleRate:   44100,
+					ChannelCount: 2,
+				},
+				SizeLength:       13,
+				IndexLength:      3,
+				IndexDeltaLength: 3,
+			}},
+		},
+	}}
+
+	for _, ca := range []string{"disabled", "enabled"} {
+		t.Run(ca, func(t *testing.T) {
+			stream, err := stream.New(
+				1460,
+				desc,
+				true,
+				0,
+				&test.NilLogger{},
+			)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			dir, err := os.MkdirTemp("", "mediamtx-agent")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+
+			w := &Agent{
+				WriteQueueSize:  1024,
+				PathFormat:      recordPath,
+				Format:          conf.RecordFormatFMP4,
+				PartDuration:    100 * time.Millisecond,
+				SegmentDuration: 10 * time.Second,
+				PathName:        "mypath",
+				Stream:          stream,
+				Parent:          &test.NilLogger{},
+			}
+			if ca == "enabled" {
+				w.InterleaveWindow = 1 * time.Second
+			}
+			w.Initialize()
+
+			start := time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC)
+
+			// video is received before audio
+			for i := 0; i < 6; i++ {
+				stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+					Base: unit.Base{
+						PTS: time.Duration(i) * 100 * time.Millisecond,
+						NTP: start.Add(time.Duration(i) * 100 * time.Millisecond),
+					},
+					AU: [][]byte{
+						test.FormatH264.SPS,
+						test.FormatH264.PPS,
+						{5}, // IDR
+					},
+				})
+			}
+
+			for i := 0; i < 6; i++ {
+				stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.MPEG4Audio{
+					Base: unit.Base{
+						PTS: time.Duration(i) * 100 * time.Millisecond,
+						NTP: start.Add(time.Duration(i) * 100 * time.Millisecond),
+					},
+					AUs: [][]byte{{1, 2, 3, 4}},
+				})
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			w.Close()
+
+			byts, err := os.ReadFile(filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4"))
+			require.NoError(t, err)
+
+			var parts fmp4.Parts
+			err = parts.Unmarshal(byts)
+			require.NoError(t, err)
+
+			var trackCounts []int
+			for _, part := range parts {
+				trackCounts = append(trackCounts, len(part.Tracks))
+			}
+
+			if ca == "enabled" {
+				// each part contains samples of both tracks.
+				require.Equal(t, []int{2, 2, 2, 2, 2}, trackCounts)
+			} else {
+				// audio samples are written together at the end.
+				require.Equal(t, []int{1, 1, 1, 1, 2}, trackCounts)
+			}
+		})
+	}
+}
+
+func TestAgentDecodeOrderDTS(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type: description.MediaTypeVideo,
+		Formats: []rtspformat.Format{&rtspformat.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+		}},
+	}}}
+
+	stream, err := stream.New(
+		1460,
+		desc,
+		true,
+		0,
+		&test.NilLogger{},
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := &Agent{
+		WriteQueueSize:  1024,
+		PathFormat:      filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		Format:          conf.RecordFormatFMP4,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 10 * time.Second,
+		DTSMode:         conf.RecordDTSModeDecodeOrder,
+		PathName:        "mypath",
+		Stream:          stream,
+		Parent:          &test.NilLogger{},
+	}
+	w.Initialize()
+
+	start := time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC)
+
+	// I P B B P B B I, in decode order
+	ptss := []int{0, 3, 1, 2, 6, 4, 5, 9}
+
+	for i, pts := range ptss {
+		au := [][]byte{{1}}
+		if i == 0 || i == len(ptss)-1 {
+			au = [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			}
+		}
+
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: time.Duration(pts) * 100 * time.Millisecond,
+				NTP: start.Add(time.Duration(i) * 100 * time.Millisecond),
+			},
+			AU: au,
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	byts, err := os.ReadFile(filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4"))
+	require.NoError(t, err)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(byts)
+	require.NoError(t, err)
+
+	var dtss []int64
+	var outPTSs []int64
+
+	for _, part := range parts {
+		for _, track := range part.Tracks {
+			dts := int64(track.BaseTime)
+			for _, sample := range track.Samples {
+				dtss = append(dtss, dts)
+				outPTSs = append(outPTSs, dts+int64(sample.PTSOffset))
+				dts += int64(sample.Duration)
+			}
+		}
+	}
+
+	for i := 1; i < len(dtss); i++ {
+		require.Greater(t, dtss[i], dtss[i-1])
+	}
+
+	// PTS are preserved. The last frame is not written since its duration is unknown.
+	require.Equal(t, []int64{0, 27000, 9000, 18000, 54000, 36000, 45000}, outPTSs)
+}
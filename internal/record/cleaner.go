@@ -5,6 +5,8 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -18,6 +20,7 @@ type CleanerEntry struct {
 	Path        string
 	Format      conf.RecordFormat
 	DeleteAfter time.Duration
+	MaxSize     uint64
 }
 
 // Cleaner removes expired recording segments from disk.
@@ -27,6 +30,7 @@ type Cleaner struct {
 
 	ctx       context.Context
 	ctxCancel func()
+	mutex     sync.Mutex
 
 	done chan struct{}
 }
@@ -50,22 +54,39 @@ func (c *Cleaner) Log(level logger.Level, format string, args ...interface{}) {
 	c.Parent.Log(level, "[record cleaner]"+format, args...)
 }
 
-func (c *Cleaner) run() {
-	defer close(c.done)
+// ReloadEntries replaces the entries of the Cleaner.
+// New entries are used starting from the next cleanup cycle.
+func (c *Cleaner) ReloadEntries(entries []CleanerEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Entries = entries
+}
 
+func (c *Cleaner) entries() []CleanerEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.Entries
+}
+
+func cleanerInterval(entries []CleanerEntry) time.Duration {
 	interval := 30 * 60 * time.Second
-	for _, e := range c.Entries {
-		if interval > (e.DeleteAfter / 2) {
+	for _, e := range entries {
+		if e.DeleteAfter != 0 && interval > (e.DeleteAfter/2) {
 			interval = e.DeleteAfter / 2
 		}
 	}
+	return interval
+}
 
-	c.doRun() //nolint:errcheck
+func (c *Cleaner) run() {
+	defer close(c.done)
+
+	interval := c.doRun()
 
 	for {
 		select {
 		case <-time.After(interval):
-			c.doRun()
+			interval = c.doRun()
 
 		case <-c.ctx.Done():
 			return
@@ -73,10 +94,14 @@ func (c *Cleaner) run() {
 	}
 }
 
-func (c *Cleaner) doRun() {
-	for _, e := range c.Entries {
+func (c *Cleaner) doRun() time.Duration {
+	entries := c.entries()
+
+	for _, e := range entries {
 		c.doRunEntry(&e) //nolint:errcheck
 	}
+
+	return cleanerInterval(entries)
 }
 
 func (c *Cleaner) doRunEntry(e *CleanerEntry) error {
@@ -89,6 +114,15 @@ func (c *Cleaner) doRunEntry(e *CleanerEntry) error {
 	commonPath := CommonPath(entryPath)
 	now := timeNow()
 
+	type segment struct {
+		fpath string
+		start time.Time
+		size  uint64
+	}
+
+	// segments that have not been removed because of their age, grouped by path
+	segmentsByPath := make(map[string][]segment)
+
 	filepath.Walk(commonPath, func(fpath string, info fs.FileInfo, err error) error { //nolint:errcheck
 		if err != nil {
 			return err
@@ -98,9 +132,15 @@ func (c *Cleaner) doRunEntry(e *CleanerEntry) error {
 			var pa Path
 			ok := pa.Decode(entryPath, fpath)
 			if ok {
-				if now.Sub(pa.Start) > e.DeleteAfter {
+				if e.DeleteAfter != 0 && now.Sub(pa.Start) > e.DeleteAfter {
 					c.Log(logger.Debug, "removing %s", fpath)
 					os.Remove(fpath)
+				} else {
+					segmentsByPath[pa.Path] = append(segmentsByPath[pa.Path], segment{
+						fpath: fpath,
+						start: pa.Start,
+						size:  uint64(info.Size()),
+					})
 				}
 			}
 		}
@@ -108,6 +148,30 @@ func (c *Cleaner) doRunEntry(e *CleanerEntry) error {
 		return nil
 	})
 
+	if e.MaxSize != 0 {
+		for _, segments := range segmentsByPath {
+			sort.Slice(segments, func(i, j int) bool {
+				return segments[i].start.Before(segments[j].start)
+			})
+
+			var totalSize uint64
+			for _, seg := range segments {
+				totalSize += seg.size
+			}
+
+			// never remove the newest segment, since it may be still in use.
+			for _, seg := range segments[:len(segments)-1] {
+				if totalSize <= e.MaxSize {
+					break
+				}
+
+				c.Log(logger.Debug, "removing %s", seg.fpath)
+				os.Remove(seg.fpath)
+				totalSize -= seg.size
+			}
+		}
+	}
+
 	filepath.Walk(commonPath, func(fpath string, info fs.FileInfo, err error) error { //nolint:errcheck
 		if err != nil {
 			return err
@@ -50,3 +50,47 @@ func TestCleaner(t *testing.T) {
 	_, err = os.Stat(filepath.Join(dir, specialChars+"_mypath", "2009-05-20_22-15-25-000427.mp4"))
 	require.NoError(t, err)
 }
+
+func TestCleanerMaxSize(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2009, 0o5, 20, 22, 15, 25, 427000, time.Local)
+	}
+
+	dir, err := os.MkdirTemp("", "mediamtx-cleaner")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+	require.NoError(t, err)
+
+	for _, name := range []string{
+		"2009-05-20_22-15-22-000000.mp4",
+		"2009-05-20_22-15-23-000000.mp4",
+		"2009-05-20_22-15-24-000000.mp4",
+	} {
+		err = os.WriteFile(filepath.Join(dir, "mypath", name), make([]byte, 10), 0o644)
+		require.NoError(t, err)
+	}
+
+	c := &Cleaner{
+		Entries: []CleanerEntry{{
+			Path:    filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+			Format:  conf.RecordFormatFMP4,
+			MaxSize: 25,
+		}},
+		Parent: test.NilLogger{},
+	}
+	c.Initialize()
+	defer c.Close()
+
+	time.Sleep(500 * time.Millisecond)
+
+	_, err = os.Stat(filepath.Join(dir, "mypath", "2009-05-20_22-15-22-000000.mp4"))
+	require.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "mypath", "2009-05-20_22-15-23-000000.mp4"))
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "mypath", "2009-05-20_22-15-24-000000.mp4"))
+	require.NoError(t, err)
+}
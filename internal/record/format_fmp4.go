@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediacommon/pkg/codecs/ac3"
 	"github.com/bluenviron/mediacommon/pkg/codecs/av1"
@@ -19,6 +20,7 @@ import (
 	"github.com/bluenviron/mediacommon/pkg/codecs/vp9"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
 
+	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/test"
@@ -29,7 +31,16 @@ func durationGoToMp4(v time.Duration, timeScale uint32) uint64 {
 	timeScale64 := uint64(timeScale)
 	secs := v / time.Second
 	dec := v % time.Second
-	return uint64(secs)*timeScale64 + uint64(dec)*timeScale64/uint64(time.Second)
+	// round to the closest tick, since timestamps are usually converted from
+	// the same time scale and truncation would shorten them by one tick.
+	return uint64(secs)*timeScale64 + (uint64(dec)*timeScale64+uint64(time.Second)/2)/uint64(time.Second)
+}
+
+func durationMp4ToGo(v uint64, timeScale uint32) time.Duration {
+	timeScale64 := uint64(timeScale)
+	secs := v / timeScale64
+	dec := v % timeScale64
+	return time.Duration(secs)*time.Second + time.Duration(dec)*time.Second/time.Duration(timeScale64)
 }
 
 func mpeg1audioChannelCount(cm mpeg1audio.ChannelMode) int {
@@ -103,12 +114,19 @@ type formatFMP4 struct {
 	a *agentInstance
 
 	tracks             []*formatFMP4Track
+	interleaver        *formatFMP4Interleaver
 	hasVideo           bool
 	currentSegment     *formatFMP4Segment
 	nextSequenceNumber uint32
 }
 
 func (f *formatFMP4) initialize() {
+	if f.a.agent.InterleaveWindow != 0 {
+		f.interleaver = &formatFMP4Interleaver{
+			window: f.a.agent.InterleaveWindow,
+		}
+	}
+
 	nextID := 1
 	var formats []rtspformat.Format
 
@@ -125,6 +143,10 @@ func (f *formatFMP4) initialize() {
 			initTrack: initTrack,
 		}
 
+		if codec.IsVideo() {
+			track.decimator = newFrameDecimator(f.a.agent.VideoFramerate)
+		}
+
 		f.tracks = append(f.tracks, track)
 		formats = append(formats, format)
 		return track
@@ -141,6 +163,10 @@ func (f *formatFMP4) initialize() {
 	}
 
 	for _, media := range f.a.agent.Stream.Desc().Medias {
+		if f.a.agent.SkipAudio && media.Type == description.MediaTypeAudio {
+			continue
+		}
+
 		for _, forma := range media.Formats {
 			switch forma := forma.(type) {
 			case *rtspformat.AV1:
@@ -308,6 +334,8 @@ func (f *formatFMP4) initialize() {
 				track := addTrack(forma, codec)
 
 				var dtsExtractor *h265.DTSExtractor
+				var decodeOrder decodeOrderDTS
+				extractFailed := false
 
 				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.H265)
@@ -351,13 +379,29 @@ func (f *formatFMP4) initialize() {
 						dtsExtractor = h265.NewDTSExtractor()
 					}
 
-					dts, err := dtsExtractor.Extract(tunit.AU, tunit.PTS)
-					if err != nil {
-						return err
+					dts := decodeOrder.derive(tunit.PTS)
+
+					if f.a.agent.DTSMode == conf.RecordDTSModeBitstream {
+						if extractFailed && randomAccess {
+							extractFailed = false
+							dtsExtractor = h265.NewDTSExtractor()
+						}
+
+						if !extractFailed {
+							extracted, err := dtsExtractor.Extract(tunit.AU, tunit.PTS)
+							if err != nil {
+								// use the DTS derived from decode order
+								// until extraction restarts from the next random access frame.
+								f.a.agent.Log(logger.Warn, "unable to extract DTS, deriving it from decode order: %v", err)
+								extractFailed = true
+							} else {
+								dts = extracted
+							}
+						}
 					}
 
 					sampl, err := fmp4.NewPartSampleH26x(
-						int32(durationGoToMp4(tunit.PTS-dts, 90000)),
+						ptsOffsetGoToMp4(tunit.PTS-dts, 90000),
 						randomAccess,
 						tunit.AU)
 					if err != nil {
@@ -386,6 +430,8 @@ func (f *formatFMP4) initialize() {
 				track := addTrack(forma, codec)
 
 				var dtsExtractor *h264.DTSExtractor
+				var decodeOrder decodeOrderDTS
+				extractFailed := false
 
 				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.H264)
@@ -422,13 +468,29 @@ func (f *formatFMP4) initialize() {
 						dtsExtractor = h264.NewDTSExtractor()
 					}
 
-					dts, err := dtsExtractor.Extract(tunit.AU, tunit.PTS)
-					if err != nil {
-						return err
+					dts := decodeOrder.derive(tunit.PTS)
+
+					if f.a.agent.DTSMode == conf.RecordDTSModeBitstream {
+						if extractFailed && randomAccess {
+							extractFailed = false
+							dtsExtractor = h264.NewDTSExtractor()
+						}
+
+						if !extractFailed {
+							extracted, err := dtsExtractor.Extract(tunit.AU, tunit.PTS)
+							if err != nil {
+								// use the DTS derived from decode order
+								// until extraction restarts from the next random access frame.
+								f.a.agent.Log(logger.Warn, "unable to extract DTS, deriving it from decode order: %v", err)
+								extractFailed = true
+							} else {
+								dts = extracted
+							}
+						}
 					}
 
 					sampl, err := fmp4.NewPartSampleH26x(
-						int32(durationGoToMp4(tunit.PTS-dts, 90000)),
+						ptsOffsetGoToMp4(tunit.PTS-dts, 90000),
 						randomAccess,
 						tunit.AU)
 					if err != nil {
@@ -439,6 +501,7 @@ func (f *formatFMP4) initialize() {
 						PartSample: sampl,
 						dts:        dts,
 						ntp:        tunit.NTP,
+						disposable: h264IsDisposable(tunit.AU),
 					})
 				})
 
@@ -587,8 +650,9 @@ func (f *formatFMP4) initialize() {
 						PartSample: &fmp4.PartSample{
 							Payload: tunit.Frame,
 						},
-						dts: tunit.PTS,
-						ntp: tunit.NTP,
+						dts:        tunit.PTS,
+						ntp:        tunit.NTP,
+						disposable: true,
 					})
 				})
 
@@ -842,6 +906,12 @@ func (f *formatFMP4) initialize() {
 }
 
 func (f *formatFMP4) close() {
+	// write samples that are still waiting to be interleaved,
+	// in order not to lose the tail of the recording.
+	if f.interleaver != nil {
+		f.interleaver.flush() //nolint:errcheck
+	}
+
 	if f.currentSegment != nil {
 		f.currentSegment.close() //nolint:errcheck
 	}
@@ -0,0 +1,46 @@
+package record
+
+import (
+	"time"
+)
+
+// maximum difference between DTS of consecutive samples
+// that is considered a reordering instead of a discontinuity.
+const maxDTSCorrection = 1 * time.Second
+
+// ptsOffsetGoToMp4 converts the difference between PTS and DTS
+// into a composition time offset. The offset is negative when PTS precedes DTS.
+func ptsOffsetGoToMp4(v time.Duration, timeScale uint32) int32 {
+	if v < 0 {
+		return -int32(durationGoToMp4(-v, timeScale))
+	}
+	return int32(durationGoToMp4(v, timeScale))
+}
+
+// decodeOrderDTS derives DTS from the decode order of frames,
+// for streams whose DTS can't be extracted from the bitstream.
+// DTS is set to PTS minus the reorder delay, that is the largest distance
+// between the PTS of a frame and the PTS of frames received before it.
+// DTS of frames that follow a reordered one are then moved forward
+// by formatFMP4Track, in order to make them strictly increasing.
+type decodeOrderDTS struct {
+	initialized bool
+	maxPTS      time.Duration
+	delay       time.Duration
+}
+
+func (d *decodeOrderDTS) derive(pts time.Duration) time.Duration {
+	if !d.initialized {
+		d.initialized = true
+		d.maxPTS = pts
+		return pts
+	}
+
+	if pts > d.maxPTS {
+		d.maxPTS = pts
+	} else if (d.maxPTS-pts) > d.delay && (d.maxPTS-pts) <= maxDTSCorrection {
+		d.delay = d.maxPTS - pts
+	}
+
+	return pts - d.delay
+}
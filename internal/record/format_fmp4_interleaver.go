@@ -0,0 +1,75 @@
+package record
+
+import (
+	"time"
+)
+
+// maximum number of samples waiting to be interleaved.
+// When exceeded, the oldest samples are written regardless of the window.
+const formatFMP4InterleaverMaxSamples = 1024
+
+type formatFMP4InterleavedSample struct {
+	track  *formatFMP4Track
+	sample *sample
+	next   *sample
+}
+
+// formatFMP4Interleaver buffers samples of all tracks and writes them in DTS order,
+// once they are older than the window with respect to the newest sample.
+// This prevents long runs of samples of the same track when tracks are received
+// with different delays.
+type formatFMP4Interleaver struct {
+	window time.Duration
+
+	queue     []formatFMP4InterleavedSample
+	newestDTS time.Duration
+}
+
+func (i *formatFMP4Interleaver) push(track *formatFMP4Track, sample *sample, next *sample) error {
+	// insert the sample after all queued samples with lower or equal DTS.
+	pos := len(i.queue)
+	for pos > 0 && i.queue[pos-1].sample.dts > sample.dts {
+		pos--
+	}
+
+	i.queue = append(i.queue, formatFMP4InterleavedSample{})
+	copy(i.queue[pos+1:], i.queue[pos:])
+	i.queue[pos] = formatFMP4InterleavedSample{
+		track:  track,
+		sample: sample,
+		next:   next,
+	}
+
+	if len(i.queue) == 1 || sample.dts > i.newestDTS {
+		i.newestDTS = sample.dts
+	}
+
+	for len(i.queue) != 0 &&
+		(len(i.queue) > formatFMP4InterleaverMaxSamples ||
+			(i.newestDTS-i.queue[0].sample.dts) >= i.window) {
+		err := i.pop()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *formatFMP4Interleaver) pop() error {
+	e := i.queue[0]
+	i.queue[0] = formatFMP4InterleavedSample{}
+	i.queue = i.queue[1:]
+	return e.track.write(e.sample, e.next)
+}
+
+// flush writes all queued samples.
+func (i *formatFMP4Interleaver) flush() error {
+	for len(i.queue) != 0 {
+		err := i.pop()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
@@ -24,6 +25,11 @@ func writePart(
 		i++
 	}
 
+	// write tracks in a stable order.
+	sort.Slice(fmp4PartTracks, func(i, j int) bool {
+		return fmp4PartTracks[i].ID < fmp4PartTracks[j].ID
+	})
+
 	part := &fmp4.Part{
 		SequenceNumber: sequenceNumber,
 		Tracks:         fmp4PartTracks,
@@ -45,7 +51,6 @@ type formatFMP4Part struct {
 	startDTS       time.Duration
 
 	partTracks map[*formatFMP4Track]*fmp4.PartTrack
-	endDTS     time.Duration
 }
 
 func (p *formatFMP4Part) initialize() {
@@ -54,7 +59,7 @@ func (p *formatFMP4Part) initialize() {
 
 func (p *formatFMP4Part) close() error {
 	if p.s.fi == nil {
-		p.s.path = Path{Start: p.s.startNTP}.Encode(p.s.f.a.pathFormat)
+		p.s.path = p.s.f.a.segmentPath(p.s.startNTP)
 		p.s.f.a.agent.Log(logger.Debug, "creating segment %s", p.s.path)
 
 		err := os.MkdirAll(filepath.Dir(p.s.path), 0o755)
@@ -67,20 +72,47 @@ func (p *formatFMP4Part) close() error {
 			return err
 		}
 
+		segmentWriteStarted(p.s.path)
+
 		p.s.f.a.agent.OnSegmentCreate(p.s.path)
 
 		err = writeInit(fi, p.s.f.tracks)
 		if err != nil {
 			fi.Close()
+			segmentWriteFinished(p.s.path)
 			return err
 		}
 
 		p.s.fi = fi
 	}
 
+	if p.s.f.a.agent.WritePrft {
+		_, err := p.s.fi.Write(p.prft())
+		if err != nil {
+			return err
+		}
+	}
+
 	return writePart(p.s.fi, p.sequenceNumber, p.partTracks)
 }
 
+// prft returns a Producer Reference Time box that allows to align
+// recordings of different paths on the wall clock.
+// The box refers to the track with the lowest ID.
+func (p *formatFMP4Part) prft() []byte {
+	var ref *formatFMP4Track
+	for track := range p.partTracks {
+		if ref == nil || track.initTrack.ID < ref.initTrack.ID {
+			ref = track
+		}
+	}
+
+	mediaTime := p.partTracks[ref].BaseTime
+	ntp := p.s.startNTP.Add(durationMp4ToGo(mediaTime, ref.initTrack.TimeScale))
+
+	return marshalPrft(uint32(ref.initTrack.ID), ntp, mediaTime)
+}
+
 func (p *formatFMP4Part) record(track *formatFMP4Track, sample *sample) error {
 	partTrack, ok := p.partTracks[track]
 	if !ok {
@@ -92,11 +124,23 @@ func (p *formatFMP4Part) record(track *formatFMP4Track, sample *sample) error {
 	}
 
 	partTrack.Samples = append(partTrack.Samples, sample.PartSample)
-	p.endDTS = sample.dts
 
 	return nil
 }
 
-func (p *formatFMP4Part) duration() time.Duration {
-	return p.endDTS - p.startDTS
+// partBoundaryReached checks whether a part has to be closed before a sample.
+// Parts are never split in the middle of a sample, therefore they are closed
+// at the sample boundary that is nearest to the target duration.
+func partBoundaryReached(
+	startDTS time.Duration,
+	partDuration time.Duration,
+	sampleDTS time.Duration,
+	nextSampleDTS time.Duration,
+) bool {
+	target := startDTS + partDuration
+	return (target - sampleDTS) <= (nextSampleDTS - target)
+}
+
+func (p *formatFMP4Part) isComplete(sampleDTS time.Duration, nextSampleDTS time.Duration) bool {
+	return partBoundaryReached(p.startDTS, p.s.f.a.agent.PartDuration, sampleDTS, nextSampleDTS)
 }
@@ -0,0 +1,47 @@
+package record
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartBoundaryReached(t *testing.T) {
+	for _, ca := range []struct {
+		name          string
+		sampleDTS     time.Duration
+		nextSampleDTS time.Duration
+		reached       bool
+	}{
+		{
+			"before target",
+			60 * time.Millisecond,
+			90 * time.Millisecond,
+			false,
+		},
+		{
+			"current boundary is nearest",
+			90 * time.Millisecond,
+			140 * time.Millisecond,
+			true,
+		},
+		{
+			"next boundary is nearest",
+			70 * time.Millisecond,
+			110 * time.Millisecond,
+			false,
+		},
+		{
+			"after target",
+			120 * time.Millisecond,
+			150 * time.Millisecond,
+			true,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			require.Equal(t, ca.reached,
+				partBoundaryReached(0, 100*time.Millisecond, ca.sampleDTS, ca.nextSampleDTS))
+		})
+	}
+}
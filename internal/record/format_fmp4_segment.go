@@ -36,12 +36,14 @@ type formatFMP4Segment struct {
 	startDTS time.Duration
 	startNTP time.Time
 
+	endDTS  time.Duration
 	path    string
 	fi      *os.File
 	curPart *formatFMP4Part
 }
 
 func (s *formatFMP4Segment) initialize() {
+	s.endDTS = s.startDTS
 }
 
 func (s *formatFMP4Segment) close() error {
@@ -54,19 +56,20 @@ func (s *formatFMP4Segment) close() error {
 	if s.fi != nil {
 		s.f.a.agent.Log(logger.Debug, "closing segment %s", s.path)
 		err2 := s.fi.Close()
+		segmentWriteFinished(s.path)
 		if err == nil {
 			err = err2
 		}
 
 		if err2 == nil {
-			s.f.a.agent.OnSegmentComplete(s.path)
+			s.f.a.agent.OnSegmentComplete(s.path, s.startNTP, s.endDTS-s.startDTS)
 		}
 	}
 
 	return err
 }
 
-func (s *formatFMP4Segment) record(track *formatFMP4Track, sample *sample) error {
+func (s *formatFMP4Segment) record(track *formatFMP4Track, sample *sample, nextDTS time.Duration) error {
 	if s.curPart == nil {
 		s.curPart = &formatFMP4Part{
 			s:              s,
@@ -75,7 +78,7 @@ func (s *formatFMP4Segment) record(track *formatFMP4Track, sample *sample) error
 		}
 		s.curPart.initialize()
 		s.f.nextSequenceNumber++
-	} else if s.curPart.duration() >= s.f.a.agent.PartDuration {
+	} else if s.curPart.isComplete(sample.dts, nextDTS) {
 		err := s.curPart.close()
 		s.curPart = nil
 
@@ -92,5 +95,10 @@ func (s *formatFMP4Segment) record(track *formatFMP4Track, sample *sample) error
 		s.f.nextSequenceNumber++
 	}
 
+	// the sample lasts until the next one.
+	if nextDTS > s.endDTS {
+		s.endDTS = nextDTS
+	}
+
 	return s.curPart.record(track, sample)
 }
@@ -1,17 +1,48 @@
 package record
 
 import (
+	"fmt"
+
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
 )
 
 type formatFMP4Track struct {
 	f         *formatFMP4
 	initTrack *fmp4.InitTrack
+	decimator *frameDecimator
 
 	nextSample *sample
 }
 
 func (t *formatFMP4Track) record(sample *sample) error {
+	// in keyframes-only mode, the duration of each keyframe
+	// lasts until the next one, therefore timestamps are preserved.
+	if t.f.a.agent.VideoMode == conf.RecordVideoModeKeyframesOnly &&
+		t.initTrack.Codec.IsVideo() &&
+		sample.IsNonSyncSample {
+		return nil
+	}
+
+	if t.decimator != nil && !t.decimator.keep(sample.dts, !sample.IsNonSyncSample, sample.disposable) {
+		return nil
+	}
+
+	// samples are received in decode order, therefore DTS must be strictly increasing.
+	// Small deviations are corrected by moving DTS after the previous one and by
+	// adjusting the composition time offset in order to preserve PTS.
+	// Otherwise, timestamps went back and the recording is restarted.
+	if t.nextSample != nil && sample.dts <= t.nextSample.dts {
+		if (t.nextSample.dts - sample.dts) > maxDTSCorrection {
+			return fmt.Errorf("DTS is not monotonically increasing")
+		}
+
+		shift := t.nextSample.dts + durationMp4ToGo(1, t.initTrack.TimeScale) - sample.dts
+		sample.dts += shift
+		sample.PTSOffset -= ptsOffsetGoToMp4(shift, t.initTrack.TimeScale)
+	}
+
 	// wait the first video sample before setting hasVideo
 	if t.initTrack.Codec.IsVideo() {
 		t.f.hasVideo = true
@@ -23,6 +54,16 @@ func (t *formatFMP4Track) record(sample *sample) error {
 	}
 	sample.Duration = uint32(durationGoToMp4(t.nextSample.dts-sample.dts, t.initTrack.TimeScale))
 
+	if t.f.interleaver != nil {
+		return t.f.interleaver.push(t, sample, t.nextSample)
+	}
+
+	return t.write(sample, t.nextSample)
+}
+
+// write writes a sample into the current segment.
+// next is the sample that follows it in the same track.
+func (t *formatFMP4Track) write(sample *sample, next *sample) error {
 	if t.f.currentSegment == nil {
 		t.f.currentSegment = &formatFMP4Segment{
 			f:        t.f,
@@ -35,14 +76,14 @@ func (t *formatFMP4Track) record(sample *sample) error {
 		return nil
 	}
 
-	err := t.f.currentSegment.record(t, sample)
+	err := t.f.currentSegment.record(t, sample, next.dts)
 	if err != nil {
 		return err
 	}
 
 	if (!t.f.hasVideo || t.initTrack.Codec.IsVideo()) &&
-		!t.nextSample.IsNonSyncSample &&
-		(t.nextSample.dts-t.f.currentSegment.startDTS) >= t.f.a.agent.SegmentDuration {
+		!next.IsNonSyncSample &&
+		(next.dts-t.f.currentSegment.startDTS) >= t.f.a.agent.SegmentDuration {
 		err := t.f.currentSegment.close()
 		if err != nil {
 			return err
@@ -50,8 +91,8 @@ func (t *formatFMP4Track) record(sample *sample) error {
 
 		t.f.currentSegment = &formatFMP4Segment{
 			f:        t.f,
-			startDTS: t.nextSample.dts,
-			startNTP: t.nextSample.ntp,
+			startDTS: next.dts,
+			startNTP: next.ntp,
 		}
 		t.f.currentSegment.initialize()
 	}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediacommon/pkg/codecs/ac3"
 	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
@@ -14,6 +15,7 @@ import (
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4video"
 	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
 
+	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/unit"
@@ -47,6 +49,21 @@ type formatMPEGTS struct {
 	mw             *mpegts.Writer
 	hasVideo       bool
 	currentSegment *formatMPEGTSSegment
+
+	// set when video parameters change, in order to start a new segment
+	// on the next random access point.
+	forceSwitch bool
+}
+
+// spsChanged checks whether an access unit contains a SPS that differs from the previous one.
+func spsChanged(prevSPS *[]byte, sps []byte) bool {
+	if sps == nil || bytes.Equal(sps, *prevSPS) {
+		return false
+	}
+
+	changed := *prevSPS != nil
+	*prevSPS = sps
+	return changed
 }
 
 func (f *formatMPEGTS) initialize() {
@@ -64,12 +81,18 @@ func (f *formatMPEGTS) initialize() {
 	}
 
 	for _, media := range f.a.agent.Stream.Desc().Medias {
+		if f.a.agent.SkipAudio && media.Type == description.MediaTypeAudio {
+			continue
+		}
+
 		for _, forma := range media.Formats {
 			switch forma := forma.(type) {
 			case *rtspformat.H265:
 				track := addTrack(forma, &mpegts.CodecH265{})
 
 				var dtsExtractor *h265.DTSExtractor
+				decimator := newFrameDecimator(f.a.agent.VideoFramerate)
+				var prevSPS []byte
 
 				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.H265)
@@ -77,6 +100,13 @@ func (f *formatMPEGTS) initialize() {
 						return nil
 					}
 
+					for _, nalu := range tunit.AU {
+						if h265.NALUType((nalu[0]>>1)&0b111111) == h265.NALUType_SPS_NUT &&
+							spsChanged(&prevSPS, nalu) {
+							f.forceSwitch = true
+						}
+					}
+
 					randomAccess := h265.IsRandomAccess(tunit.AU)
 
 					if dtsExtractor == nil {
@@ -91,13 +121,15 @@ func (f *formatMPEGTS) initialize() {
 						return err
 					}
 
-					return f.recordH26x(track, tunit.PTS, dts, tunit.NTP, randomAccess, tunit.AU)
+					return f.recordH26x(track, decimator, tunit.PTS, dts, tunit.NTP, randomAccess, false, tunit.AU)
 				})
 
 			case *rtspformat.H264:
 				track := addTrack(forma, &mpegts.CodecH264{})
 
 				var dtsExtractor *h264.DTSExtractor
+				decimator := newFrameDecimator(f.a.agent.VideoFramerate)
+				var prevSPS []byte
 
 				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.H264)
@@ -105,6 +137,13 @@ func (f *formatMPEGTS) initialize() {
 						return nil
 					}
 
+					for _, nalu := range tunit.AU {
+						if h264.NALUType(nalu[0]&0x1F) == h264.NALUTypeSPS &&
+							spsChanged(&prevSPS, nalu) {
+							f.forceSwitch = true
+						}
+					}
+
 					idrPresent := h264.IDRPresent(tunit.AU)
 
 					if dtsExtractor == nil {
@@ -119,7 +158,8 @@ func (f *formatMPEGTS) initialize() {
 						return err
 					}
 
-					return f.recordH26x(track, tunit.PTS, dts, tunit.NTP, idrPresent, tunit.AU)
+					return f.recordH26x(track, decimator, tunit.PTS, dts, tunit.NTP, idrPresent,
+						h264IsDisposable(tunit.AU), tunit.AU)
 				})
 
 			case *rtspformat.MPEG4Video:
@@ -127,6 +167,7 @@ func (f *formatMPEGTS) initialize() {
 
 				firstReceived := false
 				var lastPTS time.Duration
+				decimator := newFrameDecimator(f.a.agent.VideoFramerate)
 
 				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG4Video)
@@ -143,6 +184,13 @@ func (f *formatMPEGTS) initialize() {
 
 					f.hasVideo = true
 					randomAccess := bytes.Contains(tunit.Frame, []byte{0, 0, 1, byte(mpeg4video.GroupOfVOPStartCode)})
+					if !randomAccess && f.a.agent.VideoMode == conf.RecordVideoModeKeyframesOnly {
+						return nil
+					}
+
+					if decimator != nil && !decimator.keep(tunit.PTS, randomAccess, false) {
+						return nil
+					}
 
 					err := f.setupSegment(tunit.PTS, tunit.NTP, true, randomAccess)
 					if err != nil {
@@ -157,6 +205,7 @@ func (f *formatMPEGTS) initialize() {
 
 				firstReceived := false
 				var lastPTS time.Duration
+				decimator := newFrameDecimator(f.a.agent.VideoFramerate)
 
 				f.a.agent.Stream.AddReader(f.a.writer, media, forma, func(u unit.Unit) error {
 					tunit := u.(*unit.MPEG1Video)
@@ -173,6 +222,13 @@ func (f *formatMPEGTS) initialize() {
 
 					f.hasVideo = true
 					randomAccess := bytes.Contains(tunit.Frame, []byte{0, 0, 1, 0xB8})
+					if !randomAccess && f.a.agent.VideoMode == conf.RecordVideoModeKeyframesOnly {
+						return nil
+					}
+
+					if decimator != nil && !decimator.keep(tunit.PTS, randomAccess, false) {
+						return nil
+					}
 
 					err := f.setupSegment(tunit.PTS, tunit.NTP, true, randomAccess)
 					if err != nil {
@@ -291,6 +347,7 @@ func (f *formatMPEGTS) setupSegment(
 ) error {
 	switch {
 	case f.currentSegment == nil:
+		f.forceSwitch = false
 		f.currentSegment = &formatMPEGTSSegment{
 			f:        f,
 			startDTS: dts,
@@ -299,7 +356,9 @@ func (f *formatMPEGTS) setupSegment(
 		f.currentSegment.initialize()
 	case (!f.hasVideo || isVideo) &&
 		randomAccess &&
-		(dts-f.currentSegment.startDTS) >= f.a.agent.SegmentDuration:
+		((isVideo && f.forceSwitch) || (dts-f.currentSegment.startDTS) >= f.a.agent.SegmentDuration):
+		f.forceSwitch = false
+		f.currentSegment.endDTS = dts
 		err := f.currentSegment.close()
 		if err != nil {
 			return err
@@ -321,19 +380,33 @@ func (f *formatMPEGTS) setupSegment(
 		f.currentSegment.lastFlush = dts
 	}
 
+	if dts > f.currentSegment.endDTS {
+		f.currentSegment.endDTS = dts
+	}
+
 	return nil
 }
 
 func (f *formatMPEGTS) recordH26x(
 	track *mpegts.Track,
+	decimator *frameDecimator,
 	pts time.Duration,
 	dts time.Duration,
 	ntp time.Time,
 	randomAccess bool,
+	disposable bool,
 	au [][]byte,
 ) error {
 	f.hasVideo = true
 
+	if !randomAccess && f.a.agent.VideoMode == conf.RecordVideoModeKeyframesOnly {
+		return nil
+	}
+
+	if decimator != nil && !decimator.keep(dts, randomAccess, disposable) {
+		return nil
+	}
+
 	err := f.setupSegment(dts, ntp, true, randomAccess)
 	if err != nil {
 		return err
@@ -14,12 +14,14 @@ type formatMPEGTSSegment struct {
 	startNTP time.Time
 
 	lastFlush time.Duration
+	endDTS    time.Duration
 	path      string
 	fi        *os.File
 }
 
 func (s *formatMPEGTSSegment) initialize() {
 	s.lastFlush = s.startDTS
+	s.endDTS = s.startDTS
 	s.f.dw.setTarget(s)
 }
 
@@ -29,12 +31,13 @@ func (s *formatMPEGTSSegment) close() error {
 	if s.fi != nil {
 		s.f.a.agent.Log(logger.Debug, "closing segment %s", s.path)
 		err2 := s.fi.Close()
+		segmentWriteFinished(s.path)
 		if err == nil {
 			err = err2
 		}
 
 		if err2 == nil {
-			s.f.a.agent.OnSegmentComplete(s.path)
+			s.f.a.agent.OnSegmentComplete(s.path, s.startNTP, s.endDTS-s.startDTS)
 		}
 	}
 
@@ -43,7 +46,7 @@ func (s *formatMPEGTSSegment) close() error {
 
 func (s *formatMPEGTSSegment) Write(p []byte) (int, error) {
 	if s.fi == nil {
-		s.path = Path{Start: s.startNTP}.Encode(s.f.a.pathFormat)
+		s.path = s.f.a.segmentPath(s.startNTP)
 		s.f.a.agent.Log(logger.Debug, "creating segment %s", s.path)
 
 		err := os.MkdirAll(filepath.Dir(s.path), 0o755)
@@ -56,6 +59,8 @@ func (s *formatMPEGTSSegment) Write(p []byte) (int, error) {
 			return 0, err
 		}
 
+		segmentWriteStarted(s.path)
+
 		s.f.a.agent.OnSegmentCreate(s.path)
 
 		s.fi = fi
@@ -0,0 +1,92 @@
+package record
+
+import (
+	"time"
+
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+)
+
+// h264IsDisposable returns whether no other frame depends on the given access unit.
+func h264IsDisposable(au [][]byte) bool {
+	found := false
+
+	for _, nalu := range au {
+		typ := h264.NALUType(nalu[0] & 0x1F)
+		if typ >= h264.NALUTypeNonIDR && typ <= h264.NALUTypeIDR {
+			// nal_ref_idc
+			if (nalu[0] >> 5) != 0 {
+				return false
+			}
+			found = true
+		}
+	}
+
+	return found
+}
+
+// frameDecimator drops video frames in order to approximate a target framerate.
+//
+// Reference integrity is preserved: when a frame that is referenced by others is dropped,
+// all following frames are dropped until the next keyframe.
+// Therefore, frames are dropped cleanly when content is intra-only (i.e. M-JPEG),
+// or when dropped frames are not referenced (i.e. H264 B-frames);
+// otherwise, recordings fall back to the keyframes.
+type frameDecimator struct {
+	period time.Duration
+
+	initialized      bool
+	next             time.Duration
+	prevRandomAccess bool
+	waitingKeyframe  bool
+}
+
+func newFrameDecimator(framerate float64) *frameDecimator {
+	if framerate <= 0 {
+		return nil
+	}
+
+	return &frameDecimator{
+		period: time.Duration(float64(time.Second) / framerate),
+	}
+}
+
+func (d *frameDecimator) schedule(dts time.Duration) {
+	d.next += d.period
+	if !d.initialized || d.next <= dts {
+		d.next = dts + d.period
+	}
+	d.initialized = true
+}
+
+// keep returns whether a frame has to be recorded.
+// Timestamps of recorded frames are preserved, therefore
+// each frame lasts until the next recorded one.
+func (d *frameDecimator) keep(dts time.Duration, randomAccess bool, disposable bool) bool {
+	prevRandomAccess := d.prevRandomAccess
+	d.prevRandomAccess = randomAccess
+
+	if randomAccess {
+		d.waitingKeyframe = false
+	} else if d.waitingKeyframe {
+		return false
+	}
+
+	// allow a small tolerance to compensate rounding errors of timestamps.
+	if !d.initialized || dts >= (d.next-d.period/10) {
+		d.schedule(dts)
+		return true
+	}
+
+	// keyframes are always kept, unless content is intra-only.
+	if randomAccess && !prevRandomAccess {
+		d.initialized = false
+		d.schedule(dts)
+		return true
+	}
+
+	if !disposable {
+		d.waitingKeyframe = true
+	}
+
+	return false
+}
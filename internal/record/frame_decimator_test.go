@@ -0,0 +1,80 @@
+package record
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameDecimator(t *testing.T) {
+	type frame struct {
+		randomAccess bool
+		disposable   bool
+	}
+
+	var (
+		i = frame{randomAccess: true}
+		p = frame{}
+		b = frame{disposable: true}
+		j = frame{randomAccess: true, disposable: true}
+	)
+
+	for _, ca := range []struct {
+		name      string
+		framerate float64
+		frames    []frame
+		kept      []int
+	}{
+		{
+			"intra only",
+			5,
+			[]frame{j, j, j, j, j, j, j, j, j, j, j, j, j, j},
+			[]int{0, 6, 12},
+		},
+		{
+			"unreferenced frames",
+			15,
+			[]frame{i, b, p, b, p, b, p, b},
+			[]int{0, 2, 4, 6},
+		},
+		{
+			"referenced frames",
+			5,
+			[]frame{i, p, p, p, p, p, i, p, p, p, p, p, i},
+			[]int{0, 6, 12},
+		},
+		{
+			"keyframe before schedule",
+			5,
+			[]frame{i, p, p, p, i, p, p, p},
+			[]int{0, 4},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			d := newFrameDecimator(ca.framerate)
+
+			var kept []int
+
+			for n, fr := range ca.frames {
+				dts := time.Duration(n) * time.Second / 30
+				if d.keep(dts, fr.randomAccess, fr.disposable) {
+					kept = append(kept, n)
+				}
+			}
+
+			require.Equal(t, ca.kept, kept)
+		})
+	}
+}
+
+func TestFrameDecimatorDisabled(t *testing.T) {
+	require.Nil(t, newFrameDecimator(0))
+}
+
+func TestH264IsDisposable(t *testing.T) {
+	require.Equal(t, true, h264IsDisposable([][]byte{{0x01}}))
+	require.Equal(t, false, h264IsDisposable([][]byte{{0x41}}))
+	require.Equal(t, false, h264IsDisposable([][]byte{{0x65}}))
+	require.Equal(t, false, h264IsDisposable([][]byte{{0x09}}))
+}
@@ -62,10 +62,39 @@ func CommonPath(v string) string {
 	return common
 }
 
+// placeholder of variables that are not available when a segment is created.
+const pathPlaceholder = "unknown"
+
+var (
+	reLabelVariable        = regexp.MustCompile(`%label\{([a-zA-Z0-9_]+)\}`)
+	reLabelVariableEscaped = regexp.MustCompile(`%label\\\{[a-zA-Z0-9_]+\\\}`)
+	reLabelVariablePrefix  = regexp.MustCompile(`^%label\{[a-zA-Z0-9_]+\}`)
+	reUnsafePathChars      = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+// sanitizePathValue makes a value usable as part of a file name.
+func sanitizePathValue(v string) string {
+	if v == "" {
+		return pathPlaceholder
+	}
+	return reUnsafePathChars.ReplaceAllString(v, "_")
+}
+
+func encodeDimension(v int) string {
+	if v <= 0 {
+		return pathPlaceholder
+	}
+	return strconv.FormatInt(int64(v), 10)
+}
+
 // Path is a path of a recording segment.
 type Path struct {
-	Start time.Time
-	Path  string
+	Start      time.Time
+	Path       string
+	VideoCodec string
+	Width      int
+	Height     int
+	Labels     map[string]string
 }
 
 // Decode decodes a Path.
@@ -100,6 +129,10 @@ func (p *Path) Decode(format string, v string) bool {
 	re = strings.ReplaceAll(re, "%S", "([0-9]{2})")
 	re = strings.ReplaceAll(re, "%f", "([0-9]{6})")
 	re = strings.ReplaceAll(re, "%s", "([0-9]{10})")
+	re = strings.ReplaceAll(re, "%codec", "([^\\\\/]+?)")
+	re = strings.ReplaceAll(re, "%width", "([0-9]+|"+pathPlaceholder+")")
+	re = strings.ReplaceAll(re, "%height", "([0-9]+|"+pathPlaceholder+")")
+	re = reLabelVariableEscaped.ReplaceAllString(re, "([^\\\\/]+?)")
 	r := regexp.MustCompile(re)
 
 	var groupMapping []string
@@ -122,12 +155,19 @@ func (p *Path) Decode(format string, v string) bool {
 			"%S",
 			"%f",
 			"%s",
+			"%codec",
+			"%width",
+			"%height",
 		} {
 			if strings.HasPrefix(cur, va) {
 				groupMapping = append(groupMapping, va)
 			}
 		}
 
+		if label := reLabelVariablePrefix.FindString(cur); label != "" {
+			groupMapping = append(groupMapping, label)
+		}
+
 		cur = cur[1:]
 	}
 
@@ -186,6 +226,27 @@ func (p *Path) Decode(format string, v string) bool {
 
 		case "%s":
 			unixSec, _ = strconv.ParseInt(v, 10, 64)
+
+		case "%codec":
+			if v != pathPlaceholder {
+				p.VideoCodec = v
+			}
+
+		case "%width":
+			tmp, _ := strconv.ParseInt(v, 10, 64)
+			p.Width = int(tmp)
+
+		case "%height":
+			tmp, _ := strconv.ParseInt(v, 10, 64)
+			p.Height = int(tmp)
+
+		default:
+			if m := reLabelVariable.FindStringSubmatch(k); m != nil && v != pathPlaceholder {
+				if p.Labels == nil {
+					p.Labels = make(map[string]string)
+				}
+				p.Labels[m[1]] = v
+			}
 		}
 	}
 
@@ -209,5 +270,11 @@ func (p Path) Encode(format string) string {
 	format = strings.ReplaceAll(format, "%S", leadingZeros(p.Start.Second(), 2))
 	format = strings.ReplaceAll(format, "%f", leadingZeros(p.Start.Nanosecond()/1000, 6))
 	format = strings.ReplaceAll(format, "%s", strconv.FormatInt(p.Start.Unix(), 10))
+	format = strings.ReplaceAll(format, "%codec", sanitizePathValue(p.VideoCodec))
+	format = strings.ReplaceAll(format, "%width", encodeDimension(p.Width))
+	format = strings.ReplaceAll(format, "%height", encodeDimension(p.Height))
+	format = reLabelVariable.ReplaceAllStringFunc(format, func(v string) string {
+		return sanitizePathValue(p.Labels[reLabelVariable.FindStringSubmatch(v)[1]])
+	})
 	return format
 }
@@ -31,6 +31,19 @@ var pathCases = []struct {
 		},
 		"mypath/1638447323.mp4",
 	},
+	{
+		"stream variables",
+		"%path/%codec_%widthx%height_%label{site}/%Y-%m-%d_%H-%M-%S-%f.mp4",
+		Path{
+			Start:      time.Date(2008, 11, 0o7, 11, 22, 4, 123456000, time.Local),
+			Path:       "mypath",
+			VideoCodec: "H264",
+			Width:      1920,
+			Height:     1080,
+			Labels:     map[string]string{"site": "rome"},
+		},
+		"mypath/H264_1920x1080_rome/2008-11-07_11-22-04-123456.mp4",
+	},
 }
 
 func TestPathDecode(t *testing.T) {
@@ -51,3 +64,23 @@ func TestPathEncode(t *testing.T) {
 		})
 	}
 }
+
+func TestPathEncodeUnavailable(t *testing.T) {
+	p := Path{
+		Start:      time.Date(2008, 11, 0o7, 11, 22, 4, 123456000, time.Local),
+		Path:       "mypath",
+		VideoCodec: "MPEG-1/2 Video",
+	}
+
+	enc := p.Encode("%path/%codec_%widthx%height_%label{site}/%Y-%m-%d_%H-%M-%S-%f.mp4")
+	require.Equal(t, "mypath/MPEG-1_2_Video_unknownxunknown_unknown/2008-11-07_11-22-04-123456.mp4", enc)
+
+	var dec Path
+	ok := dec.Decode("%path/%codec_%widthx%height_%label{site}/%Y-%m-%d_%H-%M-%S-%f.mp4", enc)
+	require.Equal(t, true, ok)
+	require.Equal(t, Path{
+		Start:      p.Start,
+		Path:       "mypath",
+		VideoCodec: "MPEG-1_2_Video",
+	}, dec)
+}
@@ -0,0 +1,29 @@
+package record
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// seconds between the NTP epoch (1900) and the Unix epoch (1970).
+const ntpEpochOffset = 2208988800
+
+func ntpTimestamp(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// marshalPrft marshals a Producer Reference Time box (ISO/IEC 14496-12, 8.16.5),
+// that associates the media time of a track with the wall clock time of capture.
+func marshalPrft(trackID uint32, ntp time.Time, mediaTime uint64) []byte {
+	buf := make([]byte, 32)
+	binary.BigEndian.PutUint32(buf[0:], 32)
+	copy(buf[4:], "prft")
+	buf[8] = 1   // version
+	buf[11] = 24 // flags: the wall clock time is the capture time
+	binary.BigEndian.PutUint32(buf[12:], trackID)
+	binary.BigEndian.PutUint64(buf[16:], ntpTimestamp(ntp))
+	binary.BigEndian.PutUint64(buf[24:], mediaTime)
+	return buf
+}
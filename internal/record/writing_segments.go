@@ -0,0 +1,40 @@
+package record
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// segments that are currently being written, shared by all agents.
+var (
+	writingSegmentsMutex sync.Mutex
+	writingSegments      = make(map[string]struct{})
+)
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+func segmentWriteStarted(path string) {
+	writingSegmentsMutex.Lock()
+	defer writingSegmentsMutex.Unlock()
+	writingSegments[absPath(path)] = struct{}{}
+}
+
+func segmentWriteFinished(path string) {
+	writingSegmentsMutex.Lock()
+	defer writingSegmentsMutex.Unlock()
+	delete(writingSegments, absPath(path))
+}
+
+// IsSegmentBeingWritten returns whether a segment is currently being written by an agent.
+func IsSegmentBeingWritten(path string) bool {
+	writingSegmentsMutex.Lock()
+	defer writingSegmentsMutex.Unlock()
+	_, ok := writingSegments[absPath(path)]
+	return ok
+}
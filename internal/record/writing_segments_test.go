@@ -0,0 +1,20 @@
+package record
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritingSegments(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	segmentWriteStarted("recordings/mypath/segment.mp4")
+	require.Equal(t, true, IsSegmentBeingWritten(filepath.Join(wd, "recordings/mypath/segment.mp4")))
+
+	segmentWriteFinished("recordings/mypath/segment.mp4")
+	require.Equal(t, false, IsSegmentBeingWritten(filepath.Join(wd, "recordings/mypath/segment.mp4")))
+}
@@ -6,6 +6,8 @@ import (
 )
 
 // Restrict avoids listening on IPv6 when address is 0.0.0.0.
+// Other wildcard addresses (i.e. ":8554" and "[::]:8554") bind both IPv4 and IPv6
+// when the operating system allows it.
 func Restrict(network string, address string) (string, string) {
 	host, _, err := net.SplitHostPort(address)
 	if err == nil {
@@ -0,0 +1,26 @@
+package restrictnetwork
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestrict(t *testing.T) {
+	for _, ca := range []struct {
+		address string
+		network string
+	}{
+		{"0.0.0.0:8554", "tcp4"},
+		{":8554", "tcp"},
+		{"[::]:8554", "tcp"},
+		{"[::1]:8554", "tcp"},
+		{"127.0.0.1:8554", "tcp"},
+	} {
+		t.Run(ca.address, func(t *testing.T) {
+			network, address := Restrict("tcp", ca.address)
+			require.Equal(t, ca.network, network)
+			require.Equal(t, ca.address, address)
+		})
+	}
+}
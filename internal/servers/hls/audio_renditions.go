@@ -0,0 +1,187 @@
+package hls
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/bluenviron/gohlslib"
+	"github.com/bluenviron/gohlslib/pkg/codecparams"
+	"github.com/bluenviron/gohlslib/pkg/codecs"
+	"github.com/bluenviron/gohlslib/pkg/playlist"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+)
+
+const audioGroupID = "audio"
+
+// audioRendition is an audio track offered as an EXT-X-MEDIA rendition.
+// The first rendition is muxed together with the video track,
+// while the others are muxed by dedicated muxers.
+// Dedicated muxers receive the video track too, in order to cut segments
+// and parts on the same keyframes of the main muxer; the video track
+// is then removed from their files before serving them.
+type audioRendition struct {
+	language        string
+	track           *gohlslib.Track
+	hmuxer          *gohlslib.Muxer
+	discontinuities discontinuities
+}
+
+func audioRenditionPrefix(i int) string {
+	return "audio" + strconv.FormatInt(int64(i), 10) + "_"
+}
+
+// parseAudioRenditionFile returns the index of the rendition
+// a file belongs to, and the name of the file inside the rendition muxer.
+func parseAudioRenditionFile(fname string) (int, string, bool) {
+	if !strings.HasPrefix(fname, "audio") {
+		return 0, "", false
+	}
+
+	i := strings.IndexByte(fname, '_')
+	if i < 0 {
+		return 0, "", false
+	}
+
+	index, err := strconv.ParseUint(fname[len("audio"):i], 10, 31)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return int(index), fname[i+1:], true
+}
+
+// applyAudioRenditions adds audio renditions to a multivariant playlist.
+func applyAudioRenditions(byts []byte, renditions []*audioRendition, query string) []byte {
+	var pl playlist.Multivariant
+	err := pl.Unmarshal(byts)
+	if err != nil {
+		return byts
+	}
+
+	for _, v := range pl.Variants {
+		v.Audio = audioGroupID
+
+		// CODECS must contain the codecs of all renditions.
+	outer:
+		for _, r := range renditions[1:] {
+			codec := codecparams.Marshal(r.track.Codec)
+			for _, c := range v.Codecs {
+				if c == codec {
+					continue outer
+				}
+			}
+			v.Codecs = append(v.Codecs, codec)
+		}
+	}
+
+	for i, r := range renditions {
+		rend := &playlist.MultivariantRendition{
+			Type:       playlist.MultivariantRenditionTypeAudio,
+			GroupID:    audioGroupID,
+			Name:       r.language,
+			Language:   r.language,
+			Default:    (i == 0),
+			Autoselect: true,
+		}
+
+		// the first rendition is part of the main stream and has no URI.
+		if i != 0 {
+			rend.URI = audioRenditionPrefix(i) + mediaPlaylistName
+			if query != "" {
+				rend.URI = appendQuery(rend.URI, query)
+			}
+		}
+
+		pl.Renditions = append(pl.Renditions, rend)
+	}
+
+	out, err := pl.Marshal()
+	if err != nil {
+		return byts
+	}
+	return out
+}
+
+// cloneVideoTrack returns a copy of a video track, since muxers update
+// codec parameters of their tracks in place.
+func cloneVideoTrack(track *gohlslib.Track) *gohlslib.Track {
+	switch codec := track.Codec.(type) {
+	case *codecs.AV1:
+		c := *codec
+		return &gohlslib.Track{Codec: &c}
+
+	case *codecs.VP9:
+		c := *codec
+		return &gohlslib.Track{Codec: &c}
+
+	case *codecs.H265:
+		c := *codec
+		return &gohlslib.Track{Codec: &c}
+
+	case *codecs.H264:
+		c := *codec
+		return &gohlslib.Track{Codec: &c}
+	}
+
+	return nil
+}
+
+// stripVideoTrack removes the video track from an initialization segment,
+// segment or part produced by the muxer of an audio rendition.
+// The video track has always ID 1, while the audio track becomes track 1.
+func stripVideoTrack(byts []byte) []byte {
+	var buf seekablebuffer.Buffer
+
+	if len(byts) >= 8 && string(byts[4:8]) == "ftyp" {
+		var init fmp4.Init
+		err := init.Unmarshal(bytes.NewReader(byts))
+		if err != nil {
+			return byts
+		}
+
+		var tracks []*fmp4.InitTrack
+		for _, track := range init.Tracks {
+			if track.ID != 1 {
+				track.ID = 1
+				tracks = append(tracks, track)
+			}
+		}
+		init.Tracks = tracks
+
+		err = init.Marshal(&buf)
+		if err != nil {
+			return byts
+		}
+
+		return buf.Bytes()
+	}
+
+	var parts fmp4.Parts
+	err := parts.Unmarshal(byts)
+	if err != nil {
+		return byts
+	}
+
+	// parts that contain video only, written before audio started, are removed.
+	var audioParts fmp4.Parts
+	for _, part := range parts {
+		for _, track := range part.Tracks {
+			if track.ID != 1 {
+				track.ID = 1
+				part.Tracks = []*fmp4.PartTrack{track}
+				audioParts = append(audioParts, part)
+				break
+			}
+		}
+	}
+	parts = audioParts
+
+	err = parts.Marshal(&buf)
+	if err != nil {
+		return byts
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,87 @@
+package hls
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/playlist"
+)
+
+// insertStartTag inserts a EXT-X-START tag into a marshaled media playlist,
+// since the tag is not written by playlist.Media.
+func insertStartTag(byts []byte, offset time.Duration, precise bool) []byte {
+	tag := "#EXT-X-START:TIME-OFFSET=" + strconv.FormatFloat(offset.Seconds(), 'f', 5, 64)
+	if precise {
+		tag += ",PRECISE=YES"
+	}
+	tag += "\n"
+
+	i := bytes.Index(byts, []byte("#EXT-X-TARGETDURATION:"))
+	if i < 0 {
+		return byts
+	}
+
+	return append(byts[:i:i], append([]byte(tag), byts[i:]...)...)
+}
+
+// applyBurstOnConnect moves the starting point of a Low-Latency media playlist
+// to the most recent parts, in order to allow players to download them as soon
+// as they connect instead of waiting for new parts to be produced.
+// The starting point is moved back to the closest independent part, since
+// players can't decode frames that depend on previous ones.
+// The playlist is left untouched if it doesn't contain enough parts yet.
+func applyBurstOnConnect(byts []byte, partCount int) []byte {
+	var pl playlist.Media
+	err := pl.Unmarshal(byts)
+	if err != nil {
+		return byts
+	}
+
+	if pl.ServerControl == nil || pl.PartInf == nil {
+		return byts
+	}
+
+	var parts []*playlist.MediaPart
+	for _, seg := range pl.Segments {
+		parts = append(parts, seg.Parts...)
+	}
+	parts = append(parts, pl.Parts...)
+
+	if len(parts) < partCount {
+		return byts
+	}
+
+	// players must not start closer than PART-HOLD-BACK to the end of the playlist,
+	// therefore PART-HOLD-BACK is set to the duration of the burst.
+	partHoldBack := time.Duration(partCount) * pl.PartInf.PartTarget
+
+	var offset time.Duration
+	found := false
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i].Gap {
+			return byts
+		}
+
+		offset += parts[i].Duration
+
+		if (len(parts)-i) >= partCount && offset >= partHoldBack && parts[i].Independent {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return byts
+	}
+
+	pl.ServerControl.PartHoldBack = &partHoldBack
+
+	out, err := pl.Marshal()
+	if err != nil {
+		return byts
+	}
+
+	return insertStartTag(out, -offset, true)
+}
@@ -0,0 +1,79 @@
+package hls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testBurstPlaylist = "#EXTM3U\n" +
+	"#EXT-X-VERSION:9\n" +
+	"#EXT-X-TARGETDURATION:1\n" +
+	"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=0.60000\n" +
+	"#EXT-X-PART-INF:PART-TARGET=0.2\n" +
+	"#EXT-X-MEDIA-SEQUENCE:1\n" +
+	"#EXT-X-MAP:URI=\"init.mp4\"\n" +
+	"#EXT-X-PART:DURATION=0.20000,URI=\"part1.mp4\",INDEPENDENT=YES\n" +
+	"#EXT-X-PART:DURATION=0.20000,URI=\"part2.mp4\"\n" +
+	"#EXT-X-PART:DURATION=0.20000,URI=\"part3.mp4\"\n" +
+	"#EXT-X-PART:DURATION=0.20000,URI=\"part4.mp4\"\n" +
+	"#EXT-X-PART:DURATION=0.20000,URI=\"part5.mp4\"\n" +
+	"#EXTINF:1.00000,\n" +
+	"seg1.mp4\n" +
+	"#EXT-X-PART:DURATION=0.20000,URI=\"part6.mp4\",INDEPENDENT=YES\n" +
+	"#EXT-X-PART:DURATION=0.20000,URI=\"part7.mp4\"\n" +
+	"#EXT-X-PART:DURATION=0.20000,URI=\"part8.mp4\"\n" +
+	"#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"part9.mp4\"\n"
+
+func TestApplyBurstOnConnect(t *testing.T) {
+	for _, ca := range []struct {
+		name       string
+		partCount  int
+		start      string
+		holdBack   string
+		unmodified bool
+	}{
+		{
+			"independent",
+			3,
+			"-0.60000",
+			"0.6",
+			false,
+		},
+		{
+			"moved back to independent",
+			2,
+			"-0.60000",
+			"0.4",
+			false,
+		},
+		{
+			"previous segment",
+			4,
+			"-1.60000",
+			"0.8",
+			false,
+		},
+		{
+			"not enough parts",
+			9,
+			"",
+			"",
+			true,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			out := string(applyBurstOnConnect([]byte(testBurstPlaylist), ca.partCount))
+
+			if ca.unmodified {
+				require.Equal(t, testBurstPlaylist, out)
+				return
+			}
+
+			require.Contains(t, out, "#EXT-X-START:TIME-OFFSET="+ca.start+",PRECISE=YES\n"+
+				"#EXT-X-TARGETDURATION:1\n")
+			require.Contains(t, out, "PART-HOLD-BACK="+ca.holdBack)
+			require.Contains(t, out, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"part9.mp4\"\n")
+		})
+	}
+}
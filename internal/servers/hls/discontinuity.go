@@ -0,0 +1,110 @@
+package hls
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type discontinuity struct {
+	ntp time.Time
+
+	// segment that follows the discontinuity.
+	// It is filled when the segment appears in the playlist.
+	segmentURI string
+}
+
+// discontinuities marks points of a stream, like timestamp wraparounds
+// and changes of parameters, with EXT-X-DISCONTINUITY tags, in order to allow players to re-sync.
+// Each instance refers to the media playlist of a single muxer.
+type discontinuities struct {
+	mutex           sync.Mutex
+	list            []*discontinuity
+	removedSegments int
+}
+
+func (d *discontinuities) add(ntp time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.list = append(d.list, &discontinuity{ntp: ntp})
+}
+
+func (d *discontinuities) isEmpty() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return len(d.list) == 0 && d.removedSegments == 0
+}
+
+// rewrite adds EXT-X-DISCONTINUITY and EXT-X-DISCONTINUITY-SEQUENCE tags to a media playlist.
+// Since segmentation is performed independently from discontinuities,
+// each discontinuity is attached to the first segment that starts at or after it.
+func (d *discontinuities) rewrite(playlist []byte) []byte {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	lines := bytes.Split(playlist, []byte("\n"))
+
+	segments := parsePlaylistSegments(lines)
+	if len(segments) == 0 || segments[0].start == nil {
+		return playlist
+	}
+
+	segmentsByURI := make(map[string]*playlistSegment, len(segments))
+	for _, seg := range segments {
+		segmentsByURI[seg.uri] = seg
+	}
+
+	injected := make(map[int]struct{})
+	n := 0
+
+	for _, disc := range d.list {
+		if disc.segmentURI == "" {
+			// PROGRAM-DATE-TIME has millisecond precision.
+			ntp := disc.ntp.Truncate(time.Millisecond)
+
+			for _, seg := range segments {
+				if !seg.start.Before(ntp) {
+					disc.segmentURI = seg.uri
+					break
+				}
+			}
+		}
+
+		if disc.segmentURI != "" {
+			seg, ok := segmentsByURI[disc.segmentURI]
+			if !ok {
+				// segment has been removed from the playlist.
+				d.removedSegments++
+				continue
+			}
+			injected[seg.extinfLine] = struct{}{}
+		}
+
+		d.list[n] = disc
+		n++
+	}
+
+	for i := n; i < len(d.list); i++ {
+		d.list[i] = nil
+	}
+	d.list = d.list[:n]
+
+	var buf bytes.Buffer
+
+	for i, line := range lines {
+		if _, ok := injected[i]; ok {
+			buf.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		buf.Write(line)
+		if i != (len(lines) - 1) {
+			buf.WriteByte('\n')
+		}
+
+		if d.removedSegments != 0 && bytes.HasPrefix(line, []byte("#EXT-X-MEDIA-SEQUENCE:")) {
+			buf.WriteString("#EXT-X-DISCONTINUITY-SEQUENCE:" + strconv.Itoa(d.removedSegments) + "\n")
+		}
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,68 @@
+package hls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscontinuitiesRewrite(t *testing.T) {
+	d := discontinuities{
+		list: []*discontinuity{{
+			ntp: time.Date(2024, 1, 1, 10, 0, 3, 0, time.UTC),
+		}},
+	}
+
+	playlist := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:2\n" +
+		"#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:00Z\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg0.ts\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:02Z\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg1.ts\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:04Z\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg2.ts\n"
+
+	require.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-VERSION:3\n"+
+		"#EXT-X-TARGETDURATION:2\n"+
+		"#EXT-X-MEDIA-SEQUENCE:0\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:00Z\n"+
+		"#EXTINF:2.00000,\n"+
+		"seg0.ts\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:02Z\n"+
+		"#EXTINF:2.00000,\n"+
+		"seg1.ts\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:04Z\n"+
+		"#EXT-X-DISCONTINUITY\n"+
+		"#EXTINF:2.00000,\n"+
+		"seg2.ts\n", string(d.rewrite([]byte(playlist))))
+
+	playlist = "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:2\n" +
+		"#EXT-X-MEDIA-SEQUENCE:3\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:06Z\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg3.ts\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:08Z\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg4.ts\n"
+
+	require.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-VERSION:3\n"+
+		"#EXT-X-TARGETDURATION:2\n"+
+		"#EXT-X-MEDIA-SEQUENCE:3\n"+
+		"#EXT-X-DISCONTINUITY-SEQUENCE:1\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:06Z\n"+
+		"#EXTINF:2.00000,\n"+
+		"seg3.ts\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:08Z\n"+
+		"#EXTINF:2.00000,\n"+
+		"seg4.ts\n", string(d.rewrite([]byte(playlist))))
+}
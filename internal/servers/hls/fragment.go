@@ -0,0 +1,155 @@
+package hls
+
+import (
+	"time"
+
+	"github.com/bluenviron/gohlslib"
+	"github.com/bluenviron/gohlslib/pkg/codecs"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+)
+
+func durationMp4ToGo(v uint64, timeScale uint32) time.Duration {
+	timeScale64 := uint64(timeScale)
+	secs := v / timeScale64
+	dec := v % timeScale64
+	return time.Duration(secs)*time.Second + time.Duration(dec)*time.Second/time.Duration(timeScale64)
+}
+
+// muxerTimeScales returns the time scales of the tracks of a muxer, by track ID.
+func muxerTimeScales(hmuxer *gohlslib.Muxer) map[int]uint32 {
+	ret := make(map[int]uint32)
+	id := 1
+
+	if hmuxer.VideoTrack != nil {
+		ret[id] = 90000
+		id++
+	}
+
+	if hmuxer.AudioTrack != nil {
+		switch codec := hmuxer.AudioTrack.Codec.(type) {
+		case *codecs.MPEG4Audio:
+			ret[id] = uint32(codec.Config.SampleRate)
+
+		case *codecs.Opus:
+			ret[id] = 48000
+		}
+	}
+
+	return ret
+}
+
+// fragmentBoundaryReached checks whether a fragment has to be closed before a sample.
+// Fragments are never split in the middle of a sample, therefore they are closed
+// at the sample boundary that is nearest to the target duration.
+func fragmentBoundaryReached(
+	startDTS time.Duration,
+	fragmentDuration time.Duration,
+	sampleDTS time.Duration,
+	nextSampleDTS time.Duration,
+) bool {
+	target := startDTS + fragmentDuration
+	return (target - sampleDTS) <= (nextSampleDTS - target)
+}
+
+// splitPart splits a fMP4 part into fragments of the given duration.
+// Boundaries are computed on the first track, while samples of other tracks
+// are assigned to the fragment that contains their DTS.
+func splitPart(part *fmp4.Part, fragmentDuration time.Duration, timeScales map[int]uint32) []*fmp4.Part {
+	if len(part.Tracks) == 0 {
+		return []*fmp4.Part{part}
+	}
+
+	for _, track := range part.Tracks {
+		if timeScales[track.ID] == 0 {
+			return []*fmp4.Part{part}
+		}
+	}
+
+	ref := part.Tracks[0]
+	refTimeScale := timeScales[ref.ID]
+
+	var cuts []time.Duration
+	startDTS := durationMp4ToGo(ref.BaseTime, refTimeScale)
+	dts := ref.BaseTime
+
+	for i, sample := range ref.Samples {
+		sampleDTS := durationMp4ToGo(dts, refTimeScale)
+		dts += uint64(sample.Duration)
+
+		if i != 0 && fragmentBoundaryReached(startDTS, fragmentDuration,
+			sampleDTS, durationMp4ToGo(dts, refTimeScale)) {
+			cuts = append(cuts, sampleDTS)
+			startDTS = sampleDTS
+		}
+	}
+
+	if len(cuts) == 0 {
+		return []*fmp4.Part{part}
+	}
+
+	fragments := make([]*fmp4.Part, len(cuts)+1)
+	for i := range fragments {
+		fragments[i] = &fmp4.Part{
+			SequenceNumber: part.SequenceNumber,
+		}
+	}
+
+	for _, track := range part.Tracks {
+		timeScale := timeScales[track.ID]
+		dts := track.BaseTime
+		var cur *fmp4.PartTrack
+		curFragment := -1
+
+		for _, sample := range track.Samples {
+			i := 0
+			for i < len(cuts) && durationMp4ToGo(dts, timeScale) >= cuts[i] {
+				i++
+			}
+
+			if i != curFragment {
+				cur = &fmp4.PartTrack{
+					ID:       track.ID,
+					BaseTime: dts,
+				}
+				fragments[i].Tracks = append(fragments[i].Tracks, cur)
+				curFragment = i
+			}
+
+			cur.Samples = append(cur.Samples, sample)
+			dts += uint64(sample.Duration)
+		}
+	}
+
+	ret := make([]*fmp4.Part, 0, len(fragments))
+	for _, fragment := range fragments {
+		if len(fragment.Tracks) != 0 {
+			ret = append(ret, fragment)
+		}
+	}
+
+	return ret
+}
+
+// refragment splits the fragments (moof/mdat pairs) of a fMP4 segment
+// into fragments of the given duration, independently from keyframes.
+func refragment(byts []byte, fragmentDuration time.Duration, timeScales map[int]uint32) []byte {
+	var parts fmp4.Parts
+	err := parts.Unmarshal(byts)
+	if err != nil {
+		return byts
+	}
+
+	var fragments fmp4.Parts
+	for _, part := range parts {
+		fragments = append(fragments, splitPart(part, fragmentDuration, timeScales)...)
+	}
+
+	var buf seekablebuffer.Buffer
+	err = fragments.Marshal(&buf)
+	if err != nil {
+		return byts
+	}
+
+	return buf.Bytes()
+}
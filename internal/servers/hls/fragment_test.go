@@ -0,0 +1,104 @@
+package hls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefragment(t *testing.T) {
+	videoSamples := make([]*fmp4.PartSample, 9)
+	for i := range videoSamples {
+		videoSamples[i] = &fmp4.PartSample{
+			Duration:        3000,
+			IsNonSyncSample: (i != 0),
+			Payload:         []byte{byte(i)},
+		}
+	}
+
+	audioSamples := make([]*fmp4.PartSample, 6)
+	for i := range audioSamples {
+		audioSamples[i] = &fmp4.PartSample{
+			Duration: 2400,
+			Payload:  []byte{byte(i)},
+		}
+	}
+
+	parts := fmp4.Parts{{
+		SequenceNumber: 5,
+		Tracks: []*fmp4.PartTrack{
+			{
+				ID:       1,
+				BaseTime: 90000,
+				Samples:  videoSamples,
+			},
+			{
+				ID:       2,
+				BaseTime: 48000,
+				Samples:  audioSamples,
+			},
+		},
+	}}
+
+	var buf seekablebuffer.Buffer
+	err := parts.Marshal(&buf)
+	require.NoError(t, err)
+
+	// video samples last 33ms, audio samples last 50ms.
+	out := refragment(buf.Bytes(), 100*time.Millisecond, map[int]uint32{1: 90000, 2: 48000})
+
+	var dec fmp4.Parts
+	err = dec.Unmarshal(out)
+	require.NoError(t, err)
+
+	require.Equal(t, fmp4.Parts{
+		{
+			SequenceNumber: 5,
+			Tracks: []*fmp4.PartTrack{
+				{
+					ID:       1,
+					BaseTime: 90000,
+					Samples:  videoSamples[:3],
+				},
+				{
+					ID:       2,
+					BaseTime: 48000,
+					Samples:  audioSamples[:2],
+				},
+			},
+		},
+		{
+			SequenceNumber: 5,
+			Tracks: []*fmp4.PartTrack{
+				{
+					ID:       1,
+					BaseTime: 99000,
+					Samples:  videoSamples[3:6],
+				},
+				{
+					ID:       2,
+					BaseTime: 52800,
+					Samples:  audioSamples[2:4],
+				},
+			},
+		},
+		{
+			SequenceNumber: 5,
+			Tracks: []*fmp4.PartTrack{
+				{
+					ID:       1,
+					BaseTime: 108000,
+					Samples:  videoSamples[6:],
+				},
+				{
+					ID:       2,
+					BaseTime: 57600,
+					Samples:  audioSamples[4:],
+				},
+			},
+		},
+	}, dec)
+}
@@ -33,15 +33,18 @@ var hlsIndex []byte
 var hlsMinJS []byte
 
 type httpServer struct {
-	address        string
-	encryption     bool
-	serverKey      string
-	serverCert     string
-	allowOrigin    string
-	trustedProxies conf.IPsOrCIDRs
-	readTimeout    conf.StringDuration
-	pathManager    serverPathManager
-	parent         *Server
+	address            string
+	encryption         bool
+	serverKey          string
+	serverCert         string
+	tlsMinVersion      conf.TLSVersion
+	tlsCipherSuites    conf.TLSCipherSuites
+	allowOrigin        string
+	trustedProxies     conf.IPsOrCIDRs
+	readTimeout        conf.StringDuration
+	tcpKeepalivePeriod conf.StringDuration
+	pathManager        serverPathManager
+	parent             *Server
 
 	inner *httpp.WrappedServer
 }
@@ -68,8 +71,11 @@ func (s *httpServer) initialize() error {
 		network,
 		address,
 		time.Duration(s.readTimeout),
+		time.Duration(s.tcpKeepalivePeriod),
 		s.serverCert,
 		s.serverKey,
+		uint16(s.tlsMinVersion),
+		s.tlsCipherSuites,
 		router,
 		s,
 	)
@@ -183,6 +189,26 @@ func (s *httpServer) onRequest(ctx *gin.Context) {
 		return
 	}
 
+	if pathConf.HLSSignedURLSecret != "" && fname != "" {
+		err := checkSignedURL(pathConf.HLSSignedURLSecret, dir, ctx.Request.URL.Query())
+		if err != nil {
+			s.Log(logger.Info, "connection %v failed to validate signed URL: %v", httpp.RemoteAddr(ctx), err)
+			ctx.Writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	var opts playlistOptions
+
+	if strings.HasSuffix(fname, ".m3u8") {
+		err := opts.unmarshal(ctx.Request.URL.Query())
+		if err != nil {
+			s.Log(logger.Info, "connection %v sent invalid playlist options: %v", httpp.RemoteAddr(ctx), err)
+			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
 	switch fname {
 	case "":
 		ctx.Writer.Header().Set("Cache-Control", "max-age=3600")
@@ -203,11 +229,30 @@ func (s *httpServer) onRequest(ctx *gin.Context) {
 
 		mi := mux.getInstance()
 		if mi == nil {
+			if mux.unsupportedCodecs() {
+				ctx.Writer.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+
 			ctx.Writer.WriteHeader(http.StatusNotFound)
 			return
 		}
 
 		ctx.Request.URL.Path = fname
-		mi.handleRequest(ctx)
+		segmentBaseURL := pathSegmentBaseURL(pathConf.HLSSegmentBaseURL, dir)
+		fragmentDuration := time.Duration(pathConf.FMP4FragmentDuration)
+
+		if pathConf.HLSSignedURLSecret != "" && strings.HasSuffix(fname, ".m3u8") {
+			w := &signedPlaylistWriter{
+				ResponseWriter: ctx.Writer,
+				query:          signedURLQuery(ctx.Request.URL.Query()),
+			}
+			ctx.Writer = w
+			mi.handleRequest(ctx, opts, segmentBaseURL, fragmentDuration)
+			w.flush()
+			return
+		}
+
+		mi.handleRequest(ctx, opts, segmentBaseURL, fragmentDuration)
 	}
 }
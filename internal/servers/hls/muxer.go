@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
@@ -51,11 +52,16 @@ type muxer struct {
 	externalAuthenticationURL string
 	variant                   conf.HLSVariant
 	segmentCount              int
+	windowDuration            conf.StringDuration
+	windowMinSegments         int
 	segmentDuration           conf.StringDuration
 	partDuration              conf.StringDuration
 	segmentMaxSize            conf.StringSize
+	burstOnConnect            int
+	timestampWrap             conf.HLSTimestampWrap
 	directory                 string
 	writeQueueSize            int
+	readerQueueBudget         *asyncwriter.GrowthBudget
 	wg                        *sync.WaitGroup
 	pathName                  string
 	pathManager               serverPathManager
@@ -68,6 +74,9 @@ type muxer struct {
 	lastRequestTime *int64
 	bytesSent       *uint64
 
+	closeErrMutex sync.Mutex
+	closeErr      error
+
 	// in
 	chGetInstance chan muxerGetInstanceReq
 }
@@ -112,6 +121,10 @@ func (m *muxer) run() {
 
 	err := m.runInner()
 
+	m.closeErrMutex.Lock()
+	m.closeErr = err
+	m.closeErrMutex.Unlock()
+
 	m.ctxCancel()
 
 	m.parent.closeMuxer(m)
@@ -139,21 +152,28 @@ func (m *muxer) runInner() error {
 	var recreateTimer *time.Timer
 
 	mi := &muxerInstance{
-		variant:         m.variant,
-		segmentCount:    m.segmentCount,
-		segmentDuration: m.segmentDuration,
-		partDuration:    m.partDuration,
-		segmentMaxSize:  m.segmentMaxSize,
-		directory:       m.directory,
-		writeQueueSize:  m.writeQueueSize,
-		pathName:        m.pathName,
-		stream:          stream,
-		bytesSent:       m.bytesSent,
-		parent:          m,
+		variant:           m.variant,
+		segmentCount:      m.segmentCount,
+		windowDuration:    m.windowDuration,
+		windowMinSegments: m.windowMinSegments,
+		segmentDuration:   m.segmentDuration,
+		partDuration:      m.partDuration,
+		segmentMaxSize:    m.segmentMaxSize,
+		burstOnConnect:    m.burstOnConnect,
+		timestampWrap:     m.timestampWrap,
+		directory:         m.directory,
+		writeQueueSize:    m.writeQueueSize,
+		readerQueueBudget: m.readerQueueBudget,
+		pathConf:          m.path.SafeConf(),
+		audioLanguages:    m.path.SafeConf().HLSAudioLanguages,
+		pathName:          m.pathName,
+		stream:            stream,
+		bytesSent:         m.bytesSent,
+		parent:            m,
 	}
 	err = mi.initialize()
 	if err != nil {
-		if m.remoteAddr != "" || errors.Is(err, errNoSupportedCodecs) {
+		if m.remoteAddr != "" || isCodecError(err) {
 			return err
 		}
 
@@ -192,17 +212,24 @@ func (m *muxer) runInner() error {
 
 		case <-recreateTimer.C:
 			mi = &muxerInstance{
-				variant:         m.variant,
-				segmentCount:    m.segmentCount,
-				segmentDuration: m.segmentDuration,
-				partDuration:    m.partDuration,
-				segmentMaxSize:  m.segmentMaxSize,
-				directory:       m.directory,
-				writeQueueSize:  m.writeQueueSize,
-				pathName:        m.pathName,
-				stream:          stream,
-				bytesSent:       m.bytesSent,
-				parent:          m,
+				variant:           m.variant,
+				segmentCount:      m.segmentCount,
+				windowDuration:    m.windowDuration,
+				windowMinSegments: m.windowMinSegments,
+				segmentDuration:   m.segmentDuration,
+				partDuration:      m.partDuration,
+				segmentMaxSize:    m.segmentMaxSize,
+				burstOnConnect:    m.burstOnConnect,
+				timestampWrap:     m.timestampWrap,
+				directory:         m.directory,
+				writeQueueSize:    m.writeQueueSize,
+				readerQueueBudget: m.readerQueueBudget,
+				pathConf:          m.path.SafeConf(),
+				audioLanguages:    m.path.SafeConf().HLSAudioLanguages,
+				pathName:          m.pathName,
+				stream:            stream,
+				bytesSent:         m.bytesSent,
+				parent:            m,
 			}
 			err := mi.initialize()
 			if err != nil {
@@ -246,6 +273,14 @@ func (m *muxer) getInstance() *muxerInstance {
 	}
 }
 
+// unsupportedCodecs returns whether the muxer has been closed
+// since the stream codecs are not supported by the selected variant.
+func (m *muxer) unsupportedCodecs() bool {
+	m.closeErrMutex.Lock()
+	defer m.closeErrMutex.Unlock()
+	return isCodecError(m.closeErr)
+}
+
 // APIReaderDescribe implements reader.
 func (m *muxer) APIReaderDescribe() defs.APIPathSourceOrReader {
 	return defs.APIPathSourceOrReader{
@@ -1,14 +1,19 @@
 package hls
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bluenviron/gohlslib"
 	"github.com/bluenviron/gohlslib/pkg/codecs"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -19,28 +24,83 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// maximum number of SCTE-35 events retained by each muxer.
+const scte35MaxEvents = 64
+
 var errNoSupportedCodecs = errors.New(
 	"the stream doesn't contain any supported codec, which are currently H265, H264, Opus, MPEG-4 Audio")
 
+var errMPEGTSVideoCodec = errors.New(
+	"the MPEG-TS variant of HLS only supports H264 video. Use the fMP4 or Low-Latency variants instead")
+
+var errMPEGTSAudioCodec = errors.New(
+	"the MPEG-TS variant of HLS only supports MPEG-4 Audio. Use the fMP4 or Low-Latency variants instead")
+
+// isCodecError returns whether an error is caused by the codecs of the stream,
+// that are not supported by the selected variant.
+func isCodecError(err error) bool {
+	return errors.Is(err, errNoSupportedCodecs) ||
+		errors.Is(err, errMPEGTSVideoCodec) ||
+		errors.Is(err, errMPEGTSAudioCodec)
+}
+
+// checkVariantCodecs checks whether tracks can be muxed with given variant.
+// There's no fallback to other variants, in order to serve a single variant to all clients.
+func checkVariantCodecs(variant conf.HLSVariant, videoTrack *gohlslib.Track, audioTrack *gohlslib.Track) error {
+	if variant != conf.HLSVariant(gohlslib.MuxerVariantMPEGTS) {
+		return nil
+	}
+
+	if videoTrack != nil {
+		if _, ok := videoTrack.Codec.(*codecs.H264); !ok {
+			return errMPEGTSVideoCodec
+		}
+	}
+
+	if audioTrack != nil {
+		if _, ok := audioTrack.Codec.(*codecs.MPEG4Audio); !ok {
+			return errMPEGTSAudioCodec
+		}
+	}
+
+	return nil
+}
+
 type muxerInstance struct {
-	variant         conf.HLSVariant
-	segmentCount    int
-	segmentDuration conf.StringDuration
-	partDuration    conf.StringDuration
-	segmentMaxSize  conf.StringSize
-	directory       string
-	writeQueueSize  int
-	pathName        string
-	stream          *stream.Stream
-	bytesSent       *uint64
-	parent          logger.Writer
-
-	writer *asyncwriter.Writer
-	hmuxer *gohlslib.Muxer
+	variant           conf.HLSVariant
+	segmentCount      int
+	windowDuration    conf.StringDuration
+	windowMinSegments int
+	segmentDuration   conf.StringDuration
+	partDuration      conf.StringDuration
+	segmentMaxSize    conf.StringSize
+	burstOnConnect    int
+	timestampWrap     conf.HLSTimestampWrap
+	directory         string
+	writeQueueSize    int
+	readerQueueBudget *asyncwriter.GrowthBudget
+	pathConf          *conf.Path
+	audioLanguages    []string
+	pathName          string
+	stream            *stream.Stream
+	bytesSent         *uint64
+	parent            logger.Writer
+
+	writer          *asyncwriter.Writer
+	hmuxer          *gohlslib.Muxer
+	audioRenditions []*audioRendition
+
+	scte35Mutex  sync.Mutex
+	scte35Events []*scte35Event
+	scte35NextID int
+
+	ptsWraps        ptsWrapDetector
+	lastSPS         []byte
+	discontinuities discontinuities
 }
 
 func (mi *muxerInstance) initialize() error {
-	mi.writer = asyncwriter.New(mi.writeQueueSize, mi)
+	mi.writer = asyncwriter.NewReader(mi.pathConf, mi.writeQueueSize, mi.readerQueueBudget, mi)
 
 	videoTrack := mi.createVideoTrack()
 	audioTrack := mi.createAudioTrack()
@@ -50,6 +110,20 @@ func (mi *muxerInstance) initialize() error {
 		return errNoSupportedCodecs
 	}
 
+	err := checkVariantCodecs(mi.variant, videoTrack, audioTrack)
+	if err != nil {
+		mi.stream.RemoveReader(mi.writer)
+		return err
+	}
+
+	for _, r := range mi.audioRenditions {
+		err := checkVariantCodecs(mi.variant, nil, r.track)
+		if err != nil {
+			mi.stream.RemoveReader(mi.writer)
+			return err
+		}
+	}
+
 	var muxerDirectory string
 	if mi.directory != "" {
 		muxerDirectory = filepath.Join(mi.directory, mi.pathName)
@@ -68,12 +142,45 @@ func (mi *muxerInstance) initialize() error {
 		Directory:       muxerDirectory,
 	}
 
-	err := mi.hmuxer.Start()
+	err = mi.hmuxer.Start()
 	if err != nil {
 		mi.stream.RemoveReader(mi.writer)
 		return err
 	}
 
+	for i, r := range mi.separateAudioRenditions() {
+		var renditionVideoTrack *gohlslib.Track
+		if videoTrack != nil {
+			renditionVideoTrack = cloneVideoTrack(videoTrack)
+		}
+
+		r.hmuxer = &gohlslib.Muxer{
+			Variant:         gohlslib.MuxerVariant(mi.variant),
+			SegmentCount:    mi.segmentCount,
+			SegmentDuration: time.Duration(mi.segmentDuration),
+			PartDuration:    time.Duration(mi.partDuration),
+			SegmentMaxSize:  uint64(mi.segmentMaxSize),
+			VideoTrack:      renditionVideoTrack,
+			AudioTrack:      r.track,
+			Directory:       muxerDirectory,
+		}
+
+		err = r.hmuxer.Start()
+		if err != nil {
+			for _, r2 := range mi.separateAudioRenditions()[:i] {
+				r2.hmuxer.Close()
+			}
+			mi.hmuxer.Close()
+			mi.stream.RemoveReader(mi.writer)
+			return err
+		}
+	}
+
+	mi.stream.AddSCTE35Reader(mi.writer, func(u unit.Unit) error {
+		mi.addSCTE35(u.(*unit.SCTE35))
+		return nil
+	})
+
 	mi.Log(logger.Info, "is converting into HLS, %s",
 		defs.FormatsInfo(mi.stream.FormatsForReader(mi.writer)))
 
@@ -90,6 +197,9 @@ func (mi *muxerInstance) Log(level logger.Level, format string, args ...interfac
 func (mi *muxerInstance) close() {
 	mi.writer.Stop()
 	mi.hmuxer.Close()
+	for _, r := range mi.separateAudioRenditions() {
+		r.hmuxer.Close()
+	}
 	mi.stream.RemoveReader(mi.writer)
 }
 
@@ -105,7 +215,9 @@ func (mi *muxerInstance) createVideoTrack() *gohlslib.Track {
 				return nil
 			}
 
-			err := mi.hmuxer.WriteAV1(tunit.NTP, tunit.PTS, tunit.TU)
+			err := mi.writeVideo(func(hmuxer *gohlslib.Muxer) error {
+				return hmuxer.WriteAV1(tunit.NTP, tunit.PTS, tunit.TU)
+			})
 			if err != nil {
 				return fmt.Errorf("muxer error: %w", err)
 			}
@@ -129,7 +241,9 @@ func (mi *muxerInstance) createVideoTrack() *gohlslib.Track {
 				return nil
 			}
 
-			err := mi.hmuxer.WriteVP9(tunit.NTP, tunit.PTS, tunit.Frame)
+			err := mi.writeVideo(func(hmuxer *gohlslib.Muxer) error {
+				return hmuxer.WriteVP9(tunit.NTP, tunit.PTS, tunit.Frame)
+			})
 			if err != nil {
 				return fmt.Errorf("muxer error: %w", err)
 			}
@@ -153,11 +267,18 @@ func (mi *muxerInstance) createVideoTrack() *gohlslib.Track {
 				return nil
 			}
 
-			err := mi.hmuxer.WriteH26x(tunit.NTP, tunit.PTS, tunit.AU)
+			_, sps, _ := videoFormatH265.SafeParams()
+			mi.processSPS(sps, tunit.NTP)
+
+			err := mi.writeVideo(func(hmuxer *gohlslib.Muxer) error {
+				return hmuxer.WriteH26x(tunit.NTP, tunit.PTS, tunit.AU)
+			})
 			if err != nil {
 				return fmt.Errorf("muxer error: %w", err)
 			}
 
+			mi.processPTS(tunit.PTS, tunit.NTP)
+
 			return nil
 		})
 
@@ -183,11 +304,18 @@ func (mi *muxerInstance) createVideoTrack() *gohlslib.Track {
 				return nil
 			}
 
-			err := mi.hmuxer.WriteH26x(tunit.NTP, tunit.PTS, tunit.AU)
+			sps, _ := videoFormatH264.SafeParams()
+			mi.processSPS(sps, tunit.NTP)
+
+			err := mi.writeVideo(func(hmuxer *gohlslib.Muxer) error {
+				return hmuxer.WriteH26x(tunit.NTP, tunit.PTS, tunit.AU)
+			})
 			if err != nil {
 				return fmt.Errorf("muxer error: %w", err)
 			}
 
+			mi.processPTS(tunit.PTS, tunit.NTP)
+
 			return nil
 		})
 
@@ -204,15 +332,141 @@ func (mi *muxerInstance) createVideoTrack() *gohlslib.Track {
 	return nil
 }
 
+// processSPS marks a change of the SPS with a discontinuity,
+// when onSourceFormatChange is "renegotiate".
+func (mi *muxerInstance) processSPS(sps []byte, ntp time.Time) {
+	if mi.pathConf.OnSourceFormatChange != conf.OnSourceFormatChangeRenegotiate || sps == nil {
+		return
+	}
+
+	if mi.lastSPS != nil && !bytes.Equal(sps, mi.lastSPS) {
+		mi.discontinuities.add(ntp)
+		for _, r := range mi.separateAudioRenditions() {
+			r.discontinuities.add(ntp)
+		}
+	}
+
+	mi.lastSPS = sps
+}
+
+// processPTS marks wraparounds of MPEG-TS timestamps with discontinuities,
+// when hlsTimestampWrap is "discontinuity".
+func (mi *muxerInstance) processPTS(pts time.Duration, ntp time.Time) {
+	if mi.variant != conf.HLSVariant(gohlslib.MuxerVariantMPEGTS) ||
+		mi.timestampWrap != conf.HLSTimestampWrapDiscontinuity {
+		return
+	}
+
+	if wrapNTP, ok := mi.ptsWraps.process(pts, ntp); ok {
+		mi.discontinuities.add(wrapNTP)
+	}
+}
+
+// writeVideo writes video to the main muxer and to muxers of audio renditions.
+func (mi *muxerInstance) writeVideo(write func(*gohlslib.Muxer) error) error {
+	err := write(mi.hmuxer)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range mi.separateAudioRenditions() {
+		err = write(r.hmuxer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (mi *muxerInstance) createAudioTrack() *gohlslib.Track {
+	if len(mi.audioLanguages) != 0 {
+		return mi.createAudioRenditions()
+	}
+
 	var audioFormatOpus *format.Opus
 	audioMedia := mi.stream.Desc().FindFormat(&audioFormatOpus)
 
 	if audioMedia != nil {
-		mi.stream.AddReader(mi.writer, audioMedia, audioFormatOpus, func(u unit.Unit) error {
+		return mi.addAudioReader(audioMedia, audioFormatOpus, func() *gohlslib.Muxer {
+			return mi.hmuxer
+		})
+	}
+
+	var audioFormatMPEG4Audio *format.MPEG4Audio
+	audioMedia = mi.stream.Desc().FindFormat(&audioFormatMPEG4Audio)
+
+	if audioMedia != nil {
+		return mi.addAudioReader(audioMedia, audioFormatMPEG4Audio, func() *gohlslib.Muxer {
+			return mi.hmuxer
+		})
+	}
+
+	return nil
+}
+
+// createAudioRenditions routes each audio track to a rendition, in the order in which
+// tracks appear in the stream, and returns the track that is muxed with video.
+func (mi *muxerInstance) createAudioRenditions() *gohlslib.Track {
+	for _, media := range mi.stream.Desc().Medias {
+		if len(mi.audioRenditions) == len(mi.audioLanguages) {
+			break
+		}
+
+		if media.Type != description.MediaTypeAudio {
+			continue
+		}
+
+		for _, forma := range media.Formats {
+			r := &audioRendition{
+				language: mi.audioLanguages[len(mi.audioRenditions)],
+			}
+
+			var hmuxer func() *gohlslib.Muxer
+			if len(mi.audioRenditions) == 0 {
+				hmuxer = func() *gohlslib.Muxer {
+					return mi.hmuxer
+				}
+			} else {
+				hmuxer = func() *gohlslib.Muxer {
+					return r.hmuxer
+				}
+			}
+
+			r.track = mi.addAudioReader(media, forma, hmuxer)
+			if r.track != nil {
+				mi.audioRenditions = append(mi.audioRenditions, r)
+				break
+			}
+		}
+	}
+
+	if len(mi.audioRenditions) == 0 {
+		return nil
+	}
+
+	return mi.audioRenditions[0].track
+}
+
+// separateAudioRenditions returns renditions that are muxed by dedicated muxers.
+func (mi *muxerInstance) separateAudioRenditions() []*audioRendition {
+	if len(mi.audioRenditions) == 0 {
+		return nil
+	}
+	return mi.audioRenditions[1:]
+}
+
+func (mi *muxerInstance) addAudioReader(
+	media *description.Media,
+	forma format.Format,
+	hmuxer func() *gohlslib.Muxer,
+) *gohlslib.Track {
+	switch forma := forma.(type) {
+	case *format.Opus:
+		mi.stream.AddReader(mi.writer, media, forma, func(u unit.Unit) error {
 			tunit := u.(*unit.Opus)
 
-			err := mi.hmuxer.WriteOpus(
+			err := hmuxer().WriteOpus(
 				tunit.NTP,
 				tunit.PTS,
 				tunit.Packets)
@@ -220,33 +474,31 @@ func (mi *muxerInstance) createAudioTrack() *gohlslib.Track {
 				return fmt.Errorf("muxer error: %w", err)
 			}
 
+			mi.processPTS(tunit.PTS, tunit.NTP)
+
 			return nil
 		})
 
 		return &gohlslib.Track{
 			Codec: &codecs.Opus{
 				ChannelCount: func() int {
-					if audioFormatOpus.IsStereo {
+					if forma.IsStereo {
 						return 2
 					}
 					return 1
 				}(),
 			},
 		}
-	}
-
-	var audioFormatMPEG4Audio *format.MPEG4Audio
-	audioMedia = mi.stream.Desc().FindFormat(&audioFormatMPEG4Audio)
 
-	if audioMedia != nil {
-		mi.stream.AddReader(mi.writer, audioMedia, audioFormatMPEG4Audio, func(u unit.Unit) error {
+	case *format.MPEG4Audio:
+		mi.stream.AddReader(mi.writer, media, forma, func(u unit.Unit) error {
 			tunit := u.(*unit.MPEG4Audio)
 
 			if tunit.AUs == nil {
 				return nil
 			}
 
-			err := mi.hmuxer.WriteMPEG4Audio(
+			err := hmuxer().WriteMPEG4Audio(
 				tunit.NTP,
 				tunit.PTS,
 				tunit.AUs)
@@ -254,12 +506,14 @@ func (mi *muxerInstance) createAudioTrack() *gohlslib.Track {
 				return fmt.Errorf("muxer error: %w", err)
 			}
 
+			mi.processPTS(tunit.PTS, tunit.NTP)
+
 			return nil
 		})
 
 		return &gohlslib.Track{
 			Codec: &codecs.MPEG4Audio{
-				Config: *audioFormatMPEG4Audio.GetConfig(),
+				Config: *forma.GetConfig(),
 			},
 		}
 	}
@@ -267,15 +521,170 @@ func (mi *muxerInstance) createAudioTrack() *gohlslib.Track {
 	return nil
 }
 
+func (mi *muxerInstance) addSCTE35(u *unit.SCTE35) {
+	var e scte35Event
+	err := e.info.Unmarshal(u.Section)
+	if err != nil {
+		mi.Log(logger.Warn, "invalid SCTE-35 section: %v", err)
+		return
+	}
+
+	mi.scte35Mutex.Lock()
+	defer mi.scte35Mutex.Unlock()
+
+	e.id = mi.scte35NextID
+	e.ntp = u.NTP
+	e.section = u.Section
+	mi.scte35NextID++
+
+	// remove events that can't be part of the playlist anymore.
+	window := 2 * time.Duration(mi.segmentCount+1) * time.Duration(mi.segmentDuration)
+	n := 0
+	for _, ev := range mi.scte35Events {
+		if time.Since(ev.ntp) < window {
+			mi.scte35Events[n] = ev
+			n++
+		}
+	}
+	mi.scte35Events = mi.scte35Events[:n]
+
+	if len(mi.scte35Events) >= scte35MaxEvents {
+		mi.scte35Events = mi.scte35Events[1:]
+	}
+
+	mi.scte35Events = append(mi.scte35Events, &e)
+}
+
+func (mi *muxerInstance) currentSCTE35Events() []*scte35Event {
+	mi.scte35Mutex.Lock()
+	defer mi.scte35Mutex.Unlock()
+
+	return append([]*scte35Event(nil), mi.scte35Events...)
+}
+
 func (mi *muxerInstance) errorChan() chan error {
 	return mi.writer.Error()
 }
 
-func (mi *muxerInstance) handleRequest(ctx *gin.Context) {
+func (mi *muxerInstance) handleRequest(
+	ctx *gin.Context,
+	opts playlistOptions,
+	segmentBaseURL string,
+	fragmentDuration time.Duration,
+) {
 	w := &responseWriterWithCounter{
 		ResponseWriter: ctx.Writer,
 		bytesSent:      mi.bytesSent,
 	}
 
-	mi.hmuxer.Handle(w, ctx.Request)
+	hmuxer := mi.hmuxer
+	discs := &mi.discontinuities
+	renditionPrefix := ""
+
+	if i, fname, ok := parseAudioRenditionFile(ctx.Request.URL.Path); ok {
+		if i == 0 || i >= len(mi.audioRenditions) || fname == multivariantPlaylistName {
+			ctx.Writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		hmuxer = mi.audioRenditions[i].hmuxer
+		discs = &mi.audioRenditions[i].discontinuities
+		renditionPrefix = audioRenditionPrefix(i)
+		ctx.Request.URL.Path = fname
+	}
+
+	var rewrites []func([]byte) []byte
+
+	switch ctx.Request.URL.Path {
+	case multivariantPlaylistName:
+		if len(mi.audioRenditions) != 0 {
+			query := ""
+			if !opts.isDefault() {
+				query = opts.query()
+			}
+			rewrites = append(rewrites, func(byts []byte) []byte {
+				return applyAudioRenditions(byts, mi.audioRenditions, query)
+			})
+		}
+
+		if !opts.isDefault() {
+			rewrites = append(rewrites, opts.applyToMultivariant)
+		}
+
+	case mediaPlaylistName:
+		if mi.windowDuration > 0 {
+			removeSkipQuery(ctx.Request.URL)
+			rewrites = append(rewrites, func(byts []byte) []byte {
+				return applyWindow(byts, time.Duration(mi.windowDuration), mi.windowMinSegments)
+			})
+		}
+
+		if !opts.isDefault() {
+			if opts.disableLowLatency {
+				removeLowLatencyQuery(ctx.Request.URL)
+			}
+			rewrites = append(rewrites, opts.applyToMedia)
+		}
+
+		if mi.burstOnConnect != 0 && mi.variant == conf.HLSVariant(gohlslib.MuxerVariantLowLatency) &&
+			!opts.disableLowLatency && opts.startSegments == 0 {
+			rewrites = append(rewrites, func(byts []byte) []byte {
+				return applyBurstOnConnect(byts, mi.burstOnConnect)
+			})
+		}
+
+		if !discs.isEmpty() {
+			rewrites = append(rewrites, discs.rewrite)
+		}
+
+		if renditionPrefix != "" {
+			rewrites = append(rewrites, func(byts []byte) []byte {
+				return rewriteSegmentURIs(byts, func(uri string) string {
+					return renditionPrefix + uri
+				})
+			})
+		}
+
+		events := mi.currentSCTE35Events()
+		if len(events) != 0 && renditionPrefix == "" {
+			rewrites = append(rewrites, func(byts []byte) []byte {
+				return injectSCTE35(byts, events)
+			})
+		}
+
+		if segmentBaseURL != "" {
+			rewrites = append(rewrites, func(byts []byte) []byte {
+				return applySegmentBaseURL(byts, segmentBaseURL)
+			})
+		}
+	}
+
+	if fragmentDuration != 0 && mi.variant != conf.HLSVariant(gohlslib.MuxerVariantMPEGTS) &&
+		strings.Contains(ctx.Request.URL.Path, "_seg") && strings.HasSuffix(ctx.Request.URL.Path, ".mp4") {
+		timeScales := muxerTimeScales(hmuxer)
+		rewrites = append(rewrites, func(byts []byte) []byte {
+			return refragment(byts, fragmentDuration, timeScales)
+		})
+	}
+
+	if renditionPrefix != "" && hmuxer.VideoTrack != nil && strings.HasSuffix(ctx.Request.URL.Path, ".mp4") {
+		rewrites = append(rewrites, stripVideoTrack)
+	}
+
+	if len(rewrites) != 0 {
+		pw := &playlistWriter{
+			ResponseWriter: w,
+			rewrite: func(byts []byte) []byte {
+				for _, rewrite := range rewrites {
+					byts = rewrite(byts)
+				}
+				return byts
+			},
+		}
+		hmuxer.Handle(pw, ctx.Request)
+		pw.flush()
+		return
+	}
+
+	hmuxer.Handle(w, ctx.Request)
 }
@@ -0,0 +1,35 @@
+package hls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMuxerInstanceProcessSPS(t *testing.T) {
+	for _, ca := range []string{"renegotiate", "ignore"} {
+		t.Run(ca, func(t *testing.T) {
+			mi := &muxerInstance{
+				pathConf: &conf.Path{},
+			}
+
+			if ca == "renegotiate" {
+				mi.pathConf.OnSourceFormatChange = conf.OnSourceFormatChangeRenegotiate
+			}
+
+			ntp := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+			mi.processSPS([]byte{1, 2}, ntp)
+			mi.processSPS([]byte{1, 2}, ntp.Add(time.Second))
+			mi.processSPS([]byte{3, 4}, ntp.Add(2*time.Second))
+
+			if ca == "renegotiate" {
+				require.Equal(t, []*discontinuity{{ntp: ntp.Add(2 * time.Second)}}, mi.discontinuities.list)
+			} else {
+				require.Equal(t, true, mi.discontinuities.isEmpty())
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package hls
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/playlist"
+)
+
+// playlistOptions allow each reader to tune playlists through query parameters,
+// in order to choose between low latency and compatibility.
+type playlistOptions struct {
+	// remove Low-Latency HLS features (parts, blocking reloads) from the media playlist.
+	disableLowLatency bool
+
+	// number of segments between the starting point and the end of the playlist.
+	// Zero means the default starting point chosen by the player.
+	startSegments int
+}
+
+func parseBoolQuery(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "1", "yes", "true":
+		return true, nil
+
+	case "0", "no", "false":
+		return false, nil
+
+	default:
+		return false, fmt.Errorf("invalid boolean value '%s'", v)
+	}
+}
+
+func (o *playlistOptions) unmarshal(query url.Values) error {
+	if v := query.Get("lowLatency"); v != "" {
+		lowLatency, err := parseBoolQuery(v)
+		if err != nil {
+			return fmt.Errorf("invalid 'lowLatency' parameter: %w", err)
+		}
+		o.disableLowLatency = !lowLatency
+	}
+
+	if v := query.Get("startSegments"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 31)
+		if err != nil || n == 0 {
+			return fmt.Errorf("invalid 'startSegments' parameter: it must be a positive integer")
+		}
+		o.startSegments = int(n)
+	}
+
+	return nil
+}
+
+func (o playlistOptions) isDefault() bool {
+	return !o.disableLowLatency && o.startSegments == 0
+}
+
+// query returns the options in a form that can be appended to URIs,
+// in order to apply them to the media playlist too.
+func (o playlistOptions) query() string {
+	v := url.Values{}
+	if o.disableLowLatency {
+		v.Set("lowLatency", "no")
+	}
+	if o.startSegments != 0 {
+		v.Set("startSegments", strconv.FormatInt(int64(o.startSegments), 10))
+	}
+	return v.Encode()
+}
+
+func (o playlistOptions) applyToMultivariant(byts []byte) []byte {
+	var pl playlist.Multivariant
+	err := pl.Unmarshal(byts)
+	if err != nil {
+		return byts
+	}
+
+	q := o.query()
+	for _, v := range pl.Variants {
+		v.URI = appendQuery(v.URI, q)
+	}
+
+	out, err := pl.Marshal()
+	if err != nil {
+		return byts
+	}
+	return out
+}
+
+func (o playlistOptions) applyToMedia(byts []byte) []byte {
+	var pl playlist.Media
+	err := pl.Unmarshal(byts)
+	if err != nil {
+		return byts
+	}
+
+	if o.disableLowLatency {
+		pl.ServerControl = nil
+		pl.PartInf = nil
+		pl.Parts = nil
+		pl.PreloadHint = nil
+		for _, seg := range pl.Segments {
+			seg.Parts = nil
+		}
+	}
+
+	out, err := pl.Marshal()
+	if err != nil {
+		return byts
+	}
+
+	if o.startSegments != 0 {
+		out = insertStartTag(out, -time.Duration(o.startSegments*pl.TargetDuration)*time.Second, false)
+	}
+
+	return out
+}
+
+// removeLowLatencyQuery removes Low-Latency HLS directives from a request,
+// in order to always obtain a full playlist without blocking.
+func removeLowLatencyQuery(u *url.URL) {
+	q := u.Query()
+	for key := range q {
+		if strings.HasPrefix(key, "_HLS_") {
+			q.Del(key)
+		}
+	}
+	u.RawQuery = q.Encode()
+}
@@ -0,0 +1,42 @@
+package hls
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// names of the playlists generated by gohlslib.
+const (
+	multivariantPlaylistName = "index.m3u8"
+	mediaPlaylistName        = "stream.m3u8"
+)
+
+// playlistWriter buffers a playlist in order to rewrite it.
+type playlistWriter struct {
+	http.ResponseWriter
+	rewrite    func([]byte) []byte
+	statusCode int
+	buf        bytes.Buffer
+}
+
+func (w *playlistWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+}
+
+func (w *playlistWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *playlistWriter) flush() {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+
+	byts := w.buf.Bytes()
+	if w.statusCode == http.StatusOK {
+		byts = w.rewrite(byts)
+	}
+
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	w.ResponseWriter.Write(byts) //nolint:errcheck
+}
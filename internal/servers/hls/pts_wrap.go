@@ -0,0 +1,42 @@
+package hls
+
+import (
+	"time"
+)
+
+// MPEG-TS timestamps are 33-bit integers with a 90khz clock,
+// therefore they wrap around every ~26.5 hours.
+const mpegtsWrapPeriod = (1 << 33) * time.Second / 90000
+
+// ptsWrapDetector detects wraparounds of MPEG-TS timestamps.
+type ptsWrapDetector struct {
+	initialized bool
+	period      int64
+}
+
+func ptsWrapPeriod(pts time.Duration) int64 {
+	p := int64(pts / mpegtsWrapPeriod)
+	if pts < 0 && (pts%mpegtsWrapPeriod) != 0 {
+		p--
+	}
+	return p
+}
+
+// process returns the wall clock time of a wraparound, if it happened before the given timestamp.
+func (d *ptsWrapDetector) process(pts time.Duration, ntp time.Time) (time.Time, bool) {
+	period := ptsWrapPeriod(pts)
+
+	if !d.initialized {
+		d.initialized = true
+		d.period = period
+		return time.Time{}, false
+	}
+
+	// timestamps of different tracks, or of B-frames, are not monotonic.
+	if period <= d.period {
+		return time.Time{}, false
+	}
+
+	d.period = period
+	return ntp.Add(time.Duration(period)*mpegtsWrapPeriod - pts), true
+}
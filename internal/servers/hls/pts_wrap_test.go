@@ -0,0 +1,32 @@
+package hls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPTSWrapDetectorProcess(t *testing.T) {
+	var d ptsWrapDetector
+
+	ntp := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// normal operation
+	for i := 0; i < 10; i++ {
+		_, ok := d.process(mpegtsWrapPeriod-10*time.Second+time.Duration(i)*time.Second,
+			ntp.Add(time.Duration(i)*time.Second))
+		require.Equal(t, false, ok)
+	}
+
+	// wraparound
+	wrapNTP, ok := d.process(mpegtsWrapPeriod+500*time.Millisecond, ntp.Add(10500*time.Millisecond))
+	require.Equal(t, true, ok)
+	require.Equal(t, ntp.Add(10*time.Second), wrapNTP)
+
+	// non-monotonic timestamps
+	_, ok = d.process(mpegtsWrapPeriod-100*time.Millisecond, ntp.Add(10600*time.Millisecond))
+	require.Equal(t, false, ok)
+	_, ok = d.process(mpegtsWrapPeriod+time.Second, ntp.Add(11*time.Second))
+	require.Equal(t, false, ok)
+}
@@ -0,0 +1,168 @@
+package hls
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+)
+
+const timeRFC3339Millis = "2006-01-02T15:04:05.999Z07:00"
+
+type scte35Event struct {
+	id      int
+	ntp     time.Time
+	section []byte
+	info    mpegts.SCTE35SpliceInfo
+}
+
+func (e *scte35Event) tags(startDate time.Time) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(`#EXT-X-DATERANGE:ID="scte35-` + strconv.Itoa(e.id) + `",` +
+		`START-DATE="` + startDate.UTC().Format(timeRFC3339Millis) + `"`)
+
+	if e.info.Duration > 0 {
+		buf.WriteString(",PLANNED-DURATION=" + strconv.FormatFloat(e.info.Duration.Seconds(), 'f', 3, 64))
+	}
+
+	var attr string
+	switch {
+	case e.info.CommandType == mpegts.SCTE35CommandSpliceInsert && !e.info.Cancel && e.info.OutOfNetwork:
+		attr = "SCTE35-OUT"
+	case e.info.CommandType == mpegts.SCTE35CommandSpliceInsert && !e.info.Cancel:
+		attr = "SCTE35-IN"
+	default:
+		attr = "SCTE35-CMD"
+	}
+
+	buf.WriteString("," + attr + "=0x" + strings.ToUpper(hex.EncodeToString(e.section)) + "\n")
+	buf.WriteString("#EXT-OATCLS-SCTE35:" + base64.StdEncoding.EncodeToString(e.section) + "\n")
+
+	return buf.Bytes()
+}
+
+type playlistSegment struct {
+	extinfLine int
+	uri        string
+	duration   time.Duration
+	start      *time.Time
+}
+
+func parsePlaylistSegments(lines [][]byte) []*playlistSegment {
+	var segments []*playlistSegment
+	var pdt *time.Time
+	var cur *playlistSegment
+
+	for i, line := range lines {
+		line = bytes.TrimSpace(line)
+
+		switch {
+		case len(line) == 0:
+
+		case bytes.HasPrefix(line, []byte("#EXT-X-PROGRAM-DATE-TIME:")):
+			t, err := time.Parse(time.RFC3339Nano, string(line[len("#EXT-X-PROGRAM-DATE-TIME:"):]))
+			if err == nil {
+				pdt = &t
+			}
+
+		case bytes.HasPrefix(line, []byte("#EXTINF:")):
+			v := string(line[len("#EXTINF:"):])
+			if j := strings.IndexByte(v, ','); j >= 0 {
+				v = v[:j]
+			}
+			d, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+
+			cur = &playlistSegment{
+				extinfLine: i,
+				duration:   time.Duration(d * float64(time.Second)),
+				start:      pdt,
+			}
+
+		case line[0] == '#':
+
+		default:
+			if cur != nil {
+				cur.uri = string(line)
+				segments = append(segments, cur)
+				cur = nil
+			}
+			pdt = nil
+		}
+	}
+
+	// fill start dates of segments without a EXT-X-PROGRAM-DATE-TIME tag.
+	for i := 1; i < len(segments); i++ {
+		if segments[i].start == nil && segments[i-1].start != nil {
+			t := segments[i-1].start.Add(segments[i-1].duration)
+			segments[i].start = &t
+		}
+	}
+	for i := len(segments) - 2; i >= 0; i-- {
+		if segments[i].start == nil && segments[i+1].start != nil {
+			t := segments[i+1].start.Add(-segments[i].duration)
+			segments[i].start = &t
+		}
+	}
+
+	return segments
+}
+
+// injectSCTE35 adds SCTE-35 tags to a media playlist.
+// Since segmentation is performed independently from splice points,
+// each event is attached to the first segment that starts at or after the splice time.
+func injectSCTE35(playlist []byte, events []*scte35Event) []byte {
+	if len(events) == 0 {
+		return playlist
+	}
+
+	lines := bytes.Split(playlist, []byte("\n"))
+
+	segments := parsePlaylistSegments(lines)
+	if len(segments) == 0 || segments[0].start == nil {
+		return playlist
+	}
+
+	injected := make(map[int][]byte)
+
+	for _, e := range events {
+		// PROGRAM-DATE-TIME has millisecond precision.
+		ntp := e.ntp.Truncate(time.Millisecond)
+
+		if ntp.Before(*segments[0].start) {
+			continue
+		}
+
+		for _, seg := range segments {
+			if !seg.start.Before(ntp) {
+				injected[seg.extinfLine] = append(injected[seg.extinfLine], e.tags(*seg.start)...)
+				break
+			}
+		}
+	}
+
+	if len(injected) == 0 {
+		return playlist
+	}
+
+	var buf bytes.Buffer
+
+	for i, line := range lines {
+		if tags, ok := injected[i]; ok {
+			buf.Write(tags)
+		}
+		buf.Write(line)
+		if i != (len(lines) - 1) {
+			buf.WriteByte('\n')
+		}
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,81 @@
+package hls
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+)
+
+func TestInjectSCTE35(t *testing.T) {
+	// splice_insert, out of network, with a break duration.
+	section, err := base64.StdEncoding.DecodeString(
+		"/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo=")
+	require.NoError(t, err)
+
+	var info mpegts.SCTE35SpliceInfo
+	err = info.Unmarshal(section)
+	require.NoError(t, err)
+
+	playlist := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:2\n" +
+		"#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:00Z\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg0.ts\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:02Z\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg1.ts\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:04Z\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg2.ts\n"
+
+	events := []*scte35Event{
+		{
+			// before the playlist window
+			id:      1,
+			ntp:     time.Date(2024, 1, 1, 9, 59, 59, 0, time.UTC),
+			section: section,
+			info:    info,
+		},
+		{
+			// in the middle of the first segment
+			id:      2,
+			ntp:     time.Date(2024, 1, 1, 10, 0, 1, 0, time.UTC),
+			section: section,
+			info:    info,
+		},
+		{
+			// after the last segment
+			id:      3,
+			ntp:     time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC),
+			section: section,
+			info:    info,
+		},
+	}
+
+	out := injectSCTE35([]byte(playlist), events)
+
+	require.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-VERSION:3\n"+
+		"#EXT-X-TARGETDURATION:2\n"+
+		"#EXT-X-MEDIA-SEQUENCE:0\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:00Z\n"+
+		"#EXTINF:2.00000,\n"+
+		"seg0.ts\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:02Z\n"+
+		`#EXT-X-DATERANGE:ID="scte35-2",START-DATE="2024-01-01T10:00:02Z",PLANNED-DURATION=60.294,`+
+		"SCTE35-OUT=0x"+strings.ToUpper(hex.EncodeToString(section))+"\n"+
+		"#EXT-OATCLS-SCTE35:/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo=\n"+
+		"#EXTINF:2.00000,\n"+
+		"seg1.ts\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2024-01-01T10:00:04Z\n"+
+		"#EXTINF:2.00000,\n"+
+		"seg2.ts\n", string(out))
+}
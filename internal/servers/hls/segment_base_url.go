@@ -0,0 +1,66 @@
+package hls
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// tags of the media playlist whose URI attribute points to a segment or part.
+// EXT-X-RENDITION-REPORT is excluded since it points to a playlist.
+var reSegmentURITag = regexp.MustCompile(`^#EXT-X-(MAP|PART|PRELOAD-HINT):`)
+
+// pathSegmentBaseURL returns the segment base URL of a path.
+// $MTX_PATH is replaced with the path name; when it is not present,
+// the path name is appended, in order to prevent paths from sharing segment URLs.
+func pathSegmentBaseURL(baseURL string, pathName string) string {
+	if baseURL == "" {
+		return ""
+	}
+
+	if strings.Contains(baseURL, "$MTX_PATH") {
+		return strings.ReplaceAll(baseURL, "$MTX_PATH", pathName)
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + pathName
+}
+
+func joinSegmentBaseURL(baseURL string, uri string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + uri
+}
+
+// rewriteSegmentURIs rewrites the URIs of segments, parts and
+// initialization segments of a media playlist.
+func rewriteSegmentURIs(playlist []byte, rewrite func(string) string) []byte {
+	lines := bytes.Split(playlist, []byte("\n"))
+
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+
+		switch {
+		case len(trimmed) == 0:
+
+		case trimmed[0] == '#':
+			if reSegmentURITag.Match(trimmed) {
+				lines[i] = reURIAttribute.ReplaceAllFunc(line, func(attr []byte) []byte {
+					uri := string(reURIAttribute.FindSubmatch(attr)[1])
+					return []byte(`URI="` + rewrite(uri) + `"`)
+				})
+			}
+
+		default:
+			lines[i] = []byte(rewrite(string(trimmed)))
+		}
+	}
+
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// applySegmentBaseURL prepends a base URL to the URIs of segments, parts and
+// initialization segments of a media playlist, in order to allow them
+// to be served by a different host.
+func applySegmentBaseURL(playlist []byte, baseURL string) []byte {
+	return rewriteSegmentURIs(playlist, func(uri string) string {
+		return joinSegmentBaseURL(baseURL, uri)
+	})
+}
@@ -0,0 +1,71 @@
+package hls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathSegmentBaseURL(t *testing.T) {
+	for _, ca := range []struct {
+		name    string
+		baseURL string
+		out     string
+	}{
+		{
+			"empty",
+			"",
+			"",
+		},
+		{
+			"append",
+			"https://cdn.example.com/live/",
+			"https://cdn.example.com/live/my/path",
+		},
+		{
+			"variable",
+			"https://cdn.example.com/$MTX_PATH/hls",
+			"https://cdn.example.com/my/path/hls",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			require.Equal(t, ca.out, pathSegmentBaseURL(ca.baseURL, "my/path"))
+		})
+	}
+}
+
+func TestApplySegmentBaseURL(t *testing.T) {
+	pl := "#EXTM3U\n" +
+		"#EXT-X-VERSION:9\n" +
+		"#EXT-X-TARGETDURATION:2\n" +
+		"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=0.6\n" +
+		"#EXT-X-PART-INF:PART-TARGET=0.2\n" +
+		"#EXT-X-MEDIA-SEQUENCE:1\n" +
+		"#EXT-X-MAP:URI=\"init.mp4\"\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg1.mp4\n" +
+		"#EXT-X-PART:DURATION=0.20000,URI=\"part5.mp4\",INDEPENDENT=YES\n" +
+		"#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"part6.mp4\"\n" +
+		"#EXT-X-RENDITION-REPORT:URI=\"audio.m3u8\",LAST-MSN=2,LAST-PART=0\n"
+
+	for _, ca := range []string{
+		"https://cdn.example.com/live/mypath",
+		"https://cdn.example.com/live/mypath/",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			require.Equal(t, "#EXTM3U\n"+
+				"#EXT-X-VERSION:9\n"+
+				"#EXT-X-TARGETDURATION:2\n"+
+				"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=0.6\n"+
+				"#EXT-X-PART-INF:PART-TARGET=0.2\n"+
+				"#EXT-X-MEDIA-SEQUENCE:1\n"+
+				"#EXT-X-MAP:URI=\"https://cdn.example.com/live/mypath/init.mp4\"\n"+
+				"#EXTINF:2.00000,\n"+
+				"https://cdn.example.com/live/mypath/seg1.mp4\n"+
+				"#EXT-X-PART:DURATION=0.20000,URI=\"https://cdn.example.com/live/mypath/part5.mp4\",INDEPENDENT=YES\n"+
+				"#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"https://cdn.example.com/live/mypath/part6.mp4\"\n"+
+				"#EXT-X-RENDITION-REPORT:URI=\"audio.m3u8\",LAST-MSN=2,LAST-PART=0\n",
+				string(applySegmentBaseURL([]byte(pl), ca)))
+		})
+	}
+}
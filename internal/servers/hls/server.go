@@ -8,6 +8,7 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
@@ -57,24 +58,52 @@ type serverParent interface {
 	logger.Writer
 }
 
+// windowSegmentCount returns the number of segments to store.
+// Since each segment lasts at least segmentDuration,
+// storing this number of segments guarantees a window of at least windowDuration.
+// Segments that are not needed to cover the window are removed from playlists.
+// When the window is shorter than segmentCount segments, segmentCount is used.
+func windowSegmentCount(
+	segmentCount int,
+	segmentDuration conf.StringDuration,
+	windowDuration conf.StringDuration,
+) int {
+	if windowDuration <= 0 || segmentDuration <= 0 {
+		return segmentCount
+	}
+
+	n := int((windowDuration + segmentDuration - 1) / segmentDuration)
+	if n > segmentCount {
+		return n
+	}
+	return segmentCount
+}
+
 // Server is a HLS server.
 type Server struct {
 	Address                   string
 	Encryption                bool
 	ServerKey                 string
 	ServerCert                string
+	TLSMinVersion             conf.TLSVersion
+	TLSCipherSuites           conf.TLSCipherSuites
 	ExternalAuthenticationURL string
 	AlwaysRemux               bool
 	Variant                   conf.HLSVariant
 	SegmentCount              int
+	WindowDuration            conf.StringDuration
 	SegmentDuration           conf.StringDuration
 	PartDuration              conf.StringDuration
 	SegmentMaxSize            conf.StringSize
+	BurstOnConnect            int
+	TimestampWrap             conf.HLSTimestampWrap
 	AllowOrigin               string
 	TrustedProxies            conf.IPsOrCIDRs
 	Directory                 string
 	ReadTimeout               conf.StringDuration
+	TCPKeepalivePeriod        conf.StringDuration
 	WriteQueueSize            int
+	ReaderQueueBudget         *asyncwriter.GrowthBudget
 	PathManager               serverPathManager
 	Parent                    serverParent
 
@@ -108,15 +137,18 @@ func (s *Server) Initialize() error {
 	s.chAPIMuxerGet = make(chan serverAPIMuxersGetReq)
 
 	s.httpServer = &httpServer{
-		address:        s.Address,
-		encryption:     s.Encryption,
-		serverKey:      s.ServerKey,
-		serverCert:     s.ServerCert,
-		allowOrigin:    s.AllowOrigin,
-		trustedProxies: s.TrustedProxies,
-		readTimeout:    s.ReadTimeout,
-		pathManager:    s.PathManager,
-		parent:         s,
+		address:            s.Address,
+		encryption:         s.Encryption,
+		serverKey:          s.ServerKey,
+		serverCert:         s.ServerCert,
+		tlsMinVersion:      s.TLSMinVersion,
+		tlsCipherSuites:    s.TLSCipherSuites,
+		allowOrigin:        s.AllowOrigin,
+		trustedProxies:     s.TrustedProxies,
+		readTimeout:        s.ReadTimeout,
+		tcpKeepalivePeriod: s.TCPKeepalivePeriod,
+		pathManager:        s.PathManager,
+		parent:             s,
 	}
 	err := s.httpServer.initialize()
 	if err != nil {
@@ -222,12 +254,17 @@ func (s *Server) createMuxer(pathName string, remoteAddr string) *muxer {
 		remoteAddr:                remoteAddr,
 		externalAuthenticationURL: s.ExternalAuthenticationURL,
 		variant:                   s.Variant,
-		segmentCount:              s.SegmentCount,
+		segmentCount:              windowSegmentCount(s.SegmentCount, s.SegmentDuration, s.WindowDuration),
+		windowDuration:            s.WindowDuration,
+		windowMinSegments:         s.SegmentCount,
 		segmentDuration:           s.SegmentDuration,
 		partDuration:              s.PartDuration,
 		segmentMaxSize:            s.SegmentMaxSize,
+		burstOnConnect:            s.BurstOnConnect,
+		timestampWrap:             s.TimestampWrap,
 		directory:                 s.Directory,
 		writeQueueSize:            s.WriteQueueSize,
+		readerQueueBudget:         s.ReaderQueueBudget,
 		wg:                        &s.wg,
 		pathName:                  pathName,
 		pathManager:               s.PathManager,
@@ -1,16 +1,21 @@
 package hls
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/bluenviron/gohlslib"
 	"github.com/bluenviron/gohlslib/pkg/codecs"
+	"github.com/bluenviron/gohlslib/pkg/playlist"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
@@ -20,13 +25,18 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-type dummyPath struct{}
+type dummyPath struct {
+	pathConf *conf.Path
+}
 
 func (pa *dummyPath) Name() string {
 	return "mystream"
 }
 
 func (pa *dummyPath) SafeConf() *conf.Path {
+	if pa.pathConf != nil {
+		return pa.pathConf
+	}
 	return &conf.Path{}
 }
 
@@ -48,10 +58,14 @@ func (pa *dummyPath) RemoveReader(_ defs.PathRemoveReaderReq) {
 }
 
 type dummyPathManager struct {
-	stream *stream.Stream
+	pathConf *conf.Path
+	stream   *stream.Stream
 }
 
 func (pm *dummyPathManager) FindPathConf(_ defs.PathFindPathConfReq) (*conf.Path, error) {
+	if pm.pathConf != nil {
+		return pm.pathConf, nil
+	}
 	return &conf.Path{}, nil
 }
 
@@ -59,7 +73,25 @@ func (pm *dummyPathManager) AddReader(req defs.PathAddReaderReq) (defs.Path, *st
 	if req.AccessRequest.Name == "nonexisting" {
 		return nil, nil, fmt.Errorf("not found")
 	}
-	return &dummyPath{}, pm.stream, nil
+	return &dummyPath{pathConf: pm.pathConf}, pm.stream, nil
+}
+
+func TestWindowSegmentCount(t *testing.T) {
+	for _, ca := range []struct {
+		name     string
+		window   time.Duration
+		expected int
+	}{
+		{"disabled", 0, 7},
+		{"shorter than segment count", 5 * time.Second, 7},
+		{"exact", 2 * time.Hour, 3600},
+		{"rounded up", 2*time.Hour + time.Second, 3601},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			require.Equal(t, ca.expected, windowSegmentCount(7,
+				conf.StringDuration(2*time.Second), conf.StringDuration(ca.window)))
+		})
+	}
 }
 
 func TestServerNotFound(t *testing.T) {
@@ -117,6 +149,77 @@ func TestServerNotFound(t *testing.T) {
 	}
 }
 
+func TestServerUnsupportedCodecs(t *testing.T) {
+	for _, ca := range []string{
+		"mpegts",
+		"lowLatency",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			var desc *description.Session
+			var variant conf.HLSVariant
+
+			if ca == "mpegts" {
+				desc = &description.Session{Medias: []*description.Media{{
+					Type: description.MediaTypeVideo,
+					Formats: []format.Format{&format.H265{
+						PayloadTyp: 96,
+					}},
+				}}}
+				variant = conf.HLSVariant(gohlslib.MuxerVariantMPEGTS)
+			} else {
+				desc = &description.Session{Medias: []*description.Media{{
+					Type:    description.MediaTypeVideo,
+					Formats: []format.Format{&format.MJPEG{}},
+				}}}
+				variant = conf.HLSVariant(gohlslib.MuxerVariantLowLatency)
+			}
+
+			stream, err := stream.New(
+				1460,
+				desc,
+				true,
+				0,
+				test.NilLogger{},
+			)
+			require.NoError(t, err)
+
+			s := &Server{
+				Address:                   "127.0.0.1:8888",
+				Encryption:                false,
+				ServerKey:                 "",
+				ServerCert:                "",
+				ExternalAuthenticationURL: "",
+				AlwaysRemux:               false,
+				Variant:                   variant,
+				SegmentCount:              7,
+				SegmentDuration:           conf.StringDuration(1 * time.Second),
+				PartDuration:              conf.StringDuration(200 * time.Millisecond),
+				SegmentMaxSize:            50 * 1024 * 1024,
+				AllowOrigin:               "",
+				TrustedProxies:            conf.IPsOrCIDRs{},
+				Directory:                 "",
+				ReadTimeout:               conf.StringDuration(10 * time.Second),
+				WriteQueueSize:            512,
+				PathManager:               &dummyPathManager{stream: stream},
+				Parent:                    &test.NilLogger{},
+			}
+			err = s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			hc := &http.Client{Transport: &http.Transport{}}
+
+			req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8888/mystream/index.m3u8", nil)
+			require.NoError(t, err)
+
+			res, err := hc.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+		})
+	}
+}
+
 func TestServerRead(t *testing.T) {
 	t.Run("always remux off", func(t *testing.T) {
 		testMediaH264 := &description.Media{
@@ -130,6 +233,7 @@ func TestServerRead(t *testing.T) {
 			1460,
 			desc,
 			true,
+			0,
 			test.NilLogger{},
 		)
 		require.NoError(t, err)
@@ -221,6 +325,7 @@ func TestServerRead(t *testing.T) {
 			1460,
 			desc,
 			true,
+			0,
 			test.NilLogger{},
 		)
 		require.NoError(t, err)
@@ -301,3 +406,427 @@ func TestServerRead(t *testing.T) {
 		<-recv
 	})
 }
+
+func TestServerReadSignedURL(t *testing.T) {
+	testMediaH264 := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{test.FormatH264},
+	}
+
+	desc := &description.Session{Medias: []*description.Media{testMediaH264}}
+
+	stream, err := stream.New(
+		1460,
+		desc,
+		true,
+		0,
+		test.NilLogger{},
+	)
+	require.NoError(t, err)
+
+	pathManager := &dummyPathManager{
+		pathConf: &conf.Path{HLSSignedURLSecret: "mysecret"},
+		stream:   stream,
+	}
+
+	s := &Server{
+		Address:                   "127.0.0.1:8888",
+		Encryption:                false,
+		ServerKey:                 "",
+		ServerCert:                "",
+		ExternalAuthenticationURL: "",
+		AlwaysRemux:               false,
+		Variant:                   conf.HLSVariant(gohlslib.MuxerVariantMPEGTS),
+		SegmentCount:              7,
+		SegmentDuration:           conf.StringDuration(1 * time.Second),
+		PartDuration:              conf.StringDuration(200 * time.Millisecond),
+		SegmentMaxSize:            50 * 1024 * 1024,
+		AllowOrigin:               "",
+		TrustedProxies:            conf.IPsOrCIDRs{},
+		Directory:                 "",
+		ReadTimeout:               conf.StringDuration(10 * time.Second),
+		WriteQueueSize:            512,
+		PathManager:               pathManager,
+		Parent:                    &test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	expires := time.Now().Add(time.Minute)
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	for _, ca := range []string{
+		"missing token",
+		"other path",
+		"expired",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			var query string
+
+			switch ca {
+			case "other path":
+				query = "?token=" + SignedURLToken("mysecret", "otherstream", expires) +
+					"&expires=" + strconv.FormatInt(expires.Unix(), 10)
+
+			case "expired":
+				exp := time.Now().Add(-time.Minute)
+				query = "?token=" + SignedURLToken("mysecret", "mystream", exp) +
+					"&expires=" + strconv.FormatInt(exp.Unix(), 10)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8888/mystream/index.m3u8"+query, nil)
+			require.NoError(t, err)
+
+			res, err := hc.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusForbidden, res.StatusCode)
+		})
+	}
+
+	c := &gohlslib.Client{
+		URI: "http://127.0.0.1:8888/mystream/index.m3u8?token=" +
+			SignedURLToken("mysecret", "mystream", expires) +
+			"&expires=" + strconv.FormatInt(expires.Unix(), 10),
+	}
+
+	recv := make(chan struct{})
+
+	c.OnTracks = func(tracks []*gohlslib.Track) error {
+		c.OnDataH26x(tracks[0], func(_, _ time.Duration, _ [][]byte) {
+			close(recv)
+		})
+		return nil
+	}
+
+	err = c.Start()
+	require.NoError(t, err)
+	defer func() { <-c.Wait() }()
+	defer c.Close()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		for i := 0; i < 4; i++ {
+			stream.WriteUnit(testMediaH264, test.FormatH264, &unit.H264{
+				Base: unit.Base{
+					NTP: time.Time{},
+					PTS: time.Duration(i) * time.Second,
+				},
+				AU: [][]byte{
+					{5, 1}, // IDR
+				},
+			})
+		}
+	}()
+
+	<-recv
+}
+
+func TestServerReadPlaylistOptions(t *testing.T) {
+	testMediaH264 := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{test.FormatH264},
+	}
+
+	desc := &description.Session{Medias: []*description.Media{testMediaH264}}
+
+	stream, err := stream.New(
+		1460,
+		desc,
+		true,
+		0,
+		test.NilLogger{},
+	)
+	require.NoError(t, err)
+
+	pathManager := &dummyPathManager{stream: stream}
+
+	s := &Server{
+		Address:                   "127.0.0.1:8888",
+		Encryption:                false,
+		ServerKey:                 "",
+		ServerCert:                "",
+		ExternalAuthenticationURL: "",
+		AlwaysRemux:               true,
+		Variant:                   conf.HLSVariant(gohlslib.MuxerVariantLowLatency),
+		SegmentCount:              7,
+		SegmentDuration:           conf.StringDuration(1 * time.Second),
+		PartDuration:              conf.StringDuration(200 * time.Millisecond),
+		SegmentMaxSize:            50 * 1024 * 1024,
+		AllowOrigin:               "",
+		TrustedProxies:            conf.IPsOrCIDRs{},
+		Directory:                 "",
+		ReadTimeout:               conf.StringDuration(10 * time.Second),
+		WriteQueueSize:            512,
+		PathManager:               pathManager,
+		Parent:                    &test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.PathReady(&dummyPath{})
+
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		stream.WriteUnit(testMediaH264, test.FormatH264, &unit.H264{
+			Base: unit.Base{
+				NTP: time.Now(),
+				PTS: time.Duration(i) * time.Second,
+			},
+			AU: [][]byte{
+				{5, 1}, // IDR
+			},
+		})
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	get := func(u string) (int, string) {
+		res, err := hc.Get(u)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		byts, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res.StatusCode, string(byts)
+	}
+
+	code, _ := get("http://127.0.0.1:8888/mystream/index.m3u8?startSegments=abc")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, body := get("http://127.0.0.1:8888/mystream/index.m3u8?lowLatency=no&startSegments=3")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "\nstream.m3u8?lowLatency=no&startSegments=3\n")
+
+	code, body = get("http://127.0.0.1:8888/mystream/stream.m3u8")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "#EXT-X-PART:")
+	require.NotContains(t, body, "#EXT-X-START")
+
+	code, body = get("http://127.0.0.1:8888/mystream/stream.m3u8?lowLatency=no&startSegments=3")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "#EXT-X-START:TIME-OFFSET=-3.00000\n")
+	require.Contains(t, body, "#EXTINF:")
+	for _, tag := range []string{
+		"#EXT-X-SERVER-CONTROL",
+		"#EXT-X-PART-INF",
+		"#EXT-X-PART:",
+		"#EXT-X-PRELOAD-HINT",
+	} {
+		require.NotContains(t, body, tag)
+	}
+
+	// Low-Latency HLS features are preserved when only the starting point is changed.
+	code, body = get("http://127.0.0.1:8888/mystream/stream.m3u8?startSegments=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "#EXT-X-START:TIME-OFFSET=-2.00000\n")
+	require.Contains(t, body, "#EXT-X-PART:")
+	require.Contains(t, body, "#EXT-X-PRELOAD-HINT")
+
+	var pl playlist.Media
+	err = pl.Unmarshal([]byte(body))
+	require.NoError(t, err)
+
+	// blocking requests are answered immediately.
+	code, body = get("http://127.0.0.1:8888/mystream/stream.m3u8?lowLatency=no&_HLS_msn=1000")
+	require.Equal(t, http.StatusOK, code)
+	require.NotContains(t, body, "#EXT-X-PART:")
+}
+
+func TestServerReadAudioRenditions(t *testing.T) {
+	testMediaH264 := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{test.FormatH264},
+	}
+
+	var testMediasAudio []*description.Media
+
+	for i := 0; i < 2; i++ {
+		testMediasAudio = append(testMediasAudio, &description.Media{
+			Type: description.MediaTypeAudio,
+			Formats: []format.Format{&format.MPEG4Audio{
+				PayloadTyp:       96,
+				Config:           test.FormatMPEG4Audio.Config,
+				SizeLength:       13,
+				IndexLength:      3,
+				IndexDeltaLength: 3,
+			}},
+		})
+	}
+
+	desc := &description.Session{Medias: []*description.Media{
+		testMediaH264,
+		testMediasAudio[0],
+		testMediasAudio[1],
+	}}
+
+	stream, err := stream.New(
+		1460,
+		desc,
+		true,
+		0,
+		test.NilLogger{},
+	)
+	require.NoError(t, err)
+
+	pathManager := &dummyPathManager{
+		pathConf: &conf.Path{HLSAudioLanguages: []string{"en", "it"}},
+		stream:   stream,
+	}
+
+	s := &Server{
+		Address:                   "127.0.0.1:8888",
+		Encryption:                false,
+		ServerKey:                 "",
+		ServerCert:                "",
+		ExternalAuthenticationURL: "",
+		AlwaysRemux:               true,
+		Variant:                   conf.HLSVariant(gohlslib.MuxerVariantFMP4),
+		SegmentCount:              7,
+		SegmentDuration:           conf.StringDuration(1 * time.Second),
+		PartDuration:              conf.StringDuration(200 * time.Millisecond),
+		SegmentMaxSize:            50 * 1024 * 1024,
+		AllowOrigin:               "",
+		TrustedProxies:            conf.IPsOrCIDRs{},
+		Directory:                 "",
+		ReadTimeout:               conf.StringDuration(10 * time.Second),
+		WriteQueueSize:            512,
+		PathManager:               pathManager,
+		Parent:                    &test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.PathReady(&dummyPath{})
+
+	time.Sleep(100 * time.Millisecond)
+
+	// keyframes are 1.5s apart, while segmentDuration is 1s.
+	for i := 0; i < 5; i++ {
+		stream.WriteUnit(testMediaH264, test.FormatH264, &unit.H264{
+			Base: unit.Base{
+				NTP: time.Now(),
+				PTS: time.Duration(i) * 1500 * time.Millisecond,
+			},
+			AU: [][]byte{
+				{5, 1}, // IDR
+			},
+		})
+
+		for _, media := range testMediasAudio {
+			aus := make([][]byte, 64)
+			for j := range aus {
+				aus[j] = []byte{1, 2, 3, 4}
+			}
+
+			stream.WriteUnit(media, media.Formats[0], &unit.MPEG4Audio{
+				Base: unit.Base{
+					NTP: time.Now(),
+					PTS: time.Duration(i) * 1500 * time.Millisecond,
+				},
+				AUs: aus,
+			})
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	get := func(u string) (int, string) {
+		res, err := hc.Get(u)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		byts, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res.StatusCode, string(byts)
+	}
+
+	code, body := get("http://127.0.0.1:8888/mystream/index.m3u8")
+	require.Equal(t, http.StatusOK, code)
+
+	var mpl playlist.Multivariant
+	err = mpl.Unmarshal([]byte(body))
+	require.NoError(t, err)
+
+	require.Equal(t, "audio", mpl.Variants[0].Audio)
+	require.Equal(t, []*playlist.MultivariantRendition{
+		{
+			Type:       playlist.MultivariantRenditionTypeAudio,
+			GroupID:    "audio",
+			Name:       "en",
+			Language:   "en",
+			Default:    true,
+			Autoselect: true,
+		},
+		{
+			Type:       playlist.MultivariantRenditionTypeAudio,
+			GroupID:    "audio",
+			Name:       "it",
+			Language:   "it",
+			Autoselect: true,
+			URI:        "audio1_stream.m3u8",
+		},
+	}, mpl.Renditions)
+
+	code, body = get("http://127.0.0.1:8888/mystream/stream.m3u8")
+	require.Equal(t, http.StatusOK, code)
+
+	var mainPl playlist.Media
+	err = mainPl.Unmarshal([]byte(body))
+	require.NoError(t, err)
+
+	code, body = get("http://127.0.0.1:8888/mystream/audio1_stream.m3u8")
+	require.Equal(t, http.StatusOK, code)
+
+	var pl playlist.Media
+	err = pl.Unmarshal([]byte(body))
+	require.NoError(t, err)
+	require.NotEmpty(t, pl.Segments)
+	require.Regexp(t, "^audio1_.+_init.mp4$", pl.Map.URI)
+	require.Regexp(t, "^audio1_", pl.Segments[0].URI)
+
+	// segments are cut on the same keyframes of the main stream.
+	require.Equal(t, len(mainPl.Segments), len(pl.Segments))
+	for i, seg := range pl.Segments {
+		require.Equal(t, 1500*time.Millisecond, seg.Duration)
+		require.Equal(t, mainPl.Segments[i].Duration, seg.Duration)
+	}
+
+	code, body = get("http://127.0.0.1:8888/mystream/" + pl.Map.URI)
+	require.Equal(t, http.StatusOK, code)
+
+	var init fmp4.Init
+	err = init.Unmarshal(bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(init.Tracks))
+	require.Equal(t, 1, init.Tracks[0].ID)
+	require.IsType(t, &fmp4.CodecMPEG4Audio{}, init.Tracks[0].Codec)
+
+	// the first segment doesn't contain audio, since audio received before its creation is discarded.
+	code, body = get("http://127.0.0.1:8888/mystream/" + pl.Segments[1].URI)
+	require.Equal(t, http.StatusOK, code)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal([]byte(body))
+	require.NoError(t, err)
+	require.NotEmpty(t, parts)
+	for _, part := range parts {
+		require.Equal(t, 1, len(part.Tracks))
+		require.Equal(t, 1, part.Tracks[0].ID)
+		require.Equal(t, []byte{1, 2, 3, 4}, part.Tracks[0].Samples[0].Payload)
+	}
+
+	code, _ = get("http://127.0.0.1:8888/mystream/audio2_stream.m3u8")
+	require.Equal(t, http.StatusNotFound, code)
+}
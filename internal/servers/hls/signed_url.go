@@ -0,0 +1,125 @@
+package hls
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var reURIAttribute = regexp.MustCompile(`URI="([^"]*)"`)
+
+// SignedURLToken generates a token that allows to read a path
+// until the expiration time, when signed URLs are enabled.
+func SignedURLToken(secret string, pathName string, expires time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(pathName + "\n" + strconv.FormatInt(expires.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func checkSignedURL(secret string, pathName string, query url.Values) error {
+	expiresUnix, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid or missing 'expires' parameter")
+	}
+
+	expires := time.Unix(expiresUnix, 0)
+
+	token, err := hex.DecodeString(query.Get("token"))
+	if err != nil || len(token) == 0 {
+		return fmt.Errorf("invalid or missing 'token' parameter")
+	}
+
+	expected, _ := hex.DecodeString(SignedURLToken(secret, pathName, expires))
+	if !hmac.Equal(token, expected) {
+		return fmt.Errorf("invalid token")
+	}
+
+	if time.Now().After(expires) {
+		return fmt.Errorf("token is expired")
+	}
+
+	return nil
+}
+
+func signedURLQuery(query url.Values) string {
+	return url.Values{
+		"token":   []string{query.Get("token")},
+		"expires": []string{query.Get("expires")},
+	}.Encode()
+}
+
+func appendQuery(uri string, query string) string {
+	if strings.Contains(uri, "?") {
+		return uri + "&" + query
+	}
+	return uri + "?" + query
+}
+
+// signPlaylist appends the token and the expiration time to the URIs of a playlist,
+// in order to allow players to download the referenced files.
+func signPlaylist(playlist []byte, query string) []byte {
+	lines := bytes.Split(playlist, []byte("\n"))
+
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+
+		switch {
+		case len(trimmed) == 0:
+
+		case trimmed[0] == '#':
+			lines[i] = reURIAttribute.ReplaceAllFunc(line, func(attr []byte) []byte {
+				uri := string(reURIAttribute.FindSubmatch(attr)[1])
+				return []byte(`URI="` + appendQuery(uri, query) + `"`)
+			})
+
+		default:
+			lines[i] = []byte(appendQuery(string(trimmed), query))
+		}
+	}
+
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// signedPlaylistWriter buffers a playlist in order to sign its URIs.
+type signedPlaylistWriter struct {
+	gin.ResponseWriter
+	query      string
+	statusCode int
+	buf        bytes.Buffer
+}
+
+func (w *signedPlaylistWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+}
+
+func (w *signedPlaylistWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *signedPlaylistWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+func (w *signedPlaylistWriter) flush() {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+
+	byts := w.buf.Bytes()
+	if w.statusCode == http.StatusOK {
+		byts = signPlaylist(byts, w.query)
+	}
+
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	w.ResponseWriter.Write(byts) //nolint:errcheck
+}
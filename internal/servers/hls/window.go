@@ -0,0 +1,54 @@
+package hls
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/playlist"
+)
+
+// applyWindow removes the oldest segments from a media playlist, keeping the most recent
+// segments that cover windowDuration, and at least minSegments segments.
+// The muxer stores enough segments to cover the window in the worst case, when all
+// segments last segmentDuration, while segments are usually longer since they
+// are cut on keyframes; therefore the window is enforced here.
+// Playlist delta updates are disabled since skipped segments can't be counted.
+func applyWindow(byts []byte, windowDuration time.Duration, minSegments int) []byte {
+	var pl playlist.Media
+	err := pl.Unmarshal(byts)
+	if err != nil {
+		return byts
+	}
+
+	if pl.ServerControl != nil {
+		pl.ServerControl.CanSkipUntil = nil
+	}
+
+	keep := 0
+	var duration time.Duration
+
+	for i := len(pl.Segments) - 1; i >= 0; i-- {
+		keep++
+		duration += pl.Segments[i].Duration
+		if duration >= windowDuration && keep >= minSegments {
+			break
+		}
+	}
+
+	removed := len(pl.Segments) - keep
+	pl.Segments = pl.Segments[removed:]
+	pl.MediaSequence += removed
+
+	out, err := pl.Marshal()
+	if err != nil {
+		return byts
+	}
+	return out
+}
+
+// removeSkipQuery removes the delta update directive from a request.
+func removeSkipQuery(u *url.URL) {
+	q := u.Query()
+	q.Del("_HLS_skip")
+	u.RawQuery = q.Encode()
+}
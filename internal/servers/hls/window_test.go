@@ -0,0 +1,79 @@
+package hls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gohlslib/pkg/playlist"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyWindow(t *testing.T) {
+	pl := "#EXTM3U\n" +
+		"#EXT-X-VERSION:9\n" +
+		"#EXT-X-TARGETDURATION:6\n" +
+		"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,CAN-SKIP-UNTIL=36,PART-HOLD-BACK=0.6\n" +
+		"#EXT-X-PART-INF:PART-TARGET=0.2\n" +
+		"#EXT-X-MEDIA-SEQUENCE:10\n" +
+		"#EXT-X-MAP:URI=\"init.mp4\"\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg10.mp4\n" +
+		"#EXTINF:6.00000,\n" +
+		"seg11.mp4\n" +
+		"#EXTINF:2.00000,\n" +
+		"seg12.mp4\n" +
+		"#EXTINF:4.00000,\n" +
+		"seg13.mp4\n"
+
+	for _, ca := range []struct {
+		name        string
+		window      time.Duration
+		minSegments int
+		sequence    int
+		segments    []string
+	}{
+		{
+			"duration",
+			7 * time.Second,
+			1,
+			11,
+			[]string{"seg11.mp4", "seg12.mp4", "seg13.mp4"},
+		},
+		{
+			"exact duration",
+			6 * time.Second,
+			1,
+			12,
+			[]string{"seg12.mp4", "seg13.mp4"},
+		},
+		{
+			"segment count is larger",
+			6 * time.Second,
+			3,
+			11,
+			[]string{"seg11.mp4", "seg12.mp4", "seg13.mp4"},
+		},
+		{
+			"longer than content",
+			time.Hour,
+			1,
+			10,
+			[]string{"seg10.mp4", "seg11.mp4", "seg12.mp4", "seg13.mp4"},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var out playlist.Media
+			err := out.Unmarshal(applyWindow([]byte(pl), ca.window, ca.minSegments))
+			require.NoError(t, err)
+
+			require.Equal(t, ca.sequence, out.MediaSequence)
+			require.Nil(t, out.ServerControl.CanSkipUntil)
+
+			segments := make([]string, len(out.Segments))
+			for i, seg := range out.Segments {
+				segments[i] = seg.URI
+			}
+			require.Equal(t, ca.segments, segments)
+		})
+	}
+}
@@ -8,13 +8,11 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
-	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
-	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg1audio"
-	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
 	"github.com/google/uuid"
 
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
@@ -32,8 +30,7 @@ const (
 	pauseAfterAuthError = 2 * time.Second
 )
 
-var errNoSupportedCodecs = errors.New(
-	"the stream doesn't contain any supported codec, which are currently H264, MPEG-4 Audio, MPEG-1/2 Audio")
+var errHandshakeTimeout = errors.New("handshake timed out")
 
 func pathNameAndQuery(inURL *url.URL) (string, url.Values, string) {
 	// remove leading and trailing slashes inserted by OBS and some other clients
@@ -43,6 +40,19 @@ func pathNameAndQuery(inURL *url.URL) (string, url.Values, string) {
 	return pathName, ur.Query(), ur.RawQuery
 }
 
+// publishPathFromTemplate derives the path of a publisher from its user.
+func publishPathFromTemplate(template string, user string) (string, error) {
+	if user == "" {
+		return "", defs.AuthenticationError{Message: "a user is required in order to publish"}
+	}
+
+	if strings.ContainsAny(user, "/{}") {
+		return "", defs.AuthenticationError{Message: fmt.Sprintf("invalid user '%s'", user)}
+	}
+
+	return strings.ReplaceAll(template, "{user}", user), nil
+}
+
 type connState int
 
 const (
@@ -57,9 +67,12 @@ type conn struct {
 	readTimeout         conf.StringDuration
 	writeTimeout        conf.StringDuration
 	writeQueueSize      int
+	readerQueueBudget   *asyncwriter.GrowthBudget
 	runOnConnect        string
 	runOnConnectRestart bool
 	runOnDisconnect     string
+	publishPathTemplate string
+	handshakeTimeout    conf.StringDuration
 	wg                  *sync.WaitGroup
 	nconn               net.Conn
 	externalCmdPool     *externalcmd.Pool
@@ -75,6 +88,9 @@ type conn struct {
 	state     connState
 	pathName  string
 	query     string
+
+	handshakeTimer    *time.Timer
+	handshakeTimedOut atomic.Bool
 }
 
 func (c *conn) initialize() {
@@ -85,10 +101,29 @@ func (c *conn) initialize() {
 
 	c.Log(logger.Info, "opened")
 
+	c.startHandshakeTimer()
+
 	c.wg.Add(1)
 	go c.run()
 }
 
+// startHandshakeTimer closes the connection if the handshake is not completed in time.
+func (c *conn) startHandshakeTimer() {
+	if c.handshakeTimeout != 0 {
+		c.handshakeTimer = time.AfterFunc(time.Duration(c.handshakeTimeout), func() {
+			c.handshakeTimedOut.Store(true)
+			c.ctxCancel()
+		})
+	}
+}
+
+// handshakeComplete is called when the connection starts reading or publishing.
+func (c *conn) handshakeComplete() {
+	if c.handshakeTimer != nil {
+		c.handshakeTimer.Stop()
+	}
+}
+
 func (c *conn) Close() {
 	c.ctxCancel()
 }
@@ -122,6 +157,11 @@ func (c *conn) run() { //nolint:dupl
 
 	err := c.runInner()
 
+	c.handshakeComplete()
+	if c.handshakeTimedOut.Load() {
+		err = errHandshakeTimeout
+	}
+
 	c.ctxCancel()
 
 	c.parent.closeConn(c)
@@ -168,6 +208,10 @@ func (c *conn) runReader() error {
 func (c *conn) runRead(conn *rtmp.Conn, u *url.URL) error {
 	pathName, query, rawQuery := pathNameAndQuery(u)
 
+	// the handshake is complete. The handshake timer must be stopped before
+	// waiting for the stream, that can take up to sourceOnDemandStartTimeout.
+	c.handshakeComplete()
+
 	path, stream, err := c.pathManager.AddReader(defs.PathAddReaderReq{
 		Author: c,
 		AccessRequest: defs.PathAccessRequest{
@@ -198,24 +242,16 @@ func (c *conn) runRead(conn *rtmp.Conn, u *url.URL) error {
 	c.query = rawQuery
 	c.mutex.Unlock()
 
-	writer := asyncwriter.New(c.writeQueueSize, c)
+	writer := asyncwriter.NewReader(path.SafeConf(), c.writeQueueSize, c.readerQueueBudget, c)
 
 	defer stream.RemoveReader(writer)
 
-	var w *rtmp.Writer
-
-	videoFormat := c.setupVideo(
-		&w,
-		stream,
-		writer)
-
-	audioFormat := c.setupAudio(
-		&w,
-		stream,
-		writer)
+	// the write deadline was set before the handshake.
+	c.nconn.SetWriteDeadline(time.Now().Add(time.Duration(c.writeTimeout)))
 
-	if videoFormat == nil && audioFormat == nil {
-		return errNoSupportedCodecs
+	err = rtmp.FromStream(stream, writer, conn, c.nconn, time.Duration(c.writeTimeout))
+	if err != nil {
+		return err
 	}
 
 	c.Log(logger.Info, "is reading from path '%s', %s",
@@ -231,11 +267,6 @@ func (c *conn) runRead(conn *rtmp.Conn, u *url.URL) error {
 	})
 	defer onUnreadHook()
 
-	w, err = rtmp.NewWriter(conn, videoFormat, audioFormat)
-	if err != nil {
-		return err
-	}
-
 	// disable read deadline
 	c.nconn.SetReadDeadline(time.Time{})
 
@@ -251,151 +282,19 @@ func (c *conn) runRead(conn *rtmp.Conn, u *url.URL) error {
 	}
 }
 
-func (c *conn) setupVideo(
-	w **rtmp.Writer,
-	stream *stream.Stream,
-	writer *asyncwriter.Writer,
-) format.Format {
-	var videoFormatH264 *format.H264
-	videoMedia := stream.Desc().FindFormat(&videoFormatH264)
-
-	if videoFormatH264 != nil {
-		var videoDTSExtractor *h264.DTSExtractor
-
-		stream.AddReader(writer, videoMedia, videoFormatH264, func(u unit.Unit) error {
-			tunit := u.(*unit.H264)
-
-			if tunit.AU == nil {
-				return nil
-			}
-
-			idrPresent := false
-			nonIDRPresent := false
-
-			for _, nalu := range tunit.AU {
-				typ := h264.NALUType(nalu[0] & 0x1F)
-				switch typ {
-				case h264.NALUTypeIDR:
-					idrPresent = true
-
-				case h264.NALUTypeNonIDR:
-					nonIDRPresent = true
-				}
-			}
-
-			var dts time.Duration
-
-			// wait until we receive an IDR
-			if videoDTSExtractor == nil {
-				if !idrPresent {
-					return nil
-				}
-
-				videoDTSExtractor = h264.NewDTSExtractor()
-
-				var err error
-				dts, err = videoDTSExtractor.Extract(tunit.AU, tunit.PTS)
-				if err != nil {
-					return err
-				}
-			} else {
-				if !idrPresent && !nonIDRPresent {
-					return nil
-				}
-
-				var err error
-				dts, err = videoDTSExtractor.Extract(tunit.AU, tunit.PTS)
-				if err != nil {
-					return err
-				}
-			}
-
-			c.nconn.SetWriteDeadline(time.Now().Add(time.Duration(c.writeTimeout)))
-			return (*w).WriteH264(tunit.PTS, dts, idrPresent, tunit.AU)
-		})
-
-		return videoFormatH264
-	}
-
-	return nil
-}
-
-func (c *conn) setupAudio(
-	w **rtmp.Writer,
-	stream *stream.Stream,
-	writer *asyncwriter.Writer,
-) format.Format {
-	var audioFormatMPEG4Audio *format.MPEG4Audio
-	audioMedia := stream.Desc().FindFormat(&audioFormatMPEG4Audio)
-
-	if audioMedia != nil {
-		stream.AddReader(writer, audioMedia, audioFormatMPEG4Audio, func(u unit.Unit) error {
-			tunit := u.(*unit.MPEG4Audio)
-
-			if tunit.AUs == nil {
-				return nil
-			}
-
-			for i, au := range tunit.AUs {
-				c.nconn.SetWriteDeadline(time.Now().Add(time.Duration(c.writeTimeout)))
-				err := (*w).WriteMPEG4Audio(
-					tunit.PTS+time.Duration(i)*mpeg4audio.SamplesPerAccessUnit*
-						time.Second/time.Duration(audioFormatMPEG4Audio.ClockRate()),
-					au,
-				)
-				if err != nil {
-					return err
-				}
-			}
-
-			return nil
-		})
-
-		return audioFormatMPEG4Audio
-	}
-
-	var audioFormatMPEG1 *format.MPEG1Audio
-	audioMedia = stream.Desc().FindFormat(&audioFormatMPEG1)
-
-	if audioMedia != nil {
-		stream.AddReader(writer, audioMedia, audioFormatMPEG1, func(u unit.Unit) error {
-			tunit := u.(*unit.MPEG1Audio)
-
-			pts := tunit.PTS
-
-			for _, frame := range tunit.Frames {
-				var h mpeg1audio.FrameHeader
-				err := h.Unmarshal(frame)
-				if err != nil {
-					return err
-				}
-
-				if !(!h.MPEG2 && h.Layer == 3) {
-					return fmt.Errorf("RTMP only supports MPEG-1 layer 3 audio")
-				}
-
-				c.nconn.SetWriteDeadline(time.Now().Add(time.Duration(c.writeTimeout)))
-				err = (*w).WriteMPEG1Audio(pts, &h, frame)
-				if err != nil {
-					return err
-				}
-
-				pts += time.Duration(h.SampleCount()) *
-					time.Second / time.Duration(h.SampleRate)
-			}
-
-			return nil
-		})
-
-		return audioFormatMPEG1
-	}
-
-	return nil
-}
-
 func (c *conn) runPublish(conn *rtmp.Conn, u *url.URL) error {
 	pathName, query, rawQuery := pathNameAndQuery(u)
 
+	if c.publishPathTemplate != "" {
+		var err error
+		pathName, err = publishPathFromTemplate(c.publishPathTemplate, query.Get("user"))
+		if err != nil {
+			// wait some seconds to mitigate brute force attacks
+			<-time.After(pauseAfterAuthError)
+			return err
+		}
+	}
+
 	path, err := c.pathManager.AddPublisher(defs.PathAddPublisherReq{
 		Author: c,
 		AccessRequest: defs.PathAccessRequest{
@@ -559,6 +458,8 @@ func (c *conn) runPublish(conn *rtmp.Conn, u *url.URL) error {
 		return err
 	}
 
+	c.handshakeComplete()
+
 	// disable write deadline to allow outgoing acknowledges
 	c.nconn.SetWriteDeadline(time.Time{})
 
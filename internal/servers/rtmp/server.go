@@ -9,12 +9,15 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/keepalive"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
 	"github.com/bluenviron/mediamtx/internal/stream"
@@ -64,15 +67,22 @@ type serverParent interface {
 type Server struct {
 	Address             string
 	ReadTimeout         conf.StringDuration
+	TCPKeepalivePeriod  conf.StringDuration
 	WriteTimeout        conf.StringDuration
 	WriteQueueSize      int
+	ReaderQueueBudget   *asyncwriter.GrowthBudget
 	IsTLS               bool
 	ServerCert          string
 	ServerKey           string
+	TLSMinVersion       conf.TLSVersion
+	TLSCipherSuites     conf.TLSCipherSuites
 	RTSPAddress         string
 	RunOnConnect        string
 	RunOnConnectRestart bool
 	RunOnDisconnect     string
+	PublishPathTemplate string
+	HandshakeTimeout    conf.StringDuration
+	MaxConns            int
 	ExternalCmdPool     *externalcmd.Pool
 	PathManager         serverPathManager
 	Parent              serverParent
@@ -95,8 +105,10 @@ type Server struct {
 // Initialize initializes the server.
 func (s *Server) Initialize() error {
 	ln, err := func() (net.Listener, error) {
+		network, address := restrictnetwork.Restrict("tcp", s.Address)
+
 		if !s.IsTLS {
-			return net.Listen(restrictnetwork.Restrict("tcp", s.Address))
+			return keepalive.Listen(network, address, time.Duration(s.TCPKeepalivePeriod))
 		}
 
 		cert, err := tls.LoadX509KeyPair(s.ServerCert, s.ServerKey)
@@ -104,8 +116,16 @@ func (s *Server) Initialize() error {
 			return nil, err
 		}
 
-		network, address := restrictnetwork.Restrict("tcp", s.Address)
-		return tls.Listen(network, address, &tls.Config{Certificates: []tls.Certificate{cert}})
+		ln, err := keepalive.Listen(network, address, time.Duration(s.TCPKeepalivePeriod))
+		if err != nil {
+			return nil, err
+		}
+
+		return tls.NewListener(ln, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   uint16(s.TLSMinVersion),
+			CipherSuites: s.TLSCipherSuites,
+		}), nil
 	}()
 	if err != nil {
 		return err
@@ -166,6 +186,12 @@ outer:
 			break outer
 
 		case nconn := <-s.chNewConn:
+			if s.MaxConns != 0 && len(s.conns) >= s.MaxConns {
+				s.Log(logger.Warn, "closing connection from %v: too many connections", nconn.RemoteAddr())
+				nconn.Close()
+				continue
+			}
+
 			c := &conn{
 				parentCtx:           s.ctx,
 				isTLS:               s.IsTLS,
@@ -173,9 +199,12 @@ outer:
 				readTimeout:         s.ReadTimeout,
 				writeTimeout:        s.WriteTimeout,
 				writeQueueSize:      s.WriteQueueSize,
+				readerQueueBudget:   s.ReaderQueueBudget,
 				runOnConnect:        s.RunOnConnect,
 				runOnConnectRestart: s.RunOnConnectRestart,
 				runOnDisconnect:     s.RunOnDisconnect,
+				publishPathTemplate: s.PublishPathTemplate,
+				handshakeTimeout:    s.HandshakeTimeout,
 				wg:                  &s.wg,
 				nconn:               nconn,
 				externalCmdPool:     s.ExternalCmdPool,
@@ -2,6 +2,7 @@ package rtmp
 
 import (
 	"crypto/tls"
+	"errors"
 	"net"
 	"net/url"
 	"os"
@@ -44,6 +45,7 @@ func (p *dummyPath) StartPublisher(req defs.PathStartPublisherReq) (*stream.Stre
 		1460,
 		req.Desc,
 		true,
+		0,
 		test.NilLogger{},
 	)
 	if err != nil {
@@ -63,14 +65,18 @@ func (p *dummyPath) RemoveReader(_ defs.PathRemoveReaderReq) {
 }
 
 type dummyPathManager struct {
-	path *dummyPath
+	path           *dummyPath
+	publisherPath  string
+	addReaderDelay time.Duration
 }
 
-func (pm *dummyPathManager) AddPublisher(_ defs.PathAddPublisherReq) (defs.Path, error) {
+func (pm *dummyPathManager) AddPublisher(req defs.PathAddPublisherReq) (defs.Path, error) {
+	pm.publisherPath = req.AccessRequest.Name
 	return pm.path, nil
 }
 
 func (pm *dummyPathManager) AddReader(_ defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+	time.Sleep(pm.addReaderDelay)
 	return pm.path, pm.path.stream, nil
 }
 
@@ -140,7 +146,7 @@ func TestServerPublish(t *testing.T) {
 
 			<-path.streamCreated
 
-			aw := asyncwriter.New(512, &test.NilLogger{})
+			aw := asyncwriter.New(512, conf.ReaderOverflowPolicyDropNewest, &test.NilLogger{})
 
 			recv := make(chan struct{})
 
@@ -169,6 +175,52 @@ func TestServerPublish(t *testing.T) {
 	}
 }
 
+func TestServerPublishPathTemplate(t *testing.T) {
+	path := &dummyPath{
+		streamCreated: make(chan struct{}),
+	}
+
+	pathManager := &dummyPathManager{path: path}
+
+	s := &Server{
+		Address:             "127.0.0.1:1935",
+		ReadTimeout:         conf.StringDuration(10 * time.Second),
+		WriteTimeout:        conf.StringDuration(10 * time.Second),
+		WriteQueueSize:      512,
+		PublishPathTemplate: "users/{user}",
+		PathManager:         pathManager,
+		Parent:              &test.NilLogger{},
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	u, err := url.Parse("rtmp://127.0.0.1:1935/otherpath?user=myuser&pass=testpass")
+	require.NoError(t, err)
+
+	nconn, err := net.Dial("tcp", u.Host)
+	require.NoError(t, err)
+	defer nconn.Close()
+
+	conn, err := rtmp.NewClientConn(nconn, u, true)
+	require.NoError(t, err)
+
+	_, err = rtmp.NewWriter(conn, test.FormatH264, test.FormatMPEG4Audio)
+	require.NoError(t, err)
+
+	<-path.streamCreated
+
+	require.Equal(t, "users/myuser", pathManager.publisherPath)
+}
+
+func TestPublishPathFromTemplate(t *testing.T) {
+	_, err := publishPathFromTemplate("users/{user}", "")
+	require.EqualError(t, err, "authentication failed: a user is required in order to publish")
+
+	_, err = publishPathFromTemplate("users/{user}", "other/user")
+	require.EqualError(t, err, "authentication failed: invalid user 'other/user'")
+}
+
 func TestServerRead(t *testing.T) {
 	for _, encrypt := range []string{
 		"plain",
@@ -200,6 +252,7 @@ func TestServerRead(t *testing.T) {
 				1460,
 				desc,
 				true,
+				0,
 				test.NilLogger{},
 			)
 			require.NoError(t, err)
@@ -271,3 +324,113 @@ func TestServerRead(t *testing.T) {
 		})
 	}
 }
+
+func TestServerHandshakeTimeout(t *testing.T) {
+	s := &Server{
+		Address:          "127.0.0.1:1935",
+		ReadTimeout:      conf.StringDuration(10 * time.Second),
+		WriteTimeout:     conf.StringDuration(10 * time.Second),
+		WriteQueueSize:   512,
+		HandshakeTimeout: conf.StringDuration(200 * time.Millisecond),
+		PathManager:      &dummyPathManager{},
+		Parent:           &test.NilLogger{},
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	nconn, err := net.Dial("tcp", "127.0.0.1:1935")
+	require.NoError(t, err)
+	defer nconn.Close()
+
+	start := time.Now()
+
+	// the client never sends the handshake.
+	nconn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = nconn.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestServerHandshakeTimeoutOnDemand(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{test.FormatH264},
+	}}}
+
+	stream, err := stream.New(
+		1460,
+		desc,
+		true,
+		0,
+		test.NilLogger{},
+	)
+	require.NoError(t, err)
+
+	// the stream is provided after the handshake timeout, like an on-demand source.
+	pathManager := &dummyPathManager{
+		path:           &dummyPath{stream: stream},
+		addReaderDelay: 500 * time.Millisecond,
+	}
+
+	s := &Server{
+		Address:          "127.0.0.1:1935",
+		ReadTimeout:      conf.StringDuration(10 * time.Second),
+		WriteTimeout:     conf.StringDuration(10 * time.Second),
+		WriteQueueSize:   512,
+		HandshakeTimeout: conf.StringDuration(200 * time.Millisecond),
+		PathManager:      pathManager,
+		Parent:           &test.NilLogger{},
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	u, err := url.Parse("rtmp://127.0.0.1:1935/teststream")
+	require.NoError(t, err)
+
+	nconn, err := net.Dial("tcp", u.Host)
+	require.NoError(t, err)
+	defer nconn.Close()
+
+	conn, err := rtmp.NewClientConn(nconn, u, false)
+	require.NoError(t, err)
+
+	r, err := rtmp.NewReader(conn)
+	require.NoError(t, err)
+
+	videoTrack, _ := r.Tracks()
+	require.Equal(t, test.FormatH264, videoTrack)
+}
+
+func TestServerMaxConns(t *testing.T) {
+	s := &Server{
+		Address:        "127.0.0.1:1935",
+		ReadTimeout:    conf.StringDuration(10 * time.Second),
+		WriteTimeout:   conf.StringDuration(10 * time.Second),
+		WriteQueueSize: 512,
+		MaxConns:       1,
+		PathManager:    &dummyPathManager{},
+		Parent:         &test.NilLogger{},
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	nconn1, err := net.Dial("tcp", "127.0.0.1:1935")
+	require.NoError(t, err)
+	defer nconn1.Close()
+
+	// wait for the first connection to be registered.
+	time.Sleep(100 * time.Millisecond)
+
+	nconn2, err := net.Dial("tcp", "127.0.0.1:1935")
+	require.NoError(t, err)
+	defer nconn2.Close()
+
+	nconn2.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = nconn2.Read(make([]byte, 1))
+	require.Error(t, err)
+	var netErr net.Error
+	require.False(t, errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -7,11 +7,12 @@ import (
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4"
-	"github.com/bluenviron/gortsplib/v4/pkg/auth"
+	rtspauth "github.com/bluenviron/gortsplib/v4/pkg/auth"
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/headers"
 	"github.com/google/uuid"
 
+	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
@@ -118,7 +119,7 @@ func (c *conn) onDescribe(ctx *gortsplib.ServerHandlerOnDescribeCtx,
 
 	if c.authNonce == "" {
 		var err error
-		c.authNonce, err = auth.GenerateNonce()
+		c.authNonce, err = rtspauth.GenerateNonce()
 		if err != nil {
 			return &base.Response{
 				StatusCode: base.StatusInternalServerError,
@@ -168,9 +169,9 @@ func (c *conn) onDescribe(ctx *gortsplib.ServerHandlerOnDescribeCtx,
 
 	var stream *gortsplib.ServerStream
 	if !c.isTLS {
-		stream = res.Stream.RTSPStream(c.rserver)
+		stream = res.Stream.RTSPStream(c.rserver, time.Duration(c.parent.SenderReportPeriod))
 	} else {
-		stream = res.Stream.RTSPSStream(c.rserver)
+		stream = res.Stream.RTSPSStream(c.rserver, time.Duration(c.parent.SenderReportPeriod))
 	}
 
 	return &base.Response{
@@ -191,7 +192,7 @@ func (c *conn) handleAuthError(authErr error) (*base.Response, error) {
 		return &base.Response{
 			StatusCode: base.StatusUnauthorized,
 			Header: base.Header{
-				"WWW-Authenticate": auth.GenerateWWWAuthenticate(c.authMethods, "IPCAM", c.authNonce),
+				"WWW-Authenticate": auth.GenerateRTSPWWWAuthenticate(c.authMethods, "IPCAM", c.authNonce),
 			},
 		}, nil
 	}
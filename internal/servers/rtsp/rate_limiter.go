@@ -0,0 +1,77 @@
+package rtsp
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+type rateLimiterBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token bucket rate limiter keyed by remote IP.
+// Each IP can perform up to rate operations per second.
+type rateLimiter struct {
+	rate      int
+	exemptIPs conf.IPsOrCIDRs
+
+	mutex     sync.Mutex
+	buckets   map[string]*rateLimiterBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(rate int, exemptIPs conf.IPsOrCIDRs) *rateLimiter {
+	return &rateLimiter{
+		rate:      rate,
+		exemptIPs: exemptIPs,
+		buckets:   make(map[string]*rateLimiterBucket),
+	}
+}
+
+// allow checks whether an operation can be performed by an IP, and consumes a token.
+func (l *rateLimiter) allow(ip net.IP) bool {
+	if l.rate <= 0 || l.exemptIPs.Contains(ip) {
+		return true
+	}
+
+	now := time.Now()
+	burst := float64(l.rate)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// buckets that have been idle for more than a second are full, therefore they can be removed.
+	if now.Sub(l.lastSweep) >= time.Second {
+		for key, b := range l.buckets {
+			if now.Sub(b.last) >= time.Second {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := ip.String()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateLimiterBucket{tokens: burst}
+		l.buckets[key] = b
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * float64(l.rate)
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
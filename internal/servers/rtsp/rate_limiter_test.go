@@ -0,0 +1,41 @@
+package rtsp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var exemptIPs conf.IPsOrCIDRs
+	err := exemptIPs.UnmarshalJSON([]byte(`["192.168.1.0/24"]`))
+	require.NoError(t, err)
+
+	l := newRateLimiter(2, exemptIPs)
+
+	ip1 := net.ParseIP("10.0.0.1")
+	ip2 := net.ParseIP("10.0.0.2")
+
+	require.Equal(t, true, l.allow(ip1))
+	require.Equal(t, true, l.allow(ip1))
+	require.Equal(t, false, l.allow(ip1))
+
+	// buckets are independent for each IP.
+	require.Equal(t, true, l.allow(ip2))
+
+	// exempt IPs are never limited.
+	for i := 0; i < 10; i++ {
+		require.Equal(t, true, l.allow(net.ParseIP("192.168.1.5")))
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	l := newRateLimiter(0, nil)
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, true, l.allow(net.ParseIP("10.0.0.1")))
+	}
+}
@@ -0,0 +1,123 @@
+package rtsp
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/rtcpsender"
+	"github.com/pion/rtcp"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+func rewindFromQuery(rawQuery string) (time.Duration, error) {
+	query, _ := url.ParseQuery(rawQuery)
+
+	v := query.Get("rewind")
+	if v == "" {
+		return 0, nil
+	}
+
+	rewind, err := time.ParseDuration(v)
+	if err != nil || rewind < 0 {
+		return 0, fmt.Errorf("invalid rewind '%s'", v)
+	}
+
+	return rewind, nil
+}
+
+// rewindReader allows a session to read a stream from a point in the past.
+// Packets of the shared RTSP stream are sent to all sessions at once,
+// therefore the session reads from a dedicated RTSP stream, that is fed
+// with replayed units first and with live units after.
+type rewindReader struct {
+	server             *gortsplib.Server
+	stream             *stream.Stream
+	rewind             time.Duration
+	senderReportPeriod time.Duration
+	writer             *asyncwriter.Writer
+	onError            func(error)
+
+	rstream   *gortsplib.ServerStream
+	senders   []*rtcpsender.RTCPSender
+	started   bool
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+func (r *rewindReader) initialize() {
+	r.rstream = gortsplib.NewServerStream(r.server, r.stream.Desc())
+	r.terminate = make(chan struct{})
+	r.done = make(chan struct{})
+
+	if r.senderReportPeriod == 0 {
+		r.senderReportPeriod = stream.RTSPDefaultSenderReportPeriod
+	}
+}
+
+func (r *rewindReader) start(medias []*description.Media) {
+	for _, medi := range medias {
+		for _, forma := range medi.Formats {
+			cmedi := medi
+			cforma := forma
+
+			sender := rtcpsender.New(
+				cforma.ClockRate(),
+				r.senderReportPeriod,
+				nil,
+				func(pkt rtcp.Packet) {
+					r.rstream.WritePacketRTCP(cmedi, pkt) //nolint:errcheck
+				})
+			r.senders = append(r.senders, sender)
+
+			r.stream.AddReader(r.writer, cmedi, cforma, func(u unit.Unit) error {
+				for _, pkt := range u.GetRTPPackets() {
+					err := r.rstream.WritePacketRTPWithNTP(cmedi, pkt, u.GetNTP())
+					if err != nil {
+						return err
+					}
+					sender.ProcessPacket(pkt, u.GetNTP(), cforma.PTSEqualsDTS(pkt))
+				}
+				return nil
+			})
+		}
+	}
+
+	r.stream.ReplayToReader(r.writer, r.rewind)
+
+	r.writer.Start()
+	r.started = true
+
+	go r.run()
+}
+
+func (r *rewindReader) run() {
+	defer close(r.done)
+
+	select {
+	case err := <-r.writer.Error():
+		r.onError(err)
+
+	case <-r.terminate:
+		r.writer.Stop()
+	}
+}
+
+func (r *rewindReader) close() {
+	if r.started {
+		r.stream.RemoveReader(r.writer)
+		close(r.terminate)
+		<-r.done
+	}
+
+	for _, sender := range r.senders {
+		sender.Close()
+	}
+
+	r.rstream.Close()
+}
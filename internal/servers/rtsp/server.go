@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"sort"
 	"strings"
 	"sync"
@@ -17,10 +18,14 @@ import (
 	"github.com/bluenviron/gortsplib/v4/pkg/liberrors"
 	"github.com/google/uuid"
 
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/dscp"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/keepalive"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
 )
 
 // ErrConnNotFound is returned when a connection is not found.
@@ -29,11 +34,21 @@ var ErrConnNotFound = errors.New("connection not found")
 // ErrSessionNotFound is returned when a session is not found.
 var ErrSessionNotFound = errors.New("session not found")
 
-func printAddresses(srv *gortsplib.Server) string {
+var errRateLimitExceeded = errors.New("rate limit exceeded")
+
+var errMaxSessionsReached = errors.New("maximum number of sessions reached")
+
+var errPathMaxSessionsReached = errors.New("maximum number of sessions of path reached")
+
+func printAddresses(srv *gortsplib.Server, webSocketAddress string) string {
 	var ret []string
 
 	ret = append(ret, fmt.Sprintf("%s (TCP)", srv.RTSPAddress))
 
+	if webSocketAddress != "" {
+		ret = append(ret, fmt.Sprintf("%s (WebSocket)", webSocketAddress))
+	}
+
 	if srv.UDPRTPAddress != "" {
 		ret = append(ret, fmt.Sprintf("%s (UDP/RTP)", srv.UDPRTPAddress))
 	}
@@ -54,8 +69,12 @@ type Server struct {
 	Address             string
 	AuthMethods         []headers.AuthMethod
 	ReadTimeout         conf.StringDuration
+	TCPKeepalivePeriod  conf.StringDuration
 	WriteTimeout        conf.StringDuration
 	WriteQueueSize      int
+	MaxWriteQueueSize   int
+	ReaderQueueBudget   *asyncwriter.GrowthBudget
+	DSCP                int
 	UseUDP              bool
 	UseMulticast        bool
 	RTPAddress          string
@@ -66,8 +85,17 @@ type Server struct {
 	IsTLS               bool
 	ServerCert          string
 	ServerKey           string
+	TLSMinVersion       conf.TLSVersion
+	TLSCipherSuites     conf.TLSCipherSuites
 	RTSPAddress         string
+	WebSocketAddress    string
 	Protocols           map[conf.Protocol]struct{}
+	TransportsByIP      conf.RTSPTransportsByIP
+	SenderReportPeriod  conf.StringDuration
+	ConnRateLimit       int
+	RequestRateLimit    int
+	RateLimitExemptIPs  conf.IPsOrCIDRs
+	MaxSessions         int
 	RunOnConnect        string
 	RunOnConnectRestart bool
 	RunOnDisconnect     string
@@ -82,6 +110,15 @@ type Server struct {
 	mutex     sync.RWMutex
 	conns     map[*gortsplib.ServerConn]*conn
 	sessions  map[*gortsplib.ServerSession]*session
+
+	// sessions that passed their first SETUP, in total and by path.
+	// A dedicated mutex is used since sessions can be closed while mutex is held.
+	slotsMutex       sync.Mutex
+	sessionSlots     int
+	pathSessionSlots map[string]int
+
+	connLimiter    *rateLimiter
+	requestLimiter *rateLimiter
 }
 
 // Initialize initializes the server.
@@ -90,13 +127,53 @@ func (s *Server) Initialize() error {
 
 	s.conns = make(map[*gortsplib.ServerConn]*conn)
 	s.sessions = make(map[*gortsplib.ServerSession]*session)
+	s.pathSessionSlots = make(map[string]int)
+	s.connLimiter = newRateLimiter(s.ConnRateLimit, s.RateLimitExemptIPs)
+	s.requestLimiter = newRateLimiter(s.RequestRateLimit, s.RateLimitExemptIPs)
 
 	s.srv = &gortsplib.Server{
 		Handler:        s,
 		ReadTimeout:    time.Duration(s.ReadTimeout),
 		WriteTimeout:   time.Duration(s.WriteTimeout),
-		WriteQueueSize: s.WriteQueueSize,
+		WriteQueueSize: s.sessionWriteQueueSize(),
 		RTSPAddress:    s.Address,
+		// sender reports are sent by streams, with a configurable period.
+		DisableRTCPSenderReports: true,
+		Listen: func(network string, address string) (net.Listener, error) {
+			network, address = restrictnetwork.Restrict(network, address)
+			ln, err := keepalive.Listen(network, address, time.Duration(s.TCPKeepalivePeriod))
+			if err != nil || s.WebSocketAddress == "" {
+				return ln, err
+			}
+
+			// accept RTSP-over-WebSocket connections too
+			wln, err := newWebSocketListener(
+				ln,
+				s.WebSocketAddress,
+				time.Duration(s.ReadTimeout),
+				time.Duration(s.TCPKeepalivePeriod),
+				s,
+			)
+			if err != nil {
+				ln.Close()
+				return nil, err
+			}
+
+			return wln, nil
+		},
+		ListenPacket: func(network string, address string) (net.PacketConn, error) {
+			pc, err := net.ListenPacket(restrictnetwork.Restrict(network, address))
+			if err != nil {
+				return nil, err
+			}
+
+			err = dscp.SetPacketConn(pc, s.DSCP)
+			if err != nil {
+				s.Log(logger.Warn, "%v", err)
+			}
+
+			return pc, nil
+		},
 	}
 
 	if s.UseUDP {
@@ -116,7 +193,11 @@ func (s *Server) Initialize() error {
 			return err
 		}
 
-		s.srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		s.srv.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   uint16(s.TLSMinVersion),
+			CipherSuites: s.TLSCipherSuites,
+		}
 	}
 
 	err := s.srv.Start()
@@ -124,7 +205,7 @@ func (s *Server) Initialize() error {
 		return err
 	}
 
-	s.Log(logger.Info, "listener opened on %s", printAddresses(s.srv))
+	s.Log(logger.Info, "listener opened on %s", printAddresses(s.srv, s.WebSocketAddress))
 
 	s.wg.Add(1)
 	go s.run()
@@ -143,6 +224,25 @@ func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
 	s.Parent.Log(level, "[%s] "+format, append([]interface{}{label}, args...)...)
 }
 
+// sessionWriteQueueSize returns the size of the write queue of sessions,
+// that must be able to contain the queue of any reader and be a power of two.
+func (s *Server) sessionWriteQueueSize() int {
+	size := s.WriteQueueSize
+	if s.MaxWriteQueueSize > size {
+		size = s.MaxWriteQueueSize
+	}
+
+	if size == 0 {
+		return 0
+	}
+
+	ret := 1
+	for ret < size {
+		ret <<= 1
+	}
+	return ret
+}
+
 // Close closes the server.
 func (s *Server) Close() {
 	s.Log(logger.Info, "listener is closing")
@@ -175,6 +275,13 @@ outer:
 
 // OnConnOpen implements gortsplib.ServerHandlerOnConnOpen.
 func (s *Server) OnConnOpen(ctx *gortsplib.ServerHandlerOnConnOpenCtx) {
+	if !s.connLimiter.allow(ctx.Conn.NetConn().RemoteAddr().(*net.TCPAddr).IP) {
+		s.Log(logger.Debug, "connection from %v rejected: rate limit exceeded", ctx.Conn.NetConn().RemoteAddr())
+		// closing the net.Conn prevents any request from being read.
+		ctx.Conn.NetConn().Close() //nolint:errcheck
+		return
+	}
+
 	c := &conn{
 		isTLS:               s.IsTLS,
 		rtspAddress:         s.RTSPAddress,
@@ -200,9 +307,15 @@ func (s *Server) OnConnOpen(ctx *gortsplib.ServerHandlerOnConnOpenCtx) {
 // OnConnClose implements gortsplib.ServerHandlerOnConnClose.
 func (s *Server) OnConnClose(ctx *gortsplib.ServerHandlerOnConnCloseCtx) {
 	s.mutex.Lock()
-	c := s.conns[ctx.Conn]
+	c, ok := s.conns[ctx.Conn]
 	delete(s.conns, ctx.Conn)
 	s.mutex.Unlock()
+
+	// connection has been rejected by the rate limiter.
+	if !ok {
+		return
+	}
+
 	c.onClose(ctx.Error)
 }
 
@@ -221,14 +334,17 @@ func (s *Server) OnResponse(sc *gortsplib.ServerConn, res *base.Response) {
 // OnSessionOpen implements gortsplib.ServerHandlerOnSessionOpen.
 func (s *Server) OnSessionOpen(ctx *gortsplib.ServerHandlerOnSessionOpenCtx) {
 	se := &session{
-		isTLS:           s.IsTLS,
-		protocols:       s.Protocols,
-		rsession:        ctx.Session,
-		rconn:           ctx.Conn,
-		rserver:         s.srv,
-		externalCmdPool: s.ExternalCmdPool,
-		pathManager:     s.PathManager,
-		parent:          s,
+		isTLS:             s.IsTLS,
+		protocols:         s.Protocols,
+		transportsByIP:    s.TransportsByIP,
+		rsession:          ctx.Session,
+		rconn:             ctx.Conn,
+		rserver:           s.srv,
+		writeQueueSize:    s.WriteQueueSize,
+		readerQueueBudget: s.ReaderQueueBudget,
+		externalCmdPool:   s.ExternalCmdPool,
+		pathManager:       s.PathManager,
+		parent:            s,
 	}
 	se.initialize()
 	s.mutex.Lock()
@@ -249,10 +365,49 @@ func (s *Server) OnSessionClose(ctx *gortsplib.ServerHandlerOnSessionCloseCtx) {
 	}
 }
 
+// acquireSessionSlot is called by session before completing its first SETUP.
+func (s *Server) acquireSessionSlot(pathName string, pathMaxSessions int) error {
+	s.slotsMutex.Lock()
+	defer s.slotsMutex.Unlock()
+
+	if s.MaxSessions != 0 && s.sessionSlots >= s.MaxSessions {
+		return errMaxSessionsReached
+	}
+
+	if pathMaxSessions != 0 && s.pathSessionSlots[pathName] >= pathMaxSessions {
+		return errPathMaxSessionsReached
+	}
+
+	s.sessionSlots++
+	s.pathSessionSlots[pathName]++
+
+	return nil
+}
+
+// releaseSessionSlot is called by session when it is closed.
+func (s *Server) releaseSessionSlot(pathName string) {
+	s.slotsMutex.Lock()
+	defer s.slotsMutex.Unlock()
+
+	s.sessionSlots--
+	s.pathSessionSlots[pathName]--
+
+	if s.pathSessionSlots[pathName] == 0 {
+		delete(s.pathSessionSlots, pathName)
+	}
+}
+
 // OnDescribe implements gortsplib.ServerHandlerOnDescribe.
 func (s *Server) OnDescribe(ctx *gortsplib.ServerHandlerOnDescribeCtx,
 ) (*base.Response, *gortsplib.ServerStream, error) {
 	c := ctx.Conn.UserData().(*conn)
+
+	if !s.requestLimiter.allow(c.ip()) {
+		return &base.Response{
+			StatusCode: base.StatusServiceUnavailable,
+		}, nil, errRateLimitExceeded
+	}
+
 	return c.onDescribe(ctx)
 }
 
@@ -266,6 +421,13 @@ func (s *Server) OnAnnounce(ctx *gortsplib.ServerHandlerOnAnnounceCtx) (*base.Re
 // OnSetup implements gortsplib.ServerHandlerOnSetup.
 func (s *Server) OnSetup(ctx *gortsplib.ServerHandlerOnSetupCtx) (*base.Response, *gortsplib.ServerStream, error) {
 	c := ctx.Conn.UserData().(*conn)
+
+	if !s.requestLimiter.allow(c.ip()) {
+		return &base.Response{
+			StatusCode: base.StatusServiceUnavailable,
+		}, nil, errRateLimitExceeded
+	}
+
 	se := ctx.Session.UserData().(*session)
 	return se.onSetup(c, ctx)
 }
@@ -14,32 +14,41 @@ import (
 	"github.com/google/uuid"
 	"github.com/pion/rtp"
 
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/hooks"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/sdp"
 	"github.com/bluenviron/mediamtx/internal/stream"
 )
 
 type session struct {
-	isTLS           bool
-	protocols       map[conf.Protocol]struct{}
-	rsession        *gortsplib.ServerSession
-	rconn           *gortsplib.ServerConn
-	rserver         *gortsplib.Server
-	externalCmdPool *externalcmd.Pool
-	pathManager     defs.PathManager
-	parent          *Server
+	isTLS             bool
+	protocols         map[conf.Protocol]struct{}
+	transportsByIP    conf.RTSPTransportsByIP
+	rsession          *gortsplib.ServerSession
+	rconn             *gortsplib.ServerConn
+	rserver           *gortsplib.Server
+	writeQueueSize    int
+	readerQueueBudget *asyncwriter.GrowthBudget
+	externalCmdPool   *externalcmd.Pool
+	pathManager       defs.PathManager
+	parent            *Server
 
 	uuid            uuid.UUID
 	created         time.Time
 	path            defs.Path
 	stream          *stream.Stream
+	rtspReader      *stream.RTSPReader
+	rewindReader    *rewindReader
 	onUnreadHook    func()
+	slotPathName    *string
 	mutex           sync.Mutex
 	state           gortsplib.ServerSessionState
 	transport       *gortsplib.Transport
+	sdp             *string
 	pathName        string
 	query           string
 	decodeErrLogger logger.Writer
@@ -79,12 +88,25 @@ func (s *session) onClose(err error) {
 
 	switch s.rsession.State() {
 	case gortsplib.ServerSessionStatePrePlay, gortsplib.ServerSessionStatePlay:
+		if s.rtspReader != nil {
+			s.stream.RemoveRTSPReader(s.rtspReader)
+			s.rtspReader = nil
+		}
+		if s.rewindReader != nil {
+			s.rewindReader.close()
+			s.rewindReader = nil
+		}
 		s.path.RemoveReader(defs.PathRemoveReaderReq{Author: s})
 
 	case gortsplib.ServerSessionStatePreRecord, gortsplib.ServerSessionStateRecord:
 		s.path.RemovePublisher(defs.PathRemovePublisherReq{Author: s})
 	}
 
+	if s.slotPathName != nil {
+		s.parent.releaseSessionSlot(*s.slotPathName)
+		s.slotPathName = nil
+	}
+
 	s.path = nil
 	s.stream = nil
 
@@ -137,10 +159,13 @@ func (s *session) onAnnounce(c *conn, ctx *gortsplib.ServerHandlerOnAnnounceCtx)
 
 	s.path = path
 
+	desc := sdp.Redact(string(ctx.Request.Body))
+
 	s.mutex.Lock()
 	s.state = gortsplib.ServerSessionStatePreRecord
 	s.pathName = ctx.Path
 	s.query = ctx.Query
+	s.sdp = &desc
 	s.mutex.Unlock()
 
 	return &base.Response{
@@ -170,8 +195,24 @@ func (s *session) onSetup(c *conn, ctx *gortsplib.ServerHandlerOnSetupCtx,
 		}
 	}
 
+	// clients whose IP is listed in rtspTransportsByIP can use only the listed transport protocols.
+	if transports, ok := s.transportsByIP.Find(c.ip()); ok {
+		if _, ok := transports[conf.Protocol(ctx.Transport)]; !ok {
+			return &base.Response{
+				StatusCode: base.StatusUnsupportedTransport,
+			}, nil, nil
+		}
+	}
+
 	switch s.rsession.State() {
 	case gortsplib.ServerSessionStateInitial, gortsplib.ServerSessionStatePrePlay: // play
+		rewind, err := rewindFromQuery(ctx.Query)
+		if err != nil {
+			return &base.Response{
+				StatusCode: base.StatusBadRequest,
+			}, nil, err
+		}
+
 		baseURL := &base.URL{
 			Scheme:   ctx.Request.URL.Scheme,
 			Host:     ctx.Request.URL.Host,
@@ -227,33 +268,106 @@ func (s *session) onSetup(c *conn, ctx *gortsplib.ServerHandlerOnSetupCtx,
 			}, nil, err
 		}
 
+		if _, ok := path.SafeConf().RTSPTransports[conf.Protocol(ctx.Transport)]; !ok {
+			path.RemoveReader(defs.PathRemoveReaderReq{Author: s})
+			return &base.Response{
+				StatusCode: base.StatusUnsupportedTransport,
+			}, nil, nil
+		}
+
+		err = s.acquireSlot(path)
+		if err != nil {
+			path.RemoveReader(defs.PathRemoveReaderReq{Author: s})
+			return &base.Response{
+				StatusCode: base.StatusServiceUnavailable,
+			}, nil, err
+		}
+
 		s.path = path
 		s.stream = stream
 
+		var rstream *gortsplib.ServerStream
+		if !s.isTLS {
+			rstream = stream.RTSPStream(s.rserver, time.Duration(s.parent.SenderReportPeriod))
+		} else {
+			rstream = stream.RTSPSStream(s.rserver, time.Duration(s.parent.SenderReportPeriod))
+		}
+
+		// sessions that start from a point in the past read from a dedicated stream.
+		// Multicast sessions are shared, therefore they can't be used.
+		if rewind > 0 && path.SafeConf().LiveBufferDuration > 0 &&
+			ctx.Transport != gortsplib.TransportUDPMulticast {
+			if s.rewindReader == nil {
+				s.rewindReader = &rewindReader{
+					server:             s.rserver,
+					stream:             stream,
+					rewind:             rewind,
+					senderReportPeriod: time.Duration(s.parent.SenderReportPeriod),
+					onError: func(err error) {
+						s.Log(logger.Warn, "%v", err)
+						s.rsession.Close()
+					},
+				}
+				s.rewindReader.initialize()
+			}
+			rstream = s.rewindReader.rstream
+		}
+
+		var desc *string
+		if byts, err := rstream.Description().Marshal(false); err == nil {
+			v := sdp.Redact(string(byts))
+			desc = &v
+		}
+
 		s.mutex.Lock()
 		s.state = gortsplib.ServerSessionStatePrePlay
 		s.pathName = ctx.Path
 		s.query = ctx.Query
+		s.sdp = desc
 		s.mutex.Unlock()
 
-		var rstream *gortsplib.ServerStream
-		if !s.isTLS {
-			rstream = stream.RTSPStream(s.rserver)
-		} else {
-			rstream = stream.RTSPSStream(s.rserver)
-		}
-
 		return &base.Response{
 			StatusCode: base.StatusOK,
 		}, rstream, nil
 
 	default: // record
+		if _, ok := s.path.SafeConf().RTSPTransports[conf.Protocol(ctx.Transport)]; !ok {
+			return &base.Response{
+				StatusCode: base.StatusUnsupportedTransport,
+			}, nil, nil
+		}
+
+		err := s.acquireSlot(s.path)
+		if err != nil {
+			return &base.Response{
+				StatusCode: base.StatusServiceUnavailable,
+			}, nil, err
+		}
+
 		return &base.Response{
 			StatusCode: base.StatusOK,
 		}, nil, nil
 	}
 }
 
+// acquireSlot reserves a slot in the session limits of the server and of the path.
+// The slot is acquired once, by the first SETUP request,
+// and is released when the session is closed.
+func (s *session) acquireSlot(path defs.Path) error {
+	if s.slotPathName != nil {
+		return nil
+	}
+
+	err := s.parent.acquireSessionSlot(path.Name(), path.SafeConf().RTSPMaxSessions)
+	if err != nil {
+		return err
+	}
+
+	pathName := path.Name()
+	s.slotPathName = &pathName
+	return nil
+}
+
 // onPlay is called by rtspServer.
 func (s *session) onPlay(_ *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, error) {
 	h := make(base.Header)
@@ -273,6 +387,48 @@ func (s *session) onPlay(_ *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, e
 			Query:           s.rsession.SetuppedQuery(),
 		})
 
+		pathConf := s.path.SafeConf()
+
+		switch {
+		// sessions that start from a point in the past are fed by a reader,
+		// whose queue is handled like the one of other protocols.
+		case s.rewindReader != nil:
+			if s.rewindReader.writer == nil {
+				s.rewindReader.writer = asyncwriter.NewReader(pathConf, s.writeQueueSize, s.readerQueueBudget, s)
+				s.rewindReader.start(s.rsession.SetuppedMedias())
+			}
+
+		// the write queue of the session is allowed to grow during bursts.
+		// Multicast sessions are shared, therefore they are not affected.
+		case pathConf.ReaderQueueMaxSize != 0 &&
+			*s.rsession.SetuppedTransport() != gortsplib.TransportUDPMulticast:
+			queueSize := pathConf.ReaderQueueSize
+			if queueSize == 0 {
+				queueSize = s.writeQueueSize
+			}
+
+			s.rtspReader = &stream.RTSPReader{
+				Session:       s.rsession,
+				IsTLS:         s.isTLS,
+				QueueSize:     queueSize,
+				MaxQueueSize:  pathConf.ReaderQueueMaxSize,
+				MaxPacketSize: s.rserver.MaxPacketSize,
+				Budget:        s.readerQueueBudget,
+				OnOverflow: func() {
+					s.Log(logger.Warn, "write queue is full, closing")
+					s.rsession.Close()
+				},
+			}
+			s.stream.AddRTSPReader(s.rtspReader)
+		}
+
+		// packets written inside OnPlay are sent before live ones.
+		// Multicast sessions are shared, therefore they can't be used.
+		if pathConf.ReaderInstantStart && s.rewindReader == nil &&
+			*s.rsession.SetuppedTransport() != gortsplib.TransportUDPMulticast {
+			s.stream.ReplayToRTSPSession(s.rsession)
+		}
+
 		s.mutex.Lock()
 		s.state = gortsplib.ServerSessionStatePlay
 		s.transport = s.rsession.SetuppedTransport()
@@ -300,6 +456,8 @@ func (s *session) onRecord(_ *gortsplib.ServerHandlerOnRecordCtx) (*base.Respons
 
 	s.stream = stream
 
+	useAbsoluteTimestamp := s.path.SafeConf().UseAbsoluteTimestamp
+
 	for _, medi := range s.rsession.AnnouncedDescription().Medias {
 		for _, forma := range medi.Formats {
 			cmedi := medi
@@ -311,7 +469,14 @@ func (s *session) onRecord(_ *gortsplib.ServerHandlerOnRecordCtx) (*base.Respons
 					return
 				}
 
-				stream.WriteRTPPacket(cmedi, cforma, pkt, time.Now(), pts)
+				ntp := time.Now()
+				if useAbsoluteTimestamp {
+					if v, ok := s.rsession.PacketNTP(cmedi, pkt); ok {
+						ntp = v
+					}
+				}
+
+				stream.WriteRTPPacket(cmedi, cforma, pkt, ntp, pts)
 			})
 		}
 	}
@@ -411,6 +576,7 @@ func (s *session) apiItem() *defs.APIRTSPSession {
 			v := s.transport.String()
 			return &v
 		}(),
+		SDP:           s.sdp,
 		BytesReceived: s.rsession.BytesReceived(),
 		BytesSent:     s.rsession.BytesSent(),
 	}
@@ -0,0 +1,118 @@
+package rtsp
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
+	"github.com/bluenviron/mediamtx/internal/protocols/websocket"
+	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
+)
+
+// webSocketListener is a net.Listener that accepts connections from a TCP listener
+// and RTSP-over-WebSocket connections from a HTTP server.
+type webSocketListener struct {
+	net.Listener
+
+	httpServer *httpp.WrappedServer
+	conns      chan net.Conn
+	acceptErr  chan error
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func newWebSocketListener(
+	ln net.Listener,
+	address string,
+	readTimeout time.Duration,
+	tcpKeepalivePeriod time.Duration,
+	parent logger.Writer,
+) (*webSocketListener, error) {
+	l := &webSocketListener{
+		Listener:  ln,
+		conns:     make(chan net.Conn),
+		acceptErr: make(chan error, 1),
+		done:      make(chan struct{}),
+	}
+
+	network, address := restrictnetwork.Restrict("tcp", address)
+
+	var err error
+	l.httpServer, err = httpp.NewWrappedServer(
+		network,
+		address,
+		readTimeout,
+		tcpKeepalivePeriod,
+		"",
+		"",
+		0,
+		nil,
+		http.HandlerFunc(l.onRequest),
+		parent,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	go l.runAccept()
+
+	return l, nil
+}
+
+func (l *webSocketListener) runAccept() {
+	for {
+		nconn, err := l.Listener.Accept()
+		if err != nil {
+			l.acceptErr <- err
+			return
+		}
+
+		select {
+		case l.conns <- nconn:
+		case <-l.done:
+			nconn.Close()
+			return
+		}
+	}
+}
+
+func (l *webSocketListener) onRequest(w http.ResponseWriter, r *http.Request) {
+	nconn, err := websocket.NewNetConn(w, r)
+	if err != nil {
+		return
+	}
+
+	select {
+	case l.conns <- nconn:
+	case <-l.done:
+		nconn.Close()
+	}
+}
+
+// Accept implements net.Listener.
+func (l *webSocketListener) Accept() (net.Conn, error) {
+	select {
+	case nconn := <-l.conns:
+		return nconn, nil
+
+	case err := <-l.acceptErr:
+		return nil, err
+
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close implements net.Listener.
+func (l *webSocketListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		l.httpServer.Close()
+		err = l.Listener.Close()
+	})
+	return err
+}
@@ -0,0 +1,73 @@
+package rtsp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/test"
+)
+
+func TestWebSocketListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:8554")
+	require.NoError(t, err)
+
+	wln, err := newWebSocketListener(ln, "127.0.0.1:8555", 10*time.Second, 0, test.NilLogger{})
+	require.NoError(t, err)
+	defer wln.Close()
+
+	t.Run("tcp", func(t *testing.T) {
+		c, err := net.Dial("tcp", "127.0.0.1:8554")
+		require.NoError(t, err)
+		defer c.Close()
+
+		_, err = c.Write([]byte("OPTIONS"))
+		require.NoError(t, err)
+
+		nconn, err := wln.Accept()
+		require.NoError(t, err)
+		defer nconn.Close()
+
+		buf := make([]byte, 7)
+		_, err = nconn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, []byte("OPTIONS"), buf)
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		dialer := *websocket.DefaultDialer
+		dialer.Subprotocols = []string{"binary"}
+
+		c, res, err := dialer.Dial("ws://127.0.0.1:8555/", nil)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		defer c.Close() //nolint:errcheck
+
+		err = c.WriteMessage(websocket.BinaryMessage, []byte("OPTIONS"))
+		require.NoError(t, err)
+
+		nconn, err := wln.Accept()
+		require.NoError(t, err)
+
+		buf := make([]byte, 7)
+		_, err = nconn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, []byte("OPTIONS"), buf)
+
+		_, err = nconn.Write([]byte("RTSP/1.0 200 OK"))
+		require.NoError(t, err)
+
+		typ, byts, err := c.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, websocket.BinaryMessage, typ)
+		require.Equal(t, []byte("RTSP/1.0 200 OK"), byts)
+
+		nconn.Close()
+
+		_, _, err = c.ReadMessage()
+		require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+	})
+}
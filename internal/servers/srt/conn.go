@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
@@ -28,6 +29,8 @@ const (
 	pauseAfterAuthError = 2 * time.Second
 )
 
+var errHandshakeTimeout = errors.New("handshake timed out")
+
 func srtCheckPassphrase(connReq srt.ConnRequest, passphrase string) error {
 	if passphrase == "" {
 		return nil
@@ -58,7 +61,9 @@ type conn struct {
 	readTimeout         conf.StringDuration
 	writeTimeout        conf.StringDuration
 	writeQueueSize      int
+	readerQueueBudget   *asyncwriter.GrowthBudget
 	udpMaxPayloadSize   int
+	handshakeTimeout    conf.StringDuration
 	connReq             srt.ConnRequest
 	runOnConnect        string
 	runOnConnectRestart bool
@@ -80,6 +85,9 @@ type conn struct {
 
 	chNew     chan srtNewConnReq
 	chSetConn chan srt.Conn
+
+	handshakeTimer    *time.Timer
+	handshakeTimedOut atomic.Bool
 }
 
 func (c *conn) initialize() {
@@ -92,10 +100,29 @@ func (c *conn) initialize() {
 
 	c.Log(logger.Info, "opened")
 
+	c.startHandshakeTimer()
+
 	c.wg.Add(1)
 	go c.run()
 }
 
+// startHandshakeTimer closes the connection if the handshake is not completed in time.
+func (c *conn) startHandshakeTimer() {
+	if c.handshakeTimeout != 0 {
+		c.handshakeTimer = time.AfterFunc(time.Duration(c.handshakeTimeout), func() {
+			c.handshakeTimedOut.Store(true)
+			c.ctxCancel()
+		})
+	}
+}
+
+// handshakeComplete is called when the connection starts reading or publishing.
+func (c *conn) handshakeComplete() {
+	if c.handshakeTimer != nil {
+		c.handshakeTimer.Stop()
+	}
+}
+
 func (c *conn) Close() {
 	c.ctxCancel()
 }
@@ -125,6 +152,11 @@ func (c *conn) run() { //nolint:dupl
 
 	err := c.runInner()
 
+	c.handshakeComplete()
+	if c.handshakeTimedOut.Load() {
+		err = errHandshakeTimeout
+	}
+
 	c.ctxCancel()
 
 	c.parent.closeConn(c)
@@ -212,11 +244,11 @@ func (c *conn) runPublish(req srtNewConnReq, streamID *streamID) (bool, error) {
 
 	select {
 	case err := <-readerErr:
-		sconn.Close()
+		c.closeSRTConn(sconn)
 		return true, err
 
 	case <-c.ctx.Done():
-		sconn.Close()
+		c.closeSRTConn(sconn)
 		<-readerErr
 		return true, errors.New("terminated")
 	}
@@ -224,7 +256,10 @@ func (c *conn) runPublish(req srtNewConnReq, streamID *streamID) (bool, error) {
 
 func (c *conn) runPublishReader(sconn srt.Conn, path defs.Path) error {
 	sconn.SetReadDeadline(time.Now().Add(time.Duration(c.readTimeout)))
-	r, err := mcmpegts.NewReader(mcmpegts.NewBufferedReader(sconn))
+
+	scte35 := &mpegts.SCTE35Extractor{R: sconn}
+
+	r, err := mcmpegts.NewReader(mcmpegts.NewBufferedReader(scte35))
 	if err != nil {
 		return err
 	}
@@ -237,7 +272,7 @@ func (c *conn) runPublishReader(sconn srt.Conn, path defs.Path) error {
 
 	var stream *stream.Stream
 
-	medias, err := mpegts.ToStream(r, &stream)
+	medias, err := mpegts.ToStream(r, scte35, &stream)
 	if err != nil {
 		return err
 	}
@@ -251,6 +286,8 @@ func (c *conn) runPublishReader(sconn srt.Conn, path defs.Path) error {
 		return err
 	}
 
+	c.handshakeComplete()
+
 	for {
 		err := r.Read()
 		if err != nil {
@@ -260,6 +297,10 @@ func (c *conn) runPublishReader(sconn srt.Conn, path defs.Path) error {
 }
 
 func (c *conn) runRead(req srtNewConnReq, streamID *streamID) (bool, error) {
+	// the handshake timer must be stopped before waiting for the stream,
+	// that can take up to sourceOnDemandStartTimeout.
+	c.handshakeComplete()
+
 	path, stream, err := c.pathManager.AddReader(defs.PathAddReaderReq{
 		Author: c,
 		AccessRequest: defs.PathAccessRequest{
@@ -293,7 +334,7 @@ func (c *conn) runRead(req srtNewConnReq, streamID *streamID) (bool, error) {
 	if err != nil {
 		return true, err
 	}
-	defer sconn.Close()
+	defer c.closeSRTConn(sconn)
 
 	c.mutex.Lock()
 	c.state = connStateRead
@@ -302,7 +343,7 @@ func (c *conn) runRead(req srtNewConnReq, streamID *streamID) (bool, error) {
 	c.sconn = sconn
 	c.mutex.Unlock()
 
-	writer := asyncwriter.New(c.writeQueueSize, c)
+	writer := asyncwriter.NewReader(path.SafeConf(), c.writeQueueSize, c.readerQueueBudget, c)
 
 	defer stream.RemoveReader(writer)
 
@@ -364,6 +405,16 @@ func (c *conn) new(req srtNewConnReq) *conn {
 	}
 }
 
+// closeSRTConn detaches the SRT connection from the API and then closes it,
+// in order to prevent statistics from being read from a closed socket.
+func (c *conn) closeSRTConn(sconn srt.Conn) {
+	c.mutex.Lock()
+	c.sconn = nil
+	c.mutex.Unlock()
+
+	sconn.Close()
+}
+
 // setConn is called by srtListener .
 func (c *conn) setConn(sconn srt.Conn) {
 	select {
@@ -12,6 +12,7 @@ import (
 	srt "github.com/datarhei/gosrt"
 	"github.com/google/uuid"
 
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
@@ -75,7 +76,11 @@ type Server struct {
 	ReadTimeout         conf.StringDuration
 	WriteTimeout        conf.StringDuration
 	WriteQueueSize      int
+	ReaderQueueBudget   *asyncwriter.GrowthBudget
 	UDPMaxPayloadSize   int
+	DSCP                int
+	HandshakeTimeout    conf.StringDuration
+	MaxConns            int
 	RunOnConnect        string
 	RunOnConnectRestart bool
 	RunOnDisconnect     string
@@ -104,8 +109,23 @@ func (s *Server) Initialize() error {
 	conf.ConnectionTimeout = time.Duration(s.ReadTimeout)
 	conf.PayloadSize = uint32(srtMaxPayloadSize(s.UDPMaxPayloadSize))
 
+	// DSCP is stored in the 6 most significant bits.
+	// The library sets it on IPv4 packets only.
+	conf.IPTOS = s.DSCP << 2
+
 	var err error
 	s.ln, err = srt.Listen("srt", s.Address, conf)
+
+	// retry without DSCP, in case the platform doesn't support it.
+	if err != nil && conf.IPTOS != 0 {
+		conf.IPTOS = 0
+		var err2 error
+		s.ln, err2 = srt.Listen("srt", s.Address, conf)
+		if err2 == nil {
+			s.Log(logger.Warn, "unable to set DSCP: %v", err)
+		}
+		err = err2
+	}
 	if err != nil {
 		return err
 	}
@@ -158,13 +178,21 @@ outer:
 			break outer
 
 		case req := <-s.chNewConnRequest:
+			if s.MaxConns != 0 && len(s.conns) >= s.MaxConns {
+				s.Log(logger.Warn, "rejecting connection from %v: too many connections", req.connReq.RemoteAddr())
+				req.res <- nil
+				continue
+			}
+
 			c := &conn{
 				parentCtx:           s.ctx,
 				rtspAddress:         s.RTSPAddress,
 				readTimeout:         s.ReadTimeout,
 				writeTimeout:        s.WriteTimeout,
 				writeQueueSize:      s.WriteQueueSize,
+				readerQueueBudget:   s.ReaderQueueBudget,
 				udpMaxPayloadSize:   s.UDPMaxPayloadSize,
+				handshakeTimeout:    s.HandshakeTimeout,
 				connReq:             req.connReq,
 				runOnConnect:        s.RunOnConnect,
 				runOnConnectRestart: s.RunOnConnectRestart,
@@ -245,6 +273,9 @@ func (s *Server) newConnRequest(connReq srt.ConnRequest) *conn {
 	select {
 	case s.chNewConnRequest <- req:
 		c := <-req.res
+		if c == nil {
+			return nil
+		}
 
 		return c.new(req)
 
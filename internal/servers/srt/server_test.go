@@ -42,6 +42,7 @@ func (p *dummyPath) StartPublisher(req defs.PathStartPublisherReq) (*stream.Stre
 		1460,
 		req.Desc,
 		true,
+		0,
 		test.NilLogger{},
 	)
 	if err != nil {
@@ -133,7 +134,7 @@ func TestServerPublish(t *testing.T) {
 
 	<-path.streamCreated
 
-	aw := asyncwriter.New(512, &test.NilLogger{})
+	aw := asyncwriter.New(512, conf.ReaderOverflowPolicyDropNewest, &test.NilLogger{})
 
 	recv := make(chan struct{})
 
@@ -161,6 +162,16 @@ func TestServerPublish(t *testing.T) {
 	aw.Start()
 	<-recv
 	aw.Stop()
+
+	list, err := s.APIConnsList()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list.Items))
+
+	item, err := s.APIConnsGet(list.Items[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, defs.APISRTConnStatePublish, item.State)
+	require.NotZero(t, item.PacketsReceived)
+	require.NotZero(t, item.BytesReceived)
 }
 
 func TestServerRead(t *testing.T) {
@@ -178,6 +189,7 @@ func TestServerRead(t *testing.T) {
 		1460,
 		desc,
 		true,
+		0,
 		test.NilLogger{},
 	)
 	require.NoError(t, err)
@@ -265,3 +277,41 @@ func TestServerRead(t *testing.T) {
 		}
 	}
 }
+
+func TestServerHandshakeTimeout(t *testing.T) {
+	path := &dummyPath{
+		streamCreated: make(chan struct{}),
+	}
+
+	s := &Server{
+		Address:           "127.0.0.1:8890",
+		ReadTimeout:       conf.StringDuration(10 * time.Second),
+		WriteTimeout:      conf.StringDuration(10 * time.Second),
+		WriteQueueSize:    512,
+		UDPMaxPayloadSize: 1472,
+		HandshakeTimeout:  conf.StringDuration(300 * time.Millisecond),
+		PathManager:       &dummyPathManager{path: path},
+		Parent:            &test.NilLogger{},
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL("srt://localhost:8890?streamid=publish:mypath")
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	publisher, err := srt.Dial("srt", address, srtConf)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	start := time.Now()
+
+	// the publisher never sends any track.
+	_, err = publisher.Read(make([]byte, 1500))
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 3*time.Second)
+}
@@ -51,15 +51,18 @@ func sessionLocation(publish bool, secret uuid.UUID) string {
 }
 
 type httpServer struct {
-	address        string
-	encryption     bool
-	serverKey      string
-	serverCert     string
-	allowOrigin    string
-	trustedProxies conf.IPsOrCIDRs
-	readTimeout    conf.StringDuration
-	pathManager    defs.PathManager
-	parent         *Server
+	address            string
+	encryption         bool
+	serverKey          string
+	serverCert         string
+	tlsMinVersion      conf.TLSVersion
+	tlsCipherSuites    conf.TLSCipherSuites
+	allowOrigin        string
+	trustedProxies     conf.IPsOrCIDRs
+	readTimeout        conf.StringDuration
+	tcpKeepalivePeriod conf.StringDuration
+	pathManager        defs.PathManager
+	parent             *Server
 
 	inner *httpp.WrappedServer
 }
@@ -85,8 +88,11 @@ func (s *httpServer) initialize() error {
 		network,
 		address,
 		time.Duration(s.readTimeout),
+		time.Duration(s.tcpKeepalivePeriod),
 		s.serverCert,
 		s.serverKey,
+		uint16(s.tlsMinVersion),
+		s.tlsCipherSuites,
 		router,
 		s,
 	)
@@ -15,13 +15,16 @@ import (
 	"strconv"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/pion/logging"
 	pwebrtc "github.com/pion/webrtc/v3"
 
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/dscp"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/webrtc"
@@ -31,7 +34,7 @@ import (
 const (
 	pauseAfterAuthError        = 2 * time.Second
 	webrtcTurnSecretExpiration = 24 * 3600 * time.Second
-	webrtcPayloadMaxSize       = 1188 // 1200 - 12 (RTP header)
+	webrtcDataMaxSize          = 65535
 )
 
 // ErrSessionNotFound is returned when a session is not found.
@@ -126,6 +129,16 @@ type serverAPISessionsKickReq struct {
 	res  chan serverAPISessionsKickRes
 }
 
+type serverAPIPathsSendDataRes struct {
+	err error
+}
+
+type serverAPIPathsSendDataReq struct {
+	pathName string
+	msg      dataChannelMessage
+	res      chan serverAPIPathsSendDataRes
+}
+
 type webRTCNewSessionRes struct {
 	sx            *session
 	answer        []byte
@@ -174,16 +187,30 @@ type Server struct {
 	Encryption            bool
 	ServerKey             string
 	ServerCert            string
+	TLSMinVersion         conf.TLSVersion
+	TLSCipherSuites       conf.TLSCipherSuites
 	AllowOrigin           string
 	TrustedProxies        conf.IPsOrCIDRs
 	ReadTimeout           conf.StringDuration
+	TCPKeepalivePeriod    conf.StringDuration
 	WriteQueueSize        int
+	ReaderQueueBudget     *asyncwriter.GrowthBudget
+	DSCP                  int
 	LocalUDPAddress       string
+	LocalUDPPortRange     conf.PortRange
 	LocalTCPAddress       string
 	IPsFromInterfaces     bool
 	IPsFromInterfacesList []string
 	AdditionalHosts       []string
+	ICEInterfaceFilter    []string
+	ICEUDPMuxOnly         bool
+	ICEDisableMDNS        bool
+	ICEDisconnectTimeout  conf.StringDuration
+	ICERecoveryTimeout    conf.StringDuration
 	ICEServers            []conf.WebRTCICEServer
+	NACKBufferSize        int
+	NACKMaxAge            conf.StringDuration
+	MaxPayloadSize        int
 	ExternalCmdPool       *externalcmd.Pool
 	PathManager           defs.PathManager
 	Parent                serverParent
@@ -194,6 +221,7 @@ type Server struct {
 	udpMuxLn         net.PacketConn
 	tcpMuxLn         net.Listener
 	api              *pwebrtc.API
+	bwe              *webrtc.BandwidthEstimation
 	sessions         map[*session]struct{}
 	sessionsBySecret map[uuid.UUID]*session
 
@@ -205,6 +233,7 @@ type Server struct {
 	chAPISessionsList      chan serverAPISessionsListReq
 	chAPISessionsGet       chan serverAPISessionsGetReq
 	chAPIConnsKick         chan serverAPISessionsKickReq
+	chAPIPathsSendData     chan serverAPIPathsSendDataReq
 
 	// out
 	done chan struct{}
@@ -225,18 +254,22 @@ func (s *Server) Initialize() error {
 	s.chAPISessionsList = make(chan serverAPISessionsListReq)
 	s.chAPISessionsGet = make(chan serverAPISessionsGetReq)
 	s.chAPIConnsKick = make(chan serverAPISessionsKickReq)
+	s.chAPIPathsSendData = make(chan serverAPIPathsSendDataReq)
 	s.done = make(chan struct{})
 
 	s.httpServer = &httpServer{
-		address:        s.Address,
-		encryption:     s.Encryption,
-		serverKey:      s.ServerKey,
-		serverCert:     s.ServerCert,
-		allowOrigin:    s.AllowOrigin,
-		trustedProxies: s.TrustedProxies,
-		readTimeout:    s.ReadTimeout,
-		pathManager:    s.PathManager,
-		parent:         s,
+		address:            s.Address,
+		encryption:         s.Encryption,
+		serverKey:          s.ServerKey,
+		serverCert:         s.ServerCert,
+		tlsMinVersion:      s.TLSMinVersion,
+		tlsCipherSuites:    s.TLSCipherSuites,
+		allowOrigin:        s.AllowOrigin,
+		trustedProxies:     s.TrustedProxies,
+		readTimeout:        s.ReadTimeout,
+		tcpKeepalivePeriod: s.TCPKeepalivePeriod,
+		pathManager:        s.PathManager,
+		parent:             s,
 	}
 	err := s.httpServer.initialize()
 	if err != nil {
@@ -244,11 +277,28 @@ func (s *Server) Initialize() error {
 		return err
 	}
 
+	s.bwe, err = webrtc.NewBandwidthEstimation()
+	if err != nil {
+		s.httpServer.close()
+		ctxCancel()
+		return err
+	}
+
 	apiConf := webrtc.APIConf{
 		LocalRandomUDP:        false,
+		LocalUDPPortMin:       s.LocalUDPPortRange.Min,
+		LocalUDPPortMax:       s.LocalUDPPortRange.Max,
 		IPsFromInterfaces:     s.IPsFromInterfaces,
 		IPsFromInterfacesList: s.IPsFromInterfacesList,
 		AdditionalHosts:       s.AdditionalHosts,
+		InterfaceFilter:       s.ICEInterfaceFilter,
+		UDPMuxOnly:            s.ICEUDPMuxOnly,
+		DisableMDNS:           s.ICEDisableMDNS,
+		ICEDisconnectTimeout:  time.Duration(s.ICEDisconnectTimeout),
+		NACKBufferSize:        s.NACKBufferSize,
+		NACKMaxAge:            time.Duration(s.NACKMaxAge),
+		BandwidthEstimation:   s.bwe,
+		DSCP:                  s.DSCP,
 	}
 
 	if s.LocalUDPAddress != "" {
@@ -258,6 +308,12 @@ func (s *Server) Initialize() error {
 			ctxCancel()
 			return err
 		}
+
+		err = dscp.SetPacketConn(s.udpMuxLn, s.DSCP)
+		if err != nil {
+			s.Log(logger.Warn, "%v", err)
+		}
+
 		apiConf.ICEUDPMux = pwebrtc.NewICEUDPMux(webrtcNilLogger, s.udpMuxLn)
 	}
 
@@ -284,6 +340,8 @@ func (s *Server) Initialize() error {
 	str := "listener opened on " + s.Address + " (HTTP)"
 	if s.udpMuxLn != nil {
 		str += ", " + s.LocalUDPAddress + " (ICE/UDP)"
+	} else if !s.LocalUDPPortRange.IsEmpty() {
+		str += fmt.Sprintf(", %d-%d (ICE/UDP)", s.LocalUDPPortRange.Min, s.LocalUDPPortRange.Max)
 	}
 	if s.tcpMuxLn != nil {
 		str += ", " + s.LocalTCPAddress + " (ICE/TCP)"
@@ -317,14 +375,15 @@ outer:
 		select {
 		case req := <-s.chNewSession:
 			sx := &session{
-				parentCtx:       s.ctx,
-				writeQueueSize:  s.WriteQueueSize,
-				api:             s.api,
-				req:             req,
-				wg:              &wg,
-				externalCmdPool: s.ExternalCmdPool,
-				pathManager:     s.PathManager,
-				parent:          s,
+				parentCtx:         s.ctx,
+				writeQueueSize:    s.WriteQueueSize,
+				readerQueueBudget: s.ReaderQueueBudget,
+				api:               s.api,
+				req:               req,
+				wg:                &wg,
+				externalCmdPool:   s.ExternalCmdPool,
+				pathManager:       s.PathManager,
+				parent:            s,
 			}
 			sx.initialize()
 			s.sessions[sx] = struct{}{}
@@ -394,6 +453,15 @@ outer:
 
 			req.res <- serverAPISessionsKickRes{}
 
+		case req := <-s.chAPIPathsSendData:
+			for sx := range s.sessions {
+				if !sx.req.publish && sx.req.pathName == req.pathName {
+					sx.sendData(req.msg)
+				}
+			}
+
+			req.res <- serverAPIPathsSendDataRes{}
+
 		case <-s.ctx.Done():
 			break outer
 		}
@@ -423,6 +491,15 @@ func (s *Server) findSessionByUUID(uuid uuid.UUID) *session {
 	return nil
 }
 
+// generateLocalICEServers returns ICE servers used by server-side peer connections.
+func (s *Server) generateLocalICEServers() ([]pwebrtc.ICEServer, error) {
+	// in UDP mux only mode, server reflexive and relay candidates must not be gathered.
+	if s.ICEUDPMuxOnly {
+		return nil, nil
+	}
+	return s.generateICEServers()
+}
+
 func (s *Server) generateICEServers() ([]pwebrtc.ICEServer, error) {
 	ret := make([]pwebrtc.ICEServer, len(s.ICEServers))
 
@@ -560,3 +637,32 @@ func (s *Server) APISessionsKick(uuid uuid.UUID) error {
 		return fmt.Errorf("terminated")
 	}
 }
+
+// APIPathsSendData is called by api.
+func (s *Server) APIPathsSendData(pathName string, data []byte, isBinary bool) error {
+	if len(data) > webrtcDataMaxSize {
+		return fmt.Errorf("message is too big, maximum size is %d bytes", webrtcDataMaxSize)
+	}
+
+	if !isBinary && !utf8.Valid(data) {
+		return fmt.Errorf("message is not valid UTF-8 text")
+	}
+
+	req := serverAPIPathsSendDataReq{
+		pathName: pathName,
+		msg: dataChannelMessage{
+			data:     data,
+			isBinary: isBinary,
+		},
+		res: make(chan serverAPIPathsSendDataRes),
+	}
+
+	select {
+	case s.chAPIPathsSendData <- req:
+		res := <-req.res
+		return res.err
+
+	case <-s.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -27,11 +28,46 @@ import (
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/hooks"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	mtxsdp "github.com/bluenviron/mediamtx/internal/protocols/sdp"
 	"github.com/bluenviron/mediamtx/internal/protocols/webrtc"
 	"github.com/bluenviron/mediamtx/internal/stream"
 	"github.com/bluenviron/mediamtx/internal/unit"
 )
 
+const (
+	dataChannelQueueSize = 64
+
+	// messages are discarded when the data channel has more than this amount of
+	// unsent bytes, in order to avoid accumulating data for slow readers.
+	dataChannelMaxBufferedAmount = 1024 * 1024
+
+	bandwidthEstimatePeriod = 2 * time.Second
+
+	// the estimate is reported only when it differs from the previous
+	// reported one by more than this ratio, in order to avoid flapping.
+	bandwidthEstimateHysteresis = 0.2
+)
+
+// bandwidthEstimateChanged returns whether a new bandwidth estimate
+// is different enough from the previous one.
+func bandwidthEstimateChanged(prev uint64, cur uint64) bool {
+	if prev == 0 {
+		return cur != 0
+	}
+
+	diff := float64(cur) - float64(prev)
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return (diff / float64(prev)) > bandwidthEstimateHysteresis
+}
+
+type dataChannelMessage struct {
+	data     []byte
+	isBinary bool
+}
+
 var errNoSupportedCodecs = errors.New(
 	"the stream doesn't contain any supported codec, which are currently AV1, VP9, VP8, H264, Opus, G722, G711")
 
@@ -40,7 +76,11 @@ type setupStreamFunc func(*webrtc.OutgoingTrack) error
 func findVideoTrack(
 	stream *stream.Stream,
 	writer *asyncwriter.Writer,
+	maxPayloadSize int,
 ) (format.Format, setupStreamFunc) {
+	// RTP header is not included in the payload
+	payloadMaxSize := maxPayloadSize - 12
+
 	var av1Format *format.AV1
 	media := stream.Desc().FindFormat(&av1Format)
 
@@ -48,7 +88,7 @@ func findVideoTrack(
 		return av1Format, func(track *webrtc.OutgoingTrack) error {
 			encoder := &rtpav1.Encoder{
 				PayloadType:    105,
-				PayloadMaxSize: webrtcPayloadMaxSize,
+				PayloadMaxSize: payloadMaxSize,
 			}
 			err := encoder.Init()
 			if err != nil {
@@ -86,7 +126,7 @@ func findVideoTrack(
 		return vp9Format, func(track *webrtc.OutgoingTrack) error {
 			encoder := &rtpvp9.Encoder{
 				PayloadType:    96,
-				PayloadMaxSize: webrtcPayloadMaxSize,
+				PayloadMaxSize: payloadMaxSize,
 			}
 			err := encoder.Init()
 			if err != nil {
@@ -124,7 +164,7 @@ func findVideoTrack(
 		return vp8Format, func(track *webrtc.OutgoingTrack) error {
 			encoder := &rtpvp8.Encoder{
 				PayloadType:    96,
-				PayloadMaxSize: webrtcPayloadMaxSize,
+				PayloadMaxSize: payloadMaxSize,
 			}
 			err := encoder.Init()
 			if err != nil {
@@ -162,7 +202,7 @@ func findVideoTrack(
 		return h264Format, func(track *webrtc.OutgoingTrack) error {
 			encoder := &rtph264.Encoder{
 				PayloadType:    96,
-				PayloadMaxSize: webrtcPayloadMaxSize,
+				PayloadMaxSize: payloadMaxSize,
 			}
 			err := encoder.Init()
 			if err != nil {
@@ -277,14 +317,15 @@ func whipOffer(body []byte) *pwebrtc.SessionDescription {
 }
 
 type session struct {
-	parentCtx       context.Context
-	writeQueueSize  int
-	api             *pwebrtc.API
-	req             webRTCNewSessionReq
-	wg              *sync.WaitGroup
-	externalCmdPool *externalcmd.Pool
-	pathManager     defs.PathManager
-	parent          *Server
+	parentCtx         context.Context
+	writeQueueSize    int
+	readerQueueBudget *asyncwriter.GrowthBudget
+	api               *pwebrtc.API
+	req               webRTCNewSessionReq
+	wg                *sync.WaitGroup
+	externalCmdPool   *externalcmd.Pool
+	pathManager       defs.PathManager
+	parent            *Server
 
 	ctx       context.Context
 	ctxCancel func()
@@ -293,9 +334,17 @@ type session struct {
 	secret    uuid.UUID
 	mutex     sync.RWMutex
 	pc        *webrtc.PeerConnection
+	offer     string
+	answer    string
+
+	// last reported bandwidth estimate of the reader
+	bandwidthEstimate uint64
 
 	chNew           chan webRTCNewSessionReq
 	chAddCandidates chan webRTCAddSessionCandidatesReq
+	chSendData      chan dataChannelMessage
+
+	dataDiscardLogger logger.Writer
 }
 
 func (s *session) initialize() {
@@ -308,6 +357,8 @@ func (s *session) initialize() {
 	s.secret = uuid.New()
 	s.chNew = make(chan webRTCNewSessionReq)
 	s.chAddCandidates = make(chan webRTCAddSessionCandidatesReq)
+	s.chSendData = make(chan dataChannelMessage, dataChannelQueueSize)
+	s.dataDiscardLogger = logger.NewLimitedLogger(s)
 
 	s.Log(logger.Info, "created by %s", s.req.remoteAddr)
 
@@ -393,16 +444,17 @@ func (s *session) runPublish() (int, error) {
 
 	defer path.RemovePublisher(defs.PathRemovePublisherReq{Author: s})
 
-	iceServers, err := s.parent.generateICEServers()
+	iceServers, err := s.parent.generateLocalICEServers()
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
 
 	pc := &webrtc.PeerConnection{
-		ICEServers: iceServers,
-		API:        s.api,
-		Publish:    false,
-		Log:        s,
+		ICEServers:      iceServers,
+		API:             s.api,
+		Publish:         false,
+		Log:             s,
+		RecoveryTimeout: time.Duration(s.parent.ICERecoveryTimeout),
 	}
 	err = pc.Start()
 	if err != nil {
@@ -495,9 +547,30 @@ func (s *session) runPublish() (int, error) {
 	}
 }
 
+func rewindFromQuery(rawQuery string) (time.Duration, error) {
+	query, _ := url.ParseQuery(rawQuery)
+
+	v := query.Get("rewind")
+	if v == "" {
+		return 0, nil
+	}
+
+	rewind, err := time.ParseDuration(v)
+	if err != nil || rewind < 0 {
+		return 0, fmt.Errorf("invalid rewind '%s'", v)
+	}
+
+	return rewind, nil
+}
+
 func (s *session) runRead() (int, error) {
 	ip, _, _ := net.SplitHostPort(s.req.remoteAddr)
 
+	rewind, err := rewindFromQuery(s.req.query)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
 	path, stream, err := s.pathManager.AddReader(defs.PathAddReaderReq{
 		Author: s,
 		AccessRequest: defs.PathAccessRequest{
@@ -527,16 +600,18 @@ func (s *session) runRead() (int, error) {
 
 	defer path.RemoveReader(defs.PathRemoveReaderReq{Author: s})
 
-	iceServers, err := s.parent.generateICEServers()
+	iceServers, err := s.parent.generateLocalICEServers()
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
 
 	pc := &webrtc.PeerConnection{
-		ICEServers: iceServers,
-		API:        s.api,
-		Publish:    false,
-		Log:        s,
+		ICEServers:          iceServers,
+		API:                 s.api,
+		BandwidthEstimation: s.parent.bwe,
+		Publish:             false,
+		Log:                 s,
+		RecoveryTimeout:     time.Duration(s.parent.ICERecoveryTimeout),
 	}
 	err = pc.Start()
 	if err != nil {
@@ -544,9 +619,9 @@ func (s *session) runRead() (int, error) {
 	}
 	defer pc.Close()
 
-	writer := asyncwriter.New(s.writeQueueSize, s)
+	writer := asyncwriter.NewReader(path.SafeConf(), s.writeQueueSize, s.readerQueueBudget, s)
 
-	videoTrack, videoSetup := findVideoTrack(stream, writer)
+	videoTrack, videoSetup := findVideoTrack(stream, writer, s.parent.MaxPayloadSize)
 	audioTrack, audioSetup := findAudioTrack(stream, writer)
 
 	if videoTrack == nil && audioTrack == nil {
@@ -597,6 +672,12 @@ func (s *session) runRead() (int, error) {
 		}
 	}
 
+	if rewind > 0 {
+		stream.ReplayToReader(writer, rewind)
+	} else if path.SafeConf().ReaderInstantStart {
+		stream.ReplayToReader(writer, 0)
+	}
+
 	s.Log(logger.Info, "is reading from path '%s', %s",
 		path.Name(), defs.FormatsInfo(stream.FormatsForReader(writer)))
 
@@ -612,21 +693,71 @@ func (s *session) runRead() (int, error) {
 
 	writer.Start()
 
-	select {
-	case <-pc.Disconnected():
-		writer.Stop()
-		return 0, fmt.Errorf("peer connection closed")
+	bandwidthTicker := time.NewTicker(bandwidthEstimatePeriod)
+	defer bandwidthTicker.Stop()
 
-	case err := <-writer.Error():
-		return 0, err
+	for {
+		select {
+		case <-bandwidthTicker.C:
+			s.updateBandwidthEstimate(pc)
 
-	case <-s.ctx.Done():
-		writer.Stop()
-		return 0, fmt.Errorf("terminated")
+		case msg := <-s.chSendData:
+			s.writeDataChannel(pc, msg)
+
+		case <-pc.Disconnected():
+			writer.Stop()
+			return 0, fmt.Errorf("peer connection closed")
+
+		case err := <-writer.Error():
+			return 0, err
+
+		case <-s.ctx.Done():
+			writer.Stop()
+			return 0, fmt.Errorf("terminated")
+		}
+	}
+}
+
+func (s *session) updateBandwidthEstimate(pc *webrtc.PeerConnection) {
+	cur := pc.BandwidthEstimate()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if bandwidthEstimateChanged(s.bandwidthEstimate, cur) {
+		s.Log(logger.Debug, "estimated bandwidth: %d bit/s", cur)
+		s.bandwidthEstimate = cur
+	}
+}
+
+func (s *session) writeDataChannel(pc *webrtc.PeerConnection, msg dataChannelMessage) {
+	dc := pc.DataChannel()
+	if dc == nil || dc.ReadyState() != pwebrtc.DataChannelStateOpen {
+		return
+	}
+
+	if dc.BufferedAmount() > dataChannelMaxBufferedAmount {
+		s.dataDiscardLogger.Log(logger.Warn, "data channel is too slow, discarding message")
+		return
+	}
+
+	var err error
+	if msg.isBinary {
+		err = dc.Send(msg.data)
+	} else {
+		err = dc.SendText(string(msg.data))
+	}
+	if err != nil {
+		s.dataDiscardLogger.Log(logger.Warn, "unable to write to data channel: %v", err)
 	}
 }
 
 func (s *session) writeAnswer(answer *pwebrtc.SessionDescription) {
+	s.mutex.Lock()
+	s.offer = mtxsdp.Redact(string(s.req.offer))
+	s.answer = mtxsdp.Redact(answer.SDP)
+	s.mutex.Unlock()
+
 	s.req.res <- webRTCNewSessionRes{
 		sx:     s,
 		answer: []byte(answer.SDP),
@@ -651,6 +782,16 @@ func (s *session) readRemoteCandidates(pc *webrtc.PeerConnection) {
 	}
 }
 
+// sendData is called by Server.
+// It never blocks, in order not to stall the server and other sessions.
+func (s *session) sendData(msg dataChannelMessage) {
+	select {
+	case s.chSendData <- msg:
+	default:
+		s.dataDiscardLogger.Log(logger.Warn, "data channel queue is full, discarding message")
+	}
+}
+
 // new is called by webRTCHTTPServer through Server.
 func (s *session) new(req webRTCNewSessionReq) webRTCNewSessionRes {
 	select {
@@ -719,9 +860,12 @@ func (s *session) apiItem() *defs.APIWebRTCSession {
 			}
 			return defs.APIWebRTCSessionStateRead
 		}(),
-		Path:          s.req.pathName,
-		Query:         s.req.query,
-		BytesReceived: bytesReceived,
-		BytesSent:     bytesSent,
+		Path:              s.req.pathName,
+		Query:             s.req.query,
+		Offer:             s.offer,
+		Answer:            s.answer,
+		BytesReceived:     bytesReceived,
+		BytesSent:         bytesSent,
+		BandwidthEstimate: s.bandwidthEstimate,
 	}
 }
@@ -0,0 +1,100 @@
+// Package staticsources contains static sources.
+package staticsources
+
+import (
+	hlssource "github.com/bluenviron/mediamtx/internal/staticsources/hls"
+	onvifsource "github.com/bluenviron/mediamtx/internal/staticsources/onvif"
+	rpicamerasource "github.com/bluenviron/mediamtx/internal/staticsources/rpicamera"
+	rtmpsource "github.com/bluenviron/mediamtx/internal/staticsources/rtmp"
+	rtspsource "github.com/bluenviron/mediamtx/internal/staticsources/rtsp"
+	srtsource "github.com/bluenviron/mediamtx/internal/staticsources/srt"
+	udpsource "github.com/bluenviron/mediamtx/internal/staticsources/udp"
+	webrtcsource "github.com/bluenviron/mediamtx/internal/staticsources/webrtc"
+	"github.com/bluenviron/mediamtx/pkg/staticsource"
+)
+
+func mustRegister(factory staticsource.Factory, schemes ...string) {
+	err := staticsource.Register(factory, schemes...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func init() {
+	mustRegister(func(p staticsource.Params) staticsource.StaticSource {
+		return &rtspsource.Source{
+			ResolvedSource: p.ResolvedSource,
+			ReadTimeout:    p.ReadTimeout,
+			WriteTimeout:   p.WriteTimeout,
+			WriteQueueSize: p.WriteQueueSize,
+			Parent:         p.Parent,
+		}
+	}, "rtsp", "rtsps")
+
+	mustRegister(func(p staticsource.Params) staticsource.StaticSource {
+		return &rtmpsource.Source{
+			ResolvedSource: p.ResolvedSource,
+			ReadTimeout:    p.ReadTimeout,
+			WriteTimeout:   p.WriteTimeout,
+			Parent:         p.Parent,
+		}
+	}, "rtmp", "rtmps")
+
+	mustRegister(func(p staticsource.Params) staticsource.StaticSource {
+		return &webrtcsource.Source{
+			ResolvedSource: p.ResolvedSource,
+			ReadTimeout:    p.ReadTimeout,
+			Parent:         p.Parent,
+		}
+	}, "whep", "wheps")
+
+	// HTTP URLs that point to a WHEP endpoint are read with the WebRTC source.
+	mustRegister(func(p staticsource.Params) staticsource.StaticSource {
+		if webrtcsource.IsWHEPURL(p.ResolvedSource) {
+			return &webrtcsource.Source{
+				ResolvedSource: p.ResolvedSource,
+				ReadTimeout:    p.ReadTimeout,
+				Parent:         p.Parent,
+			}
+		}
+
+		return &hlssource.Source{
+			ResolvedSource: p.ResolvedSource,
+			ReadTimeout:    p.ReadTimeout,
+			Parent:         p.Parent,
+		}
+	}, "http", "https")
+
+	mustRegister(func(p staticsource.Params) staticsource.StaticSource {
+		return &udpsource.Source{
+			ResolvedSource: p.ResolvedSource,
+			ReadTimeout:    p.ReadTimeout,
+			Parent:         p.Parent,
+		}
+	}, "udp")
+
+	mustRegister(func(p staticsource.Params) staticsource.StaticSource {
+		return &srtsource.Source{
+			ResolvedSource: p.ResolvedSource,
+			ReadTimeout:    p.ReadTimeout,
+			Parent:         p.Parent,
+		}
+	}, "srt")
+
+	mustRegister(func(p staticsource.Params) staticsource.StaticSource {
+		return &onvifsource.Source{
+			ResolvedSource: p.ResolvedSource,
+			ReadTimeout:    p.ReadTimeout,
+			WriteTimeout:   p.WriteTimeout,
+			WriteQueueSize: p.WriteQueueSize,
+			Parent:         p.Parent,
+		}
+	}, "onvif", "onvifs")
+
+	mustRegister(func(p staticsource.Params) staticsource.StaticSource {
+		return &rpicamerasource.Source{
+			LogLevel: p.LogLevel,
+			Parent:   p.Parent,
+		}
+	}, "rpiCamera")
+}
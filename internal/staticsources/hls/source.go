@@ -2,6 +2,7 @@
 package hls
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -18,6 +19,27 @@ import (
 	"github.com/bluenviron/mediamtx/internal/unit"
 )
 
+// headersTransport adds the configured headers to every request.
+type headersTransport struct {
+	http.RoundTripper
+	userAgent string
+	headers   conf.Headers
+}
+
+func (t *headersTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	return t.RoundTripper.RoundTrip(req)
+}
+
 // Source is a HLS static source.
 type Source struct {
 	ResolvedSource string
@@ -42,13 +64,36 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 
 	decodeErrLogger := logger.NewLimitedLogger(s)
 
+	// closed when tracks have been read from the first playlists and segments.
+	connected := make(chan struct{})
+
+	var connectTimeout <-chan time.Time
+	if params.Conf.SourceConnectTimeout != 0 {
+		connectTimer := time.NewTimer(time.Duration(params.Conf.SourceConnectTimeout))
+		defer connectTimer.Stop()
+		connectTimeout = connectTimer.C
+	}
+
+	// connections are kept alive and shared by all requests,
+	// while ReadTimeout is applied to each request.
+	tr := &http.Transport{
+		TLSClientConfig:     tls.ConfigForFingerprint(params.Conf.SourceFingerprint),
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        params.Conf.HLSSourceMaxIdleConns,
+		MaxIdleConnsPerHost: params.Conf.HLSSourceMaxIdleConns,
+		IdleConnTimeout:     time.Duration(params.Conf.HLSSourceIdleConnTimeout),
+	}
+	defer tr.CloseIdleConnections()
+
 	var c *gohlslib.Client
 	c = &gohlslib.Client{
 		URI: s.ResolvedSource,
 		HTTPClient: &http.Client{
 			Timeout: time.Duration(s.ReadTimeout),
-			Transport: &http.Transport{
-				TLSClientConfig: tls.ConfigForFingerprint(params.Conf.SourceFingerprint),
+			Transport: &headersTransport{
+				RoundTripper: tr,
+				userAgent:    params.Conf.SourceUserAgent,
+				headers:      params.Conf.SourceExtraHeaders,
 			},
 		},
 		OnDownloadPrimaryPlaylist: func(u string) {
@@ -205,6 +250,7 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 			}
 
 			stream = res.Stream
+			close(connected)
 
 			return nil
 		},
@@ -221,6 +267,15 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 			c.Close()
 			return err
 
+		case <-connected:
+			connected = nil
+			connectTimeout = nil
+
+		case <-connectTimeout:
+			c.Close()
+			<-c.Wait()
+			return fmt.Errorf("source did not connect within %v", time.Duration(params.Conf.SourceConnectTimeout))
+
 		case <-params.ReloadConf:
 
 		case <-params.Context.Done():
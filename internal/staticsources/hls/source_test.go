@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"sync/atomic"
 	"testing"
 
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
@@ -39,6 +40,11 @@ func TestSource(t *testing.T) {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 
+	router.Use(func(ctx *gin.Context) {
+		require.Equal(t, "myagent", ctx.Request.Header.Get("User-Agent"))
+		require.Equal(t, "myvalue", ctx.Request.Header.Get("X-My-Header"))
+	})
+
 	router.GET("/stream.m3u8", func(ctx *gin.Context) {
 		ctx.Writer.Header().Set("Content-Type", `application/vnd.apple.mpegurl`)
 		ctx.Writer.Write([]byte("#EXTM3U\n" +
@@ -82,7 +88,16 @@ func TestSource(t *testing.T) {
 	ln, err := net.Listen("tcp", "localhost:5780")
 	require.NoError(t, err)
 
-	s := &http.Server{Handler: router}
+	var connCount int32
+
+	s := &http.Server{
+		Handler: router,
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&connCount, 1)
+			}
+		},
+	}
 	go s.Serve(ln)
 	defer s.Shutdown(context.Background())
 
@@ -93,9 +108,18 @@ func TestSource(t *testing.T) {
 				Parent:         p,
 			}
 		},
-		&conf.Path{},
+		&conf.Path{
+			SourceUserAgent: "myagent",
+			SourceExtraHeaders: conf.Headers{
+				"X-My-Header": "myvalue",
+			},
+			HLSSourceMaxIdleConns: 10,
+		},
 	)
 	defer te.Close()
 
 	<-te.Unit
+
+	// the playlist and segments are downloaded with the same connection.
+	require.Equal(t, int32(1), atomic.LoadInt32(&connCount))
 }
@@ -0,0 +1,146 @@
+// Package onvif contains the ONVIF static source.
+package onvif
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/onvif"
+	"github.com/bluenviron/mediamtx/internal/protocols/tls"
+	rtspsource "github.com/bluenviron/mediamtx/internal/staticsources/rtsp"
+)
+
+// adds credentials to the stream URI, since devices usually return URIs without them.
+// RTSP authentication (Basic or Digest) is then performed by the RTSP client.
+func addCredentials(uri string, user *url.Userinfo) (string, error) {
+	u, err := base.ParseURL(uri)
+	if err != nil {
+		return "", fmt.Errorf("device returned an invalid stream URI: %w", err)
+	}
+
+	if u.User == nil && user != nil {
+		u.User = user
+	}
+
+	return u.String(), nil
+}
+
+// redactURI removes credentials from a URI.
+func redactURI(uri string) string {
+	u, err := base.ParseURL(uri)
+	if err != nil {
+		return uri
+	}
+	u.User = nil
+	return u.String()
+}
+
+// Source is a ONVIF static source.
+// The RTSP URI of the stream is obtained with the GetStreamUri call,
+// then the stream is read with the RTSP static source.
+type Source struct {
+	ResolvedSource string
+	ReadTimeout    conf.StringDuration
+	WriteTimeout   conf.StringDuration
+	WriteQueueSize int
+	Parent         defs.StaticSourceParent
+}
+
+// Log implements logger.Writer.
+func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
+	s.Parent.Log(level, "[ONVIF source] "+format, args...)
+}
+
+// SetReady implements defs.StaticSourceParent.
+func (s *Source) SetReady(req defs.PathSourceStaticSetReadyReq) defs.PathSourceStaticSetReadyRes {
+	return s.Parent.SetReady(req)
+}
+
+// SetNotReady implements defs.StaticSourceParent.
+func (s *Source) SetNotReady(req defs.PathSourceStaticSetNotReadyReq) {
+	s.Parent.SetNotReady(req)
+}
+
+// Run implements StaticSource.
+func (s *Source) Run(params defs.StaticSourceRunParams) error {
+	u, err := url.Parse(s.ResolvedSource)
+	if err != nil {
+		return err
+	}
+
+	// the URI is resolved every time the source starts,
+	// since it may change after a device reboot or reconfiguration.
+	uri, err := s.resolveStreamURI(params, u)
+	if err != nil {
+		return err
+	}
+
+	s.Log(logger.Debug, "stream URI is %s", redactURI(uri))
+
+	rs := &rtspsource.Source{
+		ResolvedSource: uri,
+		ReadTimeout:    s.ReadTimeout,
+		WriteTimeout:   s.WriteTimeout,
+		WriteQueueSize: s.WriteQueueSize,
+		Parent:         s,
+	}
+
+	return rs.Run(params)
+}
+
+func (s *Source) resolveStreamURI(params defs.StaticSourceRunParams, u *url.URL) (string, error) {
+	s.Log(logger.Debug, "requesting stream URI")
+
+	user := ""
+	pass := ""
+	if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+	}
+
+	deviceURL := &url.URL{
+		Scheme: "http",
+		Host:   u.Host,
+	}
+	if u.Scheme == "onvifs" {
+		deviceURL.Scheme = "https"
+	}
+
+	client := &onvif.Client{
+		HTTPClient: &http.Client{
+			Timeout: time.Duration(s.ReadTimeout),
+			Transport: &http.Transport{
+				TLSClientConfig: tls.ConfigForFingerprint(params.Conf.SourceFingerprint),
+			},
+		},
+		URL:  deviceURL,
+		User: user,
+		Pass: pass,
+	}
+
+	ctx, ctxCancel := context.WithTimeout(params.Context, time.Duration(s.ReadTimeout))
+	defer ctxCancel()
+
+	uri, err := client.GetStreamURI(ctx, u.Query().Get("profile"))
+	if err != nil {
+		return "", err
+	}
+
+	return addCredentials(uri, u.User)
+}
+
+// APISourceDescribe implements StaticSource.
+func (*Source) APISourceDescribe() defs.APIPathSourceOrReader {
+	return defs.APIPathSourceOrReader{
+		Type: "onvifSource",
+		ID:   "",
+	}
+}
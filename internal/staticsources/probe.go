@@ -0,0 +1,115 @@
+package staticsources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/pkg/staticsource"
+)
+
+var errProbeCompleted = errors.New("probe completed")
+
+// probeParent is the parent of a static source that is being probed.
+// The source is stopped as soon as it becomes ready.
+type probeParent struct {
+	parent logger.Writer
+	desc   chan *description.Session
+}
+
+// Log implements logger.Writer.
+func (p *probeParent) Log(level logger.Level, format string, args ...interface{}) {
+	p.parent.Log(level, "[probe] "+format, args...)
+}
+
+// SetReady implements defs.StaticSourceParent.
+func (p *probeParent) SetReady(req defs.PathSourceStaticSetReadyReq) defs.PathSourceStaticSetReadyRes {
+	select {
+	case p.desc <- req.Desc:
+	default:
+	}
+
+	return defs.PathSourceStaticSetReadyRes{Err: errProbeCompleted}
+}
+
+// SetNotReady implements defs.StaticSourceParent.
+func (p *probeParent) SetNotReady(_ defs.PathSourceStaticSetNotReadyReq) {
+}
+
+// ProbeParams are the parameters of Probe.
+type ProbeParams struct {
+	ResolvedSource string
+	ReadTimeout    conf.StringDuration
+	WriteTimeout   conf.StringDuration
+	WriteQueueSize int
+	Conf           *conf.Path
+	Timeout        time.Duration
+	Parent         logger.Writer
+}
+
+// Probe connects to a static source, returns the description of its stream and disconnects.
+// The source is not attached to any path.
+func Probe(ctx context.Context, p ProbeParams) (*description.Session, error) {
+	parent := &probeParent{
+		parent: p.Parent,
+		desc:   make(chan *description.Session, 1),
+	}
+
+	s := staticsource.New(staticsource.Params{
+		ResolvedSource: p.ResolvedSource,
+		ReadTimeout:    p.ReadTimeout,
+		WriteTimeout:   p.WriteTimeout,
+		WriteQueueSize: p.WriteQueueSize,
+		Parent:         parent,
+	})
+	if s == nil {
+		return nil, fmt.Errorf("unsupported source: '%s'", p.ResolvedSource)
+	}
+
+	ctx, ctxCancel := context.WithTimeout(ctx, p.Timeout)
+	defer ctxCancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run(defs.StaticSourceRunParams{
+			Context: ctx,
+			Conf:    p.Conf,
+		})
+	}()
+
+	select {
+	case desc := <-parent.desc:
+		ctxCancel()
+		<-runErr
+		return desc, nil
+
+	case err := <-runErr:
+		select {
+		case desc := <-parent.desc:
+			return desc, nil
+		default:
+		}
+
+		if err == nil {
+			err = fmt.Errorf("source did not provide any stream within %v", p.Timeout)
+		}
+		return nil, err
+
+	case <-ctx.Done():
+		<-runErr
+
+		select {
+		case desc := <-parent.desc:
+			return desc, nil
+		default:
+		}
+
+		return nil, fmt.Errorf("source did not provide any stream within %v", p.Timeout)
+	}
+}
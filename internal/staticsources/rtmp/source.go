@@ -44,17 +44,28 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 	}
 
 	// add default port
-	_, _, err = net.SplitHostPort(u.Host)
-	if err != nil {
+	if u.Port() == "" {
 		if u.Scheme == "rtmp" {
-			u.Host = net.JoinHostPort(u.Host, "1935")
+			u.Host = net.JoinHostPort(u.Hostname(), "1935")
 		} else {
-			u.Host = net.JoinHostPort(u.Host, "1936")
+			u.Host = net.JoinHostPort(u.Hostname(), "1936")
 		}
 	}
 
+	// the connection phase is bounded by sourceConnectTimeout, if set,
+	// otherwise by readTimeout and writeTimeout.
+	var connectDeadline time.Time
+	if params.Conf.SourceConnectTimeout != 0 {
+		connectDeadline = time.Now().Add(time.Duration(params.Conf.SourceConnectTimeout))
+	}
+
 	nconn, err := func() (net.Conn, error) {
-		ctx2, cancel2 := context.WithTimeout(params.Context, time.Duration(s.ReadTimeout))
+		dialDeadline := connectDeadline
+		if dialDeadline.IsZero() {
+			dialDeadline = time.Now().Add(time.Duration(s.ReadTimeout))
+		}
+
+		ctx2, cancel2 := context.WithDeadline(params.Context, dialDeadline)
 		defer cancel2()
 
 		if u.Scheme == "rtmp" {
@@ -71,7 +82,7 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 
 	readDone := make(chan error)
 	go func() {
-		readDone <- s.runReader(u, nconn)
+		readDone <- s.runReader(u, nconn, connectDeadline)
 	}()
 
 	for {
@@ -90,9 +101,14 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 	}
 }
 
-func (s *Source) runReader(u *url.URL, nconn net.Conn) error {
-	nconn.SetReadDeadline(time.Now().Add(time.Duration(s.ReadTimeout)))
-	nconn.SetWriteDeadline(time.Now().Add(time.Duration(s.WriteTimeout)))
+func (s *Source) runReader(u *url.URL, nconn net.Conn, connectDeadline time.Time) error {
+	if !connectDeadline.IsZero() {
+		nconn.SetReadDeadline(connectDeadline)
+		nconn.SetWriteDeadline(connectDeadline)
+	} else {
+		nconn.SetReadDeadline(time.Now().Add(time.Duration(s.ReadTimeout)))
+		nconn.SetWriteDeadline(time.Now().Add(time.Duration(s.WriteTimeout)))
+	}
 	conn, err := rtmp.NewClientConn(nconn, u, false)
 	if err != nil {
 		return err
@@ -18,12 +18,17 @@ import (
 func TestSource(t *testing.T) {
 	for _, ca := range []string{
 		"plain",
+		"plain ipv6",
 		"tls",
 	} {
 		t.Run(ca, func(t *testing.T) {
 			ln, err := func() (net.Listener, error) {
-				if ca == "plain" {
+				switch ca {
+				case "plain":
 					return net.Listen("tcp", "127.0.0.1:1935")
+
+				case "plain ipv6":
+					return net.Listen("tcp", "[::1]:1935")
 				}
 
 				serverCertFpath, err := test.CreateTempFile(test.TLSCertPub)
@@ -60,11 +65,16 @@ func TestSource(t *testing.T) {
 
 			var te *test.SourceTester
 
-			if ca == "plain" {
+			if ca == "plain" || ca == "plain ipv6" {
+				source := "rtmp://localhost/teststream"
+				if ca == "plain ipv6" {
+					source = "rtmp://[::1]/teststream"
+				}
+
 				te = test.NewSourceTester(
 					func(p defs.StaticSourceParent) defs.StaticSource {
 						return &Source{
-							ResolvedSource: "rtmp://localhost/teststream",
+							ResolvedSource: source,
 							ReadTimeout:    conf.StringDuration(10 * time.Second),
 							WriteTimeout:   conf.StringDuration(10 * time.Second),
 							Parent:         p,
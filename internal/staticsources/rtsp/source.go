@@ -2,13 +2,18 @@
 package rtsp
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4"
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/gortsplib/v4/pkg/headers"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 
+	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
@@ -60,6 +65,27 @@ func createRangeHeader(cnf *conf.Path) (*headers.Range, error) {
 	}
 }
 
+func applyPayloadTypeOverrides(desc *description.Session, overrides conf.RTSPPayloadTypeOverrides) error {
+	for _, medi := range desc.Medias {
+		for i, forma := range medi.Formats {
+			rtpMap, ok := overrides[int(forma.PayloadType())]
+			if !ok {
+				continue
+			}
+
+			// fmtp parameters are kept, since they contain codec parameters.
+			newForma, err := format.Unmarshal(string(medi.Type), forma.PayloadType(), rtpMap, forma.FMTP())
+			if err != nil {
+				return fmt.Errorf("unable to override payload type %d: %w", forma.PayloadType(), err)
+			}
+
+			medi.Formats[i] = newForma
+		}
+	}
+
+	return nil
+}
+
 // Source is a RTSP static source.
 type Source struct {
 	ResolvedSource string
@@ -80,6 +106,8 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 
 	decodeErrLogger := logger.NewLimitedLogger(s)
 
+	var authSender auth.RTSPSender
+
 	c := &gortsplib.Client{
 		Transport:      params.Conf.RTSPTransport.Transport,
 		TLSConfig:      tls.ConfigForFingerprint(params.Conf.SourceFingerprint),
@@ -87,10 +115,18 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 		WriteTimeout:   time.Duration(s.WriteTimeout),
 		WriteQueueSize: s.WriteQueueSize,
 		AnyPortEnable:  params.Conf.RTSPAnyPort,
+		UserAgent:      params.Conf.SourceUserAgent,
 		OnRequest: func(req *base.Request) {
+			for k, v := range params.Conf.SourceExtraHeaders {
+				req.Header[k] = base.HeaderValue{v}
+			}
+
+			authSender.OnRequest(req)
+
 			s.Log(logger.Debug, "[c->s] %v", req)
 		},
 		OnResponse: func(res *base.Response) {
+			authSender.OnResponse(res)
 			s.Log(logger.Debug, "[s->c] %v", res)
 		},
 		OnTransportSwitch: func(err error) {
@@ -115,6 +151,45 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 	}
 	defer c.Close()
 
+	// closed when the DESCRIBE / SETUP / PLAY phase is complete.
+	connected := make(chan struct{})
+
+	var connectTimeout <-chan time.Time
+	if params.Conf.SourceConnectTimeout != 0 {
+		connectTimer := time.NewTimer(time.Duration(params.Conf.SourceConnectTimeout))
+		defer connectTimer.Stop()
+		connectTimeout = connectTimer.C
+	}
+
+	// receives an error when the session must be closed because of RTCP BYE packets.
+	byeErr := make(chan error, 1)
+	var liveness ssrcLiveness
+
+	onBye := func(ssrcs []uint32) {
+		var err error
+
+		switch params.Conf.RTSPByeBehavior {
+		case conf.RTSPByeBehaviorReconnect:
+			err = fmt.Errorf("received RTCP BYE")
+
+		default:
+			s.Log(logger.Debug, "received RTCP BYE for SSRCs %v", ssrcs)
+			if liveness.onBye(ssrcs) {
+				err = fmt.Errorf("all SSRCs sent RTCP BYE")
+			}
+		}
+
+		if err != nil {
+			select {
+			case byeErr <- err:
+			default:
+			}
+		}
+	}
+
+	// receives an error when too many consecutive packets can't be decoded.
+	malformedErr := make(chan error, 1)
+
 	readErr := make(chan error)
 	go func() {
 		readErr <- func() error {
@@ -123,6 +198,11 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 				return err
 			}
 
+			err = applyPayloadTypeOverrides(desc, params.Conf.RTSPPayloadTypeOverrides)
+			if err != nil {
+				return err
+			}
+
 			err = c.SetupAll(desc.BaseURL, desc.Medias)
 			if err != nil {
 				return err
@@ -138,18 +218,49 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 
 			defer s.Parent.SetNotReady(defs.PathSourceStaticSetNotReadyReq{})
 
+			if params.Conf.RTSPMaxMalformedPackets != 0 {
+				res.Stream.OnMalformedPackets(uint64(params.Conf.RTSPMaxMalformedPackets), func() {
+					select {
+					case malformedErr <- fmt.Errorf("received %d consecutive malformed packets",
+						params.Conf.RTSPMaxMalformedPackets):
+					default:
+					}
+				})
+			}
+
 			for _, medi := range desc.Medias {
 				for _, forma := range medi.Formats {
 					cmedi := medi
 					cforma := forma
 
 					c.OnPacketRTP(cmedi, cforma, func(pkt *rtp.Packet) {
+						if params.Conf.RTSPByeBehavior == conf.RTSPByeBehaviorSSRC {
+							liveness.onRTP(pkt.SSRC)
+						}
+
 						pts, ok := c.PacketPTS(cmedi, pkt)
 						if !ok {
 							return
 						}
 
-						res.Stream.WriteRTPPacket(cmedi, cforma, pkt, time.Now(), pts)
+						ntp := time.Now()
+						if params.Conf.UseAbsoluteTimestamp {
+							if v, ok := c.PacketNTP(cmedi, pkt); ok {
+								ntp = v
+							}
+						}
+
+						res.Stream.WriteRTPPacket(cmedi, cforma, pkt, ntp, pts)
+					})
+				}
+			}
+
+			if params.Conf.RTSPByeBehavior != conf.RTSPByeBehaviorIgnore {
+				for _, medi := range desc.Medias {
+					c.OnPacketRTCP(medi, func(pkt rtcp.Packet) {
+						if bye, ok := pkt.(*rtcp.Goodbye); ok {
+							onBye(bye.Sources)
+						}
 					})
 				}
 			}
@@ -164,6 +275,8 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 				return err
 			}
 
+			close(connected)
+
 			return c.Wait()
 		}()
 	}()
@@ -173,6 +286,25 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 		case err := <-readErr:
 			return err
 
+		case <-connected:
+			connected = nil
+			connectTimeout = nil
+
+		case err := <-byeErr:
+			c.Close()
+			<-readErr
+			return err
+
+		case err := <-malformedErr:
+			c.Close()
+			<-readErr
+			return err
+
+		case <-connectTimeout:
+			c.Close()
+			<-readErr
+			return fmt.Errorf("source did not connect within %v", time.Duration(params.Conf.SourceConnectTimeout))
+
 		case <-params.ReloadConf:
 
 		case <-params.Context.Done():
@@ -0,0 +1,36 @@
+package rtsp
+
+import (
+	"sync"
+)
+
+// ssrcLiveness tracks SSRCs that are sending RTP packets and
+// have not sent a RTCP BYE yet.
+type ssrcLiveness struct {
+	mutex  sync.Mutex
+	active map[uint32]struct{}
+}
+
+func (l *ssrcLiveness) onRTP(ssrc uint32) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active == nil {
+		l.active = make(map[uint32]struct{})
+	}
+
+	// a SSRC that previously sent a BYE is alive again.
+	l.active[ssrc] = struct{}{}
+}
+
+// onBye marks SSRCs as inactive and returns whether there are no active SSRCs left.
+func (l *ssrcLiveness) onBye(ssrcs []uint32) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, ssrc := range ssrcs {
+		delete(l.active, ssrc)
+	}
+
+	return len(l.active) == 0
+}
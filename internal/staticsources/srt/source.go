@@ -2,6 +2,8 @@
 package srt
 
 import (
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
@@ -29,7 +31,10 @@ func (s *Source) Log(level logger.Level, format string, args ...interface{}) {
 
 // Run implements StaticSource.
 func (s *Source) Run(params defs.StaticSourceRunParams) error {
-	s.Log(logger.Debug, "connecting")
+	u, err := url.Parse(s.ResolvedSource)
+	if err != nil {
+		return err
+	}
 
 	conf := srt.DefaultConfig()
 	address, err := conf.UnmarshalURL(s.ResolvedSource)
@@ -37,19 +42,53 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 		return err
 	}
 
+	if params.Conf.SourceConnectTimeout != 0 && !u.Query().Has("conntimeo") {
+		conf.ConnectionTimeout = time.Duration(params.Conf.SourceConnectTimeout)
+	}
+
 	err = conf.Validate()
 	if err != nil {
 		return err
 	}
 
-	sconn, err := srt.Dial("srt", address, conf)
-	if err != nil {
-		return err
+	var sconn srt.Conn
+
+	switch mode := u.Query().Get("mode"); mode {
+	case "", "caller":
+		s.Log(logger.Debug, "connecting")
+
+		sconn, err = srt.Dial("srt", address, conf)
+		if err != nil {
+			return err
+		}
+
+	case "listener":
+		var ln srt.Listener
+		ln, err = srt.Listen("srt", address, conf)
+		if err != nil {
+			return err
+		}
+
+		// closing the listener closes the accepted connection too
+		defer ln.Close()
+
+		s.Log(logger.Debug, "waiting for a caller on %s", address)
+
+		sconn, err = s.waitCaller(ln, conf, params)
+		if err != nil {
+			if params.Context.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unsupported SRT mode: '%s'", mode)
 	}
 
 	readDone := make(chan error)
 	go func() {
-		readDone <- s.runReader(sconn)
+		readDone <- s.runReader(sconn, params.Conf)
 	}()
 
 	for {
@@ -68,9 +107,84 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 	}
 }
 
-func (s *Source) runReader(sconn srt.Conn) error {
-	sconn.SetReadDeadline(time.Now().Add(time.Duration(s.ReadTimeout)))
-	r, err := mcmpegts.NewReader(mcmpegts.NewBufferedReader(sconn))
+func (s *Source) waitCaller(
+	ln srt.Listener,
+	srtConf srt.Config,
+	params defs.StaticSourceRunParams,
+) (srt.Conn, error) {
+	type acceptRes struct {
+		conn srt.Conn
+		err  error
+	}
+
+	acceptDone := make(chan acceptRes, 1)
+
+	go func() {
+		for {
+			conn, _, err := ln.Accept(func(req srt.ConnRequest) srt.ConnType {
+				if srtConf.StreamId != "" && req.StreamId() != srtConf.StreamId {
+					return srt.REJECT
+				}
+
+				if srtConf.Passphrase != "" {
+					if !req.IsEncrypted() || req.SetPassphrase(srtConf.Passphrase) != nil {
+						return srt.REJECT
+					}
+				} else if req.IsEncrypted() {
+					return srt.REJECT
+				}
+
+				return srt.PUBLISH
+			})
+			if err != nil {
+				acceptDone <- acceptRes{err: err}
+				return
+			}
+
+			// connection has been rejected, wait for another caller
+			if conn == nil {
+				continue
+			}
+
+			acceptDone <- acceptRes{conn: conn}
+			return
+		}
+	}()
+
+	var connectTimeout <-chan time.Time
+	if params.Conf.SourceConnectTimeout != 0 {
+		connectTimer := time.NewTimer(time.Duration(params.Conf.SourceConnectTimeout))
+		defer connectTimer.Stop()
+		connectTimeout = connectTimer.C
+	}
+
+	select {
+	case res := <-acceptDone:
+		return res.conn, res.err
+
+	case <-connectTimeout:
+		ln.Close()
+		<-acceptDone
+		return nil, fmt.Errorf("no caller connected within %v", time.Duration(params.Conf.SourceConnectTimeout))
+
+	case <-params.Context.Done():
+		ln.Close()
+		<-acceptDone
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+func (s *Source) runReader(sconn srt.Conn, cnf *conf.Path) error {
+	grace := &mpegts.ReadFailureGrace{
+		Grace:       time.Duration(cnf.SourceReadFailureGrace),
+		ReadTimeout: time.Duration(s.ReadTimeout),
+	}
+
+	scte35 := &mpegts.SCTE35Extractor{R: grace.Wrap(sconn)}
+
+	sconn.SetReadDeadline(grace.Deadline())
+
+	r, err := mcmpegts.NewReader(mcmpegts.NewBufferedReader(scte35))
 	if err != nil {
 		return err
 	}
@@ -83,7 +197,7 @@ func (s *Source) runReader(sconn srt.Conn) error {
 
 	var stream *stream.Stream
 
-	medias, err := mpegts.ToStream(r, &stream)
+	medias, err := mpegts.ToStream(r, scte35, &stream)
 	if err != nil {
 		return err
 	}
@@ -99,10 +213,13 @@ func (s *Source) runReader(sconn srt.Conn) error {
 	stream = res.Stream
 
 	for {
-		sconn.SetReadDeadline(time.Now().Add(time.Duration(s.ReadTimeout)))
+		sconn.SetReadDeadline(grace.Deadline())
 		err := r.Read()
 		if err != nil {
-			return err
+			if !grace.Tolerate(err) {
+				return err
+			}
+			decodeErrLogger.Log(logger.Warn, "read failed, waiting for data: %v", err)
 		}
 	}
 }
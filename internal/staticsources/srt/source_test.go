@@ -70,3 +70,56 @@ func TestSource(t *testing.T) {
 
 	<-te.Unit
 }
+
+func TestSourceListener(t *testing.T) {
+	te := test.NewSourceTester(
+		func(p defs.StaticSourceParent) defs.StaticSource {
+			return &Source{
+				ResolvedSource: "srt://localhost:9003?mode=listener&streamid=sidname&passphrase=ttest1234567",
+				ReadTimeout:    conf.StringDuration(10 * time.Second),
+				Parent:         p,
+			}
+		},
+		&conf.Path{},
+	)
+	defer te.Close()
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL("srt://localhost:9003?streamid=sidname&passphrase=ttest1234567")
+	require.NoError(t, err)
+
+	var conn srt.Conn
+
+	// wait for the source to start listening
+	for i := 0; i < 20; i++ {
+		conn, err = srt.Dial("srt", address, srtConf)
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	defer conn.Close()
+
+	track := &mpegts.Track{
+		Codec: &mpegts.CodecH264{},
+	}
+
+	bw := bufio.NewWriter(conn)
+	w := mpegts.NewWriter(bw, []*mpegts.Track{track})
+
+	err = w.WriteH26x(track, 0, 0, true, [][]byte{{ // IDR
+		5, 1,
+	}})
+	require.NoError(t, err)
+
+	err = w.WriteH26x(track, 0, 0, true, [][]byte{{ // non-IDR
+		5, 2,
+	}})
+	require.NoError(t, err)
+
+	err = bw.Flush()
+	require.NoError(t, err)
+
+	<-te.Unit
+}
@@ -68,12 +68,21 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 
 	var pc packetConn
 
-	if ip4 := addr.IP.To4(); ip4 != nil && addr.IP.IsMulticast() {
+	switch {
+	case addr.IP.To4() != nil && addr.IP.IsMulticast():
 		pc, err = multicast.NewMultiConn(hostPort, true, net.ListenPacket)
 		if err != nil {
 			return err
 		}
-	} else {
+
+	case addr.IP.IsMulticast():
+		// IPv6 multicast groups are joined on the default interface.
+		pc, err = net.ListenMulticastUDP("udp6", nil, addr)
+		if err != nil {
+			return err
+		}
+
+	default:
 		tmp, err := net.ListenPacket(restrictnetwork.Restrict("udp", addr.String()))
 		if err != nil {
 			return err
@@ -90,7 +99,7 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 
 	readerErr := make(chan error)
 	go func() {
-		readerErr <- s.runReader(pc)
+		readerErr <- s.runReader(pc, params.Conf)
 	}()
 
 	select {
@@ -104,9 +113,17 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 	}
 }
 
-func (s *Source) runReader(pc net.PacketConn) error {
-	pc.SetReadDeadline(time.Now().Add(time.Duration(s.ReadTimeout)))
-	r, err := mcmpegts.NewReader(mcmpegts.NewBufferedReader(newPacketConnReader(pc)))
+func (s *Source) runReader(pc net.PacketConn, cnf *conf.Path) error {
+	grace := &mpegts.ReadFailureGrace{
+		Grace:       time.Duration(cnf.SourceReadFailureGrace),
+		ReadTimeout: time.Duration(s.ReadTimeout),
+	}
+
+	scte35 := &mpegts.SCTE35Extractor{R: grace.Wrap(newPacketConnReader(pc))}
+
+	pc.SetReadDeadline(grace.Deadline())
+
+	r, err := mcmpegts.NewReader(mcmpegts.NewBufferedReader(scte35))
 	if err != nil {
 		return err
 	}
@@ -119,7 +136,7 @@ func (s *Source) runReader(pc net.PacketConn) error {
 
 	var stream *stream.Stream
 
-	medias, err := mpegts.ToStream(r, &stream)
+	medias, err := mpegts.ToStream(r, scte35, &stream)
 	if err != nil {
 		return err
 	}
@@ -137,10 +154,13 @@ func (s *Source) runReader(pc net.PacketConn) error {
 	stream = res.Stream
 
 	for {
-		pc.SetReadDeadline(time.Now().Add(time.Duration(s.ReadTimeout)))
+		pc.SetReadDeadline(grace.Deadline())
 		err := r.Read()
 		if err != nil {
-			return err
+			if !grace.Tolerate(err) {
+				return err
+			}
+			decodeErrLogger.Log(logger.Warn, "read failed, waiting for data: %v", err)
 		}
 	}
 }
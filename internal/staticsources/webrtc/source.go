@@ -17,6 +17,20 @@ import (
 	"github.com/bluenviron/mediamtx/internal/protocols/webrtc"
 )
 
+// IsWHEPURL checks whether a HTTP URL points to a WHEP endpoint.
+func IsWHEPURL(ur string) bool {
+	if !strings.HasPrefix(ur, "http://") && !strings.HasPrefix(ur, "https://") {
+		return false
+	}
+
+	u, err := url.Parse(ur)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasSuffix(u.Path, "/whep")
+}
+
 // Source is a WebRTC static source.
 type Source struct {
 	ResolvedSource string
@@ -139,3 +139,17 @@ func TestSource(t *testing.T) {
 
 	<-te.Unit
 }
+
+func TestIsWHEPURL(t *testing.T) {
+	for _, ca := range []struct {
+		url string
+		ok  bool
+	}{
+		{"http://localhost:8889/mystream/whep", true},
+		{"https://localhost:8889/mystream/whep?token=abc", true},
+		{"https://localhost:8888/mystream/index.m3u8", false},
+		{"whep://localhost:8889/mystream/whep", false},
+	} {
+		require.Equal(t, ca.ok, IsWHEPURL(ca.url), ca.url)
+	}
+}
@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// bitrateLimiter detects sources whose bitrate exceeds a limit for a sustained period,
+// with a leaky bucket: the bucket is filled with received bytes and leaks at the maximum
+// bitrate, therefore short bursts (i.e. keyframes) are absorbed, while an excess
+// overflows the bucket once it accumulates more than maxBitrate * window bits.
+type bitrateLimiter struct {
+	maxBitrate uint64
+	window     time.Duration
+	onExceeded func()
+
+	mutex    sync.Mutex
+	level    float64
+	last     time.Time
+	exceeded bool
+}
+
+func (l *bitrateLimiter) reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.level = 0
+	l.last = time.Time{}
+	l.exceeded = false
+}
+
+func (l *bitrateLimiter) onBytes(now time.Time, n uint64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.exceeded {
+		return
+	}
+
+	if !l.last.IsZero() {
+		l.level -= now.Sub(l.last).Seconds() * float64(l.maxBitrate) / 8
+		if l.level < 0 {
+			l.level = 0
+		}
+	}
+
+	l.last = now
+	l.level += float64(n)
+
+	if l.level > l.window.Seconds()*float64(l.maxBitrate)/8 {
+		l.exceeded = true
+		l.onExceeded()
+	}
+}
@@ -0,0 +1,44 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitrateLimiter(t *testing.T) {
+	exceeded := 0
+
+	l := &bitrateLimiter{
+		maxBitrate: 8000,
+		window:     2 * time.Second,
+		onExceeded: func() {
+			exceeded++
+		},
+	}
+
+	now := time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC)
+
+	// burst below the size of the bucket
+	l.onBytes(now, 1500)
+
+	// bitrate equal to the limit
+	for i := 0; i < 100; i++ {
+		now = now.Add(100 * time.Millisecond)
+		l.onBytes(now, 100)
+	}
+	require.Equal(t, 0, exceeded)
+
+	// bitrate twice the limit
+	for i := 0; i < 100; i++ {
+		now = now.Add(100 * time.Millisecond)
+		l.onBytes(now, 200)
+	}
+	require.Equal(t, 1, exceeded)
+
+	l.reset()
+
+	l.onBytes(now, 1500)
+	require.Equal(t, 1, exceeded)
+}
@@ -0,0 +1,142 @@
+package stream
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// GOPStats contains statistics about the distance between keyframes of a video track.
+type GOPStats struct {
+	// number of complete GOPs.
+	Count uint64
+
+	// duration and frame count of the last complete GOP.
+	LastInterval   time.Duration
+	LastFrameCount uint64
+
+	// average and standard deviation of durations and frame counts of all complete GOPs.
+	AverageInterval   time.Duration
+	IntervalStdDev    time.Duration
+	AverageFrameCount float64
+	FrameCountStdDev  float64
+}
+
+// runningStats computes mean and variance of a series
+// without storing it, with the Welford's algorithm.
+type runningStats struct {
+	count uint64
+	mean  float64
+	m2    float64
+}
+
+func (r *runningStats) add(v float64) {
+	r.count++
+	delta := v - r.mean
+	r.mean += delta / float64(r.count)
+	r.m2 += delta * (v - r.mean)
+}
+
+func (r *runningStats) stdDev() float64 {
+	if r.count == 0 {
+		return 0
+	}
+	return math.Sqrt(r.m2 / float64(r.count))
+}
+
+type gopStats struct {
+	mutex sync.Mutex
+
+	keyframeReceived bool
+	lastKeyframePTS  time.Duration
+	frameCount       uint64
+
+	lastInterval   time.Duration
+	lastFrameCount uint64
+	intervals      runningStats
+	frameCounts    runningStats
+}
+
+// newGOPStats allocates a gopStats, if the keyframes of the format can be detected.
+func newGOPStats(forma format.Format) *gopStats {
+	switch forma.(type) {
+	case *format.H264, *format.H265, *format.AV1, *format.VP9, *format.VP8:
+		return &gopStats{}
+	}
+	return nil
+}
+
+func (g *gopStats) reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.keyframeReceived = false
+	g.frameCount = 0
+	g.lastInterval = 0
+	g.lastFrameCount = 0
+	g.intervals = runningStats{}
+	g.frameCounts = runningStats{}
+}
+
+func (g *gopStats) onFrame(pts time.Duration, randomAccess bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if randomAccess {
+		if g.keyframeReceived && pts > g.lastKeyframePTS {
+			g.lastInterval = pts - g.lastKeyframePTS
+			g.lastFrameCount = g.frameCount
+			g.intervals.add(float64(g.lastInterval))
+			g.frameCounts.add(float64(g.frameCount))
+		}
+
+		g.keyframeReceived = true
+		g.lastKeyframePTS = pts
+		g.frameCount = 0
+	}
+
+	if g.keyframeReceived {
+		g.frameCount++
+	}
+}
+
+func (g *gopStats) get() GOPStats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return GOPStats{
+		Count:             g.intervals.count,
+		LastInterval:      g.lastInterval,
+		LastFrameCount:    g.lastFrameCount,
+		AverageInterval:   time.Duration(g.intervals.mean),
+		IntervalStdDev:    time.Duration(g.intervals.stdDev()),
+		AverageFrameCount: g.frameCounts.mean,
+		FrameCountStdDev:  g.frameCounts.stdDev(),
+	}
+}
+
+func unitIsEmpty(u unit.Unit) bool {
+	switch tunit := u.(type) {
+	case *unit.H264:
+		return tunit.AU == nil
+
+	case *unit.H265:
+		return tunit.AU == nil
+
+	case *unit.AV1:
+		return tunit.TU == nil
+
+	case *unit.VP9:
+		return tunit.Frame == nil
+
+	case *unit.VP8:
+		return tunit.Frame == nil
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,43 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGOPStats(t *testing.T) {
+	var gs gopStats
+
+	require.Equal(t, GOPStats{}, gs.get())
+
+	// frames before the first keyframe are ignored
+	gs.onFrame(0, false)
+
+	pts := 100 * time.Millisecond
+
+	// irregular GOPs of 10 and 30 frames
+	for _, frameCount := range []int{10, 30, 10, 30} {
+		for i := 0; i < frameCount; i++ {
+			gs.onFrame(pts, i == 0)
+			pts += 100 * time.Millisecond
+		}
+	}
+
+	// close the last GOP
+	gs.onFrame(pts, true)
+
+	require.Equal(t, GOPStats{
+		Count:             4,
+		LastInterval:      3 * time.Second,
+		LastFrameCount:    30,
+		AverageInterval:   2 * time.Second,
+		IntervalStdDev:    1 * time.Second,
+		AverageFrameCount: 20,
+		FrameCountStdDev:  10,
+	}, gs.get())
+
+	gs.reset()
+	require.Equal(t, GOPStats{}, gs.get())
+}
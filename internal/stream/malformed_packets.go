@@ -0,0 +1,40 @@
+package stream
+
+import (
+	"sync/atomic"
+)
+
+// malformedPackets counts packets written by the source
+// that have been dropped since they can't be decoded.
+type malformedPackets struct {
+	total       uint64
+	consecutive uint64
+
+	maxConsecutive uint64
+	onMaxReached   func()
+	fired          atomic.Bool
+}
+
+func (m *malformedPackets) reset() {
+	atomic.StoreUint64(&m.consecutive, 0)
+	m.fired.Store(false)
+}
+
+func (m *malformedPackets) onValid() {
+	if atomic.LoadUint64(&m.consecutive) != 0 {
+		atomic.StoreUint64(&m.consecutive, 0)
+	}
+}
+
+func (m *malformedPackets) onMalformed() {
+	atomic.AddUint64(&m.total, 1)
+	n := atomic.AddUint64(&m.consecutive, 1)
+
+	if m.onMaxReached != nil && n >= m.maxConsecutive && m.fired.CompareAndSwap(false, true) {
+		m.onMaxReached()
+	}
+}
+
+func (m *malformedPackets) get() uint64 {
+	return atomic.LoadUint64(&m.total)
+}
@@ -0,0 +1,45 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// ptsShifter shifts timestamps of a publisher that replaced a previous one,
+// in order to make them follow the timestamps of the previous publisher.
+type ptsShifter struct {
+	mutex    sync.Mutex
+	maxPTS   time.Duration
+	lastTime time.Time
+	offset   time.Duration
+	resync   bool
+}
+
+func (p *ptsShifter) shift(pts time.Duration) time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+
+	if p.resync {
+		p.offset = p.maxPTS + now.Sub(p.lastTime) - pts
+		p.resync = false
+	}
+
+	pts += p.offset
+
+	if pts > p.maxPTS {
+		p.maxPTS = pts
+	}
+	p.lastTime = now
+
+	return pts
+}
+
+// reset is called when the publisher changes.
+func (p *ptsShifter) reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.resync = !p.lastTime.IsZero()
+}
@@ -0,0 +1,65 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// rtpRewriter rewrites SSRC, sequence numbers and timestamps of RTP packets
+// of a publisher that replaced a previous one, in order to make them follow
+// the ones of the previous publisher.
+// This allows RTSP readers to receive a single continuous stream.
+type rtpRewriter struct {
+	clockRate int
+
+	mutex       sync.Mutex
+	initialized bool
+	ssrc        uint32
+	lastSeq     uint16
+	lastTS      uint32
+	lastTime    time.Time
+	seqOffset   uint16
+	tsOffset    uint32
+	resync      bool
+}
+
+func (r *rtpRewriter) rewrite(pkt *rtp.Packet) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+
+	switch {
+	case !r.initialized:
+		r.initialized = true
+		r.ssrc = pkt.SSRC
+		r.lastTS = pkt.Timestamp
+
+	case r.resync:
+		elapsed := uint32(int64(now.Sub(r.lastTime)) * int64(r.clockRate) / int64(time.Second))
+		r.seqOffset = r.lastSeq + 1 - pkt.SequenceNumber
+		r.tsOffset = r.lastTS + elapsed - pkt.Timestamp
+		r.resync = false
+	}
+
+	pkt.SSRC = r.ssrc
+	pkt.SequenceNumber += r.seqOffset
+	pkt.Timestamp += r.tsOffset
+
+	r.lastSeq = pkt.SequenceNumber
+	// timestamps are not monotonic when B-frames are present.
+	if int32(pkt.Timestamp-r.lastTS) > 0 {
+		r.lastTS = pkt.Timestamp
+	}
+	r.lastTime = now
+}
+
+// reset is called when the publisher changes.
+func (r *rtpRewriter) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.resync = r.initialized
+}
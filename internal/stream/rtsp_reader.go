@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+)
+
+// rtspWriteCounter counts bytes written to the readers of a RTSP stream.
+type rtspWriteCounter struct {
+	bytes uint64
+}
+
+func (c *rtspWriteCounter) add(size int) {
+	atomic.AddUint64(&c.bytes, uint64(size))
+}
+
+// RTSPReader is a RTSP reader whose write queue can grow during bursts.
+// Packets of RTSP readers are queued by the RTSP library, therefore
+// the size of the queue is estimated by comparing written and sent bytes.
+type RTSPReader struct {
+	Session       *gortsplib.ServerSession
+	IsTLS         bool
+	QueueSize     int
+	MaxQueueSize  int
+	MaxPacketSize int
+	Budget        *asyncwriter.GrowthBudget
+	OnOverflow    func()
+
+	counters     []*rtspWriteCounter
+	mutex        sync.Mutex
+	writtenStart uint64
+	sentStart    uint64
+	grown        uint64
+	overflowed   bool
+}
+
+func (r *RTSPReader) initialize(smedias map[*description.Media]*streamMedia) {
+	for _, medi := range r.Session.SetuppedMedias() {
+		if sm, ok := smedias[medi]; ok {
+			if r.IsTLS {
+				r.counters = append(r.counters, &sm.rtspsWritten)
+			} else {
+				r.counters = append(r.counters, &sm.rtspWritten)
+			}
+		}
+	}
+	r.writtenStart = r.written()
+	r.sentStart = r.Session.BytesSent()
+}
+
+func (r *RTSPReader) written() uint64 {
+	var n uint64
+	for _, c := range r.counters {
+		n += atomic.LoadUint64(&c.bytes)
+	}
+	return n
+}
+
+// addWritten adds a packet that is written directly to the session.
+func (r *RTSPReader) addWritten(size int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// written bytes are computed by subtracting writtenStart.
+	r.writtenStart -= uint64(size)
+}
+
+// check is called after packets are written to the stream.
+func (r *RTSPReader) check() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.overflowed {
+		return
+	}
+
+	written := r.written() - r.writtenStart
+	sent := r.Session.BytesSent() - r.sentStart
+
+	var queued uint64
+	if written > sent {
+		queued = written - sent
+	}
+
+	base := uint64(r.QueueSize * r.MaxPacketSize)
+
+	var growth uint64
+	if queued > base {
+		growth = queued - base
+	}
+
+	switch {
+	case growth > r.grown:
+		if queued > uint64(r.MaxQueueSize*r.MaxPacketSize) || !r.Budget.Acquire(growth-r.grown) {
+			r.overflowed = true
+			r.Budget.Release(r.grown)
+			r.grown = 0
+			r.OnOverflow()
+			return
+		}
+
+	case growth < r.grown:
+		r.Budget.Release(r.grown - growth)
+	}
+
+	r.grown = growth
+}
+
+func (r *RTSPReader) close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Budget.Release(r.grown)
+	r.grown = 0
+}
@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// number of recent gaps used to compute percentiles.
+	sourceStatsGapCount = 1024
+
+	// gaps longer than this are counted as stalls.
+	sourceStallThreshold = 1 * time.Second
+)
+
+// SourceStats contains statistics about the timing of data written by the source.
+type SourceStats struct {
+	// time of the last packet, zero if no packet has been received yet.
+	LastPacket time.Time
+
+	// percentiles and maximum of gaps between recent packets.
+	GapP50 time.Duration
+	GapP90 time.Duration
+	GapP99 time.Duration
+	GapMax time.Duration
+
+	// number of gaps longer than the stall threshold.
+	Stalls uint64
+}
+
+type sourceStats struct {
+	mutex    sync.Mutex
+	last     time.Time
+	gaps     [sourceStatsGapCount]time.Duration
+	gapCount int
+	gapPos   int
+	stalls   uint64
+}
+
+func (ss *sourceStats) reset() {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	ss.last = time.Time{}
+	ss.gapCount = 0
+	ss.gapPos = 0
+	ss.stalls = 0
+}
+
+func (ss *sourceStats) onPacket(now time.Time) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	if !ss.last.IsZero() {
+		gap := now.Sub(ss.last)
+
+		ss.gaps[ss.gapPos] = gap
+		ss.gapPos = (ss.gapPos + 1) % sourceStatsGapCount
+		if ss.gapCount < sourceStatsGapCount {
+			ss.gapCount++
+		}
+
+		if gap > sourceStallThreshold {
+			ss.stalls++
+		}
+	}
+
+	ss.last = now
+}
+
+func (ss *sourceStats) get() SourceStats {
+	ss.mutex.Lock()
+	gaps := append([]time.Duration(nil), ss.gaps[:ss.gapCount]...)
+	ret := SourceStats{
+		LastPacket: ss.last,
+		Stalls:     ss.stalls,
+	}
+	ss.mutex.Unlock()
+
+	if len(gaps) != 0 {
+		sort.Slice(gaps, func(i, j int) bool {
+			return gaps[i] < gaps[j]
+		})
+
+		percentile := func(p int) time.Duration {
+			return gaps[(len(gaps)-1)*p/100]
+		}
+
+		ret.GapP50 = percentile(50)
+		ret.GapP90 = percentile(90)
+		ret.GapP99 = percentile(99)
+		ret.GapMax = gaps[len(gaps)-1]
+	}
+
+	return ret
+}
@@ -0,0 +1,37 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceStats(t *testing.T) {
+	var ss sourceStats
+
+	require.Equal(t, SourceStats{}, ss.get())
+
+	now := time.Date(2008, 0o5, 20, 22, 15, 25, 0, time.UTC)
+
+	for i := 0; i < 100; i++ {
+		ss.onPacket(now)
+		now = now.Add(time.Duration(i+1) * time.Millisecond)
+	}
+
+	// stall
+	now = now.Add(2 * time.Second)
+	ss.onPacket(now)
+
+	require.Equal(t, SourceStats{
+		LastPacket: now,
+		GapP50:     50 * time.Millisecond,
+		GapP90:     90 * time.Millisecond,
+		GapP99:     99 * time.Millisecond,
+		GapMax:     2100 * time.Millisecond,
+		Stalls:     1,
+	}, ss.get())
+
+	ss.reset()
+	require.Equal(t, SourceStats{}, ss.get())
+}
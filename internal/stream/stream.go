@@ -2,6 +2,8 @@
 package stream
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -9,20 +11,40 @@ import (
 	"github.com/bluenviron/gortsplib/v4"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/rtplossdetector"
 	"github.com/pion/rtp"
 
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/formatprocessor"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/unit"
 )
 
+// RTSPDefaultSenderReportPeriod is the period of RTCP sender reports
+// sent to RTSP readers when no period is provided.
+const RTSPDefaultSenderReportPeriod = 10 * time.Second
+
 // ReadFunc is the callback passed to AddReader().
 type ReadFunc func(unit.Unit) error
 
+// ErrNoSupportedFormats is returned by New when none of the formats can be processed.
+var ErrNoSupportedFormats = errors.New("none of the tracks of the source has a supported codec")
+
+// UnsupportedFormat is a format that has been skipped
+// since it can't be processed.
+type UnsupportedFormat struct {
+	Format format.Format
+	Err    error
+}
+
+// scte35Track identifies SCTE-35 splice information inside reader queues.
+type scte35Track struct{}
+
 // Stream is a media stream.
 // It stores tracks, readers and allows to write data to readers.
 type Stream struct {
-	desc *description.Session
+	desc               *description.Session
+	generateRTPPackets bool
 
 	bytesReceived *uint64
 	bytesSent     *uint64
@@ -30,36 +52,228 @@ type Stream struct {
 	mutex         sync.RWMutex
 	rtspStream    *gortsplib.ServerStream
 	rtspsStream   *gortsplib.ServerStream
+	buffer        *streamBuffer
+	scte35Readers map[*asyncwriter.Writer]ReadFunc
+	rtspReaders   map[*gortsplib.ServerSession]*RTSPReader
+	replays       map[*asyncwriter.Writer]*streamReplay
+
+	// description of the first publisher, that contains the unsupported formats too,
+	// and medias of the stream indexed by the ones of this description.
+	sourceDesc         *description.Session
+	sourceMedias       map[*description.Media]*description.Media
+	unsupportedFormats []UnsupportedFormat
+
+	// when the publisher is replaced or unsupported formats are skipped,
+	// pointers of its description are mapped to the ones of the stream description.
+	publisherMedias  map[*description.Media]*description.Media
+	publisherFormats map[format.Format]format.Format
+	ptsShifter       *ptsShifter
+
+	sourceStats         *sourceStats
+	sourceStatsExcluded map[*description.Media]struct{}
+
+	onFormatChange   func()
+	bitrateLimiter   *bitrateLimiter
+	malformedPackets *malformedPackets
 }
 
 // New allocates a Stream.
+// When bufferDuration is greater than zero, the most recent units are retained
+// in order to allow readers to start from a point in the past.
+// Formats that can't be processed are removed from the description of the stream
+// and returned by UnsupportedFormats(). If no format is left, ErrNoSupportedFormats is returned.
 func New(
 	udpMaxPayloadSize int,
 	desc *description.Session,
 	generateRTPPackets bool,
+	bufferDuration time.Duration,
 	decodeErrLogger logger.Writer,
 ) (*Stream, error) {
 	s := &Stream{
-		desc:          desc,
-		bytesReceived: new(uint64),
-		bytesSent:     new(uint64),
+		generateRTPPackets: generateRTPPackets,
+		sourceDesc:         desc,
+		sourceMedias:       make(map[*description.Media]*description.Media),
+		bytesReceived:      new(uint64),
+		bytesSent:          new(uint64),
+		scte35Readers:      make(map[*asyncwriter.Writer]ReadFunc),
+		rtspReaders:        make(map[*gortsplib.ServerSession]*RTSPReader),
+		replays:            make(map[*asyncwriter.Writer]*streamReplay),
+		ptsShifter:         &ptsShifter{},
+		sourceStats:        &sourceStats{},
+		malformedPackets:   &malformedPackets{},
 	}
 
 	s.smedias = make(map[*description.Media]*streamMedia)
+	streamDesc := *desc
+	streamDesc.Medias = nil
+
+	for _, medi := range desc.Medias {
+		sm, unsupported := newStreamMedia(udpMaxPayloadSize, medi, generateRTPPackets, decodeErrLogger)
+		s.unsupportedFormats = append(s.unsupportedFormats, unsupported...)
 
-	for _, media := range desc.Medias {
-		var err error
-		s.smedias[media], err = newStreamMedia(udpMaxPayloadSize, media, generateRTPPackets, decodeErrLogger)
-		if err != nil {
-			return nil, err
+		if len(sm.formats) == 0 {
+			continue
+		}
+
+		streamMedi := medi
+
+		if unsupported != nil {
+			streamMedi = new(description.Media)
+			*streamMedi = *medi
+			streamMedi.Formats = nil
+
+			for _, forma := range medi.Formats {
+				if _, ok := sm.formats[forma]; ok {
+					streamMedi.Formats = append(streamMedi.Formats, forma)
+				}
+			}
+		}
+
+		s.smedias[streamMedi] = sm
+		s.sourceMedias[medi] = streamMedi
+		streamDesc.Medias = append(streamDesc.Medias, streamMedi)
+	}
+
+	if s.unsupportedFormats == nil {
+		s.desc = desc
+	} else {
+		if streamDesc.Medias == nil {
+			s.Close()
+			return nil, fmt.Errorf("%w (%v)", ErrNoSupportedFormats, s.unsupportedFormats[0].Err)
+		}
+
+		s.desc = &streamDesc
+		s.mapPublisher(desc)
+	}
+
+	if bufferDuration > 0 {
+		s.buffer = &streamBuffer{
+			duration: bufferDuration,
+		}
+
+		for _, media := range s.desc.Medias {
+			if media.Type == description.MediaTypeVideo {
+				s.buffer.hasVideo = true
+			}
 		}
 	}
 
 	return s, nil
 }
 
+// UnsupportedFormats returns formats of the source that have been skipped
+// since they can't be processed.
+func (s *Stream) UnsupportedFormats() []UnsupportedFormat {
+	return s.unsupportedFormats
+}
+
+// OnFormatChange sets a callback that is called when parameters of a
+// H264 or H265 format (for instance the resolution) change.
+// The callback is called by the goroutine of the publisher and must not block.
+// It must be called before the stream receives data.
+func (s *Stream) OnFormatChange(cb func()) {
+	s.onFormatChange = cb
+}
+
+// OnBitrateExceeded sets a callback that is called when the bitrate of the source
+// exceeds maxBitrate (in bits per second) for a sustained period.
+// Bursts are tolerated as long as the data in excess doesn't exceed
+// the one that can be sent in window at the maximum bitrate.
+// The callback is called once, by the goroutine of the publisher, and must not block.
+// It must be called before the stream receives data.
+func (s *Stream) OnBitrateExceeded(maxBitrate uint64, window time.Duration, cb func()) {
+	s.bitrateLimiter = &bitrateLimiter{
+		maxBitrate: maxBitrate,
+		window:     window,
+		onExceeded: cb,
+	}
+}
+
+// OnMalformedPackets sets a callback that is called when maxConsecutive
+// consecutive packets of the source can't be decoded and are dropped.
+// The callback is called once, by the goroutine of the publisher, and must not block.
+// It must be called before the stream receives data.
+func (s *Stream) OnMalformedPackets(maxConsecutive uint64, cb func()) {
+	s.malformedPackets.maxConsecutive = maxConsecutive
+	s.malformedPackets.onMaxReached = cb
+}
+
+// RepeatParameterSets makes H264 and H265 formats insert the latest parameter sets
+// before every key frame of RTP packets, even when the source sends them once.
+// It must be called before the stream receives data.
+func (s *Stream) RepeatParameterSets() error {
+	for _, sm := range s.smedias {
+		for _, sf := range sm.formats {
+			if r, ok := sf.proc.(formatprocessor.ParameterSetsRepeater); ok {
+				err := r.RepeatParameterSets()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ExcludeFromSourceStats excludes data of a media, that is not produced
+// by the source, from source statistics.
+// It must be called before the stream receives data.
+func (s *Stream) ExcludeFromSourceStats(medi *description.Media) {
+	if s.sourceStatsExcluded == nil {
+		s.sourceStatsExcluded = make(map[*description.Media]struct{})
+	}
+	s.sourceStatsExcluded[medi] = struct{}{}
+}
+
+// SourceStats returns statistics about the timing of data written by the source.
+// Statistics are reset when the publisher is replaced.
+func (s *Stream) SourceStats() SourceStats {
+	return s.sourceStats.get()
+}
+
+// GOPStats returns statistics about the distance between keyframes
+// of the first video track of the source whose keyframes can be detected.
+// It returns nil if there's no such track.
+// Statistics are reset when the publisher is replaced.
+func (s *Stream) GOPStats() *GOPStats {
+	for _, medi := range s.desc.Medias {
+		if _, ok := s.sourceStatsExcluded[medi]; ok || medi.Type != description.MediaTypeVideo {
+			continue
+		}
+
+		for _, forma := range medi.Formats {
+			sf := s.smedias[medi].formats[forma]
+			if sf.gopStats != nil {
+				v := sf.gopStats.get()
+				return &v
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Stream) onSourcePacket(medi *description.Media) {
+	if _, ok := s.sourceStatsExcluded[medi]; !ok {
+		s.sourceStats.onPacket(time.Now())
+	}
+}
+
 // Close closes all resources of the stream.
 func (s *Stream) Close() {
+	s.mutex.Lock()
+	for r, rp := range s.replays {
+		rp.close()
+		delete(s.replays, r)
+	}
+	s.mutex.Unlock()
+
+	for _, sm := range s.smedias {
+		for _, sf := range sm.formats {
+			sf.close()
+		}
+	}
+
 	if s.rtspStream != nil {
 		s.rtspStream.Close()
 	}
@@ -78,6 +292,12 @@ func (s *Stream) BytesReceived() uint64 {
 	return atomic.LoadUint64(s.bytesReceived)
 }
 
+// PacketsMalformed returns the number of packets of the source
+// that have been dropped since they can't be decoded.
+func (s *Stream) PacketsMalformed() uint64 {
+	return s.malformedPackets.get()
+}
+
 // BytesSent returns sent bytes.
 func (s *Stream) BytesSent() uint64 {
 	s.mutex.RLock()
@@ -94,23 +314,47 @@ func (s *Stream) BytesSent() uint64 {
 }
 
 // RTSPStream returns the RTSP stream.
-func (s *Stream) RTSPStream(server *gortsplib.Server) *gortsplib.ServerStream {
+// RTCP sender reports are sent with senderReportPeriod, therefore
+// the server must have DisableRTCPSenderReports set.
+func (s *Stream) RTSPStream(server *gortsplib.Server, senderReportPeriod time.Duration) *gortsplib.ServerStream {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	if s.rtspStream == nil {
 		s.rtspStream = gortsplib.NewServerStream(server, s.desc)
+
+		if senderReportPeriod == 0 {
+			senderReportPeriod = RTSPDefaultSenderReportPeriod
+		}
+
+		for medi, sm := range s.smedias {
+			for _, sf := range sm.formats {
+				sf.rtspSender = sf.newRTSPSender(s.rtspStream, medi, &sm.rtspWritten, senderReportPeriod)
+			}
+		}
 	}
 	return s.rtspStream
 }
 
 // RTSPSStream returns the RTSPS stream.
-func (s *Stream) RTSPSStream(server *gortsplib.Server) *gortsplib.ServerStream {
+// RTCP sender reports are sent with senderReportPeriod, therefore
+// the server must have DisableRTCPSenderReports set.
+func (s *Stream) RTSPSStream(server *gortsplib.Server, senderReportPeriod time.Duration) *gortsplib.ServerStream {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	if s.rtspsStream == nil {
 		s.rtspsStream = gortsplib.NewServerStream(server, s.desc)
+
+		if senderReportPeriod == 0 {
+			senderReportPeriod = RTSPDefaultSenderReportPeriod
+		}
+
+		for medi, sm := range s.smedias {
+			for _, sf := range sm.formats {
+				sf.rtspsSender = sf.newRTSPSender(s.rtspsStream, medi, &sm.rtspsWritten, senderReportPeriod)
+			}
+		}
 	}
 	return s.rtspsStream
 }
@@ -135,6 +379,155 @@ func (s *Stream) RemoveReader(r *asyncwriter.Writer) {
 			sf.removeReader(r)
 		}
 	}
+
+	delete(s.scte35Readers, r)
+
+	if rp, ok := s.replays[r]; ok {
+		rp.close()
+		delete(s.replays, r)
+	}
+}
+
+// AddRTSPReader adds a RTSP reader whose write queue can grow during bursts.
+// It must be called inside OnPlay.
+func (s *Stream) AddRTSPReader(r *RTSPReader) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r.initialize(s.smedias)
+	s.rtspReaders[r.Session] = r
+}
+
+// RemoveRTSPReader removes a RTSP reader.
+func (s *Stream) RemoveRTSPReader(r *RTSPReader) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.rtspReaders, r.Session)
+	r.close()
+}
+
+// AddSCTE35Reader adds a reader of SCTE-35 splice information.
+func (s *Stream) AddSCTE35Reader(r *asyncwriter.Writer, cb ReadFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.scte35Readers[r] = cb
+}
+
+// ReplayToReader sends to a reader the units received in the last duration,
+// starting from the closest previous keyframe, then continues with live units.
+// When duration is zero, units of the current GOP are sent at once,
+// in order to allow the reader to start decoding immediately.
+// Otherwise, units are sent in background with the same timing they were received,
+// slightly accelerated, and live units are sent once the reader has caught up.
+// The queue of the reader is extended in order to contain the replayed units.
+// It must be called after AddReader() and before the reader is started.
+// It has no effect if the stream has no buffer.
+func (s *Stream) ReplayToReader(r *asyncwriter.Writer, duration time.Duration) {
+	if s.buffer == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries := s.buffer.entriesSince(time.Now().Add(-duration))
+
+	// units that have already been queued are contained into the buffer too,
+	// therefore they are discarded and sent again in the right order.
+	r.Clear()
+
+	n := 0
+	for _, e := range entries {
+		if _, ok := e.sf.readers[r]; ok {
+			n++
+		}
+	}
+	r.Extend(n)
+
+	if duration > 0 && len(entries) != 0 {
+		rp := &streamReplay{
+			s:       s,
+			r:       r,
+			entries: entries,
+			done:    make(chan struct{}),
+		}
+		s.replays[r] = rp
+		go rp.run()
+		return
+	}
+
+	for _, e := range entries {
+		if cb, ok := e.sf.readers[r]; ok {
+			e.sf.pushToReader(s, r, cb, e.u, e.size, e.randomAccess)
+		}
+	}
+}
+
+// BufferedUnit is a unit retained by the buffer of a stream.
+type BufferedUnit struct {
+	Media  *description.Media
+	Format format.Format
+	Unit   unit.Unit
+}
+
+// BufferedUnits returns the units received between start and end.
+// The range is clamped to the content of the buffer and starts from the closest previous keyframe.
+func (s *Stream) BufferedUnits(start time.Time, end time.Time) ([]BufferedUnit, error) {
+	if s.buffer == nil {
+		return nil, fmt.Errorf("stream has no buffer")
+	}
+
+	entries := s.buffer.entriesBetween(start, end)
+
+	ret := make([]BufferedUnit, len(entries))
+	for i, e := range entries {
+		ret[i] = BufferedUnit{
+			Media:  e.medi,
+			Format: e.sf.forma,
+			Unit:   e.u,
+		}
+	}
+	return ret, nil
+}
+
+// ReplayToRTSPSession sends to a RTSP session the RTP packets received
+// since the last keyframe, in order to allow the session to start decoding immediately.
+// Parameters that are not sent in-band are provided by the session description.
+// It must be called inside OnPlay, before the session starts receiving live packets.
+// It has no effect if the stream has no buffer.
+func (s *Stream) ReplayToRTSPSession(ss *gortsplib.ServerSession) {
+	if s.buffer == nil {
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	setupped := make(map[*description.Media]struct{})
+	for _, medi := range ss.SetuppedMedias() {
+		setupped[medi] = struct{}{}
+	}
+
+	r := s.rtspReaders[ss]
+
+	for _, e := range s.buffer.entriesSince(time.Now()) {
+		if _, ok := setupped[e.medi]; !ok {
+			continue
+		}
+
+		for _, pkt := range e.u.GetRTPPackets() {
+			err := ss.WritePacketRTP(e.medi, pkt)
+			if err != nil {
+				return
+			}
+
+			if r != nil {
+				r.addWritten(pkt.MarshalSize())
+			}
+		}
+	}
 }
 
 // FormatsForReader returns all formats that a reader is reading.
@@ -155,14 +548,131 @@ func (s *Stream) FormatsForReader(r *asyncwriter.Writer) []format.Format {
 	return formats
 }
 
+// descsAreCompatible checks whether two descriptions contain the same tracks.
+func descsAreCompatible(desc1 *description.Session, desc2 *description.Session) bool {
+	if len(desc1.Medias) != len(desc2.Medias) {
+		return false
+	}
+
+	for i, medi1 := range desc1.Medias {
+		medi2 := desc2.Medias[i]
+
+		if medi1.Type != medi2.Type || len(medi1.Formats) != len(medi2.Formats) {
+			return false
+		}
+
+		for j, forma1 := range medi1.Formats {
+			forma2 := medi2.Formats[j]
+
+			if forma1.Codec() != forma2.Codec() ||
+				forma1.PayloadType() != forma2.PayloadType() ||
+				forma1.RTPMap() != forma2.RTPMap() {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ReplacePublisher allows a new publisher to write to the stream
+// without interrupting readers. Timestamps of the new publisher are shifted
+// in order to follow the ones of the previous publisher, and its RTP packets
+// are forwarded to RTSP readers with the SSRC of the previous publisher
+// and with continuous sequence numbers and timestamps.
+// It returns false when the description of the new publisher is not compatible
+// with the one of the stream.
+func (s *Stream) ReplacePublisher(desc *description.Session, generateRTPPackets bool) bool {
+	if generateRTPPackets != s.generateRTPPackets || !descsAreCompatible(s.sourceDesc, desc) {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.mapPublisher(desc)
+
+	s.ptsShifter.reset()
+	s.sourceStats.reset()
+	s.malformedPackets.reset()
+
+	if s.bitrateLimiter != nil {
+		s.bitrateLimiter.reset()
+	}
+
+	for _, sm := range s.smedias {
+		for _, sf := range sm.formats {
+			if sf.gopStats != nil {
+				sf.gopStats.reset()
+			}
+			if sf.rtpRewriter != nil {
+				sf.rtpRewriter.reset()
+			}
+			sf.lossDetector = rtplossdetector.New()
+			sf.afterLoss = false
+		}
+	}
+
+	return true
+}
+
+// mapPublisher maps medias and formats of a publisher to the ones of the stream.
+// Medias and formats that are not part of the stream are not mapped.
+func (s *Stream) mapPublisher(desc *description.Session) {
+	s.publisherMedias = make(map[*description.Media]*description.Media)
+	s.publisherFormats = make(map[format.Format]format.Format)
+
+	for i, medi := range desc.Medias {
+		sourceMedi := s.sourceDesc.Medias[i]
+
+		if streamMedi, ok := s.sourceMedias[sourceMedi]; ok {
+			s.publisherMedias[medi] = streamMedi
+		}
+
+		for j, forma := range medi.Formats {
+			s.publisherFormats[forma] = sourceMedi.Formats[j]
+		}
+	}
+}
+
+// lookup returns the media and format of the stream that correspond
+// to the ones of the current publisher.
+// It returns nil when they belong to a publisher that has been replaced.
+func (s *Stream) lookup(medi *description.Media, forma format.Format) (*description.Media, *streamFormat) {
+	if s.publisherMedias != nil {
+		var ok bool
+		medi, ok = s.publisherMedias[medi]
+		if !ok {
+			return nil, nil
+		}
+		forma, ok = s.publisherFormats[forma]
+		if !ok {
+			return nil, nil
+		}
+	}
+
+	sm, ok := s.smedias[medi]
+	if !ok {
+		return nil, nil
+	}
+
+	return medi, sm.formats[forma]
+}
+
 // WriteUnit writes a Unit.
 func (s *Stream) WriteUnit(medi *description.Media, forma format.Format, u unit.Unit) {
-	sm := s.smedias[medi]
-	sf := sm.formats[forma]
-
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
+	medi, sf := s.lookup(medi, forma)
+	if sf == nil {
+		return
+	}
+
+	s.onSourcePacket(medi)
+
+	u.SetPTS(s.ptsShifter.shift(u.GetPTS()))
+
 	sf.writeUnit(s, medi, u)
 }
 
@@ -174,11 +684,28 @@ func (s *Stream) WriteRTPPacket(
 	ntp time.Time,
 	pts time.Duration,
 ) {
-	sm := s.smedias[medi]
-	sf := sm.formats[forma]
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 
+	medi, sf := s.lookup(medi, forma)
+	if sf == nil {
+		return
+	}
+
+	s.onSourcePacket(medi)
+
+	sf.writeRTPPacket(s, medi, pkt, ntp, s.ptsShifter.shift(pts))
+}
+
+// WriteSCTE35 writes SCTE-35 splice information.
+func (s *Stream) WriteSCTE35(u *unit.SCTE35) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	sf.writeRTPPacket(s, medi, pkt, ntp, pts)
+	for r, cb := range s.scte35Readers {
+		ccb := cb
+		r.Push(scte35Track{}, true, uint64(len(u.Section)), func() error {
+			return ccb(u)
+		})
+	}
 }
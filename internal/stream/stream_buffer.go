@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+type streamBufferEntry struct {
+	medi         *description.Media
+	sf           *streamFormat
+	u            unit.Unit
+	size         uint64
+	randomAccess bool
+	t            time.Time
+	seq          uint64
+}
+
+// streamBufferGOP is a group of units that starts with a keyframe.
+type streamBufferGOP struct {
+	start   time.Time
+	entries []streamBufferEntry
+}
+
+// streamBuffer retains the most recent units of a stream,
+// in order to allow readers to start reading from a point in the past.
+type streamBuffer struct {
+	duration time.Duration
+	hasVideo bool
+
+	mutex sync.Mutex
+	gops  []*streamBufferGOP
+	seq   uint64
+}
+
+func (b *streamBuffer) write(e streamBufferEntry, isVideo bool) {
+	now := time.Now()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// when the stream contains video, only video keyframes can be used as starting points.
+	if e.randomAccess && (isVideo || !b.hasVideo) {
+		gop := &streamBufferGOP{start: now}
+
+		// when RTP packets are routed as is, a keyframe is split into multiple units
+		// and only the last one is marked as random access.
+		// Previous units of the same frame, and units of other tracks
+		// interleaved with them, are moved into the new GOP.
+		if isVideo && len(b.gops) != 0 {
+			prev := b.gops[len(b.gops)-1]
+			n := len(prev.entries)
+			for n > 0 {
+				pe := prev.entries[n-1]
+				if pe.sf == e.sf && (pe.randomAccess || pe.u.GetPTS() != e.u.GetPTS()) {
+					break
+				}
+				n--
+			}
+			gop.entries = append(gop.entries, prev.entries[n:]...)
+			prev.entries = prev.entries[:n]
+		}
+
+		b.gops = append(b.gops, gop)
+	}
+
+	// units received before the first keyframe can't be decoded.
+	if len(b.gops) == 0 {
+		return
+	}
+
+	b.seq++
+	e.t = now
+	e.seq = b.seq
+	gop := b.gops[len(b.gops)-1]
+	gop.entries = append(gop.entries, e)
+
+	// remove GOPs that are no longer needed to start from (now - duration).
+	cutoff := now.Add(-b.duration)
+	n := 0
+	for n < (len(b.gops)-1) && !b.gops[n+1].start.After(cutoff) {
+		n++
+	}
+
+	if n != 0 {
+		for i := 0; i < n; i++ {
+			b.gops[i] = nil
+		}
+		b.gops = b.gops[n:]
+	}
+}
+
+// entriesSince returns entries starting from the last keyframe received before t,
+// or from the first available keyframe if t is not contained in the buffer.
+func (b *streamBuffer) entriesSince(t time.Time) []streamBufferEntry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.gops) == 0 {
+		return nil
+	}
+
+	start := 0
+	for start < (len(b.gops)-1) && !b.gops[start+1].start.After(t) {
+		start++
+	}
+
+	var ret []streamBufferEntry
+	for _, gop := range b.gops[start:] {
+		ret = append(ret, gop.entries...)
+	}
+	return ret
+}
+
+// entriesBetween returns entries received until end, starting from the last keyframe
+// received before start, or from the first available keyframe if start is not contained in the buffer.
+func (b *streamBuffer) entriesBetween(start time.Time, end time.Time) []streamBufferEntry {
+	entries := b.entriesSince(start)
+
+	n := len(entries)
+	for n > 0 && entries[n-1].t.After(end) {
+		n--
+	}
+	return entries[:n]
+}
+
+// entriesAfter returns entries that follow the entry with the given sequence number.
+// If the entry has been removed, entries start from the first available keyframe.
+func (b *streamBuffer) entriesAfter(seq uint64) []streamBufferEntry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var ret []streamBufferEntry
+	for _, gop := range b.gops {
+		for _, e := range gop.entries {
+			if e.seq > seq {
+				ret = append(ret, e)
+			}
+		}
+	}
+	return ret
+}
@@ -1,11 +1,20 @@
 package stream
 
 import (
+	"bytes"
 	"sync/atomic"
 	"time"
 
+	"github.com/bluenviron/gortsplib/v4"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/rtcpsender"
+	"github.com/bluenviron/gortsplib/v4/pkg/rtplossdetector"
+	"github.com/bluenviron/mediacommon/pkg/codecs/av1"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/bluenviron/mediacommon/pkg/codecs/vp9"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 
 	"github.com/bluenviron/mediamtx/internal/asyncwriter"
@@ -22,10 +31,51 @@ func unitSize(u unit.Unit) uint64 {
 	return n
 }
 
+// unitRandomAccess checks whether a unit can be decoded without previous units.
+func unitRandomAccess(u unit.Unit) bool {
+	switch tunit := u.(type) {
+	case *unit.H264:
+		return h264.IDRPresent(tunit.AU)
+
+	case *unit.H265:
+		return h265.IsRandomAccess(tunit.AU)
+
+	case *unit.AV1:
+		ok, _ := av1.ContainsKeyFrame(tunit.TU)
+		return ok
+
+	case *unit.VP9:
+		var h vp9.Header
+		err := h.Unmarshal(tunit.Frame)
+		return err == nil && !h.ShowExistingFrame && h.FrameType == vp9.FrameTypeKeyFrame
+
+	case *unit.VP8:
+		return len(tunit.Frame) > 0 && (tunit.Frame[0]&0x01) == 0
+
+	default:
+		return true
+	}
+}
+
 type streamFormat struct {
+	forma           format.Format
 	decodeErrLogger logger.Writer
 	proc            formatprocessor.Processor
 	readers         map[*asyncwriter.Writer]ReadFunc
+	lastSPS         []byte
+	gopStats        *gopStats
+
+	// detects lost RTP packets, that cause decode errors
+	// that must not be counted as malformed packets.
+	lossDetector *rtplossdetector.LossDetector
+	afterLoss    bool
+
+	// rewrites RTP packets of publishers that replaced a previous one.
+	rtpRewriter *rtpRewriter
+
+	// sender reports sent to RTSP readers.
+	rtspSender  *rtcpsender.RTCPSender
+	rtspsSender *rtcpsender.RTCPSender
 }
 
 func newStreamFormat(
@@ -40,14 +90,50 @@ func newStreamFormat(
 	}
 
 	sf := &streamFormat{
+		forma:           forma,
 		decodeErrLogger: decodeErrLogger,
 		proc:            proc,
 		readers:         make(map[*asyncwriter.Writer]ReadFunc),
+		gopStats:        newGOPStats(forma),
+		lossDetector:    rtplossdetector.New(),
+	}
+
+	// RTP packets are forwarded to RTSP readers as they are received.
+	if !generateRTPPackets {
+		sf.rtpRewriter = &rtpRewriter{clockRate: forma.ClockRate()}
 	}
 
+	// store initial parameters, provided by the description.
+	sf.spsChanged()
+
 	return sf, nil
 }
 
+func (sf *streamFormat) close() {
+	if sf.rtspSender != nil {
+		sf.rtspSender.Close()
+	}
+	if sf.rtspsSender != nil {
+		sf.rtspsSender.Close()
+	}
+}
+
+func (sf *streamFormat) newRTSPSender(
+	rstream *gortsplib.ServerStream,
+	medi *description.Media,
+	counter *rtspWriteCounter,
+	senderReportPeriod time.Duration,
+) *rtcpsender.RTCPSender {
+	return rtcpsender.New(
+		sf.forma.ClockRate(),
+		senderReportPeriod,
+		nil,
+		func(pkt rtcp.Packet) {
+			rstream.WritePacketRTCP(medi, pkt) //nolint:errcheck
+			counter.add(pkt.MarshalSize())
+		})
+}
+
 func (sf *streamFormat) addReader(r *asyncwriter.Writer, cb ReadFunc) {
 	sf.readers[r] = cb
 }
@@ -73,39 +159,144 @@ func (sf *streamFormat) writeRTPPacket(
 	ntp time.Time,
 	pts time.Duration,
 ) {
-	hasNonRTSPReaders := len(sf.readers) > 0
+	// units must be decoded in order to detect keyframes when they are buffered
+	// or when GOP statistics are computed.
+	hasNonRTSPReaders := len(sf.readers) > 0 || s.buffer != nil || sf.gopStats != nil
+
+	_, excluded := s.sourceStatsExcluded[medi]
+
+	if sf.lossDetector.Process(pkt) != 0 {
+		sf.afterLoss = true
+	}
 
 	u, err := sf.proc.ProcessRTPPacket(pkt, ntp, pts, hasNonRTSPReaders)
 	if err != nil {
+		// packets that follow lost ones can't be decoded until the decoder resynchronizes,
+		// for instance when they are non-starting fragments. They are not malformed.
+		if !excluded && !sf.afterLoss {
+			s.malformedPackets.onMalformed()
+		}
 		sf.decodeErrLogger.Log(logger.Warn, err.Error())
 		return
 	}
 
+	sf.afterLoss = false
+
+	if !excluded {
+		s.malformedPackets.onValid()
+	}
+
 	sf.writeUnitInner(s, medi, u)
 }
 
+// spsChanged checks whether the SPS of the format differs from the previous one.
+func (sf *streamFormat) spsChanged() bool {
+	var sps []byte
+
+	switch forma := sf.forma.(type) {
+	case *format.H264:
+		sps, _ = forma.SafeParams()
+
+	case *format.H265:
+		_, sps, _ = forma.SafeParams()
+
+	default:
+		return false
+	}
+
+	if sps == nil || bytes.Equal(sps, sf.lastSPS) {
+		return false
+	}
+
+	changed := sf.lastSPS != nil
+	sf.lastSPS = sps
+	return changed
+}
+
 func (sf *streamFormat) writeUnitInner(s *Stream, medi *description.Media, u unit.Unit) {
+	if s.onFormatChange != nil && sf.spsChanged() {
+		s.onFormatChange()
+	}
+
 	size := unitSize(u)
 
 	atomic.AddUint64(s.bytesReceived, size)
 
+	if s.bitrateLimiter != nil {
+		if _, ok := s.sourceStatsExcluded[medi]; !ok {
+			s.bitrateLimiter.onBytes(time.Now(), size)
+		}
+	}
+
+	if sf.gopStats != nil && !unitIsEmpty(u) {
+		sf.gopStats.onFrame(u.GetPTS(), unitRandomAccess(u))
+	}
+
+	if sf.rtpRewriter != nil {
+		for _, pkt := range u.GetRTPPackets() {
+			sf.rtpRewriter.rewrite(pkt)
+		}
+	}
+
 	if s.rtspStream != nil {
 		for _, pkt := range u.GetRTPPackets() {
 			s.rtspStream.WritePacketRTPWithNTP(medi, pkt, u.GetNTP()) //nolint:errcheck
+			s.smedias[medi].rtspWritten.add(pkt.MarshalSize())
+			if sf.rtspSender != nil {
+				sf.rtspSender.ProcessPacket(pkt, u.GetNTP(), sf.forma.PTSEqualsDTS(pkt))
+			}
 		}
 	}
 
 	if s.rtspsStream != nil {
 		for _, pkt := range u.GetRTPPackets() {
 			s.rtspsStream.WritePacketRTPWithNTP(medi, pkt, u.GetNTP()) //nolint:errcheck
+			s.smedias[medi].rtspsWritten.add(pkt.MarshalSize())
+			if sf.rtspsSender != nil {
+				sf.rtspsSender.ProcessPacket(pkt, u.GetNTP(), sf.forma.PTSEqualsDTS(pkt))
+			}
 		}
 	}
 
+	for _, r := range s.rtspReaders {
+		r.check()
+	}
+
+	if len(sf.readers) == 0 && s.buffer == nil {
+		return
+	}
+
+	randomAccess := unitRandomAccess(u)
+
+	if s.buffer != nil {
+		s.buffer.write(streamBufferEntry{
+			medi:         medi,
+			sf:           sf,
+			u:            u,
+			size:         size,
+			randomAccess: randomAccess,
+		}, medi.Type == description.MediaTypeVideo)
+	}
+
 	for writer, cb := range sf.readers {
-		ccb := cb
-		writer.Push(func() error {
-			atomic.AddUint64(s.bytesSent, size)
-			return ccb(u)
-		})
+		// readers that are replaying receive live units once they have caught up.
+		if _, ok := s.replays[writer]; ok {
+			continue
+		}
+		sf.pushToReader(s, writer, cb, u, size, randomAccess)
 	}
 }
+
+func (sf *streamFormat) pushToReader(
+	s *Stream,
+	writer *asyncwriter.Writer,
+	cb ReadFunc,
+	u unit.Unit,
+	size uint64,
+	randomAccess bool,
+) {
+	writer.Push(sf, randomAccess, size, func() error {
+		atomic.AddUint64(s.bytesSent, size)
+		return cb(u)
+	})
+}
@@ -9,24 +9,37 @@ import (
 
 type streamMedia struct {
 	formats map[format.Format]*streamFormat
+
+	// packets written to RTSP and RTSPS readers.
+	rtspWritten  rtspWriteCounter
+	rtspsWritten rtspWriteCounter
 }
 
+// newStreamMedia allocates a streamMedia.
+// Formats that can't be processed are skipped and returned.
 func newStreamMedia(udpMaxPayloadSize int,
 	medi *description.Media,
 	generateRTPPackets bool,
 	decodeErrLogger logger.Writer,
-) (*streamMedia, error) {
+) (*streamMedia, []UnsupportedFormat) {
 	sm := &streamMedia{
 		formats: make(map[format.Format]*streamFormat),
 	}
 
+	var unsupported []UnsupportedFormat
+
 	for _, forma := range medi.Formats {
-		var err error
-		sm.formats[forma], err = newStreamFormat(udpMaxPayloadSize, forma, generateRTPPackets, decodeErrLogger)
+		sf, err := newStreamFormat(udpMaxPayloadSize, forma, generateRTPPackets, decodeErrLogger)
 		if err != nil {
-			return nil, err
+			unsupported = append(unsupported, UnsupportedFormat{
+				Format: forma,
+				Err:    err,
+			})
+			continue
 		}
+
+		sm.formats[forma] = sf
 	}
 
-	return sm, nil
+	return sm, unsupported
 }
@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+)
+
+// replaySpeed is the speed of replays compared to the original stream.
+// It is greater than one in order to allow readers to catch up with the live stream.
+const replaySpeed = 1.25
+
+// streamReplay sends buffered units to a reader with the same timing they were received,
+// until the reader reaches the live stream.
+type streamReplay struct {
+	s       *Stream
+	r       *asyncwriter.Writer
+	entries []streamBufferEntry
+
+	done chan struct{}
+}
+
+func (rp *streamReplay) close() {
+	close(rp.done)
+}
+
+func (rp *streamReplay) run() {
+	start := time.Now()
+	origin := rp.entries[0].t
+	entries := rp.entries
+	var seq uint64
+
+	for {
+		for _, e := range entries {
+			wait := time.Until(start.Add(time.Duration(float64(e.t.Sub(origin)) / replaySpeed)))
+			if wait > 0 {
+				t := time.NewTimer(wait)
+				select {
+				case <-t.C:
+				case <-rp.done:
+					t.Stop()
+					return
+				}
+			}
+
+			rp.s.mutex.RLock()
+			if cb, ok := e.sf.readers[rp.r]; ok {
+				e.sf.pushToReader(rp.s, rp.r, cb, e.u, e.size, e.randomAccess)
+			}
+			rp.s.mutex.RUnlock()
+
+			seq = e.seq
+		}
+
+		rp.s.mutex.Lock()
+
+		select {
+		case <-rp.done:
+			rp.s.mutex.Unlock()
+			return
+		default:
+		}
+
+		// units are written into the buffer and routed to readers under the same lock,
+		// therefore no unit is lost when the reader switches to the live stream.
+		entries = rp.s.buffer.entriesAfter(seq)
+		if len(entries) == 0 {
+			delete(rp.s.replays, rp.r)
+			rp.s.mutex.Unlock()
+			return
+		}
+
+		rp.s.mutex.Unlock()
+	}
+}
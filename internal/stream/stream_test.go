@@ -0,0 +1,479 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+type nilLogger struct{}
+
+func (nilLogger) Log(logger.Level, string, ...interface{}) {}
+
+var (
+	testSPS = []byte{
+		0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02,
+		0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+		0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9, 0x20,
+	}
+	testPPS = []byte{0x08, 0x06, 0x07, 0x08}
+)
+
+func TestStreamReplayToReader(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+
+	s, err := New(1460, &description.Session{Medias: []*description.Media{medi}}, true, 10*time.Second, nilLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	writeFrame := func(pts time.Duration, idr bool) {
+		au := [][]byte{{0x01, 0x02}}
+		if idr {
+			au = [][]byte{testSPS, testPPS, {0x05, 0x02}}
+		}
+		s.WriteUnit(medi, forma, &unit.H264{
+			Base: unit.Base{PTS: pts},
+			AU:   au,
+		})
+	}
+
+	writeFrame(0, false) // dropped since it precedes the first keyframe
+	writeFrame(1*time.Second, true)
+	writeFrame(2*time.Second, false)
+	writeFrame(3*time.Second, true)
+	writeFrame(4*time.Second, false)
+
+	for _, ca := range []struct {
+		name     string
+		duration time.Duration
+		livePTS  time.Duration
+		pts      []time.Duration
+	}{
+		{
+			"last keyframe",
+			0,
+			5 * time.Second,
+			[]time.Duration{3 * time.Second, 4 * time.Second, 5 * time.Second},
+		},
+		{
+			"first keyframe",
+			time.Hour,
+			6 * time.Second,
+			[]time.Duration{
+				1 * time.Second, 2 * time.Second, 3 * time.Second,
+				4 * time.Second, 5 * time.Second, 6 * time.Second,
+			},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			w := asyncwriter.New(512, conf.ReaderOverflowPolicyDropNewest, nilLogger{})
+
+			var pts []time.Duration
+			done := make(chan struct{})
+
+			s.AddReader(w, medi, forma, func(u unit.Unit) error {
+				pts = append(pts, u.GetPTS())
+				if len(pts) == len(ca.pts) {
+					close(done)
+				}
+				return nil
+			})
+			defer s.RemoveReader(w)
+
+			// this unit is queued before the replay, and must not be received twice.
+			writeFrame(ca.livePTS, false)
+
+			s.ReplayToReader(w, ca.duration)
+
+			w.Start()
+			<-done
+			w.Stop()
+
+			require.Equal(t, ca.pts, pts)
+		})
+	}
+}
+
+func TestStreamReplayToReaderExceedingQueue(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+
+	s, err := New(1460, &description.Session{Medias: []*description.Media{medi}}, true, 10*time.Second, nilLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	writeFrame := func(pts time.Duration, idr bool) {
+		au := [][]byte{{0x01, 0x02}}
+		if idr {
+			au = [][]byte{testSPS, testPPS, {0x05, 0x02}}
+		}
+		s.WriteUnit(medi, forma, &unit.H264{
+			Base: unit.Base{PTS: pts},
+			AU:   au,
+		})
+	}
+
+	for i := 0; i < 20; i++ {
+		writeFrame(time.Duration(i)*time.Millisecond, i == 0)
+	}
+
+	// the queue is smaller than the replayed units.
+	w := asyncwriter.New(4, conf.ReaderOverflowPolicyDisconnect, nilLogger{})
+
+	var pts []time.Duration
+	done := make(chan struct{})
+
+	s.AddReader(w, medi, forma, func(u unit.Unit) error {
+		pts = append(pts, u.GetPTS())
+		if len(pts) == 24 {
+			close(done)
+		}
+		return nil
+	})
+	defer s.RemoveReader(w)
+
+	s.ReplayToReader(w, time.Hour)
+
+	// live units can still be queued after the replayed ones.
+	for i := 20; i < 24; i++ {
+		writeFrame(time.Duration(i)*time.Millisecond, false)
+	}
+
+	w.Start()
+
+	select {
+	case <-done:
+	case err := <-w.Error():
+		t.Fatal(err)
+	}
+
+	w.Stop()
+
+	for i, v := range pts {
+		require.Equal(t, time.Duration(i)*time.Millisecond, v)
+	}
+}
+
+func TestStreamReplayToReaderPaced(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+
+	s, err := New(1460, &description.Session{Medias: []*description.Media{medi}}, true, 10*time.Second, nilLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	writeFrame := func(pts time.Duration, idr bool) {
+		au := [][]byte{{0x01, 0x02}}
+		if idr {
+			au = [][]byte{testSPS, testPPS, {0x05, 0x02}}
+		}
+		s.WriteUnit(medi, forma, &unit.H264{
+			Base: unit.Base{PTS: pts},
+			AU:   au,
+		})
+	}
+
+	for i := 0; i < 4; i++ {
+		writeFrame(time.Duration(i)*250*time.Millisecond, i == 0)
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	w := asyncwriter.New(512, conf.ReaderOverflowPolicyDropNewest, nilLogger{})
+
+	var pts []time.Duration
+	var received []time.Time
+	done := make(chan struct{})
+
+	s.AddReader(w, medi, forma, func(u unit.Unit) error {
+		pts = append(pts, u.GetPTS())
+		received = append(received, time.Now())
+		if len(pts) == 6 {
+			close(done)
+		}
+		return nil
+	})
+	defer s.RemoveReader(w)
+
+	s.ReplayToReader(w, time.Hour)
+
+	w.Start()
+
+	// live units are sent after replayed ones, even if they're received during the replay.
+	writeFrame(1000*time.Millisecond, false)
+	time.Sleep(250 * time.Millisecond)
+	writeFrame(1250*time.Millisecond, false)
+
+	<-done
+	w.Stop()
+
+	require.Equal(t, []time.Duration{
+		0, 250 * time.Millisecond, 500 * time.Millisecond, 750 * time.Millisecond,
+		1000 * time.Millisecond, 1250 * time.Millisecond,
+	}, pts)
+
+	// replayed units are not sent at once.
+	require.Greater(t, received[3].Sub(received[0]), 500*time.Millisecond)
+}
+
+func TestStreamBufferSplitKeyframe(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+
+	// RTP packets are routed as is, and the buffer has the minimum duration.
+	s, err := New(1460, &description.Session{Medias: []*description.Media{medi}}, false, time.Nanosecond, nilLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	seq := uint16(0)
+
+	writePacket := func(pts time.Duration, marker bool, payload []byte) {
+		seq++
+		s.WriteRTPPacket(medi, forma, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				SequenceNumber: seq,
+				Marker:         marker,
+			},
+			Payload: payload,
+		}, time.Time{}, pts)
+	}
+
+	writePacket(0, true, []byte{0x65, 0x01})             // IDR
+	writePacket(1*time.Second, true, []byte{0x41, 0x02}) // non-IDR
+	writePacket(2*time.Second, false, []byte{0x7c, 0x85, 0x03})
+	writePacket(2*time.Second, true, []byte{0x7c, 0x45, 0x04})
+
+	var seqs []uint16
+	for _, e := range s.buffer.entriesSince(time.Now()) {
+		for _, pkt := range e.u.GetRTPPackets() {
+			seqs = append(seqs, pkt.SequenceNumber)
+		}
+	}
+
+	// the keyframe is complete.
+	require.Equal(t, []uint16{3, 4}, seqs)
+}
+
+func TestStreamReplacePublisher(t *testing.T) {
+	newDesc := func() *description.Session {
+		return &description.Session{Medias: []*description.Media{{
+			Type: description.MediaTypeVideo,
+			Formats: []format.Format{&format.H264{
+				PayloadTyp:        96,
+				PacketizationMode: 1,
+			}},
+		}}}
+	}
+
+	desc := newDesc()
+
+	s, err := New(1460, desc, true, 0, nilLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	w := asyncwriter.New(512, conf.ReaderOverflowPolicyDropNewest, nilLogger{})
+
+	var pts []time.Duration
+	done := make(chan struct{})
+
+	s.AddReader(w, desc.Medias[0], desc.Medias[0].Formats[0], func(u unit.Unit) error {
+		pts = append(pts, u.GetPTS())
+		if len(pts) == 3 {
+			close(done)
+		}
+		return nil
+	})
+	defer s.RemoveReader(w)
+
+	w.Start()
+	defer w.Stop()
+
+	writeFrame := func(desc *description.Session, pts time.Duration) {
+		s.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{PTS: pts},
+			AU:   [][]byte{testSPS, testPPS, {0x05, 0x02}},
+		})
+	}
+
+	writeFrame(desc, 5*time.Second)
+
+	ok := s.ReplacePublisher(&description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeAudio,
+		Formats: []format.Format{&format.G711{PayloadTyp: 8, SampleRate: 8000, ChannelCount: 1}},
+	}}}, true)
+	require.Equal(t, false, ok)
+
+	desc2 := newDesc()
+	ok = s.ReplacePublisher(desc2, true)
+	require.Equal(t, true, ok)
+
+	// units of the previous publisher are discarded.
+	writeFrame(desc, 6*time.Second)
+
+	writeFrame(desc2, 0)
+	writeFrame(desc2, 1*time.Second)
+
+	<-done
+
+	require.Equal(t, 5*time.Second, pts[0])
+	require.Greater(t, pts[1], pts[0])
+	require.Equal(t, 1*time.Second, pts[2]-pts[1])
+}
+
+func TestStreamUnsupportedFormats(t *testing.T) {
+	videoFormat := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+
+	audioFormat := &format.G722{}
+
+	desc := &description.Session{Medias: []*description.Media{
+		{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{videoFormat},
+		},
+		{
+			Type:    description.MediaTypeAudio,
+			Formats: []format.Format{audioFormat},
+		},
+	}}
+
+	s, err := New(1460, desc, true, 0, nilLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.Equal(t, []*description.Media{desc.Medias[0]}, s.Desc().Medias)
+	require.Len(t, s.UnsupportedFormats(), 1)
+	require.Equal(t, audioFormat, s.UnsupportedFormats()[0].Format)
+
+	w := asyncwriter.New(512, conf.ReaderOverflowPolicyDropNewest, nilLogger{})
+
+	done := make(chan struct{})
+
+	s.AddReader(w, s.Desc().Medias[0], videoFormat, func(_ unit.Unit) error {
+		close(done)
+		return nil
+	})
+	defer s.RemoveReader(w)
+
+	w.Start()
+	defer w.Stop()
+
+	// units of skipped formats are discarded.
+	s.WriteUnit(desc.Medias[1], audioFormat, &unit.Generic{})
+
+	s.WriteUnit(desc.Medias[0], videoFormat, &unit.H264{
+		AU: [][]byte{testSPS, testPPS, {0x05, 0x02}},
+	})
+
+	<-done
+
+	_, err = New(1460, &description.Session{Medias: desc.Medias[1:]}, true, 0, nilLogger{})
+	require.ErrorIs(t, err, ErrNoSupportedFormats)
+}
+
+func TestStreamMalformedPackets(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+
+	// a buffer is used in order to decode packets.
+	s, err := New(1460, &description.Session{Medias: []*description.Media{medi}}, false, 1*time.Second, nilLogger{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	fired := 0
+	s.OnMalformedPackets(3, func() {
+		fired++
+	})
+
+	seqNum := uint16(0)
+
+	writePacket := func(payload []byte) {
+		s.WriteRTPPacket(medi, forma, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				Marker:         true,
+				SequenceNumber: seqNum,
+			},
+			Payload: payload,
+		}, time.Now(), 0)
+		seqNum++
+	}
+
+	writePacket([]byte{})
+	writePacket([]byte{0x1c}) // truncated FU-A
+	writePacket([]byte{0x05, 0x01})
+	writePacket([]byte{})
+	writePacket([]byte{0x1c})
+	require.Equal(t, uint64(4), s.PacketsMalformed())
+	require.Equal(t, 0, fired)
+
+	writePacket([]byte{})
+	require.Equal(t, uint64(5), s.PacketsMalformed())
+	require.Equal(t, 1, fired)
+
+	writePacket([]byte{})
+	require.Equal(t, uint64(6), s.PacketsMalformed())
+	require.Equal(t, 1, fired)
+
+	writePacket([]byte{0x05, 0x01})
+	require.Equal(t, uint64(6), s.PacketsMalformed())
+
+	// errors caused by lost packets are not counted.
+	seqNum += 3
+	writePacket([]byte{0x1c, 0x45, 0x01}) // non-starting FU-A
+	writePacket([]byte{0x1c, 0x45, 0x01})
+	require.Equal(t, uint64(6), s.PacketsMalformed())
+
+	writePacket([]byte{0x05, 0x01})
+	writePacket([]byte{0x1c, 0x45, 0x01})
+	require.Equal(t, uint64(7), s.PacketsMalformed())
+}
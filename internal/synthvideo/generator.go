@@ -0,0 +1,135 @@
+// Package synthvideo contains a generator of a synthetic black video track.
+package synthvideo
+
+import (
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/format/rtph264"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+const (
+	framerate = 5
+)
+
+// NewMedia allocates a media that contains the synthetic video track.
+func NewMedia() *description.Media {
+	return &description.Media{
+		Type: description.MediaTypeVideo,
+		Formats: []format.Format{&format.H264{
+			PayloadTyp:        96,
+			SPS:               generateSPS(),
+			PPS:               generatePPS(),
+			PacketizationMode: 1,
+		}},
+	}
+}
+
+// Generator writes black frames to a media of a stream,
+// at a low framerate in order to keep CPU usage low.
+type Generator struct {
+	Stream *stream.Stream
+	Media  *description.Media
+	Parent logger.Writer
+
+	format  *format.H264
+	encoder *rtph264.Encoder
+	idrs    [2][]byte
+	skips   [gopSize][]byte
+
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+// Initialize initializes Generator.
+func (g *Generator) Initialize() error {
+	g.format = g.Media.Formats[0].(*format.H264)
+
+	var err error
+	g.encoder, err = g.format.CreateEncoder()
+	if err != nil {
+		return err
+	}
+
+	// frames are generated once, since they are always the same.
+	for i := range g.idrs {
+		g.idrs[i] = generateIDR(uint32(i))
+	}
+	for i := 1; i < gopSize; i++ {
+		g.skips[i] = generateSkip(uint32(i))
+	}
+
+	g.terminate = make(chan struct{})
+	g.done = make(chan struct{})
+
+	g.Log(logger.Info, "generating a black video track")
+
+	go g.run()
+
+	return nil
+}
+
+// Close closes the generator.
+func (g *Generator) Close() {
+	close(g.terminate)
+	<-g.done
+}
+
+// Log implements logger.Writer.
+func (g *Generator) Log(level logger.Level, format string, args ...interface{}) {
+	g.Parent.Log(level, "[synthetic video] "+format, args...)
+}
+
+func (g *Generator) run() {
+	defer close(g.done)
+
+	ticker := time.NewTicker(time.Second / framerate)
+	defer ticker.Stop()
+
+	start := time.Now()
+	g.writeFrame(0, start)
+
+	for i := uint32(1); ; i++ {
+		select {
+		case now := <-ticker.C:
+			// timestamps are derived from the frame count, in order to avoid jitter.
+			g.writeFrame(i, now)
+
+		case <-g.terminate:
+			return
+		}
+	}
+}
+
+func (g *Generator) writeFrame(i uint32, ntp time.Time) {
+	var au [][]byte
+
+	frameNum := i % gopSize
+	if frameNum == 0 {
+		au = [][]byte{
+			g.format.SPS,
+			g.format.PPS,
+			// consecutive IDRs must have different IDs.
+			g.idrs[(i/gopSize)%2],
+		}
+	} else {
+		au = [][]byte{g.skips[frameNum]}
+	}
+
+	pkts, err := g.encoder.Encode(au)
+	if err != nil {
+		g.Log(logger.Warn, "%v", err)
+		return
+	}
+
+	pts := time.Duration(i) * time.Second / framerate
+
+	for _, pkt := range pkts {
+		pkt.Timestamp += uint32(int64(i) * 90000 / framerate)
+		g.Stream.WriteRTPPacket(g.Media, g.format, pkt, ntp, pts)
+	}
+}
@@ -0,0 +1,111 @@
+package synthvideo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/asyncwriter"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+func TestNewMedia(t *testing.T) {
+	medi := NewMedia()
+	forma := medi.Formats[0].(*format.H264)
+
+	var sps h264.SPS
+	err := sps.Unmarshal(forma.SPS)
+	require.NoError(t, err)
+	require.Equal(t, 320, sps.Width())
+	require.Equal(t, 240, sps.Height())
+	require.Equal(t, uint8(66), sps.ProfileIdc)
+}
+
+func TestEmulationPreventionAdd(t *testing.T) {
+	for _, ca := range [][]byte{
+		{0, 0, 0, 1},
+		{0, 0, 3, 0, 0, 2},
+		{1, 0, 0, 0, 0, 0, 4},
+	} {
+		byts := emulationPreventionAdd(ca)
+		require.Equal(t, ca, h264.EmulationPreventionRemove(byts))
+
+		for i := 2; i < len(byts); i++ {
+			if byts[i-2] == 0 && byts[i-1] == 0 {
+				require.Equal(t, byte(3), byts[i])
+			}
+		}
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{
+		{
+			Type:    description.MediaTypeAudio,
+			Formats: []format.Format{test.FormatMPEG4Audio},
+		},
+		NewMedia(),
+	}}
+
+	strm, err := stream.New(
+		1460,
+		desc,
+		false,
+		0,
+		&test.NilLogger{},
+	)
+	require.NoError(t, err)
+	defer strm.Close()
+
+	aw := asyncwriter.New(512, conf.ReaderOverflowPolicyDropNewest, &test.NilLogger{})
+
+	var aus [][][]byte
+	done := make(chan struct{})
+
+	strm.AddReader(aw, desc.Medias[1], desc.Medias[1].Formats[0], func(u unit.Unit) error {
+		if len(aus) < 2 {
+			aus = append(aus, u.(*unit.H264).AU)
+			if len(aus) == 2 {
+				close(done)
+			}
+		}
+		return nil
+	})
+
+	aw.Start()
+	defer aw.Stop()
+
+	g := &Generator{
+		Stream: strm,
+		Media:  desc.Medias[1],
+		Parent: &test.NilLogger{},
+	}
+	err = g.Initialize()
+	require.NoError(t, err)
+	defer g.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out")
+	}
+
+	forma := desc.Medias[1].Formats[0].(*format.H264)
+
+	require.Equal(t, [][]byte{
+		forma.SPS,
+		forma.PPS,
+		generateIDR(0),
+	}, aus[0])
+
+	require.Equal(t, [][]byte{
+		generateSkip(1),
+	}, aus[1])
+}
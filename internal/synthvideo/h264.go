@@ -0,0 +1,208 @@
+package synthvideo
+
+import (
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+)
+
+const (
+	// size of the picture, in macroblocks.
+	widthMBs  = 20
+	heightMBs = 15
+
+	// sample values of black.
+	blackLuma   = 16
+	blackChroma = 128
+
+	// number of frames between two IDRs.
+	gopSize = 5
+
+	// log2_max_frame_num_minus4 is zero.
+	maxFrameNum = 16
+)
+
+type bitWriter struct {
+	buf  []byte
+	nbit int
+}
+
+func (w *bitWriter) writeBit(v uint8) {
+	if w.nbit%8 == 0 {
+		w.buf = append(w.buf, 0)
+	}
+	w.buf[len(w.buf)-1] |= (v & 1) << (7 - w.nbit%8)
+	w.nbit++
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(uint8(v >> i))
+	}
+}
+
+func (w *bitWriter) writeUE(v uint32) {
+	v++
+	n := 0
+	for tmp := v; tmp > 1; tmp >>= 1 {
+		n++
+	}
+	w.writeBits(0, n)
+	w.writeBits(uint64(v), n+1)
+}
+
+func (w *bitWriter) writeSE(v int32) {
+	if v <= 0 {
+		w.writeUE(uint32(-2 * v))
+	} else {
+		w.writeUE(uint32(2*v - 1))
+	}
+}
+
+func (w *bitWriter) align() {
+	for w.nbit%8 != 0 {
+		w.writeBit(0)
+	}
+}
+
+// writeTrailingBits writes rbsp_trailing_bits().
+func (w *bitWriter) writeTrailingBits() {
+	w.writeBit(1)
+	w.align()
+}
+
+// emulationPreventionAdd converts a RBSP into a NALU payload.
+func emulationPreventionAdd(rbsp []byte) []byte {
+	out := make([]byte, 0, len(rbsp)+len(rbsp)/64)
+	zeros := 0
+
+	for _, b := range rbsp {
+		if zeros == 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+
+		out = append(out, b)
+
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+
+	return out
+}
+
+func nalu(header byte, w *bitWriter) []byte {
+	return append([]byte{header}, emulationPreventionAdd(w.buf)...)
+}
+
+// Constrained Baseline profile, level 3.0, POC type 2 (output order equals decoding order).
+func generateSPS() []byte {
+	w := &bitWriter{}
+	w.writeBits(66, 8)   // profile_idc
+	w.writeBits(0xC0, 8) // constraint_set0_flag, constraint_set1_flag
+	w.writeBits(30, 8)   // level_idc
+	w.writeUE(0)         // seq_parameter_set_id
+	w.writeUE(0)         // log2_max_frame_num_minus4
+	w.writeUE(2)         // pic_order_cnt_type
+	w.writeUE(1)         // max_num_ref_frames
+	w.writeBit(0)        // gaps_in_frame_num_value_allowed_flag
+	w.writeUE(widthMBs - 1)
+	w.writeUE(heightMBs - 1)
+	w.writeBit(1) // frame_mbs_only_flag
+	w.writeBit(1) // direct_8x8_inference_flag
+	w.writeBit(0) // frame_cropping_flag
+	w.writeBit(0) // vui_parameters_present_flag
+	w.writeTrailingBits()
+
+	return nalu(byte(3<<5)|byte(h264.NALUTypeSPS), w)
+}
+
+func generatePPS() []byte {
+	w := &bitWriter{}
+	w.writeUE(0)      // pic_parameter_set_id
+	w.writeUE(0)      // seq_parameter_set_id
+	w.writeBit(0)     // entropy_coding_mode_flag
+	w.writeBit(0)     // bottom_field_pic_order_in_frame_present_flag
+	w.writeUE(0)      // num_slice_groups_minus1
+	w.writeUE(0)      // num_ref_idx_l0_default_active_minus1
+	w.writeUE(0)      // num_ref_idx_l1_default_active_minus1
+	w.writeBit(0)     // weighted_pred_flag
+	w.writeBits(0, 2) // weighted_bipred_idc
+	w.writeSE(0)      // pic_init_qp_minus26
+	w.writeSE(0)      // pic_init_qs_minus26
+	w.writeSE(0)      // chroma_qp_index_offset
+	w.writeBit(0)     // deblocking_filter_control_present_flag
+	w.writeBit(0)     // constrained_intra_pred_flag
+	w.writeBit(0)     // redundant_pic_cnt_present_flag
+	w.writeTrailingBits()
+
+	return nalu(byte(3<<5)|byte(h264.NALUTypePPS), w)
+}
+
+// generateIDR generates a black IDR frame.
+// The first macroblock is I_PCM and contains black samples;
+// the other ones are Intra 16x16 with DC prediction and no residual,
+// therefore they copy the value of their neighbors.
+// This keeps the frame small regardless of its size.
+func generateIDR(idrPicID uint32) []byte {
+	w := &bitWriter{}
+	w.writeUE(0)        // first_mb_in_slice
+	w.writeUE(7)        // slice_type (I)
+	w.writeUE(0)        // pic_parameter_set_id
+	w.writeBits(0, 4)   // frame_num
+	w.writeUE(idrPicID) // idr_pic_id
+	w.writeBit(0)       // no_output_of_prior_pics_flag
+	w.writeBit(0)       // long_term_reference_flag
+	w.writeSE(0)        // slice_qp_delta
+
+	// I_PCM
+	w.writeUE(25) // mb_type
+	w.align()     // pcm_alignment_zero_bit
+	for i := 0; i < 256; i++ {
+		w.writeBits(blackLuma, 8)
+	}
+	for i := 0; i < 2*64; i++ {
+		w.writeBits(blackChroma, 8)
+	}
+
+	for i := 1; i < widthMBs*heightMBs; i++ {
+		x := i % widthMBs
+		y := i / widthMBs
+
+		w.writeUE(3) // mb_type (I_16x16_2_0_0)
+		w.writeUE(0) // intra_chroma_pred_mode (DC)
+		w.writeSE(0) // mb_qp_delta
+
+		// coeff_token of Intra16x16DCLevel with TotalCoeff = 0.
+		// nC is 16 when the only available neighbor is the I_PCM macroblock.
+		if (x == 1 && y == 0) || (x == 0 && y == 1) {
+			w.writeBits(0b000011, 6)
+		} else {
+			w.writeBit(1)
+		}
+	}
+
+	w.writeTrailingBits()
+
+	return nalu(byte(3<<5)|byte(h264.NALUTypeIDR), w)
+}
+
+// generateSkip generates a frame that repeats the previous one.
+func generateSkip(frameNum uint32) []byte {
+	w := &bitWriter{}
+	w.writeUE(0)                                 // first_mb_in_slice
+	w.writeUE(5)                                 // slice_type (P)
+	w.writeUE(0)                                 // pic_parameter_set_id
+	w.writeBits(uint64(frameNum%maxFrameNum), 4) // frame_num
+	w.writeBit(0)                                // num_ref_idx_active_override_flag
+	w.writeBit(0)                                // ref_pic_list_modification_flag_l0
+	w.writeBit(0)                                // adaptive_ref_pic_marking_mode_flag
+	w.writeSE(0)                                 // slice_qp_delta
+	w.writeUE(widthMBs * heightMBs)              // mb_skip_run
+	w.writeTrailingBits()
+
+	// frames are used as reference, since POC type 2
+	// doesn't allow consecutive non-reference frames.
+	return nalu(byte(2<<5)|byte(h264.NALUTypeNonIDR), w)
+}
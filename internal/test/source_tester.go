@@ -65,10 +65,11 @@ func (t *SourceTester) SetReady(req defs.PathSourceStaticSetReadyReq) defs.PathS
 		1460,
 		req.Desc,
 		req.GenerateRTPPackets,
+		0,
 		t,
 	)
 
-	t.writer = asyncwriter.New(2048, t)
+	t.writer = asyncwriter.New(2048, conf.ReaderOverflowPolicyDropNewest, t)
 	t.stream.AddReader(t.writer, req.Desc.Medias[0], req.Desc.Medias[0].Formats[0], func(u unit.Unit) error {
 		t.Unit <- u
 		close(t.Unit)
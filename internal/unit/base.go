@@ -27,3 +27,8 @@ func (u *Base) GetNTP() time.Time {
 func (u *Base) GetPTS() time.Duration {
 	return u.PTS
 }
+
+// SetPTS implements Unit.
+func (u *Base) SetPTS(pts time.Duration) {
+	u.PTS = pts
+}
@@ -0,0 +1,8 @@
+package unit
+
+// SCTE35 is a SCTE-35 splice information section.
+// PTS and NTP refer to the splice time.
+type SCTE35 struct {
+	Base
+	Section []byte
+}
@@ -17,4 +17,7 @@ type Unit interface {
 
 	// returns the PTS of the unit.
 	GetPTS() time.Duration
+
+	// sets the PTS of the unit.
+	SetPTS(time.Duration)
 }
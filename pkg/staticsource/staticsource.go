@@ -0,0 +1,128 @@
+// Package staticsource allows to add static sources to MediaMTX.
+//
+// A static source is registered for one or more URL schemes by calling Register
+// inside init(). Paths whose source uses one of these schemes are then read with the
+// static source. Static sources maintained in a separate module can be linked into a
+// custom build by importing their package from a file of the main package, for instance:
+//
+//	import _ "example.com/mysource"
+package staticsource
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+// LogLevel is a log level.
+type LogLevel = logger.Level
+
+// PathConf is the configuration of a path.
+type PathConf = conf.Path
+
+// Description is the description of a static source, that is shown in the API.
+type Description = defs.APIPathSourceOrReader
+
+// SetReadyReq contains arguments of Parent.SetReady().
+type SetReadyReq = defs.PathSourceStaticSetReadyReq
+
+// SetReadyRes contains the response of Parent.SetReady().
+type SetReadyRes = defs.PathSourceStaticSetReadyRes
+
+// SetNotReadyReq contains arguments of Parent.SetNotReady().
+type SetNotReadyReq = defs.PathSourceStaticSetNotReadyReq
+
+// StaticSource is a static source.
+type StaticSource = defs.StaticSource
+
+// Parent is the parent of a static source.
+type Parent = defs.StaticSourceParent
+
+// RunParams is the set of params passed to StaticSource.Run().
+type RunParams = defs.StaticSourceRunParams
+
+// Params are the parameters used to create a static source.
+type Params struct {
+	ResolvedSource string
+	LogLevel       conf.LogLevel
+	ReadTimeout    conf.StringDuration
+	WriteTimeout   conf.StringDuration
+	WriteQueueSize int
+	Parent         Parent
+}
+
+// Factory creates a static source.
+type Factory func(Params) StaticSource
+
+var (
+	mutex     sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+func init() {
+	conf.SetStaticSourceChecker(IsRegistered)
+}
+
+// Scheme returns the scheme of a source.
+// Sources without a scheme, like "rpiCamera", are returned as they are.
+func Scheme(source string) string {
+	if i := strings.Index(source, "://"); i >= 0 {
+		return source[:i]
+	}
+	return source
+}
+
+// Register registers a factory for the given schemes.
+// A scheme is the part of the source that precedes "://",
+// or the whole source when it doesn't contain "://" (like "rpiCamera").
+// It is meant to be called inside init(), and returns an error
+// if a scheme is already registered.
+// Built-in static sources are registered with this function too.
+func Register(factory Factory, schemes ...string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, scheme := range schemes {
+		switch scheme {
+		case "", "publisher", "redirect":
+			return fmt.Errorf("invalid scheme: '%s'", scheme)
+		}
+
+		if _, ok := factories[scheme]; ok {
+			return fmt.Errorf("a static source is already registered for scheme '%s'", scheme)
+		}
+	}
+
+	for _, scheme := range schemes {
+		factories[scheme] = factory
+	}
+
+	return nil
+}
+
+// IsRegistered checks whether a static source is registered for the scheme of a source.
+func IsRegistered(source string) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	_, ok := factories[Scheme(source)]
+	return ok
+}
+
+// New creates a static source with the factory registered for the scheme of the source.
+// It returns nil if no factory is registered.
+func New(p Params) StaticSource {
+	mutex.RLock()
+	factory, ok := factories[Scheme(p.ResolvedSource)]
+	mutex.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return factory(p)
+}
@@ -0,0 +1,51 @@
+package staticsource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	_ "github.com/bluenviron/mediamtx/internal/staticsources"
+	rtspsource "github.com/bluenviron/mediamtx/internal/staticsources/rtsp"
+	webrtcsource "github.com/bluenviron/mediamtx/internal/staticsources/webrtc"
+	"github.com/bluenviron/mediamtx/pkg/staticsource"
+)
+
+type testSource struct{}
+
+func (testSource) Log(staticsource.LogLevel, string, ...interface{}) {}
+
+func (testSource) Run(staticsource.RunParams) error {
+	return nil
+}
+
+func (testSource) APISourceDescribe() staticsource.Description {
+	return staticsource.Description{Type: "testSource"}
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(staticsource.Params) staticsource.StaticSource {
+		return &testSource{}
+	}
+
+	err := staticsource.Register(factory, "mytest", "rtsp")
+	require.EqualError(t, err, "a static source is already registered for scheme 'rtsp'")
+
+	err = staticsource.Register(factory, "publisher")
+	require.EqualError(t, err, "invalid scheme: 'publisher'")
+
+	err = staticsource.Register(factory, "mytest")
+	require.NoError(t, err)
+
+	err = staticsource.Register(factory, "mytest")
+	require.EqualError(t, err, "a static source is already registered for scheme 'mytest'")
+
+	require.IsType(t, &testSource{}, staticsource.New(staticsource.Params{ResolvedSource: "mytest://localhost/stream"}))
+	require.IsType(t, &rtspsource.Source{}, staticsource.New(staticsource.Params{ResolvedSource: "rtsp://localhost/stream"}))
+	require.IsType(t, &webrtcsource.Source{}, staticsource.New(staticsource.Params{ResolvedSource: "http://localhost/stream/whep"}))
+	require.Nil(t, staticsource.New(staticsource.Params{ResolvedSource: "unknown://localhost/stream"}))
+
+	pconf := conf.Path{Source: "mytest://localhost/stream"}
+	require.Equal(t, true, pconf.HasStaticSource())
+}